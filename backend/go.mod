@@ -10,16 +10,23 @@ require (
 require (
 	github.com/ARUMANDESU/validation v1.0.0
 	github.com/BurntSushi/toml v1.6.0
+	github.com/coreos/go-oidc/v3 v3.17.0
+	github.com/go-jose/go-jose/v4 v4.1.3
 	github.com/nicksnyder/go-i18n/v2 v2.6.1
+	github.com/nwaples/rardecode v1.1.3
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/image v0.25.0
+	golang.org/x/oauth2 v0.34.0
+	golang.org/x/text v0.33.0
 )
 
 require (
 	github.com/ARUMANDESU/gobuildergen v0.0.1 // indirect
+	github.com/asaskevich/govalidator v0.0.0-20230301143203-a9d515a09cc2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
-	golang.org/x/text v0.33.0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
@@ -0,0 +1,87 @@
+package localfs
+
+import (
+	"crypto/rand"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+// DiskCache keeps generated files, such as cover thumbnails, in a directory. Keys are file names
+// and should be content addressed: entries are never invalidated, missing ones are generated again.
+type DiskCache struct {
+	root *os.Root
+}
+
+// OpenDiskCache opens the cache in the directory at path, creating it if needed.
+func OpenDiskCache(path string) (*DiskCache, error) {
+	const op = errorx.Op("localfs.OpenDiskCache")
+
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, op.Wrap(err)
+	}
+	root, err := os.OpenRoot(path)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return &DiskCache{root: root}, nil
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	name, ok := entryName(key)
+	if !ok {
+		return nil, false
+	}
+	data, err := c.root.ReadFile(name)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read cache entry", "key", key, "error", err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// Add writes the entry to a temporary file first, so readers never see a partial entry.
+// Failures are only logged, the entry is generated again on the next miss.
+func (c *DiskCache) Add(key string, value []byte) {
+	name, ok := entryName(key)
+	if !ok {
+		slog.Warn("invalid cache key", "key", key)
+		return
+	}
+	if err := c.add(name, value); err != nil {
+		slog.Warn("failed to write cache entry", "key", key, "error", err)
+	}
+}
+
+func (c *DiskCache) add(name string, value []byte) error {
+	if err := c.root.MkdirAll(path.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp := path.Join(path.Dir(name), ".tmp-"+rand.Text())
+	if err := c.root.WriteFile(tmp, value, 0o644); err != nil {
+		return err
+	}
+	if err := c.root.Rename(tmp, name); err != nil {
+		_ = c.root.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (c *DiskCache) Close() error {
+	return c.root.Close()
+}
+
+// entryName spreads the entries over subdirectories named by the first two characters of the key.
+func entryName(key string) (string, bool) {
+	if len(key) < 3 || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) || !fs.ValidPath(key) {
+		return "", false
+	}
+	return key[:2] + "/" + key, true
+}
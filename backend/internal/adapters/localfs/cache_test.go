@@ -0,0 +1,38 @@
+package localfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCache(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "covers")
+	c, err := OpenDiskCache(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	_, ok := c.Get("cafe-thumbnail.jpg")
+	assert.False(t, ok)
+
+	c.Add("cafe-thumbnail.jpg", []byte("thumbnail"))
+	data, ok := c.Get("cafe-thumbnail.jpg")
+	require.True(t, ok)
+	assert.Equal(t, "thumbnail", string(data))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "ca"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary files are renamed")
+	assert.Equal(t, "cafe-thumbnail.jpg", entries[0].Name())
+
+	for _, key := range []string{"", "ab", "../secret", "ca/fe", `ca\fe`, ".tmp-x"} {
+		c.Add(key, []byte("x"))
+		_, ok := c.Get(key)
+		assert.False(t, ok, key)
+	}
+}
@@ -0,0 +1,65 @@
+package localfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	"github.com/ARUMANDESU/goread/backend/pkg/fb2x"
+	"github.com/ARUMANDESU/goread/backend/pkg/txtx"
+)
+
+// MaxConvertSize is the size of the largest file Converter reads, FB2 files embed their images so
+// they get big, but not this big.
+const MaxConvertSize = 128 << 20
+
+var ErrFileTooLarge = errors.New("file too large")
+
+// Converter reads the FB2 and text files of the library as publications to write as EPUB.
+type Converter struct {
+	fs fs.FS
+}
+
+func NewConverter(path string) *Converter {
+	return &Converter{fs: os.DirFS(path)}
+}
+
+// Book reads the file at path as a publication, it returns ErrUnsupportedFormat for formats that
+// do not convert to EPUB. Text files have no metadata, the caller sets at least the identifier
+// and title.
+func (c *Converter) Book(_ context.Context, path string) (epubx.Book, error) {
+	const op = errorx.Op("localfs.Converter.Book")
+
+	format := domain.FileFormatOf(path)
+	if !format.ConvertsToEPUB() {
+		return epubx.Book{}, op.Wrap(ErrUnsupportedFormat)
+	}
+
+	f, err := c.fs.Open(path)
+	if err != nil {
+		return epubx.Book{}, op.Wrap(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxConvertSize+1))
+	if err != nil {
+		return epubx.Book{}, op.Wrap(err)
+	}
+	if len(data) > MaxConvertSize {
+		return epubx.Book{}, op.Wrap(ErrFileTooLarge)
+	}
+
+	if format == domain.FormatTXT {
+		return txtx.Book(data), nil
+	}
+	fb, err := fb2x.Parse(bytes.NewReader(data))
+	if err != nil {
+		return epubx.Book{}, op.Wrap(err)
+	}
+	return fb.EPUB(), nil
+}
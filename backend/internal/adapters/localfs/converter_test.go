@@ -0,0 +1,42 @@
+package localfs
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/fb2x"
+)
+
+func TestConverter_Book(t *testing.T) {
+	t.Parallel()
+
+	c := &Converter{fs: fstest.MapFS{
+		"Books/voin.fb2": &fstest.MapFile{Data: []byte(`<?xml version="1.0" encoding="utf-8"?>
+<FictionBook><description><title-info><book-title>Воин</book-title></title-info></description>
+<body><section><title><p>Глава 1</p></title><p>Текст</p></section></body></FictionBook>`)},
+		"Books/voin.txt":   &fstest.MapFile{Data: []byte("Глава 1\n\nТекст\n\nГлава 2\n\nТекст")},
+		"Books/broken.fb2": &fstest.MapFile{Data: []byte("Глава 1")},
+		"Books/voin.epub":  &fstest.MapFile{},
+	}}
+
+	book, err := c.Book(t.Context(), "Books/voin.fb2")
+	require.NoError(t, err)
+	assert.Equal(t, "Воин", book.Title)
+	require.Len(t, book.Chapters, 1)
+	assert.Equal(t, "Глава 1", book.Chapters[0].Title)
+
+	book, err = c.Book(t.Context(), "Books/voin.txt")
+	require.NoError(t, err)
+	assert.Empty(t, book.Title)
+	assert.Len(t, book.Chapters, 2)
+
+	_, err = c.Book(t.Context(), "Books/broken.fb2")
+	require.ErrorIs(t, err, fb2x.ErrNotFictionBook)
+	_, err = c.Book(t.Context(), "Books/voin.epub")
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+	_, err = c.Book(t.Context(), "Books/missing.txt")
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package localfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	"github.com/ARUMANDESU/goread/backend/pkg/mobix"
+)
+
+// CoverExtractor reads the cover images of the files in the library. The cover of an EPUB is
+// the image its package document names, of a Mobipocket file the one its EXTH header names,
+// and of comic archives and PDFs the first page.
+type CoverExtractor struct {
+	fs    fs.FS
+	Pages *PageExtractor
+}
+
+func NewCoverExtractor(path string, pages *PageExtractor) *CoverExtractor {
+	return &CoverExtractor{fs: os.DirFS(path), Pages: pages}
+}
+
+// Cover returns the encoded cover image of the file at path, domain.ErrCoverNotFound if it has none.
+func (e *CoverExtractor) Cover(ctx context.Context, path string) ([]byte, error) {
+	const op = errorx.Op("localfs.CoverExtractor.Cover")
+
+	format := domain.FileFormatOf(path)
+	switch {
+	case format == domain.FormatEPUB:
+		data, err := e.read(path, func(r io.ReaderAt, size int64) ([]byte, error) {
+			data, _, err := epubx.ReadCover(r, size)
+			return data, err
+		})
+		if errors.Is(err, epubx.ErrNoCover) {
+			err = fmt.Errorf("%w: %w", domain.ErrCoverNotFound, err)
+		}
+		return data, op.Wrap(err)
+	case format.IsMobipocket():
+		data, err := e.read(path, func(r io.ReaderAt, size int64) ([]byte, error) { return mobix.Cover(r, size) })
+		if errors.Is(err, mobix.ErrNoCover) {
+			err = fmt.Errorf("%w: %w", domain.ErrCoverNotFound, err)
+		}
+		return data, op.Wrap(err)
+	case format.HasPages() && e.Pages != nil:
+		data, err := e.Pages.Page(ctx, path, 0)
+		if errors.Is(err, domain.ErrPageNotFound) || errors.Is(err, ErrUnsupportedFormat) {
+			err = fmt.Errorf("%w: %w", domain.ErrCoverNotFound, err)
+		}
+		return data, op.Wrap(err)
+	}
+	return nil, op.Wrap(domain.ErrCoverNotFound)
+}
+
+func (e *CoverExtractor) read(path string, cover func(io.ReaderAt, int64) ([]byte, error)) ([]byte, error) {
+	r, size, closer, err := openReaderAt(e.fs, path)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return cover(r, size)
+}
@@ -0,0 +1,61 @@
+package localfs
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+func TestCoverExtractor(t *testing.T) {
+	t.Parallel()
+
+	epub, err := os.ReadFile("../../../pkg/epubx/test-data/The_Dark_Elf.epub")
+	require.NoError(t, err)
+	fsys := fstest.MapFS{
+		"Books/dark elf.epub": &fstest.MapFile{Data: epub},
+		"Books/voin.fb2":      &fstest.MapFile{Data: []byte("<FictionBook/>")},
+		"Comics/plastic.cbz":  &fstest.MapFile{Data: newCBZ(t, map[string]string{"p2.jpg": "page 2", "p1.jpg": "page 1"})},
+		"Comics/empty.cbz":    &fstest.MapFile{Data: newCBZ(t, map[string]string{"ComicInfo.xml": "<ComicInfo/>"})},
+	}
+	e := &CoverExtractor{fs: fsys, Pages: &PageExtractor{fs: fsys}}
+
+	tests := []struct {
+		path          string
+		expectedCover func(t *testing.T, data []byte)
+		expectedErr   error
+	}{
+		{
+			path: "Books/dark elf.epub",
+			expectedCover: func(t *testing.T, data []byte) {
+				assert.Len(t, data, 29541)
+			},
+		},
+		{
+			path: "Comics/plastic.cbz",
+			expectedCover: func(t *testing.T, data []byte) {
+				assert.Equal(t, "page 1", string(data))
+			},
+		},
+		{path: "Comics/empty.cbz", expectedErr: domain.ErrCoverNotFound},
+		{path: "Books/voin.fb2", expectedErr: domain.ErrCoverNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := e.Cover(t.Context(), tt.path)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.expectedCover(t, data)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package localfs
+
+import (
+	"archive/zip"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+// MaxEPUBResourceSize is the size of the largest resource EPUBPool reads, bigger ones are most likely zip bombs.
+const MaxEPUBResourceSize = 64 << 20
+
+var ErrResourceTooLarge = errors.New("resource too large")
+
+// EPUBPool serves resources from inside the EPUBs of the library without unpacking them. It keeps
+// the last used EPUBs open, so the images and styles of a chapter do not reopen and reparse the
+// archive for every request. Like Files it is backed by os.Root.
+type EPUBPool struct {
+	root *os.Root
+	size int
+
+	mu   sync.Mutex
+	lru  *list.List // of *openEPUB, the most recently used first
+	open map[string]*list.Element
+}
+
+type openEPUB struct {
+	path    string
+	modTime time.Time
+	size    int64
+	file    *os.File
+	epub    epubx.EPUB
+	// resources are the zip entries of the manifest items by container path.
+	resources map[string]resource
+
+	refs    int
+	evicted bool
+}
+
+type resource struct {
+	item epubx.ManifestItem
+	file *zip.File
+}
+
+// OpenEPUBPool opens a pool of at most size open EPUBs of the library at path.
+func OpenEPUBPool(path string, size int) (*EPUBPool, error) {
+	const op = errorx.Op("localfs.OpenEPUBPool")
+
+	root, err := os.OpenRoot(path)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return &EPUBPool{root: root, size: max(size, 1), lru: list.New(), open: make(map[string]*list.Element)}, nil
+}
+
+// Resource returns the data and media type of the manifest item at the container path name of the
+// EPUB at path, domain.ErrResourceNotFound if the manifest has no such item. Files that are in the
+// container but not in the manifest, such as META-INF/encryption.xml, are not served. Obfuscated
+// fonts are deobfuscated, resources encrypted by a DRM are domain.ErrDRMProtected.
+func (p *EPUBPool) Resource(_ context.Context, path, name string) ([]byte, string, error) {
+	const op = errorx.Op("localfs.EPUBPool.Resource")
+
+	e, err := p.acquire(path)
+	if err != nil {
+		return nil, "", op.Wrap(err)
+	}
+	defer p.release(e)
+
+	res, ok := e.resources[name]
+	if !ok {
+		return nil, "", op.Wrap(domain.ErrResourceNotFound)
+	}
+	if slices.Contains(e.epub.Encryption.Encrypted, name) {
+		return nil, "", op.Wrap(domain.ErrDRMProtected)
+	}
+	if res.file.UncompressedSize64 > MaxEPUBResourceSize {
+		return nil, "", op.Wrap(ErrResourceTooLarge)
+	}
+	rc, err := res.file.Open()
+	if err != nil {
+		return nil, "", op.Wrap(err)
+	}
+	defer rc.Close()
+
+	// The header may lie about the size.
+	data, err := io.ReadAll(io.LimitReader(rc, MaxEPUBResourceSize+1))
+	if err != nil {
+		return nil, "", op.Wrap(err)
+	}
+	if len(data) > MaxEPUBResourceSize {
+		return nil, "", op.Wrap(ErrResourceTooLarge)
+	}
+	if err := e.epub.Deobfuscate(name, data); err != nil {
+		return nil, "", op.Wrap(err)
+	}
+	return data, res.item.MediaType, nil
+}
+
+// acquire returns the open EPUB at path, opening it if it is not open or was changed since.
+// Callers must release it.
+func (p *EPUBPool) acquire(path string) (*openEPUB, error) {
+	info, err := p.root.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if el, ok := p.open[path]; ok {
+		e := el.Value.(*openEPUB)
+		if e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+			e.refs++
+			p.lru.MoveToFront(el)
+			p.mu.Unlock()
+			return e, nil
+		}
+		p.evict(el)
+	}
+	p.mu.Unlock()
+
+	// Parsing takes a while, others keep using the pool meanwhile.
+	opened, err := openEPUBFile(p.root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if el, ok := p.open[path]; ok {
+		opened.file.Close()
+		e := el.Value.(*openEPUB)
+		e.refs++
+		return e, nil
+	}
+	opened.refs = 1
+	p.open[path] = p.lru.PushFront(opened)
+	for p.lru.Len() > p.size {
+		p.evict(p.lru.Back())
+	}
+	return opened, nil
+}
+
+func (p *EPUBPool) release(e *openEPUB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.refs--
+	if e.evicted && e.refs == 0 {
+		e.file.Close()
+	}
+}
+
+// evict removes el from the pool, its file is closed once the last user released it. p.mu must be held.
+func (p *EPUBPool) evict(el *list.Element) {
+	e := el.Value.(*openEPUB)
+	p.lru.Remove(el)
+	delete(p.open, e.path)
+	e.evicted = true
+	if e.refs == 0 {
+		e.file.Close()
+	}
+}
+
+// Validate checks the EPUB at path against the spec, see epubx.Validate.
+func (p *EPUBPool) Validate(_ context.Context, path string) (epubx.Report, error) {
+	const op = errorx.Op("localfs.EPUBPool.Validate")
+
+	f, err := p.root.Open(path)
+	if err != nil {
+		return epubx.Report{}, op.Wrap(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return epubx.Report{}, op.Wrap(err)
+	}
+	return epubx.Validate(f, info.Size()), nil
+}
+
+func (p *EPUBPool) Close() error {
+	p.mu.Lock()
+	for p.lru.Len() > 0 {
+		p.evict(p.lru.Back())
+	}
+	p.mu.Unlock()
+	return p.root.Close()
+}
+
+func openEPUBFile(root *os.Root, path string) (*openEPUB, error) {
+	f, err := root.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Readers open books that deviate from the spec, so does the web reader.
+	epub, _, err := epubx.ParseEPUBLenient(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, zf := range zr.File {
+		files[zf.Name] = zf
+	}
+	resources := make(map[string]resource, len(epub.Manifest.Items))
+	for _, item := range epub.Manifest.Items {
+		if zf := files[item.Path]; zf != nil {
+			resources[item.Path] = resource{item: item, file: zf}
+		}
+	}
+
+	return &openEPUB{path: path, modTime: info.ModTime(), size: info.Size(), file: f, epub: epub, resources: resources}, nil
+}
@@ -0,0 +1,158 @@
+package localfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+// newEPUB returns an EPUB with its package document at OEBPS/content.opf and a chapter
+// at OEBPS/text/ch1.xhtml with the given content.
+func newEPUB(t *testing.T, chapter string) []byte {
+	t.Helper()
+	files := []struct{ name, data string }{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles>` +
+			`<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`},
+		{"OEBPS/content.opf", `<package xmlns="http://www.idpf.org/2007/opf" version="3.0"><manifest>` +
+			`<item id="ch1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/></manifest></package>`},
+		{"OEBPS/text/ch1.xhtml", chapter},
+		{"OEBPS/secret.txt", "not in the manifest"},
+	}
+
+	return zipFiles(t, files)
+}
+
+// zipFiles returns a zip of the files in order, the mimetype is stored uncompressed.
+func zipFiles(t *testing.T, files []struct{ name, data string }) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		method := zip.Deflate
+		if f.name == "mimetype" {
+			method = zip.Store
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
+		require.NoError(t, err)
+		_, err = w.Write([]byte(f.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestEPUBPool(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, chapter string, modTime time.Time) {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(p, newEPUB(t, chapter), 0o644))
+		require.NoError(t, os.Chtimes(p, modTime, modTime))
+	}
+	before := time.Now().Add(-time.Hour)
+	write("a.epub", "<p>a</p>", before)
+	write("b.epub", "<p>b</p>", before)
+
+	pool, err := OpenEPUBPool(dir, 1)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close() })
+
+	data, mediaType, err := pool.Resource(t.Context(), "a.epub", "OEBPS/text/ch1.xhtml")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>a</p>", string(data))
+	assert.Equal(t, "application/xhtml+xml", mediaType)
+
+	_, _, err = pool.Resource(t.Context(), "a.epub", "OEBPS/secret.txt")
+	require.ErrorIs(t, err, domain.ErrResourceNotFound)
+	_, _, err = pool.Resource(t.Context(), "a.epub", "META-INF/container.xml")
+	require.ErrorIs(t, err, domain.ErrResourceNotFound)
+
+	data, _, err = pool.Resource(t.Context(), "b.epub", "OEBPS/text/ch1.xhtml")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>b</p>", string(data))
+	assert.Len(t, pool.open, 1, "a.epub is evicted")
+
+	// A replaced file is opened again.
+	write("b.epub", "<p>b, edited</p>", time.Now())
+	data, _, err = pool.Resource(t.Context(), "b.epub", "OEBPS/text/ch1.xhtml")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>b, edited</p>", string(data))
+
+	_, _, err = pool.Resource(t.Context(), "../outside.epub", "OEBPS/text/ch1.xhtml")
+	require.Error(t, err)
+}
+
+func TestEPUBPool_Validate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.epub"), newEPUB(t, "<p>a</p>"), 0o644))
+	pool, err := OpenEPUBPool(dir, 1)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close() })
+
+	report, err := pool.Validate(t.Context(), "a.epub")
+	require.NoError(t, err)
+	assert.Equal(t, "3.0", report.Version)
+	assert.Equal(t, []epubx.Issue{{Severity: epubx.SeverityWarning, Err: epubx.ErrMissingNav}}, report.Issues)
+
+	_, err = pool.Validate(t.Context(), "missing.epub")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestEPUBPool_encryption(t *testing.T) {
+	t.Parallel()
+
+	const uid = "urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9"
+	font := []byte(strings.Repeat("OTTO", 300))
+	key := sha1.Sum([]byte(uid))
+	obfuscated := bytes.Clone(font)
+	for i := range 1040 {
+		obfuscated[i] ^= key[i%len(key)]
+	}
+
+	dir := t.TempDir()
+	epub := zipFiles(t, []struct{ name, data string }{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles>` +
+			`<rootfile full-path="content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`},
+		{"META-INF/encryption.xml", `<encryption xmlns="urn:oasis:names:tc:opendocument:xmlns:container" ` +
+			`xmlns:enc="http://www.w3.org/2001/04/xmlenc#" xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` +
+			`<enc:EncryptedData><enc:EncryptionMethod Algorithm="http://www.idpf.org/2008/embedding"/>` +
+			`<enc:CipherData><enc:CipherReference URI="font.otf"/></enc:CipherData></enc:EncryptedData>` +
+			`<enc:EncryptedData><enc:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>` +
+			`<ds:KeyInfo><resource xmlns="http://ns.adobe.com/adept">urn:uuid:1</resource></ds:KeyInfo>` +
+			`<enc:CipherData><enc:CipherReference URI="ch1.xhtml"/></enc:CipherData></enc:EncryptedData></encryption>`},
+		{"content.opf", `<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">` +
+			`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:identifier id="uid">` + uid + `</dc:identifier></metadata>` +
+			`<manifest><item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="font" href="font.otf" media-type="font/otf"/></manifest></package>`},
+		{"ch1.xhtml", "encrypted"},
+		{"font.otf", string(obfuscated)},
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.epub"), epub, 0o644))
+
+	pool, err := OpenEPUBPool(dir, 1)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close() })
+
+	data, _, err := pool.Resource(t.Context(), "a.epub", "font.otf")
+	require.NoError(t, err)
+	assert.Equal(t, font, data)
+
+	_, _, err = pool.Resource(t.Context(), "a.epub", "ch1.xhtml")
+	require.ErrorIs(t, err, domain.ErrDRMProtected)
+}
@@ -0,0 +1,84 @@
+package localfs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+// Files opens files of the library for downloads and rewrites the metadata of EPUBs. It is backed
+// by os.Root, so no path, not even one through a symlink, can reach a file outside of the library.
+type Files struct {
+	root *os.Root
+}
+
+func OpenFiles(path string) (*Files, error) {
+	const op = errorx.Op("localfs.OpenFiles")
+
+	root, err := os.OpenRoot(path)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return &Files{root: root}, nil
+}
+
+// Open opens the file at the slash separated path relative to the library root.
+func (f *Files) Open(_ context.Context, path string) (fs.File, error) {
+	const op = errorx.Op("localfs.Files.Open")
+
+	file, err := f.root.Open(path)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return file, nil
+}
+
+// WriteEPUBMetadata applies u to the EPUB at p and returns the SHA-256 of the new file, the hash
+// Scanner.Snapshot computes. The new file is written next to the old one and renamed over it, so
+// readers see either the old or the new file and a failure leaves the old one as it was.
+func (f *Files) WriteEPUBMetadata(_ context.Context, p string, u epubx.MetadataUpdate) ([]byte, error) {
+	const op = errorx.Op("localfs.Files.WriteEPUBMetadata")
+
+	src, err := f.root.Open(p)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	tmp := path.Join(path.Dir(p), ".tmp-"+rand.Text())
+	dst, err := f.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	h := sha256.New()
+	err = epubx.WriteMetadata(io.MultiWriter(dst, h), src, info.Size(), u)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = f.root.Rename(tmp, p)
+	}
+	if err != nil {
+		_ = f.root.Remove(tmp)
+		return nil, op.Wrap(err)
+	}
+	return h.Sum(nil), nil
+}
+
+func (f *Files) Close() error {
+	return f.root.Close()
+}
@@ -0,0 +1,89 @@
+package localfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+func TestFiles_Open(t *testing.T) {
+	t.Parallel()
+
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600))
+
+	library := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(library, "Books", "Воин"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(library, "Books", "Воин", "book.epub"), []byte("epub"), 0o600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(library, "escape")))
+
+	files, err := OpenFiles(library)
+	require.NoError(t, err)
+	t.Cleanup(func() { files.Close() })
+
+	f, err := files.Open(t.Context(), "Books/Воин/book.epub")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "epub", string(data))
+	require.NoError(t, f.Close())
+
+	for _, path := range []string{
+		"../" + filepath.Base(outside) + "/secret.txt",
+		filepath.Join(outside, "secret.txt"),
+		"escape/secret.txt",
+		"Books/../../secret.txt",
+	} {
+		_, err := files.Open(t.Context(), path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestFiles_WriteEPUBMetadata(t *testing.T) {
+	t.Parallel()
+
+	epub, err := os.ReadFile("../../../pkg/epubx/test-data/The_Dark_Elf.epub")
+	require.NoError(t, err)
+	library := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(library, "Books"), 0o755))
+	name := filepath.Join(library, "Books", "book.epub")
+	require.NoError(t, os.WriteFile(name, epub, 0o640))
+	require.NoError(t, os.WriteFile(filepath.Join(library, "Books", "broken.epub"), []byte("not a zip"), 0o640))
+
+	files, err := OpenFiles(library)
+	require.NoError(t, err)
+	t.Cleanup(func() { files.Close() })
+
+	title := "Homeland"
+	hash, err := files.WriteEPUBMetadata(t.Context(), "Books/book.epub", epubx.MetadataUpdate{Title: &title})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, sum[:], hash)
+	got, err := epubx.ParseEPUB(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Homeland"}, got.Metadata.Titles)
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	_, err = files.WriteEPUBMetadata(t.Context(), "Books/broken.epub", epubx.MetadataUpdate{Title: &title})
+	assert.Error(t, err)
+	data, err = os.ReadFile(filepath.Join(library, "Books", "broken.epub"))
+	require.NoError(t, err)
+	assert.Equal(t, "not a zip", string(data), "a failed write leaves the file as it was")
+
+	entries, err := os.ReadDir(filepath.Join(library, "Books"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no temporary files are left behind")
+}
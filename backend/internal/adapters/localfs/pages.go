@@ -0,0 +1,267 @@
+package localfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/nwaples/rardecode"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// PDFRenderer renders pages of PDF files, file is a path in the OS.
+type PDFRenderer interface {
+	PageCount(ctx context.Context, file string) (int, error)
+	// Page renders the 0-based page n as an image.
+	Page(ctx context.Context, file string, n int) ([]byte, error)
+}
+
+// PageExtractor reads the pages of comic archives in the library, and of PDFs if PDF is set.
+// Pages of an archive are its images in natural order of their names, so page10 follows page9.
+type PageExtractor struct {
+	root string
+	fs   fs.FS
+	PDF  PDFRenderer
+}
+
+func NewPageExtractor(path string) *PageExtractor {
+	return &PageExtractor{root: path, fs: os.DirFS(path)}
+}
+
+// PageCount returns the number of pages of the file at path, it returns ErrUnsupportedFormat
+// for formats without pages.
+func (e *PageExtractor) PageCount(ctx context.Context, path string) (int, error) {
+	const op = errorx.Op("localfs.PageExtractor.PageCount")
+
+	switch domain.FileFormatOf(path) {
+	case domain.FormatCBZ:
+		zr, closer, err := e.openZip(path)
+		if err != nil {
+			return 0, op.Wrap(err)
+		}
+		defer closer.Close()
+		return len(zipPages(zr)), nil
+	case domain.FormatCBR:
+		names, err := e.rarPages(path)
+		return len(names), op.Wrap(err)
+	case domain.FormatPDF:
+		if e.PDF == nil {
+			return 0, op.Wrap(ErrUnsupportedFormat)
+		}
+		n, err := e.PDF.PageCount(ctx, e.osPath(path))
+		return n, op.Wrap(err)
+	}
+	return 0, op.Wrap(ErrUnsupportedFormat)
+}
+
+// Page returns the encoded image of the 0-based page n of the file at path,
+// domain.ErrPageNotFound if there is no such page.
+func (e *PageExtractor) Page(ctx context.Context, path string, n int) ([]byte, error) {
+	const op = errorx.Op("localfs.PageExtractor.Page")
+
+	if n < 0 {
+		return nil, op.Wrap(domain.ErrPageNotFound)
+	}
+
+	switch domain.FileFormatOf(path) {
+	case domain.FormatCBZ:
+		zr, closer, err := e.openZip(path)
+		if err != nil {
+			return nil, op.Wrap(err)
+		}
+		defer closer.Close()
+
+		pages := zipPages(zr)
+		if n >= len(pages) {
+			return nil, op.Wrap(domain.ErrPageNotFound)
+		}
+		rc, err := pages[n].Open()
+		if err != nil {
+			return nil, op.Wrap(err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		return data, op.Wrap(err)
+	case domain.FormatCBR:
+		data, err := e.rarPage(path, n)
+		return data, op.Wrap(err)
+	case domain.FormatPDF:
+		if e.PDF == nil {
+			return nil, op.Wrap(ErrUnsupportedFormat)
+		}
+		data, err := e.PDF.Page(ctx, e.osPath(path), n)
+		return data, op.Wrap(err)
+	}
+	return nil, op.Wrap(ErrUnsupportedFormat)
+}
+
+func (e *PageExtractor) osPath(p string) string {
+	return filepath.Join(e.root, filepath.FromSlash(p))
+}
+
+func (e *PageExtractor) openZip(p string) (*zip.Reader, io.Closer, error) {
+	r, size, f, err := openReaderAt(e.fs, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to read zip(%s): %w", p, err)
+	}
+	return zr, f, nil
+}
+
+// openReaderAt opens the file at p for random access, files of an fs.FS that cannot seek are read into memory.
+func openReaderAt(fsys fs.FS, p string) (io.ReaderAt, int64, io.Closer, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, nil, err
+	}
+
+	r, ok := f.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			f.Close()
+			return nil, 0, nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	return r, info.Size(), f, nil
+}
+
+func zipPages(zr *zip.Reader) []*zip.File {
+	var pages []*zip.File
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() && isPageName(f.Name) {
+			pages = append(pages, f)
+		}
+	}
+	slices.SortFunc(pages, func(a, b *zip.File) int { return naturalCompare(a.Name, b.Name) })
+	return pages
+}
+
+// rarPages returns the sorted names of the pages in the rar archive at p.
+func (e *PageExtractor) rarPages(p string) ([]string, error) {
+	f, err := e.fs.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rr, err := rardecode.NewReader(f, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rar(%s): %w", p, err)
+	}
+
+	var names []string
+	for {
+		h, err := rr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read rar(%s): %w", p, err)
+		}
+		if !h.IsDir && isPageName(h.Name) {
+			names = append(names, h.Name)
+		}
+	}
+	slices.SortFunc(names, naturalCompare)
+	return names, nil
+}
+
+// rarPage reads the archive twice, rar files can only be read sequentially and the page
+// order is only known after all names were seen.
+func (e *PageExtractor) rarPage(p string, n int) ([]byte, error) {
+	names, err := e.rarPages(p)
+	if err != nil {
+		return nil, err
+	}
+	if n >= len(names) {
+		return nil, domain.ErrPageNotFound
+	}
+
+	f, err := e.fs.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rr, err := rardecode.NewReader(f, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rar(%s): %w", p, err)
+	}
+	for {
+		h, err := rr.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rar(%s): %w", p, err)
+		}
+		if h.Name == names[n] {
+			return io.ReadAll(rr)
+		}
+	}
+}
+
+var pageExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+
+// isPageName reports whether an archive entry is a page image, macOS metadata and hidden files are not.
+func isPageName(name string) bool {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".") {
+		return false
+	}
+	return slices.Contains(pageExtensions, strings.ToLower(path.Ext(name)))
+}
+
+// naturalCompare compares strings case-insensitively with runs of digits compared by value.
+func naturalCompare(a, b string) int {
+	ar, br := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ar) && j < len(br) {
+		if unicode.IsDigit(ar[i]) && unicode.IsDigit(br[j]) {
+			si, sj := i, j
+			for i < len(ar) && unicode.IsDigit(ar[i]) {
+				i++
+			}
+			for j < len(br) && unicode.IsDigit(br[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ar[si:i]), "0")
+			nb := strings.TrimLeft(string(br[sj:j]), "0")
+			if c := len(na) - len(nb); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		if ar[i] != br[j] {
+			return int(ar[i]) - int(br[j])
+		}
+		i++
+		j++
+	}
+	return (len(ar) - i) - (len(br) - j)
+}
@@ -0,0 +1,105 @@
+package localfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type stubPDFRenderer struct{ file string }
+
+func (r *stubPDFRenderer) PageCount(_ context.Context, file string) (int, error) {
+	r.file = file
+	return 3, nil
+}
+
+func (r *stubPDFRenderer) Page(_ context.Context, file string, n int) ([]byte, error) {
+	r.file = file
+	return []byte{byte(n)}, nil
+}
+
+func newCBZ(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestPageExtractor_CBZ(t *testing.T) {
+	t.Parallel()
+
+	cbz := newCBZ(t, map[string]string{
+		"ComicInfo.xml":       "<ComicInfo/>",
+		"Plastic Man/p10.jpg": "page 10",
+		"Plastic Man/p2.JPG":  "page 2",
+		"Plastic Man/p1.png":  "page 1",
+		"__MACOSX/._p1.png":   "resource fork",
+		"Plastic Man/.cover":  "hidden",
+	})
+	e := &PageExtractor{fs: fstest.MapFS{"Comics/plastic.cbz": &fstest.MapFile{Data: cbz}}}
+
+	count, err := e.PageCount(t.Context(), "Comics/plastic.cbz")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	for n, expected := range []string{"page 1", "page 2", "page 10"} {
+		data, err := e.Page(t.Context(), "Comics/plastic.cbz", n)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+	}
+
+	_, err = e.Page(t.Context(), "Comics/plastic.cbz", 3)
+	require.ErrorIs(t, err, domain.ErrPageNotFound)
+	_, err = e.Page(t.Context(), "Comics/plastic.cbz", -1)
+	require.ErrorIs(t, err, domain.ErrPageNotFound)
+}
+
+func TestPageExtractor_PDF(t *testing.T) {
+	t.Parallel()
+
+	renderer := &stubPDFRenderer{}
+	e := &PageExtractor{root: "/library", fs: fstest.MapFS{}, PDF: renderer}
+
+	count, err := e.PageCount(t.Context(), "Manga/aot.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	data, err := e.Page(t.Context(), "Manga/aot.pdf", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{2}, data)
+	assert.Equal(t, "/library/Manga/aot.pdf", renderer.file)
+}
+
+func TestPageExtractor_unsupported(t *testing.T) {
+	t.Parallel()
+
+	e := &PageExtractor{fs: fstest.MapFS{"book.epub": &fstest.MapFile{}}}
+
+	_, err := e.PageCount(t.Context(), "book.epub")
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+	_, err = e.Page(t.Context(), "manga.pdf", 0)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestNaturalCompare(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"p10.jpg", "P2.jpg", "cover.jpg", "p2a.jpg", "p001.jpg", "v2/p1.jpg"}
+	slices.SortFunc(names, naturalCompare)
+	assert.Equal(t, []string{"cover.jpg", "p001.jpg", "P2.jpg", "p2a.jpg", "p10.jpg", "v2/p1.jpg"}, names)
+}
@@ -1,14 +1,17 @@
 package localfs
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 
 	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
 	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	"github.com/ARUMANDESU/goread/backend/pkg/koreaderx"
 )
 
 type Scanner struct {
@@ -53,3 +56,31 @@ func (s Scanner) Snapshot(ctx context.Context) (vo.LibrarySnapshot, error) {
 
 	return m, op.Wrap(errs.Filter())
 }
+
+// PartialMD5 returns the KOReader document hash of the file at path.
+func (s Scanner) PartialMD5(_ context.Context, path string) (string, error) {
+	const op = errorx.Op("localfs.Scanner.PartialMD5")
+
+	f, err := s.fs.Open(path)
+	if err != nil {
+		return "", op.Wrap(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", op.Wrap(err)
+	}
+
+	r, ok := f.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return "", op.Wrap(err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	hash, err := koreaderx.PartialMD5(r, info.Size())
+	return hash, op.Wrap(err)
+}
@@ -1,7 +1,9 @@
 package localfs
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"io/fs"
 	"testing"
 	"testing/fstest"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
+	"github.com/ARUMANDESU/goread/backend/pkg/koreaderx"
 )
 
 func TestScanner_ScanDir(t *testing.T) {
@@ -102,6 +105,25 @@ func TestScanner_ScanDir(t *testing.T) {
 	}
 }
 
+func TestScanner_PartialMD5(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 5000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	s := Scanner{fs: fstest.MapFS{"books/book.epub": &fstest.MapFile{Data: data}}}
+
+	got, err := s.PartialMD5(t.Context(), "books/book.epub")
+	require.NoError(t, err)
+	expected, err := koreaderx.PartialMD5(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	_, err = s.PartialMD5(t.Context(), "books/missing.epub")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
 func getDataHash(data []byte) []byte {
 	h := sha256.New()
 	_, _ = h.Write(data)
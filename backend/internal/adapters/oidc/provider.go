@@ -0,0 +1,128 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+const DefaultGroupsClaim = "groups"
+
+var (
+	ErrMissingIDToken = errors.New("token response has no id_token")
+	ErrNonceMismatch  = errors.New("id token nonce does not match")
+)
+
+type Config struct {
+	// IssuerURL is used for discovery, e.g. https://auth.example.com for Authelia.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is goread's callback, e.g. https://goread.example.com/api/v1/auth/oidc/callback.
+	RedirectURL string
+	// Scopes are requested in addition to openid, profile, email and groups if empty.
+	Scopes []string
+	// GroupsClaim is the ID token claim holding the user's groups, DefaultGroupsClaim if empty.
+	GroupsClaim string
+}
+
+// Provider is an OpenID Connect relying party using the authorization code flow with PKCE.
+type Provider struct {
+	oauth       oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	groupsClaim string
+}
+
+// NewProvider discovers the provider's endpoints and keys at cfg.IssuerURL.
+func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
+	const op = errorx.Op("oidc.NewProvider")
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email", "groups"}
+	}
+	groupsClaim := cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = DefaultGroupsClaim
+	}
+
+	return &Provider{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		groupsClaim: groupsClaim,
+	}, nil
+}
+
+func (p *Provider) AuthCodeURL(state, verifier, nonce string) string {
+	return p.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce))
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (vo.SSOClaims, error) {
+	const op = errorx.Op("oidc.Provider.Exchange")
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return vo.SSOClaims{}, op.Wrap(err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return vo.SSOClaims{}, op.Wrap(ErrMissingIDToken)
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return vo.SSOClaims{}, op.Wrap(err)
+	}
+	if idToken.Nonce != nonce {
+		return vo.SSOClaims{}, op.Wrap(ErrNonceMismatch)
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return vo.SSOClaims{}, op.Wrap(err)
+	}
+
+	res := vo.SSOClaims{
+		Issuer:   idToken.Issuer,
+		Subject:  idToken.Subject,
+		Username: stringClaim(claims, "preferred_username"),
+		Email:    stringClaim(claims, "email"),
+	}
+	switch groups := claims[p.groupsClaim].(type) {
+	case []any:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				res.Groups = append(res.Groups, s)
+			}
+		}
+	case string:
+		res.Groups = []string{groups}
+	case nil:
+	default:
+		return vo.SSOClaims{}, op.Wrap(fmt.Errorf("unexpected %s claim type %T", p.groupsClaim, groups))
+	}
+
+	return res, nil
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
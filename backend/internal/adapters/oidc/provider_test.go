@@ -0,0 +1,171 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
+)
+
+// stubProvider is a minimal OpenID provider: discovery, JWKS and a token endpoint that checks PKCE.
+type stubProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	groups any
+
+	mu    sync.Mutex
+	codes map[string]stubAuthorization
+}
+
+type stubAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &stubProvider{key: key, codes: map[string]stubAuthorization{}, groups: []string{"goread-admins", "family"}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "k1", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("POST /token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize plays the user approving the login, it returns the code the provider redirects back with.
+func (p *stubProvider) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.codes["code-1"] = stubAuthorization{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return "code-1"
+}
+
+func (p *stubProvider) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	p.mu.Lock()
+	authz, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authz.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	signer, _ := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key}, (&jose.SignerOptions{}).WithHeader("kid", "k1"))
+	idToken, _ := jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:   p.URL,
+		Subject:  "user-42",
+		Audience: jwt.Audience{"goread"},
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}).Claims(map[string]any{
+		"nonce":              authz.nonce,
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"groups":             p.groups,
+	}).Serialize()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func TestProvider_codeFlow(t *testing.T) {
+	t.Parallel()
+
+	stub := newStubProvider(t)
+	p, err := NewProvider(t.Context(), Config{
+		IssuerURL:   stub.URL,
+		ClientID:    "goread",
+		RedirectURL: "https://goread.example.com/callback",
+	})
+	require.NoError(t, err)
+
+	const verifier = "0123456789012345678901234567890123456789abc"
+	code := stub.authorize(t, p.AuthCodeURL("state", verifier, "nonce-1"))
+
+	claims, err := p.Exchange(t.Context(), code, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, vo.SSOClaims{
+		Issuer:   stub.URL,
+		Subject:  "user-42",
+		Username: "alice",
+		Email:    "alice@example.com",
+		Groups:   []string{"goread-admins", "family"},
+	}, claims)
+}
+
+func TestProvider_Exchange_errors(t *testing.T) {
+	t.Parallel()
+
+	stub := newStubProvider(t)
+	p, err := NewProvider(t.Context(), Config{IssuerURL: stub.URL, ClientID: "goread"})
+	require.NoError(t, err)
+
+	const verifier = "0123456789012345678901234567890123456789abc"
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code := stub.authorize(t, p.AuthCodeURL("state", verifier, "nonce-1"))
+		_, err := p.Exchange(t.Context(), code, verifier+"x", "nonce-1")
+		require.Error(t, err)
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		code := stub.authorize(t, p.AuthCodeURL("state", verifier, "nonce-1"))
+		_, err := p.Exchange(t.Context(), code, verifier, "nonce-2")
+		require.ErrorIs(t, err, ErrNonceMismatch)
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		other, err := NewProvider(t.Context(), Config{IssuerURL: stub.URL, ClientID: "someone-else"})
+		require.NoError(t, err)
+		code := stub.authorize(t, other.AuthCodeURL("state", verifier, "nonce-1"))
+		_, err = other.Exchange(t.Context(), code, verifier, "nonce-1")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,83 @@
+// Package poppler renders PDF pages with pdfinfo and pdftoppm from poppler-utils,
+// which have to be installed on the host.
+package poppler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+const DefaultResolution = 150
+
+var ErrNoPageCount = errors.New("pdfinfo printed no page count")
+
+type Renderer struct {
+	// Resolution is the DPI pages are rendered at, DefaultResolution if zero.
+	Resolution int
+	// PdfinfoPath and PdftoppmPath are looked up in PATH if empty.
+	PdfinfoPath  string
+	PdftoppmPath string
+}
+
+func (r Renderer) PageCount(ctx context.Context, file string) (int, error) {
+	const op = errorx.Op("poppler.Renderer.PageCount")
+
+	out, err := run(ctx, orDefault(r.PdfinfoPath, "pdfinfo"), file)
+	if err != nil {
+		return 0, op.Wrap(err)
+	}
+	n, err := parsePageCount(out)
+	return n, op.Wrap(err)
+}
+
+// Page renders the 0-based page n as a JPEG.
+func (r Renderer) Page(ctx context.Context, file string, n int) ([]byte, error) {
+	const op = errorx.Op("poppler.Renderer.Page")
+
+	page := strconv.Itoa(n + 1)
+	resolution := r.Resolution
+	if resolution <= 0 {
+		resolution = DefaultResolution
+	}
+
+	// Without an output root pdftoppm writes the single page to stdout.
+	out, err := run(ctx, orDefault(r.PdftoppmPath, "pdftoppm"),
+		"-f", page, "-l", page, "-singlefile", "-jpeg", "-r", strconv.Itoa(resolution), file)
+	return out, op.Wrap(err)
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// parsePageCount reads the "Pages:" line of pdfinfo output.
+func parsePageCount(out []byte) (int, error) {
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if value, ok := strings.CutPrefix(sc.Text(), "Pages:"); ok {
+			return strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	return 0, ErrNoPageCount
+}
+
+func orDefault(path, name string) string {
+	if path != "" {
+		return path
+	}
+	return name
+}
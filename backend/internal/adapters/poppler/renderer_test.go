@@ -0,0 +1,52 @@
+package poppler
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePageCount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		out         string
+		expected    int
+		expectedErr error
+	}{
+		{
+			name:     "pdfinfo output",
+			out:      "Title:          Attack on Titan #001\nProducer:       pdfTeX\nPages:          52\nEncrypted:      no\n",
+			expected: 52,
+		},
+		{name: "no pages line", out: "Title: x\n", expectedErr: ErrNoPageCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parsePageCount([]byte(tt.out))
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestRenderer_missingFile(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("pdfinfo"); err != nil {
+		t.Skip("poppler-utils are not installed")
+	}
+
+	_, err := Renderer{}.PageCount(t.Context(), "testdata/missing.pdf")
+	require.Error(t, err)
+}
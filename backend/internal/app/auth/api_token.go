@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+var ErrInvalidAPIToken = fmt.Errorf("invalid api token: %w", domain.ErrUnauthorized)
+
+type APITokenRepo interface {
+	CreateAPIToken(context.Context, *domain.APIToken) error
+	// GetAPIToken returns domain.ErrAPITokenNotFound if there is no such token.
+	GetAPIToken(context.Context, domain.APITokenID) (*domain.APIToken, error)
+	// GetAPITokenByHash returns domain.ErrAPITokenNotFound if there is no such token.
+	GetAPITokenByHash(context.Context, []byte) (*domain.APIToken, error)
+	// ListUserAPITokens returns every token of the user, including revoked and expired ones, newest first.
+	ListUserAPITokens(context.Context, domain.UserID) ([]*domain.APIToken, error)
+	UpdateAPIToken(context.Context, *domain.APIToken) error
+}
+
+type CreateAPITokenCmd struct {
+	Name   string
+	Scopes []domain.Scope
+	// ExpiresAt is optional, tokens without it stay valid until revoked.
+	ExpiresAt *time.Time
+}
+
+type CreateAPITokenResult struct {
+	Token *domain.APIToken
+	// Secret is shown to the user once, only its hash is stored.
+	Secret string
+}
+
+// CreateAPIToken creates a token of the user in ctx. Only users whose role grants
+// domain.PermManageUsers can create tokens with domain.ScopeAdmin.
+func (a *App) CreateAPIToken(ctx context.Context, cmd CreateAPITokenCmd) (CreateAPITokenResult, error) {
+	const op = errorx.Op("auth.App.CreateAPIToken")
+
+	user, err := Authenticated(ctx, domain.ScopeAdmin)
+	if err != nil {
+		return CreateAPITokenResult{}, op.Wrap(err)
+	}
+	for _, s := range cmd.Scopes {
+		if s == domain.ScopeAdmin && !user.Can(domain.PermManageUsers) {
+			return CreateAPITokenResult{}, op.Wrap(fmt.Errorf("%w: scope %s", domain.ErrPermissionDenied, s))
+		}
+	}
+
+	token, secret, err := domain.NewAPIToken(user.ID(), cmd.Name, cmd.Scopes, cmd.ExpiresAt)
+	if err != nil {
+		return CreateAPITokenResult{}, op.Wrap(err)
+	}
+	if err := a.APITokenRepo.CreateAPIToken(ctx, token); err != nil {
+		return CreateAPITokenResult{}, op.Wrap(err)
+	}
+
+	return CreateAPITokenResult{Token: token, Secret: secret}, nil
+}
+
+// ListAPITokens returns the tokens of the user in ctx.
+func (a *App) ListAPITokens(ctx context.Context) ([]*domain.APIToken, error) {
+	const op = errorx.Op("auth.App.ListAPITokens")
+
+	user, err := Authenticated(ctx, domain.ScopeAdmin)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	tokens, err := a.APITokenRepo.ListUserAPITokens(ctx, user.ID())
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes a token of the user in ctx, users with domain.PermManageUsers can revoke
+// any token. Tokens of other users look missing to everyone else.
+func (a *App) RevokeAPIToken(ctx context.Context, id domain.APITokenID) error {
+	const op = errorx.Op("auth.App.RevokeAPIToken")
+
+	user, err := Authenticated(ctx, domain.ScopeAdmin)
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	return op.Wrap(a.Session.Transaction(ctx, func(ctx context.Context) error {
+		token, err := a.APITokenRepo.GetAPIToken(ctx, id)
+		if err != nil {
+			return err
+		}
+		if token.UserID() != user.ID() && !user.Can(domain.PermManageUsers) {
+			return domain.ErrAPITokenNotFound
+		}
+		token.Revoke()
+		return a.APITokenRepo.UpdateAPIToken(ctx, token)
+	}))
+}
+
+// AuthenticateAPIToken returns the owner of an active token. Tokens are recognized by
+// domain.APITokenPrefix, anything else is rejected without a lookup.
+func (a *App) AuthenticateAPIToken(ctx context.Context, secret string) (*domain.User, *domain.APIToken, error) {
+	const op = errorx.Op("auth.App.AuthenticateAPIToken")
+
+	if !strings.HasPrefix(secret, domain.APITokenPrefix) {
+		return nil, nil, op.Wrap(ErrInvalidAPIToken)
+	}
+
+	token, err := a.APITokenRepo.GetAPITokenByHash(ctx, domain.HashToken(secret))
+	if errors.Is(err, domain.ErrAPITokenNotFound) {
+		return nil, nil, op.Wrap(ErrInvalidAPIToken)
+	} else if err != nil {
+		return nil, nil, op.Wrap(err)
+	}
+	if !token.IsActive() {
+		return nil, nil, op.Wrap(ErrInvalidAPIToken)
+	}
+
+	user, err := a.UserRepo.GetUserByID(ctx, token.UserID())
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, nil, op.Wrap(ErrInvalidAPIToken)
+	} else if err != nil {
+		return nil, nil, op.Wrap(err)
+	}
+
+	if token.Touch(time.Now()) {
+		if err := a.APITokenRepo.UpdateAPIToken(ctx, token); err != nil {
+			return nil, nil, op.Wrap(err)
+		}
+	}
+
+	return user, token, nil
+}
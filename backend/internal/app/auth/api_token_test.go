@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+func withToken(ctx context.Context, user *domain.User, scopes ...domain.Scope) context.Context {
+	token := domain.NewAPITokenBuilder().UserID(user.ID()).Scopes(scopes).Build()
+	return WithAPIToken(WithUser(ctx, user), &token)
+}
+
+func TestApp_CreateAPIToken(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		role        domain.Role
+		ctx         func(context.Context, *domain.User) context.Context
+		scopes      []domain.Scope
+		expectedErr error
+	}{
+		{name: "member reads and syncs", role: domain.RoleMember, scopes: []domain.Scope{domain.ScopeReadLibrary, domain.ScopeWriteProgress}},
+		{name: "admin scope by admin", role: domain.RoleAdmin, scopes: []domain.Scope{domain.ScopeAdmin}},
+		{name: "admin scope by member", role: domain.RoleMember, scopes: []domain.Scope{domain.ScopeAdmin}, expectedErr: domain.ErrPermissionDenied},
+		{
+			name: "token without admin scope",
+			role: domain.RoleAdmin,
+			ctx: func(ctx context.Context, u *domain.User) context.Context {
+				return withToken(ctx, u, domain.ScopeReadLibrary)
+			},
+			scopes: []domain.Scope{domain.ScopeReadLibrary}, expectedErr: domain.ErrInsufficientScope,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _, _ := newTestApp(t)
+			tr := app.APITokenRepo.(*mockAPITokenRepo)
+			tr.On("CreateAPIToken", mock.Anything, mock.Anything).Return(nil)
+
+			user := mustNewUserWithRole(t, tt.role)
+			ctx := WithUser(t.Context(), user)
+			if tt.ctx != nil {
+				ctx = tt.ctx(t.Context(), user)
+			}
+
+			res, err := app.CreateAPIToken(ctx, CreateAPITokenCmd{Name: "script", Scopes: tt.scopes})
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				tr.AssertNotCalled(t, "CreateAPIToken", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, user.ID(), res.Token.UserID())
+			assert.Equal(t, domain.HashToken(res.Secret), res.Token.TokenHash())
+			tr.AssertCalled(t, "CreateAPIToken", mock.Anything, res.Token)
+		})
+	}
+}
+
+func TestApp_RevokeAPIToken(t *testing.T) {
+	t.Parallel()
+
+	owner := mustNewUser(t)
+	stranger := mustNewUser(t)
+	admin := mustNewUserWithRole(t, domain.RoleAdmin)
+
+	tests := []struct {
+		name        string
+		actor       *domain.User
+		expectedErr error
+	}{
+		{name: "owner", actor: owner},
+		{name: "admin", actor: admin},
+		{name: "someone else", actor: stranger, expectedErr: domain.ErrAPITokenNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _, _ := newTestApp(t)
+			tr := app.APITokenRepo.(*mockAPITokenRepo)
+			token := ptr(domain.NewAPITokenBuilder().Id(domain.NewAPITokenID()).UserID(owner.ID()).Build())
+			tr.On("GetAPIToken", mock.Anything, token.ID()).Return(token, nil)
+			tr.On("UpdateAPIToken", mock.Anything, token).Return(nil)
+
+			err := app.RevokeAPIToken(WithUser(t.Context(), tt.actor), token.ID())
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.True(t, token.IsActive())
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, token.IsActive())
+		})
+	}
+}
+
+func TestApp_AuthenticateAPIToken(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	past := time.Now().Add(-time.Minute)
+	secret := domain.APITokenPrefix + "secret"
+
+	tests := []struct {
+		name        string
+		secret      string
+		token       *domain.APIToken
+		tokenErr    error
+		expectTouch bool
+		expectedErr error
+	}{
+		{
+			name:        "active",
+			secret:      secret,
+			token:       ptr(domain.NewAPITokenBuilder().UserID(user.ID()).Build()),
+			expectTouch: true,
+		},
+		{
+			name:   "used recently",
+			secret: secret,
+			token:  ptr(domain.NewAPITokenBuilder().UserID(user.ID()).LastUsedAt(ptr(time.Now())).Build()),
+		},
+		{name: "unknown", secret: secret, tokenErr: domain.ErrAPITokenNotFound, expectedErr: ErrInvalidAPIToken},
+		{name: "revoked", secret: secret, token: ptr(domain.NewAPITokenBuilder().UserID(user.ID()).RevokedAt(&past).Build()), expectedErr: ErrInvalidAPIToken},
+		{name: "expired", secret: secret, token: ptr(domain.NewAPITokenBuilder().UserID(user.ID()).ExpiresAt(&past).Build()), expectedErr: ErrInvalidAPIToken},
+		{name: "not a token", secret: "session-token", expectedErr: ErrInvalidAPIToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ur, _ := newTestApp(t)
+			tr := app.APITokenRepo.(*mockAPITokenRepo)
+			tr.On("GetAPITokenByHash", mock.Anything, domain.HashToken(secret)).Return(tt.token, tt.tokenErr)
+			tr.On("UpdateAPIToken", mock.Anything, mock.Anything).Return(nil)
+			ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+
+			got, token, err := app.AuthenticateAPIToken(t.Context(), tt.secret)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.ErrorIs(t, err, domain.ErrUnauthorized)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, user, got)
+			assert.Equal(t, tt.token, token)
+			if tt.expectTouch {
+				tr.AssertCalled(t, "UpdateAPIToken", mock.Anything, tt.token)
+			} else {
+				tr.AssertNotCalled(t, "UpdateAPIToken", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAuthorize_scopes(t *testing.T) {
+	t.Parallel()
+
+	admin := mustNewUserWithRole(t, domain.RoleAdmin)
+
+	_, err := Authorize(withToken(t.Context(), admin, domain.ScopeReadLibrary), domain.PermTriggerScan)
+	require.ErrorIs(t, err, domain.ErrInsufficientScope)
+
+	_, err = Authorize(withToken(t.Context(), admin, domain.ScopeReadLibrary), domain.PermDownload)
+	require.NoError(t, err)
+
+	_, err = Authorize(withToken(t.Context(), admin, domain.ScopeAdmin), domain.PermTriggerScan)
+	require.NoError(t, err)
+
+	_, err = Authorize(WithUser(t.Context(), admin), domain.PermTriggerScan)
+	require.NoError(t, err, "sessions are not limited by scopes")
+}
@@ -1 +1,292 @@
 package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+var (
+	ErrAlreadyBootstrapped  = fmt.Errorf("users already exist: %w", domain.ErrConflict)
+	ErrRegistrationDisabled = fmt.Errorf("registration is disabled: %w", domain.ErrForbidden)
+	ErrUserNameTaken        = fmt.Errorf("user name is taken: %w", domain.ErrConflict)
+	ErrInvalidCredentials   = fmt.Errorf("invalid credentials: %w", domain.ErrUnauthorized)
+	ErrNotAuthenticated     = fmt.Errorf("not authenticated: %w", domain.ErrUnauthorized)
+	ErrLoginLocked          = fmt.Errorf("too many failed login attempts: %w", domain.ErrRateLimited)
+	ErrChangeOwnRole        = fmt.Errorf("users can not change their own role: %w", domain.ErrForbidden)
+)
+
+type UserRepo interface {
+	CountUsers(context.Context) (int, error)
+	CreateUser(context.Context, *domain.User) error
+	// GetUserByID returns domain.ErrUserNotFound if there is no such user.
+	GetUserByID(context.Context, domain.UserID) (*domain.User, error)
+	// GetUserByName returns domain.ErrUserNotFound if there is no such user.
+	GetUserByName(context.Context, string) (*domain.User, error)
+	// ListUsers returns all users ordered by name.
+	ListUsers(context.Context) ([]*domain.User, error)
+	UpdateUser(context.Context, *domain.User) error
+}
+
+type SessionRepo interface {
+	CreateSession(context.Context, *domain.Session) error
+	// GetSessionByTokenHash returns domain.ErrSessionNotFound if there is no such session.
+	GetSessionByTokenHash(context.Context, []byte) (*domain.Session, error)
+	UpdateSession(context.Context, *domain.Session) error
+	// RevokeUserSessions revokes every active login session of the user. API tokens are not
+	// sessions and stay active.
+	RevokeUserSessions(context.Context, domain.UserID) error
+	// RevokeOtherUserSessions revokes every active login session of the user except keep.
+	RevokeOtherUserSessions(ctx context.Context, id domain.UserID, keep domain.SessionID) error
+}
+
+type App struct {
+	Mode         envx.Mode
+	Session      dbx.Session
+	UserRepo     UserRepo
+	SessionRepo  SessionRepo
+	APITokenRepo APITokenRepo
+	// PasswordResetRepo stores one-time password reset tokens.
+	PasswordResetRepo PasswordResetRepo
+	IdentityRepo      IdentityRepo
+	// OIDC is nil when OpenID Connect login is not configured.
+	OIDC         OIDCProvider
+	SSO          SSOConfig
+	LoginLimiter *LoginLimiter
+	// PasswordParams are used for new password hashes, zero value means domain.DefaultPasswordParams.
+	// Changing them upgrades existing hashes as users log in.
+	PasswordParams domain.PasswordParams
+	// SessionTTL defaults to domain.DefaultSessionTTL.
+	SessionTTL time.Duration
+	// AllowRegistration lets anyone create an account, otherwise only the first-run bootstrap can.
+	AllowRegistration bool
+
+	dummyOnce sync.Once
+	dummy     *domain.User
+}
+
+// NewApp returns app if none of its dependencies but OIDC is nil and PasswordParams, if set, are valid.
+func NewApp(app *App) (*App, error) {
+	const op = errorx.Op("auth.NewApp")
+
+	err := v.Errors{
+		"Session":           v.Validate(app.Session, v.NotNil),
+		"UserRepo":          v.Validate(app.UserRepo, v.NotNil),
+		"SessionRepo":       v.Validate(app.SessionRepo, v.NotNil),
+		"APITokenRepo":      v.Validate(app.APITokenRepo, v.NotNil),
+		"PasswordResetRepo": v.Validate(app.PasswordResetRepo, v.NotNil),
+		"IdentityRepo":      v.Validate(app.IdentityRepo, v.NotNil),
+		"LoginLimiter":      v.Validate(app.LoginLimiter, v.NotNil),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	if app.PasswordParams.Algorithm != "" {
+		if err := app.PasswordParams.Validate(); err != nil {
+			return nil, op.WrapMsg(err, "invalid PasswordParams")
+		}
+	}
+	return app, nil
+}
+
+// Bootstrap creates the first user of a fresh installation, the user is an admin.
+func (a *App) Bootstrap(ctx context.Context, name, password string) (*domain.User, error) {
+	const op = errorx.Op("auth.App.Bootstrap")
+
+	user, err := domain.NewUser(domain.NewUserID(), name, password, domain.RoleAdmin, a.passwordParams())
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		count, err := a.UserRepo.CountUsers(ctx)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyBootstrapped
+		}
+		return a.UserRepo.CreateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return user, nil
+}
+
+// Register creates a member account.
+func (a *App) Register(ctx context.Context, name, password string) (*domain.User, error) {
+	const op = errorx.Op("auth.App.Register")
+
+	if !a.AllowRegistration {
+		return nil, op.Wrap(ErrRegistrationDisabled)
+	}
+
+	user, err := domain.NewUser(domain.NewUserID(), name, password, domain.RoleMember, a.passwordParams())
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		_, err := a.UserRepo.GetUserByName(ctx, name)
+		if err == nil {
+			return ErrUserNameTaken
+		} else if !errors.Is(err, domain.ErrUserNotFound) {
+			return err
+		}
+		return a.UserRepo.CreateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return user, nil
+}
+
+type LoginCmd struct {
+	Name      string
+	Password  string
+	ClientIP  string
+	UserAgent string
+}
+
+type LoginResult struct {
+	User    *domain.User
+	Session *domain.Session
+	// Token is the session token to hand to the client, it is not stored anywhere.
+	Token string
+}
+
+// Login checks the credentials and starts a new session. Failed attempts are counted per user name
+// and per client ip, after too many of them login is locked out for a while. A successful login
+// only forgets the failures of its user name, the ip counter expires on its own so logging into
+// one account does not lift the lockout of a client guessing the passwords of others.
+func (a *App) Login(ctx context.Context, cmd LoginCmd) (LoginResult, error) {
+	const op = errorx.Op("auth.App.Login")
+
+	nameKey := nameLimiterKey(cmd.Name)
+	limiterKeys := []string{nameKey, "ip:" + cmd.ClientIP}
+	now := time.Now()
+	if _, ok := a.LoginLimiter.Allow(now, limiterKeys...); !ok {
+		return LoginResult{}, op.Wrap(ErrLoginLocked)
+	}
+
+	user, err := a.UserRepo.GetUserByName(ctx, cmd.Name)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		// compare anyway, so response time does not reveal whether the user exists
+		_ = a.dummyUser().ComparePassword(cmd.Password)
+		a.LoginLimiter.Fail(now, limiterKeys...)
+		return LoginResult{}, op.Wrap(ErrInvalidCredentials)
+	} else if err != nil {
+		return LoginResult{}, op.Wrap(err)
+	}
+
+	if !user.HasPassword() {
+		// single sign-on users, keep the timing of a real comparison
+		_ = a.dummyUser().ComparePassword(cmd.Password)
+	}
+	if err := user.ComparePassword(cmd.Password); err != nil {
+		a.LoginLimiter.Fail(now, limiterKeys...)
+		return LoginResult{}, op.Wrap(ErrInvalidCredentials)
+	}
+	a.LoginLimiter.Reset(nameKey)
+	a.rehashPassword(ctx, user, cmd.Password)
+
+	session, token, err := domain.NewSession(user.ID(), cmd.UserAgent, a.sessionTTL())
+	if err != nil {
+		return LoginResult{}, op.Wrap(err)
+	}
+	if err := a.SessionRepo.CreateSession(ctx, session); err != nil {
+		return LoginResult{}, op.Wrap(err)
+	}
+
+	return LoginResult{User: user, Session: session, Token: token}, nil
+}
+
+// Authenticate returns the user owning the session token.
+func (a *App) Authenticate(ctx context.Context, token string) (*domain.User, *domain.Session, error) {
+	const op = errorx.Op("auth.App.Authenticate")
+
+	session, err := a.SessionRepo.GetSessionByTokenHash(ctx, domain.HashToken(token))
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		return nil, nil, op.Wrap(ErrNotAuthenticated)
+	} else if err != nil {
+		return nil, nil, op.Wrap(err)
+	}
+	if !session.IsActive() {
+		return nil, nil, op.Wrap(ErrNotAuthenticated)
+	}
+
+	user, err := a.UserRepo.GetUserByID(ctx, session.UserID())
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, nil, op.Wrap(ErrNotAuthenticated)
+	} else if err != nil {
+		return nil, nil, op.Wrap(err)
+	}
+
+	return user, session, nil
+}
+
+// Logout revokes the session of the token, unknown and already revoked tokens are ignored.
+func (a *App) Logout(ctx context.Context, token string) error {
+	const op = errorx.Op("auth.App.Logout")
+
+	session, err := a.SessionRepo.GetSessionByTokenHash(ctx, domain.HashToken(token))
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		return nil
+	} else if err != nil {
+		return op.Wrap(err)
+	}
+
+	session.Revoke()
+	return op.Wrap(a.SessionRepo.UpdateSession(ctx, session))
+}
+
+// LogoutAll revokes every session of the current user, logging them out on all browsers. API
+// tokens of devices and scripts are left alone, they are revoked one by one with RevokeAPIToken.
+// An API token needs the admin scope to call it, so a read-only device token can not log the
+// user out.
+func (a *App) LogoutAll(ctx context.Context) error {
+	const op = errorx.Op("auth.App.LogoutAll")
+
+	user, err := Authenticated(ctx, domain.ScopeAdmin)
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	return op.Wrap(a.SessionRepo.RevokeUserSessions(ctx, user.ID()))
+}
+
+// nameLimiterKey is the LoginLimiter key counting failed attempts against the user name.
+func nameLimiterKey(name string) string {
+	return "name:" + strings.ToLower(name)
+}
+
+func (a *App) sessionTTL() time.Duration {
+	if a.SessionTTL == 0 {
+		return domain.DefaultSessionTTL
+	}
+	return a.SessionTTL
+}
+
+// dummyUser has a password hash with the same cost as real users, it is used to compare passwords
+// of users that do not exist.
+func (a *App) dummyUser() *domain.User {
+	a.dummyOnce.Do(func() {
+		hash, _ := domain.HashPassword("dummy-Password-0", a.passwordParams())
+		user := domain.NewUserBuilder().WithDefault().PassHash(hash).Build()
+		a.dummy = &user
+	})
+	return a.dummy
+}
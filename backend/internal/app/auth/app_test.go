@@ -0,0 +1,440 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+// --- Mocks ---
+
+type mockUserRepo struct{ mock.Mock }
+
+func (m *mockUserRepo) CountUsers(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockUserRepo) CreateUser(ctx context.Context, u *domain.User) error {
+	return m.Called(ctx, u).Error(0)
+}
+
+func (m *mockUserRepo) GetUserByID(ctx context.Context, id domain.UserID) (*domain.User, error) {
+	args := m.Called(ctx, id)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockUserRepo) GetUserByName(ctx context.Context, name string) (*domain.User, error) {
+	args := m.Called(ctx, name)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockUserRepo) ListUsers(ctx context.Context) ([]*domain.User, error) {
+	args := m.Called(ctx)
+	u, _ := args.Get(0).([]*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockUserRepo) UpdateUser(ctx context.Context, u *domain.User) error {
+	return m.Called(ctx, u).Error(0)
+}
+
+type mockSessionRepo struct{ mock.Mock }
+
+func (m *mockSessionRepo) CreateSession(ctx context.Context, s *domain.Session) error {
+	return m.Called(ctx, s).Error(0)
+}
+
+func (m *mockSessionRepo) GetSessionByTokenHash(ctx context.Context, hash []byte) (*domain.Session, error) {
+	args := m.Called(ctx, hash)
+	s, _ := args.Get(0).(*domain.Session)
+	return s, args.Error(1)
+}
+
+func (m *mockSessionRepo) UpdateSession(ctx context.Context, s *domain.Session) error {
+	return m.Called(ctx, s).Error(0)
+}
+
+func (m *mockSessionRepo) RevokeUserSessions(ctx context.Context, id domain.UserID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockSessionRepo) RevokeOtherUserSessions(ctx context.Context, id domain.UserID, keep domain.SessionID) error {
+	return m.Called(ctx, id, keep).Error(0)
+}
+
+type mockAPITokenRepo struct{ mock.Mock }
+
+func (m *mockAPITokenRepo) CreateAPIToken(ctx context.Context, t *domain.APIToken) error {
+	return m.Called(ctx, t).Error(0)
+}
+
+func (m *mockAPITokenRepo) GetAPIToken(ctx context.Context, id domain.APITokenID) (*domain.APIToken, error) {
+	args := m.Called(ctx, id)
+	t, _ := args.Get(0).(*domain.APIToken)
+	return t, args.Error(1)
+}
+
+func (m *mockAPITokenRepo) GetAPITokenByHash(ctx context.Context, hash []byte) (*domain.APIToken, error) {
+	args := m.Called(ctx, hash)
+	t, _ := args.Get(0).(*domain.APIToken)
+	return t, args.Error(1)
+}
+
+func (m *mockAPITokenRepo) ListUserAPITokens(ctx context.Context, id domain.UserID) ([]*domain.APIToken, error) {
+	args := m.Called(ctx, id)
+	t, _ := args.Get(0).([]*domain.APIToken)
+	return t, args.Error(1)
+}
+
+func (m *mockAPITokenRepo) UpdateAPIToken(ctx context.Context, t *domain.APIToken) error {
+	return m.Called(ctx, t).Error(0)
+}
+
+type mockPasswordResetRepo struct{ mock.Mock }
+
+func (m *mockPasswordResetRepo) CreatePasswordReset(ctx context.Context, r *domain.PasswordReset) error {
+	return m.Called(ctx, r).Error(0)
+}
+
+func (m *mockPasswordResetRepo) GetPasswordResetByTokenHash(ctx context.Context, hash []byte) (*domain.PasswordReset, error) {
+	args := m.Called(ctx, hash)
+	r, _ := args.Get(0).(*domain.PasswordReset)
+	return r, args.Error(1)
+}
+
+func (m *mockPasswordResetRepo) UpdatePasswordReset(ctx context.Context, r *domain.PasswordReset) error {
+	return m.Called(ctx, r).Error(0)
+}
+
+type mockIdentityRepo struct{ mock.Mock }
+
+func (m *mockIdentityRepo) GetUserByIdentity(ctx context.Context, id domain.ExternalIdentity) (*domain.User, error) {
+	args := m.Called(ctx, id)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockIdentityRepo) LinkIdentity(ctx context.Context, userID domain.UserID, id domain.ExternalIdentity) error {
+	return m.Called(ctx, userID, id).Error(0)
+}
+
+type mockSession struct{ mock.Mock }
+
+func (m *mockSession) Transaction(ctx context.Context, f func(context.Context) error) error {
+	return f(ctx)
+}
+
+func (m *mockSession) Begin(ctx context.Context) (dbx.Session, error) {
+	args := m.Called(ctx)
+	s, _ := args.Get(0).(dbx.Session)
+	return s, args.Error(1)
+}
+
+func (m *mockSession) Rollback() error          { return m.Called().Error(0) }
+func (m *mockSession) Commit() error            { return m.Called().Error(0) }
+func (m *mockSession) Context() context.Context { return m.Called().Get(0).(context.Context) }
+
+// --- Helpers ---
+
+func newTestApp(t *testing.T) (*App, *mockUserRepo, *mockSessionRepo) {
+	t.Helper()
+	ur := new(mockUserRepo)
+	sr := new(mockSessionRepo)
+	return &App{
+		Mode:              envx.Test,
+		Session:           new(mockSession),
+		UserRepo:          ur,
+		SessionRepo:       sr,
+		APITokenRepo:      new(mockAPITokenRepo),
+		PasswordResetRepo: new(mockPasswordResetRepo),
+		IdentityRepo:      new(mockIdentityRepo),
+		LoginLimiter:      NewLoginLimiter(),
+	}, ur, sr
+}
+
+func mustNewUser(t *testing.T) *domain.User {
+	return mustNewUserWithRole(t, domain.RoleMember)
+}
+
+func mustNewUserWithRole(t *testing.T, role domain.Role) *domain.User {
+	t.Helper()
+	u, err := domain.NewUser(domain.NewUserID(), domain.ValidUsername, domain.ValidPassword, role, domain.DefaultPasswordParams(envx.Test))
+	require.NoError(t, err)
+	return u
+}
+
+// --- Tests ---
+
+func TestApp_Bootstrap(t *testing.T) {
+	t.Parallel()
+
+	t.Run("first run", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ur.On("CountUsers", mock.Anything).Return(0, nil)
+		ur.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
+
+		user, err := app.Bootstrap(t.Context(), domain.ValidUsername, domain.ValidPassword)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ValidUsername, user.Name())
+		assert.Equal(t, domain.RoleAdmin, user.Role())
+		ur.AssertCalled(t, "CreateUser", mock.Anything, user)
+	})
+
+	t.Run("already bootstrapped", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ur.On("CountUsers", mock.Anything).Return(1, nil)
+
+		_, err := app.Bootstrap(t.Context(), domain.ValidUsername, domain.ValidPassword)
+		require.ErrorIs(t, err, ErrAlreadyBootstrapped)
+		require.ErrorIs(t, err, domain.ErrConflict)
+		ur.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
+}
+
+func TestApp_Register(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		_, err := app.Register(t.Context(), domain.ValidUsername, domain.ValidPassword)
+		require.ErrorIs(t, err, ErrRegistrationDisabled)
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		app.AllowRegistration = true
+		ur.On("GetUserByName", mock.Anything, domain.ValidUsername).Return(mustNewUser(t), nil)
+
+		_, err := app.Register(t.Context(), domain.ValidUsername, domain.ValidPassword)
+		require.ErrorIs(t, err, ErrUserNameTaken)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		app.AllowRegistration = true
+		ur.On("GetUserByName", mock.Anything, domain.ValidUsername).Return(nil, domain.ErrUserNotFound)
+		ur.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
+
+		user, err := app.Register(t.Context(), domain.ValidUsername, domain.ValidPassword)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleMember, user.Role())
+		ur.AssertCalled(t, "CreateUser", mock.Anything, user)
+	})
+}
+
+func TestApp_Login(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		app, ur, sr := newTestApp(t)
+		user := mustNewUser(t)
+		ur.On("GetUserByName", mock.Anything, user.Name()).Return(user, nil)
+		sr.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+
+		res, err := app.Login(t.Context(), LoginCmd{Name: user.Name(), Password: domain.ValidPassword, ClientIP: "10.0.0.1", UserAgent: "test"})
+		require.NoError(t, err)
+		assert.Equal(t, user, res.User)
+		assert.Equal(t, user.ID(), res.Session.UserID())
+		assert.Equal(t, domain.HashToken(res.Token), res.Session.TokenHash())
+		assert.WithinDuration(t, time.Now().Add(domain.DefaultSessionTTL), res.Session.ExpiresAt(), time.Second)
+		sr.AssertCalled(t, "CreateSession", mock.Anything, res.Session)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ur.On("GetUserByName", mock.Anything, "ghost").Return(nil, domain.ErrUserNotFound)
+
+		_, err := app.Login(t.Context(), LoginCmd{Name: "ghost", Password: domain.ValidPassword})
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("single sign-on user", func(t *testing.T) {
+		t.Parallel()
+		app, ur, sr := newTestApp(t)
+		user := domain.NewUserBuilder().WithDefault().PassHash(nil).Build()
+		ur.On("GetUserByName", mock.Anything, user.Name()).Return(&user, nil)
+
+		_, err := app.Login(t.Context(), LoginCmd{Name: user.Name(), Password: ""})
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+		sr.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("lockout after too many wrong passwords", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		user := mustNewUser(t)
+		ur.On("GetUserByName", mock.Anything, user.Name()).Return(user, nil)
+
+		cmd := LoginCmd{Name: user.Name(), Password: "wrong", ClientIP: "10.0.0.1"}
+		for range DefaultMaxLoginAttempts {
+			_, err := app.Login(t.Context(), cmd)
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+
+		cmd.Password = domain.ValidPassword
+		_, err := app.Login(t.Context(), cmd)
+		require.ErrorIs(t, err, ErrLoginLocked)
+		require.ErrorIs(t, err, domain.ErrRateLimited)
+	})
+
+	t.Run("own login does not lift the ip lockout", func(t *testing.T) {
+		t.Parallel()
+		app, ur, sr := newTestApp(t)
+		ur.On("GetUserByName", mock.Anything, mock.Anything).Return(mustNewUser(t), nil)
+		sr.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+
+		victim := LoginCmd{Name: "victim", Password: "wrong", ClientIP: "10.0.0.1"}
+		own := LoginCmd{Name: "mallory", Password: domain.ValidPassword, ClientIP: "10.0.0.1"}
+		for range DefaultMaxLoginAttempts - 1 {
+			_, err := app.Login(t.Context(), victim)
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+		_, err := app.Login(t.Context(), own)
+		require.NoError(t, err)
+		_, err = app.Login(t.Context(), victim)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		other := LoginCmd{Name: "other", Password: domain.ValidPassword, ClientIP: "10.0.0.1"}
+		_, err = app.Login(t.Context(), other)
+		require.ErrorIs(t, err, ErrLoginLocked)
+	})
+}
+
+func TestApp_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		session     *domain.Session
+		sessionErr  error
+		expectedErr error
+	}{
+		{
+			name:    "active session",
+			session: ptr(domain.NewSessionBuilder().UserID(user.ID()).ExpiresAt(future).Build()),
+		},
+		{
+			name:        "unknown token",
+			sessionErr:  domain.ErrSessionNotFound,
+			expectedErr: ErrNotAuthenticated,
+		},
+		{
+			name:        "expired session",
+			session:     ptr(domain.NewSessionBuilder().UserID(user.ID()).ExpiresAt(past).Build()),
+			expectedErr: ErrNotAuthenticated,
+		},
+		{
+			name:        "revoked session",
+			session:     ptr(domain.NewSessionBuilder().UserID(user.ID()).ExpiresAt(future).RevokedAt(&past).Build()),
+			expectedErr: ErrNotAuthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ur, sr := newTestApp(t)
+			sr.On("GetSessionByTokenHash", mock.Anything, domain.HashToken("token")).Return(tt.session, tt.sessionErr)
+			ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+
+			got, _, err := app.Authenticate(t.Context(), "token")
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, user, got)
+		})
+	}
+}
+
+func TestApp_Logout(t *testing.T) {
+	t.Parallel()
+
+	app, _, sr := newTestApp(t)
+	session := ptr(domain.NewSessionBuilder().ExpiresAt(time.Now().Add(time.Hour)).Build())
+	sr.On("GetSessionByTokenHash", mock.Anything, domain.HashToken("token")).Return(session, nil)
+	sr.On("UpdateSession", mock.Anything, session).Return(nil)
+
+	require.NoError(t, app.Logout(t.Context(), "token"))
+	assert.False(t, session.IsActive())
+	sr.AssertExpectations(t)
+}
+
+func TestApp_LogoutAll(t *testing.T) {
+	t.Parallel()
+
+	app, _, sr := newTestApp(t)
+	user := mustNewUser(t)
+	sr.On("RevokeUserSessions", mock.Anything, user.ID()).Return(nil)
+
+	err := app.LogoutAll(t.Context())
+	require.ErrorIs(t, err, ErrNotAuthenticated)
+
+	err = app.LogoutAll(withToken(t.Context(), user, domain.ScopeReadLibrary, domain.ScopeWriteProgress))
+	require.ErrorIs(t, err, domain.ErrInsufficientScope)
+	sr.AssertNotCalled(t, "RevokeUserSessions", mock.Anything, mock.Anything)
+
+	require.NoError(t, app.LogoutAll(WithUser(t.Context(), user)))
+	require.NoError(t, app.LogoutAll(withToken(t.Context(), user, domain.ScopeAdmin)))
+	sr.AssertNumberOfCalls(t, "RevokeUserSessions", 2)
+	// API tokens are not sessions, the mock fails the test if any of its methods is called.
+	app.APITokenRepo.(*mockAPITokenRepo).AssertExpectations(t)
+}
+
+func TestNewApp(t *testing.T) {
+	t.Parallel()
+
+	app, _, _ := newTestApp(t)
+	got, err := NewApp(app)
+	require.NoError(t, err)
+	assert.Same(t, app, got)
+
+	app, _, _ = newTestApp(t)
+	app.LoginLimiter = nil
+	_, err = NewApp(app)
+	vx.AssertValidationErrors(t, err, v.Errors{"LoginLimiter": v.ErrNotNilRequired})
+
+	app, _, _ = newTestApp(t)
+	app.SessionRepo = nil
+	app.IdentityRepo = nil
+	_, err = NewApp(app)
+	vx.AssertValidationErrors(t, err, v.Errors{
+		"SessionRepo":  v.ErrNotNilRequired,
+		"IdentityRepo": v.ErrNotNilRequired,
+	})
+
+	app, _, _ = newTestApp(t)
+	app.PasswordParams = domain.PasswordParams{Algorithm: domain.Argon2id, Argon2Memory: 1024}
+	_, err = NewApp(app)
+	require.ErrorContains(t, err, "invalid PasswordParams")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
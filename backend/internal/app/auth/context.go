@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type (
+	userKey     struct{}
+	apiTokenKey struct{}
+	sessionKey  struct{}
+)
+
+// WithUser returns a copy of ctx carrying the authenticated user.
+func WithUser(ctx context.Context, user *domain.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user stored by WithUser or ErrNotAuthenticated.
+func UserFromContext(ctx context.Context) (*domain.User, error) {
+	user, ok := ctx.Value(userKey{}).(*domain.User)
+	if !ok || user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	return user, nil
+}
+
+// WithAPIToken returns a copy of ctx carrying the API token the request was authenticated with.
+func WithAPIToken(ctx context.Context, token *domain.APIToken) context.Context {
+	return context.WithValue(ctx, apiTokenKey{}, token)
+}
+
+// APITokenFromContext returns the token stored by WithAPIToken, ok is false for session logins.
+func APITokenFromContext(ctx context.Context) (token *domain.APIToken, ok bool) {
+	token, ok = ctx.Value(apiTokenKey{}).(*domain.APIToken)
+	return token, ok && token != nil
+}
+
+// WithSession returns a copy of ctx carrying the login session the request was authenticated with.
+func WithSession(ctx context.Context, session *domain.Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session stored by WithSession, ok is false for API tokens.
+func SessionFromContext(ctx context.Context) (session *domain.Session, ok bool) {
+	session, ok = ctx.Value(sessionKey{}).(*domain.Session)
+	return session, ok && session != nil
+}
+
+// RequireScope returns domain.ErrInsufficientScope if the request was authenticated with an
+// API token that lacks s. Session logins have every scope.
+func RequireScope(ctx context.Context, s domain.Scope) error {
+	if token, ok := APITokenFromContext(ctx); ok && !token.HasScope(s) {
+		return fmt.Errorf("%w: %s", domain.ErrInsufficientScope, s)
+	}
+	return nil
+}
+
+// Authorize returns the user from ctx if their role grants p and the API token, if any, has the
+// scope of p. Otherwise it returns ErrNotAuthenticated, domain.ErrPermissionDenied or
+// domain.ErrInsufficientScope.
+func Authorize(ctx context.Context, p domain.Permission) (*domain.User, error) {
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := user.Authorize(p); err != nil {
+		return nil, err
+	}
+	if err := RequireScope(ctx, p.Scope()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Authenticated returns the user from ctx if the request may use scope s.
+func Authenticated(ctx context.Context, s domain.Scope) (*domain.User, error) {
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := RequireScope(ctx, s); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
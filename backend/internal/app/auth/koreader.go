@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+// SetKOReaderPassword sets the password the user in ctx types into KOReader's progress sync,
+// an empty password disables KOReader sync for the user.
+func (a *App) SetKOReaderPassword(ctx context.Context, password string) error {
+	const op = errorx.Op("auth.App.SetKOReaderPassword")
+
+	user, err := Authenticated(ctx, domain.ScopeAdmin)
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	if password == "" {
+		user.DisableKOReader()
+	} else if err := user.SetKOReaderPassword(password, a.passwordParams()); err != nil {
+		return op.Wrap(err)
+	}
+
+	return op.Wrap(a.UserRepo.UpdateUser(ctx, user))
+}
+
+// RegisterKOReader creates a member from KOReader's registration, key is the KOReader key of the
+// password, see domain.KOReaderKey. The user has no password for the web until an admin resets it.
+func (a *App) RegisterKOReader(ctx context.Context, name, key string) (*domain.User, error) {
+	const op = errorx.Op("auth.App.RegisterKOReader")
+
+	if !a.AllowRegistration {
+		return nil, op.Wrap(ErrRegistrationDisabled)
+	}
+
+	user, err := domain.NewExternalUser(domain.NewUserID(), name, domain.RoleMember)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	if err := user.SetKOReaderKey(key, a.passwordParams()); err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		_, err := a.UserRepo.GetUserByName(ctx, name)
+		if err == nil {
+			return ErrUserNameTaken
+		} else if !errors.Is(err, domain.ErrUserNotFound) {
+			return err
+		}
+		return a.UserRepo.CreateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return user, nil
+}
+
+type AuthenticateKOReaderCmd struct {
+	Name string
+	// Key is the x-auth-key header of KOReader.
+	Key      string
+	ClientIP string
+}
+
+// AuthenticateKOReader checks the credentials KOReader sends with every request. Failures count
+// towards the same lockout as Login, and as there a success only resets the user name.
+func (a *App) AuthenticateKOReader(ctx context.Context, cmd AuthenticateKOReaderCmd) (*domain.User, error) {
+	const op = errorx.Op("auth.App.AuthenticateKOReader")
+
+	nameKey := nameLimiterKey(cmd.Name)
+	limiterKeys := []string{nameKey, "ip:" + cmd.ClientIP}
+	now := time.Now()
+	if _, ok := a.LoginLimiter.Allow(now, limiterKeys...); !ok {
+		return nil, op.Wrap(ErrLoginLocked)
+	}
+
+	user, err := a.UserRepo.GetUserByName(ctx, cmd.Name)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		_ = a.dummyUser().ComparePassword(cmd.Key)
+		a.LoginLimiter.Fail(now, limiterKeys...)
+		return nil, op.Wrap(ErrInvalidCredentials)
+	} else if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	if !user.HasKOReaderKey() {
+		_ = a.dummyUser().ComparePassword(cmd.Key)
+	}
+	if err := user.CompareKOReaderKey(cmd.Key); err != nil {
+		a.LoginLimiter.Fail(now, limiterKeys...)
+		return nil, op.Wrap(ErrInvalidCredentials)
+	}
+	a.LoginLimiter.Reset(nameKey)
+
+	return user, nil
+}
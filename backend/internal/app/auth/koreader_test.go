@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+)
+
+const koreaderPassword = "e-ink-reader"
+
+func TestApp_SetKOReaderPassword(t *testing.T) {
+	t.Parallel()
+
+	app, ur, _ := newTestApp(t)
+	user := mustNewUser(t)
+	ur.On("UpdateUser", mock.Anything, user).Return(nil)
+	ctx := WithUser(t.Context(), user)
+
+	require.NoError(t, app.SetKOReaderPassword(ctx, koreaderPassword))
+	require.NoError(t, user.CompareKOReaderKey(domain.KOReaderKey(koreaderPassword)))
+	require.ErrorIs(t, user.CompareKOReaderKey(domain.KOReaderKey(domain.ValidPassword)), domain.ErrPasswordMismatch)
+
+	require.NoError(t, app.SetKOReaderPassword(ctx, ""))
+	assert.False(t, user.HasKOReaderKey())
+	ur.AssertNumberOfCalls(t, "UpdateUser", 2)
+}
+
+func TestApp_RegisterKOReader(t *testing.T) {
+	t.Parallel()
+
+	key := domain.KOReaderKey(koreaderPassword)
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		app.AllowRegistration = true
+		ur.On("GetUserByName", mock.Anything, "reader").Return(nil, domain.ErrUserNotFound)
+		ur.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
+
+		user, err := app.RegisterKOReader(t.Context(), "reader", key)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleMember, user.Role())
+		assert.False(t, user.HasPassword())
+		require.NoError(t, user.CompareKOReaderKey(key))
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		app.AllowRegistration = true
+		ur.On("GetUserByName", mock.Anything, "reader").Return(mustNewUser(t), nil)
+
+		_, err := app.RegisterKOReader(t.Context(), "reader", key)
+		require.ErrorIs(t, err, ErrUserNameTaken)
+	})
+
+	t.Run("registration disabled", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		_, err := app.RegisterKOReader(t.Context(), "reader", key)
+		require.ErrorIs(t, err, ErrRegistrationDisabled)
+	})
+}
+
+func TestApp_AuthenticateKOReader(t *testing.T) {
+	t.Parallel()
+
+	key := domain.KOReaderKey(koreaderPassword)
+
+	tests := []struct {
+		name        string
+		user        func(t *testing.T) *domain.User
+		key         string
+		expectedErr error
+	}{
+		{
+			name: "ok",
+			user: func(t *testing.T) *domain.User {
+				u := mustNewUser(t)
+				require.NoError(t, u.SetKOReaderPassword(koreaderPassword, domain.DefaultPasswordParams(envx.Test)))
+				return u
+			},
+			key: key,
+		},
+		{
+			name: "wrong key",
+			user: func(t *testing.T) *domain.User {
+				u := mustNewUser(t)
+				require.NoError(t, u.SetKOReaderPassword(koreaderPassword, domain.DefaultPasswordParams(envx.Test)))
+				return u
+			},
+			key:         domain.KOReaderKey("something else"),
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:        "account password does not work",
+			user:        mustNewUser,
+			key:         domain.KOReaderKey(domain.ValidPassword),
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:        "unknown user",
+			key:         key,
+			expectedErr: ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ur, _ := newTestApp(t)
+			if tt.user != nil {
+				ur.On("GetUserByName", mock.Anything, "reader").Return(tt.user(t), nil)
+			} else {
+				ur.On("GetUserByName", mock.Anything, "reader").Return(nil, domain.ErrUserNotFound)
+			}
+
+			user, err := app.AuthenticateKOReader(t.Context(), AuthenticateKOReaderCmd{Name: "reader", Key: tt.key, ClientIP: "192.0.2.1"})
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, user)
+		})
+	}
+
+	t.Run("locked out", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ur.On("GetUserByName", mock.Anything, "reader").Return(nil, domain.ErrUserNotFound)
+
+		cmd := AuthenticateKOReaderCmd{Name: "reader", Key: key, ClientIP: "192.0.2.1"}
+		for range app.LoginLimiter.MaxAttempts {
+			_, _ = app.AuthenticateKOReader(t.Context(), cmd)
+		}
+		_, err := app.AuthenticateKOReader(t.Context(), cmd)
+		require.ErrorIs(t, err, ErrLoginLocked)
+	})
+
+	t.Run("own device does not lift the ip lockout", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		user := mustNewUser(t)
+		require.NoError(t, user.SetKOReaderPassword(koreaderPassword, domain.DefaultPasswordParams(envx.Test)))
+		ur.On("GetUserByName", mock.Anything, mock.Anything).Return(user, nil)
+
+		victim := AuthenticateKOReaderCmd{Name: "victim", Key: "wrong", ClientIP: "192.0.2.1"}
+		for range app.LoginLimiter.MaxAttempts - 1 {
+			_, err := app.AuthenticateKOReader(t.Context(), victim)
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+		_, err := app.AuthenticateKOReader(t.Context(), AuthenticateKOReaderCmd{Name: "mallory", Key: key, ClientIP: "192.0.2.1"})
+		require.NoError(t, err)
+		_, err = app.AuthenticateKOReader(t.Context(), victim)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = app.AuthenticateKOReader(t.Context(), AuthenticateKOReaderCmd{Name: "other", Key: key, ClientIP: "192.0.2.1"})
+		require.ErrorIs(t, err, ErrLoginLocked)
+	})
+}
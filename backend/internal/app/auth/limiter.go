@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxLoginAttempts = 5
+	DefaultLoginWindow      = 15 * time.Minute
+	DefaultLockoutDuration  = 15 * time.Minute
+)
+
+// LoginLimiter counts failed logins per key and locks the key out once MaxAttempts failures
+// happen within Window. It is in-memory, which is enough for a single self-hosted instance.
+type LoginLimiter struct {
+	MaxAttempts int
+	Window      time.Duration
+	Lockout     time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+}
+
+type loginAttempts struct {
+	failures    int
+	firstFailAt time.Time
+	lockedUntil time.Time
+}
+
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{
+		MaxAttempts: DefaultMaxLoginAttempts,
+		Window:      DefaultLoginWindow,
+		Lockout:     DefaultLockoutDuration,
+	}
+}
+
+// Allow returns how long the caller has to wait if any of the keys is locked out.
+func (l *LoginLimiter) Allow(now time.Time, keys ...string) (retryAfter time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		a := l.attempts[key]
+		if a != nil && now.Before(a.lockedUntil) {
+			retryAfter = max(retryAfter, a.lockedUntil.Sub(now))
+		}
+	}
+
+	return retryAfter, retryAfter == 0
+}
+
+// Fail records a failed attempt for every key.
+func (l *LoginLimiter) Fail(now time.Time, keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.attempts == nil {
+		l.attempts = make(map[string]*loginAttempts)
+	}
+	l.cleanup(now)
+
+	for _, key := range keys {
+		a := l.attempts[key]
+		if a == nil || now.Sub(a.firstFailAt) > l.Window {
+			a = &loginAttempts{firstFailAt: now}
+			l.attempts[key] = a
+		}
+		a.failures++
+		if a.failures >= l.MaxAttempts {
+			a.lockedUntil = now.Add(l.Lockout)
+			a.failures = 0
+			a.firstFailAt = now
+		}
+	}
+}
+
+// Reset forgets failed attempts of the keys, it is called after a successful login.
+func (l *LoginLimiter) Reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		delete(l.attempts, key)
+	}
+}
+
+// cleanup drops entries that are neither locked nor inside the window, so the map does not grow forever.
+func (l *LoginLimiter) cleanup(now time.Time) {
+	for key, a := range l.attempts {
+		if now.After(a.lockedUntil) && now.Sub(a.firstFailAt) > l.Window {
+			delete(l.attempts, key)
+		}
+	}
+}
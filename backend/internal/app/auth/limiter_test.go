@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	t.Parallel()
+
+	l := &LoginLimiter{MaxAttempts: 3, Window: time.Minute, Lockout: 5 * time.Minute}
+	now := time.Now()
+
+	for range 2 {
+		l.Fail(now, "name:user1", "ip:10.0.0.1")
+	}
+	_, ok := l.Allow(now, "name:user1", "ip:10.0.0.1")
+	assert.True(t, ok, "below max attempts")
+
+	l.Fail(now, "name:user1", "ip:10.0.0.1")
+	retryAfter, ok := l.Allow(now.Add(time.Minute), "name:user1", "ip:10.0.0.2")
+	assert.False(t, ok, "user is locked from any ip")
+	assert.Equal(t, 4*time.Minute, retryAfter)
+
+	_, ok = l.Allow(now, "name:user2", "ip:10.0.0.1")
+	assert.False(t, ok, "ip is locked for any user")
+
+	_, ok = l.Allow(now.Add(5*time.Minute+time.Second), "name:user1", "ip:10.0.0.1")
+	assert.True(t, ok, "lockout is over")
+}
+
+func TestLoginLimiter_window(t *testing.T) {
+	t.Parallel()
+
+	l := &LoginLimiter{MaxAttempts: 2, Window: time.Minute, Lockout: time.Minute}
+	now := time.Now()
+
+	l.Fail(now, "k")
+	l.Fail(now.Add(2*time.Minute), "k")
+	_, ok := l.Allow(now.Add(2*time.Minute), "k")
+	assert.True(t, ok, "failures outside of the window are not counted together")
+
+	l.Reset("k")
+	l.Fail(now.Add(3*time.Minute), "k")
+	_, ok = l.Allow(now.Add(3*time.Minute), "k")
+	assert.True(t, ok, "reset forgets previous failures")
+}
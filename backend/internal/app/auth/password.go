@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+var (
+	ErrWrongPassword        = fmt.Errorf("current password is wrong: %w", domain.ErrForbidden)
+	ErrInvalidPasswordReset = fmt.Errorf("invalid or expired password reset token: %w", domain.ErrUnauthorized)
+)
+
+type PasswordResetRepo interface {
+	CreatePasswordReset(context.Context, *domain.PasswordReset) error
+	// GetPasswordResetByTokenHash returns domain.ErrPasswordResetNotFound if there is no such reset.
+	GetPasswordResetByTokenHash(context.Context, []byte) (*domain.PasswordReset, error)
+	UpdatePasswordReset(context.Context, *domain.PasswordReset) error
+}
+
+// ChangePassword changes the password of the user in ctx, the current password is required.
+// Wrong current passwords count towards the lockout of Login for the user name. As a password is
+// mostly changed because it may be known to someone else, the other sessions and API tokens of
+// the user are revoked, only the session or token of the request stays valid.
+func (a *App) ChangePassword(ctx context.Context, current, password string) error {
+	const op = errorx.Op("auth.App.ChangePassword")
+
+	user, err := Authenticated(ctx, domain.ScopeAdmin)
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	nameKey := nameLimiterKey(user.Name())
+	now := time.Now()
+	if _, ok := a.LoginLimiter.Allow(now, nameKey); !ok {
+		return op.Wrap(ErrLoginLocked)
+	}
+
+	// single sign-on users have no password and a broken hash matches none either
+	err = user.ChangePassword(current, password, a.passwordParams())
+	if errors.Is(err, domain.ErrPasswordMismatch) || errors.Is(err, domain.ErrUnknownPasswordHash) {
+		a.LoginLimiter.Fail(now, nameKey)
+		return op.Wrap(ErrWrongPassword)
+	} else if err != nil {
+		return op.Wrap(err)
+	}
+	a.LoginLimiter.Reset(nameKey)
+
+	return op.Wrap(a.Session.Transaction(ctx, func(ctx context.Context) error {
+		if err := a.UserRepo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		if session, ok := SessionFromContext(ctx); ok {
+			if err := a.SessionRepo.RevokeOtherUserSessions(ctx, user.ID(), session.ID()); err != nil {
+				return err
+			}
+		} else if err := a.SessionRepo.RevokeUserSessions(ctx, user.ID()); err != nil {
+			return err
+		}
+
+		requestToken, _ := APITokenFromContext(ctx)
+		tokens, err := a.APITokenRepo.ListUserAPITokens(ctx, user.ID())
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if !token.IsActive() || (requestToken != nil && token.ID() == requestToken.ID()) {
+				continue
+			}
+			token.Revoke()
+			if err := a.APITokenRepo.UpdateAPIToken(ctx, token); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+type CreatePasswordResetResult struct {
+	Reset *domain.PasswordReset
+	// Token is handed to the user out of band, e.g. as a link, it can be used once.
+	Token string
+}
+
+// CreatePasswordReset issues a one-time reset token for the user, it requires domain.PermManageUsers.
+func (a *App) CreatePasswordReset(ctx context.Context, userID domain.UserID) (CreatePasswordResetResult, error) {
+	const op = errorx.Op("auth.App.CreatePasswordReset")
+
+	admin, err := Authorize(ctx, domain.PermManageUsers)
+	if err != nil {
+		return CreatePasswordResetResult{}, op.Wrap(err)
+	}
+
+	if _, err := a.UserRepo.GetUserByID(ctx, userID); err != nil {
+		return CreatePasswordResetResult{}, op.Wrap(err)
+	}
+
+	reset, token, err := domain.NewPasswordReset(userID, admin.ID(), domain.DefaultPasswordResetTTL)
+	if err != nil {
+		return CreatePasswordResetResult{}, op.Wrap(err)
+	}
+	if err := a.PasswordResetRepo.CreatePasswordReset(ctx, reset); err != nil {
+		return CreatePasswordResetResult{}, op.Wrap(err)
+	}
+
+	return CreatePasswordResetResult{Reset: reset, Token: token}, nil
+}
+
+// ResetPassword sets a new password with a reset token and logs the user out everywhere.
+func (a *App) ResetPassword(ctx context.Context, token, password string) error {
+	const op = errorx.Op("auth.App.ResetPassword")
+
+	return op.Wrap(a.Session.Transaction(ctx, func(ctx context.Context) error {
+		reset, err := a.PasswordResetRepo.GetPasswordResetByTokenHash(ctx, domain.HashToken(token))
+		if errors.Is(err, domain.ErrPasswordResetNotFound) {
+			return ErrInvalidPasswordReset
+		} else if err != nil {
+			return err
+		}
+		if !reset.IsUsable() {
+			return ErrInvalidPasswordReset
+		}
+
+		user, err := a.UserRepo.GetUserByID(ctx, reset.UserID())
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return ErrInvalidPasswordReset
+		} else if err != nil {
+			return err
+		}
+		if err := user.SetPassword(password, a.passwordParams()); err != nil {
+			return err
+		}
+
+		reset.MarkUsed()
+		if err := a.PasswordResetRepo.UpdatePasswordReset(ctx, reset); err != nil {
+			return err
+		}
+		if err := a.UserRepo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return a.SessionRepo.RevokeUserSessions(ctx, user.ID())
+	}))
+}
+
+// rehashPassword upgrades the hash of a user who just logged in when PasswordParams changed.
+// Failures are only logged, the login itself already succeeded.
+func (a *App) rehashPassword(ctx context.Context, user *domain.User, password string) {
+	const op = errorx.Op("auth.App.rehashPassword")
+
+	changed, err := user.RehashPassword(password, a.passwordParams())
+	if err == nil && changed {
+		err = a.UserRepo.UpdateUser(ctx, user)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "failed to rehash password", "user", user.ID(), "error", op.Wrap(err))
+	}
+}
+
+func (a *App) passwordParams() domain.PasswordParams {
+	if a.PasswordParams.Algorithm != "" {
+		return a.PasswordParams
+	}
+	return domain.DefaultPasswordParams(a.Mode)
+}
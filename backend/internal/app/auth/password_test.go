@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+const newPassword = "newPassword123!"
+
+func TestApp_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		app, ur, sr := newTestApp(t)
+		tr := app.APITokenRepo.(*mockAPITokenRepo)
+		user := mustNewUser(t)
+		session := ptr(domain.NewSessionBuilder().Id(domain.NewSessionID()).UserID(user.ID()).Build())
+		token := ptr(domain.NewAPITokenBuilder().Id(domain.NewAPITokenID()).UserID(user.ID()).Build())
+		ur.On("UpdateUser", mock.Anything, user).Return(nil)
+		sr.On("RevokeOtherUserSessions", mock.Anything, user.ID(), session.ID()).Return(nil)
+		tr.On("ListUserAPITokens", mock.Anything, user.ID()).Return([]*domain.APIToken{token}, nil)
+		tr.On("UpdateAPIToken", mock.Anything, token).Return(nil)
+
+		ctx := WithSession(WithUser(t.Context(), user), session)
+		require.NoError(t, app.ChangePassword(ctx, domain.ValidPassword, newPassword))
+		require.NoError(t, user.ComparePassword(newPassword))
+		assert.False(t, token.IsActive())
+		mock.AssertExpectationsForObjects(t, ur, sr, tr)
+	})
+
+	t.Run("api token of the request stays active", func(t *testing.T) {
+		t.Parallel()
+		app, ur, sr := newTestApp(t)
+		tr := app.APITokenRepo.(*mockAPITokenRepo)
+		user := mustNewUser(t)
+		own := ptr(domain.NewAPITokenBuilder().Id(domain.NewAPITokenID()).UserID(user.ID()).Scopes([]domain.Scope{domain.ScopeAdmin}).Build())
+		other := ptr(domain.NewAPITokenBuilder().Id(domain.NewAPITokenID()).UserID(user.ID()).Build())
+		ur.On("UpdateUser", mock.Anything, user).Return(nil)
+		sr.On("RevokeUserSessions", mock.Anything, user.ID()).Return(nil)
+		tr.On("ListUserAPITokens", mock.Anything, user.ID()).Return([]*domain.APIToken{own, other}, nil)
+		tr.On("UpdateAPIToken", mock.Anything, other).Return(nil)
+
+		ctx := WithAPIToken(WithUser(t.Context(), user), own)
+		require.NoError(t, app.ChangePassword(ctx, domain.ValidPassword, newPassword))
+		assert.True(t, own.IsActive())
+		assert.False(t, other.IsActive())
+		mock.AssertExpectationsForObjects(t, ur, sr, tr)
+	})
+
+	t.Run("lockout after too many wrong passwords", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		user := mustNewUser(t)
+		ctx := WithUser(t.Context(), user)
+
+		for range DefaultMaxLoginAttempts {
+			err := app.ChangePassword(ctx, "wrong", newPassword)
+			require.ErrorIs(t, err, ErrWrongPassword)
+		}
+		err := app.ChangePassword(ctx, domain.ValidPassword, newPassword)
+		require.ErrorIs(t, err, ErrLoginLocked)
+		ur.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+
+		_, err = app.Login(t.Context(), LoginCmd{Name: user.Name(), Password: domain.ValidPassword, ClientIP: "10.0.0.1"})
+		require.ErrorIs(t, err, ErrLoginLocked)
+	})
+
+	t.Run("wrong current password", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		user := mustNewUser(t)
+
+		err := app.ChangePassword(WithUser(t.Context(), user), "wrong", newPassword)
+		require.ErrorIs(t, err, ErrWrongPassword)
+		ur.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("single sign-on user", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		for _, hash := range [][]byte{nil, []byte("$argon2id$v=19$m=1024,t=0,p=0$c2FsdA$a2V5")} {
+			user := domain.NewUserBuilder().WithDefault().PassHash(hash).Build()
+
+			err := app.ChangePassword(WithUser(t.Context(), &user), "", newPassword)
+			require.ErrorIs(t, err, ErrWrongPassword)
+		}
+		ur.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		err := app.ChangePassword(t.Context(), domain.ValidPassword, newPassword)
+		require.ErrorIs(t, err, ErrNotAuthenticated)
+	})
+}
+
+func TestApp_CreatePasswordReset(t *testing.T) {
+	t.Parallel()
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		rr := app.PasswordResetRepo.(*mockPasswordResetRepo)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+		user := mustNewUser(t)
+		ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+		rr.On("CreatePasswordReset", mock.Anything, mock.Anything).Return(nil)
+
+		res, err := app.CreatePasswordReset(WithUser(t.Context(), admin), user.ID())
+		require.NoError(t, err)
+		assert.Equal(t, user.ID(), res.Reset.UserID())
+		assert.Equal(t, admin.ID(), res.Reset.CreatedBy())
+		assert.Equal(t, domain.HashToken(res.Token), res.Reset.TokenHash())
+	})
+
+	t.Run("member", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		_, err := app.CreatePasswordReset(WithUser(t.Context(), mustNewUser(t)), domain.NewUserID())
+		require.ErrorIs(t, err, domain.ErrPermissionDenied)
+	})
+}
+
+func TestApp_ResetPassword(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		reset       *domain.PasswordReset
+		resetErr    error
+		password    string
+		expectedErr error
+	}{
+		{name: "ok", reset: ptr(domain.NewPasswordResetBuilder().ExpiresAt(future).Build()), password: newPassword},
+		{name: "unknown token", resetErr: domain.ErrPasswordResetNotFound, password: newPassword, expectedErr: ErrInvalidPasswordReset},
+		{name: "expired", reset: ptr(domain.NewPasswordResetBuilder().ExpiresAt(past).Build()), password: newPassword, expectedErr: ErrInvalidPasswordReset},
+		{name: "used", reset: ptr(domain.NewPasswordResetBuilder().ExpiresAt(future).UsedAt(&past).Build()), password: newPassword, expectedErr: ErrInvalidPasswordReset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ur, sr := newTestApp(t)
+			rr := app.PasswordResetRepo.(*mockPasswordResetRepo)
+			user := mustNewUser(t)
+			if tt.reset != nil {
+				*tt.reset = domain.NewPasswordResetBuilder().
+					UserID(user.ID()).ExpiresAt(tt.reset.ExpiresAt()).UsedAt(tt.reset.UsedAt()).Build()
+			}
+			rr.On("GetPasswordResetByTokenHash", mock.Anything, domain.HashToken("token")).Return(tt.reset, tt.resetErr)
+			rr.On("UpdatePasswordReset", mock.Anything, tt.reset).Return(nil)
+			ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+			ur.On("UpdateUser", mock.Anything, user).Return(nil)
+			sr.On("RevokeUserSessions", mock.Anything, user.ID()).Return(nil)
+
+			err := app.ResetPassword(t.Context(), "token", tt.password)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				ur.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, user.ComparePassword(newPassword))
+			assert.False(t, tt.reset.IsUsable(), "token is single use")
+			sr.AssertCalled(t, "RevokeUserSessions", mock.Anything, user.ID())
+		})
+	}
+}
+
+func TestApp_Login_rehash(t *testing.T) {
+	t.Parallel()
+
+	app, ur, sr := newTestApp(t)
+	app.PasswordParams = domain.PasswordParams{Algorithm: domain.Argon2id, Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1}
+	user := mustNewUser(t)
+	ur.On("GetUserByName", mock.Anything, user.Name()).Return(user, nil)
+	ur.On("UpdateUser", mock.Anything, user).Return(nil)
+	sr.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+
+	_, err := app.Login(t.Context(), LoginCmd{Name: user.Name(), Password: domain.ValidPassword})
+	require.NoError(t, err)
+	ur.AssertCalled(t, "UpdateUser", mock.Anything, user)
+	assert.False(t, domain.NeedsRehash(user.PassHash(), app.PasswordParams))
+	require.NoError(t, user.ComparePassword(domain.ValidPassword))
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+const (
+	oidcStateBytes    = 16
+	oidcVerifierBytes = 32
+)
+
+var (
+	ErrSSODisabled     = fmt.Errorf("single sign-on is not configured: %w", domain.ErrNotFound)
+	ErrInvalidSSOState = fmt.Errorf("invalid single sign-on state: %w", domain.ErrUnauthorized)
+)
+
+// OIDCProvider runs the OpenID Connect authorization code flow with PKCE.
+type OIDCProvider interface {
+	// AuthCodeURL returns the provider's authorization URL for the state, PKCE verifier and nonce.
+	AuthCodeURL(state, verifier, nonce string) string
+	// Exchange redeems the code, verifies the ID token and its nonce and returns its claims.
+	Exchange(ctx context.Context, code, verifier, nonce string) (vo.SSOClaims, error)
+}
+
+type IdentityRepo interface {
+	// GetUserByIdentity returns domain.ErrUserNotFound if no user is linked to the identity.
+	GetUserByIdentity(context.Context, domain.ExternalIdentity) (*domain.User, error)
+	LinkIdentity(context.Context, domain.UserID, domain.ExternalIdentity) error
+}
+
+// SSOConfig controls how users signing in through OIDC or a trusted header are provisioned.
+type SSOConfig struct {
+	// DefaultRole is given to users in none of GroupRoles, it defaults to domain.RoleMember.
+	DefaultRole domain.Role
+	// GroupRoles maps provider groups to roles, a user in several groups gets the most powerful role.
+	// When set, roles are synced from groups on every sign-in, so the provider stays the source of truth.
+	GroupRoles map[string]domain.Role
+	// LinkExistingUsers links a new identity to a local user with the same name instead of failing.
+	// Only enable it if the provider controls user names.
+	LinkExistingUsers bool
+}
+
+// roleRank orders roles from the least to the most powerful.
+var roleRank = []domain.Role{domain.RoleGuest, domain.RoleMember, domain.RoleAdmin}
+
+func (c SSOConfig) role(groups []string) domain.Role {
+	role := c.DefaultRole
+	if role == "" {
+		role = domain.RoleMember
+	}
+
+	best := -1
+	for _, g := range groups {
+		if r, ok := c.GroupRoles[g]; ok {
+			best = max(best, slices.Index(roleRank, r))
+		}
+	}
+	if best >= 0 {
+		return roleRank[best]
+	}
+	return role
+}
+
+type OIDCLoginStart struct {
+	// URL is where to redirect the browser.
+	URL string
+	// State, Verifier and Nonce are kept by the client, e.g. in a short-lived cookie,
+	// and passed back to CompleteOIDCLogin.
+	State    string
+	Verifier string
+	Nonce    string
+}
+
+// BeginOIDCLogin starts the authorization code flow.
+func (a *App) BeginOIDCLogin() (OIDCLoginStart, error) {
+	const op = errorx.Op("auth.App.BeginOIDCLogin")
+
+	if a.OIDC == nil {
+		return OIDCLoginStart{}, op.Wrap(ErrSSODisabled)
+	}
+
+	state, err := domain.NewOpaqueToken(oidcStateBytes)
+	if err != nil {
+		return OIDCLoginStart{}, op.Wrap(err)
+	}
+	nonce, err := domain.NewOpaqueToken(oidcStateBytes)
+	if err != nil {
+		return OIDCLoginStart{}, op.Wrap(err)
+	}
+	verifier, err := domain.NewOpaqueToken(oidcVerifierBytes)
+	if err != nil {
+		return OIDCLoginStart{}, op.Wrap(err)
+	}
+
+	start := OIDCLoginStart{State: state, Verifier: verifier, Nonce: nonce}
+	start.URL = a.OIDC.AuthCodeURL(start.State, start.Verifier, start.Nonce)
+
+	return start, nil
+}
+
+type CompleteOIDCLoginCmd struct {
+	Code string
+	// State is the state returned by the provider, it must equal Expected.State.
+	State     string
+	Expected  OIDCLoginStart
+	UserAgent string
+}
+
+// CompleteOIDCLogin redeems the authorization code, provisions the user and starts a session.
+func (a *App) CompleteOIDCLogin(ctx context.Context, cmd CompleteOIDCLoginCmd) (LoginResult, error) {
+	const op = errorx.Op("auth.App.CompleteOIDCLogin")
+
+	if a.OIDC == nil {
+		return LoginResult{}, op.Wrap(ErrSSODisabled)
+	}
+	if cmd.State == "" || cmd.State != cmd.Expected.State {
+		return LoginResult{}, op.Wrap(ErrInvalidSSOState)
+	}
+
+	claims, err := a.OIDC.Exchange(ctx, cmd.Code, cmd.Expected.Verifier, cmd.Expected.Nonce)
+	if err != nil {
+		return LoginResult{}, op.Wrap(fmt.Errorf("%w: %w", ErrInvalidSSOState, err))
+	}
+
+	user, err := a.provisionUser(ctx, claims)
+	if err != nil {
+		return LoginResult{}, op.Wrap(err)
+	}
+
+	session, token, err := domain.NewSession(user.ID(), cmd.UserAgent, a.sessionTTL())
+	if err != nil {
+		return LoginResult{}, op.Wrap(err)
+	}
+	if err := a.SessionRepo.CreateSession(ctx, session); err != nil {
+		return LoginResult{}, op.Wrap(err)
+	}
+
+	return LoginResult{User: user, Session: session, Token: token}, nil
+}
+
+// AuthenticateTrustedHeader returns the user a trusted reverse proxy vouches for, provisioning
+// it on first sight. Callers must make sure the request came from the proxy.
+func (a *App) AuthenticateTrustedHeader(ctx context.Context, name string, groups []string) (*domain.User, error) {
+	const op = errorx.Op("auth.App.AuthenticateTrustedHeader")
+
+	user, err := a.provisionUser(ctx, vo.SSOClaims{
+		Issuer:   domain.TrustedHeaderIssuer,
+		Subject:  name,
+		Username: name,
+		Groups:   groups,
+	})
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return user, nil
+}
+
+// provisionUser returns the user linked to the claims' identity, creating or linking one on first sign-in.
+func (a *App) provisionUser(ctx context.Context, claims vo.SSOClaims) (*domain.User, error) {
+	identity := domain.ExternalIdentity{Issuer: claims.Issuer, Subject: claims.Subject}
+	role := a.SSO.role(claims.Groups)
+	syncRole := len(a.SSO.GroupRoles) > 0
+
+	name := claims.Username
+	if name == "" {
+		name = claims.Email
+	}
+
+	var user *domain.User
+	err := a.Session.Transaction(ctx, func(ctx context.Context) (err error) {
+		user, err = a.IdentityRepo.GetUserByIdentity(ctx, identity)
+		if err == nil {
+			if syncRole && user.Role() != role {
+				if err := user.ChangeRole(role); err != nil {
+					return err
+				}
+				return a.UserRepo.UpdateUser(ctx, user)
+			}
+			return nil
+		} else if !errors.Is(err, domain.ErrUserNotFound) {
+			return err
+		}
+
+		user, err = a.UserRepo.GetUserByName(ctx, name)
+		switch {
+		case err == nil && !a.SSO.LinkExistingUsers:
+			return ErrUserNameTaken
+		case err == nil:
+			if syncRole && user.Role() != role {
+				if err := user.ChangeRole(role); err != nil {
+					return err
+				}
+				if err := a.UserRepo.UpdateUser(ctx, user); err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, domain.ErrUserNotFound):
+			user, err = domain.NewExternalUser(domain.NewUserID(), name, role)
+			if err != nil {
+				return err
+			}
+			if err := a.UserRepo.CreateUser(ctx, user); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return a.IdentityRepo.LinkIdentity(ctx, user.ID(), identity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
+)
+
+type mockOIDCProvider struct{ mock.Mock }
+
+func (m *mockOIDCProvider) AuthCodeURL(state, verifier, nonce string) string {
+	return m.Called(state, verifier, nonce).String(0)
+}
+
+func (m *mockOIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (vo.SSOClaims, error) {
+	args := m.Called(ctx, code, verifier, nonce)
+	c, _ := args.Get(0).(vo.SSOClaims)
+	return c, args.Error(1)
+}
+
+var testClaims = vo.SSOClaims{Issuer: "https://idp.example.com", Subject: "sub-1", Username: "alice", Groups: []string{"family"}}
+
+func testIdentity() domain.ExternalIdentity {
+	return domain.ExternalIdentity{Issuer: testClaims.Issuer, Subject: testClaims.Subject}
+}
+
+func TestSSOConfig_role(t *testing.T) {
+	t.Parallel()
+
+	cfg := SSOConfig{GroupRoles: map[string]domain.Role{
+		"admins": domain.RoleAdmin,
+		"kids":   domain.RoleGuest,
+		"family": domain.RoleMember,
+	}}
+
+	tests := []struct {
+		name     string
+		cfg      SSOConfig
+		groups   []string
+		expected domain.Role
+	}{
+		{name: "no groups", cfg: cfg, expected: domain.RoleMember},
+		{name: "unmapped groups", cfg: cfg, groups: []string{"other"}, expected: domain.RoleMember},
+		{name: "custom default", cfg: SSOConfig{DefaultRole: domain.RoleGuest}, expected: domain.RoleGuest},
+		{name: "single group", cfg: cfg, groups: []string{"kids"}, expected: domain.RoleGuest},
+		{name: "most powerful wins", cfg: cfg, groups: []string{"kids", "admins", "family"}, expected: domain.RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tt.cfg.role(tt.groups))
+		})
+	}
+}
+
+func TestApp_BeginOIDCLogin(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+		p := new(mockOIDCProvider)
+		app.OIDC = p
+		p.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything).Return("https://idp.example.com/authorize")
+
+		start, err := app.BeginOIDCLogin()
+		require.NoError(t, err)
+		assert.Equal(t, "https://idp.example.com/authorize", start.URL)
+		assert.NotEmpty(t, start.State)
+		assert.NotEmpty(t, start.Nonce)
+		assert.GreaterOrEqual(t, len(start.Verifier), 43, "RFC 7636 minimum verifier length")
+		p.AssertCalled(t, "AuthCodeURL", start.State, start.Verifier, start.Nonce)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		_, err := app.BeginOIDCLogin()
+		require.ErrorIs(t, err, ErrSSODisabled)
+	})
+}
+
+func TestApp_CompleteOIDCLogin(t *testing.T) {
+	t.Parallel()
+
+	expected := OIDCLoginStart{State: "state", Verifier: "verifier", Nonce: "nonce"}
+
+	t.Run("provisions new user", func(t *testing.T) {
+		t.Parallel()
+		app, ur, sr := newTestApp(t)
+		ir := app.IdentityRepo.(*mockIdentityRepo)
+		p := new(mockOIDCProvider)
+		app.OIDC = p
+		app.SSO = SSOConfig{GroupRoles: map[string]domain.Role{"family": domain.RoleGuest}}
+
+		p.On("Exchange", mock.Anything, "code", "verifier", "nonce").Return(testClaims, nil)
+		ir.On("GetUserByIdentity", mock.Anything, testIdentity()).Return(nil, domain.ErrUserNotFound)
+		ur.On("GetUserByName", mock.Anything, "alice").Return(nil, domain.ErrUserNotFound)
+		ur.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
+		ir.On("LinkIdentity", mock.Anything, mock.Anything, testIdentity()).Return(nil)
+		sr.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+
+		res, err := app.CompleteOIDCLogin(t.Context(), CompleteOIDCLoginCmd{Code: "code", State: "state", Expected: expected})
+		require.NoError(t, err)
+		assert.Equal(t, "alice", res.User.Name())
+		assert.Equal(t, domain.RoleGuest, res.User.Role())
+		assert.False(t, res.User.HasPassword())
+		assert.Equal(t, res.User.ID(), res.Session.UserID())
+		assert.NotEmpty(t, res.Token)
+		ir.AssertCalled(t, "LinkIdentity", mock.Anything, res.User.ID(), testIdentity())
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+		p := new(mockOIDCProvider)
+		app.OIDC = p
+
+		_, err := app.CompleteOIDCLogin(t.Context(), CompleteOIDCLoginCmd{Code: "code", State: "forged", Expected: expected})
+		require.ErrorIs(t, err, ErrInvalidSSOState)
+		require.ErrorIs(t, err, domain.ErrUnauthorized)
+		p.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("exchange fails", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+		p := new(mockOIDCProvider)
+		app.OIDC = p
+		p.On("Exchange", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("invalid_grant"))
+
+		_, err := app.CompleteOIDCLogin(t.Context(), CompleteOIDCLoginCmd{Code: "code", State: "state", Expected: expected})
+		require.ErrorIs(t, err, ErrInvalidSSOState)
+	})
+}
+
+func TestApp_provisionUser(t *testing.T) {
+	t.Parallel()
+
+	groupRoles := map[string]domain.Role{"family": domain.RoleAdmin}
+
+	t.Run("known identity syncs role", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ir := app.IdentityRepo.(*mockIdentityRepo)
+		app.SSO = SSOConfig{GroupRoles: groupRoles}
+		user := mustNewUser(t)
+		ir.On("GetUserByIdentity", mock.Anything, testIdentity()).Return(user, nil)
+		ur.On("UpdateUser", mock.Anything, user).Return(nil)
+
+		got, err := app.provisionUser(t.Context(), testClaims)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleAdmin, got.Role())
+		ir.AssertNotCalled(t, "LinkIdentity", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("known identity keeps role without group mapping", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ir := app.IdentityRepo.(*mockIdentityRepo)
+		user := mustNewUserWithRole(t, domain.RoleAdmin)
+		ir.On("GetUserByIdentity", mock.Anything, testIdentity()).Return(user, nil)
+
+		got, err := app.provisionUser(t.Context(), testClaims)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleAdmin, got.Role())
+		ur.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("local user with the same name", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ir := app.IdentityRepo.(*mockIdentityRepo)
+		ir.On("GetUserByIdentity", mock.Anything, testIdentity()).Return(nil, domain.ErrUserNotFound)
+		ur.On("GetUserByName", mock.Anything, "alice").Return(mustNewUser(t), nil)
+
+		_, err := app.provisionUser(t.Context(), testClaims)
+		require.ErrorIs(t, err, ErrUserNameTaken)
+		ir.AssertNotCalled(t, "LinkIdentity", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("links local user when allowed", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		ir := app.IdentityRepo.(*mockIdentityRepo)
+		app.SSO = SSOConfig{LinkExistingUsers: true}
+		user := mustNewUser(t)
+		ir.On("GetUserByIdentity", mock.Anything, testIdentity()).Return(nil, domain.ErrUserNotFound)
+		ur.On("GetUserByName", mock.Anything, "alice").Return(user, nil)
+		ir.On("LinkIdentity", mock.Anything, user.ID(), testIdentity()).Return(nil)
+
+		got, err := app.provisionUser(t.Context(), testClaims)
+		require.NoError(t, err)
+		assert.Same(t, user, got)
+		assert.True(t, got.HasPassword(), "local password keeps working")
+		ir.AssertExpectations(t)
+	})
+}
+
+func TestApp_AuthenticateTrustedHeader(t *testing.T) {
+	t.Parallel()
+
+	app, _, _ := newTestApp(t)
+	ir := app.IdentityRepo.(*mockIdentityRepo)
+	user := mustNewUser(t)
+	identity := domain.ExternalIdentity{Issuer: domain.TrustedHeaderIssuer, Subject: "alice"}
+	ir.On("GetUserByIdentity", mock.Anything, identity).Return(user, nil)
+
+	got, err := app.AuthenticateTrustedHeader(t.Context(), "alice", nil)
+	require.NoError(t, err)
+	assert.Same(t, user, got)
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+// ListUsers returns every user, it requires domain.PermManageUsers.
+func (a *App) ListUsers(ctx context.Context) ([]*domain.User, error) {
+	const op = errorx.Op("auth.App.ListUsers")
+
+	if _, err := Authorize(ctx, domain.PermManageUsers); err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	users, err := a.UserRepo.ListUsers(ctx)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return users, nil
+}
+
+// ChangeUserRole sets the role of another user, it requires domain.PermManageUsers.
+// Admins can not change their own role, so there is always at least one admin left.
+func (a *App) ChangeUserRole(ctx context.Context, id domain.UserID, role domain.Role) (*domain.User, error) {
+	const op = errorx.Op("auth.App.ChangeUserRole")
+
+	actor, err := Authorize(ctx, domain.PermManageUsers)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	if actor.ID() == id {
+		return nil, op.Wrap(ErrChangeOwnRole)
+	}
+
+	var user *domain.User
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		user, err = a.UserRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := user.ChangeRole(role); err != nil {
+			return err
+		}
+		return a.UserRepo.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return user, nil
+}
+
+// SetUserRestrictions replaces the content restrictions of a user, it requires domain.PermManageUsers.
+func (a *App) SetUserRestrictions(ctx context.Context, id domain.UserID, r domain.ContentRestrictions) (*domain.User, error) {
+	const op = errorx.Op("auth.App.SetUserRestrictions")
+
+	if _, err := Authorize(ctx, domain.PermManageUsers); err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	var user *domain.User
+	err := a.Session.Transaction(ctx, func(ctx context.Context) (err error) {
+		user, err = a.UserRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := user.SetRestrictions(r); err != nil {
+			return err
+		}
+		return a.UserRepo.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func TestApp_ListUsers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+		ur.On("ListUsers", mock.Anything).Return([]*domain.User{admin}, nil)
+
+		users, err := app.ListUsers(WithUser(t.Context(), admin))
+		require.NoError(t, err)
+		assert.Equal(t, []*domain.User{admin}, users)
+	})
+
+	t.Run("member", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+
+		_, err := app.ListUsers(WithUser(t.Context(), mustNewUser(t)))
+		require.ErrorIs(t, err, domain.ErrPermissionDenied)
+		ur.AssertNotCalled(t, "ListUsers", mock.Anything)
+	})
+}
+
+func TestApp_ChangeUserRole(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+		user := mustNewUser(t)
+		ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+		ur.On("UpdateUser", mock.Anything, user).Return(nil)
+
+		got, err := app.ChangeUserRole(WithUser(t.Context(), admin), user.ID(), domain.RoleGuest)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleGuest, got.Role())
+		ur.AssertExpectations(t)
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+		user := mustNewUser(t)
+		ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+
+		_, err := app.ChangeUserRole(WithUser(t.Context(), admin), user.ID(), "root")
+		vx.AssertValidationErrors(t, err, v.Errors{"role": v.ErrInInvalid})
+		ur.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("own role", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+
+		_, err := app.ChangeUserRole(WithUser(t.Context(), admin), admin.ID(), domain.RoleMember)
+		require.ErrorIs(t, err, ErrChangeOwnRole)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+		member := mustNewUser(t)
+
+		_, err := app.ChangeUserRole(WithUser(t.Context(), member), domain.NewUserID(), domain.RoleAdmin)
+		require.ErrorIs(t, err, domain.ErrPermissionDenied)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+		id := domain.NewUserID()
+		ur.On("GetUserByID", mock.Anything, id).Return(nil, domain.ErrUserNotFound)
+
+		_, err := app.ChangeUserRole(WithUser(t.Context(), admin), id, domain.RoleAdmin)
+		require.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestApp_SetUserRestrictions(t *testing.T) {
+	t.Parallel()
+
+	kids := domain.ContentRestrictions{AllowTypes: []domain.LibraryItemType{domain.Comic}, MaxAge: 10}
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+		user := mustNewUserWithRole(t, domain.RoleGuest)
+		ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+		ur.On("UpdateUser", mock.Anything, user).Return(nil)
+
+		got, err := app.SetUserRestrictions(WithUser(t.Context(), admin), user.ID(), kids)
+		require.NoError(t, err)
+		assert.Equal(t, kids, got.Restrictions())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		app, ur, _ := newTestApp(t)
+		admin := mustNewUserWithRole(t, domain.RoleAdmin)
+		user := mustNewUser(t)
+		ur.On("GetUserByID", mock.Anything, user.ID()).Return(user, nil)
+
+		_, err := app.SetUserRestrictions(WithUser(t.Context(), admin), user.ID(), domain.ContentRestrictions{MaxAge: 200})
+		vx.AssertValidationErrors(t, err, v.Errors{"maxAge": v.ErrMaxLessEqualThanRequired})
+		ur.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+		member := mustNewUser(t)
+
+		_, err := app.SetUserRestrictions(WithUser(t.Context(), member), member.ID(), domain.ContentRestrictions{})
+		require.ErrorIs(t, err, domain.ErrPermissionDenied)
+	})
+}
@@ -0,0 +1,148 @@
+package content
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	"github.com/ARUMANDESU/goread/backend/pkg/imagex"
+)
+
+// MaxPageWidth bounds the width clients may ask pages to be scaled to, pages are never scaled up.
+const MaxPageWidth = 4096
+
+type LibraryItemRepo interface {
+	// GetLibraryItem returns domain.ErrLibraryItemNotFound if there is no item with the given id.
+	GetLibraryItem(context.Context, domain.LibraryItemID) (*domain.LibraryItem, error)
+}
+
+type PageExtractor interface {
+	// Page returns the encoded image of the 0-based page n of the file at path,
+	// domain.ErrPageNotFound if there is no such page.
+	Page(ctx context.Context, path string, n int) ([]byte, error)
+}
+
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Add(key string, value []byte)
+}
+
+type App struct {
+	LibraryItemRepo LibraryItemRepo
+	AuthorRepo      AuthorRepo
+	Files           FileStore
+	Pages           PageExtractor
+	// PageCache keeps scaled pages, readers often go back a few pages.
+	PageCache Cache
+	Covers    CoverExtractor
+	// CoverCache keeps generated covers for good, its keys are content addressed by the file hash.
+	CoverCache Cache
+	EPUBs      EPUBResources
+	// EPUBValidator is usually the same as EPUBs.
+	EPUBValidator EPUBValidator
+	Converter     BookConverter
+	// ConversionCache keeps FB2 and text files converted to EPUB for good, its keys are content
+	// addressed by the file hash and the metadata of the item.
+	ConversionCache Cache
+}
+
+// NewApp returns app if none of its dependencies is nil.
+func NewApp(app *App) (*App, error) {
+	const op = errorx.Op("content.NewApp")
+
+	err := v.Errors{
+		"LibraryItemRepo": v.Validate(app.LibraryItemRepo, v.NotNil),
+		"AuthorRepo":      v.Validate(app.AuthorRepo, v.NotNil),
+		"Files":           v.Validate(app.Files, v.NotNil),
+		"Pages":           v.Validate(app.Pages, v.NotNil),
+		"PageCache":       v.Validate(app.PageCache, v.NotNil),
+		"Covers":          v.Validate(app.Covers, v.NotNil),
+		"CoverCache":      v.Validate(app.CoverCache, v.NotNil),
+		"EPUBs":           v.Validate(app.EPUBs, v.NotNil),
+		"EPUBValidator":   v.Validate(app.EPUBValidator, v.NotNil),
+		"Converter":       v.Validate(app.Converter, v.NotNil),
+		"ConversionCache": v.Validate(app.ConversionCache, v.NotNil),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return app, nil
+}
+
+type Image struct {
+	Data      []byte
+	MediaType string
+	// ETag identifies Data, it is empty for images that are not cached for long.
+	ETag string
+}
+
+type GetPageQuery struct {
+	ItemID domain.LibraryItemID
+	// Page is 0-based, as in OPDS-PSE.
+	Page int
+	// MaxWidth is the width wider pages are scaled down to, zero keeps the original size.
+	MaxWidth int
+}
+
+// GetPage returns a page of a PDF or comic archive as an image, for readers that stream pages
+// instead of downloading the whole file.
+func (a *App) GetPage(ctx context.Context, q GetPageQuery) (Image, error) {
+	const op = errorx.Op("content.App.GetPage")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+
+	err = v.Errors{
+		"page":     v.Validate(q.Page, v.Min(0)),
+		"maxWidth": v.Validate(q.MaxWidth, v.Min(0), v.Max(MaxPageWidth)),
+	}.Filter()
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+
+	item, err := a.visibleItem(ctx, user, q.ItemID)
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+	if !item.Format().HasPages() || (item.PageCount() > 0 && q.Page >= item.PageCount()) {
+		return Image{}, op.Wrap(domain.ErrPageNotFound)
+	}
+
+	// The file hash is part of the key, so a replaced file does not serve stale pages.
+	key := fmt.Sprintf("page:%s:%x:%d:%d", item.ID(), item.Hash(), q.Page, q.MaxWidth)
+	if data, ok := a.PageCache.Get(key); ok {
+		return Image{Data: data, MediaType: http.DetectContentType(data)}, nil
+	}
+
+	data, err := a.Pages.Page(ctx, item.Path(), q.Page)
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+	data, mediaType, err := imagex.Resize(data, q.MaxWidth, 0)
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+	a.PageCache.Add(key, data)
+
+	return Image{Data: data, MediaType: mediaType}, nil
+}
+
+// visibleItem returns domain.ErrLibraryItemNotFound for deleted items and items hidden from
+// the user by their restrictions.
+func (a *App) visibleItem(ctx context.Context, user *domain.User, id domain.LibraryItemID) (*domain.LibraryItem, error) {
+	item, err := a.LibraryItemRepo.GetLibraryItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.IsDeleted() || !user.Restrictions().Permits(item.ContentAttrs()) {
+		return nil, domain.ErrLibraryItemNotFound
+	}
+	return item, nil
+}
@@ -0,0 +1,240 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/cachex"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type mockLibraryItemRepo struct{ mock.Mock }
+
+func (m *mockLibraryItemRepo) GetLibraryItem(ctx context.Context, id domain.LibraryItemID) (*domain.LibraryItem, error) {
+	args := m.Called(ctx, id)
+	item, _ := args.Get(0).(*domain.LibraryItem)
+	return item, args.Error(1)
+}
+
+type mockPageExtractor struct{ mock.Mock }
+
+func (m *mockPageExtractor) Page(ctx context.Context, path string, n int) ([]byte, error) {
+	args := m.Called(ctx, path, n)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+type mockAuthorRepo struct{ mock.Mock }
+
+func (m *mockAuthorRepo) GetAuthors(ctx context.Context, ids []domain.AuthorID) ([]domain.Author, error) {
+	args := m.Called(ctx, ids)
+	authors, _ := args.Get(0).([]domain.Author)
+	return authors, args.Error(1)
+}
+
+type mockCoverExtractor struct{ mock.Mock }
+
+func (m *mockCoverExtractor) Cover(ctx context.Context, path string) ([]byte, error) {
+	args := m.Called(ctx, path)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+type mockBookConverter struct{ mock.Mock }
+
+func (m *mockBookConverter) Book(ctx context.Context, path string) (epubx.Book, error) {
+	args := m.Called(ctx, path)
+	book, _ := args.Get(0).(epubx.Book)
+	return book, args.Error(1)
+}
+
+// mapFileStore serves files from a fstest.MapFS.
+type mapFileStore struct{ fs fstest.MapFS }
+
+func (s mapFileStore) Open(_ context.Context, path string) (fs.File, error) {
+	return s.fs.Open(path)
+}
+
+// --- Helpers ---
+
+func newTestApp(t *testing.T) (*App, *mockLibraryItemRepo, *mockPageExtractor) {
+	t.Helper()
+	ir := new(mockLibraryItemRepo)
+	pe := new(mockPageExtractor)
+	return &App{LibraryItemRepo: ir, Pages: pe, PageCache: cachex.NewLRU(1 << 20)}, ir, pe
+}
+
+func TestNewApp(t *testing.T) {
+	t.Parallel()
+
+	full := func() *App {
+		return &App{
+			LibraryItemRepo: new(mockLibraryItemRepo),
+			AuthorRepo:      new(mockAuthorRepo),
+			Files:           mapFileStore{},
+			Pages:           new(mockPageExtractor),
+			PageCache:       cachex.NewLRU(1 << 20),
+			Covers:          new(mockCoverExtractor),
+			CoverCache:      cachex.NewLRU(1 << 20),
+			EPUBs:           new(mockEPUBResources),
+			EPUBValidator:   new(mockEPUBValidator),
+			Converter:       new(mockBookConverter),
+			ConversionCache: cachex.NewLRU(1 << 20),
+		}
+	}
+
+	app := full()
+	got, err := NewApp(app)
+	require.NoError(t, err)
+	assert.Same(t, app, got)
+
+	app = full()
+	app.PageCache = nil
+	_, err = NewApp(app)
+	vx.AssertValidationErrors(t, err, v.Errors{"PageCache": v.ErrNotNilRequired})
+
+	app = full()
+	app.Pages = nil
+	_, err = NewApp(app)
+	vx.AssertValidationErrors(t, err, v.Errors{"Pages": v.ErrNotNilRequired})
+}
+
+func newItem(path string, pages int, rating domain.AgeRating) *domain.LibraryItem {
+	item := domain.NewLibraryItemBuilder().
+		Id(domain.NewLibraryItemID()).
+		ItemType(domain.Comic).
+		Path(path).
+		Hash([]byte("hash")).
+		PageCount(pages).
+		AgeRating(rating).
+		Build()
+	return &item
+}
+
+func userContext(t *testing.T, r domain.ContentRestrictions) context.Context {
+	t.Helper()
+	return roleContext(t, domain.RoleMember, r)
+}
+
+func roleContext(t *testing.T, role domain.Role, r domain.ContentRestrictions) context.Context {
+	t.Helper()
+	user := domain.NewUserBuilder().WithDefault().Id(domain.NewUserID()).Role(role).Build()
+	require.NoError(t, user.SetRestrictions(r))
+	return auth.WithUser(t.Context(), &user)
+}
+
+func pngPage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestApp_GetPage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("scaled and cached", func(t *testing.T) {
+		t.Parallel()
+		app, ir, pe := newTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Comics/plastic.cbz", 3, "")
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		pe.On("Page", mock.Anything, "Comics/plastic.cbz", 2).Return(pngPage(t, 1600, 2400), nil).Once()
+
+		for range 2 {
+			img, err := app.GetPage(ctx, GetPageQuery{ItemID: item.ID(), Page: 2, MaxWidth: 800})
+			require.NoError(t, err)
+			assert.Equal(t, "image/jpeg", img.MediaType)
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(img.Data))
+			require.NoError(t, err)
+			assert.Equal(t, 800, cfg.Width)
+		}
+		pe.AssertExpectations(t)
+	})
+
+	t.Run("original size", func(t *testing.T) {
+		t.Parallel()
+		app, ir, pe := newTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Comics/plastic.cbz", 3, "")
+		page := pngPage(t, 100, 150)
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		pe.On("Page", mock.Anything, "Comics/plastic.cbz", 0).Return(page, nil)
+
+		img, err := app.GetPage(ctx, GetPageQuery{ItemID: item.ID()})
+		require.NoError(t, err)
+		assert.Equal(t, Image{Data: page, MediaType: "image/png"}, img)
+	})
+
+	tests := []struct {
+		name         string
+		item         *domain.LibraryItem
+		query        func(id domain.LibraryItemID) GetPageQuery
+		restrictions domain.ContentRestrictions
+		expectedErr  error
+	}{
+		{
+			name:        "past the last page",
+			item:        newItem("Comics/plastic.cbz", 3, ""),
+			query:       func(id domain.LibraryItemID) GetPageQuery { return GetPageQuery{ItemID: id, Page: 3} },
+			expectedErr: domain.ErrPageNotFound,
+		},
+		{
+			name:        "format without pages",
+			item:        newItem("Books/book.epub", 0, ""),
+			query:       func(id domain.LibraryItemID) GetPageQuery { return GetPageQuery{ItemID: id} },
+			expectedErr: domain.ErrPageNotFound,
+		},
+		{
+			name:         "restricted item looks missing",
+			item:         newItem("Comics/plastic.cbz", 3, domain.AgeRatingAdultsOnly18),
+			query:        func(id domain.LibraryItemID) GetPageQuery { return GetPageQuery{ItemID: id} },
+			restrictions: domain.ContentRestrictions{MaxAge: 12},
+			expectedErr:  domain.ErrLibraryItemNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ir, pe := newTestApp(t)
+			ctx := userContext(t, tt.restrictions)
+			ir.On("GetLibraryItem", mock.Anything, tt.item.ID()).Return(tt.item, nil)
+
+			_, err := app.GetPage(ctx, tt.query(tt.item.ID()))
+			require.ErrorIs(t, err, tt.expectedErr)
+			pe.AssertNotCalled(t, "Page", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("too wide", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+
+		_, err := app.GetPage(ctx, GetPageQuery{ItemID: domain.NewLibraryItemID(), MaxWidth: MaxPageWidth + 1})
+		vx.AssertValidationErrors(t, err, v.Errors{"maxWidth": v.ErrMaxLessEqualThanRequired})
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		_, err := app.GetPage(t.Context(), GetPageQuery{ItemID: domain.NewLibraryItemID()})
+		require.ErrorIs(t, err, domain.ErrUnauthorized)
+	})
+}
@@ -0,0 +1,144 @@
+package content
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	"github.com/ARUMANDESU/goread/backend/pkg/imagex"
+)
+
+// CoverSize is one of the sizes covers are generated in.
+type CoverSize string
+
+const (
+	CoverSizeFull      CoverSize = "full"
+	CoverSizeMedium    CoverSize = "medium"
+	CoverSizeThumbnail CoverSize = "thumbnail"
+)
+
+var CoverSizes = []any{CoverSizeFull, CoverSizeMedium, CoverSizeThumbnail}
+
+// coverBounds are the boxes covers are scaled down to fit into, as width and height.
+var coverBounds = map[CoverSize][2]int{
+	CoverSizeFull:      {1200, 1800},
+	CoverSizeMedium:    {400, 600},
+	CoverSizeThumbnail: {160, 240},
+}
+
+// CoverFormat is the image format covers are encoded in.
+type CoverFormat string
+
+const (
+	CoverFormatJPEG CoverFormat = "jpeg"
+	// CoverFormatWebP is lossless, so it keeps transparency but is only smaller than JPEG for
+	// simple covers and thumbnails.
+	CoverFormatWebP CoverFormat = "webp"
+)
+
+var CoverFormats = []any{CoverFormatJPEG, CoverFormatWebP}
+
+// coverEncodings are the encoders, media types and cache key extensions of the cover formats.
+var coverEncodings = map[CoverFormat]struct {
+	encode    func(image.Image) ([]byte, error)
+	mediaType string
+	ext       string
+}{
+	CoverFormatJPEG: {imagex.EncodeJPEG, "image/jpeg", ".jpg"},
+	CoverFormatWebP: {imagex.EncodeWebP, "image/webp", ".webp"},
+}
+
+type CoverExtractor interface {
+	// Cover returns the encoded cover image of the file at path, domain.ErrCoverNotFound if it has none.
+	Cover(ctx context.Context, path string) ([]byte, error)
+}
+
+type GetCoverQuery struct {
+	ItemID domain.LibraryItemID
+	// Size defaults to CoverSizeFull.
+	Size CoverSize
+	// Format defaults to CoverFormatJPEG.
+	Format CoverFormat
+}
+
+// GetCover returns the cover of an item in the requested size and format. Covers are generated in
+// every size of the format at once the first time one is missing from the cache.
+func (a *App) GetCover(ctx context.Context, q GetCoverQuery) (Image, error) {
+	const op = errorx.Op("content.App.GetCover")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+
+	if q.Size == "" {
+		q.Size = CoverSizeFull
+	}
+	if q.Format == "" {
+		q.Format = CoverFormatJPEG
+	}
+	err = v.Errors{
+		"size":   v.Validate(q.Size, v.In(CoverSizes...)),
+		"format": v.Validate(q.Format, v.In(CoverFormats...)),
+	}.Filter()
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+
+	item, err := a.visibleItem(ctx, user, q.ItemID)
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+
+	// Items with the same file share their covers, and a replaced file gets new ones.
+	etag := coverETag(item.Hash(), q.Size, q.Format)
+	enc := coverEncodings[q.Format]
+	if data, ok := a.CoverCache.Get(etag + enc.ext); ok {
+		return Image{Data: data, MediaType: enc.mediaType, ETag: etag}, nil
+	}
+
+	covers, err := a.generateCovers(ctx, item, q.Format)
+	if err != nil {
+		return Image{}, op.Wrap(err)
+	}
+	return Image{Data: covers[q.Size], MediaType: enc.mediaType, ETag: etag}, nil
+}
+
+// generateCovers extracts the cover of item and adds it to the cache in every size of format.
+func (a *App) generateCovers(ctx context.Context, item *domain.LibraryItem, format CoverFormat) (map[CoverSize][]byte, error) {
+	data, err := a.Covers.Cover(ctx, item.Path())
+	if err != nil {
+		return nil, err
+	}
+	img, err := imagex.Decode(data)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to decode cover", "item_id", item.ID(), "path", item.Path(), "error", err)
+		return nil, fmt.Errorf("%w: %w", domain.ErrCoverNotFound, err)
+	}
+
+	enc := coverEncodings[format]
+	covers := make(map[CoverSize][]byte, len(coverBounds))
+	for size, bounds := range coverBounds {
+		out, err := enc.encode(imagex.Fit(img, bounds[0], bounds[1]))
+		if err != nil {
+			return nil, err
+		}
+		a.CoverCache.Add(coverETag(item.Hash(), size, format)+enc.ext, out)
+		covers[size] = out
+	}
+	return covers, nil
+}
+
+// coverETag keeps the ETags of JPEG covers from before other formats were supported.
+func coverETag(hash []byte, size CoverSize, format CoverFormat) string {
+	if format == CoverFormatJPEG {
+		return fmt.Sprintf("%x-%s", hash, size)
+	}
+	return fmt.Sprintf("%x-%s-%s", hash, size, format)
+}
@@ -0,0 +1,167 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/cachex"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func newCoverTestApp(t *testing.T) (*App, *mockLibraryItemRepo, *mockCoverExtractor) {
+	t.Helper()
+	app, ir, _ := newTestApp(t)
+	ce := new(mockCoverExtractor)
+	app.Covers = ce
+	app.CoverCache = cachex.NewLRU(1 << 20)
+	return app, ir, ce
+}
+
+func TestApp_GetCover(t *testing.T) {
+	t.Parallel()
+
+	t.Run("every size generated at once", func(t *testing.T) {
+		t.Parallel()
+		app, ir, ce := newCoverTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Comics/plastic.cbz", 3, "")
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		ce.On("Cover", mock.Anything, "Comics/plastic.cbz").Return(pngPage(t, 2000, 3000), nil).Once()
+
+		for _, tt := range []struct {
+			size          CoverSize
+			width, height int
+		}{
+			{size: "", width: 1200, height: 1800},
+			{size: CoverSizeMedium, width: 400, height: 600},
+			{size: CoverSizeThumbnail, width: 160, height: 240},
+		} {
+			img, err := app.GetCover(ctx, GetCoverQuery{ItemID: item.ID(), Size: tt.size})
+			require.NoError(t, err)
+			assert.Equal(t, "image/jpeg", img.MediaType)
+			if tt.size != "" {
+				assert.Equal(t, fmt.Sprintf("%x-%s", "hash", tt.size), img.ETag)
+			}
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(img.Data))
+			require.NoError(t, err)
+			assert.Equal(t, [2]int{tt.width, tt.height}, [2]int{cfg.Width, cfg.Height})
+		}
+		ce.AssertExpectations(t)
+	})
+
+	t.Run("webp", func(t *testing.T) {
+		t.Parallel()
+		app, ir, ce := newCoverTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Comics/plastic.cbz", 3, "")
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		ce.On("Cover", mock.Anything, "Comics/plastic.cbz").Return(pngPage(t, 400, 600), nil).Twice()
+
+		img, err := app.GetCover(ctx, GetCoverQuery{ItemID: item.ID(), Size: CoverSizeThumbnail, Format: CoverFormatWebP})
+		require.NoError(t, err)
+		assert.Equal(t, "image/webp", img.MediaType)
+		assert.Equal(t, fmt.Sprintf("%x-thumbnail-webp", "hash"), img.ETag)
+		cfg, err := webp.DecodeConfig(bytes.NewReader(img.Data))
+		require.NoError(t, err)
+		assert.Equal(t, [2]int{160, 240}, [2]int{cfg.Width, cfg.Height})
+
+		// formats are cached apart, the JPEG is generated on its own
+		img, err = app.GetCover(ctx, GetCoverQuery{ItemID: item.ID(), Size: CoverSizeThumbnail})
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", img.MediaType)
+		_, err = app.GetCover(ctx, GetCoverQuery{ItemID: item.ID(), Format: CoverFormatWebP})
+		require.NoError(t, err)
+		ce.AssertExpectations(t)
+	})
+
+	t.Run("small covers are not scaled up", func(t *testing.T) {
+		t.Parallel()
+		app, ir, ce := newCoverTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Books/book.epub", 0, "")
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		ce.On("Cover", mock.Anything, "Books/book.epub").Return(pngPage(t, 300, 450), nil)
+
+		img, err := app.GetCover(ctx, GetCoverQuery{ItemID: item.ID()})
+		require.NoError(t, err)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(img.Data))
+		require.NoError(t, err)
+		assert.Equal(t, 300, cfg.Width)
+	})
+
+	tests := []struct {
+		name         string
+		item         *domain.LibraryItem
+		cover        []byte
+		coverErr     error
+		restrictions domain.ContentRestrictions
+		expectedErr  error
+	}{
+		{
+			name:        "no cover",
+			item:        newItem("Books/book.fb2", 0, ""),
+			coverErr:    domain.ErrCoverNotFound,
+			expectedErr: domain.ErrCoverNotFound,
+		},
+		{
+			name:        "undecodable cover",
+			item:        newItem("Books/book.epub", 0, ""),
+			cover:       []byte("<svg/>"),
+			expectedErr: domain.ErrCoverNotFound,
+		},
+		{
+			name:         "restricted item looks missing",
+			item:         newItem("Comics/plastic.cbz", 3, domain.AgeRatingAdultsOnly18),
+			restrictions: domain.ContentRestrictions{MaxAge: 12},
+			expectedErr:  domain.ErrLibraryItemNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ir, ce := newCoverTestApp(t)
+			ctx := userContext(t, tt.restrictions)
+			ir.On("GetLibraryItem", mock.Anything, tt.item.ID()).Return(tt.item, nil)
+			ce.On("Cover", mock.Anything, tt.item.Path()).Return(tt.cover, tt.coverErr)
+
+			_, err := app.GetCover(ctx, GetCoverQuery{ItemID: tt.item.ID()})
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+
+	t.Run("unknown size", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newCoverTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+
+		_, err := app.GetCover(ctx, GetCoverQuery{ItemID: domain.NewLibraryItemID(), Size: "huge"})
+		vx.AssertValidationErrors(t, err, v.Errors{"size": v.ErrInInvalid})
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newCoverTestApp(t)
+		ctx := userContext(t, domain.ContentRestrictions{})
+
+		_, err := app.GetCover(ctx, GetCoverQuery{ItemID: domain.NewLibraryItemID(), Format: "avif"})
+		vx.AssertValidationErrors(t, err, v.Errors{"format": v.ErrInInvalid})
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newCoverTestApp(t)
+
+		_, err := app.GetCover(t.Context(), GetCoverQuery{ItemID: domain.NewLibraryItemID()})
+		require.ErrorIs(t, err, domain.ErrUnauthorized)
+	})
+}
@@ -0,0 +1,248 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+// MaxDownloadNameLen is the maximum length of a download file name in runes, without the extension.
+const MaxDownloadNameLen = 120
+
+type FileStore interface {
+	// Open opens the file at the slash separated path relative to the library root.
+	// It must not open files outside the library, whatever the path is.
+	Open(ctx context.Context, path string) (fs.File, error)
+}
+
+type AuthorRepo interface {
+	GetAuthors(context.Context, []domain.AuthorID) ([]domain.Author, error)
+}
+
+type BookConverter interface {
+	// Book reads the file at path as a publication for epubx.WriteBook, it is only called for
+	// formats that domain.FileFormat.ConvertsToEPUB.
+	Book(ctx context.Context, path string) (epubx.Book, error)
+}
+
+type DownloadQuery struct {
+	ItemID domain.LibraryItemID
+	// Format is the format to download the item in, the format of its file by default. Items whose
+	// format converts to EPUB can also be downloaded as domain.FormatEPUB.
+	Format domain.FileFormat
+}
+
+type Download struct {
+	// Content is the file, the caller has to close it.
+	Content   io.ReadSeekCloser
+	Size      int64
+	ModTime   time.Time
+	MediaType string
+	// Name is the suggested file name, e.g. "Роберт Сальваторе - Воин.epub".
+	Name string
+	// ETag is the hex content hash of the file, it changes whenever the file does.
+	ETag string
+}
+
+// Download opens the file of the item for the user in ctx, or its conversion to EPUB if asked for
+// and the file is FB2 or text. It requires domain.PermDownload.
+func (a *App) Download(ctx context.Context, q DownloadQuery) (Download, error) {
+	const op = errorx.Op("content.App.Download")
+
+	user, err := auth.Authorize(ctx, domain.PermDownload)
+	if err != nil {
+		return Download{}, op.Wrap(err)
+	}
+
+	item, err := a.visibleItem(ctx, user, q.ItemID)
+	if err != nil {
+		return Download{}, op.Wrap(err)
+	}
+	convert := q.Format == domain.FormatEPUB && item.Format().ConvertsToEPUB()
+	err = v.Errors{
+		"format": v.Validate(q.Format, v.By(func(any) error {
+			if q.Format != "" && q.Format != item.Format() && !convert {
+				return vx.ErrUnavailableFormat
+			}
+			return nil
+		})),
+	}.Filter()
+	if err != nil {
+		return Download{}, op.Wrap(err)
+	}
+	// Paths come from scans, but a crafted one must not reach the file store. It is reported
+	// as a missing item, the user can do nothing about it.
+	if !fs.ValidPath(item.Path()) || item.Path() == "." {
+		slog.WarnContext(ctx, "library item has an unsafe path", "id", item.ID(), "path", item.Path())
+		return Download{}, op.Wrap(domain.ErrLibraryItemNotFound)
+	}
+
+	authors, err := a.AuthorRepo.GetAuthors(ctx, item.AuthorIDs())
+	if err != nil {
+		return Download{}, op.Wrap(err)
+	}
+
+	if convert {
+		d, err := a.convertToEPUB(ctx, item, authors)
+		return d, op.Wrap(err)
+	}
+
+	f, err := a.Files.Open(ctx, item.Path())
+	if err != nil {
+		return Download{}, op.Wrap(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return Download{}, op.Wrap(err)
+	}
+	content, ok := f.(io.ReadSeekCloser)
+	if !ok {
+		f.Close()
+		return Download{}, op.Wrap(fmt.Errorf("file of %s is not seekable", item.ID()))
+	}
+
+	return Download{
+		Content:   content,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		MediaType: item.Format().MediaType(),
+		Name:      downloadName(item, authors),
+		ETag:      hex.EncodeToString(item.Hash()),
+	}, nil
+}
+
+// conversionVersion is part of the keys of converted EPUBs, bumping it after changes to the
+// converters drops the old conversions.
+const conversionVersion = 1
+
+// convertToEPUB converts the file of item to EPUB. The metadata of the item overrides that of the
+// file, it may have been edited since the file was scanned, and text files have none. Conversions
+// are cached for good, their keys are content addressed by the file hash and the metadata.
+func (a *App) convertToEPUB(ctx context.Context, item *domain.LibraryItem, authors []domain.Author) (Download, error) {
+	etag, err := conversionETag(item, authors)
+	if err != nil {
+		return Download{}, err
+	}
+	name := downloadName(item, authors)
+	d := Download{
+		MediaType: domain.FormatEPUB.MediaType(),
+		Name:      strings.TrimSuffix(name, path.Ext(name)) + ".epub",
+		ETag:      etag,
+	}
+	if data, ok := a.ConversionCache.Get(etag + ".epub"); ok {
+		d.Content, d.Size = nopCloser{bytes.NewReader(data)}, int64(len(data))
+		return d, nil
+	}
+
+	book, err := a.Converter.Book(ctx, item.Path())
+	if err != nil {
+		return Download{}, err
+	}
+	book.Identifier = "urn:uuid:" + item.ID().String()
+	if item.Title() != "" {
+		book.Title = item.Title()
+	}
+	if len(authors) > 0 {
+		// Translators and other contributors are not library authors, they stay.
+		creators := make([]epubx.Author, 0, len(authors)+len(book.Creators))
+		for _, author := range authors {
+			creators = append(creators, epubx.Author{Name: author.Name(), Role: "aut"})
+		}
+		for _, c := range book.Creators {
+			if c.Role != "aut" {
+				creators = append(creators, c)
+			}
+		}
+		book.Creators = creators
+	}
+	if len(item.Languages()) > 0 {
+		book.Language = item.Languages()[0]
+	}
+	if item.Annotation() != "" {
+		book.Description = item.Annotation()
+	}
+	if len(item.Genre()) > 0 {
+		book.Subjects = item.Genre()
+	}
+
+	var buf bytes.Buffer
+	if err := epubx.WriteBook(&buf, book); err != nil {
+		return Download{}, err
+	}
+	a.ConversionCache.Add(etag+".epub", buf.Bytes())
+	d.Content, d.Size = nopCloser{bytes.NewReader(buf.Bytes())}, int64(buf.Len())
+	return d, nil
+}
+
+// conversionETag is the hex hash of everything a conversion of item to EPUB depends on.
+func conversionETag(item *domain.LibraryItem, authors []domain.Author) (string, error) {
+	names := make([]string, len(authors))
+	for i, a := range authors {
+		names[i] = a.Name()
+	}
+	data, err := json.Marshal(struct {
+		Version    int
+		Hash       []byte
+		ID         domain.LibraryItemID
+		Title      string
+		Authors    []string
+		Languages  []string
+		Annotation string
+		Genre      []string
+	}{conversionVersion, item.Hash(), item.ID(), item.Title(), names, item.Languages(), item.Annotation(), item.Genre()})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type nopCloser struct{ *bytes.Reader }
+
+func (nopCloser) Close() error { return nil }
+
+// downloadName is "<first author> - <title><ext of the file>" with characters that are unsafe in file names
+// on common systems removed.
+func downloadName(item *domain.LibraryItem, authors []domain.Author) string {
+	name := item.Title()
+	if len(authors) > 0 {
+		name = authors[0].Name() + " - " + name
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r), strings.ContainsRune(`<>:"/\|?*`, r):
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > MaxDownloadNameLen {
+		name = string(runes[:MaxDownloadNameLen])
+	}
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		name = item.ID().String()
+	}
+
+	return name + strings.ToLower(path.Ext(item.Path()))
+}
@@ -0,0 +1,226 @@
+package content
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/cachex"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func newBook(t *testing.T, title, path string, authorIDs ...domain.AuthorID) *domain.LibraryItem {
+	t.Helper()
+	item := domain.NewLibraryItemBuilder().
+		Id(domain.NewLibraryItemID()).
+		Title(title).
+		ItemType(domain.Book).
+		AuthorIDs(authorIDs).
+		Path(path).
+		Hash([]byte{0xca, 0xfe}).
+		Build()
+	return &item
+}
+
+func mustNewAuthor(t *testing.T, name string) domain.Author {
+	t.Helper()
+	a, err := domain.NewAuthor(domain.NewAuthorID(), name)
+	require.NoError(t, err)
+	return *a
+}
+
+func TestApp_Download(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	files := mapFileStore{fs: fstest.MapFS{
+		"Books/Salvatore/Воин/book.epub": &fstest.MapFile{Data: []byte("epub data"), ModTime: modTime},
+	}}
+	author := mustNewAuthor(t, "Роберт Сальваторе")
+
+	t.Run("member downloads", func(t *testing.T) {
+		t.Parallel()
+		app, ir, _ := newTestApp(t)
+		ar := new(mockAuthorRepo)
+		app.AuthorRepo, app.Files = ar, files
+		item := newBook(t, "Воин", "Books/Salvatore/Воин/book.epub", author.ID())
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		ar.On("GetAuthors", mock.Anything, []domain.AuthorID{author.ID()}).Return([]domain.Author{author}, nil)
+
+		d, err := app.Download(userContext(t, domain.ContentRestrictions{}), DownloadQuery{ItemID: item.ID()})
+		require.NoError(t, err)
+		t.Cleanup(func() { d.Content.Close() })
+
+		assert.Equal(t, "Роберт Сальваторе - Воин.epub", d.Name)
+		assert.Equal(t, "application/epub+zip", d.MediaType)
+		assert.Equal(t, "cafe", d.ETag)
+		assert.Equal(t, int64(9), d.Size)
+		assert.Equal(t, modTime, d.ModTime)
+		data, err := io.ReadAll(d.Content)
+		require.NoError(t, err)
+		assert.Equal(t, "epub data", string(data))
+	})
+
+	t.Run("guest may not download", func(t *testing.T) {
+		t.Parallel()
+		app, ir, _ := newTestApp(t)
+
+		_, err := app.Download(roleContext(t, domain.RoleGuest, domain.ContentRestrictions{}), DownloadQuery{ItemID: domain.NewLibraryItemID()})
+		require.ErrorIs(t, err, domain.ErrPermissionDenied)
+		ir.AssertNotCalled(t, "GetLibraryItem", mock.Anything, mock.Anything)
+	})
+
+	for _, path := range []string{"../../etc/passwd", "/etc/passwd", "Books/../../secret.epub", ""} {
+		t.Run("unsafe path "+path, func(t *testing.T) {
+			t.Parallel()
+			app, ir, _ := newTestApp(t)
+			app.Files = files
+			item := newBook(t, "Воин", path)
+			ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+
+			_, err := app.Download(userContext(t, domain.ContentRestrictions{}), DownloadQuery{ItemID: item.ID()})
+			require.ErrorIs(t, err, domain.ErrLibraryItemNotFound)
+		})
+	}
+
+	t.Run("deleted item", func(t *testing.T) {
+		t.Parallel()
+		app, ir, _ := newTestApp(t)
+		item := newBook(t, "Воин", "Books/Salvatore/Воин/book.epub")
+		item.Delete()
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+
+		_, err := app.Download(userContext(t, domain.ContentRestrictions{}), DownloadQuery{ItemID: item.ID()})
+		require.ErrorIs(t, err, domain.ErrLibraryItemNotFound)
+	})
+}
+
+func TestApp_Download_epub(t *testing.T) {
+	t.Parallel()
+
+	author := mustNewAuthor(t, "Роберт Сальваторе")
+	converted := epubx.Book{
+		Title:    "Воин (fb2)",
+		Language: "ru",
+		Creators: []epubx.Author{{Name: "R. A. Salvatore", Role: "aut"}, {Name: "Н. Некрасова", Role: "trl"}},
+		Chapters: []epubx.Chapter{{Title: "Глава 1", Body: "<p>Текст</p>"}},
+	}
+
+	t.Run("converted with the metadata of the item and cached", func(t *testing.T) {
+		t.Parallel()
+		app, ir, _ := newTestApp(t)
+		ar, bc := new(mockAuthorRepo), new(mockBookConverter)
+		app.AuthorRepo, app.Converter, app.ConversionCache = ar, bc, cachex.NewLRU(1<<20)
+		item := newBook(t, "Воин", "Books/Salvatore/voin.fb2", author.ID())
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		ar.On("GetAuthors", mock.Anything, []domain.AuthorID{author.ID()}).Return([]domain.Author{author}, nil)
+		bc.On("Book", mock.Anything, "Books/Salvatore/voin.fb2").Return(converted, nil).Once()
+
+		var etag string
+		for range 2 {
+			d, err := app.Download(userContext(t, domain.ContentRestrictions{}), DownloadQuery{ItemID: item.ID(), Format: domain.FormatEPUB})
+			require.NoError(t, err)
+			t.Cleanup(func() { d.Content.Close() })
+
+			assert.Equal(t, "Роберт Сальваторе - Воин.epub", d.Name)
+			assert.Equal(t, "application/epub+zip", d.MediaType)
+			assert.Len(t, d.ETag, 64)
+			if etag != "" {
+				assert.Equal(t, etag, d.ETag)
+			}
+			etag = d.ETag
+
+			data, err := io.ReadAll(d.Content)
+			require.NoError(t, err)
+			assert.Equal(t, d.Size, int64(len(data)))
+			epub, err := epubx.ParseEPUB(bytes.NewReader(data), d.Size)
+			require.NoError(t, err)
+			assert.Equal(t, "urn:uuid:"+item.ID().String(), epub.Identifier())
+			assert.Equal(t, []string{"Воин"}, epub.Metadata.Titles)
+			assert.Equal(t, []epubx.Author{
+				{Name: "Роберт Сальваторе", ID: "creator1", Role: "aut"},
+				{Name: "Н. Некрасова", ID: "creator2", Role: "trl"},
+			}, epub.Metadata.Creators)
+			assert.Equal(t, []string{"ru"}, epub.Metadata.Languages)
+		}
+		bc.AssertExpectations(t)
+	})
+
+	t.Run("edited metadata is converted again", func(t *testing.T) {
+		t.Parallel()
+		item := newBook(t, "Воин", "Books/Salvatore/voin.fb2")
+		before, err := conversionETag(item, nil)
+		require.NoError(t, err)
+		after, err := conversionETag(item, []domain.Author{author})
+		require.NoError(t, err)
+		assert.NotEqual(t, before, after)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		format domain.FileFormat
+	}{
+		{name: "epub of a pdf", path: "Books/book.pdf", format: domain.FormatEPUB},
+		{name: "fb2 of an epub", path: "Books/book.epub", format: domain.FormatFB2},
+		{name: "unknown format", path: "Books/book.txt", format: "docx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ir, _ := newTestApp(t)
+			item := newBook(t, "Воин", tt.path)
+			ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+
+			_, err := app.Download(userContext(t, domain.ContentRestrictions{}), DownloadQuery{ItemID: item.ID(), Format: tt.format})
+			vx.AssertValidationErrors(t, err, v.Errors{"format": vx.ErrUnavailableFormat})
+		})
+	}
+}
+
+func TestDownloadName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		title    string
+		authors  []string
+		path     string
+		expected string
+	}{
+		{name: "author and title", title: "Воин", authors: []string{"Роберт Сальваторе", "Someone"}, path: "a/b.EPUB", expected: "Роберт Сальваторе - Воин.epub"},
+		{name: "no author", title: "Plastic Man #002", path: "c/p.cbz", expected: "Plastic Man #002.cbz"},
+		{name: "unsafe characters", title: "../What? A \"Title\": Part\\1\r\n", path: "x.pdf", expected: "What A Title Part 1.pdf"},
+		{name: "too long", title: strings.Repeat("я", MaxDownloadNameLen+10), path: "x.fb2", expected: strings.Repeat("я", MaxDownloadNameLen) + ".fb2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			authors := make([]domain.Author, len(tt.authors))
+			for i, name := range tt.authors {
+				authors[i] = mustNewAuthor(t, name)
+			}
+			item := newBook(t, tt.title, tt.path)
+			assert.Equal(t, tt.expected, downloadName(item, authors))
+		})
+	}
+
+	t.Run("nothing left falls back to the id", func(t *testing.T) {
+		t.Parallel()
+		item := newBook(t, "???", "x.epub")
+		assert.Equal(t, item.ID().String()+".epub", downloadName(item, nil))
+	})
+}
@@ -0,0 +1,115 @@
+package content
+
+import (
+	"context"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"path"
+	"slices"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+type EPUBResources interface {
+	// Resource returns the data and media type of the manifest item at the container path name of
+	// the EPUB at path, domain.ErrResourceNotFound if the manifest has no such item.
+	Resource(ctx context.Context, path, name string) ([]byte, string, error)
+}
+
+type EPUBValidator interface {
+	// Validate checks the EPUB at path against the spec, see epubx.Validate.
+	Validate(ctx context.Context, path string) (epubx.Report, error)
+}
+
+type GetEPUBResourceQuery struct {
+	ItemID domain.LibraryItemID
+	// Path is the path of the resource in the EPUB container, see epubx.ManifestItem.Path.
+	Path string
+}
+
+// Resource is a file from inside a library item, such as a chapter or a stylesheet of an EPUB.
+type Resource struct {
+	Data      []byte
+	MediaType string
+	ETag      string
+}
+
+// sanitizedMediaTypes are the types of documents that can run scripts, SanitizeXHTML cleans them.
+var sanitizedMediaTypes = []string{"application/xhtml+xml", "image/svg+xml", "text/html"}
+
+// GetEPUBResource returns a resource of an EPUB for the web reader. XHTML and SVG documents are
+// sanitized, the book is not trusted to be free of scripts. DRM protected books cannot be read,
+// it returns domain.ErrDRMProtected for them.
+func (a *App) GetEPUBResource(ctx context.Context, q GetEPUBResourceQuery) (Resource, error) {
+	const op = errorx.Op("content.App.GetEPUBResource")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return Resource{}, op.Wrap(err)
+	}
+	if !fs.ValidPath(q.Path) || q.Path == "." {
+		return Resource{}, op.Wrap(domain.ErrResourceNotFound)
+	}
+
+	item, err := a.visibleItem(ctx, user, q.ItemID)
+	if err != nil {
+		return Resource{}, op.Wrap(err)
+	}
+	if item.Format() != domain.FormatEPUB {
+		return Resource{}, op.Wrap(domain.ErrResourceNotFound)
+	}
+	if item.IsProtected() {
+		return Resource{}, op.Wrap(domain.ErrDRMProtected)
+	}
+
+	data, mediaType, err := a.EPUBs.Resource(ctx, item.Path(), q.Path)
+	if err != nil {
+		return Resource{}, op.Wrap(err)
+	}
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(path.Ext(q.Path))
+	}
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+
+	if isSanitized(mediaType) {
+		data, err = epubx.SanitizeXHTML(data)
+		if err != nil {
+			return Resource{}, op.Wrap(err)
+		}
+	}
+
+	return Resource{Data: data, MediaType: mediaType, ETag: hex.EncodeToString(item.Hash())}, nil
+}
+
+// ValidateEPUB returns the validation report of the EPUB of the item, it requires
+// domain.PermValidateFiles. Readers open books with issues, admins use it to find and fix them.
+func (a *App) ValidateEPUB(ctx context.Context, id domain.LibraryItemID) (epubx.Report, error) {
+	const op = errorx.Op("content.App.ValidateEPUB")
+
+	user, err := auth.Authorize(ctx, domain.PermValidateFiles)
+	if err != nil {
+		return epubx.Report{}, op.Wrap(err)
+	}
+
+	item, err := a.visibleItem(ctx, user, id)
+	if err != nil {
+		return epubx.Report{}, op.Wrap(err)
+	}
+	if item.Format() != domain.FormatEPUB {
+		return epubx.Report{}, op.Wrap(domain.ErrResourceNotFound)
+	}
+
+	report, err := a.EPUBValidator.Validate(ctx, item.Path())
+	return report, op.Wrap(err)
+}
+
+func isSanitized(mediaType string) bool {
+	mediaType, _, _ = mime.ParseMediaType(mediaType)
+	return slices.Contains(sanitizedMediaTypes, mediaType)
+}
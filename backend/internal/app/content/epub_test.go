@@ -0,0 +1,167 @@
+package content
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+type mockEPUBResources struct{ mock.Mock }
+
+func (m *mockEPUBResources) Resource(ctx context.Context, path, name string) ([]byte, string, error) {
+	args := m.Called(ctx, path, name)
+	data, _ := args.Get(0).([]byte)
+	return data, args.String(1), args.Error(2)
+}
+
+type mockEPUBValidator struct{ mock.Mock }
+
+func (m *mockEPUBValidator) Validate(ctx context.Context, path string) (epubx.Report, error) {
+	args := m.Called(ctx, path)
+	return args.Get(0).(epubx.Report), args.Error(1)
+}
+
+func TestApp_GetEPUBResource(t *testing.T) {
+	t.Parallel()
+
+	book := newItem("Books/dark elf.epub", 0, "")
+	comic := newItem("Comics/plastic.cbz", 3, "")
+	protected := newItem("Books/homeland.epub", 0, "")
+	protected.SetDRM("adobe-adept")
+
+	tests := []struct {
+		name              string
+		item              *domain.LibraryItem
+		path              string
+		data              []byte
+		mediaType         string
+		expectedData      string
+		expectedMediaType string
+		expectedErr       error
+	}{
+		{
+			name:              "chapter is sanitized",
+			item:              book,
+			path:              "OEBPS/text/ch1.xhtml",
+			data:              []byte(`<p onclick="x()">Дзирт<script>steal()</script></p>`),
+			mediaType:         "application/xhtml+xml",
+			expectedData:      `<p>Дзирт</p>`,
+			expectedMediaType: "application/xhtml+xml",
+		},
+		{
+			name:              "stylesheet as is",
+			item:              book,
+			path:              "OEBPS/style.css",
+			data:              []byte(`p > em { color: red }`),
+			mediaType:         "text/css",
+			expectedData:      `p > em { color: red }`,
+			expectedMediaType: "text/css",
+		},
+		{
+			name:              "media type from the extension if the manifest has none",
+			item:              book,
+			path:              "OEBPS/images/cover.png",
+			data:              []byte("png"),
+			expectedData:      "png",
+			expectedMediaType: "image/png",
+		},
+		{
+			name:        "not an epub",
+			item:        comic,
+			path:        "p1.jpg",
+			expectedErr: domain.ErrResourceNotFound,
+		},
+		{
+			name:        "drm protected",
+			item:        protected,
+			path:        "OEBPS/text/ch1.xhtml",
+			expectedErr: domain.ErrDRMProtected,
+		},
+		{
+			name:        "path out of the container",
+			item:        book,
+			path:        "../secret",
+			expectedErr: domain.ErrResourceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ir, _ := newTestApp(t)
+			er := new(mockEPUBResources)
+			app.EPUBs = er
+			ctx := userContext(t, domain.ContentRestrictions{})
+			ir.On("GetLibraryItem", mock.Anything, tt.item.ID()).Return(tt.item, nil)
+			er.On("Resource", mock.Anything, tt.item.Path(), tt.path).Return(tt.data, tt.mediaType, nil)
+
+			res, err := app.GetEPUBResource(ctx, GetEPUBResourceQuery{ItemID: tt.item.ID(), Path: tt.path})
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				er.AssertNotCalled(t, "Resource", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedData, string(res.Data))
+			assert.Equal(t, tt.expectedMediaType, res.MediaType)
+			assert.Equal(t, "68617368", res.ETag)
+		})
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		_, err := app.GetEPUBResource(t.Context(), GetEPUBResourceQuery{ItemID: book.ID(), Path: "OEBPS/text/ch1.xhtml"})
+		require.ErrorIs(t, err, domain.ErrUnauthorized)
+	})
+}
+
+func TestApp_ValidateEPUB(t *testing.T) {
+	t.Parallel()
+
+	book := newItem("Books/dark elf.epub", 0, "")
+	comic := newItem("Comics/plastic.cbz", 3, "")
+	report := epubx.Report{
+		Version: "3.0",
+		Issues:  []epubx.Issue{{Severity: epubx.SeverityWarning, Err: epubx.ErrMissingNav}},
+	}
+
+	tests := []struct {
+		name        string
+		role        domain.Role
+		item        *domain.LibraryItem
+		expectedErr error
+	}{
+		{name: "admin", role: domain.RoleAdmin, item: book},
+		{name: "member", role: domain.RoleMember, item: book, expectedErr: domain.ErrPermissionDenied},
+		{name: "not an epub", role: domain.RoleAdmin, item: comic, expectedErr: domain.ErrResourceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, ir, _ := newTestApp(t)
+			ev := new(mockEPUBValidator)
+			app.EPUBValidator = ev
+			ctx := roleContext(t, tt.role, domain.ContentRestrictions{})
+			ir.On("GetLibraryItem", mock.Anything, tt.item.ID()).Return(tt.item, nil)
+			ev.On("Validate", mock.Anything, tt.item.Path()).Return(report, nil)
+
+			got, err := app.ValidateEPUB(ctx, tt.item.ID())
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				ev.AssertNotCalled(t, "Validate", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, report, got)
+		})
+	}
+}
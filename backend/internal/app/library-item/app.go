@@ -1 +1,146 @@
 package library_item
+
+import (
+	"context"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type ReadModel interface {
+	// ListLibraryItems returns up to filter.Limit items ordered by filter.SortBy, filter.SortOrder and then id,
+	// starting strictly after filter.After. Items never read by filter.ReaderID go last when sorting by last read.
+	ListLibraryItems(context.Context, LibraryItemsFilter) ([]LibraryItemView, error)
+	// GetLibraryItem returns domain.ErrLibraryItemNotFound if there is no item with the given id.
+	// readerID is only used for LibraryItemView.LastReadAt.
+	GetLibraryItem(ctx context.Context, id domain.LibraryItemID, readerID domain.UserID) (LibraryItemView, error)
+	// ListFacetValues returns up to filter.Limit values ordered by label and then value,
+	// starting strictly after filter.After.
+	ListFacetValues(context.Context, FacetValuesFilter) ([]FacetValue, error)
+}
+
+type App struct {
+	ReadModel         ReadModel
+	Session           dbx.Session
+	LibraryItemRepo   LibraryItemRepo
+	AuthorRepo        AuthorRepo
+	SeriesRepo        SeriesRepo
+	MetadataAuditRepo MetadataAuditRepo
+	// WriteBack writes edited metadata into the files, edits stay in the library if it is nil.
+	WriteBack MetadataWriteBack
+}
+
+// ListLibraryItems returns a page of the items the user in ctx is permitted to see. Only users
+// with domain.PermDeleteItems may list deleted items.
+func (a *App) ListLibraryItems(ctx context.Context, q ListLibraryItemsQuery) (LibraryItemPage, error) {
+	const op = errorx.Op("library_item.App.ListLibraryItems")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return LibraryItemPage{}, op.Wrap(err)
+	}
+
+	if q.SortBy == "" {
+		q.SortBy = SortByAdded
+	}
+	if q.SortOrder == "" {
+		q.SortOrder = Desc
+		if q.SortBy == SortByTitle {
+			q.SortOrder = Asc
+		}
+	}
+	if q.Deleted == "" {
+		q.Deleted = NotDeleted
+	}
+	if q.Limit == 0 {
+		q.Limit = vx.DefaultPageLimit
+	}
+
+	var after *Cursor
+	err = v.Errors{
+		"types":     v.Validate(q.Types, v.Each(v.In(domain.Book, domain.Manga, domain.Comic))),
+		"deleted":   v.Validate(q.Deleted, v.In(NotDeleted, OnlyDeleted, AnyDeleted)),
+		"sortBy":    v.Validate(q.SortBy, v.In(SortByTitle, SortByAdded, SortByLastRead)),
+		"sortOrder": v.Validate(q.SortOrder, v.In(Asc, Desc)),
+		"limit":     v.Validate(q.Limit, v.Min(1), v.Max(vx.MaxPageLimit)),
+		"cursor": v.Validate(q.Cursor, vx.Cursor(func(cursor string) error {
+			c, err := DecodeCursor(cursor)
+			if err != nil {
+				return err
+			}
+			if c.SortBy != q.SortBy || c.SortOrder != q.SortOrder {
+				return vx.ErrInvalidCursor
+			}
+			after = &c
+			return nil
+		})),
+	}.Filter()
+	if err != nil {
+		return LibraryItemPage{}, op.Wrap(err)
+	}
+	if q.Deleted != NotDeleted {
+		if _, err := auth.Authorize(ctx, domain.PermDeleteItems); err != nil {
+			return LibraryItemPage{}, op.Wrap(err)
+		}
+	}
+
+	items, err := a.ReadModel.ListLibraryItems(ctx, LibraryItemsFilter{
+		Types:        q.Types,
+		AuthorID:     q.AuthorID,
+		SeriesID:     q.SeriesID,
+		Genre:        q.Genre,
+		Language:     q.Language,
+		TitlePrefix:  q.TitlePrefix,
+		Search:       q.Search,
+		Deleted:      q.Deleted,
+		SortBy:       q.SortBy,
+		SortOrder:    q.SortOrder,
+		ReaderID:     user.ID(),
+		Restrictions: user.Restrictions(),
+		After:        after,
+		Limit:        q.Limit + 1, // one extra item tells us whether there is a next page
+	})
+	if err != nil {
+		return LibraryItemPage{}, op.Wrap(err)
+	}
+
+	page := LibraryItemPage{Items: items}
+	if len(items) > q.Limit {
+		page.Items = items[:q.Limit]
+		page.NextCursor = CursorAfter(page.Items[q.Limit-1], q.SortBy, q.SortOrder).Encode()
+	}
+
+	return page, nil
+}
+
+// GetLibraryItem returns domain.ErrLibraryItemNotFound also when the user in ctx is not permitted
+// to see the item, so restricted users can not probe for hidden items. Deleted items are only
+// found by users with domain.PermDeleteItems.
+func (a *App) GetLibraryItem(ctx context.Context, id domain.LibraryItemID) (LibraryItemView, error) {
+	const op = errorx.Op("library_item.App.GetLibraryItem")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return LibraryItemView{}, op.Wrap(err)
+	}
+
+	item, err := a.ReadModel.GetLibraryItem(ctx, id, user.ID())
+	if err != nil {
+		return LibraryItemView{}, op.Wrap(err)
+	}
+	if !user.Restrictions().Permits(item.ContentAttrs()) {
+		return LibraryItemView{}, op.Wrap(domain.ErrLibraryItemNotFound)
+	}
+	if item.DeletedAt != nil {
+		if _, err := auth.Authorize(ctx, domain.PermDeleteItems); err != nil {
+			return LibraryItemView{}, op.Wrap(domain.ErrLibraryItemNotFound)
+		}
+	}
+
+	return item, nil
+}
@@ -0,0 +1,339 @@
+package library_item
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type mockReadModel struct{ mock.Mock }
+
+func (m *mockReadModel) ListLibraryItems(ctx context.Context, f LibraryItemsFilter) ([]LibraryItemView, error) {
+	args := m.Called(ctx, f)
+	items, _ := args.Get(0).([]LibraryItemView)
+	return items, args.Error(1)
+}
+
+func (m *mockReadModel) GetLibraryItem(ctx context.Context, id domain.LibraryItemID, readerID domain.UserID) (LibraryItemView, error) {
+	args := m.Called(ctx, id, readerID)
+	item, _ := args.Get(0).(LibraryItemView)
+	return item, args.Error(1)
+}
+
+func (m *mockReadModel) ListFacetValues(ctx context.Context, f FacetValuesFilter) ([]FacetValue, error) {
+	args := m.Called(ctx, f)
+	values, _ := args.Get(0).([]FacetValue)
+	return values, args.Error(1)
+}
+
+type mockLibraryItemRepo struct{ mock.Mock }
+
+func (m *mockLibraryItemRepo) GetLibraryItem(ctx context.Context, id domain.LibraryItemID) (*domain.LibraryItem, error) {
+	args := m.Called(ctx, id)
+	item, _ := args.Get(0).(*domain.LibraryItem)
+	return item, args.Error(1)
+}
+
+func (m *mockLibraryItemRepo) UpdateLibraryItems(ctx context.Context, items []*domain.LibraryItem) error {
+	return m.Called(ctx, items).Error(0)
+}
+
+type mockAuthorRepo struct{ mock.Mock }
+
+func (m *mockAuthorRepo) GetOrCreateAuthors(ctx context.Context, names []string) ([]domain.Author, error) {
+	args := m.Called(ctx, names)
+	authors, _ := args.Get(0).([]domain.Author)
+	return authors, args.Error(1)
+}
+
+type mockSeriesRepo struct{ mock.Mock }
+
+func (m *mockSeriesRepo) GetOrCreateSeries(ctx context.Context, names []string) ([]*domain.Series, error) {
+	args := m.Called(ctx, names)
+	series, _ := args.Get(0).([]*domain.Series)
+	return series, args.Error(1)
+}
+
+type mockMetadataAuditRepo struct{ mock.Mock }
+
+func (m *mockMetadataAuditRepo) AddMetadataAuditEntry(ctx context.Context, e *domain.MetadataAuditEntry) error {
+	return m.Called(ctx, e).Error(0)
+}
+
+func (m *mockMetadataAuditRepo) ListMetadataAuditEntries(ctx context.Context, id domain.LibraryItemID) ([]*domain.MetadataAuditEntry, error) {
+	args := m.Called(ctx, id)
+	entries, _ := args.Get(0).([]*domain.MetadataAuditEntry)
+	return entries, args.Error(1)
+}
+
+type mockWriteBack struct{ mock.Mock }
+
+func (m *mockWriteBack) WriteBackMetadata(ctx context.Context, id domain.LibraryItemID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+type mockSession struct{ mock.Mock }
+
+func (m *mockSession) Transaction(ctx context.Context, f func(context.Context) error) error {
+	return f(ctx)
+}
+
+func (m *mockSession) Begin(ctx context.Context) (dbx.Session, error) {
+	args := m.Called(ctx)
+	s, _ := args.Get(0).(dbx.Session)
+	return s, args.Error(1)
+}
+
+func (m *mockSession) Rollback() error          { return m.Called().Error(0) }
+func (m *mockSession) Commit() error            { return m.Called().Error(0) }
+func (m *mockSession) Context() context.Context { return m.Called().Get(0).(context.Context) }
+
+func userContext(t *testing.T, r domain.ContentRestrictions) (context.Context, *domain.User) {
+	t.Helper()
+	user := domain.NewUserBuilder().WithDefault().Build()
+	require.NoError(t, user.SetRestrictions(r))
+	return auth.WithUser(t.Context(), &user), &user
+}
+
+func views(titles ...string) []LibraryItemView {
+	res := make([]LibraryItemView, len(titles))
+	for i, t := range titles {
+		res[i] = LibraryItemView{ID: domain.NewLibraryItemID(), Title: t}
+	}
+	return res
+}
+
+func TestApp_ListLibraryItems_defaults(t *testing.T) {
+	t.Parallel()
+
+	rm := new(mockReadModel)
+	app := &App{ReadModel: rm}
+	restrictions := domain.ContentRestrictions{DenyTypes: []domain.LibraryItemType{domain.Manga}, MaxAge: 12}
+	ctx, user := userContext(t, restrictions)
+
+	rm.On("ListLibraryItems", mock.Anything, LibraryItemsFilter{
+		Deleted:      NotDeleted,
+		SortBy:       SortByAdded,
+		SortOrder:    Desc,
+		ReaderID:     user.ID(),
+		Restrictions: restrictions,
+		Limit:        vx.DefaultPageLimit + 1,
+	}).Return(views("a", "b"), nil).Once()
+
+	page, err := app.ListLibraryItems(ctx, ListLibraryItemsQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.Empty(t, page.NextCursor)
+	rm.AssertExpectations(t)
+}
+
+func TestApp_ListLibraryItems_pagination(t *testing.T) {
+	t.Parallel()
+
+	rm := new(mockReadModel)
+	app := &App{ReadModel: rm}
+	ctx, _ := userContext(t, domain.ContentRestrictions{})
+
+	first := views("a", "b", "c")
+	rm.On("ListLibraryItems", mock.Anything, mock.MatchedBy(func(f LibraryItemsFilter) bool {
+		return f.After == nil && f.Limit == 3
+	})).Return(first, nil).Once()
+
+	page, err := app.ListLibraryItems(ctx, ListLibraryItemsQuery{SortBy: SortByTitle, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	require.NotEmpty(t, page.NextCursor)
+
+	rm.On("ListLibraryItems", mock.Anything, mock.MatchedBy(func(f LibraryItemsFilter) bool {
+		return f.After != nil && f.After.ID == first[1].ID && f.After.Value == "b" && f.SortOrder == Asc
+	})).Return(first[2:], nil).Once()
+
+	page, err = app.ListLibraryItems(ctx, ListLibraryItemsQuery{SortBy: SortByTitle, Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, first[2:], page.Items)
+	assert.Empty(t, page.NextCursor)
+	rm.AssertExpectations(t)
+}
+
+func TestApp_ListLibraryItems_validation(t *testing.T) {
+	t.Parallel()
+
+	titleCursor := Cursor{SortBy: SortByTitle, SortOrder: Asc, Value: "a", ID: domain.NewLibraryItemID()}.Encode()
+
+	tests := []struct {
+		name        string
+		query       ListLibraryItemsQuery
+		expectedErr error
+	}{
+		{
+			name:        "unknown type",
+			query:       ListLibraryItemsQuery{Types: []domain.LibraryItemType{domain.Book, "audiobook"}},
+			expectedErr: v.Errors{"types": v.Errors{"1": v.ErrInInvalid}},
+		},
+		{
+			name:        "unknown sort field",
+			query:       ListLibraryItemsQuery{SortBy: "rating"},
+			expectedErr: v.Errors{"sortBy": v.ErrInInvalid},
+		},
+		{
+			name:        "unknown deleted filter",
+			query:       ListLibraryItemsQuery{Deleted: "yes"},
+			expectedErr: v.Errors{"deleted": v.ErrInInvalid},
+		},
+		{
+			name:        "limit too big",
+			query:       ListLibraryItemsQuery{Limit: vx.MaxPageLimit + 1},
+			expectedErr: v.Errors{"limit": v.ErrMaxLessEqualThanRequired},
+		},
+		{
+			name:        "garbage cursor",
+			query:       ListLibraryItemsQuery{Cursor: "not a cursor"},
+			expectedErr: v.Errors{"cursor": vx.ErrInvalidCursor},
+		},
+		{
+			name:        "cursor from another sort",
+			query:       ListLibraryItemsQuery{SortBy: SortByAdded, Cursor: titleCursor},
+			expectedErr: v.Errors{"cursor": vx.ErrInvalidCursor},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{ReadModel: new(mockReadModel)}
+			ctx, _ := userContext(t, domain.ContentRestrictions{})
+			_, err := app.ListLibraryItems(ctx, tt.query)
+			vx.AssertValidationErrors(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestApp_ListLibraryItems_deleted(t *testing.T) {
+	t.Parallel()
+
+	t.Run("member", func(t *testing.T) {
+		t.Parallel()
+		rm := new(mockReadModel)
+		app := &App{ReadModel: rm}
+		ctx, _ := userContext(t, domain.ContentRestrictions{})
+
+		for _, deleted := range []DeletedFilter{OnlyDeleted, AnyDeleted} {
+			_, err := app.ListLibraryItems(ctx, ListLibraryItemsQuery{Deleted: deleted})
+			require.ErrorIs(t, err, domain.ErrPermissionDenied)
+		}
+		rm.AssertNotCalled(t, "ListLibraryItems", mock.Anything, mock.Anything)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		rm := new(mockReadModel)
+		app := &App{ReadModel: rm}
+		admin := domain.NewUserBuilder().WithDefault().Role(domain.RoleAdmin).Build()
+		ctx := auth.WithUser(t.Context(), &admin)
+		rm.On("ListLibraryItems", mock.Anything, mock.MatchedBy(func(f LibraryItemsFilter) bool {
+			return f.Deleted == OnlyDeleted
+		})).Return(views("a"), nil).Once()
+
+		page, err := app.ListLibraryItems(ctx, ListLibraryItemsQuery{Deleted: OnlyDeleted})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 1)
+		rm.AssertExpectations(t)
+	})
+}
+
+func TestApp_ListLibraryItems_anonymous(t *testing.T) {
+	t.Parallel()
+
+	app := &App{ReadModel: new(mockReadModel)}
+	_, err := app.ListLibraryItems(t.Context(), ListLibraryItemsQuery{})
+	require.ErrorIs(t, err, domain.ErrUnauthorized)
+}
+
+func TestApp_GetLibraryItem(t *testing.T) {
+	t.Parallel()
+
+	visible := LibraryItemView{ID: domain.NewLibraryItemID(), Type: domain.Comic, AgeRating: domain.AgeRatingEveryone}
+	mature := LibraryItemView{ID: domain.NewLibraryItemID(), Type: domain.Comic, AgeRating: domain.AgeRatingMature17}
+	deletedAt := time.Now()
+	deleted := LibraryItemView{ID: domain.NewLibraryItemID(), Type: domain.Comic, AgeRating: domain.AgeRatingEveryone, DeletedAt: &deletedAt}
+	missing := domain.NewLibraryItemID()
+
+	tests := []struct {
+		name        string
+		role        domain.Role
+		id          domain.LibraryItemID
+		expected    LibraryItemView
+		expectedErr error
+	}{
+		{name: "permitted", id: visible.ID, expected: visible},
+		{name: "restricted looks missing", id: mature.ID, expectedErr: domain.ErrLibraryItemNotFound},
+		{name: "not found", id: missing, expectedErr: domain.ErrLibraryItemNotFound},
+		{name: "deleted looks missing to members", id: deleted.ID, expectedErr: domain.ErrLibraryItemNotFound},
+		{name: "deleted found by admins", role: domain.RoleAdmin, id: deleted.ID, expected: deleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rm := new(mockReadModel)
+			app := &App{ReadModel: rm}
+			user := domain.NewUserBuilder().WithDefault().Build()
+			if tt.role != "" {
+				user = domain.NewUserBuilder().WithDefault().Role(tt.role).Build()
+			}
+			require.NoError(t, user.SetRestrictions(domain.ContentRestrictions{MaxAge: 12}))
+			ctx := auth.WithUser(t.Context(), &user)
+			rm.On("GetLibraryItem", mock.Anything, visible.ID, user.ID()).Return(visible, nil)
+			rm.On("GetLibraryItem", mock.Anything, mature.ID, user.ID()).Return(mature, nil)
+			rm.On("GetLibraryItem", mock.Anything, deleted.ID, user.ID()).Return(deleted, nil)
+			rm.On("GetLibraryItem", mock.Anything, missing, user.ID()).Return(nil, domain.ErrLibraryItemNotFound)
+
+			got, err := app.GetLibraryItem(ctx, tt.id)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCursor_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	readAt := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	tests := []struct {
+		name   string
+		view   LibraryItemView
+		sortBy SortField
+	}{
+		{name: "title", view: LibraryItemView{ID: domain.NewLibraryItemID(), Title: "Воин"}, sortBy: SortByTitle},
+		{name: "added", view: LibraryItemView{ID: domain.NewLibraryItemID()}, sortBy: SortByAdded},
+		{name: "last read", view: LibraryItemView{ID: domain.NewLibraryItemID(), LastReadAt: &readAt}, sortBy: SortByLastRead},
+		{name: "never read", view: LibraryItemView{ID: domain.NewLibraryItemID()}, sortBy: SortByLastRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := CursorAfter(tt.view, tt.sortBy, Desc)
+			decoded, err := DecodeCursor(c.Encode())
+			require.NoError(t, err)
+			assert.Equal(t, c, decoded)
+		})
+	}
+}
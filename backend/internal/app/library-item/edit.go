@@ -0,0 +1,251 @@
+package library_item
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type LibraryItemRepo interface {
+	// GetLibraryItem returns domain.ErrLibraryItemNotFound if there is no item with the given id.
+	GetLibraryItem(context.Context, domain.LibraryItemID) (*domain.LibraryItem, error)
+	UpdateLibraryItems(context.Context, []*domain.LibraryItem) error
+}
+
+type AuthorRepo interface {
+	GetOrCreateAuthors(ctx context.Context, names []string) ([]domain.Author, error)
+}
+
+type SeriesRepo interface {
+	// GetOrCreateSeries returns the series with the given names, the missing ones are created.
+	GetOrCreateSeries(ctx context.Context, names []string) ([]*domain.Series, error)
+}
+
+type MetadataAuditRepo interface {
+	AddMetadataAuditEntry(context.Context, *domain.MetadataAuditEntry) error
+	// ListMetadataAuditEntries returns the entries of the item, the latest first.
+	ListMetadataAuditEntries(context.Context, domain.LibraryItemID) ([]*domain.MetadataAuditEntry, error)
+}
+
+// MetadataWriteBack writes the metadata of an item into its file, see sync_app.App.WriteBackMetadata.
+type MetadataWriteBack interface {
+	WriteBackMetadata(ctx context.Context, id domain.LibraryItemID) error
+}
+
+// SeriesRef places an item in the series with the name, which is added if the library has none
+// yet. An empty name takes the item out of its series.
+type SeriesRef struct {
+	Name  string
+	Index float64
+}
+
+type EditMetadataCmd struct {
+	ItemID domain.LibraryItemID
+	// The fields are as in domain.MetadataEdit, nil fields are left as they are.
+	Title    *string
+	ItemType *domain.LibraryItemType
+	// Authors are names, authors who are not in the library yet are added.
+	Authors    []string
+	Genre      []string
+	Languages  []string
+	Annotation *string
+	Series     *SeriesRef
+	// AgeRating overrides the rating read from the file, an empty rating clears it.
+	AgeRating *domain.AgeRating
+	// Unlock are fields rescans and metadata providers may change again.
+	Unlock []domain.MetadataField
+}
+
+// EditMetadata edits the metadata of an item and locks the edited fields, see
+// domain.LibraryItem.EditMetadata, and records the changes in the audit log. It requires
+// domain.PermEditMetadata. The edits are written back into the file if WriteBack is set, a
+// failure to do so is only logged, the library keeps the edits.
+func (a *App) EditMetadata(ctx context.Context, cmd EditMetadataCmd) (LibraryItemView, error) {
+	const op = errorx.Op("library_item.App.EditMetadata")
+
+	user, err := auth.Authorize(ctx, domain.PermEditMetadata)
+	if err != nil {
+		return LibraryItemView{}, op.Wrap(err)
+	}
+
+	var seriesName string
+	if cmd.Series != nil {
+		seriesName = cmd.Series.Name
+	}
+	err = v.Errors{
+		"authors": v.Validate(cmd.Authors,
+			v.When(cmd.Authors != nil, v.Required),
+			v.Each(vx.Required, v.Length(domain.MinAuthorNameLen, domain.MaxAuthorNameLen)),
+		),
+		"series": v.Errors{"name": v.Validate(seriesName, v.Length(0, domain.MaxSeriesNameLen))}.Filter(),
+	}.Filter()
+	if err != nil {
+		return LibraryItemView{}, op.Wrap(err)
+	}
+
+	var changed bool
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		item, err := a.LibraryItemRepo.GetLibraryItem(ctx, cmd.ItemID)
+		if err != nil {
+			return err
+		}
+		if item.IsDeleted() || !user.Restrictions().Permits(item.ContentAttrs()) {
+			return domain.ErrLibraryItemNotFound
+		}
+
+		edit := domain.MetadataEdit{
+			Title:      cmd.Title,
+			ItemType:   cmd.ItemType,
+			Genre:      cmd.Genre,
+			Languages:  cmd.Languages,
+			Annotation: cmd.Annotation,
+			AgeRating:  cmd.AgeRating,
+		}
+		if cmd.Series != nil {
+			edit.Series = &domain.ItemSeries{Index: cmd.Series.Index}
+			if cmd.Series.Name != "" {
+				series, err := a.SeriesRepo.GetOrCreateSeries(ctx, []string{cmd.Series.Name})
+				if err != nil {
+					return err
+				}
+				if len(series) > 0 {
+					edit.Series.ID = series[0].ID()
+				}
+			}
+		}
+		if cmd.Authors != nil {
+			authors, err := a.AuthorRepo.GetOrCreateAuthors(ctx, cmd.Authors)
+			if err != nil {
+				return err
+			}
+			edit.AuthorIDs, err = authorIDsInOrder(cmd.Authors, authors)
+			if err != nil {
+				return err
+			}
+		}
+
+		changes, err := item.EditMetadata(edit, cmd.Unlock)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		changed = true
+
+		entry, err := domain.NewMetadataAuditEntry(item.ID(), user.ID(), changes)
+		if err != nil {
+			return err
+		}
+		if err := a.LibraryItemRepo.UpdateLibraryItems(ctx, []*domain.LibraryItem{item}); err != nil {
+			return err
+		}
+		return a.MetadataAuditRepo.AddMetadataAuditEntry(ctx, entry)
+	})
+	if err != nil {
+		return LibraryItemView{}, op.Wrap(err)
+	}
+
+	if changed && a.WriteBack != nil {
+		if err := a.WriteBack.WriteBackMetadata(ctx, cmd.ItemID); err != nil {
+			slog.ErrorContext(ctx, "failed to write metadata back into the file", "item_id", cmd.ItemID, "error", err)
+		}
+	}
+
+	view, err := a.ReadModel.GetLibraryItem(ctx, cmd.ItemID, user.ID())
+	if err != nil {
+		return LibraryItemView{}, op.Wrap(err)
+	}
+	return view, nil
+}
+
+// authorIDsInOrder returns the ids of the authors named by names, in the order of names,
+// duplicate names are dropped. Names are compared regardless of case and spacing, as the repo
+// may store them normalized, a name without an author is an error rather than dropped.
+func authorIDsInOrder(names []string, authors []domain.Author) ([]domain.AuthorID, error) {
+	idByName := make(map[string]domain.AuthorID, len(authors))
+	for _, a := range authors {
+		idByName[authorKey(a.Name())] = a.ID()
+	}
+	ids := make([]domain.AuthorID, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := authorKey(name)
+		if seen[key] {
+			continue
+		}
+		id, ok := idByName[key]
+		if !ok {
+			return nil, fmt.Errorf("no author named %q", name)
+		}
+		ids = append(ids, id)
+		seen[key] = true
+	}
+	return ids, nil
+}
+
+func authorKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ListMetadataAudit returns who changed the metadata of the item and how, the latest change first.
+// It requires domain.PermEditMetadata.
+func (a *App) ListMetadataAudit(ctx context.Context, id domain.LibraryItemID) ([]*domain.MetadataAuditEntry, error) {
+	const op = errorx.Op("library_item.App.ListMetadataAudit")
+
+	user, err := auth.Authorize(ctx, domain.PermEditMetadata)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	item, err := a.LibraryItemRepo.GetLibraryItem(ctx, id)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	if !user.Restrictions().Permits(item.ContentAttrs()) {
+		return nil, op.Wrap(domain.ErrLibraryItemNotFound)
+	}
+
+	entries, err := a.MetadataAuditRepo.ListMetadataAuditEntries(ctx, id)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return entries, nil
+}
+
+// DeleteLibraryItem removes the item from the library, it requires domain.PermDeleteItems. The
+// file stays on disk and the item is only marked deleted, so reading progress and the audit log
+// survive. Deleting an item twice is not an error.
+func (a *App) DeleteLibraryItem(ctx context.Context, id domain.LibraryItemID) error {
+	const op = errorx.Op("library_item.App.DeleteLibraryItem")
+
+	user, err := auth.Authorize(ctx, domain.PermDeleteItems)
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		item, err := a.LibraryItemRepo.GetLibraryItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !user.Restrictions().Permits(item.ContentAttrs()) {
+			return domain.ErrLibraryItemNotFound
+		}
+		if item.IsDeleted() {
+			return nil
+		}
+
+		item.Delete()
+		return a.LibraryItemRepo.UpdateLibraryItems(ctx, []*domain.LibraryItem{item})
+	})
+	return op.Wrap(err)
+}
@@ -0,0 +1,367 @@
+package library_item
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type editMocks struct {
+	rm *mockReadModel
+	ir *mockLibraryItemRepo
+	ar *mockAuthorRepo
+	sr *mockSeriesRepo
+	mr *mockMetadataAuditRepo
+	wb *mockWriteBack
+}
+
+func newEditApp(t *testing.T) (*App, editMocks) {
+	t.Helper()
+	m := editMocks{
+		rm: new(mockReadModel),
+		ir: new(mockLibraryItemRepo),
+		ar: new(mockAuthorRepo),
+		sr: new(mockSeriesRepo),
+		mr: new(mockMetadataAuditRepo),
+		wb: new(mockWriteBack),
+	}
+	app := &App{
+		ReadModel:         m.rm,
+		Session:           new(mockSession),
+		LibraryItemRepo:   m.ir,
+		AuthorRepo:        m.ar,
+		SeriesRepo:        m.sr,
+		MetadataAuditRepo: m.mr,
+		WriteBack:         m.wb,
+	}
+	return app, m
+}
+
+func newEditItem(t *testing.T, rating domain.AgeRating) *domain.LibraryItem {
+	t.Helper()
+	item := domain.NewLibraryItemBuilder().
+		Id(domain.NewLibraryItemID()).
+		Title("Воин").
+		ItemType(domain.Book).
+		AuthorIDs([]domain.AuthorID{domain.NewAuthorID()}).
+		Path("Books/voin.epub").
+		AgeRating(rating).
+		Build()
+	return &item
+}
+
+func mustNewAuthor(t *testing.T, name string) domain.Author {
+	t.Helper()
+	a, err := domain.NewAuthor(domain.NewAuthorID(), name)
+	require.NoError(t, err)
+	return *a
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestApp_EditMetadata(t *testing.T) {
+	t.Parallel()
+
+	app, m := newEditApp(t)
+	ctx, user := userContext(t, domain.ContentRestrictions{})
+	item := newEditItem(t, "")
+	salvatore, nekrasova := mustNewAuthor(t, "Роберт Сальваторе"), mustNewAuthor(t, "Н. Некрасова")
+	darkElf, err := domain.NewSeries(domain.NewSeriesID(), "Темный эльф")
+	require.NoError(t, err)
+	view := LibraryItemView{ID: item.ID(), Title: "Воин (Темный эльф)"}
+
+	m.ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+	m.ar.On("GetOrCreateAuthors", mock.Anything, []string{"Роберт Сальваторе", "Н. Некрасова", "Роберт Сальваторе"}).
+		Return([]domain.Author{nekrasova, salvatore}, nil)
+	m.sr.On("GetOrCreateSeries", mock.Anything, []string{"Темный эльф"}).Return([]*domain.Series{darkElf}, nil)
+	m.ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{item}).Return(nil)
+	m.mr.On("AddMetadataAuditEntry", mock.Anything, mock.MatchedBy(func(e *domain.MetadataAuditEntry) bool {
+		return e.ItemID() == item.ID() && e.UserID() == user.ID() && len(e.Changes()) == 4
+	})).Return(nil)
+	m.wb.On("WriteBackMetadata", mock.Anything, item.ID()).Return(errors.New("read-only file system"))
+	m.rm.On("GetLibraryItem", mock.Anything, item.ID(), user.ID()).Return(view, nil)
+
+	got, err := app.EditMetadata(ctx, EditMetadataCmd{
+		ItemID:  item.ID(),
+		Title:   ptr("Воин (Темный эльф)"),
+		Authors: []string{"Роберт Сальваторе", "Н. Некрасова", "Роберт Сальваторе"},
+		Series:  &SeriesRef{Name: "Темный эльф", Index: 3},
+	})
+	require.NoError(t, err, "a failed write back keeps the edit")
+	assert.Equal(t, view, got)
+	assert.Equal(t, "Воин (Темный эльф)", item.Title())
+	assert.Equal(t, []domain.AuthorID{salvatore.ID(), nekrasova.ID()}, item.AuthorIDs())
+	assert.Equal(t, domain.ItemSeries{ID: darkElf.ID(), Index: 3}, item.Series())
+	assert.Equal(t, []domain.MetadataField{domain.FieldAuthors, domain.FieldSeries, domain.FieldTitle}, item.LockedFields())
+	m.mr.AssertExpectations(t)
+	m.wb.AssertExpectations(t)
+}
+
+func TestApp_EditMetadata_authors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("names normalized by the repo", func(t *testing.T) {
+		t.Parallel()
+		app, m := newEditApp(t)
+		ctx, user := userContext(t, domain.ContentRestrictions{})
+		item := newEditItem(t, "")
+		salvatore := mustNewAuthor(t, "Роберт Сальваторе")
+		m.ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		m.ar.On("GetOrCreateAuthors", mock.Anything, []string{" Роберт  Сальваторе", "роберт сальваторе"}).
+			Return([]domain.Author{salvatore}, nil)
+		m.ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{item}).Return(nil)
+		m.mr.On("AddMetadataAuditEntry", mock.Anything, mock.Anything).Return(nil)
+		m.wb.On("WriteBackMetadata", mock.Anything, item.ID()).Return(nil)
+		m.rm.On("GetLibraryItem", mock.Anything, item.ID(), user.ID()).Return(LibraryItemView{ID: item.ID()}, nil)
+
+		_, err := app.EditMetadata(ctx, EditMetadataCmd{ItemID: item.ID(), Authors: []string{" Роберт  Сальваторе", "роберт сальваторе"}})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.AuthorID{salvatore.ID()}, item.AuthorIDs())
+	})
+
+	t.Run("unresolved name", func(t *testing.T) {
+		t.Parallel()
+		app, m := newEditApp(t)
+		ctx, _ := userContext(t, domain.ContentRestrictions{})
+		item := newEditItem(t, "")
+		authorIDs := item.AuthorIDs()
+		salvatore := mustNewAuthor(t, "Роберт Сальваторе")
+		m.ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		m.ar.On("GetOrCreateAuthors", mock.Anything, []string{"Роберт Сальваторе", "Н. Некрасова"}).
+			Return([]domain.Author{salvatore}, nil)
+
+		_, err := app.EditMetadata(ctx, EditMetadataCmd{ItemID: item.ID(), Authors: []string{"Роберт Сальваторе", "Н. Некрасова"}})
+		require.Error(t, err)
+		assert.Equal(t, authorIDs, item.AuthorIDs())
+		m.ir.AssertNotCalled(t, "UpdateLibraryItems", mock.Anything, mock.Anything)
+	})
+}
+
+func TestApp_EditMetadata_unchanged(t *testing.T) {
+	t.Parallel()
+
+	app, m := newEditApp(t)
+	ctx, user := userContext(t, domain.ContentRestrictions{})
+	item := newEditItem(t, "")
+	_, err := item.EditMetadata(domain.MetadataEdit{Title: ptr("Воин")}, nil)
+	require.NoError(t, err)
+	m.ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+	m.rm.On("GetLibraryItem", mock.Anything, item.ID(), user.ID()).Return(LibraryItemView{ID: item.ID()}, nil)
+
+	_, err = app.EditMetadata(ctx, EditMetadataCmd{ItemID: item.ID(), Title: ptr("Воин")})
+	require.NoError(t, err)
+	m.ir.AssertNotCalled(t, "UpdateLibraryItems", mock.Anything, mock.Anything)
+	m.mr.AssertNotCalled(t, "AddMetadataAuditEntry", mock.Anything, mock.Anything)
+	m.wb.AssertNotCalled(t, "WriteBackMetadata", mock.Anything, mock.Anything)
+}
+
+func TestApp_EditMetadata_ageRating(t *testing.T) {
+	t.Parallel()
+
+	app, m := newEditApp(t)
+	ctx, user := userContext(t, domain.ContentRestrictions{})
+	item := newEditItem(t, domain.AgeRatingEveryone)
+	m.ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+	m.ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{item}).Return(nil)
+	m.mr.On("AddMetadataAuditEntry", mock.Anything, mock.Anything).Return(nil)
+	m.wb.On("WriteBackMetadata", mock.Anything, item.ID()).Return(nil)
+	m.rm.On("GetLibraryItem", mock.Anything, item.ID(), user.ID()).Return(LibraryItemView{ID: item.ID()}, nil)
+
+	_, err := app.EditMetadata(ctx, EditMetadataCmd{ItemID: item.ID(), AgeRating: ptr(domain.AgeRatingMature17)})
+	require.NoError(t, err)
+	assert.Equal(t, domain.AgeRatingMature17, item.AgeRating())
+	assert.True(t, item.IsLocked(domain.FieldAgeRating), "rescans keep the override")
+	assert.False(t, domain.ContentRestrictions{MaxAge: 12}.Permits(item.ContentAttrs()))
+}
+
+func TestApp_EditMetadata_errors(t *testing.T) {
+	t.Parallel()
+
+	deleted := newEditItem(t, "")
+	deleted.Delete()
+
+	tests := []struct {
+		name         string
+		role         domain.Role
+		restrictions domain.ContentRestrictions
+		item         *domain.LibraryItem
+		cmd          func(id domain.LibraryItemID) EditMetadataCmd
+		wantErr      error
+	}{
+		{
+			name: "guest",
+			role: domain.RoleGuest,
+			item: newEditItem(t, ""),
+			cmd: func(id domain.LibraryItemID) EditMetadataCmd {
+				return EditMetadataCmd{ItemID: id, Title: ptr("Воин")}
+			},
+			wantErr: domain.ErrPermissionDenied,
+		},
+		{
+			name:         "restricted item",
+			role:         domain.RoleMember,
+			restrictions: domain.ContentRestrictions{MaxAge: 12},
+			item:         newEditItem(t, domain.AgeRatingAdultsOnly18),
+			cmd: func(id domain.LibraryItemID) EditMetadataCmd {
+				return EditMetadataCmd{ItemID: id, Title: ptr("Воин")}
+			},
+			wantErr: domain.ErrLibraryItemNotFound,
+		},
+		{
+			name: "deleted item",
+			role: domain.RoleMember,
+			item: deleted,
+			cmd: func(id domain.LibraryItemID) EditMetadataCmd {
+				return EditMetadataCmd{ItemID: id, Title: ptr("Воин")}
+			},
+			wantErr: domain.ErrLibraryItemNotFound,
+		},
+		{
+			name: "invalid authors",
+			role: domain.RoleMember,
+			item: newEditItem(t, ""),
+			cmd: func(id domain.LibraryItemID) EditMetadataCmd {
+				return EditMetadataCmd{ItemID: id, Authors: []string{"Роберт Сальваторе", ""}}
+			},
+			wantErr: v.Errors{"authors": v.Errors{"1": v.ErrRequired}},
+		},
+		{
+			name:    "no authors",
+			role:    domain.RoleMember,
+			item:    newEditItem(t, ""),
+			cmd:     func(id domain.LibraryItemID) EditMetadataCmd { return EditMetadataCmd{ItemID: id, Authors: []string{}} },
+			wantErr: v.Errors{"authors": v.ErrRequired},
+		},
+		{
+			name: "invalid series",
+			role: domain.RoleMember,
+			item: newEditItem(t, ""),
+			cmd: func(id domain.LibraryItemID) EditMetadataCmd {
+				return EditMetadataCmd{ItemID: id, Series: &SeriesRef{Name: strings.Repeat("x", domain.MaxSeriesNameLen+1)}}
+			},
+			wantErr: v.Errors{"series": v.Errors{"name": v.ErrLengthTooLong}},
+		},
+		{
+			name: "index without series",
+			role: domain.RoleMember,
+			item: newEditItem(t, ""),
+			cmd: func(id domain.LibraryItemID) EditMetadataCmd {
+				return EditMetadataCmd{ItemID: id, Series: &SeriesRef{Index: 2}}
+			},
+			wantErr: v.Errors{"series": v.Errors{"index": v.ErrEmpty}},
+		},
+		{
+			name: "invalid edit",
+			role: domain.RoleMember,
+			item: newEditItem(t, ""),
+			cmd: func(id domain.LibraryItemID) EditMetadataCmd {
+				return EditMetadataCmd{ItemID: id, Title: ptr(""), Unlock: []domain.MetadataField{"path"}}
+			},
+			wantErr: v.Errors{"title": v.ErrRequired, "unlock": v.Errors{"0": v.ErrInInvalid}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, m := newEditApp(t)
+			user := domain.NewUserBuilder().WithDefault().Role(tt.role).Build()
+			require.NoError(t, user.SetRestrictions(tt.restrictions))
+			ctx := auth.WithUser(t.Context(), &user)
+			m.ir.On("GetLibraryItem", mock.Anything, tt.item.ID()).Return(tt.item, nil)
+
+			_, err := app.EditMetadata(ctx, tt.cmd(tt.item.ID()))
+			var verrs v.Errors
+			if errors.As(tt.wantErr, &verrs) {
+				vx.AssertValidationErrors(t, err, tt.wantErr)
+			} else {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+			m.ir.AssertNotCalled(t, "UpdateLibraryItems", mock.Anything, mock.Anything)
+			m.mr.AssertNotCalled(t, "AddMetadataAuditEntry", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestApp_ListMetadataAudit(t *testing.T) {
+	t.Parallel()
+
+	app, m := newEditApp(t)
+	ctx, user := userContext(t, domain.ContentRestrictions{})
+	item := newEditItem(t, "")
+	entry, err := domain.NewMetadataAuditEntry(item.ID(), user.ID(), []domain.MetadataChange{{Field: domain.FieldTitle, Old: "a", New: "b"}})
+	require.NoError(t, err)
+	m.ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+	m.mr.On("ListMetadataAuditEntries", mock.Anything, item.ID()).Return([]*domain.MetadataAuditEntry{entry}, nil)
+
+	entries, err := app.ListMetadataAudit(ctx, item.ID())
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.MetadataAuditEntry{entry}, entries)
+
+	guest := domain.NewUserBuilder().WithDefault().Role(domain.RoleGuest).Build()
+	_, err = app.ListMetadataAudit(auth.WithUser(context.Background(), &guest), item.ID())
+	require.ErrorIs(t, err, domain.ErrPermissionDenied)
+}
+
+func TestApp_DeleteLibraryItem(t *testing.T) {
+	t.Parallel()
+
+	admin := domain.NewUserBuilder().WithDefault().Role(domain.RoleAdmin).Build()
+	member := domain.NewUserBuilder().WithDefault().Role(domain.RoleMember).Build()
+	restricted := domain.NewUserBuilder().WithDefault().Role(domain.RoleAdmin).Build()
+	require.NoError(t, restricted.SetRestrictions(domain.ContentRestrictions{MaxAge: 12}))
+
+	tests := []struct {
+		name        string
+		user        *domain.User
+		item        *domain.LibraryItem
+		updated     bool
+		expectedErr error
+	}{
+		{name: "admin", user: &admin, item: newEditItem(t, ""), updated: true},
+		{name: "already deleted", user: &admin, item: func() *domain.LibraryItem {
+			item := newEditItem(t, "")
+			item.Delete()
+			return item
+		}()},
+		{name: "member", user: &member, item: newEditItem(t, ""), expectedErr: domain.ErrPermissionDenied},
+		{name: "hidden by restrictions", user: &restricted, item: newEditItem(t, domain.AgeRatingMature17), expectedErr: domain.ErrLibraryItemNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, m := newEditApp(t)
+			m.ir.On("GetLibraryItem", mock.Anything, tt.item.ID()).Return(tt.item, nil)
+			m.ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{tt.item}).Return(nil)
+
+			err := app.DeleteLibraryItem(auth.WithUser(t.Context(), tt.user), tt.item.ID())
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.False(t, tt.item.IsDeleted())
+				m.ir.AssertNotCalled(t, "UpdateLibraryItems", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.item.IsDeleted())
+			if tt.updated {
+				m.ir.AssertCalled(t, "UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{tt.item})
+			} else {
+				m.ir.AssertNotCalled(t, "UpdateLibraryItems", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package library_item
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+// Facet is a property items are grouped by when browsing, e.g. in OPDS navigation feeds.
+type Facet string
+
+const (
+	// FacetAuthor values are author ids, labels are their names.
+	FacetAuthor Facet = "author"
+	// FacetSeries values are series ids, labels are their names.
+	FacetSeries   Facet = "series"
+	FacetGenre    Facet = "genre"
+	FacetLanguage Facet = "language"
+)
+
+type ListFacetValuesQuery struct {
+	Facet Facet
+	// Cursor is the opaque value of FacetValuePage.NextCursor from the previous page.
+	Cursor string
+	Limit  int
+}
+
+// FacetValuesFilter is the validated query passed down to the ReadModel.
+type FacetValuesFilter struct {
+	Facet Facet
+	// Restrictions of the reader, the ReadModel must only count items they permit
+	// and leave out values without such items.
+	Restrictions domain.ContentRestrictions
+	// After is the keyset position to continue from, nil for the first page.
+	After *FacetCursor
+	Limit int
+}
+
+// FacetValue is a value of a facet and the number of not deleted items that have it.
+type FacetValue struct {
+	Value string
+	Label string
+	Count int
+}
+
+type FacetValuePage struct {
+	Values []FacetValue
+	// NextCursor is empty when there are no more values.
+	NextCursor string
+}
+
+// FacetCursor is the label and value of the last value of a page, values are ordered by both.
+type FacetCursor struct {
+	Facet Facet  `json:"f"`
+	Label string `json:"l"`
+	Value string `json:"v"`
+}
+
+func (c FacetCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeFacetCursor returns vx.ErrInvalidCursor if s is not a cursor encoded by FacetCursor.Encode.
+func DecodeFacetCursor(s string) (FacetCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return FacetCursor{}, vx.ErrInvalidCursor
+	}
+	var c FacetCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Value == "" {
+		return FacetCursor{}, vx.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ListFacetValues returns a page of the values of q.Facet among the items the user in ctx is
+// permitted to see, ordered by label.
+func (a *App) ListFacetValues(ctx context.Context, q ListFacetValuesQuery) (FacetValuePage, error) {
+	const op = errorx.Op("library_item.App.ListFacetValues")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return FacetValuePage{}, op.Wrap(err)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = vx.DefaultPageLimit
+	}
+
+	var after *FacetCursor
+	err = v.Errors{
+		"facet": v.Validate(q.Facet, vx.Required, v.In(FacetAuthor, FacetSeries, FacetGenre, FacetLanguage)),
+		"limit": v.Validate(q.Limit, v.Min(1), v.Max(vx.MaxPageLimit)),
+		"cursor": v.Validate(q.Cursor, vx.Cursor(func(cursor string) error {
+			c, err := DecodeFacetCursor(cursor)
+			if err != nil {
+				return err
+			}
+			if c.Facet != q.Facet {
+				return vx.ErrInvalidCursor
+			}
+			after = &c
+			return nil
+		})),
+	}.Filter()
+	if err != nil {
+		return FacetValuePage{}, op.Wrap(err)
+	}
+
+	values, err := a.ReadModel.ListFacetValues(ctx, FacetValuesFilter{
+		Facet:        q.Facet,
+		Restrictions: user.Restrictions(),
+		After:        after,
+		Limit:        q.Limit + 1,
+	})
+	if err != nil {
+		return FacetValuePage{}, op.Wrap(err)
+	}
+
+	page := FacetValuePage{Values: values}
+	if len(values) > q.Limit {
+		page.Values = values[:q.Limit]
+		last := page.Values[q.Limit-1]
+		page.NextCursor = FacetCursor{Facet: q.Facet, Label: last.Label, Value: last.Value}.Encode()
+	}
+
+	return page, nil
+}
@@ -0,0 +1,92 @@
+package library_item
+
+import (
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func TestApp_ListFacetValues_pagination(t *testing.T) {
+	t.Parallel()
+
+	rm := new(mockReadModel)
+	app := &App{ReadModel: rm}
+	restrictions := domain.ContentRestrictions{MaxAge: 12}
+	ctx, _ := userContext(t, restrictions)
+
+	first := []FacetValue{
+		{Value: "en", Label: "en", Count: 3},
+		{Value: "kk", Label: "kk", Count: 1},
+		{Value: "ru", Label: "ru", Count: 2},
+	}
+	rm.On("ListFacetValues", mock.Anything, FacetValuesFilter{
+		Facet:        FacetLanguage,
+		Restrictions: restrictions,
+		Limit:        3,
+	}).Return(first, nil).Once()
+
+	page, err := app.ListFacetValues(ctx, ListFacetValuesQuery{Facet: FacetLanguage, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, first[:2], page.Values)
+	require.NotEmpty(t, page.NextCursor)
+
+	rm.On("ListFacetValues", mock.Anything, mock.MatchedBy(func(f FacetValuesFilter) bool {
+		return f.After != nil && *f.After == FacetCursor{Facet: FacetLanguage, Label: "kk", Value: "kk"}
+	})).Return(first[2:], nil).Once()
+
+	page, err = app.ListFacetValues(ctx, ListFacetValuesQuery{Facet: FacetLanguage, Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, first[2:], page.Values)
+	assert.Empty(t, page.NextCursor)
+	rm.AssertExpectations(t)
+}
+
+func TestApp_ListFacetValues_validation(t *testing.T) {
+	t.Parallel()
+
+	genreCursor := FacetCursor{Facet: FacetGenre, Label: "Horror", Value: "Horror"}.Encode()
+
+	tests := []struct {
+		name        string
+		query       ListFacetValuesQuery
+		expectedErr error
+	}{
+		{
+			name:        "missing facet",
+			query:       ListFacetValuesQuery{},
+			expectedErr: v.Errors{"facet": v.ErrRequired},
+		},
+		{
+			name:        "unknown facet",
+			query:       ListFacetValuesQuery{Facet: "publisher"},
+			expectedErr: v.Errors{"facet": v.ErrInInvalid},
+		},
+		{
+			name:        "cursor of another facet",
+			query:       ListFacetValuesQuery{Facet: FacetAuthor, Cursor: genreCursor},
+			expectedErr: v.Errors{"cursor": vx.ErrInvalidCursor},
+		},
+		{
+			name:        "malformed cursor",
+			query:       ListFacetValuesQuery{Facet: FacetAuthor, Cursor: "not a cursor"},
+			expectedErr: v.Errors{"cursor": vx.ErrInvalidCursor},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{ReadModel: new(mockReadModel)}
+			ctx, _ := userContext(t, domain.ContentRestrictions{})
+			_, err := app.ListFacetValues(ctx, tt.query)
+			vx.AssertValidationErrors(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package library_item
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type SortField string
+
+const (
+	SortByTitle    SortField = "title"
+	SortByAdded    SortField = "added"
+	SortByLastRead SortField = "last_read"
+)
+
+type SortOrder string
+
+const (
+	Asc  SortOrder = "asc"
+	Desc SortOrder = "desc"
+)
+
+type DeletedFilter string
+
+const (
+	NotDeleted  DeletedFilter = "not_deleted"
+	OnlyDeleted DeletedFilter = "only_deleted"
+	AnyDeleted  DeletedFilter = "any"
+)
+
+// ListLibraryItemsQuery is the input of App.ListLibraryItems, zero values mean "no filter".
+type ListLibraryItemsQuery struct {
+	Types       []domain.LibraryItemType
+	AuthorID    domain.AuthorID
+	SeriesID    domain.SeriesID
+	Genre       string
+	Language    string
+	TitlePrefix string
+	// Search matches words of the title or author names.
+	Search  string
+	Deleted DeletedFilter
+
+	SortBy    SortField
+	SortOrder SortOrder
+
+	// Cursor is the opaque value of LibraryItemPage.NextCursor from the previous page.
+	Cursor string
+	Limit  int
+}
+
+// LibraryItemsFilter is the validated query passed down to the ReadModel.
+type LibraryItemsFilter struct {
+	Types       []domain.LibraryItemType
+	AuthorID    domain.AuthorID
+	SeriesID    domain.SeriesID
+	Genre       string
+	Language    string
+	TitlePrefix string
+	Search      string
+	Deleted     DeletedFilter
+
+	SortBy    SortField
+	SortOrder SortOrder
+	// ReaderID is the user whose reading history is used for SortByLastRead and LastReadAt.
+	ReaderID domain.UserID
+	// Restrictions of the reader, the ReadModel must leave out items they do not permit.
+	Restrictions domain.ContentRestrictions
+
+	// After is the keyset position to continue from, nil for the first page.
+	After *Cursor
+	// Limit is the maximum number of items the ReadModel should return.
+	Limit int
+}
+
+type AuthorView struct {
+	ID   domain.AuthorID
+	Name string
+}
+
+type LibraryItemView struct {
+	ID      domain.LibraryItemID
+	Title   string
+	Type    domain.LibraryItemType
+	Authors []AuthorView
+	// Contributors are the people other than authors, see domain.LibraryItem.Contributors.
+	Contributors []domain.Contributor
+	// SeriesID is nil and Series empty if the item is in no series.
+	SeriesID domain.SeriesID
+	// Series is the name of the series.
+	Series string
+	// SeriesIndex is the position in Series, zero if unknown.
+	SeriesIndex float64
+	Genre       []string
+	Languages   []string
+	Annotation  string
+	Root        string
+	Format      domain.FileFormat
+	// PageCount is the number of pages of PDFs and comic archives, zero for other formats.
+	PageCount int
+	Tags      []string
+	AgeRating domain.AgeRating
+	// PublicationModified is zero if unknown, see domain.LibraryItem.PublicationModified.
+	PublicationModified time.Time
+	AddedAt             time.Time
+	DeletedAt           *time.Time
+	LastReadAt          *time.Time
+	// DRM is the scheme protecting the file, empty if it is not protected, see domain.LibraryItem.DRM.
+	DRM string
+	// LockedFields were edited by hand, see domain.LibraryItem.LockedFields.
+	LockedFields []domain.MetadataField
+}
+
+// Readable reports whether the item can be read without a licensed reading system.
+func (v LibraryItemView) Readable() bool {
+	return v.DRM == ""
+}
+
+func (v LibraryItemView) ContentAttrs() domain.ContentAttrs {
+	return domain.ContentAttrs{
+		Type:      v.Type,
+		Genres:    v.Genre,
+		Root:      v.Root,
+		Tags:      v.Tags,
+		AgeRating: v.AgeRating,
+	}
+}
+
+type LibraryItemPage struct {
+	Items []LibraryItemView
+	// NextCursor is empty when there are no more items.
+	NextCursor string
+}
+
+// Cursor is a keyset position: the sort key and id of the last item of a page.
+// Because it points at values rather than offsets, pages stay stable when items are inserted.
+type Cursor struct {
+	SortBy    SortField `json:"s"`
+	SortOrder SortOrder `json:"o"`
+	// Value is the sort key of the last item: title for SortByTitle, RFC 3339 time for SortByLastRead
+	// (empty if the item was never read), unused for SortByAdded since the id itself is the key.
+	Value string               `json:"v,omitempty"`
+	ID    domain.LibraryItemID `json:"id"`
+}
+
+func CursorAfter(view LibraryItemView, sortBy SortField, order SortOrder) Cursor {
+	c := Cursor{SortBy: sortBy, SortOrder: order, ID: view.ID}
+	switch sortBy {
+	case SortByTitle:
+		c.Value = view.Title
+	case SortByLastRead:
+		if view.LastReadAt != nil {
+			c.Value = view.LastReadAt.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return c
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns vx.ErrInvalidCursor if s is not a cursor encoded by Cursor.Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, vx.ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID.IsNil() {
+		return Cursor{}, vx.ErrInvalidCursor
+	}
+	if c.SortBy == SortByLastRead && c.Value != "" {
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return Cursor{}, vx.ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
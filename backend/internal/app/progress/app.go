@@ -0,0 +1,167 @@
+package progress
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type ReadingProgressRepo interface {
+	// GetReadingProgress returns domain.ErrReadingProgressNotFound if the user has no progress in the item.
+	GetReadingProgress(context.Context, domain.UserID, domain.LibraryItemID) (*domain.ReadingProgress, error)
+	// SaveReadingProgress creates or replaces the progress of its user in its item.
+	SaveReadingProgress(context.Context, *domain.ReadingProgress) error
+}
+
+type LibraryItemRepo interface {
+	// GetLibraryItem returns domain.ErrLibraryItemNotFound if there is no item with the given id.
+	GetLibraryItem(context.Context, domain.LibraryItemID) (*domain.LibraryItem, error)
+	// GetLibraryItemByPartialMD5 returns domain.ErrLibraryItemNotFound if no item has the KOReader hash.
+	GetLibraryItemByPartialMD5(ctx context.Context, hash string) (*domain.LibraryItem, error)
+}
+
+type App struct {
+	Session             dbx.Session
+	ReadingProgressRepo ReadingProgressRepo
+	LibraryItemRepo     LibraryItemRepo
+}
+
+// GetProgress returns the progress of the user in ctx in the item.
+func (a *App) GetProgress(ctx context.Context, itemID domain.LibraryItemID) (*domain.ReadingProgress, error) {
+	const op = errorx.Op("progress.App.GetProgress")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	if _, err := a.visibleItem(ctx, user, itemID); err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	p, err := a.ReadingProgressRepo.GetReadingProgress(ctx, user.ID(), itemID)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return p, nil
+}
+
+type SaveProgressCmd struct {
+	ItemID  domain.LibraryItemID
+	Locator domain.Locator
+	Device  string
+	// At is when the device was at Locator, zero means now.
+	At time.Time
+	// BaseVersion is the progress version the device last saw, zero if it has never seen any.
+	BaseVersion int
+	// Overwrite skips conflict detection, for clients that do not track versions such as KOReader.
+	Overwrite bool
+}
+
+type SaveProgressResult struct {
+	Progress *domain.ReadingProgress
+	// Conflict is set when another device moved the progress since the device last synced.
+	Conflict *domain.ProgressConflict
+}
+
+// SaveProgress records the position of the user in ctx, see domain.ReadingProgress.Update for conflicts.
+func (a *App) SaveProgress(ctx context.Context, cmd SaveProgressCmd) (SaveProgressResult, error) {
+	const op = errorx.Op("progress.App.SaveProgress")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeWriteProgress)
+	if err != nil {
+		return SaveProgressResult{}, op.Wrap(err)
+	}
+
+	var res SaveProgressResult
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		item, err := a.visibleItem(ctx, user, cmd.ItemID)
+		if err != nil {
+			return err
+		}
+
+		err = v.Errors{
+			"locator": v.Errors{
+				"kind": v.Validate(cmd.Locator.Kind, v.By(func(any) error {
+					if !cmd.Locator.Kind.Supports(item.Format()) {
+						return vx.ErrUnsupportedLocator
+					}
+					return nil
+				})),
+			}.Filter(),
+		}.Filter()
+		if err != nil {
+			return err
+		}
+
+		p, err := a.ReadingProgressRepo.GetReadingProgress(ctx, user.ID(), item.ID())
+		switch {
+		case errors.Is(err, domain.ErrReadingProgressNotFound):
+			p, err = domain.NewReadingProgress(user.ID(), item.ID(), cmd.Locator, cmd.Device, cmd.At)
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if cmd.Overwrite {
+				cmd.BaseVersion = p.Version()
+			}
+			res.Conflict, err = p.Update(cmd.Locator, cmd.Device, cmd.At, cmd.BaseVersion)
+			if err != nil {
+				return err
+			}
+			if res.Conflict != nil && !res.Conflict.Overwritten {
+				res.Progress = p
+				return nil
+			}
+		}
+
+		res.Progress = p
+		return a.ReadingProgressRepo.SaveReadingProgress(ctx, p)
+	})
+	if err != nil {
+		return SaveProgressResult{}, op.Wrap(err)
+	}
+
+	return res, nil
+}
+
+// FindDocument returns the item with the KOReader document hash, see koreaderx.PartialMD5.
+func (a *App) FindDocument(ctx context.Context, partialMD5 string) (*domain.LibraryItem, error) {
+	const op = errorx.Op("progress.App.FindDocument")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	item, err := a.LibraryItemRepo.GetLibraryItemByPartialMD5(ctx, partialMD5)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	if !user.Restrictions().Permits(item.ContentAttrs()) {
+		return nil, op.Wrap(domain.ErrLibraryItemNotFound)
+	}
+	return item, nil
+}
+
+// visibleItem returns domain.ErrLibraryItemNotFound for items hidden from the user by their restrictions.
+func (a *App) visibleItem(ctx context.Context, user *domain.User, id domain.LibraryItemID) (*domain.LibraryItem, error) {
+	item, err := a.LibraryItemRepo.GetLibraryItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Restrictions().Permits(item.ContentAttrs()) {
+		return nil, domain.ErrLibraryItemNotFound
+	}
+	return item, nil
+}
@@ -0,0 +1,249 @@
+package progress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type mockReadingProgressRepo struct{ mock.Mock }
+
+func (m *mockReadingProgressRepo) GetReadingProgress(ctx context.Context, userID domain.UserID, itemID domain.LibraryItemID) (*domain.ReadingProgress, error) {
+	args := m.Called(ctx, userID, itemID)
+	p, _ := args.Get(0).(*domain.ReadingProgress)
+	return p, args.Error(1)
+}
+
+func (m *mockReadingProgressRepo) SaveReadingProgress(ctx context.Context, p *domain.ReadingProgress) error {
+	return m.Called(ctx, p).Error(0)
+}
+
+type mockLibraryItemRepo struct{ mock.Mock }
+
+func (m *mockLibraryItemRepo) GetLibraryItem(ctx context.Context, id domain.LibraryItemID) (*domain.LibraryItem, error) {
+	args := m.Called(ctx, id)
+	item, _ := args.Get(0).(*domain.LibraryItem)
+	return item, args.Error(1)
+}
+
+func (m *mockLibraryItemRepo) GetLibraryItemByPartialMD5(ctx context.Context, hash string) (*domain.LibraryItem, error) {
+	args := m.Called(ctx, hash)
+	item, _ := args.Get(0).(*domain.LibraryItem)
+	return item, args.Error(1)
+}
+
+type mockSession struct{ mock.Mock }
+
+func (m *mockSession) Transaction(ctx context.Context, f func(context.Context) error) error {
+	return f(ctx)
+}
+
+func (m *mockSession) Begin(ctx context.Context) (dbx.Session, error) {
+	args := m.Called(ctx)
+	s, _ := args.Get(0).(dbx.Session)
+	return s, args.Error(1)
+}
+
+func (m *mockSession) Rollback() error          { return m.Called().Error(0) }
+func (m *mockSession) Commit() error            { return m.Called().Error(0) }
+func (m *mockSession) Context() context.Context { return m.Called().Get(0).(context.Context) }
+
+// --- Helpers ---
+
+func newTestApp(t *testing.T) (*App, *mockReadingProgressRepo, *mockLibraryItemRepo) {
+	t.Helper()
+	pr := new(mockReadingProgressRepo)
+	ir := new(mockLibraryItemRepo)
+	return &App{Session: new(mockSession), ReadingProgressRepo: pr, LibraryItemRepo: ir}, pr, ir
+}
+
+func newItem(path string, rating domain.AgeRating) *domain.LibraryItem {
+	item := domain.NewLibraryItemBuilder().
+		Id(domain.NewLibraryItemID()).
+		ItemType(domain.Book).
+		Path(path).
+		AgeRating(rating).
+		Build()
+	return &item
+}
+
+func userContext(t *testing.T, r domain.ContentRestrictions) (context.Context, *domain.User) {
+	t.Helper()
+	user := domain.NewUserBuilder().WithDefault().Id(domain.NewUserID()).Build()
+	require.NoError(t, user.SetRestrictions(r))
+	return auth.WithUser(t.Context(), &user), &user
+}
+
+func withToken(t *testing.T, ctx context.Context, user *domain.User, scopes ...domain.Scope) context.Context {
+	t.Helper()
+	token, _, err := domain.NewAPIToken(user.ID(), "reader", scopes, nil)
+	require.NoError(t, err)
+	return auth.WithAPIToken(ctx, token)
+}
+
+var cfi = domain.Locator{Kind: domain.LocatorCFI, CFI: "epubcfi(/6/4!/4/2/1:0)", Percentage: 0.25}
+
+func TestApp_SaveProgress(t *testing.T) {
+	t.Parallel()
+
+	t.Run("first save", func(t *testing.T) {
+		t.Parallel()
+		app, pr, ir := newTestApp(t)
+		ctx, user := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Books/Author/Book/book.epub", "")
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		pr.On("GetReadingProgress", mock.Anything, user.ID(), item.ID()).Return(nil, domain.ErrReadingProgressNotFound)
+		pr.On("SaveReadingProgress", mock.Anything, mock.Anything).Return(nil)
+
+		res, err := app.SaveProgress(ctx, SaveProgressCmd{ItemID: item.ID(), Locator: cfi, Device: "kobo"})
+		require.NoError(t, err)
+		assert.Nil(t, res.Conflict)
+		assert.Equal(t, cfi, res.Progress.Locator())
+		assert.Equal(t, "kobo", res.Progress.Device())
+		assert.Equal(t, 1, res.Progress.Version())
+		pr.AssertCalled(t, "SaveReadingProgress", mock.Anything, res.Progress)
+	})
+
+	t.Run("stale update is not saved", func(t *testing.T) {
+		t.Parallel()
+		app, pr, ir := newTestApp(t)
+		ctx, user := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Books/Author/Book/book.epub", "")
+		current, err := domain.NewReadingProgress(user.ID(), item.ID(), cfi, "kobo", time.Now())
+		require.NoError(t, err)
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		pr.On("GetReadingProgress", mock.Anything, user.ID(), item.ID()).Return(current, nil)
+
+		res, err := app.SaveProgress(ctx, SaveProgressCmd{
+			ItemID:  item.ID(),
+			Locator: domain.Locator{Kind: domain.LocatorPercentage, Percentage: 0.1},
+			Device:  "phone",
+			At:      time.Now().Add(-time.Hour),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res.Conflict)
+		assert.False(t, res.Conflict.Overwritten)
+		assert.Equal(t, "kobo", res.Conflict.Device)
+		assert.Same(t, current, res.Progress)
+		pr.AssertNotCalled(t, "SaveReadingProgress", mock.Anything, mock.Anything)
+	})
+
+	t.Run("overwrite skips conflict detection", func(t *testing.T) {
+		t.Parallel()
+		app, pr, ir := newTestApp(t)
+		ctx, user := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Books/Author/Book/book.epub", "")
+		current, err := domain.NewReadingProgress(user.ID(), item.ID(), cfi, "kobo", time.Now())
+		require.NoError(t, err)
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+		pr.On("GetReadingProgress", mock.Anything, user.ID(), item.ID()).Return(current, nil)
+		pr.On("SaveReadingProgress", mock.Anything, current).Return(nil)
+
+		next := domain.Locator{Kind: domain.LocatorPercentage, Percentage: 0.1}
+		res, err := app.SaveProgress(ctx, SaveProgressCmd{ItemID: item.ID(), Locator: next, Device: "koreader", Overwrite: true})
+		require.NoError(t, err)
+		assert.Nil(t, res.Conflict)
+		assert.Equal(t, next, res.Progress.Locator())
+		assert.Equal(t, 2, res.Progress.Version())
+	})
+
+	t.Run("locator of another format", func(t *testing.T) {
+		t.Parallel()
+		app, _, ir := newTestApp(t)
+		ctx, _ := userContext(t, domain.ContentRestrictions{})
+		item := newItem("Comics/Plastic Man (1944)/Plastic Man #002 (1944).cbz", "")
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+
+		_, err := app.SaveProgress(ctx, SaveProgressCmd{ItemID: item.ID(), Locator: cfi})
+		vx.AssertValidationErrors(t, err, v.Errors{"locator": v.Errors{"kind": vx.ErrUnsupportedLocator}})
+	})
+
+	t.Run("restricted item looks missing", func(t *testing.T) {
+		t.Parallel()
+		app, pr, ir := newTestApp(t)
+		ctx, _ := userContext(t, domain.ContentRestrictions{MaxAge: 12})
+		item := newItem("Books/Author/Book/book.epub", domain.AgeRatingAdultsOnly18)
+		ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+
+		_, err := app.SaveProgress(ctx, SaveProgressCmd{ItemID: item.ID(), Locator: cfi})
+		require.ErrorIs(t, err, domain.ErrLibraryItemNotFound)
+		pr.AssertNotCalled(t, "GetReadingProgress", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("token without progress scope", func(t *testing.T) {
+		t.Parallel()
+		app, _, ir := newTestApp(t)
+		ctx, user := userContext(t, domain.ContentRestrictions{})
+		ctx = withToken(t, ctx, user, domain.ScopeReadLibrary)
+
+		_, err := app.SaveProgress(ctx, SaveProgressCmd{ItemID: domain.NewLibraryItemID(), Locator: cfi})
+		require.ErrorIs(t, err, domain.ErrInsufficientScope)
+		ir.AssertNotCalled(t, "GetLibraryItem", mock.Anything, mock.Anything)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		t.Parallel()
+		app, _, _ := newTestApp(t)
+
+		_, err := app.SaveProgress(t.Context(), SaveProgressCmd{ItemID: domain.NewLibraryItemID(), Locator: cfi})
+		require.ErrorIs(t, err, domain.ErrUnauthorized)
+	})
+}
+
+func TestApp_GetProgress(t *testing.T) {
+	t.Parallel()
+
+	app, pr, ir := newTestApp(t)
+	ctx, user := userContext(t, domain.ContentRestrictions{})
+	ctx = withToken(t, ctx, user, domain.ScopeReadLibrary)
+	item := newItem("Books/Author/Book/book.epub", "")
+	current, err := domain.NewReadingProgress(user.ID(), item.ID(), cfi, "kobo", time.Now())
+	require.NoError(t, err)
+	ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+	pr.On("GetReadingProgress", mock.Anything, user.ID(), item.ID()).Return(current, nil)
+
+	got, err := app.GetProgress(ctx, item.ID())
+	require.NoError(t, err)
+	assert.Same(t, current, got)
+}
+
+func TestApp_FindDocument(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		restrictions domain.ContentRestrictions
+		expectedErr  error
+	}{
+		{name: "found"},
+		{name: "restricted looks missing", restrictions: domain.ContentRestrictions{MaxAge: 12}, expectedErr: domain.ErrLibraryItemNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _, ir := newTestApp(t)
+			ctx, _ := userContext(t, tt.restrictions)
+			item := newItem("Books/Author/Book/book.epub", domain.AgeRatingMature17)
+			ir.On("GetLibraryItemByPartialMD5", mock.Anything, "5f4dcc3b5aa765d61d8327deb882cf99").Return(item, nil)
+
+			got, err := app.FindDocument(ctx, "5f4dcc3b5aa765d61d8327deb882cf99")
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Same(t, item, got)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package series
+
+import (
+	"context"
+	"fmt"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+var (
+	ErrSeriesNameTaken = fmt.Errorf("series name is taken: %w", domain.ErrConflict)
+	// ErrNothingUnread is returned by App.NextUnread when the reader finished every item of the series.
+	ErrNothingUnread = fmt.Errorf("unread item %w", domain.ErrNotFound)
+)
+
+type ReadModel interface {
+	// ListSeries returns up to filter.Limit series ordered by name and then id, starting strictly
+	// after filter.After.
+	ListSeries(context.Context, SeriesFilter) ([]SeriesView, error)
+	// GetSeries returns domain.ErrSeriesNotFound if there is no series with the given id or none of
+	// its items are permitted by restrictions. readerID is only used for SeriesView.ReadCount.
+	GetSeries(ctx context.Context, id domain.SeriesID, readerID domain.UserID, restrictions domain.ContentRestrictions) (SeriesView, error)
+	// ListSeriesItems returns the items of the series ordered by index, title and then id.
+	ListSeriesItems(context.Context, SeriesItemsFilter) ([]SeriesItemView, error)
+}
+
+type SeriesRepo interface {
+	// GetSeries returns domain.ErrSeriesNotFound if there is no series with the given id.
+	GetSeries(context.Context, domain.SeriesID) (*domain.Series, error)
+	// UpdateSeries returns ErrSeriesNameTaken if another series has the name of one of them.
+	UpdateSeries(context.Context, []*domain.Series) error
+}
+
+type App struct {
+	Session    dbx.Session
+	ReadModel  ReadModel
+	SeriesRepo SeriesRepo
+}
+
+// ListSeries returns a page of the series that have items the user in ctx is permitted to see.
+func (a *App) ListSeries(ctx context.Context, q ListSeriesQuery) (SeriesPage, error) {
+	const op = errorx.Op("series.App.ListSeries")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return SeriesPage{}, op.Wrap(err)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = vx.DefaultPageLimit
+	}
+
+	var after *Cursor
+	err = v.Errors{
+		"limit": v.Validate(q.Limit, v.Min(1), v.Max(vx.MaxPageLimit)),
+		"cursor": v.Validate(q.Cursor, vx.Cursor(func(cursor string) error {
+			c, err := DecodeCursor(cursor)
+			if err != nil {
+				return err
+			}
+			after = &c
+			return nil
+		})),
+	}.Filter()
+	if err != nil {
+		return SeriesPage{}, op.Wrap(err)
+	}
+
+	series, err := a.ReadModel.ListSeries(ctx, SeriesFilter{
+		Search:       q.Search,
+		ReaderID:     user.ID(),
+		Restrictions: user.Restrictions(),
+		After:        after,
+		Limit:        q.Limit + 1, // one extra series tells us whether there is a next page
+	})
+	if err != nil {
+		return SeriesPage{}, op.Wrap(err)
+	}
+
+	page := SeriesPage{Series: series}
+	if len(series) > q.Limit {
+		page.Series = series[:q.Limit]
+		last := page.Series[q.Limit-1]
+		page.NextCursor = Cursor{Name: last.Name, ID: last.ID}.Encode()
+	}
+
+	return page, nil
+}
+
+// GetSeries returns domain.ErrSeriesNotFound also when the user in ctx is not permitted to see
+// any item of the series.
+func (a *App) GetSeries(ctx context.Context, id domain.SeriesID) (SeriesView, error) {
+	const op = errorx.Op("series.App.GetSeries")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return SeriesView{}, op.Wrap(err)
+	}
+
+	s, err := a.ReadModel.GetSeries(ctx, id, user.ID(), user.Restrictions())
+	if err != nil {
+		return SeriesView{}, op.Wrap(err)
+	}
+	return s, nil
+}
+
+// ListSeriesItems returns the items of the series the user in ctx is permitted to see, in the
+// order they are read.
+func (a *App) ListSeriesItems(ctx context.Context, id domain.SeriesID) ([]SeriesItemView, error) {
+	const op = errorx.Op("series.App.ListSeriesItems")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	items, err := a.seriesItems(ctx, user, id)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return items, nil
+}
+
+// NextUnread returns the item of the series the user in ctx should read next, see nextUnread. It
+// returns ErrNothingUnread if they finished every item.
+func (a *App) NextUnread(ctx context.Context, id domain.SeriesID) (SeriesItemView, error) {
+	const op = errorx.Op("series.App.NextUnread")
+
+	user, err := auth.Authenticated(ctx, domain.ScopeReadLibrary)
+	if err != nil {
+		return SeriesItemView{}, op.Wrap(err)
+	}
+
+	items, err := a.seriesItems(ctx, user, id)
+	if err != nil {
+		return SeriesItemView{}, op.Wrap(err)
+	}
+
+	item, ok := nextUnread(items)
+	if !ok {
+		return SeriesItemView{}, op.Wrap(ErrNothingUnread)
+	}
+	return item, nil
+}
+
+// seriesItems returns domain.ErrSeriesNotFound if the user is not permitted to see any item of the series.
+func (a *App) seriesItems(ctx context.Context, user *domain.User, id domain.SeriesID) ([]SeriesItemView, error) {
+	items, err := a.ReadModel.ListSeriesItems(ctx, SeriesItemsFilter{
+		SeriesID:     id,
+		ReaderID:     user.ID(),
+		Restrictions: user.Restrictions(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, domain.ErrSeriesNotFound
+	}
+	return items, nil
+}
+
+// nextUnread picks up where the reader is furthest in the series: the furthest item they opened
+// if they did not finish it, else the first unfinished item after it. Once they got through the
+// end, it returns the first item they skipped. items must be in series order.
+func nextUnread(items []SeriesItemView) (SeriesItemView, bool) {
+	furthest := -1
+	for i, item := range items {
+		if item.Percentage != nil {
+			furthest = i
+		}
+	}
+	if furthest >= 0 && !items[furthest].Finished() {
+		return items[furthest], true
+	}
+	for _, item := range items[furthest+1:] {
+		if !item.Finished() {
+			return item, true
+		}
+	}
+	for _, item := range items[:max(furthest, 0)] {
+		if !item.Finished() {
+			return item, true
+		}
+	}
+	return SeriesItemView{}, false
+}
+
+// UpdateSeriesCmd changes a series, nil fields are left as they are, see domain.SeriesEdit.
+type UpdateSeriesCmd struct {
+	ID               domain.SeriesID
+	Name             *string
+	Description      *string
+	Status           *domain.SeriesStatus
+	ReadingDirection *domain.ReadingDirection
+}
+
+// UpdateSeries requires domain.PermEditMetadata, it returns ErrSeriesNameTaken when renaming the
+// series after another one.
+func (a *App) UpdateSeries(ctx context.Context, cmd UpdateSeriesCmd) (SeriesView, error) {
+	const op = errorx.Op("series.App.UpdateSeries")
+
+	user, err := auth.Authorize(ctx, domain.PermEditMetadata)
+	if err != nil {
+		return SeriesView{}, op.Wrap(err)
+	}
+
+	if _, err := a.ReadModel.GetSeries(ctx, cmd.ID, user.ID(), user.Restrictions()); err != nil {
+		return SeriesView{}, op.Wrap(err)
+	}
+
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
+		s, err := a.SeriesRepo.GetSeries(ctx, cmd.ID)
+		if err != nil {
+			return err
+		}
+		err = s.Edit(domain.SeriesEdit{
+			Name:             cmd.Name,
+			Description:      cmd.Description,
+			Status:           cmd.Status,
+			ReadingDirection: cmd.ReadingDirection,
+		})
+		if err != nil {
+			return err
+		}
+		return a.SeriesRepo.UpdateSeries(ctx, []*domain.Series{s})
+	})
+	if err != nil {
+		return SeriesView{}, op.Wrap(err)
+	}
+
+	s, err := a.ReadModel.GetSeries(ctx, cmd.ID, user.ID(), user.Restrictions())
+	if err != nil {
+		return SeriesView{}, op.Wrap(err)
+	}
+	return s, nil
+}
@@ -0,0 +1,259 @@
+package series
+
+import (
+	"context"
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type mockReadModel struct{ mock.Mock }
+
+func (m *mockReadModel) ListSeries(ctx context.Context, f SeriesFilter) ([]SeriesView, error) {
+	args := m.Called(ctx, f)
+	series, _ := args.Get(0).([]SeriesView)
+	return series, args.Error(1)
+}
+
+func (m *mockReadModel) GetSeries(ctx context.Context, id domain.SeriesID, readerID domain.UserID, r domain.ContentRestrictions) (SeriesView, error) {
+	args := m.Called(ctx, id, readerID, r)
+	s, _ := args.Get(0).(SeriesView)
+	return s, args.Error(1)
+}
+
+func (m *mockReadModel) ListSeriesItems(ctx context.Context, f SeriesItemsFilter) ([]SeriesItemView, error) {
+	args := m.Called(ctx, f)
+	items, _ := args.Get(0).([]SeriesItemView)
+	return items, args.Error(1)
+}
+
+type mockSeriesRepo struct{ mock.Mock }
+
+func (m *mockSeriesRepo) GetSeries(ctx context.Context, id domain.SeriesID) (*domain.Series, error) {
+	args := m.Called(ctx, id)
+	s, _ := args.Get(0).(*domain.Series)
+	return s, args.Error(1)
+}
+
+func (m *mockSeriesRepo) UpdateSeries(ctx context.Context, series []*domain.Series) error {
+	return m.Called(ctx, series).Error(0)
+}
+
+type mockSession struct{ mock.Mock }
+
+func (m *mockSession) Transaction(ctx context.Context, f func(context.Context) error) error {
+	return f(ctx)
+}
+
+func (m *mockSession) Begin(ctx context.Context) (dbx.Session, error) {
+	args := m.Called(ctx)
+	s, _ := args.Get(0).(dbx.Session)
+	return s, args.Error(1)
+}
+
+func (m *mockSession) Rollback() error          { return m.Called().Error(0) }
+func (m *mockSession) Commit() error            { return m.Called().Error(0) }
+func (m *mockSession) Context() context.Context { return m.Called().Get(0).(context.Context) }
+
+func userContext(t *testing.T, role domain.Role) (context.Context, *domain.User) {
+	t.Helper()
+	user := domain.NewUserBuilder().WithDefault().Role(role).Build()
+	return auth.WithUser(t.Context(), &user), &user
+}
+
+func percent(p float64) *float64 {
+	return &p
+}
+
+func seriesItems(percentages ...*float64) []SeriesItemView {
+	items := make([]SeriesItemView, len(percentages))
+	for i, p := range percentages {
+		items[i] = SeriesItemView{ID: domain.NewLibraryItemID(), Index: float64(i + 1), Percentage: p}
+	}
+	return items
+}
+
+func TestNextUnread(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		items  []SeriesItemView
+		want   int
+		wantOK bool
+	}{
+		{name: "never read", items: seriesItems(nil, nil, nil), want: 0, wantOK: true},
+		{name: "in the middle of one", items: seriesItems(percent(1), percent(0.4), nil), want: 1, wantOK: true},
+		{name: "finished one", items: seriesItems(percent(1), percent(0.995), nil), want: 2, wantOK: true},
+		{name: "reread an earlier one", items: seriesItems(percent(0.2), percent(1), percent(1), nil), want: 3, wantOK: true},
+		{name: "skipped one", items: seriesItems(percent(1), nil, percent(1), nil), want: 3, wantOK: true},
+		{name: "came back for the skipped one", items: seriesItems(percent(1), nil, percent(1)), want: 1, wantOK: true},
+		{name: "opened the last one only", items: seriesItems(nil, nil, percent(0)), want: 2, wantOK: true},
+		{name: "read them all", items: seriesItems(percent(1), percent(1)), wantOK: false},
+		{name: "no items", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := nextUnread(tt.items)
+			require.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.items[tt.want], got)
+			}
+		})
+	}
+}
+
+func TestApp_ListSeries(t *testing.T) {
+	t.Parallel()
+
+	rm := new(mockReadModel)
+	app := &App{ReadModel: rm}
+	ctx, user := userContext(t, domain.RoleMember)
+	after := Cursor{Name: "Berserk", ID: domain.NewSeriesID()}
+	series := []SeriesView{
+		{ID: domain.NewSeriesID(), Name: "Saga", ItemCount: 2},
+		{ID: domain.NewSeriesID(), Name: "Атака титанов", ItemCount: 34, ReadCount: 3},
+	}
+	rm.On("ListSeries", mock.Anything, SeriesFilter{
+		Search:       "титан",
+		ReaderID:     user.ID(),
+		Restrictions: user.Restrictions(),
+		After:        &after,
+		Limit:        2,
+	}).Return(series, nil)
+
+	page, err := app.ListSeries(ctx, ListSeriesQuery{Search: "титан", Cursor: after.Encode(), Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, series[:1], page.Series)
+	next, err := DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{Name: "Saga", ID: series[0].ID}, next)
+
+	_, err = app.ListSeries(ctx, ListSeriesQuery{Cursor: "garbage", Limit: vx.MaxPageLimit + 1})
+	vx.AssertValidationErrors(t, err, v.Errors{"cursor": vx.ErrInvalidCursor, "limit": v.ErrMaxLessEqualThanRequired})
+
+	_, err = app.ListSeries(t.Context(), ListSeriesQuery{})
+	require.ErrorIs(t, err, domain.ErrUnauthorized)
+}
+
+func TestApp_NextUnread(t *testing.T) {
+	t.Parallel()
+
+	rm := new(mockReadModel)
+	app := &App{ReadModel: rm}
+	ctx, user := userContext(t, domain.RoleMember)
+	started, finished, hidden := domain.NewSeriesID(), domain.NewSeriesID(), domain.NewSeriesID()
+	filter := func(id domain.SeriesID) SeriesItemsFilter {
+		return SeriesItemsFilter{SeriesID: id, ReaderID: user.ID(), Restrictions: user.Restrictions()}
+	}
+	startedItems := seriesItems(percent(1), percent(0.5))
+	rm.On("ListSeriesItems", mock.Anything, filter(started)).Return(startedItems, nil)
+	rm.On("ListSeriesItems", mock.Anything, filter(finished)).Return(seriesItems(percent(1)), nil)
+	rm.On("ListSeriesItems", mock.Anything, filter(hidden)).Return([]SeriesItemView{}, nil)
+
+	item, err := app.NextUnread(ctx, started)
+	require.NoError(t, err)
+	assert.Equal(t, startedItems[1], item)
+
+	_, err = app.NextUnread(ctx, finished)
+	require.ErrorIs(t, err, ErrNothingUnread)
+
+	_, err = app.NextUnread(ctx, hidden)
+	require.ErrorIs(t, err, domain.ErrSeriesNotFound)
+
+	_, err = app.ListSeriesItems(ctx, hidden)
+	require.ErrorIs(t, err, domain.ErrSeriesNotFound)
+}
+
+func TestApp_UpdateSeries(t *testing.T) {
+	t.Parallel()
+
+	newApp := func(t *testing.T) (*App, *mockReadModel, *mockSeriesRepo, *domain.Series) {
+		t.Helper()
+		rm, repo := new(mockReadModel), new(mockSeriesRepo)
+		s, err := domain.NewSeries(domain.NewSeriesID(), "Shingeki no Kyojin")
+		require.NoError(t, err)
+		repo.On("GetSeries", mock.Anything, s.ID()).Return(s, nil)
+		return &App{Session: new(mockSession), ReadModel: rm, SeriesRepo: repo}, rm, repo, s
+	}
+
+	t.Run("edit", func(t *testing.T) {
+		t.Parallel()
+
+		app, rm, repo, s := newApp(t)
+		ctx, user := userContext(t, domain.RoleMember)
+		view := SeriesView{ID: s.ID(), Name: "Атака титанов", Status: domain.SeriesCompleted, ReadingDirection: domain.RightToLeft}
+		rm.On("GetSeries", mock.Anything, s.ID(), user.ID(), user.Restrictions()).Return(view, nil)
+		repo.On("UpdateSeries", mock.Anything, []*domain.Series{s}).Return(nil)
+
+		name, status, direction := "Атака титанов", domain.SeriesCompleted, domain.RightToLeft
+		got, err := app.UpdateSeries(ctx, UpdateSeriesCmd{ID: s.ID(), Name: &name, Status: &status, ReadingDirection: &direction})
+		require.NoError(t, err)
+		assert.Equal(t, view, got)
+		assert.Equal(t, "Атака титанов", s.Name())
+		assert.Equal(t, domain.SeriesCompleted, s.Status())
+		assert.Equal(t, domain.RightToLeft, s.ReadingDirection())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		app, rm, repo, s := newApp(t)
+		ctx, user := userContext(t, domain.RoleMember)
+		rm.On("GetSeries", mock.Anything, s.ID(), user.ID(), user.Restrictions()).Return(SeriesView{ID: s.ID()}, nil)
+
+		status := domain.SeriesStatus("abandoned")
+		_, err := app.UpdateSeries(ctx, UpdateSeriesCmd{ID: s.ID(), Status: &status})
+		vx.AssertValidationErrors(t, err, v.Errors{"status": v.ErrInInvalid})
+		repo.AssertNotCalled(t, "UpdateSeries", mock.Anything, mock.Anything)
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		t.Parallel()
+
+		app, rm, repo, s := newApp(t)
+		ctx, user := userContext(t, domain.RoleMember)
+		rm.On("GetSeries", mock.Anything, s.ID(), user.ID(), user.Restrictions()).Return(SeriesView{ID: s.ID()}, nil)
+		repo.On("UpdateSeries", mock.Anything, mock.Anything).Return(ErrSeriesNameTaken)
+
+		name := "Berserk"
+		_, err := app.UpdateSeries(ctx, UpdateSeriesCmd{ID: s.ID(), Name: &name})
+		require.ErrorIs(t, err, domain.ErrConflict)
+	})
+
+	t.Run("hidden", func(t *testing.T) {
+		t.Parallel()
+
+		app, rm, repo, s := newApp(t)
+		ctx, user := userContext(t, domain.RoleMember)
+		rm.On("GetSeries", mock.Anything, s.ID(), user.ID(), user.Restrictions()).Return(nil, domain.ErrSeriesNotFound)
+
+		name := "Berserk"
+		_, err := app.UpdateSeries(ctx, UpdateSeriesCmd{ID: s.ID(), Name: &name})
+		require.ErrorIs(t, err, domain.ErrSeriesNotFound)
+		repo.AssertNotCalled(t, "GetSeries", mock.Anything, mock.Anything)
+	})
+
+	t.Run("guest", func(t *testing.T) {
+		t.Parallel()
+
+		app, _, repo, s := newApp(t)
+		ctx, _ := userContext(t, domain.RoleGuest)
+
+		name := "Berserk"
+		_, err := app.UpdateSeries(ctx, UpdateSeriesCmd{ID: s.ID(), Name: &name})
+		require.ErrorIs(t, err, domain.ErrPermissionDenied)
+		repo.AssertNotCalled(t, "UpdateSeries", mock.Anything, mock.Anything)
+	})
+}
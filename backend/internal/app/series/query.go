@@ -0,0 +1,100 @@
+package series
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+// ListSeriesQuery is the input of App.ListSeries, zero values mean "no filter".
+type ListSeriesQuery struct {
+	// Search matches words of the name.
+	Search string
+	// Cursor is the opaque value of SeriesPage.NextCursor from the previous page.
+	Cursor string
+	Limit  int
+}
+
+// SeriesFilter is the validated query passed down to the ReadModel.
+type SeriesFilter struct {
+	Search string
+	// ReaderID is the user whose reading progress is used for SeriesView.ReadCount.
+	ReaderID domain.UserID
+	// Restrictions of the reader, the ReadModel must only count items they permit and leave out
+	// series without such items.
+	Restrictions domain.ContentRestrictions
+	// After is the keyset position to continue from, nil for the first page.
+	After *Cursor
+	Limit int
+}
+
+// SeriesItemsFilter is passed down to the ReadModel to list the items of a series.
+type SeriesItemsFilter struct {
+	SeriesID domain.SeriesID
+	// ReaderID is the user whose reading progress is used for SeriesItemView.Percentage.
+	ReaderID domain.UserID
+	// Restrictions of the reader, the ReadModel must leave out items they do not permit.
+	Restrictions domain.ContentRestrictions
+}
+
+type SeriesView struct {
+	ID               domain.SeriesID
+	Name             string
+	Description      string
+	Status           domain.SeriesStatus
+	ReadingDirection domain.ReadingDirection
+	// ItemCount is the number of not deleted items in the series.
+	ItemCount int
+	// ReadCount is the number of those items the reader finished, see domain.FinishedPercentage.
+	ReadCount int
+}
+
+type SeriesPage struct {
+	Series []SeriesView
+	// NextCursor is empty when there are no more series.
+	NextCursor string
+}
+
+// SeriesItemView is a not deleted item of a series with the progress of the reader.
+type SeriesItemView struct {
+	ID    domain.LibraryItemID
+	Title string
+	Type  domain.LibraryItemType
+	// Index is the position in the series, zero if unknown.
+	Index float64
+	// Percentage is how far the reader got, nil if they never opened the item.
+	Percentage *float64
+	LastReadAt *time.Time
+}
+
+// Finished reports whether the reader got through the item.
+func (v SeriesItemView) Finished() bool {
+	return v.Percentage != nil && *v.Percentage >= domain.FinishedPercentage
+}
+
+// Cursor is the name and id of the last series of a page, series are ordered by both.
+type Cursor struct {
+	Name string          `json:"n"`
+	ID   domain.SeriesID `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns vx.ErrInvalidCursor if s is not a cursor encoded by Cursor.Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, vx.ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID.IsNil() {
+		return Cursor{}, vx.ErrInvalidCursor
+	}
+	return c, nil
+}
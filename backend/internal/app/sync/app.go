@@ -4,6 +4,9 @@ import (
 	"context"
 	"log/slog"
 
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
 	"github.com/ARUMANDESU/goread/backend/internal/domain"
 	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
 	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
@@ -81,28 +84,87 @@ type MetadataExtractor interface {
 	Extract(context.Context, []vo.Path) (map[vo.Path]vo.Metadata, error)
 }
 
+// DocumentHasher computes the hash KOReader identifies documents by, see koreaderx.PartialMD5.
+type DocumentHasher interface {
+	PartialMD5(ctx context.Context, path vo.Path) (string, error)
+}
+
+// PageCounter counts the pages of PDFs and comic archives, see domain.FileFormat.HasPages.
+type PageCounter interface {
+	PageCount(ctx context.Context, path vo.Path) (int, error)
+}
+
 type SnapshotRepo interface {
 	GetLibrarySnapshot(context.Context) (vo.LibrarySnapshot, error)
 	ReplaceSnapshot(context.Context, vo.LibrarySnapshot) error
+	// SetSnapshotHash sets the hash of the file at path, a file rewritten in place stays the same entry.
+	SetSnapshotHash(ctx context.Context, path vo.Path, hash vo.Hash) error
 }
 
 type AuthorRepo interface {
 	GetOrCreateAuthors(ctx context.Context, names []string) ([]domain.Author, error)
+	GetAuthors(context.Context, []domain.AuthorID) ([]domain.Author, error)
+}
+
+type SeriesRepo interface {
+	// GetOrCreateSeries returns the series with the given names, the missing ones are created.
+	GetOrCreateSeries(ctx context.Context, names []string) ([]*domain.Series, error)
+	// GetSeries returns domain.ErrSeriesNotFound if there is no series with the given id.
+	GetSeries(context.Context, domain.SeriesID) (*domain.Series, error)
+	UpdateSeries(context.Context, []*domain.Series) error
 }
 
 type LibraryItemRepo interface {
+	// GetLibraryItem returns domain.ErrLibraryItemNotFound if there is no item with the given id.
+	GetLibraryItem(context.Context, domain.LibraryItemID) (*domain.LibraryItem, error)
 	CreateLibraryItems(context.Context, []*domain.LibraryItem) error
 	GetLibraryItemsByHash(context.Context, []vo.Hash) ([]*domain.LibraryItem, error)
 	UpdateLibraryItems(context.Context, []*domain.LibraryItem) error
+	// ListLibraryItemsWithoutPartialMD5 returns the items that are not deleted and have no
+	// partial MD5, such as the items added before KOReader sync. Clearing the partial MD5 of an
+	// item makes the next scan recompute it, as needed for files over 1 GiB hashed before
+	// koreaderx.PartialMD5 included its last sample.
+	ListLibraryItemsWithoutPartialMD5(context.Context) ([]*domain.LibraryItem, error)
+	// ListLibraryItemsWithoutPageCount returns the items that are not deleted, are made of pages,
+	// see domain.FileFormat.HasPages, and have a page count of zero.
+	ListLibraryItemsWithoutPageCount(context.Context) ([]*domain.LibraryItem, error)
 }
 
 type App struct {
 	Session           dbx.Session
 	Snapshotter       Snapshotter
 	MetadataExtractor MetadataExtractor
+	DocumentHasher    DocumentHasher
+	PageCounter       PageCounter
 	SnapshotRepo      SnapshotRepo
 	LibraryItemRepo   LibraryItemRepo
 	AuthorRepo        AuthorRepo
+	SeriesRepo        SeriesRepo
+	MetadataWriter    MetadataWriter
+	// WriteBack enables WriteBackMetadata, files are only read unless it is set.
+	WriteBack bool
+}
+
+// NewApp returns app if none of its dependencies is nil, MetadataWriter is only needed with WriteBack.
+func NewApp(app *App) (*App, error) {
+	const op = errorx.Op("sync.NewApp")
+
+	err := v.Errors{
+		"Session":           v.Validate(app.Session, v.NotNil),
+		"Snapshotter":       v.Validate(app.Snapshotter, v.NotNil),
+		"MetadataExtractor": v.Validate(app.MetadataExtractor, v.NotNil),
+		"DocumentHasher":    v.Validate(app.DocumentHasher, v.NotNil),
+		"PageCounter":       v.Validate(app.PageCounter, v.NotNil),
+		"SnapshotRepo":      v.Validate(app.SnapshotRepo, v.NotNil),
+		"LibraryItemRepo":   v.Validate(app.LibraryItemRepo, v.NotNil),
+		"AuthorRepo":        v.Validate(app.AuthorRepo, v.NotNil),
+		"SeriesRepo":        v.Validate(app.SeriesRepo, v.NotNil),
+		"MetadataWriter":    v.Validate(app.MetadataWriter, v.When(app.WriteBack, v.NotNil)),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	return app, nil
 }
 
 func (a *App) ScanLibrary(ctx context.Context) error {
@@ -121,29 +183,77 @@ func (a *App) ScanLibrary(ctx context.Context) error {
 
 	results := vo.CompareSnapshots(oldSnapshot, snapshot)
 
-	addedPaths := make([]string, 0, len(results.Added))
+	readPaths := make([]string, 0, len(results.Added)+len(results.Changed))
 	for _, v := range results.Added {
-		addedPaths = append(addedPaths, v.Path)
+		readPaths = append(readPaths, v.Path)
+	}
+	// files changed in place are read again, their items keep their id, edits and locks
+	changedPaths := make(map[vo.Path]bool, len(results.Changed))
+	for _, v := range results.Changed {
+		readPaths = append(readPaths, v.Path)
+		changedPaths[v.Path] = true
 	}
 
-	metadataMap, err := a.MetadataExtractor.Extract(ctx, addedPaths)
+	metadataMap, err := a.MetadataExtractor.Extract(ctx, readPaths)
 	if err != nil {
 		return op.Wrap(err)
 	}
 
+	partialMD5s := make(map[vo.Path]string, len(metadataMap))
+	for path := range metadataMap {
+		hash, err := a.DocumentHasher.PartialMD5(ctx, path)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to hash document for KOReader", "path", path, "error", err)
+			continue
+		}
+		partialMD5s[path] = hash
+	}
+
+	pageCounts := make(map[vo.Path]int)
+	for path := range metadataMap {
+		if !domain.FileFormatOf(path).HasPages() {
+			continue
+		}
+		n, err := a.PageCounter.PageCount(ctx, path)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to count pages", "path", path, "error", err)
+			continue
+		}
+		pageCounts[path] = n
+	}
+
 	uniqueNames := make(map[string]struct{})
-	for _, md := range metadataMap {
+	uniqueSeries := make(map[string]struct{})
+	for path, md := range metadataMap {
 		for _, name := range md.Authors {
 			uniqueNames[name] = struct{}{}
 		}
+
+		// calibre and ComicInfo name the series, comics without either may still tell by their name.
+		// Book names such as "Orwell - 1984" look too much like a series to guess for them.
+		if md.Series == "" && domain.FileFormatOf(path).IsComicArchive() {
+			md.Series, md.SeriesIndex, _ = vo.SeriesFromPath(path)
+		}
+		if len(md.Series) > domain.MaxSeriesNameLen {
+			slog.WarnContext(ctx, "ignoring too long series name", "path", path, "series", md.Series)
+			md.Series, md.SeriesIndex = "", 0
+		}
+		if md.Series != "" {
+			uniqueSeries[md.Series] = struct{}{}
+		}
+		metadataMap[path] = md
 	}
 
 	names := make([]string, 0, len(uniqueNames))
 	for name := range uniqueNames {
 		names = append(names, name)
 	}
+	seriesNames := make([]string, 0, len(uniqueSeries))
+	for name := range uniqueSeries {
+		seriesNames = append(seriesNames, name)
+	}
 
-	return a.Session.Transaction(ctx, func(ctx context.Context) error {
+	err = a.Session.Transaction(ctx, func(ctx context.Context) error {
 		authors, err := a.AuthorRepo.GetOrCreateAuthors(ctx, names)
 		if err != nil {
 			return op.Wrap(err)
@@ -153,19 +263,74 @@ func (a *App) ScanLibrary(ctx context.Context) error {
 		for _, author := range authors {
 			authorByName[author.Name()] = author.ID()
 		}
+		authorIDs := func(names []string) []domain.AuthorID {
+			if len(names) == 0 {
+				return nil
+			}
+			ids := make([]domain.AuthorID, len(names))
+			for i, name := range names {
+				ids[i] = authorByName[name]
+			}
+			return ids
+		}
+
+		seriesByName := make(map[string]*domain.Series, len(seriesNames))
+		if len(seriesNames) > 0 {
+			series, err := a.SeriesRepo.GetOrCreateSeries(ctx, seriesNames)
+			if err != nil {
+				return op.Wrap(err)
+			}
+			for _, s := range series {
+				seriesByName[s.Name()] = s
+			}
+		}
+		// directedSeries learned their reading direction from the files of this scan
+		var directedSeries []*domain.Series
+
+		// applyFileMetadata sets what the file tells beyond the fields of NewLibraryItem, the
+		// series and age rating are left alone if they are locked.
+		applyFileMetadata := func(item *domain.LibraryItem, path vo.Path, md vo.Metadata) {
+			item.SetTags(md.Tags)
+			contributors := make([]domain.Contributor, len(md.Contributors))
+			for i, c := range md.Contributors {
+				contributors[i] = domain.Contributor{Name: c.Name, Role: c.Role}
+			}
+			item.SetContributors(contributors)
+			item.SetPublicationModified(md.Modified)
+			item.SetPartialMD5(partialMD5s[path])
+			item.SetPageCount(pageCounts[path])
+			item.SetDRM(md.DRM)
+			if series, ok := seriesByName[md.Series]; ok {
+				_, err := item.ApplyMetadata(domain.MetadataEdit{Series: &domain.ItemSeries{ID: series.ID(), Index: md.SeriesIndex}})
+				if err != nil {
+					slog.WarnContext(ctx, "ignoring invalid series", "path", path, "series", md.Series, "error", err)
+				}
+				// the direction set by hand or by an earlier file wins
+				if md.ReadingDirection != "" && series.ReadingDirection() == "" {
+					direction := domain.ReadingDirection(md.ReadingDirection)
+					if err := series.Edit(domain.SeriesEdit{ReadingDirection: &direction}); err != nil {
+						slog.WarnContext(ctx, "ignoring unknown reading direction", "path", path, "readingDirection", md.ReadingDirection)
+					} else {
+						directedSeries = append(directedSeries, series)
+					}
+				}
+			}
+			if err := item.SetAgeRating(domain.AgeRating(md.AgeRating)); err != nil {
+				slog.WarnContext(ctx, "ignoring unknown age rating", "path", path, "ageRating", md.AgeRating)
+			}
+		}
 
 		libraryItems := make([]*domain.LibraryItem, 0, len(metadataMap))
 		for path, md := range metadataMap {
-			ids := make([]domain.AuthorID, len(md.Authors))
-			for i, name := range md.Authors {
-				ids[i] = authorByName[name]
+			if changedPaths[path] {
+				continue
 			}
 
 			item, err := domain.NewLibraryItem(
 				domain.NewLibraryItemID(),
 				md.Title,
-				domain.Book, // TODO parse properly
-				ids,
+				domain.ItemTypeOf(domain.FileFormatOf(path), md.Manga),
+				authorIDs(md.Authors),
 				md.Subjects,
 				md.Languages,
 				md.Description,
@@ -177,6 +342,7 @@ func (a *App) ScanLibrary(ctx context.Context) error {
 				delete(snapshot, path)
 				continue
 			}
+			applyFileMetadata(item, path, md)
 			libraryItems = append(libraryItems, item)
 		}
 
@@ -185,13 +351,16 @@ func (a *App) ScanLibrary(ctx context.Context) error {
 			return op.Wrap(err)
 		}
 
-		hashes := make([]vo.Hash, 0, len(results.Moved)+len(results.Removed))
+		hashes := make([]vo.Hash, 0, len(results.Moved)+len(results.Removed)+len(results.Changed))
 		for _, v := range results.Moved {
 			hashes = append(hashes, v.Hash)
 		}
 		for _, v := range results.Removed {
 			hashes = append(hashes, v.Hash)
 		}
+		for _, v := range results.Changed {
+			hashes = append(hashes, v.Hash)
+		}
 
 		libraryItems, err = a.LibraryItemRepo.GetLibraryItemsByHash(ctx, hashes)
 		if err != nil {
@@ -205,6 +374,33 @@ func (a *App) ScanLibrary(ctx context.Context) error {
 			if f, ok := results.Moved[string(item.Hash())]; ok {
 				item.UpdatePath(f.NewPath)
 			}
+			if f, ok := results.Changed[string(item.Hash())]; ok {
+				item.UpdateHash(f.NewHash)
+				md, ok := metadataMap[f.Path]
+				if !ok {
+					continue
+				}
+				// ApplyMetadata leaves the fields locked by manual edits alone
+				itemType := domain.ItemTypeOf(domain.FileFormatOf(f.Path), md.Manga)
+				_, err := item.ApplyMetadata(domain.MetadataEdit{
+					Title:      &md.Title,
+					ItemType:   &itemType,
+					AuthorIDs:  authorIDs(md.Authors),
+					Genre:      md.Subjects,
+					Languages:  md.Languages,
+					Annotation: &md.Description,
+				})
+				if err != nil {
+					slog.WarnContext(ctx, "keeping metadata of changed file", "path", f.Path, "error", err)
+				}
+				applyFileMetadata(item, f.Path, md)
+			}
+		}
+
+		if len(directedSeries) > 0 {
+			if err := a.SeriesRepo.UpdateSeries(ctx, directedSeries); err != nil {
+				return op.Wrap(err)
+			}
 		}
 
 		err = a.LibraryItemRepo.UpdateLibraryItems(ctx, libraryItems)
@@ -219,4 +415,73 @@ func (a *App) ScanLibrary(ctx context.Context) error {
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if err := a.backfillPartialMD5s(ctx); err != nil {
+		return op.Wrap(err)
+	}
+	return op.Wrap(a.backfillPageCounts(ctx))
+}
+
+// backfillPartialMD5s hashes the items that have no partial MD5 yet, because they were added
+// before KOReader sync or their file could not be hashed back then. Files that still fail are
+// logged and tried again by the next scan.
+func (a *App) backfillPartialMD5s(ctx context.Context) error {
+	items, err := a.LibraryItemRepo.ListLibraryItemsWithoutPartialMD5(ctx)
+	if err != nil {
+		return err
+	}
+
+	hashed := make([]*domain.LibraryItem, 0, len(items))
+	for _, item := range items {
+		hash, err := a.DocumentHasher.PartialMD5(ctx, item.Path())
+		if err != nil {
+			slog.WarnContext(ctx, "failed to hash document for KOReader", "path", item.Path(), "error", err)
+			continue
+		}
+		item.SetPartialMD5(hash)
+		hashed = append(hashed, item)
+	}
+	if len(hashed) == 0 {
+		return nil
+	}
+	return a.LibraryItemRepo.UpdateLibraryItems(ctx, hashed)
+}
+
+// backfillPageCounts counts the pages of the items that have none yet, because they were added
+// before page counts were kept or their file could not be read back then. Files that still fail
+// are logged and tried again by the next scan.
+func (a *App) backfillPageCounts(ctx context.Context) error {
+	items, err := a.LibraryItemRepo.ListLibraryItemsWithoutPageCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	counted := make([]*domain.LibraryItem, 0, len(items))
+	for _, item := range items {
+		n, err := a.PageCounter.PageCount(ctx, item.Path())
+		if err != nil {
+			slog.WarnContext(ctx, "failed to count pages", "path", item.Path(), "error", err)
+			continue
+		}
+		item.SetPageCount(n)
+		counted = append(counted, item)
+	}
+	if len(counted) == 0 {
+		return nil
+	}
+	return a.LibraryItemRepo.UpdateLibraryItems(ctx, counted)
+}
+
+// TriggerScan runs ScanLibrary on behalf of the user in ctx, it requires domain.PermTriggerScan.
+func (a *App) TriggerScan(ctx context.Context) error {
+	const op = errorx.Op("sync.App.TriggerScan")
+
+	if _, err := auth.Authorize(ctx, domain.PermTriggerScan); err != nil {
+		return op.Wrap(err)
+	}
+
+	return op.Wrap(a.ScanLibrary(ctx))
 }
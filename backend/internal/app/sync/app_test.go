@@ -5,14 +5,19 @@ import (
 	"errors"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
 	"github.com/ARUMANDESU/goread/backend/internal/domain"
 	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
 	"github.com/ARUMANDESU/goread/backend/pkg/dbx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
 )
 
 // --- Mocks ---
@@ -41,6 +46,10 @@ func (m *mockSnapshotRepo) GetLibrarySnapshot(ctx context.Context) (vo.LibrarySn
 	return s, args.Error(1)
 }
 
+func (m *mockSnapshotRepo) SetSnapshotHash(ctx context.Context, path vo.Path, hash vo.Hash) error {
+	return m.Called(ctx, path, hash).Error(0)
+}
+
 func (m *mockSnapshotRepo) ReplaceSnapshot(ctx context.Context, s vo.LibrarySnapshot) error {
 	return m.Called(ctx, s).Error(0)
 }
@@ -53,8 +62,20 @@ func (m *mockAuthorRepo) GetOrCreateAuthors(ctx context.Context, names []string)
 	return a, args.Error(1)
 }
 
+func (m *mockAuthorRepo) GetAuthors(ctx context.Context, ids []domain.AuthorID) ([]domain.Author, error) {
+	args := m.Called(ctx, ids)
+	authors, _ := args.Get(0).([]domain.Author)
+	return authors, args.Error(1)
+}
+
 type mockLibraryItemRepo struct{ mock.Mock }
 
+func (m *mockLibraryItemRepo) GetLibraryItem(ctx context.Context, id domain.LibraryItemID) (*domain.LibraryItem, error) {
+	args := m.Called(ctx, id)
+	item, _ := args.Get(0).(*domain.LibraryItem)
+	return item, args.Error(1)
+}
+
 func (m *mockLibraryItemRepo) CreateLibraryItems(ctx context.Context, items []*domain.LibraryItem) error {
 	return m.Called(ctx, items).Error(0)
 }
@@ -69,6 +90,57 @@ func (m *mockLibraryItemRepo) UpdateLibraryItems(ctx context.Context, items []*d
 	return m.Called(ctx, items).Error(0)
 }
 
+func (m *mockLibraryItemRepo) ListLibraryItemsWithoutPartialMD5(ctx context.Context) ([]*domain.LibraryItem, error) {
+	args := m.Called(ctx)
+	items, _ := args.Get(0).([]*domain.LibraryItem)
+	return items, args.Error(1)
+}
+
+func (m *mockLibraryItemRepo) ListLibraryItemsWithoutPageCount(ctx context.Context) ([]*domain.LibraryItem, error) {
+	args := m.Called(ctx)
+	items, _ := args.Get(0).([]*domain.LibraryItem)
+	return items, args.Error(1)
+}
+
+// stubDocumentHasher hashes a document to "md5:" followed by its path, failing for paths in fail.
+type stubDocumentHasher struct{ fail []vo.Path }
+
+func (h stubDocumentHasher) PartialMD5(_ context.Context, path vo.Path) (string, error) {
+	if slices.Contains(h.fail, path) {
+		return "", errors.New("read failed")
+	}
+	return "md5:" + path, nil
+}
+
+// stubPageCounter counts the pages of the paths in counts and fails for others.
+type stubPageCounter struct{ counts map[vo.Path]int }
+
+func (c stubPageCounter) PageCount(_ context.Context, path vo.Path) (int, error) {
+	n, ok := c.counts[path]
+	if !ok {
+		return 0, errors.New("read failed")
+	}
+	return n, nil
+}
+
+type mockSeriesRepo struct{ mock.Mock }
+
+func (m *mockSeriesRepo) GetOrCreateSeries(ctx context.Context, names []string) ([]*domain.Series, error) {
+	args := m.Called(ctx, names)
+	series, _ := args.Get(0).([]*domain.Series)
+	return series, args.Error(1)
+}
+
+func (m *mockSeriesRepo) GetSeries(ctx context.Context, id domain.SeriesID) (*domain.Series, error) {
+	args := m.Called(ctx, id)
+	series, _ := args.Get(0).(*domain.Series)
+	return series, args.Error(1)
+}
+
+func (m *mockSeriesRepo) UpdateSeries(ctx context.Context, series []*domain.Series) error {
+	return m.Called(ctx, series).Error(0)
+}
+
 type mockSession struct{ mock.Mock }
 
 func (m *mockSession) Transaction(ctx context.Context, f func(context.Context) error) error {
@@ -109,6 +181,8 @@ func newTestApp(t *testing.T) (
 		Session:           sess,
 		Snapshotter:       snap,
 		MetadataExtractor: ext,
+		DocumentHasher:    stubDocumentHasher{},
+		PageCounter:       stubPageCounter{},
 		SnapshotRepo:      sr,
 		LibraryItemRepo:   ir,
 		AuthorRepo:        ar,
@@ -197,6 +271,8 @@ func setupEmptyTx(
 	sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
 	ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
 	ir.On("CreateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+	ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 	ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).Return([]*domain.LibraryItem{}, nil)
 	ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(nil)
 	sr.On("ReplaceSnapshot", mock.Anything, mock.Anything).Return(nil)
@@ -246,6 +322,8 @@ func TestScanLibrary(t *testing.T) {
 		ir.On("CreateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
 			return len(items) == 2
 		})).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, mock.MatchedBy(func(h []vo.Hash) bool {
 			return len(h) == 0
 		})).Return([]*domain.LibraryItem{}, nil)
@@ -291,6 +369,8 @@ func TestScanLibrary(t *testing.T) {
 		sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
 		ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
 		ir.On("CreateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, matchHashes(hash1, hash2)).
 			Return([]*domain.LibraryItem{item1, item2}, nil)
 		ir.On("UpdateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
@@ -323,6 +403,8 @@ func TestScanLibrary(t *testing.T) {
 		sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
 		ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
 		ir.On("CreateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, matchHashes(hash1)).
 			Return([]*domain.LibraryItem{item}, nil)
 		ir.On("UpdateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
@@ -371,6 +453,8 @@ func TestScanLibrary(t *testing.T) {
 		ir.On("CreateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
 			return len(items) == 1
 		})).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, matchHashes(hashB, hashC)).
 			Return([]*domain.LibraryItem{movedItem, removedItem}, nil)
 		ir.On("UpdateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
@@ -412,6 +496,8 @@ func TestScanLibrary(t *testing.T) {
 		ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).
 			Return([]domain.Author{author}, nil)
 		ir.On("CreateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).Return([]*domain.LibraryItem{}, nil)
 		ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(nil)
 		sr.On("ReplaceSnapshot", mock.Anything, mock.Anything).Return(nil)
@@ -494,6 +580,8 @@ func TestScanLibrary(t *testing.T) {
 		sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
 		ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
 		ir.On("CreateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).
 			Return(nil, errors.New("hash lookup error"))
 
@@ -517,6 +605,8 @@ func TestScanLibrary(t *testing.T) {
 		sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
 		ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
 		ir.On("CreateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).
 			Return([]*domain.LibraryItem{item}, nil)
 		ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(errors.New("update error"))
@@ -535,6 +625,8 @@ func TestScanLibrary(t *testing.T) {
 		sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
 		ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
 		ir.On("CreateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).Return([]*domain.LibraryItem{}, nil)
 		ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(nil)
 		sr.On("ReplaceSnapshot", mock.Anything, mock.Anything).Return(errors.New("replace error"))
@@ -577,6 +669,8 @@ func TestScanLibrary(t *testing.T) {
 		ir.On("CreateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
 			return len(items) == 1
 		})).Return(nil)
+		ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+		ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
 		ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).Return([]*domain.LibraryItem{}, nil)
 		ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(nil)
 		sr.On("ReplaceSnapshot", mock.Anything, mock.MatchedBy(func(s vo.LibrarySnapshot) bool {
@@ -590,3 +684,315 @@ func TestScanLibrary(t *testing.T) {
 		mock.AssertExpectationsForObjects(t, snap, ext, sr, ar, ir, sess)
 	})
 }
+
+func TestTriggerScan(t *testing.T) {
+	t.Parallel()
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		app, snap, ext, sr, ar, ir, sess := newTestApp(t)
+		snap.On("Snapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+		sr.On("GetLibrarySnapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+		setupEmptyTx(ext, sr, ar, ir, sess)
+
+		admin := domain.NewUserBuilder().WithDefault().Role(domain.RoleAdmin).Build()
+		err := app.TriggerScan(auth.WithUser(context.Background(), &admin))
+		assert.NoError(t, err)
+		snap.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		expectedErr error
+	}{
+		{name: "anonymous", ctx: context.Background(), expectedErr: domain.ErrUnauthorized},
+		{name: "member", ctx: withRole(domain.RoleMember), expectedErr: domain.ErrPermissionDenied},
+		{name: "guest", ctx: withRole(domain.RoleGuest), expectedErr: domain.ErrPermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, snap, _, _, _, _, _ := newTestApp(t)
+
+			err := app.TriggerScan(tt.ctx)
+			require.ErrorIs(t, err, tt.expectedErr)
+			snap.AssertNotCalled(t, "Snapshot", mock.Anything)
+		})
+	}
+}
+
+func withRole(role domain.Role) context.Context {
+	u := domain.NewUserBuilder().WithDefault().Role(role).Build()
+	return auth.WithUser(context.Background(), &u)
+}
+
+func TestScanLibrary_tagsAndAgeRating(t *testing.T) {
+	t.Parallel()
+
+	app, snap, ext, sr, ar, ir, sess := newTestApp(t)
+	app.DocumentHasher = stubDocumentHasher{fail: []vo.Path{"Comics/b.cbz"}}
+	app.PageCounter = stubPageCounter{counts: map[vo.Path]int{"Comics/a.cbz": 24}}
+	current := vo.LibrarySnapshot{"Comics/a.cbz": []byte("h1"), "Comics/b.cbz": []byte("h2")}
+	author := mustNewAuthor(t, "Jack Cole")
+
+	rated := validMeta("Rated", "Jack Cole")
+	rated.Tags = []string{"golden age"}
+	rated.AgeRating = string(domain.AgeRatingTeen)
+	rated.Contributors = []vo.Contributor{{Name: "Bill Woolfolk", Role: "ill"}}
+	rated.Modified = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	bogus := validMeta("Bogus", "Jack Cole")
+	bogus.AgeRating = "NC-17"
+	bogus.DRM = "adobe-adept"
+
+	snap.On("Snapshot", mock.Anything).Return(current, nil)
+	sr.On("GetLibrarySnapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+	ext.On("Extract", mock.Anything, mock.Anything).
+		Return(map[vo.Path]vo.Metadata{"Comics/a.cbz": rated, "Comics/b.cbz": bogus}, nil)
+	sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
+	ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{author}, nil)
+	ir.On("CreateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
+		if len(items) != 2 {
+			return false
+		}
+		for _, item := range items {
+			switch item.Title() {
+			case "Rated":
+				if item.AgeRating() != domain.AgeRatingTeen || !slices.Equal(item.Tags(), []string{"golden age"}) ||
+					item.PartialMD5() != "md5:Comics/a.cbz" || item.PageCount() != 24 || item.IsProtected() ||
+					!slices.Equal(item.Contributors(), []domain.Contributor{{Name: "Bill Woolfolk", Role: "ill"}}) ||
+					!item.PublicationModified().Equal(rated.Modified) {
+					return false
+				}
+			case "Bogus":
+				// hashing and counting failures only cost KOReader sync and page streaming
+				if item.AgeRating() != "" || item.PartialMD5() != "" || item.PageCount() != 0 || item.DRM() != "adobe-adept" {
+					return false
+				}
+			}
+		}
+		return true
+	})).Return(nil)
+	ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).Return([]*domain.LibraryItem{}, nil)
+	ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+	sr.On("ReplaceSnapshot", mock.Anything, current).Return(nil)
+
+	require.NoError(t, app.ScanLibrary(context.Background()))
+	ir.AssertExpectations(t)
+}
+
+func TestScanLibrary_backfillPartialMD5(t *testing.T) {
+	t.Parallel()
+
+	app, snap, ext, sr, ar, ir, sess := newTestApp(t)
+	app.DocumentHasher = stubDocumentHasher{fail: []vo.Path{"Books/gone.epub"}}
+	old := domain.NewLibraryItemBuilder().Id(domain.NewLibraryItemID()).Path("Books/old.epub").Build()
+	gone := domain.NewLibraryItemBuilder().Id(domain.NewLibraryItemID()).Path("Books/gone.epub").Build()
+
+	snap.On("Snapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+	sr.On("GetLibrarySnapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+	ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{&old, &gone}, nil)
+	ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{&old}).Return(nil)
+	setupEmptyTx(ext, sr, ar, ir, sess)
+
+	require.NoError(t, app.ScanLibrary(context.Background()))
+	ir.AssertCalled(t, "UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{&old})
+	assert.Equal(t, "md5:Books/old.epub", old.PartialMD5())
+	assert.Empty(t, gone.PartialMD5(), "tried again by the next scan")
+}
+
+func TestScanLibrary_backfillPageCount(t *testing.T) {
+	t.Parallel()
+
+	app, snap, ext, sr, ar, ir, sess := newTestApp(t)
+	app.PageCounter = stubPageCounter{counts: map[vo.Path]int{"Comics/old.cbz": 24}}
+	old := domain.NewLibraryItemBuilder().Id(domain.NewLibraryItemID()).Path("Comics/old.cbz").Build()
+	broken := domain.NewLibraryItemBuilder().Id(domain.NewLibraryItemID()).Path("Comics/broken.cbz").Build()
+
+	snap.On("Snapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+	sr.On("GetLibrarySnapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+	ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{&old, &broken}, nil)
+	ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{&old}).Return(nil)
+	setupEmptyTx(ext, sr, ar, ir, sess)
+
+	require.NoError(t, app.ScanLibrary(context.Background()))
+	ir.AssertCalled(t, "UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{&old})
+	assert.Equal(t, 24, old.PageCount())
+	assert.Zero(t, broken.PageCount(), "tried again by the next scan")
+}
+
+func TestNewApp(t *testing.T) {
+	t.Parallel()
+
+	app, _, _, _, _, _, _ := newTestApp(t)
+	app.SeriesRepo = new(mockSeriesRepo)
+	got, err := NewApp(app)
+	require.NoError(t, err)
+	assert.Same(t, app, got)
+
+	app, _, _, _, _, _, _ = newTestApp(t)
+	app.SeriesRepo = new(mockSeriesRepo)
+	app.PageCounter = nil
+	_, err = NewApp(app)
+	vx.AssertValidationErrors(t, err, v.Errors{"PageCounter": v.ErrNotNilRequired})
+
+	app, _, _, _, _, _, _ = newTestApp(t)
+	app.SeriesRepo = new(mockSeriesRepo)
+	app.WriteBack = true
+	_, err = NewApp(app)
+	vx.AssertValidationErrors(t, err, v.Errors{"MetadataWriter": v.ErrNotNilRequired})
+}
+
+func TestScanLibrary_itemTypes(t *testing.T) {
+	t.Parallel()
+
+	app, snap, ext, sr, ar, ir, sess := newTestApp(t)
+	current := vo.LibrarySnapshot{
+		"Books/Dune.epub":        []byte("h1"),
+		"Comics/Plastic Man.cbz": []byte("h2"),
+		"Manga/Berserk.cbz":      []byte("h3"),
+	}
+	berserk := validMeta("Berserk 1", "Kentaro Miura")
+	berserk.Manga = true
+
+	snap.On("Snapshot", mock.Anything).Return(current, nil)
+	sr.On("GetLibrarySnapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+	ext.On("Extract", mock.Anything, mock.Anything).Return(map[vo.Path]vo.Metadata{
+		"Books/Dune.epub":        validMeta("Dune", "Frank Herbert"),
+		"Comics/Plastic Man.cbz": validMeta("Plastic Man", "Jack Cole"),
+		"Manga/Berserk.cbz":      berserk,
+	}, nil)
+	sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
+	ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
+	var created []*domain.LibraryItem
+	ir.On("CreateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
+		created = items
+		return true
+	})).Return(nil)
+	ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).Return([]*domain.LibraryItem{}, nil)
+	ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+	sr.On("ReplaceSnapshot", mock.Anything, current).Return(nil)
+
+	require.NoError(t, app.ScanLibrary(context.Background()))
+
+	types := make(map[string]domain.LibraryItemType, len(created))
+	noManga := domain.ContentRestrictions{DenyTypes: []domain.LibraryItemType{domain.Manga}}
+	var permitted []string
+	for _, item := range created {
+		types[item.Title()] = item.ItemType()
+		if noManga.Permits(item.ContentAttrs()) {
+			permitted = append(permitted, item.Title())
+		}
+	}
+	assert.Equal(t, map[string]domain.LibraryItemType{"Dune": domain.Book, "Plastic Man": domain.Comic, "Berserk 1": domain.Manga}, types)
+	assert.ElementsMatch(t, []string{"Dune", "Plastic Man"}, permitted)
+}
+
+func TestScanLibrary_series(t *testing.T) {
+	t.Parallel()
+
+	app, snap, ext, sr, ar, ir, sess := newTestApp(t)
+	seriesRepo := new(mockSeriesRepo)
+	app.SeriesRepo = seriesRepo
+	current := vo.LibrarySnapshot{
+		"Manga/Berserk/Berserk v01.cbz":     []byte("h1"),
+		"Manga/Berserk/Berserk v02.cbz":     []byte("h2"),
+		"Books/Salvatore/Homeland.epub":     []byte("h3"),
+		"Books/Salvatore/Standalone.epub":   []byte("h4"),
+		"Books/Salvatore/Works Vol. 2.epub": []byte("h5"),
+	}
+	berserk, err := domain.NewSeries(domain.NewSeriesID(), "Berserk")
+	require.NoError(t, err)
+	darkElf, err := domain.NewSeries(domain.NewSeriesID(), "The Dark Elf Trilogy")
+	require.NoError(t, err)
+
+	fromComicInfo := validMeta("Berserk 2", "Kentaro Miura")
+	fromComicInfo.Series, fromComicInfo.SeriesIndex, fromComicInfo.ReadingDirection = "Berserk", 2, "rtl"
+	fromCalibre := validMeta("Homeland", "R. A. Salvatore")
+	fromCalibre.Series, fromCalibre.SeriesIndex = "The Dark Elf Trilogy", 1.5
+
+	snap.On("Snapshot", mock.Anything).Return(current, nil)
+	sr.On("GetLibrarySnapshot", mock.Anything).Return(vo.LibrarySnapshot{}, nil)
+	ext.On("Extract", mock.Anything, mock.Anything).Return(map[vo.Path]vo.Metadata{
+		"Manga/Berserk/Berserk v01.cbz":     validMeta("Berserk 1", "Kentaro Miura"),
+		"Manga/Berserk/Berserk v02.cbz":     fromComicInfo,
+		"Books/Salvatore/Homeland.epub":     fromCalibre,
+		"Books/Salvatore/Standalone.epub":   validMeta("Standalone", "R. A. Salvatore"),
+		"Books/Salvatore/Works Vol. 2.epub": validMeta("Works", "R. A. Salvatore"),
+	}, nil)
+	sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
+	ar.On("GetOrCreateAuthors", mock.Anything, mock.Anything).Return([]domain.Author{}, nil)
+	seriesRepo.On("GetOrCreateSeries", mock.Anything, matchStrings("Berserk", "The Dark Elf Trilogy")).
+		Return([]*domain.Series{berserk, darkElf}, nil)
+	seriesRepo.On("UpdateSeries", mock.Anything, []*domain.Series{berserk}).Return(nil)
+	ir.On("CreateLibraryItems", mock.Anything, mock.MatchedBy(func(items []*domain.LibraryItem) bool {
+		want := map[string]domain.ItemSeries{
+			"Berserk 1":  {ID: berserk.ID(), Index: 1},
+			"Berserk 2":  {ID: berserk.ID(), Index: 2},
+			"Homeland":   {ID: darkElf.ID(), Index: 1.5},
+			"Standalone": {},
+			"Works":      {},
+		}
+		if len(items) != len(want) {
+			return false
+		}
+		for _, item := range items {
+			if item.Series() != want[item.Title()] || len(item.LockedFields()) > 0 {
+				return false
+			}
+		}
+		return true
+	})).Return(nil)
+	ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("GetLibraryItemsByHash", mock.Anything, mock.Anything).Return([]*domain.LibraryItem{}, nil)
+	ir.On("UpdateLibraryItems", mock.Anything, mock.Anything).Return(nil)
+	sr.On("ReplaceSnapshot", mock.Anything, current).Return(nil)
+
+	require.NoError(t, app.ScanLibrary(context.Background()))
+	ir.AssertExpectations(t)
+	seriesRepo.AssertExpectations(t)
+	assert.Equal(t, domain.RightToLeft, berserk.ReadingDirection())
+	assert.Empty(t, darkElf.ReadingDirection())
+}
+
+func TestScanLibrary_changedFile(t *testing.T) {
+	t.Parallel()
+
+	app, snap, ext, sr, ar, ir, sess := newTestApp(t)
+	author := mustNewAuthor(t, "Author")
+	item := mustNewLibraryItem(t, "a.epub", []byte("h1"), []domain.AuthorID{author.ID()})
+	title := "Edited Title"
+	_, err := item.EditMetadata(domain.MetadataEdit{Title: &title}, nil)
+	require.NoError(t, err)
+
+	md := validMeta("Title From File", "Author")
+	md.Subjects, md.Description = []string{"fantasy"}, "A new description"
+	current := vo.LibrarySnapshot{"a.epub": []byte("h2")}
+
+	snap.On("Snapshot", mock.Anything).Return(current, nil)
+	sr.On("GetLibrarySnapshot", mock.Anything).Return(vo.LibrarySnapshot{"a.epub": []byte("h1")}, nil)
+	ext.On("Extract", mock.Anything, matchStrings("a.epub")).Return(map[vo.Path]vo.Metadata{"a.epub": md}, nil)
+	sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
+	ar.On("GetOrCreateAuthors", mock.Anything, matchStrings("Author")).Return([]domain.Author{author}, nil)
+	ir.On("CreateLibraryItems", mock.Anything, []*domain.LibraryItem{}).Return(nil)
+	ir.On("GetLibraryItemsByHash", mock.Anything, matchHashes([]byte("h1"))).Return([]*domain.LibraryItem{item}, nil)
+	ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{item}).Return(nil)
+	ir.On("ListLibraryItemsWithoutPartialMD5", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	ir.On("ListLibraryItemsWithoutPageCount", mock.Anything).Return([]*domain.LibraryItem{}, nil).Maybe()
+	sr.On("ReplaceSnapshot", mock.Anything, current).Return(nil)
+
+	require.NoError(t, app.ScanLibrary(context.Background()))
+	mock.AssertExpectationsForObjects(t, snap, ext, sr, ar, ir, sess)
+	assert.Equal(t, "Edited Title", item.Title())
+	assert.Equal(t, []domain.MetadataField{domain.FieldTitle}, item.LockedFields())
+	assert.Equal(t, []byte("h2"), item.Hash())
+	assert.Equal(t, []string{"fantasy"}, item.Genre())
+	assert.Equal(t, "A new description", item.Annotation())
+	assert.False(t, item.IsDeleted())
+}
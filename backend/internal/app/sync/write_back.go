@@ -0,0 +1,97 @@
+package sync_app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+// MetadataWriter writes metadata into the files of the library.
+type MetadataWriter interface {
+	// WriteEPUBMetadata applies u to the EPUB at path, replacing the file atomically, and returns the
+	// hash of the new file.
+	WriteEPUBMetadata(ctx context.Context, path vo.Path, u epubx.MetadataUpdate) (vo.Hash, error)
+}
+
+// WriteBackMetadata writes the metadata of the item into its EPUB, so other reading systems see the
+// edits made in the library. It does nothing unless App.WriteBack is set, and leaves files of other
+// formats, deleted items and DRM protected books alone. Only the authors of the file are replaced,
+// its translators, illustrators and other creators are kept. The identifiers and cover of the file
+// are left as they are, the library keeps neither of its own and can not edit them, so there is
+// nothing to write even though epubx.MetadataUpdate could.
+//
+// The hashes of the item and the snapshot follow the new file, so the next scan does not take it for
+// a new one and a later move is still recognized.
+func (a *App) WriteBackMetadata(ctx context.Context, id domain.LibraryItemID) error {
+	const op = errorx.Op("sync.App.WriteBackMetadata")
+
+	if !a.WriteBack {
+		return nil
+	}
+
+	item, err := a.LibraryItemRepo.GetLibraryItem(ctx, id)
+	if err != nil {
+		return op.Wrap(err)
+	}
+	if item.Format() != domain.FormatEPUB || item.IsDeleted() || item.IsProtected() {
+		return nil
+	}
+
+	authors, err := a.AuthorRepo.GetAuthors(ctx, item.AuthorIDs())
+	if err != nil {
+		return op.Wrap(err)
+	}
+	nameByID := make(map[domain.AuthorID]string, len(authors))
+	for _, author := range authors {
+		nameByID[author.ID()] = author.Name()
+	}
+	creators := make([]epubx.Author, 0, len(item.AuthorIDs()))
+	for _, id := range item.AuthorIDs() {
+		if name, ok := nameByID[id]; ok {
+			creators = append(creators, epubx.Author{Name: name, Role: "aut"})
+		}
+	}
+
+	title, annotation := item.Title(), item.Annotation()
+	u := epubx.MetadataUpdate{
+		Title:       &title,
+		Creators:    creators,
+		Subjects:    append([]string{}, item.Genre()...),
+		Description: &annotation,
+		Modified:    time.Now(),
+	}
+	// Items scanned before series were kept have none, that must not remove the series of the file.
+	if series := item.Series(); !series.ID.IsNil() {
+		s, err := a.SeriesRepo.GetSeries(ctx, series.ID)
+		if err != nil {
+			return op.Wrap(err)
+		}
+		u.Series = &epubx.Collection{Name: s.Name(), Type: "series", Position: series.Index}
+	} else if item.IsLocked(domain.FieldSeries) {
+		u.Series = &epubx.Collection{Type: "series"}
+	}
+	hash, err := a.MetadataWriter.WriteEPUBMetadata(ctx, item.Path(), u)
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	item.UpdateHash(hash)
+	partialMD5, err := a.DocumentHasher.PartialMD5(ctx, item.Path())
+	if err != nil {
+		slog.WarnContext(ctx, "failed to hash document for KOReader", "path", item.Path(), "error", err)
+	} else {
+		item.SetPartialMD5(partialMD5)
+	}
+
+	return a.Session.Transaction(ctx, func(ctx context.Context) error {
+		if err := a.LibraryItemRepo.UpdateLibraryItems(ctx, []*domain.LibraryItem{item}); err != nil {
+			return op.Wrap(err)
+		}
+		return op.Wrap(a.SnapshotRepo.SetSnapshotHash(ctx, item.Path(), hash))
+	})
+}
@@ -0,0 +1,146 @@
+package sync_app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	vo "github.com/ARUMANDESU/goread/backend/internal/domain/value-object"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+type mockMetadataWriter struct{ mock.Mock }
+
+func (m *mockMetadataWriter) WriteEPUBMetadata(ctx context.Context, path vo.Path, u epubx.MetadataUpdate) (vo.Hash, error) {
+	args := m.Called(ctx, path, u)
+	hash, _ := args.Get(0).(vo.Hash)
+	return hash, args.Error(1)
+}
+
+func TestWriteBackMetadata(t *testing.T) {
+	t.Parallel()
+
+	author := mustNewAuthor(t, "Роберт Сальваторе")
+	darkElf, err := domain.NewSeries(domain.NewSeriesID(), "Темный эльф")
+	require.NoError(t, err)
+	newHash := vo.Hash("new-hash")
+
+	tests := []struct {
+		name       string
+		writeBack  bool
+		path       string
+		setup      func(t *testing.T, item *domain.LibraryItem)
+		writeErr   error
+		wantErr    error
+		wantWrite  bool
+		wantSeries *epubx.Collection
+	}{
+		{name: "disabled", writeBack: false, path: "Books/book.epub"},
+		{name: "epub", writeBack: true, path: "Books/book.epub", wantWrite: true},
+		{
+			name:      "series",
+			writeBack: true,
+			path:      "Books/book.epub",
+			setup: func(t *testing.T, item *domain.LibraryItem) {
+				_, err := item.ApplyMetadata(domain.MetadataEdit{Series: &domain.ItemSeries{ID: darkElf.ID(), Index: 3}})
+				require.NoError(t, err)
+			},
+			wantWrite:  true,
+			wantSeries: &epubx.Collection{Name: "Темный эльф", Type: "series", Position: 3},
+		},
+		{
+			name:      "series removed by hand",
+			writeBack: true,
+			path:      "Books/book.epub",
+			setup: func(t *testing.T, item *domain.LibraryItem) {
+				_, err := item.EditMetadata(domain.MetadataEdit{Series: &domain.ItemSeries{}}, nil)
+				require.NoError(t, err)
+			},
+			wantWrite:  true,
+			wantSeries: &epubx.Collection{Type: "series"},
+		},
+		{name: "not an epub", writeBack: true, path: "Books/book.pdf"},
+		{name: "drm protected", writeBack: true, path: "Books/book.epub", setup: func(t *testing.T, item *domain.LibraryItem) { item.SetDRM("adobe-adept") }},
+		{name: "deleted", writeBack: true, path: "Books/book.epub", setup: func(t *testing.T, item *domain.LibraryItem) { item.Delete() }},
+		{
+			name:      "write fails",
+			writeBack: true,
+			path:      "Books/book.epub",
+			writeErr:  epubx.ErrMissingMetadata,
+			wantErr:   epubx.ErrMissingMetadata,
+			wantWrite: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, _, _, sr, ar, ir, sess := newTestApp(t)
+			writer := new(mockMetadataWriter)
+			app.MetadataWriter = writer
+			seriesRepo := new(mockSeriesRepo)
+			app.SeriesRepo = seriesRepo
+			seriesRepo.On("GetSeries", mock.Anything, darkElf.ID()).Return(darkElf, nil)
+			app.WriteBack = tt.writeBack
+
+			item := mustNewLibraryItem(t, tt.path, []byte("old-hash"), []domain.AuthorID{author.ID()})
+			item.SetPartialMD5("md5:old")
+			if tt.setup != nil {
+				tt.setup(t, item)
+			}
+			ir.On("GetLibraryItem", mock.Anything, item.ID()).Return(item, nil)
+			ar.On("GetAuthors", mock.Anything, []domain.AuthorID{author.ID()}).Return([]domain.Author{author}, nil)
+			writer.On("WriteEPUBMetadata", mock.Anything, tt.path, mock.MatchedBy(func(u epubx.MetadataUpdate) bool {
+				return *u.Title == "Test Title" &&
+					assert.ObjectsAreEqual([]epubx.Author{{Name: "Роберт Сальваторе", Role: "aut"}}, u.Creators) &&
+					assert.ObjectsAreEqual([]string{"fiction"}, u.Subjects) &&
+					*u.Description == "A test book" && !u.Modified.IsZero() &&
+					assert.ObjectsAreEqual(tt.wantSeries, u.Series) &&
+					u.Identifiers == nil && u.Cover == nil
+			})).Return(newHash, tt.writeErr)
+			sess.On("Transaction", mock.Anything, mock.Anything).Return(nil)
+			ir.On("UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{item}).Return(nil)
+			sr.On("SetSnapshotHash", mock.Anything, tt.path, newHash).Return(nil)
+
+			err := app.WriteBackMetadata(t.Context(), item.ID())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !tt.wantWrite {
+				writer.AssertNotCalled(t, "WriteEPUBMetadata", mock.Anything, mock.Anything, mock.Anything)
+				assert.Equal(t, []byte("old-hash"), item.Hash())
+				return
+			}
+			writer.AssertExpectations(t)
+			if tt.wantErr != nil {
+				sr.AssertNotCalled(t, "SetSnapshotHash", mock.Anything, mock.Anything, mock.Anything)
+				assert.Equal(t, []byte("old-hash"), item.Hash())
+				return
+			}
+			assert.Equal(t, []byte(newHash), item.Hash())
+			assert.Equal(t, "md5:"+tt.path, item.PartialMD5())
+			ir.AssertCalled(t, "UpdateLibraryItems", mock.Anything, []*domain.LibraryItem{item})
+			sr.AssertCalled(t, "SetSnapshotHash", mock.Anything, tt.path, newHash)
+		})
+	}
+}
+
+func TestWriteBackMetadata_notFound(t *testing.T) {
+	t.Parallel()
+
+	app, _, _, _, _, ir, _ := newTestApp(t)
+	app.WriteBack = true
+	id := domain.NewLibraryItemID()
+	ir.On("GetLibraryItem", mock.Anything, id).Return(nil, domain.ErrLibraryItemNotFound)
+
+	err := app.WriteBackMetadata(t.Context(), id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,52 @@
+package domain
+
+// AgeRating uses the values of the ComicInfo AgeRating element, so ratings read from comics
+// are stored as is. Books get it from manual edits.
+type AgeRating string
+
+const (
+	AgeRatingUnknown        AgeRating = "Unknown"
+	AgeRatingAdultsOnly18   AgeRating = "Adults Only 18+"
+	AgeRatingEarlyChildhood AgeRating = "Early Childhood"
+	AgeRatingEveryone       AgeRating = "Everyone"
+	AgeRatingEveryone10     AgeRating = "Everyone 10+"
+	AgeRatingG              AgeRating = "G"
+	AgeRatingKidsToAdults   AgeRating = "Kids to Adults"
+	AgeRatingM              AgeRating = "M"
+	AgeRatingMA15           AgeRating = "MA15+"
+	AgeRatingMature17       AgeRating = "Mature 17+"
+	AgeRatingPG             AgeRating = "PG"
+	AgeRatingR18            AgeRating = "R18+"
+	AgeRatingPending        AgeRating = "Rating Pending"
+	AgeRatingTeen           AgeRating = "Teen"
+	AgeRatingX18            AgeRating = "X18+"
+)
+
+var AgeRatings = []any{
+	AgeRatingUnknown, AgeRatingAdultsOnly18, AgeRatingEarlyChildhood, AgeRatingEveryone, AgeRatingEveryone10,
+	AgeRatingG, AgeRatingKidsToAdults, AgeRatingM, AgeRatingMA15, AgeRatingMature17, AgeRatingPG, AgeRatingR18,
+	AgeRatingPending, AgeRatingTeen, AgeRatingX18,
+}
+
+var ageRatingMinAge = map[AgeRating]int{
+	AgeRatingG:              0,
+	AgeRatingEarlyChildhood: 3,
+	AgeRatingEveryone:       6,
+	AgeRatingKidsToAdults:   6,
+	AgeRatingEveryone10:     10,
+	AgeRatingPG:             10,
+	AgeRatingTeen:           13,
+	AgeRatingMA15:           15,
+	AgeRatingM:              17,
+	AgeRatingMature17:       17,
+	AgeRatingR18:            18,
+	AgeRatingAdultsOnly18:   18,
+	AgeRatingX18:            18,
+}
+
+// MinAge returns the youngest age the rating is suitable for, ok is false for unrated items:
+// empty, Unknown and Rating Pending.
+func (r AgeRating) MinAge() (age int, ok bool) {
+	age, ok = ageRatingMinAge[r]
+	return age, ok
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+const (
+	// APITokenPrefix makes tokens recognizable, e.g. by secret scanners.
+	APITokenPrefix     = "grt_"
+	APITokenBytes      = 32
+	MaxAPITokenNameLen = 100
+	// APITokenTouchInterval limits how often LastUsedAt is written for a busy token.
+	APITokenTouchInterval = time.Minute
+)
+
+// Scope limits what an API token can be used for, sessions are not limited by scopes.
+type Scope string
+
+const (
+	ScopeReadLibrary   Scope = "library:read"
+	ScopeWriteProgress Scope = "progress:write"
+	// ScopeAdmin allows the permissions of the token owner's role beyond reading and syncing.
+	ScopeAdmin Scope = "admin"
+)
+
+var Scopes = []any{ScopeReadLibrary, ScopeWriteProgress, ScopeAdmin}
+
+var ErrInsufficientScope = fmt.Errorf("insufficient token scope: %w", ErrForbidden)
+
+type APITokenID = uuid.UUID
+
+// APIToken is a long-lived credential for scripts and e-reader apps that can not log in
+// interactively. Like sessions, only the hash of the token is stored.
+//
+//go:generate go tool gobuildergen --type APIToken
+type APIToken struct {
+	id         APITokenID
+	userID     UserID
+	name       string
+	tokenHash  []byte
+	scopes     []Scope
+	createdAt  time.Time
+	expiresAt  *time.Time
+	lastUsedAt *time.Time
+	revokedAt  *time.Time
+}
+
+func NewAPITokenID() APITokenID {
+	return uuid.Must(uuid.NewV7())
+}
+
+// NewAPIToken creates a token of the user and returns it with its secret. A nil expiresAt
+// means the token never expires.
+func NewAPIToken(userID UserID, name string, scopes []Scope, expiresAt *time.Time) (*APIToken, string, error) {
+	const op = errorx.Op("domain.NewAPIToken")
+
+	now := time.Now()
+	err := v.Errors{
+		"userID":    v.Validate(userID, vx.Required),
+		"name":      v.Validate(name, vx.Required, v.Length(1, MaxAPITokenNameLen)),
+		"scopes":    v.Validate(scopes, v.Required, v.Each(v.In(Scopes...))),
+		"expiresAt": v.Validate(expiresAt, v.Min(now).Exclusive()),
+	}.Filter()
+	if err != nil {
+		return nil, "", op.Wrap(err)
+	}
+
+	secret, err := NewOpaqueToken(APITokenBytes)
+	if err != nil {
+		return nil, "", op.Wrap(err)
+	}
+	secret = APITokenPrefix + secret
+
+	return &APIToken{
+		id:        NewAPITokenID(),
+		userID:    userID,
+		name:      name,
+		tokenHash: HashToken(secret),
+		scopes:    dedupScopes(scopes),
+		createdAt: now,
+		expiresAt: expiresAt,
+	}, secret, nil
+}
+
+func dedupScopes(scopes []Scope) []Scope {
+	res := make([]Scope, 0, len(scopes))
+	for _, s := range scopes {
+		if !slices.Contains(res, s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func (t *APIToken) ID() APITokenID {
+	return t.id
+}
+
+func (t *APIToken) UserID() UserID {
+	return t.userID
+}
+
+func (t *APIToken) Name() string {
+	return t.name
+}
+
+func (t *APIToken) TokenHash() []byte {
+	return t.tokenHash
+}
+
+func (t *APIToken) Scopes() []Scope {
+	return t.scopes
+}
+
+func (t *APIToken) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+func (t *APIToken) ExpiresAt() *time.Time {
+	return t.expiresAt
+}
+
+func (t *APIToken) LastUsedAt() *time.Time {
+	return t.lastUsedAt
+}
+
+func (t *APIToken) RevokedAt() *time.Time {
+	return t.revokedAt
+}
+
+// IsActive reports whether the token can still authenticate requests.
+func (t *APIToken) IsActive() bool {
+	return t.revokedAt == nil && (t.expiresAt == nil || time.Now().Before(*t.expiresAt))
+}
+
+func (t *APIToken) HasScope(s Scope) bool {
+	return slices.Contains(t.scopes, s)
+}
+
+// Touch records a use of the token and reports whether LastUsedAt changed, it is only
+// updated once per APITokenTouchInterval.
+func (t *APIToken) Touch(now time.Time) bool {
+	if t.lastUsedAt != nil && now.Sub(*t.lastUsedAt) < APITokenTouchInterval {
+		return false
+	}
+	t.lastUsedAt = &now
+	return true
+}
+
+func (t *APIToken) Revoke() {
+	if t.revokedAt != nil {
+		return
+	}
+	now := time.Now()
+	t.revokedAt = &now
+}
+
+// Scope returns the API token scope required to use the permission.
+func (p Permission) Scope() Scope {
+	if p == PermDownload {
+		return ScopeReadLibrary
+	}
+	return ScopeAdmin
+}
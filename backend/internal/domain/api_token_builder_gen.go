@@ -0,0 +1,65 @@
+// Code generated by github.com/ARUMANDESU/gobuildergen. DO NOT EDIT.
+package domain
+
+import "time"
+
+type APITokenBuilder struct {
+    val APIToken
+}
+
+func NewAPITokenBuilder() *APITokenBuilder {
+    return &APITokenBuilder{}
+}
+
+func (b *APITokenBuilder) WithDefault() *APITokenBuilder {
+    return b
+}
+
+func (b *APITokenBuilder) Id(v APITokenID) *APITokenBuilder {
+    b.val.id = v
+    return b
+}
+
+func (b *APITokenBuilder) UserID(v UserID) *APITokenBuilder {
+    b.val.userID = v
+    return b
+}
+
+func (b *APITokenBuilder) Name(v string) *APITokenBuilder {
+    b.val.name = v
+    return b
+}
+
+func (b *APITokenBuilder) TokenHash(v []byte) *APITokenBuilder {
+    b.val.tokenHash = v
+    return b
+}
+
+func (b *APITokenBuilder) Scopes(v []Scope) *APITokenBuilder {
+    b.val.scopes = v
+    return b
+}
+
+func (b *APITokenBuilder) CreatedAt(v time.Time) *APITokenBuilder {
+    b.val.createdAt = v
+    return b
+}
+
+func (b *APITokenBuilder) ExpiresAt(v *time.Time) *APITokenBuilder {
+    b.val.expiresAt = v
+    return b
+}
+
+func (b *APITokenBuilder) LastUsedAt(v *time.Time) *APITokenBuilder {
+    b.val.lastUsedAt = v
+    return b
+}
+
+func (b *APITokenBuilder) RevokedAt(v *time.Time) *APITokenBuilder {
+    b.val.revokedAt = v
+    return b
+}
+
+func (b *APITokenBuilder) Build() APIToken {
+    return b.val
+}
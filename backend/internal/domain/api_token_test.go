@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func TestNewAPIToken(t *testing.T) {
+	t.Parallel()
+
+	userID := NewUserID()
+	expiresAt := time.Now().Add(time.Hour)
+
+	token, secret, err := NewAPIToken(userID, "koreader", []Scope{ScopeReadLibrary, ScopeWriteProgress, ScopeReadLibrary}, &expiresAt)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, APITokenPrefix))
+	assert.Equal(t, HashToken(secret), token.TokenHash())
+	assert.Equal(t, userID, token.UserID())
+	assert.Equal(t, []Scope{ScopeReadLibrary, ScopeWriteProgress}, token.Scopes())
+	assert.True(t, token.IsActive())
+	assert.Nil(t, token.LastUsedAt())
+}
+
+func TestNewAPIToken_errors(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Minute)
+	_, _, err := NewAPIToken(UserID{}, "", []Scope{"everything"}, &past)
+	vx.AssertValidationErrors(t, err, v.Errors{
+		"userID":    v.ErrRequired,
+		"name":      v.ErrRequired,
+		"scopes":    v.Errors{"0": v.ErrInInvalid},
+		"expiresAt": v.ErrMinGreaterThanRequired,
+	})
+
+	_, _, err = NewAPIToken(NewUserID(), "script", nil, nil)
+	vx.AssertValidationErrors(t, err, v.Errors{"scopes": v.ErrRequired})
+}
+
+func TestAPIToken_IsActive(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		token    APIToken
+		expected bool
+	}{
+		{name: "no expiry", token: NewAPITokenBuilder().Build(), expected: true},
+		{name: "not expired", token: NewAPITokenBuilder().ExpiresAt(&future).Build(), expected: true},
+		{name: "expired", token: NewAPITokenBuilder().ExpiresAt(&past).Build()},
+		{name: "revoked", token: NewAPITokenBuilder().RevokedAt(&past).Build()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tt.token.IsActive())
+		})
+	}
+}
+
+func TestAPIToken_Touch(t *testing.T) {
+	t.Parallel()
+
+	token := NewAPITokenBuilder().Build()
+	now := time.Now()
+
+	assert.True(t, token.Touch(now))
+	assert.False(t, token.Touch(now.Add(APITokenTouchInterval/2)), "touched recently")
+	assert.True(t, token.Touch(now.Add(APITokenTouchInterval)))
+	assert.Equal(t, now.Add(APITokenTouchInterval), *token.LastUsedAt())
+}
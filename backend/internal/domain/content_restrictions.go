@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+
+	v "github.com/ARUMANDESU/validation"
+)
+
+const MaxRestrictionAge = 99
+
+// ContentRestrictions limit which library items a user can see, e.g. for kids' accounts.
+// Allow lists are ignored when empty, deny lists win over allow lists. Genres, roots and
+// tags are compared case-insensitively.
+type ContentRestrictions struct {
+	AllowTypes  []LibraryItemType
+	DenyTypes   []LibraryItemType
+	AllowGenres []string
+	DenyGenres  []string
+	// AllowRoots and DenyRoots are top level directories of the library, see LibraryItem.Root.
+	AllowRoots []string
+	DenyRoots  []string
+	AllowTags  []string
+	DenyTags   []string
+	// MaxAge hides items rated for older readers, 0 means no age limit.
+	MaxAge int
+	// AllowUnrated lets a user with MaxAge see items without an age rating.
+	AllowUnrated bool
+}
+
+// ContentAttrs are the properties of a library item that restrictions are checked against.
+type ContentAttrs struct {
+	Type      LibraryItemType
+	Genres    []string
+	Root      string
+	Tags      []string
+	AgeRating AgeRating
+}
+
+func (r ContentRestrictions) Validate() error {
+	return v.Errors{
+		"allowTypes": v.Validate(r.AllowTypes, v.Each(v.In(Book, Manga, Comic))),
+		"denyTypes":  v.Validate(r.DenyTypes, v.Each(v.In(Book, Manga, Comic))),
+		"maxAge":     v.Validate(r.MaxAge, v.Min(0), v.Max(MaxRestrictionAge)),
+	}.Filter()
+}
+
+// IsZero reports whether r restricts nothing.
+func (r ContentRestrictions) IsZero() bool {
+	return len(r.AllowTypes) == 0 && len(r.DenyTypes) == 0 &&
+		len(r.AllowGenres) == 0 && len(r.DenyGenres) == 0 &&
+		len(r.AllowRoots) == 0 && len(r.DenyRoots) == 0 &&
+		len(r.AllowTags) == 0 && len(r.DenyTags) == 0 &&
+		r.MaxAge == 0
+}
+
+// Permits reports whether an item with the given attributes passes the restrictions.
+func (r ContentRestrictions) Permits(a ContentAttrs) bool {
+	if len(r.AllowTypes) > 0 && !slices.Contains(r.AllowTypes, a.Type) {
+		return false
+	}
+	if slices.Contains(r.DenyTypes, a.Type) {
+		return false
+	}
+
+	if !allowed(r.AllowGenres, r.DenyGenres, a.Genres) ||
+		!allowed(r.AllowRoots, r.DenyRoots, []string{a.Root}) ||
+		!allowed(r.AllowTags, r.DenyTags, a.Tags) {
+		return false
+	}
+
+	if r.MaxAge > 0 {
+		age, ok := a.AgeRating.MinAge()
+		if !ok {
+			return r.AllowUnrated
+		}
+		return age <= r.MaxAge
+	}
+
+	return true
+}
+
+// allowed is true if values share an element with allow (when allow is set) and none with deny.
+func allowed(allow, deny, values []string) bool {
+	if slices.ContainsFunc(values, func(s string) bool { return containsFold(deny, s) }) {
+		return false
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(values, func(s string) bool { return containsFold(allow, s) })
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(e string) bool { return strings.EqualFold(e, s) })
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func TestContentRestrictions_Permits(t *testing.T) {
+	t.Parallel()
+
+	kidsManga := ContentAttrs{
+		Type:      Manga,
+		Genres:    []string{"Adventure"},
+		Root:      "Manga",
+		Tags:      []string{"shonen"},
+		AgeRating: AgeRatingEveryone10,
+	}
+
+	tests := []struct {
+		name     string
+		r        ContentRestrictions
+		attrs    ContentAttrs
+		expected bool
+	}{
+		{name: "no restrictions", attrs: kidsManga, expected: true},
+		{name: "allowed type", r: ContentRestrictions{AllowTypes: []LibraryItemType{Manga, Comic}}, attrs: kidsManga, expected: true},
+		{name: "type not in allow list", r: ContentRestrictions{AllowTypes: []LibraryItemType{Book}}, attrs: kidsManga},
+		{name: "denied type", r: ContentRestrictions{DenyTypes: []LibraryItemType{Manga}}, attrs: kidsManga},
+		{name: "allowed genre ignores case", r: ContentRestrictions{AllowGenres: []string{"adventure"}}, attrs: kidsManga, expected: true},
+		{name: "genre not in allow list", r: ContentRestrictions{AllowGenres: []string{"Comedy"}}, attrs: kidsManga},
+		{name: "deny wins over allow", r: ContentRestrictions{AllowTags: []string{"shonen"}, DenyTags: []string{"SHONEN"}}, attrs: kidsManga},
+		{name: "denied root", r: ContentRestrictions{DenyRoots: []string{"Manga"}}, attrs: kidsManga},
+		{name: "root not in allow list", r: ContentRestrictions{AllowRoots: []string{"Kids"}}, attrs: kidsManga},
+		{name: "old enough", r: ContentRestrictions{MaxAge: 10}, attrs: kidsManga, expected: true},
+		{name: "too young", r: ContentRestrictions{MaxAge: 9}, attrs: kidsManga},
+		{name: "unrated hidden by age limit", r: ContentRestrictions{MaxAge: 12}, attrs: ContentAttrs{Type: Book}},
+		{name: "unrated allowed", r: ContentRestrictions{MaxAge: 12, AllowUnrated: true}, attrs: ContentAttrs{Type: Book, AgeRating: AgeRatingPending}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tt.r.Permits(tt.attrs))
+		})
+	}
+}
+
+func TestContentRestrictions_Validate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ContentRestrictions{AllowTypes: []LibraryItemType{Comic}, MaxAge: 12}.Validate())
+	vx.AssertValidationErrors(t,
+		ContentRestrictions{DenyTypes: []LibraryItemType{"magazine"}, MaxAge: -1}.Validate(),
+		v.Errors{"denyTypes": v.Errors{"0": v.ErrInInvalid}, "maxAge": v.ErrMinGreaterEqualThanRequired},
+	)
+}
+
+func TestLibraryItem_ContentAttrs(t *testing.T) {
+	t.Parallel()
+
+	item := NewLibraryItemBuilder().ItemType(Comic).Genre([]string{"Humor"}).Path("Comics/Plastic Man (1944)/Plastic Man #002 (1944).cbz").Build()
+	item.SetTags([]string{" golden age ", "golden age", ""})
+	require.NoError(t, item.SetAgeRating(AgeRatingTeen))
+
+	assert.Equal(t, ContentAttrs{
+		Type:      Comic,
+		Genres:    []string{"Humor"},
+		Root:      "Comics",
+		Tags:      []string{"golden age"},
+		AgeRating: AgeRatingTeen,
+	}, item.ContentAttrs())
+
+	vx.AssertValidationErrors(t, item.SetAgeRating("NC-17"), v.Errors{"ageRating": v.ErrInInvalid})
+	assert.Equal(t, "", PathRoot("top-level.epub"))
+}
+
+func TestItemTypeOf(t *testing.T) {
+	t.Parallel()
+
+	booksOnly := ContentRestrictions{DenyTypes: []LibraryItemType{Manga, Comic}}
+	tests := []struct {
+		path     string
+		manga    bool
+		expected LibraryItemType
+	}{
+		{path: "Books/Dune.epub", expected: Book},
+		{path: "Books/Воин.fb2", expected: Book},
+		{path: "Books/scan.pdf", expected: Book},
+		{path: "Comics/Plastic Man #002 (1944).cbz", expected: Comic},
+		{path: "Comics/Saga 01.cbr", expected: Comic},
+		{path: "Manga/Berserk v01.cbz", manga: true, expected: Manga},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			typ := ItemTypeOf(FileFormatOf(tt.path), tt.manga)
+			assert.Equal(t, tt.expected, typ)
+
+			item := NewLibraryItemBuilder().ItemType(typ).Path(tt.path).Build()
+			assert.Equal(t, typ == Book, booksOnly.Permits(item.ContentAttrs()))
+		})
+	}
+}
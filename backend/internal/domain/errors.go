@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors, wrap them to describe the failure more precisely, so callers can still check the kind with errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limited")
+
+	ErrLibraryItemNotFound     = fmt.Errorf("library item %w", ErrNotFound)
+	ErrUserNotFound            = fmt.Errorf("user %w", ErrNotFound)
+	ErrSessionNotFound         = fmt.Errorf("session %w", ErrNotFound)
+	ErrAPITokenNotFound        = fmt.Errorf("api token %w", ErrNotFound)
+	ErrPasswordResetNotFound   = fmt.Errorf("password reset %w", ErrNotFound)
+	ErrReadingProgressNotFound = fmt.Errorf("reading progress %w", ErrNotFound)
+	ErrPageNotFound            = fmt.Errorf("page %w", ErrNotFound)
+	ErrResourceNotFound        = fmt.Errorf("resource %w", ErrNotFound)
+	ErrCoverNotFound           = fmt.Errorf("cover %w", ErrNotFound)
+	ErrSeriesNotFound          = fmt.Errorf("series %w", ErrNotFound)
+
+	// ErrDRMProtected is returned for content only licensed reading systems can read.
+	ErrDRMProtected = fmt.Errorf("drm protected: %w", ErrForbidden)
+)
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// FileFormat is the format of a library item's file, it is taken from the file extension.
+type FileFormat string
+
+const (
+	FormatUnknown FileFormat = ""
+	FormatEPUB    FileFormat = "epub"
+	FormatPDF     FileFormat = "pdf"
+	FormatFB2     FileFormat = "fb2"
+	FormatCBZ     FileFormat = "cbz"
+	FormatCBR     FileFormat = "cbr"
+	FormatCB7     FileFormat = "cb7"
+	FormatMOBI    FileFormat = "mobi"
+	FormatAZW3    FileFormat = "azw3"
+	FormatTXT     FileFormat = "txt"
+)
+
+var fileFormats = []FileFormat{FormatEPUB, FormatPDF, FormatFB2, FormatCBZ, FormatCBR, FormatCB7, FormatMOBI, FormatAZW3, FormatTXT}
+
+// FileFormatOf returns the format of the file at path or FormatUnknown.
+func FileFormatOf(path string) FileFormat {
+	ext := FileFormat(strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")))
+	for _, f := range fileFormats {
+		if f == ext {
+			return f
+		}
+	}
+	return FormatUnknown
+}
+
+// IsComicArchive reports whether the file is an archive of page images.
+func (f FileFormat) IsComicArchive() bool {
+	return f == FormatCBZ || f == FormatCBR || f == FormatCB7
+}
+
+// IsMobipocket reports whether the file is a Mobipocket PalmDB, as are Kindle books.
+func (f FileFormat) IsMobipocket() bool {
+	return f == FormatMOBI || f == FormatAZW3
+}
+
+// HasPages reports whether the file is made of fixed pages that can be served as images one by one.
+func (f FileFormat) HasPages() bool {
+	return f == FormatPDF || f.IsComicArchive()
+}
+
+// ConvertsToEPUB reports whether the file can be converted to EPUB for clients that read nothing else.
+func (f FileFormat) ConvertsToEPUB() bool {
+	return f == FormatFB2 || f == FormatTXT
+}
+
+// MediaType returns the MIME type of the format, application/octet-stream for FormatUnknown.
+func (f FileFormat) MediaType() string {
+	switch f {
+	case FormatEPUB:
+		return "application/epub+zip"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatFB2:
+		return "application/x-fictionbook+xml"
+	case FormatCBZ:
+		return "application/vnd.comicbook+zip"
+	case FormatCBR:
+		return "application/vnd.comicbook-rar"
+	case FormatCB7:
+		return "application/x-cb7"
+	case FormatMOBI:
+		return "application/x-mobipocket-ebook"
+	case FormatAZW3:
+		return "application/vnd.amazon.ebook"
+	case FormatTXT:
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
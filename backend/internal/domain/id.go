@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// TimeFromID extracts the unix millisecond timestamp encoded in the first 48 bits of a UUIDv7.
+// Returns zero time for any other uuid version.
+func TimeFromID(id uuid.UUID) time.Time {
+	if id.Version() != uuid.V7 {
+		return time.Time{}
+	}
+	ms := binary.BigEndian.Uint64(append([]byte{0, 0}, id[:6]...))
+	return time.UnixMilli(int64(ms))
+}
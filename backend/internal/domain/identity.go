@@ -0,0 +1,11 @@
+package domain
+
+// TrustedHeaderIssuer is the issuer of identities asserted by a reverse proxy through a header,
+// their subject is the user name sent by the proxy.
+const TrustedHeaderIssuer = "trusted-header"
+
+// ExternalIdentity links a user to an account at a single sign-on provider.
+type ExternalIdentity struct {
+	Issuer  string
+	Subject string
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	v "github.com/ARUMANDESU/validation"
@@ -26,18 +28,55 @@ const (
 	Comic LibraryItemType = "comic"
 )
 
+// ItemTypeOf returns the type of an item read from a file of the format, manga tells whether its
+// metadata says it is manga. Comic archives are comics and all other formats books.
+func ItemTypeOf(format FileFormat, manga bool) LibraryItemType {
+	switch {
+	case manga:
+		return Manga
+	case format.IsComicArchive():
+		return Comic
+	}
+	return Book
+}
+
 //go:generate go tool gobuildergen --type LibraryItem
 type LibraryItem struct {
-	id         LibraryItemID
-	title      string
-	itemType   LibraryItemType
-	authorIDs  []AuthorID
-	genre      []string
-	languages  []string
-	annotation string
-	path       string
-	hash       []byte
-	deletedAt  *time.Time
+	id        LibraryItemID
+	title     string
+	itemType  LibraryItemType
+	authorIDs []AuthorID
+	// contributors are the people other than authors, such as illustrators and translators.
+	contributors []Contributor
+	genre        []string
+	languages    []string
+	annotation   string
+	// seriesID and seriesIndex place the item in a series, see ItemSeries.
+	seriesID    SeriesID
+	seriesIndex float64
+	// lockedFields were edited by hand, rescans and metadata providers leave them alone.
+	lockedFields []MetadataField
+	path         string
+	hash         []byte
+	// partialMD5 identifies the file for KOReader, see koreaderx.PartialMD5.
+	partialMD5 string
+	// pageCount is the number of pages of PDFs and comic archives, zero for other formats.
+	pageCount int
+	// drm is the DRM scheme protecting the file, such as "adobe-adept", empty if it is not protected.
+	drm       string
+	tags      []string
+	ageRating AgeRating
+	// publicationModified is the last change of the publication the file says, such as the
+	// dcterms:modified of EPUBs, zero if unknown.
+	publicationModified time.Time
+	deletedAt           *time.Time
+}
+
+// Contributor is a person other than an author who worked on an item.
+type Contributor struct {
+	Name string
+	// Role is a MARC relator code, e.g. ill for illustrators or trl for translators.
+	Role string
 }
 
 func NewLibraryItemID() LibraryItemID {
@@ -82,15 +121,183 @@ func NewLibraryItem(
 	}, nil
 }
 
+// SetTags replaces the tags of the item, blank and duplicate tags are dropped.
+func (l *LibraryItem) SetTags(tags []string) {
+	cleaned := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(cleaned, tag) {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	l.tags = cleaned
+}
+
+// SetAgeRating sets the rating read from the file, a rating locked by a manual edit is left alone.
+func (l *LibraryItem) SetAgeRating(rating AgeRating) error {
+	const op = errorx.Op("domain.LibraryItem.SetAgeRating")
+	err := v.Errors{
+		"ageRating": v.Validate(rating, v.In(AgeRatings...)),
+	}.Filter()
+	if err != nil {
+		return op.Wrap(err)
+	}
+	if !l.IsLocked(FieldAgeRating) {
+		l.ageRating = rating
+	}
+	return nil
+}
+
+// SetContributors replaces the contributors read from the file, nameless and duplicate ones are dropped.
+func (l *LibraryItem) SetContributors(contributors []Contributor) {
+	cleaned := make([]Contributor, 0, len(contributors))
+	for _, c := range contributors {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name != "" && !slices.Contains(cleaned, c) {
+			cleaned = append(cleaned, c)
+		}
+	}
+	l.contributors = cleaned
+}
+
+func (l *LibraryItem) SetPublicationModified(t time.Time) {
+	l.publicationModified = t
+}
+
+func (l *LibraryItem) SetPartialMD5(hash string) {
+	l.partialMD5 = hash
+}
+
+func (l *LibraryItem) SetPageCount(n int) {
+	l.pageCount = max(n, 0)
+}
+
+func (l *LibraryItem) SetDRM(scheme string) {
+	l.drm = scheme
+}
+
 func (l *LibraryItem) UpdatePath(path string) {
 	l.path = path
 }
 
+// UpdateHash sets the hash of the file after it was rewritten in place, such as by writing its
+// metadata back, so a later move of the file is still recognized.
+func (l *LibraryItem) UpdateHash(hash []byte) {
+	l.hash = hash
+}
+
 func (l *LibraryItem) Delete() {
 	now := time.Now()
 	l.deletedAt = &now
 }
 
+func (l *LibraryItem) ID() LibraryItemID {
+	return l.id
+}
+
+func (l *LibraryItem) Title() string {
+	return l.title
+}
+
+func (l *LibraryItem) ItemType() LibraryItemType {
+	return l.itemType
+}
+
+func (l *LibraryItem) AuthorIDs() []AuthorID {
+	return l.authorIDs
+}
+
+func (l *LibraryItem) Genre() []string {
+	return l.genre
+}
+
+func (l *LibraryItem) Languages() []string {
+	return l.languages
+}
+
+func (l *LibraryItem) Annotation() string {
+	return l.annotation
+}
+
+func (l *LibraryItem) Path() string {
+	return l.path
+}
+
+func (l *LibraryItem) Format() FileFormat {
+	return FileFormatOf(l.path)
+}
+
 func (l *LibraryItem) Hash() []byte {
 	return l.hash
 }
+
+func (l *LibraryItem) PartialMD5() string {
+	return l.partialMD5
+}
+
+func (l *LibraryItem) PageCount() int {
+	return l.pageCount
+}
+
+func (l *LibraryItem) DRM() string {
+	return l.drm
+}
+
+// IsProtected reports whether the file is DRM protected, only licensed reading systems can read it.
+func (l *LibraryItem) IsProtected() bool {
+	return l.drm != ""
+}
+
+func (l *LibraryItem) Contributors() []Contributor {
+	return l.contributors
+}
+
+func (l *LibraryItem) PublicationModified() time.Time {
+	return l.publicationModified
+}
+
+func (l *LibraryItem) Tags() []string {
+	return l.tags
+}
+
+func (l *LibraryItem) AgeRating() AgeRating {
+	return l.ageRating
+}
+
+// Root returns the top level directory of the item inside the library, e.g. "Comics".
+func (l *LibraryItem) Root() string {
+	return PathRoot(l.path)
+}
+
+func (l *LibraryItem) ContentAttrs() ContentAttrs {
+	return ContentAttrs{
+		Type:      l.itemType,
+		Genres:    l.genre,
+		Root:      l.Root(),
+		Tags:      l.tags,
+		AgeRating: l.ageRating,
+	}
+}
+
+func (l *LibraryItem) DeletedAt() *time.Time {
+	return l.deletedAt
+}
+
+func (l *LibraryItem) IsDeleted() bool {
+	return l.deletedAt != nil
+}
+
+// AddedAt returns the time the item was added to the library, taken from its UUIDv7 id.
+func (l *LibraryItem) AddedAt() time.Time {
+	return TimeFromID(l.id)
+}
+
+// PathRoot returns the first element of a slash separated library path, or "" for files
+// at the top level.
+func PathRoot(p string) string {
+	root, _, found := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	if !found {
+		return ""
+	}
+	return root
+}
@@ -1,6 +1,7 @@
 // Code generated by github.com/ARUMANDESU/gobuildergen. DO NOT EDIT.
 package domain
 
+import "time"
 
 type LibraryItemBuilder struct {
     val LibraryItem
@@ -34,6 +35,11 @@ func (b *LibraryItemBuilder) AuthorIDs(v []AuthorID) *LibraryItemBuilder {
     return b
 }
 
+func (b *LibraryItemBuilder) Contributors(v []Contributor) *LibraryItemBuilder {
+    b.val.contributors = v
+    return b
+}
+
 func (b *LibraryItemBuilder) Genre(v []string) *LibraryItemBuilder {
     b.val.genre = v
     return b
@@ -49,6 +55,66 @@ func (b *LibraryItemBuilder) Annotation(v string) *LibraryItemBuilder {
     return b
 }
 
+func (b *LibraryItemBuilder) SeriesID(v SeriesID) *LibraryItemBuilder {
+    b.val.seriesID = v
+    return b
+}
+
+func (b *LibraryItemBuilder) SeriesIndex(v float64) *LibraryItemBuilder {
+    b.val.seriesIndex = v
+    return b
+}
+
+func (b *LibraryItemBuilder) LockedFields(v []MetadataField) *LibraryItemBuilder {
+    b.val.lockedFields = v
+    return b
+}
+
+func (b *LibraryItemBuilder) Path(v string) *LibraryItemBuilder {
+    b.val.path = v
+    return b
+}
+
+func (b *LibraryItemBuilder) Hash(v []byte) *LibraryItemBuilder {
+    b.val.hash = v
+    return b
+}
+
+func (b *LibraryItemBuilder) PartialMD5(v string) *LibraryItemBuilder {
+    b.val.partialMD5 = v
+    return b
+}
+
+func (b *LibraryItemBuilder) PageCount(v int) *LibraryItemBuilder {
+    b.val.pageCount = v
+    return b
+}
+
+func (b *LibraryItemBuilder) Drm(v string) *LibraryItemBuilder {
+    b.val.drm = v
+    return b
+}
+
+func (b *LibraryItemBuilder) Tags(v []string) *LibraryItemBuilder {
+    b.val.tags = v
+    return b
+}
+
+func (b *LibraryItemBuilder) AgeRating(v AgeRating) *LibraryItemBuilder {
+    b.val.ageRating = v
+    return b
+}
+
+func (b *LibraryItemBuilder) PublicationModified(v time.Time) *LibraryItemBuilder {
+    b.val.publicationModified = v
+    return b
+}
+
+func (b *LibraryItemBuilder) DeletedAt(v *time.Time) *LibraryItemBuilder {
+    b.val.deletedAt = v
+    return b
+}
+
 func (b *LibraryItemBuilder) Build() LibraryItem {
     return b.val
 }
@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"slices"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+// MetadataField is a field of the metadata of a library item that can be edited and locked.
+type MetadataField string
+
+const (
+	FieldTitle      MetadataField = "title"
+	FieldItemType   MetadataField = "itemType"
+	FieldAuthors    MetadataField = "authors"
+	FieldGenre      MetadataField = "genre"
+	FieldLanguages  MetadataField = "languages"
+	FieldAnnotation MetadataField = "annotation"
+	FieldSeries     MetadataField = "series"
+	FieldAgeRating  MetadataField = "ageRating"
+	// FieldLocked names the change of the locked fields in a MetadataChange, it is no field itself.
+	FieldLocked MetadataField = "locked"
+)
+
+var MetadataFields = []any{FieldTitle, FieldItemType, FieldAuthors, FieldGenre, FieldLanguages, FieldAnnotation, FieldSeries, FieldAgeRating}
+
+// ItemSeries places an item in a series, the zero value places it in none.
+type ItemSeries struct {
+	ID SeriesID
+	// Index is the position in the series, such as 1.5 for a volume between the first and
+	// second, zero if unknown.
+	Index float64
+}
+
+func (s ItemSeries) Validate() error {
+	return v.Errors{
+		"index": v.Validate(s.Index, v.Min(0.0), v.When(s.ID.IsNil(), v.Empty)),
+	}.Filter()
+}
+
+// MetadataEdit changes the metadata of a library item, nil fields are left as they are. An empty
+// but not nil Genre or Languages clears the field.
+type MetadataEdit struct {
+	Title      *string
+	ItemType   *LibraryItemType
+	AuthorIDs  []AuthorID
+	Genre      []string
+	Languages  []string
+	Annotation *string
+	Series     *ItemSeries
+	// AgeRating overrides the rating of the file, an empty rating clears it.
+	AgeRating *AgeRating
+}
+
+// Validate applies the rules of NewLibraryItem to the fields that are set.
+func (e MetadataEdit) Validate() error {
+	return e.errors().Filter()
+}
+
+func (e MetadataEdit) errors() v.Errors {
+	var series error
+	if e.Series != nil {
+		series = e.Series.Validate()
+	}
+	return v.Errors{
+		"title":      v.Validate(e.Title, v.When(e.Title != nil, vx.Required, v.Length(MinLibraryItemTitleLen, MaxLibraryItemTitleLen))),
+		"itemType":   v.Validate(e.ItemType, v.When(e.ItemType != nil, vx.Required, v.In(Book, Manga, Comic))),
+		"authorIDs":  v.Validate(e.AuthorIDs, v.When(e.AuthorIDs != nil, v.Required, v.Length(1, 0))),
+		"genre":      v.Validate(e.Genre, v.Length(1, 0)),
+		"languages":  v.Validate(e.Languages, v.Length(1, 0)),
+		"annotation": v.Validate(e.Annotation, v.Length(MinAnnotationLen, MaxAnnotationLen)),
+		"series":     series,
+		"ageRating":  v.Validate(e.AgeRating, v.In(AgeRatings...)),
+	}
+}
+
+// fields returns the fields the edit sets.
+func (e MetadataEdit) fields() []MetadataField {
+	var fields []MetadataField
+	for _, f := range []struct {
+		field MetadataField
+		set   bool
+	}{
+		{FieldTitle, e.Title != nil},
+		{FieldItemType, e.ItemType != nil},
+		{FieldAuthors, e.AuthorIDs != nil},
+		{FieldGenre, e.Genre != nil},
+		{FieldLanguages, e.Languages != nil},
+		{FieldAnnotation, e.Annotation != nil},
+		{FieldSeries, e.Series != nil},
+		{FieldAgeRating, e.AgeRating != nil},
+	} {
+		if f.set {
+			fields = append(fields, f.field)
+		}
+	}
+	return fields
+}
+
+// MetadataChange is the change of one field, Old and New are the values of the field, such as
+// a string for FieldTitle or []AuthorID for FieldAuthors.
+type MetadataChange struct {
+	Field MetadataField
+	Old   any
+	New   any
+}
+
+// EditMetadata applies a manual edit and locks the fields it sets, so rescans and metadata
+// providers leave them alone from now on. Fields in unlock are unlocked after the edit. It
+// returns the changes, including one of FieldLocked if the locked fields changed.
+func (l *LibraryItem) EditMetadata(e MetadataEdit, unlock []MetadataField) ([]MetadataChange, error) {
+	const op = errorx.Op("domain.LibraryItem.EditMetadata")
+
+	errs := e.errors()
+	errs["unlock"] = v.Validate(unlock, v.Each(v.In(MetadataFields...)))
+	if err := errs.Filter(); err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	changes := l.applyMetadata(e, false)
+	locked := slices.Clone(l.lockedFields)
+	for _, f := range e.fields() {
+		if !slices.Contains(locked, f) {
+			locked = append(locked, f)
+		}
+	}
+	locked = slices.DeleteFunc(locked, func(f MetadataField) bool { return slices.Contains(unlock, f) })
+	slices.Sort(locked)
+	if !slices.Equal(locked, l.lockedFields) {
+		changes = append(changes, MetadataChange{Field: FieldLocked, Old: l.lockedFields, New: locked})
+		l.lockedFields = locked
+	}
+	return changes, nil
+}
+
+// ApplyMetadata applies metadata read from the file or a metadata provider to the fields that are
+// not locked, and returns the changes.
+func (l *LibraryItem) ApplyMetadata(e MetadataEdit) ([]MetadataChange, error) {
+	const op = errorx.Op("domain.LibraryItem.ApplyMetadata")
+
+	if err := e.Validate(); err != nil {
+		return nil, op.Wrap(err)
+	}
+	return l.applyMetadata(e, true), nil
+}
+
+func (l *LibraryItem) applyMetadata(e MetadataEdit, skipLocked bool) []MetadataChange {
+	var changes []MetadataChange
+	change := func(f MetadataField, equal bool, old, new any, set func()) {
+		if equal || (skipLocked && l.IsLocked(f)) {
+			return
+		}
+		set()
+		changes = append(changes, MetadataChange{Field: f, Old: old, New: new})
+	}
+
+	if e.Title != nil {
+		change(FieldTitle, l.title == *e.Title, l.title, *e.Title, func() { l.title = *e.Title })
+	}
+	if e.ItemType != nil {
+		change(FieldItemType, l.itemType == *e.ItemType, l.itemType, *e.ItemType, func() { l.itemType = *e.ItemType })
+	}
+	if e.AuthorIDs != nil {
+		change(FieldAuthors, slices.Equal(l.authorIDs, e.AuthorIDs), l.authorIDs, e.AuthorIDs, func() { l.authorIDs = e.AuthorIDs })
+	}
+	if e.Genre != nil {
+		change(FieldGenre, slices.Equal(l.genre, e.Genre), l.genre, e.Genre, func() { l.genre = e.Genre })
+	}
+	if e.Languages != nil {
+		change(FieldLanguages, slices.Equal(l.languages, e.Languages), l.languages, e.Languages, func() { l.languages = e.Languages })
+	}
+	if e.Annotation != nil {
+		change(FieldAnnotation, l.annotation == *e.Annotation, l.annotation, *e.Annotation, func() { l.annotation = *e.Annotation })
+	}
+	if e.Series != nil {
+		change(FieldSeries, l.Series() == *e.Series, l.Series(), *e.Series, func() { l.seriesID, l.seriesIndex = e.Series.ID, e.Series.Index })
+	}
+	if e.AgeRating != nil {
+		change(FieldAgeRating, l.ageRating == *e.AgeRating, l.ageRating, *e.AgeRating, func() { l.ageRating = *e.AgeRating })
+	}
+	return changes
+}
+
+// Series returns the series of the item, the zero value if it is in none.
+func (l *LibraryItem) Series() ItemSeries {
+	return ItemSeries{ID: l.seriesID, Index: l.seriesIndex}
+}
+
+// LockedFields returns the fields rescans and metadata providers leave alone, in the order of
+// their names.
+func (l *LibraryItem) LockedFields() []MetadataField {
+	return l.lockedFields
+}
+
+func (l *LibraryItem) IsLocked(f MetadataField) bool {
+	return slices.Contains(l.lockedFields, f)
+}
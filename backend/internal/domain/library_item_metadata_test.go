@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func newMetadataItem() *LibraryItem {
+	item := NewLibraryItemBuilder().
+		Id(NewLibraryItemID()).
+		Title("Воин").
+		ItemType(Book).
+		AuthorIDs([]AuthorID{NewAuthorID()}).
+		Genre([]string{"sf_fantasy"}).
+		Annotation("Покинув подземный мир").
+		Build()
+	return &item
+}
+
+func TestLibraryItem_EditMetadata(t *testing.T) {
+	t.Parallel()
+
+	item := newMetadataItem()
+	oldAuthorIDs, authorID, seriesID := item.AuthorIDs(), NewAuthorID(), NewSeriesID()
+
+	changes, err := item.EditMetadata(MetadataEdit{
+		Title:     ptr("Воин"),
+		ItemType:  ptr(Manga),
+		AuthorIDs: []AuthorID{authorID},
+		Genre:     []string{},
+		Series:    &ItemSeries{ID: seriesID, Index: 2.5},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []MetadataChange{
+		{Field: FieldItemType, Old: Book, New: Manga},
+		{Field: FieldAuthors, Old: oldAuthorIDs, New: []AuthorID{authorID}},
+		{Field: FieldGenre, Old: []string{"sf_fantasy"}, New: []string{}},
+		{Field: FieldSeries, Old: ItemSeries{}, New: ItemSeries{ID: seriesID, Index: 2.5}},
+		{Field: FieldLocked, Old: []MetadataField(nil), New: []MetadataField{FieldAuthors, FieldGenre, FieldItemType, FieldSeries, FieldTitle}},
+	}, changes, "the unchanged title is locked all the same")
+	assert.Equal(t, Manga, item.ItemType())
+	assert.Equal(t, []AuthorID{authorID}, item.AuthorIDs())
+	assert.Empty(t, item.Genre())
+	assert.Equal(t, ItemSeries{ID: seriesID, Index: 2.5}, item.Series())
+	assert.Equal(t, []MetadataField{FieldAuthors, FieldGenre, FieldItemType, FieldSeries, FieldTitle}, item.LockedFields())
+
+	changes, err = item.EditMetadata(MetadataEdit{Annotation: ptr("Дзирт")}, []MetadataField{FieldGenre, FieldTitle, FieldAnnotation})
+	require.NoError(t, err)
+	assert.Equal(t, []MetadataChange{
+		{Field: FieldAnnotation, Old: "Покинув подземный мир", New: "Дзирт"},
+		{
+			Field: FieldLocked,
+			Old:   []MetadataField{FieldAuthors, FieldGenre, FieldItemType, FieldSeries, FieldTitle},
+			New:   []MetadataField{FieldAuthors, FieldItemType, FieldSeries},
+		},
+	}, changes)
+
+	changes, err = item.EditMetadata(MetadataEdit{Annotation: ptr("Дзирт")}, []MetadataField{FieldAnnotation})
+	require.NoError(t, err)
+	assert.Empty(t, changes, "nothing changed")
+}
+
+func TestLibraryItem_EditMetadata_errors(t *testing.T) {
+	t.Parallel()
+
+	item := newMetadataItem()
+	_, err := item.EditMetadata(MetadataEdit{
+		Title:      ptr(""),
+		ItemType:   ptr(LibraryItemType("novel")),
+		AuthorIDs:  []AuthorID{},
+		Annotation: ptr(strings.Repeat("я", MaxAnnotationLen+1)),
+		Series:     &ItemSeries{Index: 1},
+	}, []MetadataField{"path"})
+	vx.AssertValidationErrors(t, err, v.Errors{
+		"title":      v.ErrRequired,
+		"itemType":   v.ErrInInvalid,
+		"authorIDs":  v.ErrRequired,
+		"annotation": v.ErrLengthOutOfRange,
+		"series":     v.Errors{"index": v.ErrEmpty},
+		"unlock":     v.Errors{"0": v.ErrInInvalid},
+	})
+	assert.Equal(t, "Воин", item.Title())
+	assert.Empty(t, item.LockedFields())
+}
+
+func TestLibraryItem_ApplyMetadata(t *testing.T) {
+	t.Parallel()
+
+	item := newMetadataItem()
+	seriesID := NewSeriesID()
+	_, err := item.EditMetadata(MetadataEdit{Title: ptr("Воин (edited)")}, nil)
+	require.NoError(t, err)
+
+	changes, err := item.ApplyMetadata(MetadataEdit{
+		Title:     ptr("Воин"),
+		Languages: []string{"ru"},
+		Series:    &ItemSeries{ID: seriesID, Index: 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []MetadataChange{
+		{Field: FieldLanguages, Old: []string(nil), New: []string{"ru"}},
+		{Field: FieldSeries, Old: ItemSeries{}, New: ItemSeries{ID: seriesID, Index: 3}},
+	}, changes)
+	assert.Equal(t, "Воин (edited)", item.Title(), "locked fields are kept")
+	assert.Equal(t, []MetadataField{FieldTitle}, item.LockedFields(), "applied fields are not locked")
+
+	_, err = item.ApplyMetadata(MetadataEdit{Title: ptr("x")})
+	vx.AssertValidationErrors(t, err, v.Errors{"title": v.ErrLengthOutOfRange})
+}
+
+func TestLibraryItem_EditMetadata_ageRating(t *testing.T) {
+	t.Parallel()
+
+	item := newMetadataItem()
+	require.NoError(t, item.SetAgeRating(AgeRatingEveryone))
+
+	changes, err := item.EditMetadata(MetadataEdit{AgeRating: ptr(AgeRatingMature17)}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []MetadataChange{
+		{Field: FieldAgeRating, Old: AgeRatingEveryone, New: AgeRatingMature17},
+		{Field: FieldLocked, Old: []MetadataField(nil), New: []MetadataField{FieldAgeRating}},
+	}, changes)
+
+	require.NoError(t, item.SetAgeRating(AgeRatingEveryone))
+	assert.Equal(t, AgeRatingMature17, item.AgeRating(), "the rating of the file does not override the edit")
+
+	_, err = item.EditMetadata(MetadataEdit{AgeRating: ptr(AgeRating(""))}, []MetadataField{FieldAgeRating})
+	require.NoError(t, err)
+	assert.Empty(t, item.AgeRating(), "an empty rating clears it")
+	require.NoError(t, item.SetAgeRating(AgeRatingEveryone))
+	assert.Equal(t, AgeRatingEveryone, item.AgeRating(), "unlocked ratings follow the file again")
+
+	_, err = item.EditMetadata(MetadataEdit{AgeRating: ptr(AgeRating("NC-17"))}, nil)
+	vx.AssertValidationErrors(t, err, v.Errors{"ageRating": v.ErrInInvalid})
+}
+
+func TestNewMetadataAuditEntry(t *testing.T) {
+	t.Parallel()
+
+	itemID, userID := NewLibraryItemID(), NewUserID()
+	changes := []MetadataChange{{Field: FieldTitle, Old: "Воин", New: "Воин (edited)"}}
+	entry, err := NewMetadataAuditEntry(itemID, userID, changes)
+	require.NoError(t, err)
+	assert.Equal(t, itemID, entry.ItemID())
+	assert.Equal(t, userID, entry.UserID())
+	assert.Equal(t, changes, entry.Changes())
+	assert.WithinDuration(t, TimeFromID(entry.ID()), entry.At(), 0)
+	assert.False(t, entry.At().IsZero())
+
+	_, err = NewMetadataAuditEntry(LibraryItemID{}, userID, nil)
+	vx.AssertValidationErrors(t, err, v.Errors{"itemID": v.ErrRequired, "changes": v.ErrRequired})
+}
+
+func TestLibraryItem_SetContributors(t *testing.T) {
+	t.Parallel()
+
+	item := newMetadataItem()
+	item.SetContributors([]Contributor{
+		{Name: " Александр Фишман ", Role: "trl"},
+		{Name: "Александр Фишман", Role: "trl"},
+		{Name: "Александр Фишман", Role: "edt"},
+		{Name: " ", Role: "ill"},
+	})
+
+	assert.Equal(t, []Contributor{{Name: "Александр Фишман", Role: "trl"}, {Name: "Александр Фишман", Role: "edt"}}, item.Contributors())
+}
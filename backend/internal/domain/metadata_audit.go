@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type MetadataAuditEntryID = uuid.UUID
+
+func NewMetadataAuditEntryID() MetadataAuditEntryID {
+	return uuid.Must(uuid.NewV7())
+}
+
+// MetadataAuditEntry records who changed the metadata of a library item and how, entries are
+// never changed once written.
+//
+//go:generate go tool gobuildergen --type MetadataAuditEntry
+type MetadataAuditEntry struct {
+	id      MetadataAuditEntryID
+	itemID  LibraryItemID
+	userID  UserID
+	changes []MetadataChange
+}
+
+func NewMetadataAuditEntry(itemID LibraryItemID, userID UserID, changes []MetadataChange) (*MetadataAuditEntry, error) {
+	const op = errorx.Op("domain.NewMetadataAuditEntry")
+
+	err := v.Errors{
+		"itemID":  v.Validate(itemID, vx.Required),
+		"userID":  v.Validate(userID, vx.Required),
+		"changes": v.Validate(changes, v.Required),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return &MetadataAuditEntry{
+		id:      NewMetadataAuditEntryID(),
+		itemID:  itemID,
+		userID:  userID,
+		changes: changes,
+	}, nil
+}
+
+func (e *MetadataAuditEntry) ID() MetadataAuditEntryID {
+	return e.id
+}
+
+func (e *MetadataAuditEntry) ItemID() LibraryItemID {
+	return e.itemID
+}
+
+func (e *MetadataAuditEntry) UserID() UserID {
+	return e.userID
+}
+
+func (e *MetadataAuditEntry) Changes() []MetadataChange {
+	return e.changes
+}
+
+// At returns when the change was made, taken from the UUIDv7 id.
+func (e *MetadataAuditEntry) At() time.Time {
+	return TimeFromID(e.id)
+}
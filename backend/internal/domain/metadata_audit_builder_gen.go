@@ -0,0 +1,39 @@
+// Code generated by github.com/ARUMANDESU/gobuildergen. DO NOT EDIT.
+package domain
+
+
+type MetadataAuditEntryBuilder struct {
+    val MetadataAuditEntry
+}
+
+func NewMetadataAuditEntryBuilder() *MetadataAuditEntryBuilder {
+    return &MetadataAuditEntryBuilder{}
+}
+
+func (b *MetadataAuditEntryBuilder) WithDefault() *MetadataAuditEntryBuilder {
+    return b
+}
+
+func (b *MetadataAuditEntryBuilder) Id(v MetadataAuditEntryID) *MetadataAuditEntryBuilder {
+    b.val.id = v
+    return b
+}
+
+func (b *MetadataAuditEntryBuilder) ItemID(v LibraryItemID) *MetadataAuditEntryBuilder {
+    b.val.itemID = v
+    return b
+}
+
+func (b *MetadataAuditEntryBuilder) UserID(v UserID) *MetadataAuditEntryBuilder {
+    b.val.userID = v
+    return b
+}
+
+func (b *MetadataAuditEntryBuilder) Changes(v []MetadataChange) *MetadataAuditEntryBuilder {
+    b.val.changes = v
+    return b
+}
+
+func (b *MetadataAuditEntryBuilder) Build() MetadataAuditEntry {
+    return b.val
+}
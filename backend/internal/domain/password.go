@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	v "github.com/ARUMANDESU/validation"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+type PasswordAlgorithm string
+
+const (
+	Bcrypt   PasswordAlgorithm = "bcrypt"
+	Argon2id PasswordAlgorithm = "argon2id"
+)
+
+const (
+	PasswordCostFactor = 12
+
+	// Argon2id defaults follow the second recommended option of RFC 9106.
+	DefaultArgon2Time    = 3
+	DefaultArgon2Memory  = 64 * 1024
+	DefaultArgon2Threads = 4
+	argon2SaltLen        = 16
+	argon2KeyLen         = 32
+)
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+// PasswordParams configure how new password hashes are made. Existing hashes made with other
+// parameters keep working and are upgraded on the next login, see User.RehashPassword.
+type PasswordParams struct {
+	Algorithm  PasswordAlgorithm
+	BcryptCost int
+	// Argon2Memory is in KiB.
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+// DefaultPasswordParams returns bcrypt with PasswordCostFactor, or the minimal cost in tests.
+func DefaultPasswordParams(mode envx.Mode) PasswordParams {
+	cost := PasswordCostFactor
+	if mode == envx.Test {
+		cost = bcrypt.MinCost
+	}
+	return PasswordParams{Algorithm: Bcrypt, BcryptCost: cost}
+}
+
+// Validate rejects parameters the hash functions can not work with, an Argon2id time or thread
+// count of zero would panic and a bcrypt cost of zero would never match the cost of a hash. An
+// empty Algorithm is bcrypt.
+func (p PasswordParams) Validate() error {
+	argon := p.Algorithm == Argon2id
+	return v.Errors{
+		"algorithm":     v.Validate(p.Algorithm, v.In(Bcrypt, Argon2id)),
+		"bcryptCost":    v.Validate(p.BcryptCost, v.When(!argon, v.Required, v.Min(bcrypt.MinCost), v.Max(bcrypt.MaxCost))),
+		"argon2Time":    v.Validate(p.Argon2Time, v.When(argon, v.Required)),
+		"argon2Memory":  v.Validate(p.Argon2Memory, v.When(argon, v.Required, v.Min(8*uint32(p.Argon2Threads)))),
+		"argon2Threads": v.Validate(p.Argon2Threads, v.When(argon, v.Required)),
+	}.Filter()
+}
+
+func DefaultArgon2idParams() PasswordParams {
+	return PasswordParams{
+		Algorithm:     Argon2id,
+		Argon2Time:    DefaultArgon2Time,
+		Argon2Memory:  DefaultArgon2Memory,
+		Argon2Threads: DefaultArgon2Threads,
+	}
+}
+
+// HashPassword hashes password with p. Argon2id hashes are stored in the PHC string format
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>.
+func HashPassword(password string, p PasswordParams) ([]byte, error) {
+	const op = errorx.Op("domain.HashPassword")
+
+	if err := p.Validate(); err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	switch p.Algorithm {
+	case Argon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, op.WrapMsg(err, "failed to read salt")
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, argon2KeyLen)
+		return fmt.Appendf(nil, "$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.Argon2Memory, p.Argon2Time, p.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		if err != nil {
+			return nil, op.WrapMsg(err, "failed to generate password hash")
+		}
+		return hash, nil
+	}
+}
+
+// NewPasswordHash hashes password with DefaultPasswordParams.
+func NewPasswordHash(password string, mode envx.Mode) ([]byte, error) {
+	return HashPassword(password, DefaultPasswordParams(mode))
+}
+
+// ComparePasswordHash returns ErrPasswordMismatch if password does not match hash, an empty hash
+// matches no password. It returns ErrUnknownPasswordHash if hash can not be decoded.
+func ComparePasswordHash(hash []byte, password string) error {
+	const op = errorx.Op("domain.ComparePasswordHash")
+
+	if len(hash) == 0 {
+		return op.Wrap(ErrPasswordMismatch)
+	}
+	if !bytes.HasPrefix(hash, []byte("$argon2id$")) {
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return op.Wrap(ErrPasswordMismatch)
+		} else if err != nil {
+			return op.Wrap(ErrUnknownPasswordHash)
+		}
+		return nil
+	}
+
+	p, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return op.Wrap(err)
+	}
+	got := argon2.IDKey([]byte(password), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return op.Wrap(ErrPasswordMismatch)
+	}
+	return nil
+}
+
+// PasswordHashParams returns the parameters hash was made with.
+func PasswordHashParams(hash []byte) (PasswordParams, error) {
+	const op = errorx.Op("domain.PasswordHashParams")
+
+	if bytes.HasPrefix(hash, []byte("$argon2id$")) {
+		p, _, _, err := parseArgon2id(hash)
+		return p, op.Wrap(err)
+	}
+	cost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return PasswordParams{}, op.Wrap(ErrUnknownPasswordHash)
+	}
+	return PasswordParams{Algorithm: Bcrypt, BcryptCost: cost}, nil
+}
+
+// NeedsRehash reports whether hash was made with parameters other than p. Invalid p never asks
+// for a rehash, as no hash can be made with them.
+func NeedsRehash(hash []byte, p PasswordParams) bool {
+	if p.Validate() != nil {
+		return false
+	}
+	got, err := PasswordHashParams(hash)
+	if err != nil {
+		return true
+	}
+	if p.Algorithm == "" {
+		p.Algorithm = Bcrypt
+	}
+	if p.Algorithm == Bcrypt {
+		p = PasswordParams{Algorithm: Bcrypt, BcryptCost: p.BcryptCost}
+	} else {
+		p.BcryptCost = 0
+	}
+	return got != p
+}
+
+func parseArgon2id(hash []byte) (p PasswordParams, salt, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return PasswordParams{}, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return PasswordParams{}, nil, nil, ErrUnknownPasswordHash
+	}
+
+	p.Algorithm = Argon2id
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Argon2Memory, &p.Argon2Time, &p.Argon2Threads); err != nil {
+		return PasswordParams{}, nil, nil, ErrUnknownPasswordHash
+	}
+	// argon2.IDKey panics on a time or thread count of zero
+	if p.Validate() != nil {
+		return PasswordParams{}, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return PasswordParams{}, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return PasswordParams{}, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,55 @@
+// Code generated by github.com/ARUMANDESU/gobuildergen. DO NOT EDIT.
+package domain
+
+import "time"
+
+type PasswordResetBuilder struct {
+    val PasswordReset
+}
+
+func NewPasswordResetBuilder() *PasswordResetBuilder {
+    return &PasswordResetBuilder{}
+}
+
+func (b *PasswordResetBuilder) WithDefault() *PasswordResetBuilder {
+    return b
+}
+
+func (b *PasswordResetBuilder) Id(v PasswordResetID) *PasswordResetBuilder {
+    b.val.id = v
+    return b
+}
+
+func (b *PasswordResetBuilder) UserID(v UserID) *PasswordResetBuilder {
+    b.val.userID = v
+    return b
+}
+
+func (b *PasswordResetBuilder) CreatedBy(v UserID) *PasswordResetBuilder {
+    b.val.createdBy = v
+    return b
+}
+
+func (b *PasswordResetBuilder) TokenHash(v []byte) *PasswordResetBuilder {
+    b.val.tokenHash = v
+    return b
+}
+
+func (b *PasswordResetBuilder) CreatedAt(v time.Time) *PasswordResetBuilder {
+    b.val.createdAt = v
+    return b
+}
+
+func (b *PasswordResetBuilder) ExpiresAt(v time.Time) *PasswordResetBuilder {
+    b.val.expiresAt = v
+    return b
+}
+
+func (b *PasswordResetBuilder) UsedAt(v *time.Time) *PasswordResetBuilder {
+    b.val.usedAt = v
+    return b
+}
+
+func (b *PasswordResetBuilder) Build() PasswordReset {
+    return b.val
+}
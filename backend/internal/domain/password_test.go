@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+// testArgon2idParams are cheap enough for tests.
+var testArgon2idParams = PasswordParams{Algorithm: Argon2id, Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1}
+
+func TestHashPassword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		params PasswordParams
+	}{
+		{name: "bcrypt", params: DefaultPasswordParams(envx.Test)},
+		{name: "argon2id", params: testArgon2idParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := HashPassword(ValidPassword, tt.params)
+			require.NoError(t, err)
+			require.NoError(t, ComparePasswordHash(hash, ValidPassword))
+			require.ErrorIs(t, ComparePasswordHash(hash, ValidPassword+"x"), ErrPasswordMismatch)
+
+			params, err := PasswordHashParams(hash)
+			require.NoError(t, err)
+			assert.Equal(t, tt.params, params)
+			assert.False(t, NeedsRehash(hash, tt.params))
+		})
+	}
+}
+
+func TestPasswordParams_Validate(t *testing.T) {
+	t.Parallel()
+
+	_, err := HashPassword(ValidPassword, PasswordParams{Algorithm: Argon2id, Argon2Memory: 4})
+	vx.AssertValidationErrors(t, err, v.Errors{"argon2Time": v.ErrRequired, "argon2Threads": v.ErrRequired})
+	_, err = HashPassword(ValidPassword, PasswordParams{Algorithm: Bcrypt})
+	vx.AssertValidationErrors(t, err, v.Errors{"bcryptCost": v.ErrRequired})
+	_, err = HashPassword(ValidPassword, PasswordParams{Algorithm: "scrypt", BcryptCost: bcrypt.MinCost})
+	vx.AssertValidationErrors(t, err, v.Errors{"algorithm": v.ErrInInvalid})
+
+	require.NoError(t, DefaultArgon2idParams().Validate())
+	require.NoError(t, DefaultPasswordParams(envx.Prod).Validate())
+}
+
+func TestComparePasswordHash_invalid(t *testing.T) {
+	t.Parallel()
+
+	require.ErrorIs(t, ComparePasswordHash(nil, ""), ErrPasswordMismatch, "single sign-on users have no hash")
+	require.ErrorIs(t, ComparePasswordHash([]byte("garbage"), ValidPassword), ErrUnknownPasswordHash)
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5",
+	} {
+		require.ErrorIs(t, ComparePasswordHash([]byte(hash), ValidPassword), ErrUnknownPasswordHash, hash)
+		_, err := PasswordHashParams([]byte(hash))
+		require.ErrorIs(t, err, ErrUnknownPasswordHash, hash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	t.Parallel()
+
+	bcryptHash, err := HashPassword(ValidPassword, PasswordParams{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+	argonHash, err := HashPassword(ValidPassword, testArgon2idParams)
+	require.NoError(t, err)
+
+	stronger := testArgon2idParams
+	stronger.Argon2Time++
+
+	assert.True(t, NeedsRehash(bcryptHash, PasswordParams{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost + 1}), "cost changed")
+	assert.True(t, NeedsRehash(bcryptHash, testArgon2idParams), "algorithm changed")
+	assert.True(t, NeedsRehash(argonHash, stronger), "argon2 time changed")
+	assert.True(t, NeedsRehash([]byte("garbage"), testArgon2idParams))
+	assert.False(t, NeedsRehash(bcryptHash, PasswordParams{BcryptCost: bcrypt.MinCost}), "empty algorithm is bcrypt")
+	assert.False(t, NeedsRehash(bcryptHash, PasswordParams{Algorithm: Bcrypt}), "no hash can be made without a cost")
+}
+
+func TestUser_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	params := DefaultPasswordParams(envx.Test)
+	u, err := NewUser(NewUserID(), ValidUsername, ValidPassword, RoleMember, params)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, u.ChangePassword("wrong", "newPassword123!", params), ErrPasswordMismatch)
+	vx.AssertValidationErrors(t, u.ChangePassword(ValidPassword, "short", params), v.Errors{"password": vx.ErrInvalidPasswordFormat})
+
+	require.NoError(t, u.ChangePassword(ValidPassword, "newPassword123!", params))
+	require.NoError(t, u.ComparePassword("newPassword123!"))
+	require.ErrorIs(t, u.ComparePassword(ValidPassword), ErrPasswordMismatch)
+}
+
+func TestUser_RehashPassword(t *testing.T) {
+	t.Parallel()
+
+	u, err := NewUser(NewUserID(), ValidUsername, ValidPassword, RoleMember, DefaultPasswordParams(envx.Test))
+	require.NoError(t, err)
+
+	changed, err := u.RehashPassword(ValidPassword, DefaultPasswordParams(envx.Test))
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = u.RehashPassword(ValidPassword, testArgon2idParams)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.False(t, NeedsRehash(u.PassHash(), testArgon2idParams))
+	require.NoError(t, u.ComparePassword(ValidPassword))
+}
+
+func TestNewPasswordReset(t *testing.T) {
+	t.Parallel()
+
+	userID, adminID := NewUserID(), NewUserID()
+	reset, token, err := NewPasswordReset(userID, adminID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, HashToken(token), reset.TokenHash())
+	assert.Equal(t, adminID, reset.CreatedBy())
+	assert.True(t, reset.IsUsable())
+
+	reset.MarkUsed()
+	assert.False(t, reset.IsUsable())
+
+	past := time.Now().Add(-time.Second)
+	expired := NewPasswordResetBuilder().ExpiresAt(past).Build()
+	assert.False(t, expired.IsUsable())
+
+	_, _, err = NewPasswordReset(UserID{}, adminID, 0)
+	vx.AssertValidationErrors(t, err, v.Errors{"userID": v.ErrRequired, "ttl": v.ErrRequired})
+}
@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx/cfi"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+const (
+	MaxDeviceNameLen = 100
+	MaxCFILen        = 1024
+	MaxXPointerLen   = 1024
+	// MaxProgressClockSkew is how far in the future a client's timestamp may be, later ones are
+	// clamped so a device with a wrong clock cannot win every conflict.
+	MaxProgressClockSkew = time.Minute
+)
+
+// LocatorKind tells how a Locator points into the item.
+type LocatorKind string
+
+const (
+	// LocatorCFI is an EPUB canonical fragment identifier.
+	LocatorCFI LocatorKind = "cfi"
+	// LocatorPDFPage is a 1-based PDF page number.
+	LocatorPDFPage LocatorKind = "pdf_page"
+	// LocatorComicPage is a 0-based index of a page in a comic archive.
+	LocatorComicPage LocatorKind = "comic_page"
+	// LocatorXPointer is a KOReader xpointer into a reflowable document, e.g. /body/DocFragment[12]/body/p[3]/text().0
+	LocatorXPointer LocatorKind = "xpointer"
+	// LocatorPercentage only has the percentage, it is accepted for every format.
+	LocatorPercentage LocatorKind = "percentage"
+)
+
+var LocatorKinds = []any{LocatorCFI, LocatorPDFPage, LocatorComicPage, LocatorXPointer, LocatorPercentage}
+
+// Supports reports whether locators of kind k can point into files of format f.
+func (k LocatorKind) Supports(f FileFormat) bool {
+	switch k {
+	case LocatorCFI:
+		return f == FormatEPUB
+	case LocatorPDFPage:
+		return f == FormatPDF
+	case LocatorComicPage:
+		return f.IsComicArchive()
+	case LocatorXPointer:
+		return f == FormatEPUB || f == FormatFB2
+	case LocatorPercentage:
+		return true
+	}
+	return false
+}
+
+// Locator is a position in a library item. Percentage is set for every kind,
+// so clients that cannot resolve the format specific part still can show and sync the progress.
+type Locator struct {
+	Kind LocatorKind
+	// CFI is set for LocatorCFI, e.g. epubcfi(/6/4!/4/2/1:0).
+	CFI string
+	// XPointer is set for LocatorXPointer.
+	XPointer string
+	// Page is set for LocatorPDFPage and LocatorComicPage.
+	Page int
+	// Percentage is the read fraction of the item, from 0 to 1.
+	Percentage float64
+}
+
+func (l Locator) Validate() error {
+	return v.Errors{
+		"kind": v.Validate(l.Kind, vx.Required, v.In(LocatorKinds...)),
+		"cfi": v.Validate(l.CFI,
+			v.When(l.Kind == LocatorCFI, vx.Required, v.Length(0, MaxCFILen), v.By(validateCFI)).Else(v.Empty),
+		),
+		"xpointer": v.Validate(l.XPointer,
+			v.When(l.Kind == LocatorXPointer, vx.Required, v.Length(0, MaxXPointerLen)).Else(v.Empty),
+		),
+		"page": v.Validate(l.Page,
+			v.When(l.Kind == LocatorPDFPage, v.Required, v.Min(1)),
+			v.When(l.Kind == LocatorComicPage, v.Min(0)),
+			v.When(l.Kind == LocatorCFI || l.Kind == LocatorXPointer || l.Kind == LocatorPercentage, v.Empty),
+		),
+		"percentage": v.Validate(l.Percentage, v.Min(0.0), v.Max(1.0)),
+	}.Filter()
+}
+
+func validateCFI(value any) error {
+	s, _ := value.(string)
+	if !strings.HasPrefix(s, "epubcfi(") {
+		return vx.ErrInvalidCFI
+	}
+	if _, err := cfi.Parse(s); err != nil {
+		return vx.ErrInvalidCFI
+	}
+	return nil
+}
+
+// ReadingProgress is how far a user got in a library item, there is at most one per user and item.
+// Every device saves its position here, the last writer wins, and a write from a device that did not
+// see the latest position is reported as a conflict, see Update.
+//
+//go:generate go tool gobuildergen --type ReadingProgress
+type ReadingProgress struct {
+	userID    UserID
+	itemID    LibraryItemID
+	locator   Locator
+	device    string
+	updatedAt time.Time
+	// version grows with every applied update, clients send the version they last saw.
+	version int
+}
+
+// ProgressConflict describes the progress another device saved since the writer last synced.
+type ProgressConflict struct {
+	Device    string
+	Locator   Locator
+	UpdatedAt time.Time
+	// Overwritten is true if the update was newer and replaced this progress,
+	// false if the update was older and discarded.
+	Overwritten bool
+}
+
+// NewReadingProgress records the first position of the user in the item, at is when the device was there.
+func NewReadingProgress(userID UserID, itemID LibraryItemID, locator Locator, device string, at time.Time) (*ReadingProgress, error) {
+	const op = errorx.Op("domain.NewReadingProgress")
+
+	err := v.Errors{
+		"userID":  v.Validate(userID, vx.Required),
+		"itemID":  v.Validate(itemID, vx.Required),
+		"locator": locator.Validate(),
+		"device":  v.Validate(device, v.Length(0, MaxDeviceNameLen)),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return &ReadingProgress{
+		userID:    userID,
+		itemID:    itemID,
+		locator:   locator,
+		device:    strings.TrimSpace(device),
+		updatedAt: clampProgressTime(at),
+		version:   1,
+	}, nil
+}
+
+// Update moves the progress to locator. baseVersion is the version the device last saw.
+//
+// If the device saw the current version, or wrote it itself, the update is applied. Otherwise the
+// devices diverged: the update is applied only if it is newer than the current progress, and the
+// returned conflict describes the progress the device did not see.
+func (p *ReadingProgress) Update(locator Locator, device string, at time.Time, baseVersion int) (*ProgressConflict, error) {
+	const op = errorx.Op("domain.ReadingProgress.Update")
+
+	err := v.Errors{
+		"locator":     locator.Validate(),
+		"device":      v.Validate(device, v.Length(0, MaxDeviceNameLen)),
+		"baseVersion": v.Validate(baseVersion, v.Min(0), v.Max(p.version)),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	device = strings.TrimSpace(device)
+	at = clampProgressTime(at)
+
+	var conflict *ProgressConflict
+	if baseVersion != p.version && (device == "" || device != p.device) {
+		conflict = &ProgressConflict{
+			Device:      p.device,
+			Locator:     p.locator,
+			UpdatedAt:   p.updatedAt,
+			Overwritten: at.After(p.updatedAt),
+		}
+		if !conflict.Overwritten {
+			return conflict, nil
+		}
+	}
+
+	p.locator = locator
+	p.device = device
+	p.updatedAt = at
+	p.version++
+
+	return conflict, nil
+}
+
+func clampProgressTime(at time.Time) time.Time {
+	now := time.Now()
+	if at.IsZero() || at.After(now.Add(MaxProgressClockSkew)) {
+		return now
+	}
+	return at
+}
+
+func (p *ReadingProgress) UserID() UserID {
+	return p.userID
+}
+
+func (p *ReadingProgress) ItemID() LibraryItemID {
+	return p.itemID
+}
+
+func (p *ReadingProgress) Locator() Locator {
+	return p.locator
+}
+
+func (p *ReadingProgress) Device() string {
+	return p.device
+}
+
+func (p *ReadingProgress) UpdatedAt() time.Time {
+	return p.updatedAt
+}
+
+func (p *ReadingProgress) Version() int {
+	return p.version
+}
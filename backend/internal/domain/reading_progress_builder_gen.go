@@ -0,0 +1,50 @@
+// Code generated by github.com/ARUMANDESU/gobuildergen. DO NOT EDIT.
+package domain
+
+import "time"
+
+type ReadingProgressBuilder struct {
+    val ReadingProgress
+}
+
+func NewReadingProgressBuilder() *ReadingProgressBuilder {
+    return &ReadingProgressBuilder{}
+}
+
+func (b *ReadingProgressBuilder) WithDefault() *ReadingProgressBuilder {
+    return b
+}
+
+func (b *ReadingProgressBuilder) UserID(v UserID) *ReadingProgressBuilder {
+    b.val.userID = v
+    return b
+}
+
+func (b *ReadingProgressBuilder) ItemID(v LibraryItemID) *ReadingProgressBuilder {
+    b.val.itemID = v
+    return b
+}
+
+func (b *ReadingProgressBuilder) Locator(v Locator) *ReadingProgressBuilder {
+    b.val.locator = v
+    return b
+}
+
+func (b *ReadingProgressBuilder) Device(v string) *ReadingProgressBuilder {
+    b.val.device = v
+    return b
+}
+
+func (b *ReadingProgressBuilder) UpdatedAt(v time.Time) *ReadingProgressBuilder {
+    b.val.updatedAt = v
+    return b
+}
+
+func (b *ReadingProgressBuilder) Version(v int) *ReadingProgressBuilder {
+    b.val.version = v
+    return b
+}
+
+func (b *ReadingProgressBuilder) Build() ReadingProgress {
+    return b.val
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func TestLocator_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		locator     Locator
+		expectedErr error
+	}{
+		{name: "cfi", locator: Locator{Kind: LocatorCFI, CFI: "epubcfi(/6/4!/4/2/1:0)", Percentage: 0.1}},
+		{name: "pdf page", locator: Locator{Kind: LocatorPDFPage, Page: 12, Percentage: 0.5}},
+		{name: "first comic page", locator: Locator{Kind: LocatorComicPage, Page: 0}},
+		{name: "xpointer", locator: Locator{Kind: LocatorXPointer, XPointer: "/body/DocFragment[12]/body/p[3]/text().0", Percentage: 0.3}},
+		{name: "percentage", locator: Locator{Kind: LocatorPercentage, Percentage: 1}},
+		{
+			name:        "unknown kind",
+			locator:     Locator{Kind: "line"},
+			expectedErr: v.Errors{"kind": v.ErrInInvalid},
+		},
+		{
+			name:        "malformed cfi",
+			locator:     Locator{Kind: LocatorCFI, CFI: "/6/4!/4/2/1:0"},
+			expectedErr: v.Errors{"cfi": vx.ErrInvalidCFI},
+		},
+		{
+			name:        "cfi with a step after the offset",
+			locator:     Locator{Kind: LocatorCFI, CFI: "epubcfi(/6/4!/4/2/1:0/3)"},
+			expectedErr: v.Errors{"cfi": vx.ErrInvalidCFI},
+		},
+		{
+			name:        "pdf pages start at one",
+			locator:     Locator{Kind: LocatorPDFPage, Page: 0},
+			expectedErr: v.Errors{"page": v.ErrRequired},
+		},
+		{
+			name:        "negative comic page",
+			locator:     Locator{Kind: LocatorComicPage, Page: -1},
+			expectedErr: v.Errors{"page": v.ErrMinGreaterEqualThanRequired},
+		},
+		{
+			name:        "cfi on a page locator",
+			locator:     Locator{Kind: LocatorComicPage, CFI: "epubcfi(/6/4)"},
+			expectedErr: v.Errors{"cfi": v.ErrEmpty},
+		},
+		{
+			name:        "percentage out of range",
+			locator:     Locator{Kind: LocatorPercentage, Percentage: 1.5},
+			expectedErr: v.Errors{"percentage": v.ErrMaxLessEqualThanRequired},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.locator.Validate()
+			if tt.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			vx.AssertValidationErrors(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestLocatorKind_Supports(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, LocatorCFI.Supports(FormatEPUB))
+	assert.False(t, LocatorCFI.Supports(FormatPDF))
+	assert.True(t, LocatorPDFPage.Supports(FormatPDF))
+	assert.True(t, LocatorComicPage.Supports(FileFormatOf("Plastic Man #002 (1944).CBZ")))
+	assert.False(t, LocatorComicPage.Supports(FormatEPUB))
+	assert.True(t, LocatorXPointer.Supports(FormatFB2))
+	assert.False(t, LocatorXPointer.Supports(FileFormatOf("Homeland.azw3")))
+	assert.False(t, LocatorXPointer.Supports(FormatCBZ))
+	assert.True(t, LocatorPercentage.Supports(FormatUnknown))
+}
+
+func TestReadingProgress_Update(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now().Add(-time.Hour)
+	first := Locator{Kind: LocatorPDFPage, Page: 10, Percentage: 0.1}
+	next := Locator{Kind: LocatorPDFPage, Page: 20, Percentage: 0.2}
+
+	newProgress := func(t *testing.T) *ReadingProgress {
+		t.Helper()
+		p, err := NewReadingProgress(NewUserID(), NewLibraryItemID(), first, "kobo", start)
+		require.NoError(t, err)
+		require.Equal(t, 1, p.Version())
+		return p
+	}
+
+	tests := []struct {
+		name             string
+		device           string
+		at               time.Time
+		baseVersion      int
+		expectedLocator  Locator
+		expectedConflict *ProgressConflict
+	}{
+		{
+			name:            "fast forward from another device",
+			device:          "phone",
+			at:              start.Add(time.Minute),
+			baseVersion:     1,
+			expectedLocator: next,
+		},
+		{
+			name:            "same device never conflicts",
+			device:          "kobo",
+			at:              start.Add(time.Minute),
+			baseVersion:     0,
+			expectedLocator: next,
+		},
+		{
+			name:            "diverged and newer wins",
+			device:          "phone",
+			at:              start.Add(time.Minute),
+			baseVersion:     0,
+			expectedLocator: next,
+			expectedConflict: &ProgressConflict{
+				Device: "kobo", Locator: first, UpdatedAt: start, Overwritten: true,
+			},
+		},
+		{
+			name:            "diverged and older loses",
+			device:          "phone",
+			at:              start.Add(-time.Minute),
+			baseVersion:     0,
+			expectedLocator: first,
+			expectedConflict: &ProgressConflict{
+				Device: "kobo", Locator: first, UpdatedAt: start, Overwritten: false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newProgress(t)
+
+			conflict, err := p.Update(next, tt.device, tt.at, tt.baseVersion)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedConflict, conflict)
+			assert.Equal(t, tt.expectedLocator, p.Locator())
+			if tt.expectedLocator == next {
+				assert.Equal(t, 2, p.Version())
+				assert.Equal(t, tt.device, p.Device())
+			} else {
+				assert.Equal(t, 1, p.Version())
+			}
+		})
+	}
+}
+
+func TestReadingProgress_Update_clampsFutureTime(t *testing.T) {
+	t.Parallel()
+
+	p, err := NewReadingProgress(NewUserID(), NewLibraryItemID(), Locator{Kind: LocatorPercentage}, "", time.Now().Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), p.UpdatedAt(), time.Second)
+
+	_, err = p.Update(Locator{Kind: LocatorPercentage}, "", time.Now(), 2)
+	vx.AssertValidationErrors(t, err, v.Errors{"baseVersion": v.ErrMaxLessEqualThanRequired})
+}
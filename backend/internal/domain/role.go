@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Role is the access level of a user.
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	// RoleGuest can only browse and read the library.
+	RoleGuest Role = "guest"
+)
+
+var Roles = []any{RoleAdmin, RoleMember, RoleGuest}
+
+type Permission string
+
+const (
+	PermManageUsers   Permission = "manage_users"
+	PermTriggerScan   Permission = "trigger_scan"
+	PermEditMetadata  Permission = "edit_metadata"
+	PermDeleteItems   Permission = "delete_items"
+	PermDownload      Permission = "download"
+	PermValidateFiles Permission = "validate_files"
+)
+
+var ErrPermissionDenied = fmt.Errorf("permission denied: %w", ErrForbidden)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:  {PermManageUsers, PermTriggerScan, PermEditMetadata, PermDeleteItems, PermDownload, PermValidateFiles},
+	RoleMember: {PermEditMetadata, PermDownload},
+	RoleGuest:  {},
+}
+
+// Permissions returns the permissions granted to the role, unknown roles have none.
+func (r Role) Permissions() []Permission {
+	return slices.Clone(rolePermissions[r])
+}
+
+func (r Role) Can(p Permission) bool {
+	return slices.Contains(rolePermissions[r], p)
+}
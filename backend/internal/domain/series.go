@@ -0,0 +1,135 @@
+package domain
+
+import (
+	v "github.com/ARUMANDESU/validation"
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+const (
+	MaxSeriesNameLen        = 150
+	MaxSeriesDescriptionLen = 2000
+	// FinishedPercentage is how far a reader must get for an item to count as read, readers
+	// rarely report exactly the end of the last page.
+	FinishedPercentage = 0.99
+)
+
+type SeriesID = uuid.UUID
+
+func NewSeriesID() SeriesID {
+	return uuid.Must(uuid.NewV7())
+}
+
+// SeriesStatus tells whether more items of a series are to come, the zero value is unknown.
+type SeriesStatus string
+
+const (
+	SeriesOngoing   SeriesStatus = "ongoing"
+	SeriesCompleted SeriesStatus = "completed"
+	SeriesHiatus    SeriesStatus = "hiatus"
+	SeriesCancelled SeriesStatus = "cancelled"
+)
+
+var SeriesStatuses = []any{SeriesOngoing, SeriesCompleted, SeriesHiatus, SeriesCancelled}
+
+// ReadingDirection is how the pages of a series are turned, the zero value is unknown and
+// readers choose by the item type.
+type ReadingDirection string
+
+const (
+	LeftToRight ReadingDirection = "ltr"
+	RightToLeft ReadingDirection = "rtl"
+	// Vertical is a continuous top to bottom strip, such as webtoons.
+	Vertical ReadingDirection = "vertical"
+)
+
+var ReadingDirections = []any{LeftToRight, RightToLeft, Vertical}
+
+// Series groups library items that are read in order, such as the volumes of a manga or the
+// novels of a cycle. Items link to it with an index, see ItemSeries. Series are identified by
+// their name when they are found in files.
+//
+//go:generate go tool gobuildergen --type Series
+type Series struct {
+	id               SeriesID
+	name             string
+	description      string
+	status           SeriesStatus
+	readingDirection ReadingDirection
+}
+
+func NewSeries(id SeriesID, name string) (*Series, error) {
+	const op = errorx.Op("domain.NewSeries")
+
+	err := v.Errors{
+		"id":   v.Validate(id, vx.Required),
+		"name": v.Validate(name, vx.Required, v.Length(0, MaxSeriesNameLen)),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return &Series{
+		id:   id,
+		name: name,
+	}, nil
+}
+
+// SeriesEdit changes a series, nil fields are left as they are. Empty Status and
+// ReadingDirection make them unknown.
+type SeriesEdit struct {
+	Name             *string
+	Description      *string
+	Status           *SeriesStatus
+	ReadingDirection *ReadingDirection
+}
+
+func (s *Series) Edit(e SeriesEdit) error {
+	const op = errorx.Op("domain.Series.Edit")
+
+	err := v.Errors{
+		"name":             v.Validate(e.Name, v.When(e.Name != nil, vx.Required, v.Length(0, MaxSeriesNameLen))),
+		"description":      v.Validate(e.Description, v.Length(0, MaxSeriesDescriptionLen)),
+		"status":           v.Validate(e.Status, v.In(SeriesStatuses...)),
+		"readingDirection": v.Validate(e.ReadingDirection, v.In(ReadingDirections...)),
+	}.Filter()
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	if e.Name != nil {
+		s.name = *e.Name
+	}
+	if e.Description != nil {
+		s.description = *e.Description
+	}
+	if e.Status != nil {
+		s.status = *e.Status
+	}
+	if e.ReadingDirection != nil {
+		s.readingDirection = *e.ReadingDirection
+	}
+	return nil
+}
+
+func (s *Series) ID() SeriesID {
+	return s.id
+}
+
+func (s *Series) Name() string {
+	return s.name
+}
+
+func (s *Series) Description() string {
+	return s.description
+}
+
+func (s *Series) Status() SeriesStatus {
+	return s.status
+}
+
+func (s *Series) ReadingDirection() ReadingDirection {
+	return s.readingDirection
+}
@@ -0,0 +1,44 @@
+// Code generated by github.com/ARUMANDESU/gobuildergen. DO NOT EDIT.
+package domain
+
+
+type SeriesBuilder struct {
+    val Series
+}
+
+func NewSeriesBuilder() *SeriesBuilder {
+    return &SeriesBuilder{}
+}
+
+func (b *SeriesBuilder) WithDefault() *SeriesBuilder {
+    return b
+}
+
+func (b *SeriesBuilder) Id(v SeriesID) *SeriesBuilder {
+    b.val.id = v
+    return b
+}
+
+func (b *SeriesBuilder) Name(v string) *SeriesBuilder {
+    b.val.name = v
+    return b
+}
+
+func (b *SeriesBuilder) Description(v string) *SeriesBuilder {
+    b.val.description = v
+    return b
+}
+
+func (b *SeriesBuilder) Status(v SeriesStatus) *SeriesBuilder {
+    b.val.status = v
+    return b
+}
+
+func (b *SeriesBuilder) ReadingDirection(v ReadingDirection) *SeriesBuilder {
+    b.val.readingDirection = v
+    return b
+}
+
+func (b *SeriesBuilder) Build() Series {
+    return b.val
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func TestNewSeries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		id      SeriesID
+		series  string
+		wantErr v.Errors
+	}{
+		{name: "valid", id: NewSeriesID(), series: "Атака титанов"},
+		{name: "one letter", id: NewSeriesID(), series: "X"},
+		{name: "missing id", series: "Berserk", wantErr: v.Errors{"id": v.ErrRequired}},
+		{name: "empty name", id: NewSeriesID(), series: "", wantErr: v.Errors{"name": v.ErrRequired}},
+		{name: "long name", id: NewSeriesID(), series: strings.Repeat("x", MaxSeriesNameLen+1), wantErr: v.Errors{"name": v.ErrLengthTooLong}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := NewSeries(tt.id, tt.series)
+			if tt.wantErr != nil {
+				vx.AssertValidationErrors(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.series, s.Name())
+			assert.Empty(t, s.Status())
+			assert.Empty(t, s.ReadingDirection())
+		})
+	}
+}
+
+func TestSeries_Edit(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewSeries(NewSeriesID(), "Shingeki no Kyojin")
+	require.NoError(t, err)
+
+	err = s.Edit(SeriesEdit{
+		Name:             ptr("Атака титанов"),
+		Description:      ptr("Человечество живёт за стенами"),
+		Status:           ptr(SeriesCompleted),
+		ReadingDirection: ptr(RightToLeft),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Атака титанов", s.Name())
+	assert.Equal(t, "Человечество живёт за стенами", s.Description())
+	assert.Equal(t, SeriesCompleted, s.Status())
+	assert.Equal(t, RightToLeft, s.ReadingDirection())
+
+	require.NoError(t, s.Edit(SeriesEdit{Status: ptr(SeriesStatus(""))}))
+	assert.Empty(t, s.Status(), "the status can be made unknown again")
+	assert.Equal(t, RightToLeft, s.ReadingDirection(), "fields that are not set are kept")
+
+	err = s.Edit(SeriesEdit{
+		Name:             ptr(""),
+		Description:      ptr(strings.Repeat("x", MaxSeriesDescriptionLen+1)),
+		Status:           ptr(SeriesStatus("abandoned")),
+		ReadingDirection: ptr(ReadingDirection("btt")),
+	})
+	vx.AssertValidationErrors(t, err, v.Errors{
+		"name":             v.ErrRequired,
+		"description":      v.ErrLengthTooLong,
+		"status":           v.ErrInInvalid,
+		"readingDirection": v.ErrInInvalid,
+	})
+	assert.Equal(t, "Атака титанов", s.Name())
+}
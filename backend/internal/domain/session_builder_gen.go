@@ -0,0 +1,55 @@
+// Code generated by github.com/ARUMANDESU/gobuildergen. DO NOT EDIT.
+package domain
+
+import "time"
+
+type SessionBuilder struct {
+    val Session
+}
+
+func NewSessionBuilder() *SessionBuilder {
+    return &SessionBuilder{}
+}
+
+func (b *SessionBuilder) WithDefault() *SessionBuilder {
+    return b
+}
+
+func (b *SessionBuilder) Id(v SessionID) *SessionBuilder {
+    b.val.id = v
+    return b
+}
+
+func (b *SessionBuilder) UserID(v UserID) *SessionBuilder {
+    b.val.userID = v
+    return b
+}
+
+func (b *SessionBuilder) TokenHash(v []byte) *SessionBuilder {
+    b.val.tokenHash = v
+    return b
+}
+
+func (b *SessionBuilder) UserAgent(v string) *SessionBuilder {
+    b.val.userAgent = v
+    return b
+}
+
+func (b *SessionBuilder) CreatedAt(v time.Time) *SessionBuilder {
+    b.val.createdAt = v
+    return b
+}
+
+func (b *SessionBuilder) ExpiresAt(v time.Time) *SessionBuilder {
+    b.val.expiresAt = v
+    return b
+}
+
+func (b *SessionBuilder) RevokedAt(v *time.Time) *SessionBuilder {
+    b.val.revokedAt = v
+    return b
+}
+
+func (b *SessionBuilder) Build() Session {
+    return b.val
+}
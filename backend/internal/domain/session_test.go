@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+func TestNewSession(t *testing.T) {
+	t.Parallel()
+
+	userID := NewUserID()
+	s, token, err := NewSession(userID, "KOReader/2024.07", time.Hour)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, token)
+	assert.Equal(t, HashToken(token), s.TokenHash())
+	assert.Equal(t, userID, s.UserID())
+	assert.Equal(t, "KOReader/2024.07", s.UserAgent())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), s.ExpiresAt(), time.Second)
+	assert.True(t, s.IsActive())
+
+	_, other, err := NewSession(userID, "", time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other, "tokens must be random")
+}
+
+func TestNewSession_errors(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := NewSession(UserID{}, "", 0)
+	vx.AssertValidationErrors(t, err, v.Errors{
+		"userID": v.ErrRequired,
+		"ttl":    v.ErrRequired,
+	})
+
+	_, _, err = NewSession(NewUserID(), "", -time.Hour)
+	vx.AssertValidationErrors(t, err, v.Errors{"ttl": v.ErrMinGreaterEqualThanRequired})
+}
+
+func TestSession_IsActive(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		session  Session
+		expected bool
+	}{
+		{name: "active", session: NewSessionBuilder().ExpiresAt(future).Build(), expected: true},
+		{name: "expired", session: NewSessionBuilder().ExpiresAt(past).Build(), expected: false},
+		{name: "revoked", session: NewSessionBuilder().ExpiresAt(future).RevokedAt(&past).Build(), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tt.session.IsActive())
+		})
+	}
+}
+
+func TestSession_Revoke(t *testing.T) {
+	t.Parallel()
+
+	s, _, err := NewSession(NewUserID(), "", time.Hour)
+	require.NoError(t, err)
+
+	s.Revoke()
+	require.NotNil(t, s.RevokedAt())
+	revokedAt := *s.RevokedAt()
+	assert.False(t, s.IsActive())
+
+	s.Revoke()
+	assert.Equal(t, revokedAt, *s.RevokedAt(), "second revoke keeps the first time")
+}
@@ -1,21 +1,26 @@
 package domain
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+
 	v "github.com/ARUMANDESU/validation"
+	"github.com/ARUMANDESU/validation/is"
 	"github.com/gofrs/uuid"
-	"golang.org/x/crypto/bcrypt"
 
-	"github.com/ARUMANDESU/goread/backend/pkg/envx"
 	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
 	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
 )
 
 const (
-	PasswordCostFactor = 12
 	MinUserNameLen     = 3
 	MaxUserNameLen     = 75
 	MinUserPasswordLen = 8
 	MaxUserPasswordLen = 128
+	// MinKOReaderPasswordLen is checked instead of the full password rules,
+	// the password is typed on e-ink keyboards.
+	MinKOReaderPasswordLen = 8
 )
 
 const (
@@ -32,6 +37,11 @@ type User struct {
 	id       UserID `builder:"default=NewUserID()"`
 	name     string `builder:"default=ValidUsername"`
 	passHash []byte
+	role     Role `builder:"default=RoleMember"`
+
+	restrictions ContentRestrictions
+	// koreaderKeyHash is the hash of the key KOReader sends, see SetKOReaderPassword.
+	koreaderKeyHash []byte
 }
 
 func NewUserID() UserID {
@@ -42,7 +52,8 @@ func NewUser(
 	id UserID,
 	name string,
 	password string,
-	mode envx.Mode,
+	role Role,
+	params PasswordParams,
 ) (*User, error) {
 	const op = errorx.Op("domain.NewUser")
 
@@ -50,12 +61,13 @@ func NewUser(
 		"id":       v.Validate(id, vx.Required),
 		"name":     v.Validate(name, vx.Required, v.Length(MinUserNameLen, MaxUserNameLen)),
 		"password": v.Validate(password, vx.Required, vx.Password(MinUserPasswordLen, MaxUserPasswordLen)),
+		"role":     v.Validate(role, vx.Required, v.In(Roles...)),
 	}.Filter()
 	if err != nil {
 		return nil, op.Wrap(err)
 	}
 
-	passHash, err := NewPasswordHash(password, mode)
+	passHash, err := HashPassword(password, params)
 	if err != nil {
 		return nil, op.Wrap(err)
 	}
@@ -64,23 +76,196 @@ func NewUser(
 		id:       id,
 		name:     name,
 		passHash: passHash,
+		role:     role,
 	}, nil
 }
 
+// NewExternalUser creates a user that signs in through single sign-on or KOReader, it has no password.
+func NewExternalUser(id UserID, name string, role Role) (*User, error) {
+	const op = errorx.Op("domain.NewExternalUser")
+
+	err := v.Errors{
+		"id":   v.Validate(id, vx.Required),
+		"name": v.Validate(name, vx.Required, v.Length(MinUserNameLen, MaxUserNameLen)),
+		"role": v.Validate(role, vx.Required, v.In(Roles...)),
+	}.Filter()
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	return &User{
+		id:   id,
+		name: name,
+		role: role,
+	}, nil
+}
+
+func (u *User) ID() UserID {
+	return u.id
+}
+
+func (u *User) Name() string {
+	return u.name
+}
+
+func (u *User) PassHash() []byte {
+	return u.passHash
+}
+
+func (u *User) Role() Role {
+	return u.role
+}
+
+func (u *User) Can(p Permission) bool {
+	return u.role.Can(p)
+}
+
+// Authorize returns ErrPermissionDenied if the user's role does not grant p.
+func (u *User) Authorize(p Permission) error {
+	if !u.Can(p) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, p)
+	}
+	return nil
+}
+
+func (u *User) ChangeRole(role Role) error {
+	const op = errorx.Op("domain.User.ChangeRole")
+	err := v.Errors{
+		"role": v.Validate(role, vx.Required, v.In(Roles...)),
+	}.Filter()
+	if err != nil {
+		return op.Wrap(err)
+	}
+	u.role = role
+	return nil
+}
+
+func (u *User) Restrictions() ContentRestrictions {
+	return u.restrictions
+}
+
+func (u *User) SetRestrictions(r ContentRestrictions) error {
+	const op = errorx.Op("domain.User.SetRestrictions")
+	if err := r.Validate(); err != nil {
+		return op.Wrap(err)
+	}
+	u.restrictions = r
+	return nil
+}
+
+// HasPassword is false for users created by single sign-on until they set one.
+func (u *User) HasPassword() bool {
+	return len(u.passHash) > 0
+}
+
+// ComparePassword returns ErrPasswordMismatch if password is not the user's password.
 func (u *User) ComparePassword(password string) error {
 	const op = errorx.Op("domain.User.ComparePassword")
-	return op.Wrap(bcrypt.CompareHashAndPassword(u.passHash, []byte(password)))
+	if !u.HasPassword() {
+		return op.Wrap(ErrPasswordMismatch)
+	}
+	return op.Wrap(ComparePasswordHash(u.passHash, password))
+}
+
+// SetPassword replaces the password, e.g. after a reset.
+func (u *User) SetPassword(password string, params PasswordParams) error {
+	const op = errorx.Op("domain.User.SetPassword")
+
+	err := v.Errors{
+		"password": v.Validate(password, vx.Required, vx.Password(MinUserPasswordLen, MaxUserPasswordLen)),
+	}.Filter()
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	hash, err := HashPassword(password, params)
+	if err != nil {
+		return op.Wrap(err)
+	}
+	u.passHash = hash
+	return nil
 }
 
-func NewPasswordHash(password string, mode envx.Mode) ([]byte, error) {
-	const op = errorx.Op("domain.NewPasswordHash")
-	costFactor := PasswordCostFactor
-	if mode == envx.Test {
-		costFactor = bcrypt.MinCost
+// ChangePassword replaces the password after checking the current one.
+func (u *User) ChangePassword(current, password string, params PasswordParams) error {
+	const op = errorx.Op("domain.User.ChangePassword")
+
+	if err := u.ComparePassword(current); err != nil {
+		return op.Wrap(err)
 	}
-	passhash, err := bcrypt.GenerateFromPassword([]byte(password), costFactor)
+	return op.Wrap(u.SetPassword(password, params))
+}
+
+// RehashPassword rehashes the already verified password when the stored hash was made with
+// other parameters than params, and reports whether it did.
+func (u *User) RehashPassword(password string, params PasswordParams) (bool, error) {
+	const op = errorx.Op("domain.User.RehashPassword")
+
+	if !NeedsRehash(u.passHash, params) {
+		return false, nil
+	}
+	hash, err := HashPassword(password, params)
 	if err != nil {
-		return nil, op.WrapMsg(err, "failed to generate password hash")
+		return false, op.Wrap(err)
+	}
+	u.passHash = hash
+	return true, nil
+}
+
+// KOReaderKey returns the key KOReader derives from the password typed into its sync settings,
+// the hex encoded MD5 of it. KOReader only ever sends the key.
+func KOReaderKey(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetKOReaderPassword enables KOReader sync with a password of its own. It is kept apart from the
+// account password because KOReader stores its unsalted MD5 on the device.
+func (u *User) SetKOReaderPassword(password string, params PasswordParams) error {
+	const op = errorx.Op("domain.User.SetKOReaderPassword")
+
+	err := v.Errors{
+		"password": v.Validate(password, vx.Required, v.Length(MinKOReaderPasswordLen, MaxUserPasswordLen)),
+	}.Filter()
+	if err != nil {
+		return op.Wrap(err)
+	}
+	return op.Wrap(u.SetKOReaderKey(KOReaderKey(password), params))
+}
+
+// SetKOReaderKey is SetKOReaderPassword for clients that only send the key, like KOReader registration.
+func (u *User) SetKOReaderKey(key string, params PasswordParams) error {
+	const op = errorx.Op("domain.User.SetKOReaderKey")
+
+	err := v.Errors{
+		"key": v.Validate(key, vx.Required, is.LowerCase, is.Hexadecimal, v.Length(md5.Size*2, md5.Size*2)),
+	}.Filter()
+	if err != nil {
+		return op.Wrap(err)
+	}
+
+	hash, err := HashPassword(key, params)
+	if err != nil {
+		return op.Wrap(err)
+	}
+	u.koreaderKeyHash = hash
+	return nil
+}
+
+// DisableKOReader removes the KOReader password.
+func (u *User) DisableKOReader() {
+	u.koreaderKeyHash = nil
+}
+
+func (u *User) HasKOReaderKey() bool {
+	return len(u.koreaderKeyHash) > 0
+}
+
+// CompareKOReaderKey returns ErrPasswordMismatch if key is not the user's KOReader key.
+func (u *User) CompareKOReaderKey(key string) error {
+	const op = errorx.Op("domain.User.CompareKOReaderKey")
+	if !u.HasKOReaderKey() {
+		return op.Wrap(ErrPasswordMismatch)
 	}
-	return passhash, nil
+	return op.Wrap(ComparePasswordHash(u.koreaderKeyHash, key))
 }
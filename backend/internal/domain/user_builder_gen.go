@@ -13,6 +13,7 @@ func NewUserBuilder() *UserBuilder {
 func (b *UserBuilder) WithDefault() *UserBuilder {
     b.val.id = NewUserID()
     b.val.name = ValidUsername
+    b.val.role = RoleMember
     return b
 }
 
@@ -31,6 +32,21 @@ func (b *UserBuilder) PassHash(v []byte) *UserBuilder {
     return b
 }
 
+func (b *UserBuilder) Role(v Role) *UserBuilder {
+    b.val.role = v
+    return b
+}
+
+func (b *UserBuilder) Restrictions(v ContentRestrictions) *UserBuilder {
+    b.val.restrictions = v
+    return b
+}
+
+func (b *UserBuilder) KoreaderKeyHash(v []byte) *UserBuilder {
+    b.val.koreaderKeyHash = v
+    return b
+}
+
 func (b *UserBuilder) Build() User {
     return b.val
 }
@@ -1,10 +1,12 @@
 package domain
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
 	v "github.com/ARUMANDESU/validation"
+	"github.com/ARUMANDESU/validation/is"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -19,10 +21,11 @@ func TestNewUser(t *testing.T) {
 		id       UserID
 		username string
 		password string
+		role     Role
 	}
 
 	validArgs := func() args {
-		return args{NewUserID(), ValidUsername, ValidPassword}
+		return args{NewUserID(), ValidUsername, ValidPassword, RoleMember}
 	}
 
 	tests := []struct {
@@ -122,15 +125,34 @@ func TestNewUser(t *testing.T) {
 			},
 			expectedErr: v.Errors{"password": vx.ErrInvalidPasswordFormat},
 		},
+		{
+			name: "invalid role: blank",
+			args: func() args {
+				a := validArgs()
+				a.role = ""
+				return a
+			},
+			expectedErr: v.Errors{"role": v.ErrRequired},
+		},
+		{
+			name: "invalid role: unknown",
+			args: func() args {
+				a := validArgs()
+				a.role = "owner"
+				return a
+			},
+			expectedErr: v.Errors{"role": v.ErrInInvalid},
+		},
 		{
 			name: "multiple validation errors",
 			args: func() args {
-				return args{id: UserID{}, username: "", password: ""}
+				return args{id: UserID{}, username: "", password: "", role: ""}
 			},
 			expectedErr: v.Errors{
 				"id":       v.ErrRequired,
 				"password": v.ErrRequired,
 				"name":     v.ErrRequired,
+				"role":     v.ErrRequired,
 			},
 		},
 	}
@@ -140,7 +162,7 @@ func TestNewUser(t *testing.T) {
 			t.Parallel()
 
 			a := tt.args()
-			u, err := NewUser(a.id, a.username, a.password, envx.Test)
+			u, err := NewUser(a.id, a.username, a.password, a.role, DefaultPasswordParams(envx.Test))
 			if tt.expectedErr != nil {
 				vx.AssertValidationErrors(t, err, tt.expectedErr)
 				return
@@ -150,11 +172,96 @@ func TestNewUser(t *testing.T) {
 			require.NotNil(t, u)
 			assert.Equal(t, a.id, u.id)
 			assert.Equal(t, a.username, u.name)
+			assert.Equal(t, a.role, u.role)
 			assert.NoError(t, u.ComparePassword(a.password), "password should verify against hash")
 		})
 	}
 }
 
+func TestNewExternalUser(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		u, err := NewExternalUser(NewUserID(), ValidUsername, RoleGuest)
+		require.NoError(t, err)
+		assert.Equal(t, RoleGuest, u.Role())
+		assert.False(t, u.HasPassword())
+		require.ErrorIs(t, u.ComparePassword(""), ErrPasswordMismatch)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewExternalUser(NewUserID(), "", "owner")
+		vx.AssertValidationErrors(t, err, v.Errors{"name": v.ErrRequired, "role": v.ErrInInvalid})
+	})
+}
+
+func TestUser_KOReader(t *testing.T) {
+	t.Parallel()
+
+	params := DefaultPasswordParams(envx.Test)
+	assert.Equal(t, "5f4dcc3b5aa765d61d8327deb882cf99", KOReaderKey("password"))
+
+	u := NewUserBuilder().WithDefault().Build()
+	assert.False(t, u.HasKOReaderKey())
+	require.ErrorIs(t, u.CompareKOReaderKey(KOReaderKey("")), ErrPasswordMismatch)
+
+	err := u.SetKOReaderPassword("short", params)
+	vx.AssertValidationErrors(t, err, v.Errors{"password": v.ErrLengthOutOfRange})
+	err = u.SetKOReaderKey("5F4DCC3B5AA765D61D8327DEB882CF99", params)
+	vx.AssertValidationErrors(t, err, v.Errors{"key": is.ErrLowerCase})
+
+	require.NoError(t, u.SetKOReaderPassword("password", params))
+	require.NoError(t, u.CompareKOReaderKey("5f4dcc3b5aa765d61d8327deb882cf99"))
+
+	u.DisableKOReader()
+	assert.False(t, u.HasKOReaderKey())
+}
+
+func TestUser_Authorize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		role    Role
+		allowed []Permission
+	}{
+		{role: RoleAdmin, allowed: []Permission{PermManageUsers, PermTriggerScan, PermEditMetadata, PermDeleteItems, PermDownload, PermValidateFiles}},
+		{role: RoleMember, allowed: []Permission{PermEditMetadata, PermDownload}},
+		{role: RoleGuest},
+		{role: "unknown"},
+	}
+
+	all := []Permission{PermManageUsers, PermTriggerScan, PermEditMetadata, PermDeleteItems, PermDownload, PermValidateFiles}
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			t.Parallel()
+
+			u := NewUserBuilder().WithDefault().Role(tt.role).Build()
+			for _, p := range all {
+				err := u.Authorize(p)
+				if slices.Contains(tt.allowed, p) {
+					assert.NoError(t, err, p)
+				} else {
+					assert.ErrorIs(t, err, ErrPermissionDenied, p)
+					assert.ErrorIs(t, err, ErrForbidden, p)
+				}
+			}
+		})
+	}
+}
+
+func TestUser_ChangeRole(t *testing.T) {
+	t.Parallel()
+
+	u := NewUserBuilder().WithDefault().Build()
+	require.NoError(t, u.ChangeRole(RoleAdmin))
+	assert.Equal(t, RoleAdmin, u.Role())
+
+	vx.AssertValidationErrors(t, u.ChangeRole("root"), v.Errors{"role": v.ErrInInvalid})
+	assert.Equal(t, RoleAdmin, u.Role(), "role is unchanged on error")
+}
+
 func usernameWithLength(l int) string {
 	return strings.Repeat("a", l)
 }
@@ -12,22 +12,30 @@ type FileInfo struct {
 	Hash    []byte
 	Path    string
 	NewPath string
+	// NewHash is the hash of a file that changed in place.
+	NewHash []byte
 }
 
 type CompareSnapshotsResult struct {
 	Added   map[HashStr]FileInfo
 	Removed map[HashStr]FileInfo
 	Moved   map[HashStr]FileInfo
+	// Changed are the files whose content changed at the same path, keyed by their old hash.
+	Changed map[HashStr]FileInfo
 }
 
 func CompareSnapshots(old, curr LibrarySnapshot) CompareSnapshotsResult {
 	added := make(map[HashStr]FileInfo)
 	moved := make(map[HashStr]FileInfo)
 	removed := make(map[HashStr]FileInfo)
+	changed := make(map[HashStr]FileInfo)
 
 	for path, oldHash := range old {
-		if _, ok := curr[path]; !ok {
+		currHash, ok := curr[path]
+		if !ok {
 			removed[string(oldHash)] = FileInfo{Hash: oldHash, Path: path}
+		} else if string(currHash) != string(oldHash) {
+			changed[string(oldHash)] = FileInfo{Hash: oldHash, Path: path, NewHash: currHash}
 		}
 	}
 
@@ -47,5 +55,6 @@ func CompareSnapshots(old, curr LibrarySnapshot) CompareSnapshotsResult {
 		Added:   added,
 		Removed: removed,
 		Moved:   moved,
+		Changed: changed,
 	}
 }
@@ -19,13 +19,13 @@ func TestCompareSnapshots(t *testing.T) {
 			name:     "both empty",
 			old:      LibrarySnapshot{},
 			curr:     LibrarySnapshot{},
-			expected: CompareSnapshotsResult{Added: map[HashStr]FileInfo{}, Removed: map[HashStr]FileInfo{}, Moved: map[HashStr]FileInfo{}},
+			expected: CompareSnapshotsResult{Added: map[HashStr]FileInfo{}, Removed: map[HashStr]FileInfo{}, Moved: map[HashStr]FileInfo{}, Changed: map[HashStr]FileInfo{}},
 		},
 		{
 			name:     "no changes",
 			old:      LibrarySnapshot{"a.epub": []byte("h1"), "b.epub": []byte("h2")},
 			curr:     LibrarySnapshot{"a.epub": []byte("h1"), "b.epub": []byte("h2")},
-			expected: CompareSnapshotsResult{Added: map[HashStr]FileInfo{}, Removed: map[HashStr]FileInfo{}, Moved: map[HashStr]FileInfo{}},
+			expected: CompareSnapshotsResult{Added: map[HashStr]FileInfo{}, Removed: map[HashStr]FileInfo{}, Moved: map[HashStr]FileInfo{}, Changed: map[HashStr]FileInfo{}},
 		},
 		{
 			name: "file added",
@@ -35,6 +35,7 @@ func TestCompareSnapshots(t *testing.T) {
 				Added:   map[HashStr]FileInfo{"h1": {Hash: []byte("h1"), Path: "a.epub"}},
 				Removed: map[HashStr]FileInfo{},
 				Moved:   map[HashStr]FileInfo{},
+				Changed: map[HashStr]FileInfo{},
 			},
 		},
 		{
@@ -48,6 +49,7 @@ func TestCompareSnapshots(t *testing.T) {
 				},
 				Removed: map[HashStr]FileInfo{},
 				Moved:   map[HashStr]FileInfo{},
+				Changed: map[HashStr]FileInfo{},
 			},
 		},
 		{
@@ -58,6 +60,7 @@ func TestCompareSnapshots(t *testing.T) {
 				Added:   map[HashStr]FileInfo{},
 				Removed: map[HashStr]FileInfo{"h1": {Hash: []byte("h1"), Path: "a.epub"}},
 				Moved:   map[HashStr]FileInfo{},
+				Changed: map[HashStr]FileInfo{},
 			},
 		},
 		{
@@ -70,7 +73,8 @@ func TestCompareSnapshots(t *testing.T) {
 					"h2": {Hash: []byte("h2"), Path: "b.epub"},
 					"h3": {Hash: []byte("h3"), Path: "c.epub"},
 				},
-				Moved: map[HashStr]FileInfo{},
+				Moved:   map[HashStr]FileInfo{},
+				Changed: map[HashStr]FileInfo{},
 			},
 		},
 		{
@@ -81,6 +85,7 @@ func TestCompareSnapshots(t *testing.T) {
 				Added:   map[HashStr]FileInfo{},
 				Removed: map[HashStr]FileInfo{},
 				Moved:   map[HashStr]FileInfo{"h1": {Hash: []byte("h1"), Path: "old/a.epub", NewPath: "new/a.epub"}},
+				Changed: map[HashStr]FileInfo{},
 			},
 		},
 		{
@@ -91,6 +96,7 @@ func TestCompareSnapshots(t *testing.T) {
 				Added:   map[HashStr]FileInfo{"h2": {Hash: []byte("h2"), Path: "b.epub"}},
 				Removed: map[HashStr]FileInfo{"h1": {Hash: []byte("h1"), Path: "a.epub"}},
 				Moved:   map[HashStr]FileInfo{},
+				Changed: map[HashStr]FileInfo{},
 			},
 		},
 		{
@@ -101,13 +107,19 @@ func TestCompareSnapshots(t *testing.T) {
 				Added:   map[HashStr]FileInfo{"h4": {Hash: []byte("h4"), Path: "e.epub"}},
 				Removed: map[HashStr]FileInfo{"h3": {Hash: []byte("h3"), Path: "c.epub"}},
 				Moved:   map[HashStr]FileInfo{"h2": {Hash: []byte("h2"), Path: "b.epub", NewPath: "d.epub"}},
+				Changed: map[HashStr]FileInfo{},
 			},
 		},
 		{
-			name:     "content changed at same path",
-			old:      LibrarySnapshot{"a.epub": []byte("h1")},
-			curr:     LibrarySnapshot{"a.epub": []byte("h2")},
-			expected: CompareSnapshotsResult{Added: map[HashStr]FileInfo{}, Removed: map[HashStr]FileInfo{}, Moved: map[HashStr]FileInfo{}},
+			name: "content changed at same path",
+			old:  LibrarySnapshot{"a.epub": []byte("h1")},
+			curr: LibrarySnapshot{"a.epub": []byte("h2")},
+			expected: CompareSnapshotsResult{
+				Added:   map[HashStr]FileInfo{},
+				Removed: map[HashStr]FileInfo{},
+				Moved:   map[HashStr]FileInfo{},
+				Changed: map[HashStr]FileInfo{"h1": {Hash: []byte("h1"), Path: "a.epub", NewHash: []byte("h2")}},
+			},
 		},
 	}
 
@@ -120,6 +132,7 @@ func TestCompareSnapshots(t *testing.T) {
 			assert.Equal(t, tt.expected.Added, result.Added, "Added mismatch")
 			assert.Equal(t, tt.expected.Removed, result.Removed, "Removed mismatch")
 			assert.Equal(t, tt.expected.Moved, result.Moved, "Moved mismatch")
+			assert.Equal(t, tt.expected.Changed, result.Changed, "Changed mismatch")
 		})
 	}
 }
@@ -1,20 +1,53 @@
 package vo
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/comicinfox"
 	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
 )
 
 type Metadata struct {
-	Title       string
-	Authors     []string
+	Title   string
+	Authors []string
+	// Contributors are the people other than authors, such as illustrators and translators.
+	Contributors []Contributor
+	Series       string
+	// SeriesIndex is the position in Series, zero if unknown.
+	SeriesIndex float64
+	// ReadingDirection is a domain.ReadingDirection of Series, empty if unknown.
+	ReadingDirection string
+	// Manga is set when the file says it is manga, see domain.ItemTypeOf.
+	Manga bool
+	// Modified is when the file's publication was last changed, zero if unknown.
+	Modified    time.Time
 	Publishers  []string
 	Date        string
 	Languages   []string
 	Subjects    []string
 	Description string
 	ISBN        string
+	Tags        []string
+	// AgeRating is a ComicInfo AgeRating value, see domain.AgeRating.
+	AgeRating string
+	// DRM is the scheme protecting the file, see epubx.DRM, empty if it is not protected.
+	DRM string
+}
+
+type Contributor struct {
+	Name string
+	// Role is a MARC relator code, e.g. ill for illustrators or trl for translators.
+	Role string
 }
 
+// Roles of creators and contributors, see https://www.loc.gov/marc/relators/relaterm.html.
+const (
+	roleAuthor       = "aut"
+	roleBookProducer = "bkp"
+)
+
 func MetadataFromEPUB(em epubx.Metadata) Metadata {
 	var m Metadata
 	if len(em.Titles) > 0 && em.Titles[0] != "" {
@@ -24,9 +57,29 @@ func MetadataFromEPUB(em epubx.Metadata) Metadata {
 		m.Date = em.Dates[0]
 	}
 
+	// Creators without a role are taken as authors, contributors are only authors if they say so.
+	// Book producers are the software that made the file.
 	for _, v := range em.Creators {
-		m.Authors = append(m.Authors, v.Name)
+		if v.Role == "" || v.Role == roleAuthor {
+			m.Authors = append(m.Authors, v.Name)
+		} else {
+			m.Contributors = append(m.Contributors, Contributor{Name: v.Name, Role: v.Role})
+		}
+	}
+	for _, v := range em.Contributors {
+		switch v.Role {
+		case roleAuthor:
+			m.Authors = append(m.Authors, v.Name)
+		case roleBookProducer:
+		default:
+			m.Contributors = append(m.Contributors, Contributor{Name: v.Name, Role: v.Role})
+		}
 	}
+	if series, ok := em.Series(); ok {
+		m.Series = series.Name
+		m.SeriesIndex = series.Position
+	}
+	m.Modified, _ = em.Modified()
 	for _, v := range em.Identifiers {
 		if v.Scheme == "ISBN" {
 			m.ISBN = v.ID
@@ -39,3 +92,43 @@ func MetadataFromEPUB(em epubx.Metadata) Metadata {
 
 	return m
 }
+
+func MetadataFromComicInfo(ci comicinfox.ComicInfo) Metadata {
+	m := Metadata{
+		Title:       ci.Title,
+		Authors:     ci.Writers(),
+		Series:      ci.Series,
+		Subjects:    ci.Genres(),
+		Description: ci.Summary,
+		Tags:        ci.TagList(),
+		AgeRating:   ci.AgeRating,
+		Manga:       ci.IsManga(),
+	}
+	if m.Title == "" && ci.Series != "" {
+		m.Title = ci.Series
+		if ci.Number != "" {
+			m.Title += " #" + ci.Number
+		}
+	}
+	if n, err := strconv.ParseFloat(ci.Number, 64); err == nil {
+		m.SeriesIndex = n
+	}
+	if ci.Manga == comicinfox.MangaRightToLeft {
+		m.ReadingDirection = "rtl"
+	}
+	if ci.Publisher != "" {
+		m.Publishers = []string{ci.Publisher}
+	}
+	if ci.LanguageISO != "" {
+		m.Languages = []string{ci.LanguageISO}
+	}
+	switch {
+	case ci.Year > 0 && ci.Month > 0 && ci.Day > 0:
+		m.Date = fmt.Sprintf("%04d-%02d-%02d", ci.Year, ci.Month, ci.Day)
+	case ci.Year > 0 && ci.Month > 0:
+		m.Date = fmt.Sprintf("%04d-%02d", ci.Year, ci.Month)
+	case ci.Year > 0:
+		m.Date = fmt.Sprintf("%04d", ci.Year)
+	}
+	return m
+}
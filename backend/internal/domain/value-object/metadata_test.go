@@ -0,0 +1,56 @@
+package vo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/comicinfox"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+func TestMetadataFromEPUB(t *testing.T) {
+	t.Parallel()
+
+	m := MetadataFromEPUB(epubx.Metadata{
+		Titles: []string{"Homeland"},
+		Creators: []epubx.Author{
+			{Name: "R. A. Salvatore", Role: "aut"},
+			{Name: "Todd Lockwood", Role: "ill"},
+			{Name: "Anonymous"},
+		},
+		Contributors: []epubx.Author{
+			{Name: "Н. Некрасова", Role: "trl"},
+			{Name: "calibre (2.55.0)", Role: "bkp"},
+			{Name: "Co-author", Role: "aut"},
+		},
+		Meta: []epubx.MetaProperty{
+			{Name: "calibre:series", Content: "The Dark Elf Trilogy"},
+			{Name: "calibre:series_index", Content: "1.5"},
+			{Property: "dcterms:modified", Value: "2024-05-06T07:08:09Z"},
+		},
+	})
+
+	assert.Equal(t, []string{"R. A. Salvatore", "Anonymous", "Co-author"}, m.Authors)
+	assert.Equal(t, []Contributor{{Name: "Todd Lockwood", Role: "ill"}, {Name: "Н. Некрасова", Role: "trl"}}, m.Contributors)
+	assert.Equal(t, "The Dark Elf Trilogy", m.Series)
+	assert.Equal(t, 1.5, m.SeriesIndex)
+	assert.Equal(t, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC), m.Modified)
+}
+
+func TestMetadataFromComicInfo_series(t *testing.T) {
+	t.Parallel()
+
+	m := MetadataFromComicInfo(comicinfox.ComicInfo{Series: "Plastic Man", Number: "2"})
+	assert.Equal(t, "Plastic Man #2", m.Title)
+	assert.Equal(t, "Plastic Man", m.Series)
+	assert.Equal(t, 2.0, m.SeriesIndex)
+	assert.Empty(t, m.ReadingDirection)
+	assert.False(t, m.Manga)
+
+	m = MetadataFromComicInfo(comicinfox.ComicInfo{Series: "Berserk", Number: "1.5", Manga: comicinfox.MangaRightToLeft})
+	assert.Equal(t, 1.5, m.SeriesIndex)
+	assert.Equal(t, "rtl", m.ReadingDirection)
+	assert.True(t, m.Manga)
+}
@@ -0,0 +1,82 @@
+package vo
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// bracketGroupPattern matches a trailing group such as (2012), [Digital] or (of 6).
+	bracketGroupPattern = regexp.MustCompile(`\s*[(\[][^)\]]*[)\]]\s*$`)
+	// scanGroupPattern matches a leading group such as [Scanlator].
+	scanGroupPattern = regexp.MustCompile(`^\s*\[[^\]]*\]\s*`)
+
+	// seriesPatterns are tried in order, the first group is the name and the second the index.
+	seriesPatterns = []*regexp.Regexp{
+		// Plastic Man #002
+		regexp.MustCompile(`^(.*?)\s*#\s*(\d+(?:\.\d+)?)\b`),
+		// One Piece v01 c003, One Piece - Chapter 3.5
+		regexp.MustCompile(`(?i)^(.*?)(?:[\s,._-]+v(?:ol)?\.?\s*\d+(?:\.\d+)?)?[\s,._-]*(?:^|[\s,._-])(?:c|ch\.?|chapter|глава)\s*(\d+(?:\.\d+)?)\b`),
+		// Berserk v01, Berserk Vol. 1.5, Атака титанов Том 3, but not "С.Кинг - Т. 2 Оно" where a title
+		// follows the volume
+		regexp.MustCompile(`(?i)^(.*?)[\s,._-]*(?:^|[\s,._-])(?:v|vol\.?|volume|tome|т\.|том)\s*(\d+(?:\.\d+)?)$`),
+		// Berserk - 12, but not "Orwell - 1984" where four digits are more likely a title
+		regexp.MustCompile(`^(.*?)\s+-\s+(\d{1,3}(?:\.\d+)?)$`),
+		// Saga 001, numbers that are not zero padded are too often part of the title
+		regexp.MustCompile(`^(.*?)\s+(0\d+(?:\.\d+)?)$`),
+	}
+)
+
+// SeriesFromPath guesses the series of a file and its position in it from the file name, for files
+// whose metadata does not tell, such as "Attack on Titan #001 (2012).cbz" or "Berserk v01.cbz". A
+// file name without the series name, such as "Vol. 01.cbz", takes the name of its directory. ok is
+// false if the name does not look like part of a series.
+func SeriesFromPath(p Path) (name string, index float64, ok bool) {
+	dir, file := path.Split(p)
+	base := cleanSeriesName(strings.TrimSuffix(file, path.Ext(file)))
+
+	for _, pattern := range seriesPatterns {
+		m := pattern.FindStringSubmatch(base)
+		if m == nil {
+			continue
+		}
+		index, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			return "", 0, false
+		}
+		name = strings.Trim(m[1], " -_,.")
+		// a file right under a library root has no directory of its own to be named after
+		if name == "" && strings.Count(strings.Trim(dir, "/"), "/") >= 1 {
+			name = cleanSeriesName(path.Base(dir))
+		}
+		if name == "" || isNumber(name) {
+			return "", 0, false
+		}
+		return name, index, true
+	}
+	return "", 0, false
+}
+
+// cleanSeriesName removes bracketed groups around the name and takes underscores as spaces if the name
+// has no spaces.
+func cleanSeriesName(s string) string {
+	s = scanGroupPattern.ReplaceAllString(s, "")
+	for {
+		trimmed := bracketGroupPattern.ReplaceAllString(s, "")
+		if trimmed == s || trimmed == "" {
+			break
+		}
+		s = trimmed
+	}
+	if !strings.Contains(s, " ") {
+		s = strings.ReplaceAll(s, "_", " ")
+	}
+	return strings.TrimSpace(s)
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,53 @@
+package vo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesFromPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path      Path
+		wantName  string
+		wantIndex float64
+		wantOK    bool
+	}{
+		{path: "Comics/Plastic Man (1944)/Plastic Man #002 (1944).cbz", wantName: "Plastic Man", wantIndex: 2, wantOK: true},
+		{path: "Manga/Attack on Titan (2012)/Attack on Titan #001 (2012).pdf", wantName: "Attack on Titan", wantIndex: 1, wantOK: true},
+		{path: "Comics/Saga #1.5.cbz", wantName: "Saga", wantIndex: 1.5, wantOK: true},
+		{path: "Manga/Berserk/Berserk v01.cbz", wantName: "Berserk", wantIndex: 1, wantOK: true},
+		{path: "Manga/Berserk/Berserk Vol. 1.5 [Digital].cbz", wantName: "Berserk", wantIndex: 1.5, wantOK: true},
+		{path: "Manga/Berserk/Berserk - Volume 3.cbz", wantName: "Berserk", wantIndex: 3, wantOK: true},
+		{path: "Manga/One Piece/[Group] One Piece v01 c003.cbz", wantName: "One Piece", wantIndex: 3, wantOK: true},
+		{path: "Manga/One Piece/One_Piece_Chapter_12.5.cbz", wantName: "One Piece", wantIndex: 12.5, wantOK: true},
+		{path: "Manga/Атака титанов/Атака титанов Том 3.cbz", wantName: "Атака титанов", wantIndex: 3, wantOK: true},
+		{path: "Manga/Атака титанов/Атака титанов т. 4.cbz", wantName: "Атака титанов", wantIndex: 4, wantOK: true},
+		{path: "Comics/Berserk - 12.cbz", wantName: "Berserk", wantIndex: 12, wantOK: true},
+		{path: "Comics/Saga/Saga 001.cbz", wantName: "Saga", wantIndex: 1, wantOK: true},
+		{path: "Manga/Berserk (1990)/Vol. 01.cbz", wantName: "Berserk", wantIndex: 1, wantOK: true},
+		{path: "Manga/Vol. 01.cbz"},
+		{path: "Books/Ray Bradbury/Fahrenheit 451/Fahrenheit 451.epub"},
+		{path: "Books/George Orwell/1984/1984.epub"},
+		{path: "Books/Author/Book1/Book1.epub"},
+		{path: "Books/Author/Volcano/Volcano.epub"},
+		{path: "Books/Author/Chapterhouse Dune/Chapterhouse Dune.epub"},
+		{path: "Books/George Orwell/Orwell - 1984.epub"},
+		{path: "Books/Метро - 2033.fb2"},
+		{path: "Books/Стивен Кинг/С.Кинг - Т. 2 Оно.fb2"},
+		{path: "Comics/2000 AD #12.cbz", wantName: "2000 AD", wantIndex: 12, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			name, index, ok := SeriesFromPath(tt.path)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantIndex, index)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package vo
+
+// SSOClaims are what an identity provider, or a trusted reverse proxy, asserts about a user.
+type SSOClaims struct {
+	// Issuer and Subject identify the user at the provider, Subject never changes.
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
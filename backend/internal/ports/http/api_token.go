@@ -0,0 +1,100 @@
+package http_port
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type createAPITokenRequest struct {
+	Name      string         `json:"name"`
+	Scopes    []domain.Scope `json:"scopes"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+}
+
+type apiTokenResponse struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Scopes     []domain.Scope `json:"scopes"`
+	CreatedAt  time.Time      `json:"created_at"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+}
+
+type createAPITokenResponse struct {
+	apiTokenResponse
+	// Token is only returned once, on creation.
+	Token string `json:"token"`
+}
+
+func newAPITokenResponse(t *domain.APIToken) apiTokenResponse {
+	return apiTokenResponse{
+		ID:         t.ID().String(),
+		Name:       t.Name(),
+		Scopes:     t.Scopes(),
+		CreatedAt:  t.CreatedAt(),
+		ExpiresAt:  t.ExpiresAt(),
+		LastUsedAt: t.LastUsedAt(),
+		RevokedAt:  t.RevokedAt(),
+	}
+}
+
+// listAPITokens handles GET /api/v1/api-tokens
+func (s *Server) listAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.AuthApp.ListAPITokens(r.Context())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res := make([]apiTokenResponse, len(tokens))
+	for i, t := range tokens {
+		res[i] = newAPITokenResponse(t)
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// createAPIToken handles POST /api/v1/api-tokens
+func (s *Server) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	var req createAPITokenRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res, err := s.AuthApp.CreateAPIToken(r.Context(), auth.CreateAPITokenCmd{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, createAPITokenResponse{
+		apiTokenResponse: newAPITokenResponse(res.Token),
+		Token:            res.Secret,
+	})
+}
+
+// revokeAPIToken handles DELETE /api/v1/api-tokens/{id}
+func (s *Server) revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrAPITokenNotFound)
+		return
+	}
+
+	if err := s.AuthApp.RevokeAPIToken(r.Context(), id); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
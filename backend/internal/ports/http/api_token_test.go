@@ -0,0 +1,121 @@
+package http_port
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+func TestServer_authenticate_apiToken(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	const secret = domain.APITokenPrefix + "valid"
+	token := domain.NewAPITokenBuilder().UserID(user.ID()).Scopes([]domain.Scope{domain.ScopeReadLibrary}).Build()
+
+	tests := []struct {
+		name              string
+		setAuth           func(*http.Request)
+		expectedStatus    int
+		expectedChallenge string
+	}{
+		{
+			name:           "bearer",
+			setAuth:        func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+secret) },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "basic",
+			setAuth:        func(r *http.Request) { r.SetBasicAuth(strings.ToUpper(user.Name()), secret) },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "basic with another user name",
+			setAuth:           func(r *http.Request) { r.SetBasicAuth("someone-else", secret) },
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Basic realm="goread", charset="UTF-8"`,
+		},
+		{
+			name:              "invalid bearer",
+			setAuth:           func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") },
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: "Bearer",
+		},
+		{
+			name:              "unknown scheme",
+			setAuth:           func(r *http.Request) { r.Header.Set("Authorization", "Digest whatever") },
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: "Bearer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, authApp := newAuthedServer(t, user)
+			authApp.On("AuthenticateAPIToken", mock.Anything, secret).Return(user, &token, nil)
+			authApp.On("AuthenticateAPIToken", mock.Anything, mock.Anything).Return(nil, nil, auth.ErrInvalidAPIToken)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+			tt.setAuth(req)
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.expectedChallenge, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestServer_createAPIToken(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	token, secret, err := domain.NewAPIToken(user.ID(), "koreader", []domain.Scope{domain.ScopeReadLibrary, domain.ScopeWriteProgress}, nil)
+	require.NoError(t, err)
+
+	srv, authApp := newAuthedServer(t, user)
+	authApp.On("CreateAPIToken", mock.Anything, auth.CreateAPITokenCmd{
+		Name:   "koreader",
+		Scopes: []domain.Scope{domain.ScopeReadLibrary, domain.ScopeWriteProgress},
+	}).Return(auth.CreateAPITokenResult{Token: token, Secret: secret}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/api-tokens",
+		strings.NewReader(`{"name":"koreader","scopes":["library:read","progress:write"]}`))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(req))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var res createAPITokenResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, secret, res.Token)
+	assert.Equal(t, token.ID().String(), res.ID)
+	assert.Equal(t, token.Scopes(), res.Scopes)
+}
+
+func TestServer_revokeAPIToken(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	id := domain.NewAPITokenID()
+	srv, authApp := newAuthedServer(t, user)
+	authApp.On("RevokeAPIToken", mock.Anything, id).Return(nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodDelete, "/api/v1/api-tokens/"+id.String(), nil)))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodDelete, "/api/v1/api-tokens/42", nil)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
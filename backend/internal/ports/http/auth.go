@@ -0,0 +1,317 @@
+package http_port
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+const sessionCookieName = "goread_session"
+
+type AuthApp interface {
+	Bootstrap(ctx context.Context, name, password string) (*domain.User, error)
+	Register(ctx context.Context, name, password string) (*domain.User, error)
+	Login(context.Context, auth.LoginCmd) (auth.LoginResult, error)
+	Authenticate(ctx context.Context, token string) (*domain.User, *domain.Session, error)
+	Logout(ctx context.Context, token string) error
+	LogoutAll(context.Context) error
+	ListUsers(context.Context) ([]*domain.User, error)
+	ChangeUserRole(ctx context.Context, id domain.UserID, role domain.Role) (*domain.User, error)
+	SetUserRestrictions(ctx context.Context, id domain.UserID, r domain.ContentRestrictions) (*domain.User, error)
+	CreateAPIToken(context.Context, auth.CreateAPITokenCmd) (auth.CreateAPITokenResult, error)
+	ListAPITokens(context.Context) ([]*domain.APIToken, error)
+	RevokeAPIToken(context.Context, domain.APITokenID) error
+	AuthenticateAPIToken(ctx context.Context, secret string) (*domain.User, *domain.APIToken, error)
+	ChangePassword(ctx context.Context, current, password string) error
+	CreatePasswordReset(context.Context, domain.UserID) (auth.CreatePasswordResetResult, error)
+	ResetPassword(ctx context.Context, token, password string) error
+	BeginOIDCLogin() (auth.OIDCLoginStart, error)
+	CompleteOIDCLogin(context.Context, auth.CompleteOIDCLoginCmd) (auth.LoginResult, error)
+	AuthenticateTrustedHeader(ctx context.Context, name string, groups []string) (*domain.User, error)
+	SetKOReaderPassword(ctx context.Context, password string) error
+	RegisterKOReader(ctx context.Context, name, key string) (*domain.User, error)
+	AuthenticateKOReader(context.Context, auth.AuthenticateKOReaderCmd) (*domain.User, error)
+}
+
+type credentialsRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type userResponse struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Role        domain.Role         `json:"role"`
+	Permissions []domain.Permission `json:"permissions"`
+	// Restrictions is omitted for unrestricted users.
+	Restrictions *restrictionsDTO `json:"restrictions,omitempty"`
+}
+
+func newUserResponse(u *domain.User) userResponse {
+	res := userResponse{
+		ID:          u.ID().String(),
+		Name:        u.Name(),
+		Role:        u.Role(),
+		Permissions: u.Role().Permissions(),
+	}
+	if r := u.Restrictions(); !r.IsZero() {
+		dto := newRestrictionsDTO(r)
+		res.Restrictions = &dto
+	}
+	return res
+}
+
+// bootstrap handles POST /api/v1/auth/bootstrap, it only works while there are no users.
+func (s *Server) bootstrap(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	user, err := s.AuthApp.Bootstrap(r.Context(), req.Name, req.Password)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, newUserResponse(user))
+}
+
+// register handles POST /api/v1/auth/register
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	user, err := s.AuthApp.Register(r.Context(), req.Name, req.Password)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, newUserResponse(user))
+}
+
+// login handles POST /api/v1/auth/login, the session token is set as an http-only cookie.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res, err := s.AuthApp.Login(r.Context(), auth.LoginCmd{
+		Name:      req.Name,
+		Password:  req.Password,
+		ClientIP:  clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.setSessionCookie(w, res.Token, res.Session.ExpiresAt())
+	writeJSON(w, r, http.StatusOK, newUserResponse(res.User))
+}
+
+// logout handles POST /api/v1/auth/logout
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookieName); err == nil {
+		if err := s.AuthApp.Logout(r.Context(), c.Value); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+	}
+
+	s.setSessionCookie(w, "", time.Unix(0, 0))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logoutAll handles POST /api/v1/auth/logout-all
+func (s *Server) logoutAll(w http.ResponseWriter, r *http.Request) {
+	if err := s.AuthApp.LogoutAll(r.Context()); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.setSessionCookie(w, "", time.Unix(0, 0))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// changePassword handles POST /api/v1/auth/password
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request) {
+	var req changePasswordRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	if err := s.AuthApp.ChangePassword(r.Context(), req.CurrentPassword, req.NewPassword); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// resetPassword handles POST /api/v1/auth/password-reset, the token comes from an admin.
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	if err := s.AuthApp.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type setKOReaderPasswordRequest struct {
+	// Password is typed into KOReader's progress sync settings, empty disables KOReader sync.
+	Password string `json:"password"`
+}
+
+// setKOReaderPassword handles PUT /api/v1/auth/koreader
+func (s *Server) setKOReaderPassword(w http.ResponseWriter, r *http.Request) {
+	var req setKOReaderPasswordRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	if err := s.AuthApp.SetKOReaderPassword(r.Context(), req.Password); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// me handles GET /api/v1/auth/me
+func (s *Server) me(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newUserResponse(user))
+}
+
+// authenticate attaches the user of the request to its context. API tokens are accepted in the
+// Authorization header as Bearer or, for clients that only support it, as the password of HTTP Basic.
+// Requests from a trusted proxy may carry the user in a header, see TrustedHeader.
+// Wrong credentials in the header are rejected, requests without a valid session cookie continue
+// anonymously, see requireUser.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authenticateTrustedHeader(next, w, r) {
+			return
+		}
+		if r.Header.Get("Authorization") != "" {
+			s.authenticateHeader(next, w, r)
+			return
+		}
+
+		c, err := r.Cookie(sessionCookieName)
+		if err != nil || c.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, session, err := s.AuthApp.Authenticate(r.Context(), c.Value)
+		if err != nil {
+			if !errors.Is(err, domain.ErrUnauthorized) {
+				s.writeError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithSession(auth.WithUser(r.Context(), user), session)))
+	})
+}
+
+func (s *Server) authenticateHeader(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	var (
+		secret, name string
+		challenge    = "Bearer"
+	)
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		secret = strings.TrimSpace(token)
+	} else if n, p, ok := r.BasicAuth(); ok {
+		name, secret = n, p
+		challenge = `Basic realm="goread", charset="UTF-8"`
+	}
+
+	user, token, err := s.AuthApp.AuthenticateAPIToken(r.Context(), secret)
+	if err == nil && name != "" && !strings.EqualFold(name, user.Name()) {
+		err = auth.ErrInvalidAPIToken
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrUnauthorized) {
+			w.Header().Set("WWW-Authenticate", challenge)
+		}
+		s.writeError(w, r, err)
+		return
+	}
+
+	ctx := auth.WithAPIToken(auth.WithUser(r.Context(), user), token)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// requireUser responds with 401 to requests without an authenticated user.
+func (s *Server) requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.UserFromContext(r.Context()); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) setSessionCookie(w http.ResponseWriter, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   s.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
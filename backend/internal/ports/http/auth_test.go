@@ -0,0 +1,333 @@
+package http_port
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+)
+
+type mockAuthApp struct{ mock.Mock }
+
+func (m *mockAuthApp) Bootstrap(ctx context.Context, name, password string) (*domain.User, error) {
+	args := m.Called(ctx, name, password)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockAuthApp) Register(ctx context.Context, name, password string) (*domain.User, error) {
+	args := m.Called(ctx, name, password)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockAuthApp) Login(ctx context.Context, cmd auth.LoginCmd) (auth.LoginResult, error) {
+	args := m.Called(ctx, cmd)
+	r, _ := args.Get(0).(auth.LoginResult)
+	return r, args.Error(1)
+}
+
+func (m *mockAuthApp) Authenticate(ctx context.Context, token string) (*domain.User, *domain.Session, error) {
+	args := m.Called(ctx, token)
+	u, _ := args.Get(0).(*domain.User)
+	s, _ := args.Get(1).(*domain.Session)
+	return u, s, args.Error(2)
+}
+
+func (m *mockAuthApp) Logout(ctx context.Context, token string) error {
+	return m.Called(ctx, token).Error(0)
+}
+
+func (m *mockAuthApp) LogoutAll(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockAuthApp) ListUsers(ctx context.Context) ([]*domain.User, error) {
+	args := m.Called(ctx)
+	u, _ := args.Get(0).([]*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockAuthApp) ChangeUserRole(ctx context.Context, id domain.UserID, role domain.Role) (*domain.User, error) {
+	args := m.Called(ctx, id, role)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockAuthApp) SetUserRestrictions(ctx context.Context, id domain.UserID, r domain.ContentRestrictions) (*domain.User, error) {
+	args := m.Called(ctx, id, r)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockAuthApp) CreateAPIToken(ctx context.Context, cmd auth.CreateAPITokenCmd) (auth.CreateAPITokenResult, error) {
+	args := m.Called(ctx, cmd)
+	r, _ := args.Get(0).(auth.CreateAPITokenResult)
+	return r, args.Error(1)
+}
+
+func (m *mockAuthApp) ListAPITokens(ctx context.Context) ([]*domain.APIToken, error) {
+	args := m.Called(ctx)
+	t, _ := args.Get(0).([]*domain.APIToken)
+	return t, args.Error(1)
+}
+
+func (m *mockAuthApp) RevokeAPIToken(ctx context.Context, id domain.APITokenID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockAuthApp) AuthenticateAPIToken(ctx context.Context, secret string) (*domain.User, *domain.APIToken, error) {
+	args := m.Called(ctx, secret)
+	u, _ := args.Get(0).(*domain.User)
+	t, _ := args.Get(1).(*domain.APIToken)
+	return u, t, args.Error(2)
+}
+
+func (m *mockAuthApp) ChangePassword(ctx context.Context, current, password string) error {
+	return m.Called(ctx, current, password).Error(0)
+}
+
+func (m *mockAuthApp) CreatePasswordReset(ctx context.Context, id domain.UserID) (auth.CreatePasswordResetResult, error) {
+	args := m.Called(ctx, id)
+	r, _ := args.Get(0).(auth.CreatePasswordResetResult)
+	return r, args.Error(1)
+}
+
+func (m *mockAuthApp) ResetPassword(ctx context.Context, token, password string) error {
+	return m.Called(ctx, token, password).Error(0)
+}
+
+func (m *mockAuthApp) BeginOIDCLogin() (auth.OIDCLoginStart, error) {
+	args := m.Called()
+	r, _ := args.Get(0).(auth.OIDCLoginStart)
+	return r, args.Error(1)
+}
+
+func (m *mockAuthApp) CompleteOIDCLogin(ctx context.Context, cmd auth.CompleteOIDCLoginCmd) (auth.LoginResult, error) {
+	args := m.Called(ctx, cmd)
+	r, _ := args.Get(0).(auth.LoginResult)
+	return r, args.Error(1)
+}
+
+func (m *mockAuthApp) AuthenticateTrustedHeader(ctx context.Context, name string, groups []string) (*domain.User, error) {
+	args := m.Called(ctx, name, groups)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockAuthApp) SetKOReaderPassword(ctx context.Context, password string) error {
+	return m.Called(ctx, password).Error(0)
+}
+
+func (m *mockAuthApp) RegisterKOReader(ctx context.Context, name, key string) (*domain.User, error) {
+	args := m.Called(ctx, name, key)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+func (m *mockAuthApp) AuthenticateKOReader(ctx context.Context, cmd auth.AuthenticateKOReaderCmd) (*domain.User, error) {
+	args := m.Called(ctx, cmd)
+	u, _ := args.Get(0).(*domain.User)
+	return u, args.Error(1)
+}
+
+const testSessionToken = "test-session-token"
+
+// newAuthedServer returns a server whose AuthApp accepts testSessionToken as a session of user.
+func newAuthedServer(t *testing.T, user *domain.User) (*Server, *mockAuthApp) {
+	t.Helper()
+	authApp := new(mockAuthApp)
+	authApp.On("Authenticate", mock.Anything, testSessionToken).Return(user, nil, nil)
+	authApp.On("Authenticate", mock.Anything, mock.Anything).Return(nil, nil, auth.ErrNotAuthenticated)
+	return &Server{AuthApp: authApp}, authApp
+}
+
+func withSession(r *http.Request) *http.Request {
+	r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: testSessionToken})
+	return r
+}
+
+func mustNewUser(t *testing.T) *domain.User {
+	return mustNewUserWithRole(t, domain.RoleMember)
+}
+
+func mustNewUserWithRole(t *testing.T, role domain.Role) *domain.User {
+	t.Helper()
+	u, err := domain.NewUser(domain.NewUserID(), domain.ValidUsername, domain.ValidPassword, role, domain.DefaultPasswordParams(envx.Test))
+	require.NoError(t, err)
+	return u
+}
+
+func TestServer_login(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	session, token, err := domain.NewSession(user.ID(), "", time.Hour)
+	require.NoError(t, err)
+
+	srv, authApp := newAuthedServer(t, user)
+	authApp.On("Login", mock.Anything, auth.LoginCmd{
+		Name:      user.Name(),
+		Password:  domain.ValidPassword,
+		ClientIP:  "192.0.2.1",
+		UserAgent: "test-agent",
+	}).Return(auth.LoginResult{User: user, Session: session, Token: token}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+		strings.NewReader(`{"name":"`+user.Name()+`","password":"`+domain.ValidPassword+`"}`))
+	req.Header.Set("User-Agent", "test-agent")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, sessionCookieName, cookies[0].Name)
+	assert.Equal(t, token, cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+
+	var res userResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, newUserResponse(user), res)
+}
+
+func TestServer_login_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		body           string
+		loginErr       error
+		expectedStatus int
+	}{
+		{name: "malformed body", body: `{"name":`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown field", body: `{"username":"x"}`, expectedStatus: http.StatusBadRequest},
+		{name: "wrong password", body: `{"name":"a","password":"b"}`, loginErr: auth.ErrInvalidCredentials, expectedStatus: http.StatusUnauthorized},
+		{name: "locked out", body: `{"name":"a","password":"b"}`, loginErr: auth.ErrLoginLocked, expectedStatus: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, authApp := newAuthedServer(t, mustNewUser(t))
+			authApp.On("Login", mock.Anything, mock.Anything).Return(nil, tt.loginErr)
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(tt.body)))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestServer_me(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	srv, _ := newAuthedServer(t, user)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "no cookie")
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "revoked"})
+	srv.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "invalid session")
+}
+
+func TestServer_logout(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	srv, authApp := newAuthedServer(t, user)
+	authApp.On("Logout", mock.Anything, testSessionToken).Return(nil)
+	authApp.On("LogoutAll", mock.Anything).Return(nil)
+
+	for _, path := range []string{"/api/v1/auth/logout", "/api/v1/auth/logout-all"} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodPost, path, nil)))
+		require.Equal(t, http.StatusNoContent, rec.Code, path)
+
+		cookies := rec.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Empty(t, cookies[0].Value, "cookie is cleared")
+	}
+	authApp.AssertExpectations(t)
+}
+
+func TestServer_changePassword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusNoContent},
+		{name: "wrong current password", err: auth.ErrWrongPassword, status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, authApp := newAuthedServer(t, mustNewUser(t))
+			authApp.On("ChangePassword", mock.Anything, "old", "new").Return(tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password",
+				strings.NewReader(`{"current_password":"old","new_password":"new"}`))
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(req))
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestServer_passwordReset(t *testing.T) {
+	t.Parallel()
+
+	admin := mustNewUserWithRole(t, domain.RoleAdmin)
+	user := mustNewUser(t)
+	reset, token, err := domain.NewPasswordReset(user.ID(), admin.ID(), time.Hour)
+	require.NoError(t, err)
+
+	srv, authApp := newAuthedServer(t, admin)
+	authApp.On("CreatePasswordReset", mock.Anything, user.ID()).
+		Return(auth.CreatePasswordResetResult{Reset: reset, Token: token}, nil)
+	authApp.On("ResetPassword", mock.Anything, token, domain.ValidPassword).Return(nil)
+	authApp.On("ResetPassword", mock.Anything, mock.Anything, mock.Anything).Return(auth.ErrInvalidPasswordReset)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodPost, "/api/v1/users/"+user.ID().String()+"/password-reset", nil)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var res passwordResetResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, token, res.Token)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/password-reset",
+		strings.NewReader(`{"token":"`+token+`","password":"`+domain.ValidPassword+`"}`)))
+	assert.Equal(t, http.StatusNoContent, rec.Code, "reset works without a session")
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/password-reset",
+		strings.NewReader(`{"token":"used","password":"`+domain.ValidPassword+`"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
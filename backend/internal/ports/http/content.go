@@ -0,0 +1,228 @@
+package http_port
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/ARUMANDESU/validation/is"
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/content"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+type ContentApp interface {
+	GetPage(context.Context, content.GetPageQuery) (content.Image, error)
+	Download(context.Context, content.DownloadQuery) (content.Download, error)
+	GetCover(context.Context, content.GetCoverQuery) (content.Image, error)
+	GetEPUBResource(context.Context, content.GetEPUBResourceQuery) (content.Resource, error)
+	ValidateEPUB(context.Context, domain.LibraryItemID) (epubx.Report, error)
+}
+
+type epubIssueResponse struct {
+	Severity epubx.Severity `json:"severity"`
+	Message  string         `json:"message"`
+}
+
+type epubValidationResponse struct {
+	Valid   bool                `json:"valid"`
+	Version string              `json:"version,omitempty"`
+	Issues  []epubIssueResponse `json:"issues"`
+	// Error is why the book cannot be read at all.
+	Error string `json:"error,omitempty"`
+}
+
+// epubContentSecurityPolicy keeps book content from running scripts in our origin, on top of the
+// sanitization: the web reader shows chapters in a frame, so they still may be same-origin.
+const epubContentSecurityPolicy = "default-src 'none'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; " +
+	"font-src 'self' data:; media-src 'self'; script-src 'none'; object-src 'none'; form-action 'none'; " +
+	"base-uri 'none'; frame-ancestors 'self'; sandbox allow-same-origin"
+
+// download handles GET /api/v1/library-items/{id}/download, it supports Range and conditional requests.
+//
+// query params:
+//   - format: the format to download the item in, e.g. epub for FB2 and text books
+func (s *Server) download(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	d, err := s.ContentApp.Download(r.Context(), content.DownloadQuery{
+		ItemID: id,
+		Format: domain.FileFormat(r.URL.Query().Get("format")),
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	defer d.Content.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", d.MediaType)
+	h.Set("Content-Disposition", contentDisposition(d.Name))
+	h.Set("ETag", `"`+d.ETag+`"`)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, d.Name, d.ModTime, d.Content)
+}
+
+// getPage handles GET /api/v1/library-items/{id}/pages/{page}, page is 0-based.
+//
+// query params:
+//   - max_width: wider pages are scaled down to it, the OPDS-PSE {maxWidth}
+func (s *Server) getPage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+	page, err := strconv.Atoi(r.PathValue("page"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrPageNotFound)
+		return
+	}
+
+	q := content.GetPageQuery{ItemID: id, Page: page}
+	if raw := r.URL.Query().Get("max_width"); raw != "" {
+		if q.MaxWidth, err = strconv.Atoi(raw); err != nil {
+			s.writeError(w, r, v.Errors{"max_width": is.ErrInt})
+			return
+		}
+	}
+
+	img, err := s.ContentApp.GetPage(r.Context(), q)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeImage(w, img)
+}
+
+// getCover handles GET /api/v1/library-items/{id}/cover, it supports conditional requests.
+//
+// query params:
+//   - size: full (default), medium or thumbnail
+//   - format: jpeg (default) or webp
+func (s *Server) getCover(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	img, err := s.ContentApp.GetCover(r.Context(), content.GetCoverQuery{
+		ItemID: id,
+		Size:   content.CoverSize(r.URL.Query().Get("size")),
+		Format: content.CoverFormat(r.URL.Query().Get("format")),
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// The URL of a cover stays the same when the file changes, so caches revalidate after a week.
+	h := w.Header()
+	h.Set("Content-Type", img.MediaType)
+	h.Set("ETag", `"`+img.ETag+`"`)
+	h.Set("Cache-Control", "private, max-age=604800")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(img.Data))
+}
+
+// getEPUBResource handles GET /api/v1/library-items/{id}/epub/{path...}, path is the path of a
+// manifest item in the EPUB container, so relative links between resources resolve as in the book.
+func (s *Server) getEPUBResource(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	res, err := s.ContentApp.GetEPUBResource(r.Context(), content.GetEPUBResourceQuery{ItemID: id, Path: r.PathValue("path")})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", res.MediaType)
+	h.Set("Content-Security-Policy", epubContentSecurityPolicy)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cross-Origin-Resource-Policy", "same-origin")
+	h.Set("ETag", `"`+res.ETag+`"`)
+	h.Set("Cache-Control", "private, max-age=3600")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(res.Data))
+}
+
+// validateEPUB handles GET /api/v1/library-items/{id}/epub-validation, it reports every deviation
+// of the EPUB from the spec.
+func (s *Server) validateEPUB(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	report, err := s.ContentApp.ValidateEPUB(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res := epubValidationResponse{
+		Valid:   report.Valid(),
+		Version: report.Version,
+		Issues:  make([]epubIssueResponse, len(report.Issues)),
+	}
+	for i, issue := range report.Issues {
+		res.Issues[i] = epubIssueResponse{Severity: issue.Severity, Message: issue.Error()}
+	}
+	if report.Err != nil {
+		res.Error = report.Err.Error()
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// contentDisposition returns an attachment header with name as an RFC 6266 filename*, and an ASCII
+// only filename for old clients.
+func contentDisposition(name string) string {
+	var ascii, encoded strings.Builder
+	for _, r := range name {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' {
+			r = '_'
+		}
+		ascii.WriteRune(r)
+	}
+	for _, b := range []byte(name) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return `attachment; filename="` + ascii.String() + `"; filename*=UTF-8''` + encoded.String()
+}
+
+// isAttrChar reports whether b may appear unencoded in an RFC 5987 value.
+func isAttrChar(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// writeImage writes img, it may be cached by the browser but not by shared caches
+// since restrictions differ between users.
+func writeImage(w http.ResponseWriter, img content.Image) {
+	w.Header().Set("Content-Type", img.MediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(img.Data)))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(img.Data)
+}
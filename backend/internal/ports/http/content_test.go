@@ -0,0 +1,307 @@
+package http_port
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/content"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	vx "github.com/ARUMANDESU/goread/backend/pkg/validationx"
+)
+
+type mockContentApp struct{ mock.Mock }
+
+func (m *mockContentApp) GetPage(ctx context.Context, q content.GetPageQuery) (content.Image, error) {
+	args := m.Called(ctx, q)
+	img, _ := args.Get(0).(content.Image)
+	return img, args.Error(1)
+}
+
+func (m *mockContentApp) Download(ctx context.Context, q content.DownloadQuery) (content.Download, error) {
+	args := m.Called(ctx, q)
+	d, _ := args.Get(0).(content.Download)
+	return d, args.Error(1)
+}
+
+func (m *mockContentApp) GetCover(ctx context.Context, q content.GetCoverQuery) (content.Image, error) {
+	args := m.Called(ctx, q)
+	img, _ := args.Get(0).(content.Image)
+	return img, args.Error(1)
+}
+
+func (m *mockContentApp) GetEPUBResource(ctx context.Context, q content.GetEPUBResourceQuery) (content.Resource, error) {
+	args := m.Called(ctx, q)
+	res, _ := args.Get(0).(content.Resource)
+	return res, args.Error(1)
+}
+
+func (m *mockContentApp) ValidateEPUB(ctx context.Context, id domain.LibraryItemID) (epubx.Report, error) {
+	args := m.Called(ctx, id)
+	report, _ := args.Get(0).(epubx.Report)
+	return report, args.Error(1)
+}
+
+// nopCloser adds Close to a strings.Reader.
+type nopCloser struct{ *strings.Reader }
+
+func (nopCloser) Close() error { return nil }
+
+func TestServer_download(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewLibraryItemID()
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	newDownload := func() content.Download {
+		return content.Download{
+			Content:   nopCloser{strings.NewReader("0123456789")},
+			Size:      10,
+			ModTime:   modTime,
+			MediaType: "application/epub+zip",
+			Name:      `Роберт Сальваторе - Воин "1".epub`,
+			ETag:      "cafe",
+		}
+	}
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		status       int
+		expectedBody string
+	}{
+		{name: "whole file", status: http.StatusOK, expectedBody: "0123456789"},
+		{name: "range", header: http.Header{"Range": {"bytes=2-4"}}, status: http.StatusPartialContent, expectedBody: "234"},
+		{name: "unsatisfiable range", header: http.Header{"Range": {"bytes=20-"}}, status: http.StatusRequestedRangeNotSatisfiable},
+		{name: "etag matches", header: http.Header{"If-None-Match": {`"cafe"`}}, status: http.StatusNotModified},
+		{name: "stale if-range", header: http.Header{"Range": {"bytes=2-4"}, "If-Range": {`"beef"`}}, status: http.StatusOK, expectedBody: "0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := new(mockContentApp)
+			app.On("Download", mock.Anything, content.DownloadQuery{ItemID: id}).Return(newDownload(), nil)
+			srv, _ := newAuthedServer(t, mustNewUser(t))
+			srv.ContentApp = app
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/download", nil)
+			maps.Copy(req.Header, tt.header)
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(req))
+
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.expectedBody != "" {
+				assert.Equal(t, `"cafe"`, rec.Header().Get("ETag"))
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+				assert.Equal(t, "application/epub+zip", rec.Header().Get("Content-Type"))
+				assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+				assert.Equal(t,
+					`attachment; filename="______ __________ - ____ _1_.epub"; `+
+						`filename*=UTF-8''%D0%A0%D0%BE%D0%B1%D0%B5%D1%80%D1%82%20%D0%A1%D0%B0%D0%BB%D1%8C%D0%B2%D0%B0%D1%82%D0%BE%D1%80%D0%B5%20-%20%D0%92%D0%BE%D0%B8%D0%BD%20%221%22.epub`,
+					rec.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
+
+func TestServer_download_denied(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewLibraryItemID()
+	app := new(mockContentApp)
+	app.On("Download", mock.Anything, content.DownloadQuery{ItemID: id}).Return(nil, domain.ErrPermissionDenied)
+	srv, _ := newAuthedServer(t, mustNewUserWithRole(t, domain.RoleGuest))
+	srv.ContentApp = app
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/download", nil)))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestServer_download_format(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewLibraryItemID()
+	app := new(mockContentApp)
+	app.On("Download", mock.Anything, content.DownloadQuery{ItemID: id, Format: domain.FormatEPUB}).Return(content.Download{
+		Content:   nopCloser{strings.NewReader("epub")},
+		Size:      4,
+		MediaType: "application/epub+zip",
+		Name:      "Воин.epub",
+		ETag:      "cafe",
+	}, nil)
+	app.On("Download", mock.Anything, content.DownloadQuery{ItemID: id, Format: domain.FormatPDF}).
+		Return(nil, v.Errors{"format": vx.ErrUnavailableFormat})
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	srv.ContentApp = app
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/download?format=epub", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/epub+zip", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "epub", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/download?format=pdf", nil)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestServer_getPage(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewLibraryItemID()
+	app := new(mockContentApp)
+	app.On("GetPage", mock.Anything, content.GetPageQuery{ItemID: id, Page: 4, MaxWidth: 600}).
+		Return(content.Image{Data: []byte("jpeg"), MediaType: "image/jpeg"}, nil)
+	app.On("GetPage", mock.Anything, content.GetPageQuery{ItemID: id, Page: 99}).
+		Return(nil, domain.ErrPageNotFound)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "scaled page", path: "/api/v1/library-items/" + id.String() + "/pages/4?max_width=600", status: http.StatusOK},
+		{name: "missing page", path: "/api/v1/library-items/" + id.String() + "/pages/99", status: http.StatusNotFound},
+		{name: "malformed page", path: "/api/v1/library-items/" + id.String() + "/pages/first", status: http.StatusNotFound},
+		{name: "malformed width", path: "/api/v1/library-items/" + id.String() + "/pages/4?max_width=wide", status: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, _ := newAuthedServer(t, mustNewUser(t))
+			srv.ContentApp = app
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, tt.path, nil)))
+
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+				assert.Equal(t, "private, max-age=86400", rec.Header().Get("Cache-Control"))
+				assert.Equal(t, "jpeg", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_getCover(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewLibraryItemID()
+	app := new(mockContentApp)
+	app.On("GetCover", mock.Anything, content.GetCoverQuery{ItemID: id, Size: content.CoverSizeThumbnail}).
+		Return(content.Image{Data: []byte("jpeg"), MediaType: "image/jpeg", ETag: "cafe-thumbnail"}, nil)
+	app.On("GetCover", mock.Anything, content.GetCoverQuery{ItemID: id, Size: content.CoverSizeThumbnail, Format: content.CoverFormatWebP}).
+		Return(content.Image{Data: []byte("webp"), MediaType: "image/webp", ETag: "cafe-thumbnail"}, nil)
+	app.On("GetCover", mock.Anything, content.GetCoverQuery{ItemID: id}).
+		Return(nil, domain.ErrCoverNotFound)
+
+	tests := []struct {
+		name         string
+		path         string
+		header       http.Header
+		status       int
+		expectedType string
+		expectedBody string
+	}{
+		{name: "thumbnail", path: "/cover?size=thumbnail", status: http.StatusOK, expectedType: "image/jpeg", expectedBody: "jpeg"},
+		{name: "webp thumbnail", path: "/cover?size=thumbnail&format=webp", status: http.StatusOK, expectedType: "image/webp", expectedBody: "webp"},
+		{
+			name:   "etag matches",
+			path:   "/cover?size=thumbnail",
+			header: http.Header{"If-None-Match": {`"cafe-thumbnail"`}},
+			status: http.StatusNotModified,
+		},
+		{name: "no cover", path: "/cover", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, _ := newAuthedServer(t, mustNewUser(t))
+			srv.ContentApp = app
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+tt.path, nil)
+			maps.Copy(req.Header, tt.header)
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(req))
+
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.status != http.StatusNotFound {
+				assert.Equal(t, `"cafe-thumbnail"`, rec.Header().Get("ETag"))
+				assert.Equal(t, "private, max-age=604800", rec.Header().Get("Cache-Control"))
+			}
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedType, rec.Header().Get("Content-Type"))
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_getEPUBResource(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewLibraryItemID()
+	app := new(mockContentApp)
+	app.On("GetEPUBResource", mock.Anything, content.GetEPUBResourceQuery{ItemID: id, Path: "OEBPS/text/chapter 1.xhtml"}).
+		Return(content.Resource{Data: []byte("<p>Дзирт</p>"), MediaType: "application/xhtml+xml", ETag: "cafe"}, nil)
+	app.On("GetEPUBResource", mock.Anything, content.GetEPUBResourceQuery{ItemID: id, Path: "META-INF/encryption.xml"}).
+		Return(nil, domain.ErrResourceNotFound)
+
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	srv.ContentApp = app
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/epub/OEBPS/text/chapter%201.xhtml", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/xhtml+xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, epubContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "<p>Дзирт</p>", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/epub/META-INF/encryption.xml", nil)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_validateEPUB(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewLibraryItemID()
+	app := new(mockContentApp)
+	app.On("ValidateEPUB", mock.Anything, id).Return(epubx.Report{
+		Version: "2.0",
+		Issues: []epubx.Issue{
+			{Severity: epubx.SeverityError, Err: epubx.ErrMimetypeCompressed},
+			{Severity: epubx.SeverityWarning, Err: epubx.ErrMissingResource, Detail: "text/ch2.xhtml"},
+		},
+	}, nil)
+
+	srv, _ := newAuthedServer(t, mustNewUserWithRole(t, domain.RoleAdmin))
+	srv.ContentApp = app
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/epub-validation", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{
+		"valid": false,
+		"version": "2.0",
+		"issues": [
+			{"severity": "error", "message": "mimetype must be uncompressed"},
+			{"severity": "warning", "message": "manifest item not in container: text/ch2.xhtml"}
+		]
+	}`, rec.Body.String())
+}
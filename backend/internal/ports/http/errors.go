@@ -0,0 +1,130 @@
+package http_port
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+)
+
+// errMalformedRequest is returned when the request can not be decoded at all.
+var errMalformedRequest = errors.New("malformed request")
+
+const (
+	codeBadRequest       = "bad_request"
+	codeValidationFailed = "validation_failed"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeDRMProtected     = "drm_protected"
+	codeRateLimited      = "rate_limited"
+	codeInternal         = "internal"
+)
+
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields maps the invalid field (nested fields are joined with dots) to a localized message.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Debug is the whole error chain with op trace, it is only sent in the local, dev and test
+	// modes, an unset mode counts as prod.
+	Debug string `json:"debug,omitempty"`
+}
+
+var sentinelErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{err: errMalformedRequest, status: http.StatusBadRequest, code: codeBadRequest},
+	{err: domain.ErrNotFound, status: http.StatusNotFound, code: codeNotFound},
+	{err: domain.ErrConflict, status: http.StatusConflict, code: codeConflict},
+	{err: domain.ErrUnauthorized, status: http.StatusUnauthorized, code: codeUnauthorized},
+	{err: domain.ErrDRMProtected, status: http.StatusForbidden, code: codeDRMProtected},
+	{err: domain.ErrForbidden, status: http.StatusForbidden, code: codeForbidden},
+	{err: domain.ErrRateLimited, status: http.StatusTooManyRequests, code: codeRateLimited},
+}
+
+// writeError maps err to a status code and writes it as errorResponse:
+//   - v.Errors -> 422 with localized field messages
+//   - domain sentinel errors -> their status code
+//   - anything else -> 500
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	res := errorResponse{Code: codeInternal}
+
+	var verrs v.Errors
+	if errors.As(err, &verrs) {
+		if fields, ok := s.validationFields(r, verrs); ok {
+			status = http.StatusUnprocessableEntity
+			res.Code = codeValidationFailed
+			res.Fields = fields
+		}
+	} else {
+		for _, se := range sentinelErrors {
+			if errors.Is(err, se.err) {
+				status, res.Code = se.status, se.code
+				break
+			}
+		}
+	}
+	res.Message = http.StatusText(status)
+
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	}
+	if s.Mode == envx.Local || s.Mode == envx.Dev || s.Mode == envx.Test {
+		res.Debug = err.Error()
+	}
+
+	writeJSON(w, r, status, res)
+}
+
+// validationFields flattens and localizes errs, ok is false if errs contains v.InternalError,
+// which means validation itself failed and the client is not at fault.
+func (s *Server) validationFields(r *http.Request, errs v.Errors) (fields map[string]string, ok bool) {
+	ok = true
+	fields = make(map[string]string, len(errs))
+	var walk func(prefix string, errs v.Errors)
+	walk = func(prefix string, errs v.Errors) {
+		for field, err := range errs {
+			if prefix != "" {
+				field = prefix + "." + field
+			}
+
+			var nested v.Errors
+			if errors.As(err, &nested) {
+				walk(field, nested)
+				continue
+			}
+
+			var internalErr v.InternalError
+			if errors.As(err, &internalErr) {
+				ok = false
+				continue
+			}
+
+			var verr v.Error
+			if !errors.As(err, &verr) {
+				// not a validation rule error, its text may be anything so it is not shown to the client
+				verr = v.ErrInInvalid
+			}
+			fields[field] = s.translateError(r, verr)
+		}
+	}
+	walk("", errs)
+
+	return fields, ok
+}
+
+func (s *Server) translateError(r *http.Request, verr v.Error) string {
+	if s.Translator == nil {
+		return verr.Error()
+	}
+	return s.Translator.TranslateError(r.Context(), verr)
+}
@@ -0,0 +1,168 @@
+package http_port
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+	"github.com/ARUMANDESU/goread/backend/pkg/i18nx"
+)
+
+func TestServer_writeError(t *testing.T) {
+	t.Parallel()
+
+	translator, err := i18nx.NewTranslator()
+	require.NoError(t, err)
+
+	const op = errorx.Op("domain.NewUser")
+	lengthErr := v.ErrLengthOutOfRange.SetParams(map[string]any{i18nx.PhMin: 3, i18nx.PhMax: 75})
+
+	tests := []struct {
+		name           string
+		mode           envx.Mode
+		acceptLanguage string
+		err            error
+		expectedStatus int
+		expected       errorResponse
+	}{
+		{
+			name:           "validation errors in english",
+			mode:           envx.Prod,
+			err:            op.Wrap(v.Errors{"name": lengthErr, "id": v.ErrRequired}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected: errorResponse{
+				Code:    codeValidationFailed,
+				Message: "Unprocessable Entity",
+				Fields: map[string]string{
+					"name": "the length must be between 3 and 75",
+					"id":   "cannot be blank",
+				},
+			},
+		},
+		{
+			name:           "validation errors in russian",
+			mode:           envx.Prod,
+			acceptLanguage: "ru-RU,ru;q=0.9,en;q=0.8",
+			err:            op.Wrap(v.Errors{"name": lengthErr}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected: errorResponse{
+				Code:    codeValidationFailed,
+				Message: "Unprocessable Entity",
+				Fields:  map[string]string{"name": "длина должна быть от 3 до 75"},
+			},
+		},
+		{
+			name:           "nested validation errors",
+			mode:           envx.Prod,
+			acceptLanguage: "kk",
+			err:            v.Errors{"types": v.Errors{"1": v.ErrInInvalid}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected: errorResponse{
+				Code:    codeValidationFailed,
+				Message: "Unprocessable Entity",
+				Fields:  map[string]string{"types.1": "жарамды мән болуы тиіс"},
+			},
+		},
+		{
+			name:           "non validation leaf error is hidden",
+			mode:           envx.Prod,
+			err:            v.Errors{"password": errors.New("value is not a string")},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected: errorResponse{
+				Code:    codeValidationFailed,
+				Message: "Unprocessable Entity",
+				Fields:  map[string]string{"password": "must be a valid value"},
+			},
+		},
+		{
+			name:           "not found",
+			mode:           envx.Prod,
+			err:            op.Wrap(domain.ErrLibraryItemNotFound),
+			expectedStatus: http.StatusNotFound,
+			expected:       errorResponse{Code: codeNotFound, Message: "Not Found"},
+		},
+		{
+			name:           "conflict",
+			mode:           envx.Prod,
+			err:            op.Wrap(domain.ErrConflict),
+			expectedStatus: http.StatusConflict,
+			expected:       errorResponse{Code: codeConflict, Message: "Conflict"},
+		},
+		{
+			name:           "unauthorized",
+			mode:           envx.Prod,
+			err:            op.Wrap(domain.ErrUnauthorized),
+			expectedStatus: http.StatusUnauthorized,
+			expected:       errorResponse{Code: codeUnauthorized, Message: "Unauthorized"},
+		},
+		{
+			name:           "drm protected",
+			mode:           envx.Prod,
+			err:            op.Wrap(domain.ErrDRMProtected),
+			expectedStatus: http.StatusForbidden,
+			expected:       errorResponse{Code: codeDRMProtected, Message: "Forbidden"},
+		},
+		{
+			name:           "internal error does not leak in prod",
+			mode:           envx.Prod,
+			err:            op.Wrap(errors.New("pq: connection refused")),
+			expectedStatus: http.StatusInternalServerError,
+			expected:       errorResponse{Code: codeInternal, Message: "Internal Server Error"},
+		},
+		{
+			name:           "internal error has debug outside prod",
+			mode:           envx.Dev,
+			err:            op.Wrap(errors.New("pq: connection refused")),
+			expectedStatus: http.StatusInternalServerError,
+			expected: errorResponse{
+				Code:    codeInternal,
+				Message: "Internal Server Error",
+				Debug:   "domain.NewUser: pq: connection refused",
+			},
+		},
+		{
+			name:           "internal error does not leak without a mode",
+			err:            op.Wrap(errors.New("pq: connection refused")),
+			expectedStatus: http.StatusInternalServerError,
+			expected:       errorResponse{Code: codeInternal, Message: "Internal Server Error"},
+		},
+		{
+			name:           "validation internal error",
+			mode:           envx.Prod,
+			err:            v.Errors{"name": v.NewInternalError(errors.New("boom"))},
+			expectedStatus: http.StatusInternalServerError,
+			expected:       errorResponse{Code: codeInternal, Message: "Internal Server Error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{Mode: tt.mode, Translator: translator}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+
+			s.withLanguage(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s.writeError(w, r, tt.err)
+			})).ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			var res errorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package http_port
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	v "github.com/ARUMANDESU/validation"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/app/progress"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+// The KOReader sync protocol, see https://github.com/koreader/koreader-sync-server.
+// KOReader is pointed at https://<goread>/kosync as a custom sync server. Documents are matched by
+// koreaderx.PartialMD5 computed during scans, users by name and their KOReader password.
+
+// kosync error codes, KOReader shows the message of unknown ones.
+const (
+	kosyncCodeInternal        = 2000
+	kosyncCodeUnauthorized    = 2001
+	kosyncCodeUserExists      = 2002
+	kosyncCodeInvalidFields   = 2003
+	kosyncCodeMissingDocument = 2004
+	kosyncCodeUnknownDocument = 2005
+)
+
+type kosyncErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type kosyncUserRequest struct {
+	Username string `json:"username"`
+	// Password is the KOReader key of the password, see domain.KOReaderKey.
+	Password string `json:"password"`
+}
+
+type kosyncProgressRequest struct {
+	Document   string  `json:"document"`
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+}
+
+type kosyncProgressResponse struct {
+	Document   string  `json:"document"`
+	Progress   string  `json:"progress,omitempty"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device,omitempty"`
+	DeviceID   string  `json:"device_id,omitempty"`
+	Timestamp  int64   `json:"timestamp"`
+}
+
+func (s *Server) kosyncRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /kosync/healthcheck", s.kosyncHealthcheck)
+	mux.HandleFunc("POST /kosync/users/create", s.kosyncCreateUser)
+	mux.HandleFunc("GET /kosync/users/auth", s.requireKOReaderUser(s.kosyncAuth))
+	mux.HandleFunc("PUT /kosync/syncs/progress", s.requireKOReaderUser(s.kosyncSaveProgress))
+	mux.HandleFunc("GET /kosync/syncs/progress/{document}", s.requireKOReaderUser(s.kosyncGetProgress))
+}
+
+// kosyncHealthcheck handles GET /kosync/healthcheck
+func (s *Server) kosyncHealthcheck(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]string{"state": "OK"})
+}
+
+// kosyncCreateUser handles POST /kosync/users/create, it is KOReader's registration.
+func (s *Server) kosyncCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req kosyncUserRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeKOSyncError(w, r, err)
+		return
+	}
+
+	user, err := s.AuthApp.RegisterKOReader(r.Context(), req.Username, req.Password)
+	if err != nil {
+		s.writeKOSyncError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, map[string]string{"username": user.Name()})
+}
+
+// kosyncAuth handles GET /kosync/users/auth, KOReader calls it on login.
+func (s *Server) kosyncAuth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]string{"authorized": "OK"})
+}
+
+// kosyncSaveProgress handles PUT /kosync/syncs/progress. KOReader does not track versions,
+// so its progress always overwrites.
+func (s *Server) kosyncSaveProgress(w http.ResponseWriter, r *http.Request) {
+	var req kosyncProgressRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeKOSyncError(w, r, err)
+		return
+	}
+	if req.Document == "" {
+		writeJSON(w, r, http.StatusForbidden, kosyncErrorResponse{Code: kosyncCodeMissingDocument, Message: "Field 'document' not provided."})
+		return
+	}
+
+	item, err := s.ProgressApp.FindDocument(r.Context(), req.Document)
+	if err != nil {
+		s.writeKOSyncError(w, r, err)
+		return
+	}
+
+	res, err := s.ProgressApp.SaveProgress(r.Context(), progress.SaveProgressCmd{
+		ItemID:    item.ID(),
+		Locator:   kosyncLocator(item.Format(), req.Progress, req.Percentage),
+		Device:    req.Device,
+		Overwrite: true,
+	})
+	if err != nil {
+		s.writeKOSyncError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"document":  req.Document,
+		"timestamp": res.Progress.UpdatedAt().Unix(),
+	})
+}
+
+// kosyncGetProgress handles GET /kosync/syncs/progress/{document}, like the reference server it
+// responds with an empty object when there is no progress.
+func (s *Server) kosyncGetProgress(w http.ResponseWriter, r *http.Request) {
+	document := r.PathValue("document")
+
+	item, err := s.ProgressApp.FindDocument(r.Context(), document)
+	if errors.Is(err, domain.ErrLibraryItemNotFound) {
+		writeJSON(w, r, http.StatusOK, struct{}{})
+		return
+	} else if err != nil {
+		s.writeKOSyncError(w, r, err)
+		return
+	}
+
+	p, err := s.ProgressApp.GetProgress(r.Context(), item.ID())
+	if errors.Is(err, domain.ErrReadingProgressNotFound) {
+		writeJSON(w, r, http.StatusOK, struct{}{})
+		return
+	} else if err != nil {
+		s.writeKOSyncError(w, r, err)
+		return
+	}
+
+	l := p.Locator()
+	writeJSON(w, r, http.StatusOK, kosyncProgressResponse{
+		Document:   document,
+		Progress:   kosyncProgress(l),
+		Percentage: l.Percentage,
+		Device:     p.Device(),
+		Timestamp:  p.UpdatedAt().Unix(),
+	})
+}
+
+// kosyncLocator converts KOReader's progress: an xpointer for reflowable documents
+// and a 1-based page number for paged ones.
+func kosyncLocator(format domain.FileFormat, progress string, percentage float64) domain.Locator {
+	l := domain.Locator{Kind: domain.LocatorPercentage, Percentage: percentage}
+
+	page, err := strconv.Atoi(progress)
+	switch {
+	case strings.HasPrefix(progress, "/") && domain.LocatorXPointer.Supports(format):
+		l.Kind, l.XPointer = domain.LocatorXPointer, progress
+	case err == nil && format == domain.FormatPDF:
+		l.Kind, l.Page = domain.LocatorPDFPage, page
+	case err == nil && format.IsComicArchive():
+		l.Kind, l.Page = domain.LocatorComicPage, page-1
+	}
+	return l
+}
+
+// kosyncProgress is the reverse of kosyncLocator. EPUB CFIs saved by the web reader have no
+// KOReader equivalent, only their percentage is synced.
+func kosyncProgress(l domain.Locator) string {
+	switch l.Kind {
+	case domain.LocatorXPointer:
+		return l.XPointer
+	case domain.LocatorPDFPage:
+		return strconv.Itoa(l.Page)
+	case domain.LocatorComicPage:
+		return strconv.Itoa(l.Page + 1)
+	}
+	return ""
+}
+
+// requireKOReaderUser authenticates the x-auth-user and x-auth-key headers KOReader sends.
+func (s *Server) requireKOReaderUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.AuthApp.AuthenticateKOReader(r.Context(), auth.AuthenticateKOReaderCmd{
+			Name:     r.Header.Get("X-Auth-User"),
+			Key:      r.Header.Get("X-Auth-Key"),
+			ClientIP: clientIP(r),
+		})
+		if err != nil {
+			s.writeKOSyncError(w, r, err)
+			return
+		}
+		next(w, r.WithContext(auth.WithUser(r.Context(), user)))
+	}
+}
+
+// writeKOSyncError writes err in the format of the reference server, KOReader shows the message.
+func (s *Server) writeKOSyncError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		verrs v.Errors
+		res   kosyncErrorResponse
+		code  int
+	)
+	switch {
+	case errors.Is(err, domain.ErrUnauthorized), errors.Is(err, domain.ErrRateLimited):
+		code, res = http.StatusUnauthorized, kosyncErrorResponse{Code: kosyncCodeUnauthorized, Message: "Unauthorized"}
+	case errors.Is(err, auth.ErrUserNameTaken):
+		code, res = http.StatusPaymentRequired, kosyncErrorResponse{Code: kosyncCodeUserExists, Message: "Username is already registered."}
+	case errors.Is(err, domain.ErrLibraryItemNotFound):
+		code, res = http.StatusNotFound, kosyncErrorResponse{Code: kosyncCodeUnknownDocument, Message: "Document is not in the library."}
+	case errors.As(err, &verrs), errors.Is(err, errMalformedRequest), errors.Is(err, domain.ErrForbidden):
+		code, res = http.StatusForbidden, kosyncErrorResponse{Code: kosyncCodeInvalidFields, Message: "Invalid request"}
+	default:
+		slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		code, res = http.StatusInternalServerError, kosyncErrorResponse{Code: kosyncCodeInternal, Message: "Unknown server error."}
+	}
+	writeJSON(w, r, code, res)
+}
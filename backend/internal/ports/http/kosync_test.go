@@ -0,0 +1,228 @@
+package http_port
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/app/progress"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+const (
+	testKOReaderKey = "5f4dcc3b5aa765d61d8327deb882cf99"
+	testDocument    = "0b6c1d9dd1d1e5f0b0c0a3c1f2e3d4c5"
+)
+
+// newKOSyncServer returns a server that accepts "reader" with testKOReaderKey as user.
+func newKOSyncServer(t *testing.T, user *domain.User) (*Server, *mockAuthApp, *mockProgressApp) {
+	t.Helper()
+	srv, authApp := newAuthedServer(t, user)
+	progressApp := new(mockProgressApp)
+	srv.ProgressApp = progressApp
+	authApp.On("AuthenticateKOReader", mock.Anything, auth.AuthenticateKOReaderCmd{
+		Name: "reader", Key: testKOReaderKey, ClientIP: "192.0.2.1",
+	}).Return(user, nil)
+	authApp.On("AuthenticateKOReader", mock.Anything, mock.Anything).Return(nil, auth.ErrInvalidCredentials)
+	return srv, authApp, progressApp
+}
+
+func withKOReaderAuth(r *http.Request) *http.Request {
+	r.Header.Set("X-Auth-User", "reader")
+	r.Header.Set("X-Auth-Key", testKOReaderKey)
+	r.Header.Set("Accept", "application/vnd.koreader.v1+json")
+	return r
+}
+
+func newKOSyncItem(path string) *domain.LibraryItem {
+	item := domain.NewLibraryItemBuilder().Id(domain.NewLibraryItemID()).Path(path).Build()
+	return &item
+}
+
+func TestServer_kosyncAuth(t *testing.T) {
+	t.Parallel()
+
+	srv, _, _ := newKOSyncServer(t, mustNewUser(t))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withKOReaderAuth(httptest.NewRequest(http.MethodGet, "/kosync/users/auth", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"authorized":"OK"}`, rec.Body.String())
+
+	req := withKOReaderAuth(httptest.NewRequest(http.MethodGet, "/kosync/users/auth", nil))
+	req.Header.Set("X-Auth-Key", "wrong")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.JSONEq(t, `{"code":2001,"message":"Unauthorized"}`, rec.Body.String())
+}
+
+func TestServer_kosyncCreateUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		registerErr    error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "created", expectedStatus: http.StatusCreated, expectedBody: `{"username":"user1"}`},
+		{name: "taken", registerErr: auth.ErrUserNameTaken, expectedStatus: http.StatusPaymentRequired, expectedBody: `{"code":2002,"message":"Username is already registered."}`},
+		{name: "disabled", registerErr: auth.ErrRegistrationDisabled, expectedStatus: http.StatusForbidden, expectedBody: `{"code":2003,"message":"Invalid request"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			user := mustNewUser(t)
+			srv, authApp, _ := newKOSyncServer(t, user)
+			authApp.On("RegisterKOReader", mock.Anything, "user1", testKOReaderKey).Return(user, tt.registerErr)
+
+			body := `{"username":"user1","password":"` + testKOReaderKey + `"}`
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/kosync/users/create", strings.NewReader(body)))
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestServer_kosyncSaveProgress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		path            string
+		progress        string
+		expectedLocator domain.Locator
+	}{
+		{
+			name:            "epub xpointer",
+			path:            "Books/Author/Book/book.epub",
+			progress:        "/body/DocFragment[12]/body/p[3]/text().0",
+			expectedLocator: domain.Locator{Kind: domain.LocatorXPointer, XPointer: "/body/DocFragment[12]/body/p[3]/text().0", Percentage: 0.5},
+		},
+		{
+			name:            "pdf page",
+			path:            "Manga/Attack on Titan (2012)/Attack on Titan #001 (2012).pdf",
+			progress:        "42",
+			expectedLocator: domain.Locator{Kind: domain.LocatorPDFPage, Page: 42, Percentage: 0.5},
+		},
+		{
+			name:            "comic pages are 1-based in KOReader",
+			path:            "Comics/Plastic Man (1944)/Plastic Man #002 (1944).cbz",
+			progress:        "1",
+			expectedLocator: domain.Locator{Kind: domain.LocatorComicPage, Page: 0, Percentage: 0.5},
+		},
+		{
+			name:            "unknown progress keeps the percentage",
+			path:            "Books/Author/Book/book.epub",
+			progress:        "42",
+			expectedLocator: domain.Locator{Kind: domain.LocatorPercentage, Percentage: 0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			user := mustNewUser(t)
+			srv, _, progressApp := newKOSyncServer(t, user)
+			item := newKOSyncItem(tt.path)
+			saved, err := domain.NewReadingProgress(user.ID(), item.ID(), tt.expectedLocator, "Kobo", time.Unix(1700000000, 0))
+			require.NoError(t, err)
+			progressApp.On("FindDocument", mock.Anything, testDocument).Return(item, nil)
+			progressApp.On("SaveProgress", mock.Anything, progress.SaveProgressCmd{
+				ItemID:    item.ID(),
+				Locator:   tt.expectedLocator,
+				Device:    "Kobo",
+				Overwrite: true,
+			}).Return(progress.SaveProgressResult{Progress: saved}, nil)
+
+			body, err := json.Marshal(kosyncProgressRequest{
+				Document: testDocument, Progress: tt.progress, Percentage: 0.5, Device: "Kobo", DeviceID: "ABCDEF",
+			})
+			require.NoError(t, err)
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withKOReaderAuth(httptest.NewRequest(http.MethodPut, "/kosync/syncs/progress", strings.NewReader(string(body)))))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.JSONEq(t, `{"document":"`+testDocument+`","timestamp":1700000000}`, rec.Body.String())
+		})
+	}
+
+	t.Run("document not in library", func(t *testing.T) {
+		t.Parallel()
+		srv, _, progressApp := newKOSyncServer(t, mustNewUser(t))
+		progressApp.On("FindDocument", mock.Anything, testDocument).Return(nil, domain.ErrLibraryItemNotFound)
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, withKOReaderAuth(httptest.NewRequest(http.MethodPut, "/kosync/syncs/progress",
+			strings.NewReader(`{"document":"`+testDocument+`","progress":"1","percentage":0.1,"device":"Kobo","device_id":"A"}`))))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("missing document", func(t *testing.T) {
+		t.Parallel()
+		srv, _, _ := newKOSyncServer(t, mustNewUser(t))
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, withKOReaderAuth(httptest.NewRequest(http.MethodPut, "/kosync/syncs/progress", strings.NewReader(`{"progress":"1"}`))))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.JSONEq(t, `{"code":2004,"message":"Field 'document' not provided."}`, rec.Body.String())
+	})
+}
+
+func TestServer_kosyncGetProgress(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	item := newKOSyncItem("Manga/Attack on Titan (2012)/Attack on Titan #001 (2012).pdf")
+	p, err := domain.NewReadingProgress(user.ID(), item.ID(), domain.Locator{Kind: domain.LocatorPDFPage, Page: 7, Percentage: 0.25}, "web", time.Unix(1700000000, 0))
+	require.NoError(t, err)
+	unread := newKOSyncItem("Books/Author/Book/book.epub")
+
+	srv, _, progressApp := newKOSyncServer(t, user)
+	progressApp.On("FindDocument", mock.Anything, testDocument).Return(item, nil)
+	progressApp.On("FindDocument", mock.Anything, "unread").Return(unread, nil)
+	progressApp.On("FindDocument", mock.Anything, "unknown").Return(nil, domain.ErrLibraryItemNotFound)
+	progressApp.On("GetProgress", mock.Anything, item.ID()).Return(p, nil)
+	progressApp.On("GetProgress", mock.Anything, unread.ID()).Return(nil, domain.ErrReadingProgressNotFound)
+
+	tests := []struct {
+		document     string
+		expectedBody string
+	}{
+		{
+			document:     testDocument,
+			expectedBody: `{"document":"` + testDocument + `","progress":"7","percentage":0.25,"device":"web","timestamp":1700000000}`,
+		},
+		{document: "unread", expectedBody: `{}`},
+		{document: "unknown", expectedBody: `{}`},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, withKOReaderAuth(httptest.NewRequest(http.MethodGet, "/kosync/syncs/progress/"+tt.document, nil)))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.JSONEq(t, tt.expectedBody, rec.Body.String(), tt.document)
+	}
+}
+
+func TestServer_setKOReaderPassword(t *testing.T) {
+	t.Parallel()
+
+	srv, authApp := newAuthedServer(t, mustNewUser(t))
+	authApp.On("SetKOReaderPassword", mock.Anything, "e-ink-reader").Return(nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodPut, "/api/v1/auth/koreader", strings.NewReader(`{"password":"e-ink-reader"}`))))
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	authApp.AssertExpectations(t)
+}
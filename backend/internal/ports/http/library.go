@@ -0,0 +1,20 @@
+package http_port
+
+import (
+	"context"
+	"net/http"
+)
+
+type SyncApp interface {
+	TriggerScan(context.Context) error
+}
+
+// triggerScan handles POST /api/v1/library/scan, it responds once the scan is finished.
+func (s *Server) triggerScan(w http.ResponseWriter, r *http.Request) {
+	if err := s.SyncApp.TriggerScan(r.Context()); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
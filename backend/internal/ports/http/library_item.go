@@ -0,0 +1,343 @@
+package http_port
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/ARUMANDESU/validation/is"
+	"github.com/gofrs/uuid"
+
+	library_item "github.com/ARUMANDESU/goread/backend/internal/app/library-item"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type LibraryItemApp interface {
+	ListLibraryItems(context.Context, library_item.ListLibraryItemsQuery) (library_item.LibraryItemPage, error)
+	GetLibraryItem(context.Context, domain.LibraryItemID) (library_item.LibraryItemView, error)
+	ListFacetValues(context.Context, library_item.ListFacetValuesQuery) (library_item.FacetValuePage, error)
+	EditMetadata(context.Context, library_item.EditMetadataCmd) (library_item.LibraryItemView, error)
+	ListMetadataAudit(context.Context, domain.LibraryItemID) ([]*domain.MetadataAuditEntry, error)
+	DeleteLibraryItem(context.Context, domain.LibraryItemID) error
+}
+
+type authorResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type contributorResponse struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+type libraryItemResponse struct {
+	ID           string                `json:"id"`
+	Title        string                `json:"title"`
+	Type         string                `json:"type"`
+	Authors      []authorResponse      `json:"authors"`
+	Contributors []contributorResponse `json:"contributors"`
+	SeriesID     string                `json:"series_id,omitempty"`
+	Series       string                `json:"series,omitempty"`
+	SeriesIndex  float64               `json:"series_index,omitempty"`
+	Genre        []string              `json:"genre"`
+	Languages    []string              `json:"languages"`
+	Annotation   string                `json:"annotation,omitempty"`
+	Tags         []string              `json:"tags"`
+	AgeRating    string                `json:"age_rating,omitempty"`
+	// PublicationModified is when the publication was last changed as the file says.
+	PublicationModified *time.Time `json:"publication_modified,omitempty"`
+	Readable            bool       `json:"readable"`
+	DRM                 string     `json:"drm,omitempty"`
+	AddedAt             time.Time  `json:"added_at"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+	LastReadAt          *time.Time `json:"last_read_at,omitempty"`
+	// LockedFields were edited by hand, rescans leave them alone.
+	LockedFields []domain.MetadataField `json:"locked_fields"`
+}
+
+type libraryItemPageResponse struct {
+	Items      []libraryItemResponse `json:"items"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+func newLibraryItemResponse(item library_item.LibraryItemView) libraryItemResponse {
+	authors := make([]authorResponse, len(item.Authors))
+	for i, a := range item.Authors {
+		authors[i] = authorResponse{ID: a.ID.String(), Name: a.Name}
+	}
+	contributors := make([]contributorResponse, len(item.Contributors))
+	for i, c := range item.Contributors {
+		contributors[i] = contributorResponse{Name: c.Name, Role: c.Role}
+	}
+	var seriesID string
+	if !item.SeriesID.IsNil() {
+		seriesID = item.SeriesID.String()
+	}
+	var publicationModified *time.Time
+	if !item.PublicationModified.IsZero() {
+		publicationModified = &item.PublicationModified
+	}
+	return libraryItemResponse{
+		ID:                  item.ID.String(),
+		Title:               item.Title,
+		Type:                string(item.Type),
+		Authors:             authors,
+		Contributors:        contributors,
+		SeriesID:            seriesID,
+		Series:              item.Series,
+		SeriesIndex:         item.SeriesIndex,
+		Genre:               item.Genre,
+		Languages:           item.Languages,
+		Annotation:          item.Annotation,
+		Tags:                item.Tags,
+		AgeRating:           string(item.AgeRating),
+		PublicationModified: publicationModified,
+		Readable:            item.Readable(),
+		DRM:                 item.DRM,
+		AddedAt:             item.AddedAt,
+		DeletedAt:           item.DeletedAt,
+		LastReadAt:          item.LastReadAt,
+		LockedFields:        item.LockedFields,
+	}
+}
+
+// listLibraryItems handles GET /api/v1/library-items
+//
+// query params:
+//   - type: book|manga|comic, repeatable or comma separated
+//   - author: author id
+//   - series: series id
+//   - genre, language, title_prefix
+//   - q: words of the title or author names
+//   - deleted: not_deleted (default)|only_deleted|any, the others need the delete_items permission
+//   - sort: added (default)|title|last_read
+//   - order: asc|desc
+//   - cursor: next_cursor of the previous page
+//   - limit: 1..100, default 20
+func (s *Server) listLibraryItems(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListLibraryItemsQuery(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	page, err := s.LibraryItemApp.ListLibraryItems(r.Context(), query)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res := libraryItemPageResponse{
+		Items:      make([]libraryItemResponse, len(page.Items)),
+		NextCursor: page.NextCursor,
+	}
+	for i, item := range page.Items {
+		res.Items[i] = newLibraryItemResponse(item)
+	}
+
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// getLibraryItem handles GET /api/v1/library-items/{id}
+func (s *Server) getLibraryItem(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	item, err := s.LibraryItemApp.GetLibraryItem(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newLibraryItemResponse(item))
+}
+
+// seriesRefDTO places an item in the series with the name, an empty name takes it out of its series.
+type seriesRefDTO struct {
+	Name  string  `json:"name"`
+	Index float64 `json:"index"`
+}
+
+type itemSeriesResponse struct {
+	ID    string  `json:"id"`
+	Index float64 `json:"index"`
+}
+
+// editMetadataRequest leaves fields that are missing or null as they are.
+type editMetadataRequest struct {
+	Title      *string                 `json:"title"`
+	Type       *domain.LibraryItemType `json:"type"`
+	Authors    []string                `json:"authors"`
+	Genre      []string                `json:"genre"`
+	Languages  []string                `json:"languages"`
+	Annotation *string                 `json:"annotation"`
+	Series     *seriesRefDTO           `json:"series"`
+	AgeRating  *domain.AgeRating       `json:"age_rating"`
+	Unlock     []domain.MetadataField  `json:"unlock"`
+}
+
+type metadataChangeResponse struct {
+	Field domain.MetadataField `json:"field"`
+	Old   any                  `json:"old"`
+	New   any                  `json:"new"`
+}
+
+type metadataAuditEntryResponse struct {
+	ID      string                   `json:"id"`
+	UserID  string                   `json:"user_id"`
+	Changes []metadataChangeResponse `json:"changes"`
+	At      time.Time                `json:"at"`
+}
+
+func newMetadataChangeValue(v any) any {
+	switch v := v.(type) {
+	case domain.ItemSeries:
+		if v == (domain.ItemSeries{}) {
+			return nil
+		}
+		return itemSeriesResponse{ID: v.ID.String(), Index: v.Index}
+	case []domain.AuthorID:
+		ids := make([]string, len(v))
+		for i, id := range v {
+			ids[i] = id.String()
+		}
+		return ids
+	}
+	return v
+}
+
+// editMetadata handles PATCH /api/v1/library-items/{id}
+//
+// Edited fields are locked, fields in unlock may be changed by rescans again.
+func (s *Server) editMetadata(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	var req editMetadataRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	cmd := library_item.EditMetadataCmd{
+		ItemID:     id,
+		Title:      req.Title,
+		ItemType:   req.Type,
+		Authors:    req.Authors,
+		Genre:      req.Genre,
+		Languages:  req.Languages,
+		Annotation: req.Annotation,
+		AgeRating:  req.AgeRating,
+		Unlock:     req.Unlock,
+	}
+	if req.Series != nil {
+		cmd.Series = &library_item.SeriesRef{Name: req.Series.Name, Index: req.Series.Index}
+	}
+
+	item, err := s.LibraryItemApp.EditMetadata(r.Context(), cmd)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newLibraryItemResponse(item))
+}
+
+// deleteLibraryItem handles DELETE /api/v1/library-items/{id}
+func (s *Server) deleteLibraryItem(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	if err := s.LibraryItemApp.DeleteLibraryItem(r.Context(), id); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listMetadataAudit handles GET /api/v1/library-items/{id}/metadata-history, the latest change first.
+func (s *Server) listMetadataAudit(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	entries, err := s.LibraryItemApp.ListMetadataAudit(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res := make([]metadataAuditEntryResponse, len(entries))
+	for i, e := range entries {
+		changes := make([]metadataChangeResponse, len(e.Changes()))
+		for j, c := range e.Changes() {
+			changes[j] = metadataChangeResponse{Field: c.Field, Old: newMetadataChangeValue(c.Old), New: newMetadataChangeValue(c.New)}
+		}
+		res[i] = metadataAuditEntryResponse{ID: e.ID().String(), UserID: e.UserID().String(), Changes: changes, At: e.At()}
+	}
+
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+func parseListLibraryItemsQuery(r *http.Request) (library_item.ListLibraryItemsQuery, error) {
+	values := r.URL.Query()
+	q := library_item.ListLibraryItemsQuery{
+		Genre:       values.Get("genre"),
+		Language:    values.Get("language"),
+		TitlePrefix: values.Get("title_prefix"),
+		Search:      values.Get("q"),
+		Deleted:     library_item.DeletedFilter(values.Get("deleted")),
+		SortBy:      library_item.SortField(values.Get("sort")),
+		SortOrder:   library_item.SortOrder(values.Get("order")),
+		Cursor:      values.Get("cursor"),
+	}
+
+	for _, raw := range values["type"] {
+		for t := range strings.SplitSeq(raw, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				q.Types = append(q.Types, domain.LibraryItemType(t))
+			}
+		}
+	}
+
+	errs := v.Errors{}
+	if raw := values.Get("author"); raw != "" {
+		id, err := uuid.FromString(raw)
+		if err != nil {
+			errs["author"] = is.ErrUUID
+		}
+		q.AuthorID = id
+	}
+	if raw := values.Get("series"); raw != "" {
+		id, err := uuid.FromString(raw)
+		if err != nil {
+			errs["series"] = is.ErrUUID
+		}
+		q.SeriesID = id
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			errs["limit"] = is.ErrInt
+		}
+		q.Limit = limit
+	}
+
+	return q, errs.Filter()
+}
@@ -0,0 +1,276 @@
+package http_port
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	library_item "github.com/ARUMANDESU/goread/backend/internal/app/library-item"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type mockLibraryItemApp struct{ mock.Mock }
+
+func (m *mockLibraryItemApp) ListLibraryItems(ctx context.Context, q library_item.ListLibraryItemsQuery) (library_item.LibraryItemPage, error) {
+	args := m.Called(ctx, q)
+	p, _ := args.Get(0).(library_item.LibraryItemPage)
+	return p, args.Error(1)
+}
+
+func (m *mockLibraryItemApp) GetLibraryItem(ctx context.Context, id domain.LibraryItemID) (library_item.LibraryItemView, error) {
+	args := m.Called(ctx, id)
+	item, _ := args.Get(0).(library_item.LibraryItemView)
+	return item, args.Error(1)
+}
+
+func (m *mockLibraryItemApp) ListFacetValues(ctx context.Context, q library_item.ListFacetValuesQuery) (library_item.FacetValuePage, error) {
+	args := m.Called(ctx, q)
+	p, _ := args.Get(0).(library_item.FacetValuePage)
+	return p, args.Error(1)
+}
+
+func (m *mockLibraryItemApp) EditMetadata(ctx context.Context, cmd library_item.EditMetadataCmd) (library_item.LibraryItemView, error) {
+	args := m.Called(ctx, cmd)
+	v, _ := args.Get(0).(library_item.LibraryItemView)
+	return v, args.Error(1)
+}
+
+func (m *mockLibraryItemApp) ListMetadataAudit(ctx context.Context, id domain.LibraryItemID) ([]*domain.MetadataAuditEntry, error) {
+	args := m.Called(ctx, id)
+	entries, _ := args.Get(0).([]*domain.MetadataAuditEntry)
+	return entries, args.Error(1)
+}
+
+func (m *mockLibraryItemApp) DeleteLibraryItem(ctx context.Context, id domain.LibraryItemID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func TestServer_listLibraryItems(t *testing.T) {
+	t.Parallel()
+
+	authorID, seriesID := domain.NewAuthorID(), domain.NewSeriesID()
+	item := library_item.LibraryItemView{
+		ID:                  domain.NewLibraryItemID(),
+		Title:               "Воин",
+		Type:                domain.Book,
+		Authors:             []library_item.AuthorView{{ID: authorID, Name: "Роберт Сальваторе"}},
+		SeriesID:            seriesID,
+		Series:              "Темный эльф",
+		SeriesIndex:         3,
+		Contributors:        []domain.Contributor{{Name: "Александр Фишман", Role: "trl"}},
+		PublicationModified: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	user := mustNewUser(t)
+	app := new(mockLibraryItemApp)
+	app.On("ListLibraryItems", mock.Anything, library_item.ListLibraryItemsQuery{
+		Types:       []domain.LibraryItemType{domain.Book, domain.Manga, domain.Comic},
+		AuthorID:    authorID,
+		SeriesID:    seriesID,
+		Genre:       "sf_fantasy",
+		Language:    "ru",
+		TitlePrefix: "Во",
+		Deleted:     library_item.AnyDeleted,
+		SortBy:      library_item.SortByTitle,
+		SortOrder:   library_item.Asc,
+		Cursor:      "abc",
+		Limit:       5,
+	}).Return(library_item.LibraryItemPage{Items: []library_item.LibraryItemView{item}, NextCursor: "next"}, nil)
+
+	srv, _ := newAuthedServer(t, user)
+	srv.LibraryItemApp = app
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/library-items?type=book,manga&type=comic&author="+authorID.String()+"&series="+seriesID.String()+
+		"&genre=sf_fantasy&language=ru&title_prefix=%D0%92%D0%BE&deleted=any&sort=title&order=asc&cursor=abc&limit=5", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(req))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res libraryItemPageResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, "next", res.NextCursor)
+	require.Len(t, res.Items, 1)
+	assert.Equal(t, item.ID.String(), res.Items[0].ID)
+	assert.Equal(t, "Воин", res.Items[0].Title)
+	assert.Equal(t, []authorResponse{{ID: authorID.String(), Name: "Роберт Сальваторе"}}, res.Items[0].Authors)
+	assert.Equal(t, seriesID.String(), res.Items[0].SeriesID)
+	assert.Equal(t, 3.0, res.Items[0].SeriesIndex)
+	assert.Equal(t, []contributorResponse{{Name: "Александр Фишман", Role: "trl"}}, res.Items[0].Contributors)
+	require.NotNil(t, res.Items[0].PublicationModified)
+	assert.True(t, item.PublicationModified.Equal(*res.Items[0].PublicationModified))
+}
+
+func TestServer_listLibraryItems_invalidParams(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	srv.LibraryItemApp = new(mockLibraryItemApp)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/library-items?author=nope&series=nope&limit=ten", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(req))
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var res errorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Contains(t, res.Fields, "author")
+	assert.Contains(t, res.Fields, "series")
+	assert.Contains(t, res.Fields, "limit")
+}
+
+func TestServer_getLibraryItem(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	found := domain.NewLibraryItemID()
+	missing := domain.NewLibraryItemID()
+
+	app := new(mockLibraryItemApp)
+	app.On("GetLibraryItem", mock.Anything, found).
+		Return(library_item.LibraryItemView{ID: found, Title: "Воин", Type: domain.Book}, nil)
+	app.On("GetLibraryItem", mock.Anything, missing).
+		Return(nil, domain.ErrLibraryItemNotFound)
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "found", id: found.String(), status: http.StatusOK},
+		{name: "not found", id: missing.String(), status: http.StatusNotFound},
+		{name: "malformed id", id: "42", status: http.StatusNotFound},
+		{name: "anonymous", id: found.String(), status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, _ := newAuthedServer(t, user)
+			srv.LibraryItemApp = app
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+tt.id, nil)
+			if tt.status != http.StatusUnauthorized {
+				req = withSession(req)
+			}
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestServer_editMetadata(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	id := domain.NewLibraryItemID()
+	title, teen := "Воин (Темный эльф)", domain.AgeRatingTeen
+	cmd := library_item.EditMetadataCmd{
+		ItemID:    id,
+		Title:     &title,
+		Authors:   []string{"Роберт Сальваторе"},
+		Genre:     []string{},
+		Series:    &library_item.SeriesRef{Name: "Темный эльф", Index: 3},
+		AgeRating: &teen,
+		Unlock:    []domain.MetadataField{domain.FieldLanguages},
+	}
+	view := library_item.LibraryItemView{
+		ID:           id,
+		Title:        title,
+		Type:         domain.Book,
+		Series:       "Темный эльф",
+		SeriesIndex:  3,
+		LockedFields: []domain.MetadataField{domain.FieldAuthors, domain.FieldGenre, domain.FieldSeries, domain.FieldTitle},
+	}
+
+	srv, _ := newAuthedServer(t, user)
+	app := new(mockLibraryItemApp)
+	srv.LibraryItemApp = app
+	app.On("EditMetadata", mock.Anything, cmd).Return(view, nil)
+
+	body := `{"title":"Воин (Темный эльф)","authors":["Роберт Сальваторе"],"genre":[],"annotation":null,` +
+		`"series":{"name":"Темный эльф","index":3},"age_rating":"Teen","unlock":["languages"]}`
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodPatch, "/api/v1/library-items/"+id.String(), strings.NewReader(body))))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res libraryItemResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, title, res.Title)
+	assert.Equal(t, 3.0, res.SeriesIndex)
+	assert.Equal(t, view.LockedFields, res.LockedFields)
+	app.AssertExpectations(t)
+}
+
+func TestServer_deleteLibraryItem(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	id, hidden := domain.NewLibraryItemID(), domain.NewLibraryItemID()
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "deleted", id: id.String(), status: http.StatusNoContent},
+		{name: "not permitted", id: hidden.String(), status: http.StatusForbidden},
+		{name: "invalid id", id: "nope", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, _ := newAuthedServer(t, user)
+			app := new(mockLibraryItemApp)
+			srv.LibraryItemApp = app
+			app.On("DeleteLibraryItem", mock.Anything, id).Return(nil)
+			app.On("DeleteLibraryItem", mock.Anything, hidden).Return(domain.ErrPermissionDenied)
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodDelete, "/api/v1/library-items/"+tt.id, nil)))
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestServer_listMetadataAudit(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	id := domain.NewLibraryItemID()
+	authorID, seriesID := domain.NewAuthorID(), domain.NewSeriesID()
+	entry, err := domain.NewMetadataAuditEntry(id, user.ID(), []domain.MetadataChange{
+		{Field: domain.FieldTitle, Old: "Воин", New: "Воин (Темный эльф)"},
+		{Field: domain.FieldAuthors, Old: []domain.AuthorID{}, New: []domain.AuthorID{authorID}},
+		{Field: domain.FieldSeries, Old: domain.ItemSeries{}, New: domain.ItemSeries{ID: seriesID, Index: 3}},
+	})
+	require.NoError(t, err)
+
+	srv, _ := newAuthedServer(t, user)
+	app := new(mockLibraryItemApp)
+	srv.LibraryItemApp = app
+	app.On("ListMetadataAudit", mock.Anything, id).Return([]*domain.MetadataAuditEntry{entry}, nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+id.String()+"/metadata-history", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `[{
+		"id": "`+entry.ID().String()+`",
+		"user_id": "`+user.ID().String()+`",
+		"at": "`+entry.At().Format(time.RFC3339Nano)+`",
+		"changes": [
+			{"field": "title", "old": "Воин", "new": "Воин (Темный эльф)"},
+			{"field": "authors", "old": [], "new": ["`+authorID.String()+`"]},
+			{"field": "series", "old": null, "new": {"id": "`+seriesID.String()+`", "index": 3}}
+		]
+	}]`, rec.Body.String())
+}
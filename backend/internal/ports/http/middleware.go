@@ -0,0 +1,18 @@
+package http_port
+
+import (
+	"net/http"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/i18nx"
+)
+
+// withLanguage stores the language negotiated from Accept-Language into the request context.
+func (s *Server) withLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Translator != nil {
+			tag := s.Translator.Match(r.Header.Get("Accept-Language"))
+			r = r.WithContext(i18nx.WithLanguage(r.Context(), tag))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,392 @@
+package http_port
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/ARUMANDESU/validation/is"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	library_item "github.com/ARUMANDESU/goread/backend/internal/app/library-item"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+// OPDS catalogs for e-reader apps, see https://specs.opds.io. OPDS 1.2 (Atom) is served under /opds
+// and OPDS 2.0 (JSON) under /opds/v2, both are built from the same navigation and acquisition feeds.
+// Apps authenticate with HTTP Basic, an API token as the password.
+
+const (
+	opdsNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	opdsAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	openSearchType      = "application/opensearchdescription+xml"
+	opds2Type           = "application/opds+json"
+
+	opdsRelAcquisition = "http://opds-spec.org/acquisition"
+	opdsRelImage       = "http://opds-spec.org/image"
+	opdsRelThumbnail   = "http://opds-spec.org/image/thumbnail"
+
+	// OPDS Page Streaming Extension, see https://anansi-project.github.io/docs/opds-pse/specs/v1.2
+	pseNamespace = "http://vaemendis.net/opds-pse/ns"
+	pseRelStream = "http://vaemendis.net/opds-pse/stream"
+)
+
+// opdsCatalog renders the feeds in one OPDS version. Hrefs of opdsNavEntry are relative to base.
+type opdsCatalog interface {
+	base() string
+	navigation(w http.ResponseWriter, r *http.Request, title string, entries []opdsNavEntry, nextCursor string)
+	acquisition(w http.ResponseWriter, r *http.Request, title string, page library_item.LibraryItemPage)
+}
+
+type opdsNavEntry struct {
+	ID    string
+	Title string
+	Href  string
+	// Acquisition is true if Href leads to an acquisition feed.
+	Acquisition bool
+	// Count is the number of items behind the entry, zero if unknown.
+	Count int
+}
+
+type opdsFacet struct {
+	path  string
+	facet library_item.Facet
+	// param is the query param of /items filtering by the facet.
+	param string
+	title string
+}
+
+var opdsFacets = []opdsFacet{
+	{path: "/authors", facet: library_item.FacetAuthor, param: "author", title: "Authors"},
+	{path: "/series", facet: library_item.FacetSeries, param: "series", title: "Series"},
+	{path: "/genres", facet: library_item.FacetGenre, param: "genre", title: "Genres"},
+	{path: "/languages", facet: library_item.FacetLanguage, param: "language", title: "Languages"},
+}
+
+func (s *Server) opdsRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /opds/search.xml", s.requireOPDSUser(s.openSearchDescription))
+	for _, c := range []opdsCatalog{atomCatalog{}, opds2Catalog{}} {
+		mux.HandleFunc("GET "+c.base(), s.requireOPDSUser(s.opdsRoot(c)))
+		mux.HandleFunc("GET "+c.base()+"/items", s.requireOPDSUser(s.opdsItems(c)))
+		mux.HandleFunc("GET "+c.base()+"/types", s.requireOPDSUser(s.opdsTypes(c)))
+		for _, f := range opdsFacets {
+			mux.HandleFunc("GET "+c.base()+f.path, s.requireOPDSUser(s.opdsFacetValues(c, f)))
+		}
+	}
+}
+
+// opdsRoot handles GET /opds and GET /opds/v2, the start of the catalog.
+func (s *Server) opdsRoot(c opdsCatalog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries := []opdsNavEntry{
+			{ID: "recent", Title: "Recently added", Href: "/items?sort=added&order=desc", Acquisition: true},
+			{ID: "titles", Title: "All titles", Href: "/items?sort=title", Acquisition: true},
+			{ID: "types", Title: "Types", Href: "/types"},
+		}
+		for _, f := range opdsFacets {
+			entries = append(entries, opdsNavEntry{ID: string(f.facet), Title: f.title, Href: f.path})
+		}
+		c.navigation(w, r, "goread", entries, "")
+	}
+}
+
+// opdsItems handles GET /opds/items and GET /opds/v2/items, it takes the query params of
+// GET /api/v1/library-items.
+func (s *Server) opdsItems(c opdsCatalog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseListLibraryItemsQuery(r)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		page, err := s.LibraryItemApp.ListLibraryItems(r.Context(), query)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		title := "Library"
+		if query.Search != "" {
+			title = "Search: " + query.Search
+		}
+		c.acquisition(w, r, title, page)
+	}
+}
+
+// opdsTypes handles GET /opds/types and GET /opds/v2/types
+func (s *Server) opdsTypes(c opdsCatalog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries := []opdsNavEntry{
+			{ID: "type:book", Title: "Books", Href: "/items?type=book", Acquisition: true},
+			{ID: "type:manga", Title: "Manga", Href: "/items?type=manga", Acquisition: true},
+			{ID: "type:comic", Title: "Comics", Href: "/items?type=comic", Acquisition: true},
+		}
+		c.navigation(w, r, "Types", entries, "")
+	}
+}
+
+// opdsFacetValues handles GET /opds/{authors,series,genres,languages} and their /opds/v2 equivalents.
+//
+// query params:
+//   - cursor: from the next link of the previous page
+//   - limit: 1..100, default 20
+func (s *Server) opdsFacetValues(c opdsCatalog, f opdsFacet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := library_item.ListFacetValuesQuery{Facet: f.facet, Cursor: r.URL.Query().Get("cursor")}
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil {
+				s.writeError(w, r, v.Errors{"limit": is.ErrInt})
+				return
+			}
+			query.Limit = limit
+		}
+
+		page, err := s.LibraryItemApp.ListFacetValues(r.Context(), query)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		entries := make([]opdsNavEntry, len(page.Values))
+		for i, value := range page.Values {
+			entries[i] = opdsNavEntry{
+				ID:          string(f.facet) + ":" + value.Value,
+				Title:       value.Label,
+				Href:        "/items?" + url.Values{f.param: {value.Value}}.Encode(),
+				Acquisition: true,
+				Count:       value.Count,
+			}
+		}
+		c.navigation(w, r, f.title, entries, page.NextCursor)
+	}
+}
+
+// requireOPDSUser challenges anonymous requests with HTTP Basic, which every OPDS app supports.
+func (s *Server) requireOPDSUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.UserFromContext(r.Context()); err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="goread", charset="UTF-8"`)
+			s.writeError(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// canDownload reports whether the request may use the acquisition links of items.
+func canDownload(r *http.Request) bool {
+	_, err := auth.Authorize(r.Context(), domain.PermDownload)
+	return err == nil
+}
+
+func itemDownloadHref(id domain.LibraryItemID) string {
+	return "/api/v1/library-items/" + id.String() + "/download"
+}
+
+// itemEPUBDownloadHref is the alternate acquisition of FB2 and text books, for readers such as
+// Kobo and Apple Books that only open EPUB.
+func itemEPUBDownloadHref(id domain.LibraryItemID) string {
+	return itemDownloadHref(id) + "?format=" + string(domain.FormatEPUB)
+}
+
+// itemPagesHref is templated with the {pageNumber} and {maxWidth} of OPDS-PSE.
+func itemPagesHref(id domain.LibraryItemID) string {
+	return "/api/v1/library-items/" + id.String() + "/pages/{pageNumber}?max_width={maxWidth}"
+}
+
+func itemCoverHref(id domain.LibraryItemID) string {
+	return "/api/v1/library-items/" + id.String() + "/cover"
+}
+
+func itemThumbnailHref(id domain.LibraryItemID) string {
+	return itemCoverHref(id) + "?size=thumbnail"
+}
+
+// pageHref returns the URL of r with the cursor param replaced.
+func pageHref(r *http.Request, cursor string) string {
+	values := r.URL.Query()
+	values.Set("cursor", cursor)
+	return r.URL.Path + "?" + values.Encode()
+}
+
+// --- OPDS 1.2 ---
+
+type atomCatalog struct{}
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	XMLNSDC   string      `xml:"xmlns:dc,attr"`
+	XMLNSOPDS string      `xml:"xmlns:opds,attr"`
+	XMLNSPSE  string      `xml:"xmlns:pse,attr"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   time.Time   `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+	// PSECount is the page count of pse:stream links.
+	PSECount int `xml:"pse:count,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    time.Time      `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author"`
+	Languages  []string       `xml:"dc:language"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    *atomContent   `xml:"content"`
+	Links      []atomLink     `xml:"link"`
+}
+
+func (atomCatalog) base() string { return "/opds" }
+
+func (c atomCatalog) feed(r *http.Request, title, kind string) atomFeed {
+	return atomFeed{
+		XMLNSDC:   "http://purl.org/dc/terms/",
+		XMLNSOPDS: "http://opds-spec.org/2010/catalog",
+		XMLNSPSE:  pseNamespace,
+		ID:        "urn:goread:opds:" + r.URL.RequestURI(),
+		Title:     title,
+		Updated:   time.Now().UTC().Truncate(time.Second),
+		Links: []atomLink{
+			{Rel: "self", Href: r.URL.RequestURI(), Type: kind},
+			{Rel: "start", Href: c.base(), Type: opdsNavigationType},
+			{Rel: "search", Href: c.base() + "/search.xml", Type: openSearchType},
+		},
+	}
+}
+
+func (c atomCatalog) navigation(w http.ResponseWriter, r *http.Request, title string, entries []opdsNavEntry, nextCursor string) {
+	feed := c.feed(r, title, opdsNavigationType)
+	if nextCursor != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "next", Href: pageHref(r, nextCursor), Type: opdsNavigationType})
+	}
+	for _, e := range entries {
+		kind := opdsNavigationType
+		if e.Acquisition {
+			kind = opdsAcquisitionType
+		}
+		entry := atomEntry{
+			ID:      "urn:goread:opds:" + e.ID,
+			Title:   e.Title,
+			Updated: feed.Updated,
+			Links:   []atomLink{{Rel: "subsection", Href: c.base() + e.Href, Type: kind}},
+		}
+		if e.Count > 0 {
+			entry.Content = &atomContent{Type: "text", Text: fmt.Sprintf("Items: %d", e.Count)}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	writeXML(w, r, opdsNavigationType, feed)
+}
+
+func (c atomCatalog) acquisition(w http.ResponseWriter, r *http.Request, title string, page library_item.LibraryItemPage) {
+	feed := c.feed(r, title, opdsAcquisitionType)
+	if page.NextCursor != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "next", Href: pageHref(r, page.NextCursor), Type: opdsAcquisitionType})
+	}
+
+	download := canDownload(r)
+	for _, item := range page.Items {
+		entry := atomEntry{
+			ID:        "urn:uuid:" + item.ID.String(),
+			Title:     item.Title,
+			Updated:   item.AddedAt.UTC(),
+			Languages: item.Languages,
+			Summary:   item.Annotation,
+			Links: []atomLink{
+				{Rel: opdsRelImage, Href: itemCoverHref(item.ID), Type: "image/jpeg"},
+				{Rel: opdsRelThumbnail, Href: itemThumbnailHref(item.ID), Type: "image/jpeg"},
+			},
+		}
+		for _, a := range item.Authors {
+			entry.Authors = append(entry.Authors, atomAuthor{
+				Name: a.Name,
+				URI:  c.base() + "/items?" + url.Values{"author": {a.ID.String()}}.Encode(),
+			})
+		}
+		for _, g := range item.Genre {
+			entry.Categories = append(entry.Categories, atomCategory{Term: g, Label: g})
+		}
+		if download {
+			entry.Links = append(entry.Links, atomLink{Rel: opdsRelAcquisition, Href: itemDownloadHref(item.ID), Type: item.Format.MediaType()})
+			if item.Format.ConvertsToEPUB() {
+				entry.Links = append(entry.Links, atomLink{Rel: opdsRelAcquisition, Href: itemEPUBDownloadHref(item.ID), Type: domain.FormatEPUB.MediaType()})
+			}
+		}
+		if item.Format.HasPages() && item.PageCount > 0 {
+			entry.Links = append(entry.Links, atomLink{
+				Rel:      pseRelStream,
+				Href:     itemPagesHref(item.ID),
+				Type:     "image/jpeg",
+				PSECount: item.PageCount,
+			})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	writeXML(w, r, opdsAcquisitionType, feed)
+}
+
+type openSearchDescription struct {
+	XMLName        xml.Name      `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName      string        `xml:"ShortName"`
+	Description    string        `xml:"Description"`
+	InputEncoding  string        `xml:"InputEncoding"`
+	OutputEncoding string        `xml:"OutputEncoding"`
+	URL            openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// openSearchDescription handles GET /opds/search.xml
+func (s *Server) openSearchDescription(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, r, openSearchType, openSearchDescription{
+		ShortName:      "goread",
+		Description:    "Search the goread library",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URL:            openSearchURL{Type: opdsAcquisitionType, Template: "/opds/items?q={searchTerms}"},
+	})
+}
+
+func writeXML(w http.ResponseWriter, r *http.Request, contentType string, body any) {
+	w.Header().Set("Content-Type", contentType+";charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode response body", "error", err)
+	}
+}
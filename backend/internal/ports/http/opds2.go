@@ -0,0 +1,148 @@
+package http_port
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	library_item "github.com/ARUMANDESU/goread/backend/internal/app/library-item"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+// --- OPDS 2.0 ---
+
+type opds2Catalog struct{}
+
+type opds2Feed struct {
+	Metadata     opds2FeedMetadata  `json:"metadata"`
+	Links        []opds2Link        `json:"links"`
+	Navigation   []opds2Link        `json:"navigation,omitempty"`
+	Publications []opds2Publication `json:"publications,omitempty"`
+}
+
+type opds2FeedMetadata struct {
+	Title string `json:"title"`
+}
+
+type opds2Link struct {
+	Rel        string           `json:"rel,omitempty"`
+	Href       string           `json:"href"`
+	Type       string           `json:"type,omitempty"`
+	Title      string           `json:"title,omitempty"`
+	Templated  bool             `json:"templated,omitempty"`
+	Properties *opds2Properties `json:"properties,omitempty"`
+}
+
+type opds2Properties struct {
+	NumberOfItems int `json:"numberOfItems,omitempty"`
+}
+
+type opds2Publication struct {
+	Metadata opds2PublicationMetadata `json:"metadata"`
+	Links    []opds2Link              `json:"links"`
+	Images   []opds2Link              `json:"images"`
+}
+
+type opds2PublicationMetadata struct {
+	Type        string             `json:"@type"`
+	Identifier  string             `json:"identifier"`
+	Title       string             `json:"title"`
+	Author      []opds2Contributor `json:"author,omitempty"`
+	Language    []string           `json:"language,omitempty"`
+	Subject     []string           `json:"subject,omitempty"`
+	Description string             `json:"description,omitempty"`
+	BelongsTo   *opds2BelongsTo    `json:"belongsTo,omitempty"`
+}
+
+type opds2Contributor struct {
+	Name  string      `json:"name"`
+	Links []opds2Link `json:"links,omitempty"`
+}
+
+type opds2BelongsTo struct {
+	Series []opds2Contributor `json:"series"`
+}
+
+func (opds2Catalog) base() string { return "/opds/v2" }
+
+func (c opds2Catalog) feed(r *http.Request, title string) opds2Feed {
+	return opds2Feed{
+		Metadata: opds2FeedMetadata{Title: title},
+		Links: []opds2Link{
+			{Rel: "self", Href: r.URL.RequestURI(), Type: opds2Type},
+			{Rel: "start", Href: c.base(), Type: opds2Type},
+			{Rel: "search", Href: c.base() + "/items{?q}", Type: opds2Type, Templated: true},
+		},
+	}
+}
+
+func (c opds2Catalog) navigation(w http.ResponseWriter, r *http.Request, title string, entries []opdsNavEntry, nextCursor string) {
+	feed := c.feed(r, title)
+	if nextCursor != "" {
+		feed.Links = append(feed.Links, opds2Link{Rel: "next", Href: pageHref(r, nextCursor), Type: opds2Type})
+	}
+	feed.Navigation = make([]opds2Link, len(entries))
+	for i, e := range entries {
+		feed.Navigation[i] = opds2Link{Href: c.base() + e.Href, Type: opds2Type, Title: e.Title}
+		if e.Count > 0 {
+			feed.Navigation[i].Properties = &opds2Properties{NumberOfItems: e.Count}
+		}
+	}
+	writeOPDS2(w, r, feed)
+}
+
+func (c opds2Catalog) acquisition(w http.ResponseWriter, r *http.Request, title string, page library_item.LibraryItemPage) {
+	feed := c.feed(r, title)
+	if page.NextCursor != "" {
+		feed.Links = append(feed.Links, opds2Link{Rel: "next", Href: pageHref(r, page.NextCursor), Type: opds2Type})
+	}
+
+	download := canDownload(r)
+	feed.Publications = make([]opds2Publication, len(page.Items))
+	for i, item := range page.Items {
+		pub := opds2Publication{
+			Metadata: opds2PublicationMetadata{
+				Type:        "http://schema.org/Book",
+				Identifier:  "urn:uuid:" + item.ID.String(),
+				Title:       item.Title,
+				Language:    item.Languages,
+				Subject:     item.Genre,
+				Description: item.Annotation,
+			},
+			Links: []opds2Link{},
+			Images: []opds2Link{
+				{Href: itemCoverHref(item.ID), Type: "image/jpeg"},
+				{Href: itemThumbnailHref(item.ID), Type: "image/jpeg", Rel: "thumbnail"},
+			},
+		}
+		for _, a := range item.Authors {
+			pub.Metadata.Author = append(pub.Metadata.Author, opds2Contributor{
+				Name:  a.Name,
+				Links: []opds2Link{{Href: c.base() + "/items?" + url.Values{"author": {a.ID.String()}}.Encode(), Type: opds2Type}},
+			})
+		}
+		if item.Series != "" {
+			pub.Metadata.BelongsTo = &opds2BelongsTo{Series: []opds2Contributor{{
+				Name:  item.Series,
+				Links: []opds2Link{{Href: c.base() + "/items?" + url.Values{"series": {item.SeriesID.String()}}.Encode(), Type: opds2Type}},
+			}}}
+		}
+		if download {
+			pub.Links = append(pub.Links, opds2Link{Rel: opdsRelAcquisition, Href: itemDownloadHref(item.ID), Type: item.Format.MediaType()})
+			if item.Format.ConvertsToEPUB() {
+				pub.Links = append(pub.Links, opds2Link{Rel: opdsRelAcquisition, Href: itemEPUBDownloadHref(item.ID), Type: domain.FormatEPUB.MediaType()})
+			}
+		}
+		feed.Publications[i] = pub
+	}
+	writeOPDS2(w, r, feed)
+}
+
+func writeOPDS2(w http.ResponseWriter, r *http.Request, feed opds2Feed) {
+	w.Header().Set("Content-Type", opds2Type)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(feed); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode response body", "error", err)
+	}
+}
@@ -0,0 +1,297 @@
+package http_port
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	library_item "github.com/ARUMANDESU/goread/backend/internal/app/library-item"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+func findAtomLink(links []atomLink, rel string) (atomLink, bool) {
+	for _, l := range links {
+		if l.Rel == rel {
+			return l, true
+		}
+	}
+	return atomLink{}, false
+}
+
+func TestServer_opds_anonymous(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{"/opds", "/opds/items", "/opds/v2/authors", "/opds/search.xml"} {
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+
+			srv, _ := newAuthedServer(t, mustNewUser(t))
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, `Basic realm="goread", charset="UTF-8"`, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestServer_opdsRoot(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/opds", nil)))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Type"), "kind=navigation")
+
+	var feed atomFeed
+	require.NoError(t, xml.NewDecoder(rec.Body).Decode(&feed))
+	search, ok := findAtomLink(feed.Links, "search")
+	require.True(t, ok)
+	assert.Equal(t, "/opds/search.xml", search.Href)
+
+	hrefs := make([]string, len(feed.Entries))
+	for i, e := range feed.Entries {
+		require.Len(t, e.Links, 1)
+		hrefs[i] = e.Links[0].Href
+	}
+	assert.Equal(t, []string{
+		"/opds/items?sort=added&order=desc",
+		"/opds/items?sort=title",
+		"/opds/types",
+		"/opds/authors",
+		"/opds/series",
+		"/opds/genres",
+		"/opds/languages",
+	}, hrefs)
+}
+
+func TestServer_opdsItems(t *testing.T) {
+	t.Parallel()
+
+	authorID := domain.NewAuthorID()
+	item := library_item.LibraryItemView{
+		ID:        domain.NewLibraryItemID(),
+		Title:     "Воин",
+		Type:      domain.Book,
+		Authors:   []library_item.AuthorView{{ID: authorID, Name: "Роберт Сальваторе"}},
+		Genre:     []string{"sf_fantasy"},
+		Languages: []string{"ru"},
+		Format:    domain.FormatEPUB,
+		AddedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name         string
+		role         domain.Role
+		acquisitions int
+	}{
+		{name: "member downloads", role: domain.RoleMember, acquisitions: 1},
+		{name: "guest only browses", role: domain.RoleGuest, acquisitions: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := new(mockLibraryItemApp)
+			app.On("ListLibraryItems", mock.Anything, library_item.ListLibraryItemsQuery{
+				Search: "воин",
+				Cursor: "abc",
+			}).Return(library_item.LibraryItemPage{Items: []library_item.LibraryItemView{item}, NextCursor: "next"}, nil)
+
+			srv, _ := newAuthedServer(t, mustNewUserWithRole(t, tt.role))
+			srv.LibraryItemApp = app
+			req := httptest.NewRequest(http.MethodGet, "/opds/items?q=%D0%B2%D0%BE%D0%B8%D0%BD&cursor=abc", nil)
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(req))
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Contains(t, rec.Header().Get("Content-Type"), "kind=acquisition")
+
+			var feed atomFeed
+			require.NoError(t, xml.NewDecoder(rec.Body).Decode(&feed))
+			next, ok := findAtomLink(feed.Links, "next")
+			require.True(t, ok)
+			assert.Equal(t, "/opds/items?cursor=next&q=%D0%B2%D0%BE%D0%B8%D0%BD", next.Href)
+
+			require.Len(t, feed.Entries, 1)
+			entry := feed.Entries[0]
+			assert.Equal(t, "urn:uuid:"+item.ID.String(), entry.ID)
+			assert.Equal(t, "Воин", entry.Title)
+			assert.Equal(t, []atomAuthor{{Name: "Роберт Сальваторе", URI: "/opds/items?author=" + authorID.String()}}, entry.Authors)
+
+			thumbnail, ok := findAtomLink(entry.Links, opdsRelThumbnail)
+			require.True(t, ok)
+			assert.Equal(t, "/api/v1/library-items/"+item.ID.String()+"/cover?size=thumbnail", thumbnail.Href)
+
+			var acquisitions []atomLink
+			for _, l := range entry.Links {
+				if l.Rel == opdsRelAcquisition {
+					acquisitions = append(acquisitions, l)
+				}
+			}
+			require.Len(t, acquisitions, tt.acquisitions)
+			if tt.acquisitions > 0 {
+				assert.Equal(t, "application/epub+zip", acquisitions[0].Type)
+				assert.Equal(t, "/api/v1/library-items/"+item.ID.String()+"/download", acquisitions[0].Href)
+			}
+		})
+	}
+}
+
+func TestServer_opdsItems_pageStreaming(t *testing.T) {
+	t.Parallel()
+
+	comic := library_item.LibraryItemView{ID: domain.NewLibraryItemID(), Title: "Plastic Man #002", Format: domain.FormatCBZ, PageCount: 52}
+	book := library_item.LibraryItemView{ID: domain.NewLibraryItemID(), Title: "Воин", Format: domain.FormatEPUB}
+	app := new(mockLibraryItemApp)
+	app.On("ListLibraryItems", mock.Anything, mock.Anything).
+		Return(library_item.LibraryItemPage{Items: []library_item.LibraryItemView{comic, book}}, nil)
+
+	srv, _ := newAuthedServer(t, mustNewUserWithRole(t, domain.RoleGuest))
+	srv.LibraryItemApp = app
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/opds/items", nil)))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	body := rec.Body.String()
+	assert.Contains(t, body, `xmlns:pse="http://vaemendis.net/opds-pse/ns"`)
+
+	var feed atomFeed
+	require.NoError(t, xml.NewDecoder(strings.NewReader(body)).Decode(&feed))
+	require.Len(t, feed.Entries, 2)
+	stream, ok := findAtomLink(feed.Entries[0].Links, pseRelStream)
+	require.True(t, ok, "guests can stream pages without download permission")
+	assert.Equal(t, "/api/v1/library-items/"+comic.ID.String()+"/pages/{pageNumber}?max_width={maxWidth}", stream.Href)
+	assert.Contains(t, body, `pse:count="52"`)
+	_, ok = findAtomLink(feed.Entries[1].Links, pseRelStream)
+	assert.False(t, ok, "reflowable books have no pages")
+}
+
+func TestServer_opdsItems_epubConversion(t *testing.T) {
+	t.Parallel()
+
+	fb2 := library_item.LibraryItemView{ID: domain.NewLibraryItemID(), Title: "Воин", Format: domain.FormatFB2}
+	app := new(mockLibraryItemApp)
+	app.On("ListLibraryItems", mock.Anything, mock.Anything).
+		Return(library_item.LibraryItemPage{Items: []library_item.LibraryItemView{fb2}}, nil)
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	srv.LibraryItemApp = app
+	expected := []opds2Link{
+		{Rel: opdsRelAcquisition, Href: "/api/v1/library-items/" + fb2.ID.String() + "/download", Type: "application/x-fictionbook+xml"},
+		{Rel: opdsRelAcquisition, Href: "/api/v1/library-items/" + fb2.ID.String() + "/download?format=epub", Type: "application/epub+zip"},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/opds/items", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var feed atomFeed
+	require.NoError(t, xml.NewDecoder(rec.Body).Decode(&feed))
+	require.Len(t, feed.Entries, 1)
+	var acquisitions []opds2Link
+	for _, l := range feed.Entries[0].Links {
+		if l.Rel == opdsRelAcquisition {
+			acquisitions = append(acquisitions, opds2Link{Rel: l.Rel, Href: l.Href, Type: l.Type})
+		}
+	}
+	assert.Equal(t, expected, acquisitions)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/opds/v2/items", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var feed2 opds2Feed
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&feed2))
+	require.Len(t, feed2.Publications, 1)
+	for _, l := range expected {
+		assert.Contains(t, feed2.Publications[0].Links, l)
+	}
+}
+
+func TestServer_opdsItems_linksHaveRoutes(t *testing.T) {
+	t.Parallel()
+
+	items := []library_item.LibraryItemView{
+		{ID: domain.NewLibraryItemID(), Title: "Воин", Format: domain.FormatEPUB},
+		{ID: domain.NewLibraryItemID(), Title: "Воин", Format: domain.FormatFB2},
+		{ID: domain.NewLibraryItemID(), Title: "Plastic Man #002", Format: domain.FormatCBZ, PageCount: 52},
+	}
+	app := new(mockLibraryItemApp)
+	app.On("ListLibraryItems", mock.Anything, mock.Anything).Return(library_item.LibraryItemPage{Items: items}, nil)
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	srv.LibraryItemApp = app
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/opds/items", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var feed atomFeed
+	require.NoError(t, xml.NewDecoder(rec.Body).Decode(&feed))
+
+	var hrefs []string
+	for _, entry := range feed.Entries {
+		for _, l := range entry.Links {
+			if strings.HasPrefix(l.Href, "/api/") {
+				hrefs = append(hrefs, strings.NewReplacer("{pageNumber}", "0", "{maxWidth}", "800").Replace(l.Href))
+			}
+		}
+	}
+	require.NotEmpty(t, hrefs)
+	for _, href := range hrefs {
+		// routes that exist ask anonymous requests to log in, the others are not found
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, href, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, href)
+	}
+}
+
+func TestServer_opds2FacetValues(t *testing.T) {
+	t.Parallel()
+
+	app := new(mockLibraryItemApp)
+	app.On("ListFacetValues", mock.Anything, library_item.ListFacetValuesQuery{Facet: library_item.FacetSeries, Limit: 1}).
+		Return(library_item.FacetValuePage{
+			Values:     []library_item.FacetValue{{Value: "Plastic Man & Co", Label: "Plastic Man & Co", Count: 12}},
+			NextCursor: "next",
+		}, nil)
+
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	srv.LibraryItemApp = app
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/opds/v2/series?limit=1", nil)))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, opds2Type, rec.Header().Get("Content-Type"))
+
+	var feed opds2Feed
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&feed))
+	assert.Contains(t, feed.Links, opds2Link{Rel: "next", Href: "/opds/v2/series?cursor=next&limit=1", Type: opds2Type})
+	assert.Equal(t, []opds2Link{{
+		Href:       "/opds/v2/items?series=Plastic+Man+%26+Co",
+		Type:       opds2Type,
+		Title:      "Plastic Man & Co",
+		Properties: &opds2Properties{NumberOfItems: 12},
+	}}, feed.Navigation)
+}
+
+func TestServer_openSearchDescription(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/opds/search.xml", nil)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var desc openSearchDescription
+	require.NoError(t, xml.NewDecoder(rec.Body).Decode(&desc))
+	assert.Equal(t, "/opds/items?q={searchTerms}", desc.URL.Template)
+}
@@ -0,0 +1,132 @@
+package http_port
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/progress"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type ProgressApp interface {
+	GetProgress(context.Context, domain.LibraryItemID) (*domain.ReadingProgress, error)
+	SaveProgress(context.Context, progress.SaveProgressCmd) (progress.SaveProgressResult, error)
+	FindDocument(ctx context.Context, partialMD5 string) (*domain.LibraryItem, error)
+}
+
+type locatorDTO struct {
+	Kind       domain.LocatorKind `json:"kind"`
+	CFI        string             `json:"cfi,omitempty"`
+	XPointer   string             `json:"xpointer,omitempty"`
+	Page       int                `json:"page,omitempty"`
+	Percentage float64            `json:"percentage"`
+}
+
+func newLocatorDTO(l domain.Locator) locatorDTO {
+	return locatorDTO{Kind: l.Kind, CFI: l.CFI, XPointer: l.XPointer, Page: l.Page, Percentage: l.Percentage}
+}
+
+func (d locatorDTO) toDomain() domain.Locator {
+	return domain.Locator{Kind: d.Kind, CFI: d.CFI, XPointer: d.XPointer, Page: d.Page, Percentage: d.Percentage}
+}
+
+type saveProgressRequest struct {
+	Locator locatorDTO `json:"locator"`
+	Device  string     `json:"device"`
+	// UpdatedAt is when the device was at the locator, defaults to now.
+	UpdatedAt time.Time `json:"updated_at"`
+	// BaseVersion is the version the device got from its last sync, 0 if it never synced.
+	BaseVersion int `json:"base_version"`
+}
+
+type progressConflictResponse struct {
+	Device      string     `json:"device"`
+	Locator     locatorDTO `json:"locator"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Overwritten bool       `json:"overwritten"`
+}
+
+type progressResponse struct {
+	ItemID    string     `json:"item_id"`
+	Locator   locatorDTO `json:"locator"`
+	Device    string     `json:"device,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Version   int        `json:"version"`
+	// Conflict is set when another device moved the progress since the last sync of this one.
+	Conflict *progressConflictResponse `json:"conflict,omitempty"`
+}
+
+func newProgressResponse(p *domain.ReadingProgress) progressResponse {
+	return progressResponse{
+		ItemID:    p.ItemID().String(),
+		Locator:   newLocatorDTO(p.Locator()),
+		Device:    p.Device(),
+		UpdatedAt: p.UpdatedAt(),
+		Version:   p.Version(),
+	}
+}
+
+// getProgress handles GET /api/v1/library-items/{id}/progress
+func (s *Server) getProgress(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	p, err := s.ProgressApp.GetProgress(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newProgressResponse(p))
+}
+
+// saveProgress handles PUT /api/v1/library-items/{id}/progress
+//
+// It responds with 409 and the current progress if another device saved a newer position
+// since the last sync of this one, the update is discarded then.
+func (s *Server) saveProgress(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrLibraryItemNotFound)
+		return
+	}
+
+	var req saveProgressRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res, err := s.ProgressApp.SaveProgress(r.Context(), progress.SaveProgressCmd{
+		ItemID:      id,
+		Locator:     req.Locator.toDomain(),
+		Device:      req.Device,
+		At:          req.UpdatedAt,
+		BaseVersion: req.BaseVersion,
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	body := newProgressResponse(res.Progress)
+	if c := res.Conflict; c != nil {
+		body.Conflict = &progressConflictResponse{
+			Device:      c.Device,
+			Locator:     newLocatorDTO(c.Locator),
+			UpdatedAt:   c.UpdatedAt,
+			Overwritten: c.Overwritten,
+		}
+		if !c.Overwritten {
+			status = http.StatusConflict
+		}
+	}
+	writeJSON(w, r, status, body)
+}
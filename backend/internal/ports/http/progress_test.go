@@ -0,0 +1,134 @@
+package http_port
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/progress"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type mockProgressApp struct{ mock.Mock }
+
+func (m *mockProgressApp) GetProgress(ctx context.Context, id domain.LibraryItemID) (*domain.ReadingProgress, error) {
+	args := m.Called(ctx, id)
+	p, _ := args.Get(0).(*domain.ReadingProgress)
+	return p, args.Error(1)
+}
+
+func (m *mockProgressApp) SaveProgress(ctx context.Context, cmd progress.SaveProgressCmd) (progress.SaveProgressResult, error) {
+	args := m.Called(ctx, cmd)
+	r, _ := args.Get(0).(progress.SaveProgressResult)
+	return r, args.Error(1)
+}
+
+func (m *mockProgressApp) FindDocument(ctx context.Context, partialMD5 string) (*domain.LibraryItem, error) {
+	args := m.Called(ctx, partialMD5)
+	item, _ := args.Get(0).(*domain.LibraryItem)
+	return item, args.Error(1)
+}
+
+func TestServer_getProgress(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	itemID := domain.NewLibraryItemID()
+	locator := domain.Locator{Kind: domain.LocatorPDFPage, Page: 12, Percentage: 0.5}
+	p, err := domain.NewReadingProgress(user.ID(), itemID, locator, "kobo", time.Now())
+	require.NoError(t, err)
+	missing := domain.NewLibraryItemID()
+
+	srv, _ := newAuthedServer(t, user)
+	app := new(mockProgressApp)
+	srv.ProgressApp = app
+	app.On("GetProgress", mock.Anything, itemID).Return(p, nil)
+	app.On("GetProgress", mock.Anything, missing).Return(nil, domain.ErrReadingProgressNotFound)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+itemID.String()+"/progress", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res progressResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, newLocatorDTO(locator), res.Locator)
+	assert.Equal(t, "kobo", res.Device)
+	assert.Equal(t, 1, res.Version)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/library-items/"+missing.String()+"/progress", nil)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_saveProgress(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	itemID := domain.NewLibraryItemID()
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	locator := domain.Locator{Kind: domain.LocatorCFI, CFI: "epubcfi(/6/4!/4/2/1:0)", Percentage: 0.25}
+	saved, err := domain.NewReadingProgress(user.ID(), itemID, locator, "phone", at)
+	require.NoError(t, err)
+	cmd := progress.SaveProgressCmd{ItemID: itemID, Locator: locator, Device: "phone", At: at, BaseVersion: 3}
+	body := `{"locator":{"kind":"cfi","cfi":"epubcfi(/6/4!/4/2/1:0)","percentage":0.25},"device":"phone","updated_at":"2025-03-01T10:00:00Z","base_version":3}`
+
+	tests := []struct {
+		name           string
+		result         progress.SaveProgressResult
+		expectedStatus int
+	}{
+		{
+			name:           "saved",
+			result:         progress.SaveProgressResult{Progress: saved},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "overwrote another device",
+			result: progress.SaveProgressResult{Progress: saved, Conflict: &domain.ProgressConflict{
+				Device: "kobo", Locator: domain.Locator{Kind: domain.LocatorPercentage, Percentage: 0.2}, Overwritten: true,
+			}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "discarded",
+			result: progress.SaveProgressResult{Progress: saved, Conflict: &domain.ProgressConflict{
+				Device: "kobo", Locator: domain.Locator{Kind: domain.LocatorPercentage, Percentage: 0.9},
+			}},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, _ := newAuthedServer(t, user)
+			app := new(mockProgressApp)
+			srv.ProgressApp = app
+			app.On("SaveProgress", mock.Anything, cmd).Return(tt.result, nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/library-items/"+itemID.String()+"/progress", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(req))
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+
+			var res progressResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			assert.Equal(t, newLocatorDTO(locator), res.Locator)
+			if tt.result.Conflict == nil {
+				assert.Nil(t, res.Conflict)
+				return
+			}
+			require.NotNil(t, res.Conflict)
+			assert.Equal(t, tt.result.Conflict.Device, res.Conflict.Device)
+			assert.Equal(t, tt.result.Conflict.Overwritten, res.Conflict.Overwritten)
+		})
+	}
+}
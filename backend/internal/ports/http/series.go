@@ -0,0 +1,203 @@
+package http_port
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/ARUMANDESU/validation/is"
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/series"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type SeriesApp interface {
+	ListSeries(context.Context, series.ListSeriesQuery) (series.SeriesPage, error)
+	GetSeries(context.Context, domain.SeriesID) (series.SeriesView, error)
+	UpdateSeries(context.Context, series.UpdateSeriesCmd) (series.SeriesView, error)
+	ListSeriesItems(context.Context, domain.SeriesID) ([]series.SeriesItemView, error)
+	NextUnread(context.Context, domain.SeriesID) (series.SeriesItemView, error)
+}
+
+type seriesResponse struct {
+	ID               string                  `json:"id"`
+	Name             string                  `json:"name"`
+	Description      string                  `json:"description,omitempty"`
+	Status           domain.SeriesStatus     `json:"status,omitempty"`
+	ReadingDirection domain.ReadingDirection `json:"reading_direction,omitempty"`
+	ItemCount        int                     `json:"item_count"`
+	ReadCount        int                     `json:"read_count"`
+}
+
+type seriesPageResponse struct {
+	Series     []seriesResponse `json:"series"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type seriesItemResponse struct {
+	ID    string                 `json:"id"`
+	Title string                 `json:"title"`
+	Type  domain.LibraryItemType `json:"type"`
+	Index float64                `json:"index"`
+	// Percentage is missing if the user never opened the item.
+	Percentage *float64   `json:"percentage,omitempty"`
+	Finished   bool       `json:"finished"`
+	LastReadAt *time.Time `json:"last_read_at,omitempty"`
+}
+
+// updateSeriesRequest leaves fields that are missing or null as they are.
+type updateSeriesRequest struct {
+	Name             *string                  `json:"name"`
+	Description      *string                  `json:"description"`
+	Status           *domain.SeriesStatus     `json:"status"`
+	ReadingDirection *domain.ReadingDirection `json:"reading_direction"`
+}
+
+func newSeriesResponse(s series.SeriesView) seriesResponse {
+	return seriesResponse{
+		ID:               s.ID.String(),
+		Name:             s.Name,
+		Description:      s.Description,
+		Status:           s.Status,
+		ReadingDirection: s.ReadingDirection,
+		ItemCount:        s.ItemCount,
+		ReadCount:        s.ReadCount,
+	}
+}
+
+func newSeriesItemResponse(item series.SeriesItemView) seriesItemResponse {
+	return seriesItemResponse{
+		ID:         item.ID.String(),
+		Title:      item.Title,
+		Type:       item.Type,
+		Index:      item.Index,
+		Percentage: item.Percentage,
+		Finished:   item.Finished(),
+		LastReadAt: item.LastReadAt,
+	}
+}
+
+// listSeries handles GET /api/v1/series
+//
+// query params:
+//   - q: words of the name
+//   - cursor: next_cursor of the previous page
+//   - limit: 1..100, default 20
+func (s *Server) listSeries(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	query := series.ListSeriesQuery{Search: values.Get("q"), Cursor: values.Get("cursor")}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			s.writeError(w, r, v.Errors{"limit": is.ErrInt})
+			return
+		}
+		query.Limit = limit
+	}
+
+	page, err := s.SeriesApp.ListSeries(r.Context(), query)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res := seriesPageResponse{
+		Series:     make([]seriesResponse, len(page.Series)),
+		NextCursor: page.NextCursor,
+	}
+	for i, sr := range page.Series {
+		res.Series[i] = newSeriesResponse(sr)
+	}
+
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// getSeries handles GET /api/v1/series/{id}
+func (s *Server) getSeries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrSeriesNotFound)
+		return
+	}
+
+	sr, err := s.SeriesApp.GetSeries(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newSeriesResponse(sr))
+}
+
+// updateSeries handles PATCH /api/v1/series/{id}
+func (s *Server) updateSeries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrSeriesNotFound)
+		return
+	}
+
+	var req updateSeriesRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	sr, err := s.SeriesApp.UpdateSeries(r.Context(), series.UpdateSeriesCmd{
+		ID:               id,
+		Name:             req.Name,
+		Description:      req.Description,
+		Status:           req.Status,
+		ReadingDirection: req.ReadingDirection,
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newSeriesResponse(sr))
+}
+
+// listSeriesItems handles GET /api/v1/series/{id}/items, the items are in reading order.
+func (s *Server) listSeriesItems(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrSeriesNotFound)
+		return
+	}
+
+	items, err := s.SeriesApp.ListSeriesItems(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res := make([]seriesItemResponse, len(items))
+	for i, item := range items {
+		res[i] = newSeriesItemResponse(item)
+	}
+
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// nextUnread handles GET /api/v1/series/{id}/next-unread
+//
+// It responds with 404 if the user finished every item of the series.
+func (s *Server) nextUnread(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrSeriesNotFound)
+		return
+	}
+
+	item, err := s.SeriesApp.NextUnread(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newSeriesItemResponse(item))
+}
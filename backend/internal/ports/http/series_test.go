@@ -0,0 +1,169 @@
+package http_port
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/series"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type mockSeriesApp struct{ mock.Mock }
+
+func (m *mockSeriesApp) ListSeries(ctx context.Context, q series.ListSeriesQuery) (series.SeriesPage, error) {
+	args := m.Called(ctx, q)
+	p, _ := args.Get(0).(series.SeriesPage)
+	return p, args.Error(1)
+}
+
+func (m *mockSeriesApp) GetSeries(ctx context.Context, id domain.SeriesID) (series.SeriesView, error) {
+	args := m.Called(ctx, id)
+	s, _ := args.Get(0).(series.SeriesView)
+	return s, args.Error(1)
+}
+
+func (m *mockSeriesApp) UpdateSeries(ctx context.Context, cmd series.UpdateSeriesCmd) (series.SeriesView, error) {
+	args := m.Called(ctx, cmd)
+	s, _ := args.Get(0).(series.SeriesView)
+	return s, args.Error(1)
+}
+
+func (m *mockSeriesApp) ListSeriesItems(ctx context.Context, id domain.SeriesID) ([]series.SeriesItemView, error) {
+	args := m.Called(ctx, id)
+	items, _ := args.Get(0).([]series.SeriesItemView)
+	return items, args.Error(1)
+}
+
+func (m *mockSeriesApp) NextUnread(ctx context.Context, id domain.SeriesID) (series.SeriesItemView, error) {
+	args := m.Called(ctx, id)
+	item, _ := args.Get(0).(series.SeriesItemView)
+	return item, args.Error(1)
+}
+
+func TestServer_listSeries(t *testing.T) {
+	t.Parallel()
+
+	berserk := series.SeriesView{ID: domain.NewSeriesID(), Name: "Berserk", ReadingDirection: domain.RightToLeft, ItemCount: 41, ReadCount: 2}
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	app := new(mockSeriesApp)
+	srv.SeriesApp = app
+	app.On("ListSeries", mock.Anything, series.ListSeriesQuery{Search: "ber", Cursor: "abc", Limit: 1}).
+		Return(series.SeriesPage{Series: []series.SeriesView{berserk}, NextCursor: "next"}, nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/series?q=ber&cursor=abc&limit=1", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{
+		"series": [{"id": "`+berserk.ID.String()+`", "name": "Berserk", "reading_direction": "rtl", "item_count": 41, "read_count": 2}],
+		"next_cursor": "next"
+	}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/series?limit=ten", nil)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestServer_getSeries(t *testing.T) {
+	t.Parallel()
+
+	found, missing := domain.NewSeriesID(), domain.NewSeriesID()
+	app := new(mockSeriesApp)
+	app.On("GetSeries", mock.Anything, found).Return(series.SeriesView{ID: found, Name: "Saga"}, nil)
+	app.On("GetSeries", mock.Anything, missing).Return(nil, domain.ErrSeriesNotFound)
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "found", id: found.String(), status: http.StatusOK},
+		{name: "not found", id: missing.String(), status: http.StatusNotFound},
+		{name: "malformed id", id: "saga", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, _ := newAuthedServer(t, mustNewUser(t))
+			srv.SeriesApp = app
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/series/"+tt.id, nil)))
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestServer_updateSeries(t *testing.T) {
+	t.Parallel()
+
+	id := domain.NewSeriesID()
+	name, status := "Атака титанов", domain.SeriesCompleted
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	app := new(mockSeriesApp)
+	srv.SeriesApp = app
+	app.On("UpdateSeries", mock.Anything, series.UpdateSeriesCmd{ID: id, Name: &name, Status: &status}).
+		Return(series.SeriesView{ID: id, Name: name, Status: status}, nil)
+
+	body := `{"name":"Атака титанов","status":"completed","reading_direction":null}`
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodPatch, "/api/v1/series/"+id.String(), strings.NewReader(body))))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res seriesResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, name, res.Name)
+	assert.Equal(t, status, res.Status)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodPatch, "/api/v1/series/"+id.String(), strings.NewReader(`{"title":"x"}`))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServer_seriesItems(t *testing.T) {
+	t.Parallel()
+
+	id, finished := domain.NewSeriesID(), domain.NewSeriesID()
+	read, half := 1.0, 0.5
+	items := []series.SeriesItemView{
+		{ID: domain.NewLibraryItemID(), Title: "Berserk v01", Type: domain.Manga, Index: 1, Percentage: &read},
+		{ID: domain.NewLibraryItemID(), Title: "Berserk v01.5", Type: domain.Manga, Index: 1.5, Percentage: &half},
+		{ID: domain.NewLibraryItemID(), Title: "Berserk v02", Type: domain.Manga, Index: 2},
+	}
+	srv, _ := newAuthedServer(t, mustNewUser(t))
+	app := new(mockSeriesApp)
+	srv.SeriesApp = app
+	app.On("ListSeriesItems", mock.Anything, id).Return(items, nil)
+	app.On("NextUnread", mock.Anything, id).Return(items[1], nil)
+	app.On("NextUnread", mock.Anything, finished).Return(nil, series.ErrNothingUnread)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/series/"+id.String()+"/items", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var res []seriesItemResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Len(t, res, 3)
+	assert.True(t, res[0].Finished)
+	assert.Equal(t, 1.5, res[1].Index)
+	assert.False(t, res[1].Finished)
+	assert.Nil(t, res[2].Percentage)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/series/"+id.String()+"/next-unread", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var next seriesItemResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&next))
+	assert.Equal(t, items[1].ID.String(), next.ID)
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/series/"+finished.String()+"/next-unread", nil)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,73 @@
+package http_port
+
+import (
+	"net/http"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/envx"
+	"github.com/ARUMANDESU/goread/backend/pkg/i18nx"
+)
+
+type Server struct {
+	Mode       envx.Mode
+	Translator *i18nx.Translator
+	// SecureCookies marks cookies Secure, enable it when goread is served over https.
+	SecureCookies bool
+	TrustedHeader TrustedHeader
+
+	AuthApp        AuthApp
+	LibraryItemApp LibraryItemApp
+	SyncApp        SyncApp
+	ProgressApp    ProgressApp
+	ContentApp     ContentApp
+	SeriesApp      SeriesApp
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/v1/auth/bootstrap", s.bootstrap)
+	mux.HandleFunc("POST /api/v1/auth/register", s.register)
+	mux.HandleFunc("POST /api/v1/auth/login", s.login)
+	mux.HandleFunc("POST /api/v1/auth/logout", s.logout)
+	mux.HandleFunc("POST /api/v1/auth/logout-all", s.requireUser(s.logoutAll))
+	mux.HandleFunc("GET /api/v1/auth/me", s.requireUser(s.me))
+	mux.HandleFunc("POST /api/v1/auth/password", s.requireUser(s.changePassword))
+	mux.HandleFunc("POST /api/v1/auth/password-reset", s.resetPassword)
+	mux.HandleFunc("GET /api/v1/auth/oidc/login", s.oidcLogin)
+	mux.HandleFunc("GET /api/v1/auth/oidc/callback", s.oidcCallback)
+	mux.HandleFunc("PUT /api/v1/auth/koreader", s.requireUser(s.setKOReaderPassword))
+
+	mux.HandleFunc("GET /api/v1/api-tokens", s.requireUser(s.listAPITokens))
+	mux.HandleFunc("POST /api/v1/api-tokens", s.requireUser(s.createAPIToken))
+	mux.HandleFunc("DELETE /api/v1/api-tokens/{id}", s.requireUser(s.revokeAPIToken))
+
+	mux.HandleFunc("GET /api/v1/users", s.requireUser(s.listUsers))
+	mux.HandleFunc("PUT /api/v1/users/{id}/role", s.requireUser(s.changeUserRole))
+	mux.HandleFunc("PUT /api/v1/users/{id}/restrictions", s.requireUser(s.setUserRestrictions))
+	mux.HandleFunc("POST /api/v1/users/{id}/password-reset", s.requireUser(s.createPasswordReset))
+
+	mux.HandleFunc("POST /api/v1/library/scan", s.requireUser(s.triggerScan))
+	mux.HandleFunc("GET /api/v1/library-items", s.requireUser(s.listLibraryItems))
+	mux.HandleFunc("GET /api/v1/library-items/{id}", s.requireUser(s.getLibraryItem))
+	mux.HandleFunc("PATCH /api/v1/library-items/{id}", s.requireUser(s.editMetadata))
+	mux.HandleFunc("DELETE /api/v1/library-items/{id}", s.requireUser(s.deleteLibraryItem))
+	mux.HandleFunc("GET /api/v1/library-items/{id}/metadata-history", s.requireUser(s.listMetadataAudit))
+	mux.HandleFunc("GET /api/v1/library-items/{id}/download", s.requireUser(s.download))
+	mux.HandleFunc("GET /api/v1/library-items/{id}/cover", s.requireUser(s.getCover))
+	mux.HandleFunc("GET /api/v1/library-items/{id}/epub/{path...}", s.requireUser(s.getEPUBResource))
+	mux.HandleFunc("GET /api/v1/library-items/{id}/epub-validation", s.requireUser(s.validateEPUB))
+	mux.HandleFunc("GET /api/v1/library-items/{id}/pages/{page}", s.requireUser(s.getPage))
+	mux.HandleFunc("GET /api/v1/library-items/{id}/progress", s.requireUser(s.getProgress))
+	mux.HandleFunc("PUT /api/v1/library-items/{id}/progress", s.requireUser(s.saveProgress))
+
+	mux.HandleFunc("GET /api/v1/series", s.requireUser(s.listSeries))
+	mux.HandleFunc("GET /api/v1/series/{id}", s.requireUser(s.getSeries))
+	mux.HandleFunc("PATCH /api/v1/series/{id}", s.requireUser(s.updateSeries))
+	mux.HandleFunc("GET /api/v1/series/{id}/items", s.requireUser(s.listSeriesItems))
+	mux.HandleFunc("GET /api/v1/series/{id}/next-unread", s.requireUser(s.nextUnread))
+
+	s.opdsRoutes(mux)
+	s.kosyncRoutes(mux)
+
+	return s.withLanguage(s.authenticate(mux))
+}
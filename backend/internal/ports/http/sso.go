@@ -0,0 +1,139 @@
+package http_port
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+)
+
+const (
+	oidcCookieName = "goread_oidc"
+	oidcCookiePath = "/api/v1/auth/oidc"
+	// oidcLoginTTL bounds how long the user may spend on the provider's login page.
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// TrustedHeader configures authentication by a reverse proxy that has already signed the user in,
+// e.g. Authelia or oauth2-proxy. Zero value disables it.
+type TrustedHeader struct {
+	// UserHeader holds the user name, defaults to Remote-User.
+	UserHeader string
+	// GroupsHeader holds comma separated groups, defaults to Remote-Groups.
+	GroupsHeader string
+	// Proxies are the addresses the headers are accepted from, headers of other clients are ignored.
+	Proxies []netip.Prefix
+}
+
+func (t TrustedHeader) userHeader() string {
+	if t.UserHeader == "" {
+		return "Remote-User"
+	}
+	return t.UserHeader
+}
+
+func (t TrustedHeader) groupsHeader() string {
+	if t.GroupsHeader == "" {
+		return "Remote-Groups"
+	}
+	return t.GroupsHeader
+}
+
+// trusts reports whether the request came directly from one of the proxies.
+func (t TrustedHeader) trusts(r *http.Request) bool {
+	addr, err := netip.ParseAddr(clientIP(r))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range t.Proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateTrustedHeader returns false if the request carries no trusted user header.
+func (s *Server) authenticateTrustedHeader(next http.Handler, w http.ResponseWriter, r *http.Request) bool {
+	name := strings.TrimSpace(r.Header.Get(s.TrustedHeader.userHeader()))
+	if name == "" || !s.TrustedHeader.trusts(r) {
+		return false
+	}
+
+	var groups []string
+	for g := range strings.SplitSeq(r.Header.Get(s.TrustedHeader.groupsHeader()), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+
+	user, err := s.AuthApp.AuthenticateTrustedHeader(r.Context(), name, groups)
+	if err != nil {
+		s.writeError(w, r, err)
+		return true
+	}
+
+	next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+	return true
+}
+
+// oidcLogin handles GET /api/v1/auth/oidc/login, it redirects the browser to the provider.
+// The state, PKCE verifier and nonce wait for the callback in a short-lived cookie.
+func (s *Server) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	start, err := s.AuthApp.BeginOIDCLogin()
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.setOIDCCookie(w, strings.Join([]string{start.State, start.Verifier, start.Nonce}, "."), time.Now().Add(oidcLoginTTL))
+	http.Redirect(w, r, start.URL, http.StatusFound)
+}
+
+// oidcCallback handles GET /api/v1/auth/oidc/callback, the provider redirects back here.
+func (s *Server) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	s.setOIDCCookie(w, "", time.Unix(0, 0))
+
+	q := r.URL.Query()
+	if q.Get("error") != "" {
+		s.writeError(w, r, auth.ErrInvalidSSOState)
+		return
+	}
+
+	var expected auth.OIDCLoginStart
+	if c, err := r.Cookie(oidcCookieName); err == nil {
+		if parts := strings.Split(c.Value, "."); len(parts) == 3 {
+			expected = auth.OIDCLoginStart{State: parts[0], Verifier: parts[1], Nonce: parts[2]}
+		}
+	}
+
+	res, err := s.AuthApp.CompleteOIDCLogin(r.Context(), auth.CompleteOIDCLoginCmd{
+		Code:      q.Get("code"),
+		State:     q.Get("state"),
+		Expected:  expected,
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.setSessionCookie(w, res.Token, res.Session.ExpiresAt())
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (s *Server) setOIDCCookie(w http.ResponseWriter, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    value,
+		Path:     oidcCookiePath,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   s.SecureCookies,
+		// Lax lets the cookie through on the provider's top-level redirect back to us.
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,129 @@
+package http_port
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+func TestServer_trustedHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		headers        map[string]string
+		expectedStatus int
+		expectedGroups []string
+	}{
+		{
+			name:           "trusted proxy",
+			remoteAddr:     "10.0.0.2:4242",
+			headers:        map[string]string{"Remote-User": "alice", "Remote-Groups": "admins, family,"},
+			expectedStatus: http.StatusOK,
+			expectedGroups: []string{"admins", "family"},
+		},
+		{
+			name:           "ipv4 mapped ipv6 proxy",
+			remoteAddr:     "[::ffff:10.0.0.2]:4242",
+			headers:        map[string]string{"Remote-User": "alice"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "untrusted client is ignored",
+			remoteAddr:     "192.0.2.1:4242",
+			headers:        map[string]string{"Remote-User": "alice"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "no header",
+			remoteAddr:     "10.0.0.2:4242",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			user := mustNewUser(t)
+			srv, authApp := newAuthedServer(t, user)
+			srv.TrustedHeader = TrustedHeader{Proxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+			authApp.On("AuthenticateTrustedHeader", mock.Anything, "alice", tt.expectedGroups).Return(user, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			if tt.expectedStatus != http.StatusOK {
+				authApp.AssertNotCalled(t, "AuthenticateTrustedHeader", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestServer_oidcFlow(t *testing.T) {
+	t.Parallel()
+
+	user := mustNewUser(t)
+	session, token, err := domain.NewSession(user.ID(), "", time.Hour)
+	require.NoError(t, err)
+
+	start := auth.OIDCLoginStart{URL: "https://idp.example.com/authorize?state=s1", State: "s1", Verifier: "v1", Nonce: "n1"}
+	srv, authApp := newAuthedServer(t, user)
+	authApp.On("BeginOIDCLogin").Return(start, nil)
+	authApp.On("CompleteOIDCLogin", mock.Anything, auth.CompleteOIDCLoginCmd{
+		Code:     "code-1",
+		State:    "s1",
+		Expected: auth.OIDCLoginStart{State: "s1", Verifier: "v1", Nonce: "n1"},
+	}).Return(auth.LoginResult{User: user, Session: session, Token: token}, nil)
+	authApp.On("CompleteOIDCLogin", mock.Anything, mock.Anything).Return(nil, auth.ErrInvalidSSOState)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, start.URL, rec.Header().Get("Location"))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	oidcCookie := cookies[0]
+	assert.Equal(t, oidcCookieName, oidcCookie.Name)
+	assert.True(t, oidcCookie.HttpOnly)
+
+	t.Run("callback", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?code=code-1&state=s1", nil)
+		req.AddCookie(oidcCookie)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusFound, rec.Code, rec.Body.String())
+		assert.Equal(t, "/", rec.Header().Get("Location"))
+		var got *http.Cookie
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == sessionCookieName {
+				got = c
+			}
+		}
+		require.NotNil(t, got)
+		assert.Equal(t, token, got.Value)
+	})
+
+	t.Run("callback without login cookie", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?code=code-1&state=s1", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,119 @@
+package http_port
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type changeRoleRequest struct {
+	Role domain.Role `json:"role"`
+}
+
+type restrictionsDTO struct {
+	AllowTypes   []domain.LibraryItemType `json:"allow_types,omitempty"`
+	DenyTypes    []domain.LibraryItemType `json:"deny_types,omitempty"`
+	AllowGenres  []string                 `json:"allow_genres,omitempty"`
+	DenyGenres   []string                 `json:"deny_genres,omitempty"`
+	AllowRoots   []string                 `json:"allow_roots,omitempty"`
+	DenyRoots    []string                 `json:"deny_roots,omitempty"`
+	AllowTags    []string                 `json:"allow_tags,omitempty"`
+	DenyTags     []string                 `json:"deny_tags,omitempty"`
+	MaxAge       int                      `json:"max_age,omitempty"`
+	AllowUnrated bool                     `json:"allow_unrated,omitempty"`
+}
+
+func newRestrictionsDTO(r domain.ContentRestrictions) restrictionsDTO {
+	return restrictionsDTO(r)
+}
+
+func (d restrictionsDTO) toDomain() domain.ContentRestrictions {
+	return domain.ContentRestrictions(d)
+}
+
+// listUsers handles GET /api/v1/users
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.AuthApp.ListUsers(r.Context())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	res := make([]userResponse, len(users))
+	for i, u := range users {
+		res[i] = newUserResponse(u)
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// changeUserRole handles PUT /api/v1/users/{id}/role
+func (s *Server) changeUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrUserNotFound)
+		return
+	}
+
+	var req changeRoleRequest
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	user, err := s.AuthApp.ChangeUserRole(r.Context(), id, req.Role)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newUserResponse(user))
+}
+
+// setUserRestrictions handles PUT /api/v1/users/{id}/restrictions, an empty object lifts all restrictions.
+func (s *Server) setUserRestrictions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrUserNotFound)
+		return
+	}
+
+	var req restrictionsDTO
+	if err := readJSON(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	user, err := s.AuthApp.SetUserRestrictions(r.Context(), id, req.toDomain())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newUserResponse(user))
+}
+
+type passwordResetResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// createPasswordReset handles POST /api/v1/users/{id}/password-reset, the returned one-time token
+// is passed to the user, who sets a new password with it.
+func (s *Server) createPasswordReset(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, domain.ErrUserNotFound)
+		return
+	}
+
+	res, err := s.AuthApp.CreatePasswordReset(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, passwordResetResponse{Token: res.Token, ExpiresAt: res.Reset.ExpiresAt()})
+}
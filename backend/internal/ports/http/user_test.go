@@ -0,0 +1,133 @@
+package http_port
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/internal/app/auth"
+	"github.com/ARUMANDESU/goread/backend/internal/domain"
+)
+
+type mockSyncApp struct{ mock.Mock }
+
+func (m *mockSyncApp) TriggerScan(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func TestServer_listUsers(t *testing.T) {
+	t.Parallel()
+
+	admin := mustNewUserWithRole(t, domain.RoleAdmin)
+	srv, authApp := newAuthedServer(t, admin)
+	authApp.On("ListUsers", mock.Anything).Return([]*domain.User{admin}, nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res []userResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Len(t, res, 1)
+	assert.Equal(t, domain.RoleAdmin, res[0].Role)
+	assert.Contains(t, res[0].Permissions, domain.PermManageUsers)
+}
+
+func TestServer_changeUserRole(t *testing.T) {
+	t.Parallel()
+
+	admin := mustNewUserWithRole(t, domain.RoleAdmin)
+	target := mustNewUserWithRole(t, domain.RoleGuest)
+	forbidden := domain.NewUserID()
+
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+	}{
+		{name: "ok", id: target.ID().String(), body: `{"role":"guest"}`, status: http.StatusOK},
+		{name: "forbidden", id: forbidden.String(), body: `{"role":"admin"}`, status: http.StatusForbidden},
+		{name: "malformed id", id: "42", body: `{"role":"admin"}`, status: http.StatusNotFound},
+		{name: "malformed body", id: target.ID().String(), body: `{"role":`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, authApp := newAuthedServer(t, admin)
+			authApp.On("ChangeUserRole", mock.Anything, target.ID(), domain.RoleGuest).Return(target, nil)
+			authApp.On("ChangeUserRole", mock.Anything, forbidden, domain.RoleAdmin).Return(nil, domain.ErrPermissionDenied)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+tt.id+"/role", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(req))
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestServer_triggerScan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		scanErr error
+		status  int
+	}{
+		{name: "ok", status: http.StatusNoContent},
+		{name: "forbidden", scanErr: domain.ErrPermissionDenied, status: http.StatusForbidden},
+		{name: "anonymous", scanErr: auth.ErrNotAuthenticated, status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, _ := newAuthedServer(t, mustNewUser(t))
+			syncApp := new(mockSyncApp)
+			syncApp.On("TriggerScan", mock.Anything).Return(tt.scanErr)
+			srv.SyncApp = syncApp
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, withSession(httptest.NewRequest(http.MethodPost, "/api/v1/library/scan", nil)))
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestServer_setUserRestrictions(t *testing.T) {
+	t.Parallel()
+
+	admin := mustNewUserWithRole(t, domain.RoleAdmin)
+	kid := mustNewUserWithRole(t, domain.RoleGuest)
+	restrictions := domain.ContentRestrictions{
+		AllowTypes: []domain.LibraryItemType{domain.Comic},
+		DenyTags:   []string{"horror"},
+		MaxAge:     10,
+	}
+	require.NoError(t, kid.SetRestrictions(restrictions))
+
+	srv, authApp := newAuthedServer(t, admin)
+	authApp.On("SetUserRestrictions", mock.Anything, kid.ID(), restrictions).Return(kid, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+kid.ID().String()+"/restrictions",
+		strings.NewReader(`{"allow_types":["comic"],"deny_tags":["horror"],"max_age":10}`))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, withSession(req))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res userResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.NotNil(t, res.Restrictions)
+	assert.Equal(t, restrictions, res.Restrictions.toDomain())
+	authApp.AssertExpectations(t)
+}
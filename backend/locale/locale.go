@@ -0,0 +1,7 @@
+// Package locale embeds the translation files, so they ship inside the binary.
+package locale
+
+import "embed"
+
+//go:embed *.toml
+var FS embed.FS
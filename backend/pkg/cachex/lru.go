@@ -0,0 +1,63 @@
+// Package cachex has caches for derived data that is expensive to compute, such as resized images.
+package cachex
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRU is an in-memory cache of byte values, it evicts the least recently used values when
+// the total size of the values exceeds its limit. It is safe for concurrent use.
+type LRU struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+func NewLRU(maxBytes int) *LRU {
+	return &LRU{maxBytes: maxBytes, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *LRU) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// Add stores value under key, values bigger than the whole cache are not stored.
+func (c *LRU) Add(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	if len(value) > c.maxBytes {
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	c.size += len(value)
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := c.order.Remove(el).(*lruEntry)
+	delete(c.items, e.key)
+	c.size -= len(e.value)
+}
@@ -0,0 +1,35 @@
+package cachex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	t.Parallel()
+
+	c := NewLRU(10)
+	c.Add("a", []byte("aaaa"))
+	c.Add("b", []byte("bbbb"))
+
+	_, ok := c.Get("a") // a is now used more recently than b
+	assert.True(t, ok)
+
+	c.Add("c", []byte("cccc"))
+	_, ok = c.Get("b")
+	assert.False(t, ok, "least recently used value is evicted")
+	got, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("aaaa"), got)
+
+	c.Add("a", []byte("a"))
+	got, _ = c.Get("a")
+	assert.Equal(t, []byte("a"), got, "adding replaces the value")
+
+	c.Add("huge", make([]byte, 11))
+	_, ok = c.Get("huge")
+	assert.False(t, ok, "values bigger than the cache are not stored")
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+}
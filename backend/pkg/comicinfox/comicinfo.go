@@ -0,0 +1,92 @@
+package comicinfox
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// FileName is the name of the metadata file inside comic archives, it is looked up at the archive root.
+const FileName = "ComicInfo.xml"
+
+// MangaRightToLeft is the value of the Manga element of manga read from right to left.
+const MangaRightToLeft = "YesAndRightToLeft"
+
+var ErrNotFound = errors.New("ComicInfo.xml not found")
+
+// ComicInfo is the subset of the ComicInfo 2.x schema goread uses,
+// see https://anansi-project.github.io/docs/comicinfo/schemas/v2.0.
+type ComicInfo struct {
+	XMLName     xml.Name `xml:"ComicInfo"`
+	Title       string   `xml:"Title"`
+	Series      string   `xml:"Series"`
+	Number      string   `xml:"Number"`
+	Volume      int      `xml:"Volume"`
+	Summary     string   `xml:"Summary"`
+	Year        int      `xml:"Year"`
+	Month       int      `xml:"Month"`
+	Day         int      `xml:"Day"`
+	Writer      string   `xml:"Writer"`
+	Publisher   string   `xml:"Publisher"`
+	Genre       string   `xml:"Genre"`
+	Tags        string   `xml:"Tags"`
+	LanguageISO string   `xml:"LanguageISO"`
+	Manga       string   `xml:"Manga"`
+	AgeRating   string   `xml:"AgeRating"`
+	PageCount   int      `xml:"PageCount"`
+}
+
+func Parse(r io.Reader) (ComicInfo, error) {
+	var ci ComicInfo
+	if err := xml.NewDecoder(r).Decode(&ci); err != nil {
+		return ComicInfo{}, err
+	}
+	return ci, nil
+}
+
+// FromZip parses ComicInfo.xml of a CBZ archive, it returns ErrNotFound if the archive has none.
+func FromZip(zr *zip.Reader) (ComicInfo, error) {
+	for _, f := range zr.File {
+		if !strings.EqualFold(f.Name, FileName) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return ComicInfo{}, err
+		}
+		defer rc.Close()
+		return Parse(rc)
+	}
+	return ComicInfo{}, ErrNotFound
+}
+
+// Writers returns the comma separated Writer element as a list.
+func (ci ComicInfo) Writers() []string {
+	return SplitList(ci.Writer)
+}
+
+func (ci ComicInfo) Genres() []string {
+	return SplitList(ci.Genre)
+}
+
+func (ci ComicInfo) TagList() []string {
+	return SplitList(ci.Tags)
+}
+
+// IsManga reports whether the Manga element is Yes or YesAndRightToLeft.
+func (ci ComicInfo) IsManga() bool {
+	return strings.HasPrefix(ci.Manga, "Yes")
+}
+
+// SplitList splits the comma separated values ComicInfo uses for multi-value elements.
+func SplitList(s string) []string {
+	var list []string
+	for part := range strings.SplitSeq(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
@@ -0,0 +1,67 @@
+package comicinfox
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `<?xml version="1.0" encoding="utf-8"?>
+<ComicInfo xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <Title>The Plastic Man</Title>
+  <Series>Plastic Man</Series>
+  <Number>2</Number>
+  <Year>1944</Year>
+  <Writer>Jack Cole, Woolfolk</Writer>
+  <Genre>Superhero, Humor</Genre>
+  <Tags>golden age,</Tags>
+  <LanguageISO>en</LanguageISO>
+  <Manga>No</Manga>
+  <AgeRating>Everyone 10+</AgeRating>
+</ComicInfo>`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	ci, err := Parse(strings.NewReader(sample))
+	require.NoError(t, err)
+	assert.Equal(t, "The Plastic Man", ci.Title)
+	assert.Equal(t, "Plastic Man", ci.Series)
+	assert.Equal(t, 1944, ci.Year)
+	assert.Equal(t, []string{"Jack Cole", "Woolfolk"}, ci.Writers())
+	assert.Equal(t, []string{"Superhero", "Humor"}, ci.Genres())
+	assert.Equal(t, []string{"golden age"}, ci.TagList())
+	assert.Equal(t, "Everyone 10+", ci.AgeRating)
+	assert.False(t, ci.IsManga())
+}
+
+func TestFromZip(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("001.jpg")
+	require.NoError(t, err)
+	_, _ = w.Write([]byte("not really a jpeg"))
+	w, err = zw.Create("comicinfo.xml")
+	require.NoError(t, err)
+	_, _ = w.Write([]byte(sample))
+	require.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	ci, err := FromZip(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "The Plastic Man", ci.Title)
+
+	var empty bytes.Buffer
+	require.NoError(t, zip.NewWriter(&empty).Close())
+	zr, err = zip.NewReader(bytes.NewReader(empty.Bytes()), int64(empty.Len()))
+	require.NoError(t, err)
+	_, err = FromZip(zr)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,408 @@
+package epubx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/xmlx"
+)
+
+var (
+	ErrMissingIdentifier = errors.New("book has no identifier")
+	ErrMissingTitle      = errors.New("book has no title")
+	ErrNoChapters        = errors.New("book has no chapters")
+)
+
+// Book is a publication WriteBook makes a new EPUB of, such as one converted from FB2 or plain text.
+type Book struct {
+	// Identifier is the unique identifier, such as urn:uuid:<uuid>.
+	Identifier string
+	Title      string
+	// Language is a BCP 47 tag, und if empty.
+	Language string
+	// Creators are written in order, their Role is aut if empty.
+	Creators    []Author
+	Publisher   string
+	Description string
+	Subjects    []string
+	Series      *Collection
+	Cover       *Cover
+	Chapters    []Chapter
+	// Resources are the images and other files the chapters refer to.
+	Resources []Resource
+	// Modified is the dcterms:modified of the package, the time of writing if zero.
+	Modified time.Time
+}
+
+// Chapter is a content document of a book, followed by its sub-chapters in the reading order.
+// Chapters without a title are read, but not listed in the table of contents.
+type Chapter struct {
+	// ID names the document of the chapter, text/<ID>.xhtml, so other chapters can link to it as
+	// <ID>.xhtml. It is made of letters, digits, - and _, and made up from the chapter's place in
+	// the reading order if empty.
+	ID    string
+	Title string
+	// Body is the XHTML content of the body element, elements without a prefix are in the XHTML
+	// namespace. Chapters are in the text directory, so they refer to resources as ../<Href>.
+	Body     string
+	Children []Chapter
+}
+
+// Resource is a file of a book other than a chapter.
+type Resource struct {
+	// Href is relative to the package document, such as images/map.png, it must not be in the text
+	// directory of the chapters.
+	Href      string
+	MediaType string
+	Data      []byte
+}
+
+// bookStyle keeps converted books readable in reading systems without their own styles.
+const bookStyle = `body { margin: 0 5%; text-align: justify; }
+h1, h2, h3, h4 { text-align: center; text-indent: 0; }
+p { margin: 0; text-indent: 1.5em; }
+blockquote { margin: 1em 2em; }
+.epigraph { margin-left: 40%; font-style: italic; }
+.subtitle { text-align: center; text-indent: 0; font-weight: bold; margin: 1em 0; }
+.text-author { text-align: right; font-style: italic; }
+.poem { margin: 1em 10%; }
+.poem p { text-indent: 0; }
+.stanza { margin: 1em 0; }
+.image { text-align: center; text-indent: 0; }
+.cover { text-align: center; margin: 0; padding: 0; }
+.cover img { max-width: 100%; max-height: 100%; }
+img { max-width: 100%; }
+`
+
+// bookEntry is a file of the package directory with its manifest item.
+type bookEntry struct {
+	id, href, mediaType, properties string
+	data                            []byte
+	// title is set for chapters listed in the table of contents, depth is their level from 1.
+	title string
+	depth int
+}
+
+// WriteBook writes b as an EPUB 3 with an EPUB 2 NCX, so reading systems of either version can
+// read it. The package, chapters and resources are in the OEBPS directory.
+func WriteBook(w io.Writer, b Book) error {
+	if b.Identifier == "" {
+		return ErrMissingIdentifier
+	}
+	if b.Title == "" {
+		return ErrMissingTitle
+	}
+	if len(b.Chapters) == 0 {
+		return ErrNoChapters
+	}
+	if b.Language == "" {
+		b.Language = "und"
+	}
+	if b.Modified.IsZero() {
+		b.Modified = time.Now()
+	}
+
+	var entries []bookEntry
+	entries = append(entries, bookEntry{id: "css", href: "style.css", mediaType: "text/css", data: []byte(bookStyle)})
+	var spine []bookEntry
+	if b.Cover != nil {
+		ext, ok := coverExtensions[b.Cover.MediaType]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCover, b.Cover.MediaType)
+		}
+		image := bookEntry{id: "cover-image", href: "images/cover" + ext, mediaType: b.Cover.MediaType, properties: "cover-image", data: b.Cover.Data}
+		page := bookEntry{
+			id:        "cover",
+			href:      "text/cover.xhtml",
+			mediaType: "application/xhtml+xml",
+			data:      contentDocument(b.Language, b.Title, `<div class="cover"><img src="../`+image.href+`" alt="`+xmlx.EscapeAttr(b.Title)+`"/></div>`),
+		}
+		entries = append(entries, image)
+		spine = append(spine, page)
+	}
+	for i, r := range b.Resources {
+		entries = append(entries, bookEntry{id: "res" + strconv.Itoa(i+1), href: r.Href, mediaType: r.MediaType, data: r.Data})
+	}
+
+	var addChapters func(chapters []Chapter, depth int)
+	addChapters = func(chapters []Chapter, depth int) {
+		for _, c := range chapters {
+			if c.ID == "" {
+				c.ID = fmt.Sprintf("ch%03d", len(spine)+1)
+			}
+			title := c.Title
+			if title == "" {
+				title = b.Title
+			}
+			spine = append(spine, bookEntry{
+				id:        "text-" + c.ID,
+				href:      "text/" + c.ID + ".xhtml",
+				mediaType: "application/xhtml+xml",
+				data:      contentDocument(b.Language, title, c.Body),
+				title:     c.Title,
+				depth:     depth,
+			})
+			childDepth := depth
+			if c.Title != "" {
+				childDepth++
+			}
+			addChapters(c.Children, childDepth)
+		}
+	}
+	addChapters(b.Chapters, 1)
+	hrefs := make(map[string]bool, len(spine))
+	for _, e := range spine {
+		if hrefs[e.href] {
+			return fmt.Errorf("%w: %s", ErrDuplicateID, e.href)
+		}
+		hrefs[e.href] = true
+	}
+
+	nav := bookEntry{id: "nav", href: "nav.xhtml", mediaType: "application/xhtml+xml", properties: "nav", data: bookNav(b, spine)}
+	ncx := bookEntry{id: "ncx", href: "toc.ncx", mediaType: "application/x-dtbncx+xml", data: bookNCX(b, spine)}
+	entries = append(entries, nav, ncx)
+	opf := packageDocument(b, append(entries, spine...), spine)
+
+	zw := zip.NewWriter(w)
+	if err := writeEntry(zw, &zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: b.Modified}, []byte(expectedMimetype)); err != nil {
+		return err
+	}
+	container := `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+	if err := writeEntry(zw, &zip.FileHeader{Name: containerPath, Method: zip.Deflate, Modified: b.Modified}, []byte(container)); err != nil {
+		return err
+	}
+	if err := writeEntry(zw, &zip.FileHeader{Name: "OEBPS/content.opf", Method: zip.Deflate, Modified: b.Modified}, opf); err != nil {
+		return err
+	}
+	for _, e := range append(entries, spine...) {
+		method := zip.Deflate
+		if isCompressedImage(e.mediaType) {
+			method = zip.Store
+		}
+		if err := writeEntry(zw, &zip.FileHeader{Name: path.Join("OEBPS", e.href), Method: method, Modified: b.Modified}, e.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// isCompressedImage reports whether deflating files of the media type gains nothing.
+func isCompressedImage(mediaType string) bool {
+	return mediaType == "image/jpeg" || mediaType == "image/png" || mediaType == "image/gif" || mediaType == "image/webp"
+}
+
+func packageDocument(b Book, manifest, spine []bookEntry) []byte {
+	pw := packageWriter{version3: true, dc: "dc"}
+	buf := &pw.out
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid" xml:lang="`)
+	xmlx.WriteAttr(buf, b.Language)
+	buf.WriteString(`">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">`)
+
+	const indent = "\n    "
+	pw.element(indent, "dc:identifier", []string{"id", "uid"}, b.Identifier)
+	pw.element(indent, "dc:title", nil, b.Title)
+	pw.element(indent, "dc:language", nil, b.Language)
+	for i, a := range b.Creators {
+		role := a.Role
+		if role == "" {
+			role = "aut"
+		}
+		id := "creator" + strconv.Itoa(i+1)
+		pw.element(indent, "dc:creator", []string{"id", id}, a.Name)
+		pw.element(indent, "meta", []string{"refines", "#" + id, "property", "role", "scheme", "marc:relators"}, role)
+		if a.FileAs != "" {
+			pw.element(indent, "meta", []string{"refines", "#" + id, "property", "file-as"}, a.FileAs)
+		}
+	}
+	if b.Publisher != "" {
+		pw.element(indent, "dc:publisher", nil, b.Publisher)
+	}
+	if b.Description != "" {
+		pw.element(indent, "dc:description", nil, b.Description)
+	}
+	for _, s := range b.Subjects {
+		pw.element(indent, "dc:subject", nil, s)
+	}
+	if s := b.Series; s != nil && s.Name != "" {
+		pw.element(indent, "meta", []string{"property", "belongs-to-collection", "id", "series"}, s.Name)
+		pw.element(indent, "meta", []string{"refines", "#series", "property", "collection-type"}, "series")
+		if s.Position > 0 {
+			pw.element(indent, "meta", []string{"refines", "#series", "property", "group-position"}, strconv.FormatFloat(s.Position, 'f', -1, 64))
+		}
+		// EPUB 2 reading systems, calibre first of all, know series from these.
+		pw.element(indent, "meta", []string{"name", "calibre:series", "content", s.Name}, "")
+		if s.Position > 0 {
+			pw.element(indent, "meta", []string{"name", "calibre:series_index", "content", strconv.FormatFloat(s.Position, 'f', -1, 64)}, "")
+		}
+	}
+	if b.Cover != nil {
+		pw.element(indent, "meta", []string{"name", "cover", "content", "cover-image"}, "")
+	}
+	pw.element(indent, "meta", []string{"property", "dcterms:modified"}, b.Modified.UTC().Format(time.RFC3339))
+	pw.out.WriteString("\n  </metadata>\n  <manifest>")
+
+	for _, e := range manifest {
+		attrs := []string{"id", e.id, "href", e.href, "media-type", e.mediaType}
+		if e.properties != "" {
+			attrs = append(attrs, "properties", e.properties)
+		}
+		pw.element(indent, "item", attrs, "")
+	}
+	pw.out.WriteString("\n  </manifest>\n  <spine toc=\"ncx\">")
+	for _, e := range spine {
+		pw.element(indent, "itemref", []string{"idref", e.id}, "")
+	}
+	pw.out.WriteString("\n  </spine>")
+	if b.Cover != nil {
+		pw.out.WriteString("\n  <guide>")
+		pw.element(indent, "reference", []string{"type", "cover", "title", "Cover", "href", spine[0].href}, "")
+		pw.out.WriteString("\n  </guide>")
+	}
+	pw.out.WriteString("\n</package>\n")
+	return pw.out.Bytes()
+}
+
+func contentDocument(lang, title, body string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="`)
+	xmlx.WriteAttr(&buf, lang)
+	buf.WriteString(`" xml:lang="`)
+	xmlx.WriteAttr(&buf, lang)
+	buf.WriteString(`">
+<head>
+  <meta charset="UTF-8"/>
+  <title>`)
+	xmlx.WriteText(&buf, title)
+	buf.WriteString(`</title>
+  <link rel="stylesheet" type="text/css" href="../style.css"/>
+</head>
+<body>
+`)
+	buf.WriteString(body)
+	buf.WriteString("\n</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+// tocEntries returns the chapters of the spine listed in the table of contents, no deeper than one
+// level below the entry before. A book without chapter titles lists its first chapter, a table of
+// contents must not be empty.
+func tocEntries(b Book, spine []bookEntry) []bookEntry {
+	var entries []bookEntry
+	depth := 0
+	for _, e := range spine {
+		if e.title != "" {
+			e.depth = min(e.depth, depth+1)
+			depth = e.depth
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		e := spine[0]
+		e.title, e.depth = b.Title, 1
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func bookNav(b Book, spine []bookEntry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="`)
+	xmlx.WriteAttr(&buf, b.Language)
+	buf.WriteString(`" xml:lang="`)
+	xmlx.WriteAttr(&buf, b.Language)
+	buf.WriteString(`">
+<head>
+  <meta charset="UTF-8"/>
+  <title>`)
+	xmlx.WriteText(&buf, b.Title)
+	buf.WriteString(`</title>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <h1>`)
+	xmlx.WriteText(&buf, b.Title)
+	buf.WriteString("</h1>")
+
+	depth := 0
+	for _, e := range tocEntries(b, spine) {
+		if e.depth > depth {
+			buf.WriteString("\n<ol>")
+		} else {
+			buf.WriteString("</li>")
+		}
+		for ; depth > e.depth; depth-- {
+			buf.WriteString("\n</ol></li>")
+		}
+		depth = e.depth
+		buf.WriteString("\n<li><a href=\"")
+		xmlx.WriteAttr(&buf, e.href)
+		buf.WriteString(`">`)
+		xmlx.WriteText(&buf, e.title)
+		buf.WriteString("</a>")
+	}
+	for ; depth > 0; depth-- {
+		buf.WriteString("</li>\n</ol>")
+	}
+	buf.WriteString("\n  </nav>\n</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+func bookNCX(b Book, spine []bookEntry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1" xml:lang="`)
+	xmlx.WriteAttr(&buf, b.Language)
+	buf.WriteString(`">
+  <head>
+    <meta name="dtb:uid" content="`)
+	xmlx.WriteAttr(&buf, b.Identifier)
+	entries := tocEntries(b, spine)
+	maxDepth := 1
+	for _, e := range entries {
+		maxDepth = max(maxDepth, e.depth)
+	}
+	fmt.Fprintf(&buf, `"/>
+    <meta name="dtb:depth" content="%d"/>
+    <meta name="dtb:totalPageCount" content="0"/>
+    <meta name="dtb:maxPageNumber" content="0"/>
+  </head>
+  <docTitle><text>`, maxDepth)
+	xmlx.WriteText(&buf, b.Title)
+	buf.WriteString("</text></docTitle>\n  <navMap>")
+
+	depth := 0
+	for i, e := range entries {
+		for ; depth >= e.depth; depth-- {
+			buf.WriteString("</navPoint>")
+		}
+		fmt.Fprintf(&buf, "\n<navPoint id=\"np%d\" playOrder=\"%d\"><navLabel><text>", i+1, i+1)
+		xmlx.WriteText(&buf, e.title)
+		buf.WriteString("</text></navLabel><content src=\"")
+		xmlx.WriteAttr(&buf, e.href)
+		buf.WriteString(`"/>`)
+		depth = e.depth
+	}
+	for ; depth > 0; depth-- {
+		buf.WriteString("</navPoint>")
+	}
+	buf.WriteString("\n  </navMap>\n</ncx>\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,167 @@
+package epubx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBook(t *testing.T) {
+	t.Parallel()
+
+	book := Book{
+		Identifier:  "urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9",
+		Title:       "Воин & <Дроу>",
+		Language:    "ru",
+		Creators:    []Author{{Name: "Роберт Сальваторе", FileAs: "Сальваторе, Роберт"}, {Name: "Н. Некрасова", Role: "trl"}},
+		Publisher:   "ИЦ «Максима»",
+		Description: "Покинув подземный мир, темный эльф Дзирт До'Урден начинает свое странствие.",
+		Subjects:    []string{"sf_fantasy"},
+		Series:      &Collection{Name: "Темный эльф", Position: 3},
+		Cover:       &Cover{Data: []byte("\xff\xd8\xff cover"), MediaType: "image/jpeg"},
+		Chapters: []Chapter{
+			{Body: "<p>Аннотация</p>"},
+			{
+				Title: "Часть 1",
+				Body:  "<h1>Часть 1</h1>",
+				Children: []Chapter{
+					{Title: "Глава 1", Body: `<h2>Глава 1</h2><p>Текст <img src="../images/map.png" alt=""/></p>`},
+					{Title: "Глава 2", Body: "<h2>Глава 2</h2><p>Текст</p>"},
+				},
+			},
+			{Title: "Эпилог", Body: `<h1>Эпилог</h1><p>Конец<a href="notes.xhtml#n1">1</a></p>`},
+			{ID: "notes", Title: "Примечания", Body: `<h1>Примечания</h1><p id="n1">Примечание</p>`},
+		},
+		Resources: []Resource{{Href: "images/map.png", MediaType: "image/png", Data: []byte("\x89PNG")}},
+		Modified:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteBook(&buf, book))
+	r := bytes.NewReader(buf.Bytes())
+
+	report := Validate(r, r.Size())
+	require.True(t, report.Valid(), "%v", report.Issues)
+	assert.Equal(t, "3.0", report.Version)
+
+	epub, err := ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	meta := epub.Metadata
+	assert.Equal(t, book.Identifier, epub.Identifier())
+	assert.Equal(t, []string{"Воин & <Дроу>"}, meta.Titles)
+	assert.Equal(t, []string{"ru"}, meta.Languages)
+	assert.Equal(t, []Author{
+		{Name: "Роберт Сальваторе", ID: "creator1", Role: "aut", FileAs: "Сальваторе, Роберт"},
+		{Name: "Н. Некрасова", ID: "creator2", Role: "trl"},
+	}, meta.Creators)
+	assert.Equal(t, []string{"ИЦ «Максима»"}, meta.Publishers)
+	assert.Equal(t, book.Description, meta.Description)
+	assert.Equal(t, []string{"sf_fantasy"}, meta.Subjects)
+	series, ok := meta.Series()
+	require.True(t, ok)
+	assert.Equal(t, Collection{Name: "Темный эльф", Type: "series", Position: 3}, series)
+	modified, ok := meta.Modified()
+	require.True(t, ok)
+	assert.Equal(t, book.Modified, modified)
+
+	cover, mediaType, err := ReadCover(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, book.Cover.Data, cover)
+	assert.Equal(t, "image/jpeg", mediaType)
+
+	var order []string
+	for _, item := range epub.ReadingOrder() {
+		order = append(order, item.Path)
+	}
+	assert.Equal(t, []string{
+		"OEBPS/text/cover.xhtml",
+		"OEBPS/text/ch002.xhtml",
+		"OEBPS/text/ch003.xhtml",
+		"OEBPS/text/ch004.xhtml",
+		"OEBPS/text/ch005.xhtml",
+		"OEBPS/text/ch006.xhtml",
+		"OEBPS/text/notes.xhtml",
+	}, order)
+	assert.Equal(t, []TOCEntry{
+		{Title: "Часть 1", Path: "OEBPS/text/ch003.xhtml", Children: []TOCEntry{
+			{Title: "Глава 1", Path: "OEBPS/text/ch004.xhtml"},
+			{Title: "Глава 2", Path: "OEBPS/text/ch005.xhtml"},
+		}},
+		{Title: "Эпилог", Path: "OEBPS/text/ch006.xhtml"},
+		{Title: "Примечания", Path: "OEBPS/text/notes.xhtml"},
+	}, epub.TOC)
+
+	// The NCX has the same entries for EPUB 2 reading systems.
+	zr, err := zip.NewReader(r, r.Size())
+	require.NoError(t, err)
+	for _, f := range zr.File {
+		if f.Name != "OEBPS/toc.ncx" {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		toc, err := parseNCX(rc, f.Name)
+		rc.Close()
+		require.NoError(t, err)
+		assert.Equal(t, epub.TOC, toc)
+	}
+	assert.Equal(t, "mimetype", zr.File[0].Name)
+	assert.Equal(t, zip.Store, zr.File[0].Method)
+}
+
+func TestWriteBook_untitledChapters(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteBook(&buf, Book{
+		Identifier: "urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9",
+		Title:      "Untitled",
+		Chapters:   []Chapter{{Body: "<p>one</p>"}, {Body: "<p>two</p>"}},
+	}))
+	r := bytes.NewReader(buf.Bytes())
+
+	report := Validate(r, r.Size())
+	require.True(t, report.Valid(), "%v", report.Issues)
+	epub, err := ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"und"}, epub.Metadata.Languages)
+	assert.Equal(t, []TOCEntry{{Title: "Untitled", Path: "OEBPS/text/ch001.xhtml"}}, epub.TOC)
+}
+
+func TestWriteBook_errors(t *testing.T) {
+	t.Parallel()
+
+	chapters := []Chapter{{Title: "1", Body: "<p>1</p>"}}
+	tests := []struct {
+		name    string
+		book    Book
+		wantErr error
+	}{
+		{name: "no identifier", book: Book{Title: "T", Chapters: chapters}, wantErr: ErrMissingIdentifier},
+		{name: "no title", book: Book{Identifier: "id", Chapters: chapters}, wantErr: ErrMissingTitle},
+		{name: "no chapters", book: Book{Identifier: "id", Title: "T"}, wantErr: ErrNoChapters},
+		{
+			name:    "duplicate chapter ids",
+			book:    Book{Identifier: "id", Title: "T", Chapters: []Chapter{{ID: "notes"}, {ID: "notes"}}},
+			wantErr: ErrDuplicateID,
+		},
+		{
+			name:    "unsupported cover",
+			book:    Book{Identifier: "id", Title: "T", Chapters: chapters, Cover: &Cover{MediaType: "image/bmp"}},
+			wantErr: ErrUnsupportedCover,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.ErrorIs(t, WriteBook(io.Discard, tt.book), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Package cfi parses, serializes, compares and resolves EPUB Canonical Fragment Identifiers,
+// see https://idpf.org/epub/linking/cfi/epub-cfi.html.
+package cfi
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid epub cfi")
+
+// OffsetKind tells what the terminus of a path is.
+type OffsetKind int
+
+const (
+	// CharacterOffset ":n" is the position before the n-th character of a text node.
+	CharacterOffset OffsetKind = iota + 1
+	// TemporalOffset "~s" is a time in seconds into audio or video.
+	TemporalOffset
+	// SpatialOffset "@x:y" is a point in an image or video, in percents of its size.
+	SpatialOffset
+	// TemporalSpatialOffset "~s@x:y" is both.
+	TemporalSpatialOffset
+)
+
+// CFI is a location in a publication, or a range if Start and End are set. Their paths continue Path.
+type CFI struct {
+	Path  Path
+	Start *Path
+	End   *Path
+}
+
+type Path struct {
+	Steps  []Step
+	Offset *Offset
+}
+
+type Step struct {
+	// Index is even for elements, 2 for the first one, and odd for the text between them.
+	Index int
+	// ID is the id assertion, e.g. chap01ref of /4[chap01ref].
+	ID string
+	// Indirect is set if the step is preceded by a "!", it steps into the document the step before refers to.
+	Indirect bool
+}
+
+type Offset struct {
+	Kind      OffsetKind
+	Character int
+	Seconds   float64
+	X, Y      float64
+	// Assertion is the text location assertion of a character offset.
+	Assertion *TextAssertion
+}
+
+// TextAssertion is the text around a character offset, e.g. [yyy,xxx;s=b], to find it again
+// after the document changed.
+type TextAssertion struct {
+	Before string
+	After  string
+	// Side is the side bias, "a" or "b", empty if there is none.
+	Side string
+}
+
+// Parse parses a CFI, with or without the epubcfi( ) wrapper.
+func Parse(s string) (CFI, error) {
+	p := parser{s: s}
+	if strings.HasPrefix(s, "epubcfi(") {
+		if !strings.HasSuffix(s, ")") {
+			return CFI{}, fmt.Errorf("%w: missing )", ErrInvalid)
+		}
+		p.s = s[len("epubcfi(") : len(s)-1]
+	}
+
+	var c CFI
+	var err error
+	if c.Path, err = p.path(false); err != nil {
+		return CFI{}, err
+	}
+	if len(c.Path.Steps) == 0 {
+		return CFI{}, p.errorf("missing path")
+	}
+	if p.peek() == ',' {
+		if c.Path.Offset != nil {
+			return CFI{}, p.errorf("offset before range")
+		}
+		p.pos++
+		start, err := p.path(true)
+		if err != nil {
+			return CFI{}, err
+		}
+		if !p.consume(',') {
+			return CFI{}, p.errorf("missing range end")
+		}
+		end, err := p.path(true)
+		if err != nil {
+			return CFI{}, err
+		}
+		c.Start, c.End = &start, &end
+	}
+	if !p.done() {
+		return CFI{}, p.errorf("unexpected %q", p.peek())
+	}
+	return c, nil
+}
+
+// MustParse is Parse for constants, it panics on invalid CFIs.
+func MustParse(s string) CFI {
+	c, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c CFI) IsRange() bool {
+	return c.Start != nil && c.End != nil
+}
+
+// StartLocation returns the location a range starts at, c itself if it is not a range.
+func (c CFI) StartLocation() CFI {
+	if !c.IsRange() {
+		return c
+	}
+	return CFI{Path: c.Path.join(*c.Start)}
+}
+
+// EndLocation returns the location a range ends at, c itself if it is not a range.
+func (c CFI) EndLocation() CFI {
+	if !c.IsRange() {
+		return c
+	}
+	return CFI{Path: c.Path.join(*c.End)}
+}
+
+func (p Path) join(local Path) Path {
+	return Path{Steps: slices.Concat(p.Steps, local.Steps), Offset: local.Offset}
+}
+
+// String returns the CFI with the epubcfi( ) wrapper.
+func (c CFI) String() string {
+	var b strings.Builder
+	b.WriteString("epubcfi(")
+	c.Path.write(&b)
+	if c.IsRange() {
+		b.WriteByte(',')
+		c.Start.write(&b)
+		b.WriteByte(',')
+		c.End.write(&b)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func (p Path) write(b *strings.Builder) {
+	for _, s := range p.Steps {
+		if s.Indirect {
+			b.WriteByte('!')
+		}
+		b.WriteByte('/')
+		b.WriteString(strconv.Itoa(s.Index))
+		if s.ID != "" {
+			b.WriteByte('[')
+			b.WriteString(escape(s.ID))
+			b.WriteByte(']')
+		}
+	}
+	if o := p.Offset; o != nil {
+		switch o.Kind {
+		case CharacterOffset:
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(o.Character))
+			if a := o.Assertion; a != nil {
+				b.WriteByte('[')
+				b.WriteString(escape(a.Before))
+				if a.After != "" {
+					b.WriteByte(',')
+					b.WriteString(escape(a.After))
+				}
+				if a.Side != "" {
+					b.WriteString(";s=")
+					b.WriteString(a.Side)
+				}
+				b.WriteByte(']')
+			}
+		case TemporalOffset, TemporalSpatialOffset:
+			b.WriteByte('~')
+			b.WriteString(formatNumber(o.Seconds))
+			if o.Kind == TemporalSpatialOffset {
+				b.WriteByte('@')
+				b.WriteString(formatNumber(o.X))
+				b.WriteByte(':')
+				b.WriteString(formatNumber(o.Y))
+			}
+		case SpatialOffset:
+			b.WriteByte('@')
+			b.WriteString(formatNumber(o.X))
+			b.WriteByte(':')
+			b.WriteString(formatNumber(o.Y))
+		}
+	}
+}
+
+// Compare orders CFIs by the position of their start in the publication, ranges with the same start
+// by their end. A location is before the locations inside of it.
+func Compare(a, b CFI) int {
+	if c := comparePaths(a.StartLocation().Path, b.StartLocation().Path); c != 0 {
+		return c
+	}
+	return comparePaths(a.EndLocation().Path, b.EndLocation().Path)
+}
+
+// Sort sorts CFIs in reading order, see Compare.
+func Sort(cfis []CFI) {
+	slices.SortStableFunc(cfis, Compare)
+}
+
+func comparePaths(a, b Path) int {
+	for i := range min(len(a.Steps), len(b.Steps)) {
+		if c := cmp.Compare(a.Steps[i].Index, b.Steps[i].Index); c != 0 {
+			return c
+		}
+	}
+	if c := cmp.Compare(len(a.Steps), len(b.Steps)); c != 0 {
+		return c
+	}
+
+	switch {
+	case a.Offset == nil && b.Offset == nil:
+		return 0
+	case a.Offset == nil:
+		return -1
+	case b.Offset == nil:
+		return 1
+	}
+	return cmp.Or(
+		cmp.Compare(a.Offset.Character, b.Offset.Character),
+		cmp.Compare(a.Offset.Seconds, b.Offset.Seconds),
+		cmp.Compare(a.Offset.Y, b.Offset.Y),
+		cmp.Compare(a.Offset.X, b.Offset.X),
+	)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// specialChars have to be escaped with a ^ in assertions.
+const specialChars = "^[](),;="
+
+func escape(s string) string {
+	if !strings.ContainsAny(s, specialChars) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(specialChars, r) {
+			b.WriteByte('^')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
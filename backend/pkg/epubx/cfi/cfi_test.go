@@ -0,0 +1,157 @@
+package cfi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		cfi      string
+		expected CFI
+	}{
+		{
+			cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/3:10)",
+			expected: CFI{Path: Path{
+				Steps: []Step{
+					{Index: 6}, {Index: 4, ID: "chap01ref"},
+					{Index: 4, ID: "body01", Indirect: true}, {Index: 10, ID: "para05"}, {Index: 3},
+				},
+				Offset: &Offset{Kind: CharacterOffset, Character: 10},
+			}},
+		},
+		{
+			cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/16[svgimg])",
+			expected: CFI{Path: Path{Steps: []Step{
+				{Index: 6}, {Index: 4, ID: "chap01ref"}, {Index: 4, ID: "body01", Indirect: true}, {Index: 16, ID: "svgimg"},
+			}}},
+		},
+		{
+			cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05],/2/1:1,/3:4)",
+			expected: CFI{
+				Path: Path{Steps: []Step{
+					{Index: 6}, {Index: 4, ID: "chap01ref"}, {Index: 4, ID: "body01", Indirect: true}, {Index: 10, ID: "para05"},
+				}},
+				Start: &Path{Steps: []Step{{Index: 2}, {Index: 1}}, Offset: &Offset{Kind: CharacterOffset, Character: 1}},
+				End:   &Path{Steps: []Step{{Index: 3}}, Offset: &Offset{Kind: CharacterOffset, Character: 4}},
+			},
+		},
+		{
+			cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/3:10[yyy,xxx;s=b])",
+			expected: CFI{Path: Path{
+				Steps: []Step{
+					{Index: 6}, {Index: 4, ID: "chap01ref"},
+					{Index: 4, ID: "body01", Indirect: true}, {Index: 10, ID: "para05"}, {Index: 3},
+				},
+				Offset: &Offset{Kind: CharacterOffset, Character: 10, Assertion: &TextAssertion{Before: "yyy", After: "xxx", Side: "b"}},
+			}},
+		},
+		{
+			cfi: "epubcfi(/6/14[chap05ref]!/4[body01]/10/2/1:3[2^[1^]])",
+			expected: CFI{Path: Path{
+				Steps: []Step{
+					{Index: 6}, {Index: 14, ID: "chap05ref"},
+					{Index: 4, ID: "body01", Indirect: true}, {Index: 10}, {Index: 2}, {Index: 1},
+				},
+				Offset: &Offset{Kind: CharacterOffset, Character: 3, Assertion: &TextAssertion{Before: "2[1]"}},
+			}},
+		},
+		{
+			cfi: "epubcfi(/6/4!/4/2[video]~23.5@50:30.25)",
+			expected: CFI{Path: Path{
+				Steps:  []Step{{Index: 6}, {Index: 4}, {Index: 4, Indirect: true}, {Index: 2, ID: "video"}},
+				Offset: &Offset{Kind: TemporalSpatialOffset, Seconds: 23.5, X: 50, Y: 30.25},
+			}},
+		},
+		{
+			cfi: "epubcfi(/6/4!/4/2@0:100)",
+			expected: CFI{Path: Path{
+				Steps:  []Step{{Index: 6}, {Index: 4}, {Index: 4, Indirect: true}, {Index: 2}},
+				Offset: &Offset{Kind: SpatialOffset, X: 0, Y: 100},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cfi, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := Parse(tt.cfi)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c)
+			assert.Equal(t, tt.cfi, c.String(), "round trip")
+		})
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		"",
+		"epubcfi()",
+		"epubcfi(/6/4",
+		"epubcfi(6/4)",
+		"epubcfi(/6/04)",
+		"epubcfi(/6/4!)",
+		"epubcfi(/6/4[chap01ref)",
+		"epubcfi(/6/4[chap[01]])",
+		"epubcfi(/6/4!/4:x)",
+		"epubcfi(/6/4!/4/3:1,/1:2)",
+		"epubcfi(/6/4!/4/3,/1:2)",
+		"epubcfi(/6/4!/4/3,,/1:2)",
+		"epubcfi(/6/4!/4@101:0)",
+		"epubcfi(/6/4!/4/3:1 )",
+	} {
+		t.Run(s, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(s)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestCFI_locations(t *testing.T) {
+	t.Parallel()
+
+	c := MustParse("epubcfi(/6/4[chap01ref]!/4[body01]/10[para05],/2/1:1,/3:4)")
+	require.True(t, c.IsRange())
+	assert.Equal(t, "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/2/1:1)", c.StartLocation().String())
+	assert.Equal(t, "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/3:4)", c.EndLocation().String())
+
+	point := MustParse("epubcfi(/6/4!/4/2/1:0)")
+	assert.Equal(t, point, point.StartLocation())
+}
+
+func TestSort(t *testing.T) {
+	t.Parallel()
+
+	sorted := []string{
+		"epubcfi(/6/2!/4/2/1:5)",
+		"epubcfi(/6/4[chap01ref]!/4[body01]/10[para05])",
+		"epubcfi(/6/4[chap01ref]!/4[body01]/10[para05],/2/1:1,/3:4)",
+		"epubcfi(/6/4[chap01ref]!/4[body01]/10[para05],/2/1:1,/3:10)",
+		"epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/2/1:3)",
+		"epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/3:5)",
+		"epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/3:10)",
+		"epubcfi(/6/4[chap01ref]!/4[body01]/16[svgimg])",
+		"epubcfi(/6/14[chap05ref]!/4[body01]/10/2/1:3)",
+	}
+
+	cfis := make([]CFI, len(sorted))
+	for i, j := range []int{8, 3, 0, 6, 2, 5, 1, 7, 4} {
+		cfis[i] = MustParse(sorted[j])
+	}
+	Sort(cfis)
+
+	actual := make([]string, len(cfis))
+	for i, c := range cfis {
+		actual[i] = c.String()
+	}
+	assert.Equal(t, sorted, actual)
+}
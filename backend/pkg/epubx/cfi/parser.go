@@ -0,0 +1,221 @@
+package cfi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at %d", ErrInvalid, fmt.Sprintf(format, args...), p.pos)
+}
+
+// path parses steps and a terminating offset, the local paths of a range may have no steps.
+func (p *parser) path(local bool) (Path, error) {
+	var path Path
+	for {
+		indirect := p.consume('!')
+		if !p.consume('/') {
+			if indirect {
+				return Path{}, p.errorf("missing step after !")
+			}
+			break
+		}
+		n, err := p.integer()
+		if err != nil {
+			return Path{}, err
+		}
+		step := Step{Index: n, Indirect: indirect}
+		if p.peek() == '[' {
+			values, _, err := p.assertion()
+			if err != nil {
+				return Path{}, err
+			}
+			step.ID = values[0]
+		}
+		path.Steps = append(path.Steps, step)
+	}
+
+	offset, err := p.offset()
+	if err != nil {
+		return Path{}, err
+	}
+	path.Offset = offset
+	if local && len(path.Steps) == 0 && offset == nil {
+		return Path{}, p.errorf("empty local path")
+	}
+	return path, nil
+}
+
+func (p *parser) offset() (*Offset, error) {
+	switch {
+	case p.consume(':'):
+		n, err := p.integer()
+		if err != nil {
+			return nil, err
+		}
+		o := &Offset{Kind: CharacterOffset, Character: n}
+		if p.peek() == '[' {
+			values, params, err := p.assertion()
+			if err != nil {
+				return nil, err
+			}
+			o.Assertion = &TextAssertion{Before: values[0], Side: params["s"]}
+			if len(values) > 1 {
+				o.Assertion.After = values[1]
+			}
+		}
+		return o, nil
+	case p.consume('~'):
+		seconds, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		o := &Offset{Kind: TemporalOffset, Seconds: seconds}
+		if p.consume('@') {
+			o.Kind = TemporalSpatialOffset
+			if o.X, o.Y, err = p.point(); err != nil {
+				return nil, err
+			}
+		}
+		return o, nil
+	case p.consume('@'):
+		x, y, err := p.point()
+		if err != nil {
+			return nil, err
+		}
+		return &Offset{Kind: SpatialOffset, X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func (p *parser) point() (float64, float64, error) {
+	x, err := p.number()
+	if err != nil {
+		return 0, 0, err
+	}
+	if !p.consume(':') {
+		return 0, 0, p.errorf("missing y of spatial offset")
+	}
+	y, err := p.number()
+	if err != nil {
+		return 0, 0, err
+	}
+	if x < 0 || x > 100 || y < 0 || y > 100 {
+		return 0, 0, p.errorf("spatial offset out of 0 to 100")
+	}
+	return x, y, nil
+}
+
+// integer parses a non-negative integer without leading zeros.
+func (p *parser) integer() (int, error) {
+	start := p.pos
+	for !p.done() && isDigit(p.peek()) {
+		p.pos++
+	}
+	digits := p.s[start:p.pos]
+	if digits == "" {
+		return 0, p.errorf("missing integer")
+	}
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, p.errorf("leading zero in %s", digits)
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, p.errorf("integer %s out of range", digits)
+	}
+	return n, nil
+}
+
+func (p *parser) number() (float64, error) {
+	start := p.pos
+	for !p.done() && (isDigit(p.peek()) || p.peek() == '.') {
+		p.pos++
+	}
+	n, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return 0, p.errorf("invalid number %q", p.s[start:p.pos])
+	}
+	return n, nil
+}
+
+// assertion parses [value,value;param=value], values are unescaped, there is always at least one.
+func (p *parser) assertion() ([]string, map[string]string, error) {
+	p.pos++ // [
+	values := []string{""}
+	var params map[string]string
+	var param []string // key and value of the parameter being read
+	var cur strings.Builder
+	for {
+		if p.done() {
+			return nil, nil, p.errorf("unclosed assertion")
+		}
+		c := p.s[p.pos]
+		p.pos++
+
+		switch {
+		case c == '^':
+			if p.done() {
+				return nil, nil, p.errorf("escape at end")
+			}
+			cur.WriteByte(p.s[p.pos])
+			p.pos++
+			continue
+		case c == ',' && param == nil:
+			values[len(values)-1] = cur.String()
+			values = append(values, "")
+		case c == ';' || c == ']':
+			if param == nil {
+				values[len(values)-1] = cur.String()
+			} else {
+				if len(param) != 1 {
+					return nil, nil, p.errorf("invalid assertion parameter")
+				}
+				if params == nil {
+					params = make(map[string]string)
+				}
+				params[param[0]] = cur.String()
+			}
+			if c == ']' {
+				return values, params, nil
+			}
+			param = []string{}
+		case c == '=' && param != nil:
+			param = append(param, cur.String())
+		case strings.IndexByte("[](),;=", c) >= 0:
+			return nil, nil, p.errorf("unescaped %q in assertion", c)
+		default:
+			cur.WriteByte(c)
+			continue
+		}
+		cur.Reset()
+	}
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
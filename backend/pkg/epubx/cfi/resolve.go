@@ -0,0 +1,206 @@
+package cfi
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+// spineStep is the step to the spine in the package document, it is the third child element
+// of the package after the metadata and the manifest.
+const spineStep = 6
+
+var ErrUnresolvable = errors.New("epub cfi does not resolve")
+
+type NodeType int
+
+const (
+	ElementNode NodeType = iota + 1
+	TextNode
+)
+
+// Node is a node of the DOM of a content document, as far as CFIs need it: elements and text.
+// Adjacent text, as of CDATA sections or around comments, is a single node.
+type Node struct {
+	Type NodeType
+	// Name is the local name of an element.
+	Name     string
+	ID       string
+	Text     string
+	Parent   *Node
+	Children []*Node
+}
+
+// ParseDocument parses an XHTML or SVG document and returns its root element.
+func ParseDocument(r io.Reader) (*Node, error) {
+	d := xml.NewDecoder(r)
+	d.Entity = xml.HTMLEntity
+
+	var root, cur *Node
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &Node{Type: ElementNode, Name: t.Name.Local, Parent: cur}
+			for _, a := range t.Attr {
+				if a.Name.Local == "id" && (a.Name.Space == "" || a.Name.Space == "http://www.w3.org/XML/1998/namespace") {
+					n.ID = a.Value
+				}
+			}
+			if cur == nil {
+				root = n
+			} else {
+				cur.Children = append(cur.Children, n)
+			}
+			cur = n
+		case xml.EndElement:
+			cur = cur.Parent
+		case xml.CharData:
+			if cur == nil {
+				continue
+			}
+			if last := len(cur.Children) - 1; last >= 0 && cur.Children[last].Type == TextNode {
+				cur.Children[last].Text += string(t)
+			} else {
+				cur.Children = append(cur.Children, &Node{Type: TextNode, Text: string(t), Parent: cur})
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%w: no root element", ErrUnresolvable)
+	}
+	return root, nil
+}
+
+// child returns the child at a CFI step index: the element at index/2 for even indices, the text
+// before, between or after elements for odd ones, which is empty if there is no text there.
+func (n *Node) child(index int) *Node {
+	if index < 1 {
+		return nil
+	}
+	pos := 0 // the index of the last child seen
+	for _, c := range n.Children {
+		if c.Type == ElementNode {
+			pos += 2 - pos%2 // the next even index
+		} else {
+			pos++
+		}
+		if pos == index {
+			return c
+		}
+		if pos > index {
+			break
+		}
+	}
+	if index%2 == 1 && index <= pos+1 {
+		return &Node{Type: TextNode, Parent: n}
+	}
+	return nil
+}
+
+func (n *Node) elementByID(id string) *Node {
+	if n.Type == ElementNode && n.ID == id {
+		return n
+	}
+	for _, c := range n.Children {
+		if found := c.elementByID(id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Position is what a CFI resolves to in a content document: an element, or a text node and an
+// offset in it. Offset counts UTF-16 code units, as JavaScript does in the DOM of browsers.
+type Position struct {
+	Node   *Node
+	Offset int
+}
+
+// RuneOffset returns Offset counted in runes of Node.Text.
+func (p Position) RuneOffset() int {
+	units := 0
+	for i, r := range []rune(p.Node.Text) {
+		if units >= p.Offset {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len([]rune(p.Node.Text))
+}
+
+// ResolveSpine returns the index in spine.ItemRefs of the content document c points into, the
+// location in the document is resolved by ResolveDocument. An id assertion of the itemref step
+// wins over its index if they disagree, as the spec recommends.
+func ResolveSpine(c CFI, spine epubx.Spine) (int, error) {
+	steps := c.Path.Steps
+	if len(steps) < 2 || steps[0].Index != spineStep || steps[1].Index%2 != 0 || steps[1].Indirect {
+		return 0, fmt.Errorf("%w: %s does not point into the spine", ErrUnresolvable, c)
+	}
+
+	if id := steps[1].ID; id != "" {
+		for i, ref := range spine.ItemRefs {
+			if ref.ID == id {
+				return i, nil
+			}
+		}
+	}
+	i := steps[1].Index/2 - 1
+	if i >= len(spine.ItemRefs) {
+		return 0, fmt.Errorf("%w: %s points past the spine", ErrUnresolvable, c)
+	}
+	return i, nil
+}
+
+// ResolveDocument resolves the location of c in the content document with the root element doc,
+// the steps after the indirection. Ranges resolve to their start.
+func ResolveDocument(c CFI, doc *Node) (Position, error) {
+	path := c.StartLocation().Path
+	indirection := -1
+	for i, s := range path.Steps {
+		if s.Indirect {
+			if indirection >= 0 {
+				return Position{}, fmt.Errorf("%w: %s steps into a nested document", ErrUnresolvable, c)
+			}
+			indirection = i
+		}
+	}
+	if indirection < 0 {
+		return Position{}, fmt.Errorf("%w: %s does not step into a content document", ErrUnresolvable, c)
+	}
+
+	n := doc
+	for _, s := range path.Steps[indirection:] {
+		next := n.child(s.Index)
+		if s.ID != "" && (next == nil || next.ID != s.ID) {
+			// The document changed since the CFI was made, the id still finds the element.
+			next = doc.elementByID(s.ID)
+		}
+		if next == nil {
+			return Position{}, fmt.Errorf("%w: %s has no step /%d", ErrUnresolvable, c, s.Index)
+		}
+		n = next
+	}
+
+	pos := Position{Node: n}
+	if o := path.Offset; o != nil && o.Kind == CharacterOffset {
+		if n.Type != TextNode {
+			return Position{}, fmt.Errorf("%w: %s has a character offset into an element", ErrUnresolvable, c)
+		}
+		if o.Character > len(utf16.Encode([]rune(n.Text))) {
+			return Position{}, fmt.Errorf("%w: %s points past the text", ErrUnresolvable, c)
+		}
+		pos.Offset = o.Character
+	}
+	return pos, nil
+}
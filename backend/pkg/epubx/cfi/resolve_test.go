@@ -0,0 +1,134 @@
+package cfi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+// specDocument is the content document of the examples in the EPUB CFI spec.
+const specDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml">
+  <head>
+    <title>…</title>
+  </head>
+  <body id="body01">
+    <p>…</p>
+    <p>…</p>
+    <p>…</p>
+    <p>…</p>
+    <p id="para05">xxx<em>yyy</em>0123456789</p>
+    <p>…</p>
+    <p>…</p>
+    <img id="svgimg" src="foo.svg" alt="…"/>
+    <p>Дзирт&nbsp;𝔇о'Урден</p>
+    <p>…</p>
+  </body>
+</html>`
+
+var specSpine = epubx.Spine{ItemRefs: []epubx.ItemRef{
+	{IDRef: "cover", Linear: "no"},
+	{IDRef: "chap01", ID: "chap01ref"},
+	{IDRef: "chap02", ID: "chap02ref"},
+	{IDRef: "chap03", ID: "chap03ref"},
+	{IDRef: "chap04", ID: "chap04ref"},
+}}
+
+func TestResolveSpine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		cfi         string
+		expected    int
+		expectedErr error
+	}{
+		{cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/3:10)", expected: 1},
+		{cfi: "epubcfi(/6/2!/4)", expected: 0},
+		{cfi: "epubcfi(/6/4[chap03ref]!/4)", expected: 3},
+		{cfi: "epubcfi(/6/12!/4)", expectedErr: ErrUnresolvable},
+		{cfi: "epubcfi(/4/2!/4)", expectedErr: ErrUnresolvable},
+		{cfi: "epubcfi(/6/3:1)", expectedErr: ErrUnresolvable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cfi, func(t *testing.T) {
+			t.Parallel()
+
+			i, err := ResolveSpine(MustParse(tt.cfi), specSpine)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, i)
+		})
+	}
+}
+
+func TestResolveDocument(t *testing.T) {
+	t.Parallel()
+
+	doc, err := ParseDocument(strings.NewReader(specDocument))
+	require.NoError(t, err)
+
+	tests := []struct {
+		cfi          string
+		expectedText string
+		expectedName string
+		expectedErr  error
+		offset       int
+	}{
+		{cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/3:10)", expectedText: "0123456789", offset: 10},
+		{cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/2/1:3)", expectedText: "yyy", offset: 3},
+		{cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05]/1:0)", expectedText: "xxx"},
+		{cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/16[svgimg])", expectedName: "img"},
+		{cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/10[para05],/2/1:1,/3:4)", expectedText: "yyy", offset: 1},
+		{cfi: "epubcfi(/6/4!/4/16/1:0)", expectedText: ""},
+		// The id assertion wins over a stale index.
+		{cfi: "epubcfi(/6/4[chap01ref]!/4[body01]/2[para05]/3:2)", expectedText: "0123456789", offset: 2},
+		{cfi: "epubcfi(/6/4!/4/10/3:11)", expectedErr: ErrUnresolvable},
+		{cfi: "epubcfi(/6/4!/4/10/5:0)", expectedErr: ErrUnresolvable},
+		{cfi: "epubcfi(/6/4!/4/10:1)", expectedErr: ErrUnresolvable},
+		{cfi: "epubcfi(/6/4!/4/40)", expectedErr: ErrUnresolvable},
+		{cfi: "epubcfi(/6/4/4/10)", expectedErr: ErrUnresolvable},
+		{cfi: "epubcfi(/6/4!/4/10!/2)", expectedErr: ErrUnresolvable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cfi, func(t *testing.T) {
+			t.Parallel()
+
+			pos, err := ResolveDocument(MustParse(tt.cfi), doc)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectedName != "" {
+				assert.Equal(t, ElementNode, pos.Node.Type)
+				assert.Equal(t, tt.expectedName, pos.Node.Name)
+				return
+			}
+			assert.Equal(t, TextNode, pos.Node.Type)
+			assert.Equal(t, tt.expectedText, pos.Node.Text)
+			assert.Equal(t, tt.offset, pos.Offset)
+		})
+	}
+}
+
+func TestPosition_RuneOffset(t *testing.T) {
+	t.Parallel()
+
+	doc, err := ParseDocument(strings.NewReader(specDocument))
+	require.NoError(t, err)
+
+	// 𝔇 is two UTF-16 code units but one rune.
+	pos, err := ResolveDocument(MustParse("epubcfi(/6/4!/4/18/1:8)"), doc)
+	require.NoError(t, err)
+	assert.Equal(t, 7, pos.RuneOffset())
+	assert.Equal(t, "о'Урден", string([]rune(pos.Node.Text)[pos.RuneOffset():]))
+}
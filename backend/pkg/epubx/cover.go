@@ -0,0 +1,95 @@
+package epubx
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// MaxCoverSize is the size of the largest cover ReadCover reads, bigger ones are most likely zip bombs.
+const MaxCoverSize = 20 << 20
+
+var (
+	ErrNoCover       = errors.New("no cover")
+	ErrCoverTooLarge = errors.New("cover too large")
+)
+
+// Item returns the manifest item with the given id.
+func (m Manifest) Item(id string) (ManifestItem, bool) {
+	i := slices.IndexFunc(m.Items, func(item ManifestItem) bool { return item.ID == id })
+	if i < 0 {
+		return ManifestItem{}, false
+	}
+	return m.Items[i], true
+}
+
+// HasProperty reports whether the item has the manifest property p.
+func (i ManifestItem) HasProperty(p string) bool {
+	return slices.Contains(strings.Fields(i.Properties), p)
+}
+
+// CoverItem returns the manifest item of the cover image: the EPUB 3 item with the cover-image
+// property, otherwise the item the EPUB 2 <meta name="cover"> refers to.
+func (e EPUB) CoverItem() (ManifestItem, bool) {
+	for _, item := range e.Manifest.Items {
+		if item.HasProperty("cover-image") {
+			return item, true
+		}
+	}
+
+	for _, meta := range e.Metadata.Meta {
+		if meta.Name != "cover" || meta.Content == "" {
+			continue
+		}
+		item, ok := e.Manifest.Item(meta.Content)
+		if !ok {
+			// Some tools put the href instead of the id into the meta.
+			i := slices.IndexFunc(e.Manifest.Items, func(item ManifestItem) bool { return item.Href == meta.Content })
+			if i < 0 {
+				continue
+			}
+			item, ok = e.Manifest.Items[i], true
+		}
+		if strings.HasPrefix(item.MediaType, "image/") {
+			return item, true
+		}
+	}
+
+	return ManifestItem{}, false
+}
+
+// ReadCover returns the cover image of the EPUB in r and its media type, ErrNoCover if it has none.
+// Books that only deviate from the spec still have their covers read.
+func ReadCover(r io.ReaderAt, size int64) ([]byte, string, error) {
+	epub, _, err := ParseEPUBLenient(r, size)
+	if err != nil {
+		return nil, "", err
+	}
+	item, ok := epub.CoverItem()
+	if !ok {
+		return nil, "", ErrNoCover
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, "", err
+	}
+	name := item.Path
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s: %w", ErrNoCover, name, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxCoverSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > MaxCoverSize {
+		return nil, "", ErrCoverTooLarge
+	}
+	return data, item.MediaType, nil
+}
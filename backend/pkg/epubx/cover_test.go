@@ -0,0 +1,111 @@
+package epubx
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCover_epub2Meta(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	data, mediaType, err := ReadCover(f, info.Size())
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", mediaType)
+	assert.Len(t, data, 29541)
+	assert.Equal(t, []byte{0xff, 0xd8}, data[:2])
+}
+
+func TestReadCover(t *testing.T) {
+	t.Parallel()
+
+	opf := func(manifest string, meta string) []byte {
+		return []byte(`<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Test</dc:title>` + meta + `
+  </metadata>
+  <manifest>` + manifest + `</manifest>
+</package>`)
+	}
+	cover := []byte("\x89PNG cover")
+
+	tests := []struct {
+		name          string
+		files         []zipEntry
+		expectedCover []byte
+		expectedErr   error
+	}{
+		{
+			name: "epub3 cover-image property relative to the package document",
+			files: []zipEntry{
+				{name: "mimetype", data: validMimetype},
+				{name: "META-INF/container.xml", data: nestedOPFContainer("OEBPS/content.opf")},
+				{name: "OEBPS/content.opf", data: opf(`
+    <item id="c" href="images/cover%20art.png" media-type="image/png" properties="cover-image"/>`, "")},
+				{name: "OEBPS/images/cover art.png", data: cover},
+			},
+			expectedCover: cover,
+		},
+		{
+			name: "epub2 meta with the href instead of the id",
+			files: []zipEntry{
+				{name: "mimetype", data: validMimetype},
+				{name: "META-INF/container.xml", data: validContainer},
+				{name: "content.opf", data: opf(`<item id="c" href="cover.png" media-type="image/png"/>`, `<meta name="cover" content="cover.png"/>`)},
+				{name: "cover.png", data: cover},
+			},
+			expectedCover: cover,
+		},
+		{
+			name: "epub2 meta pointing to a page",
+			files: []zipEntry{
+				{name: "mimetype", data: validMimetype},
+				{name: "META-INF/container.xml", data: validContainer},
+				{name: "content.opf", data: opf(`<item id="c" href="cover.xhtml" media-type="application/xhtml+xml"/>`, `<meta name="cover" content="c"/>`)},
+			},
+			expectedErr: ErrNoCover,
+		},
+		{
+			name: "cover missing in the container",
+			files: []zipEntry{
+				{name: "mimetype", data: validMimetype},
+				{name: "META-INF/container.xml", data: validContainer},
+				{name: "content.opf", data: opf(`<item id="c" href="cover.png" media-type="image/png" properties="cover-image"/>`, "")},
+			},
+			expectedErr: ErrNoCover,
+		},
+		{
+			name: "no cover",
+			files: []zipEntry{
+				{name: "mimetype", data: validMimetype},
+				{name: "META-INF/container.xml", data: validContainer},
+				{name: "content.opf", data: minimalOPF},
+			},
+			expectedErr: ErrNoCover,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := buildEPUBZip(t, tt.files...)
+			data, _, err := ReadCover(r, r.Size())
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCover, data)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package epubx
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	encryptionPath = "META-INF/encryption.xml"
+	rightsPath     = "META-INF/rights.xml"
+	lcpLicensePath = "META-INF/license.lcpl"
+	sinfPath       = "META-INF/sinf.xml"
+
+	adeptNamespace   = "http://ns.adobe.com/adept"
+	fairPlayDataEnc  = "http://itunes.apple.com/dataenc"
+	lcpKeyTypePrefix = "http://readium.org/2014/01/lcp"
+)
+
+// Font obfuscation algorithms. Obfuscation only keeps fonts from being copied out of the book,
+// reading systems undo it with a key derived from the book's identifier.
+const (
+	ObfuscationIDPF  = "http://www.idpf.org/2008/embedding"
+	ObfuscationAdobe = "http://ns.adobe.com/pdf/enc#RC"
+)
+
+var (
+	ErrInvalidEncryption = errors.New("invalid encryption.xml")
+	ErrNoObfuscationKey  = errors.New("no identifier to derive the font obfuscation key from")
+)
+
+// DRM is a scheme of encrypting books so only licensed reading systems can read them.
+type DRM string
+
+const (
+	DRMNone          DRM = ""
+	DRMAdobeADEPT    DRM = "adobe-adept"
+	DRMReadiumLCP    DRM = "readium-lcp"
+	DRMAppleFairPlay DRM = "apple-fairplay"
+	// DRMUnknown is for resources encrypted with an algorithm that is not a font obfuscation.
+	DRMUnknown DRM = "unknown"
+)
+
+// Encryption is what META-INF/encryption.xml and the license files of DRM schemes say about an EPUB.
+type Encryption struct {
+	DRM DRM
+	// Encrypted are the container paths of the resources encrypted by the DRM, they cannot be read.
+	Encrypted []string
+	// Obfuscated maps the container paths of obfuscated fonts to ObfuscationIDPF or ObfuscationAdobe.
+	Obfuscated map[string]string
+}
+
+// IsProtected reports whether the book is DRM protected, obfuscated fonts alone do not protect it.
+func (e Encryption) IsProtected() bool {
+	return e.DRM != DRMNone
+}
+
+type encryptionXML struct {
+	Data []struct {
+		Method struct {
+			Algorithm string `xml:"Algorithm,attr"`
+		} `xml:"EncryptionMethod"`
+		KeyInfo struct {
+			// Resource is the ADEPT resource id.
+			Resource        string `xml:"resource"`
+			RetrievalMethod struct {
+				URI  string `xml:"URI,attr"`
+				Type string `xml:"Type,attr"`
+			} `xml:"RetrievalMethod"`
+		} `xml:"KeyInfo"`
+		CipherData struct {
+			Reference struct {
+				URI string `xml:"URI,attr"`
+			} `xml:"CipherReference"`
+		} `xml:"CipherData"`
+	} `xml:"EncryptedData"`
+}
+
+// parseEncryption reads the encryption of the container files. The DRM is detected from its license
+// files even if encryption.xml is malformed, the error then wraps ErrInvalidEncryption.
+func parseEncryption(files map[string]*zip.File) (Encryption, error) {
+	var enc Encryption
+	var err error
+	if f := files[encryptionPath]; f != nil {
+		enc, err = readEncryption(f)
+	}
+
+	switch {
+	case files[lcpLicensePath] != nil:
+		enc.DRM = DRMReadiumLCP
+	case files[sinfPath] != nil:
+		enc.DRM = DRMAppleFairPlay
+	case files[rightsPath] != nil && isADEPTRights(files[rightsPath]):
+		enc.DRM = DRMAdobeADEPT
+	}
+	return enc, err
+}
+
+func readEncryption(f *zip.File) (Encryption, error) {
+	r, err := f.Open()
+	if err != nil {
+		return Encryption{}, err
+	}
+	defer r.Close()
+
+	var doc encryptionXML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return Encryption{}, fmt.Errorf("%w: %w", ErrInvalidEncryption, err)
+	}
+
+	var enc Encryption
+	for _, data := range doc.Data {
+		p, ok := cipherReferencePath(data.CipherData.Reference.URI)
+		if !ok {
+			continue
+		}
+		algorithm := data.Method.Algorithm
+		switch {
+		case algorithm == ObfuscationIDPF || algorithm == ObfuscationAdobe:
+			if enc.Obfuscated == nil {
+				enc.Obfuscated = make(map[string]string)
+			}
+			enc.Obfuscated[p] = algorithm
+			continue
+		case strings.HasPrefix(data.KeyInfo.RetrievalMethod.Type, lcpKeyTypePrefix):
+			enc.DRM = DRMReadiumLCP
+		case algorithm == fairPlayDataEnc:
+			enc.DRM = DRMAppleFairPlay
+		case data.KeyInfo.Resource != "":
+			enc.DRM = DRMAdobeADEPT
+		case enc.DRM == DRMNone:
+			enc.DRM = DRMUnknown
+		}
+		enc.Encrypted = append(enc.Encrypted, p)
+	}
+	return enc, nil
+}
+
+// cipherReferencePath resolves the URI of a cipher reference, it is relative to the container root.
+func cipherReferencePath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.IsAbs() || u.Path == "" {
+		return "", false
+	}
+	p := path.Clean(strings.TrimPrefix(u.Path, "/"))
+	return p, fs.ValidPath(p)
+}
+
+// isADEPTRights reports whether rights.xml is an Adobe ADEPT license, some tools write empty ones.
+func isADEPTRights(f *zip.File) bool {
+	r, err := f.Open()
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	return err == nil && bytes.Contains(data, []byte(adeptNamespace))
+}
+
+// Identifier returns the identifier the package's unique-identifier refers to, or the first
+// identifier if it refers to none.
+func (p Package) Identifier() string {
+	for _, id := range p.Metadata.Identifiers {
+		if id.ElementID != "" && id.ElementID == p.UniqueIdentifier {
+			return id.ID
+		}
+	}
+	if len(p.Metadata.Identifiers) > 0 {
+		return p.Metadata.Identifiers[0].ID
+	}
+	return ""
+}
+
+// Deobfuscate removes the font obfuscation from data of the resource at the container path name in
+// place, data of resources that are not obfuscated is left as is.
+func (e EPUB) Deobfuscate(name string, data []byte) error {
+	var key []byte
+	var n int
+	switch e.Encryption.Obfuscated[name] {
+	case ObfuscationIDPF:
+		// The key is the SHA-1 of the unique identifier without whitespace, it masks the first 1040 bytes.
+		uid := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, e.Identifier())
+		if uid == "" {
+			return ErrNoObfuscationKey
+		}
+		sum := sha1.Sum([]byte(uid))
+		key, n = sum[:], 1040
+	case ObfuscationAdobe:
+		// The key is the 16 bytes of the book's UUID, it masks the first 1024 bytes.
+		key, n = e.adobeKey(), 1024
+		if key == nil {
+			return ErrNoObfuscationKey
+		}
+	default:
+		return nil
+	}
+
+	for i := range min(n, len(data)) {
+		data[i] ^= key[i%len(key)]
+	}
+	return nil
+}
+
+func (e EPUB) adobeKey() []byte {
+	for _, id := range e.Metadata.Identifiers {
+		s := strings.TrimSpace(id.ID)
+		s = strings.TrimPrefix(strings.ToLower(s), "urn:uuid:")
+		key, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		if err == nil && len(key) == 16 {
+			return key
+		}
+	}
+	return nil
+}
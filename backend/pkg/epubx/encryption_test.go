@@ -0,0 +1,191 @@
+package epubx
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encryptionXMLWith(data string) []byte {
+	return []byte(`<?xml version="1.0"?>
+<encryption xmlns="urn:oasis:names:tc:opendocument:xmlns:container"
+  xmlns:enc="http://www.w3.org/2001/04/xmlenc#" xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` + data + `</encryption>`)
+}
+
+const (
+	obfuscatedFont = `<enc:EncryptedData>
+  <enc:EncryptionMethod Algorithm="http://www.idpf.org/2008/embedding"/>
+  <enc:CipherData><enc:CipherReference URI="OEBPS/fonts/Serif%20Bold.otf"/></enc:CipherData>
+</enc:EncryptedData>`
+	adeptChapter = `<enc:EncryptedData>
+  <enc:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+  <ds:KeyInfo><resource xmlns="http://ns.adobe.com/adept">urn:uuid:0f1e2d3c</resource></ds:KeyInfo>
+  <enc:CipherData><enc:CipherReference URI="OEBPS/ch1.xhtml"/></enc:CipherData>
+</enc:EncryptedData>`
+	lcpChapter = `<enc:EncryptedData>
+  <enc:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#aes256-cbc"/>
+  <ds:KeyInfo><ds:RetrievalMethod URI="license.lcpl#/encryption/content_key"
+    Type="http://readium.org/2014/01/lcp#EncryptedContentKey"/></ds:KeyInfo>
+  <enc:CipherData><enc:CipherReference URI="OEBPS/ch1.xhtml"/></enc:CipherData>
+</enc:EncryptedData>`
+	aesChapter = `<enc:EncryptedData>
+  <enc:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+  <enc:CipherData><enc:CipherReference URI="OEBPS/ch1.xhtml"/></enc:CipherData>
+</enc:EncryptedData>`
+)
+
+func TestParseEPUB_encryption(t *testing.T) {
+	t.Parallel()
+
+	adeptRights := []byte(`<adept:rights xmlns:adept="http://ns.adobe.com/adept"><adept:licenseToken/></adept:rights>`)
+
+	tests := []struct {
+		name     string
+		files    []zipEntry
+		expected Encryption
+	}{
+		{name: "none"},
+		{
+			name:     "obfuscated font",
+			files:    []zipEntry{{name: encryptionPath, data: encryptionXMLWith(obfuscatedFont)}},
+			expected: Encryption{Obfuscated: map[string]string{"OEBPS/fonts/Serif Bold.otf": ObfuscationIDPF}},
+		},
+		{
+			name: "adobe adept",
+			files: []zipEntry{
+				{name: encryptionPath, data: encryptionXMLWith(obfuscatedFont + adeptChapter)},
+				{name: rightsPath, data: adeptRights},
+			},
+			expected: Encryption{
+				DRM:        DRMAdobeADEPT,
+				Encrypted:  []string{"OEBPS/ch1.xhtml"},
+				Obfuscated: map[string]string{"OEBPS/fonts/Serif Bold.otf": ObfuscationIDPF},
+			},
+		},
+		{
+			name:     "adobe adept rights only",
+			files:    []zipEntry{{name: rightsPath, data: adeptRights}},
+			expected: Encryption{DRM: DRMAdobeADEPT},
+		},
+		{
+			name:  "empty rights",
+			files: []zipEntry{{name: rightsPath, data: []byte(`<rights/>`)}},
+		},
+		{
+			name: "readium lcp",
+			files: []zipEntry{
+				{name: encryptionPath, data: encryptionXMLWith(lcpChapter)},
+				{name: lcpLicensePath, data: []byte(`{}`)},
+			},
+			expected: Encryption{DRM: DRMReadiumLCP, Encrypted: []string{"OEBPS/ch1.xhtml"}},
+		},
+		{
+			name:     "apple fairplay",
+			files:    []zipEntry{{name: sinfPath, data: []byte(`<fairplay:sinf xmlns:fairplay="http://itunes.apple.com/ns/epub"/>`)}},
+			expected: Encryption{DRM: DRMAppleFairPlay},
+		},
+		{
+			name:     "unknown",
+			files:    []zipEntry{{name: encryptionPath, data: encryptionXMLWith(aesChapter)}},
+			expected: Encryption{DRM: DRMUnknown, Encrypted: []string{"OEBPS/ch1.xhtml"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := append([]zipEntry{
+				{name: "mimetype", data: validMimetype},
+				{name: "META-INF/container.xml", data: validContainer},
+				{name: "content.opf", data: minimalOPF},
+			}, tt.files...)
+			r := buildEPUBZip(t, files...)
+
+			epub, err := ParseEPUB(r, r.Size())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, epub.Encryption)
+			assert.Equal(t, tt.expected.DRM != DRMNone, epub.Encryption.IsProtected())
+		})
+	}
+}
+
+func TestParseEPUBLenient_invalidEncryption(t *testing.T) {
+	t.Parallel()
+
+	r := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: validContainer},
+		zipEntry{name: "content.opf", data: minimalOPF},
+		zipEntry{name: encryptionPath, data: []byte(`<encryption>`)},
+		zipEntry{name: lcpLicensePath, data: []byte(`{}`)},
+	)
+
+	epub, issues, err := ParseEPUBLenient(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, DRMReadiumLCP, epub.Encryption.DRM)
+	require.NotEmpty(t, issues)
+	assert.ErrorIs(t, issues[0], ErrInvalidEncryption)
+}
+
+func TestEPUB_Deobfuscate(t *testing.T) {
+	t.Parallel()
+
+	font := bytes.Repeat([]byte{0xAA}, 2048)
+	epub := EPUB{
+		Package: Package{
+			UniqueIdentifier: "uid",
+			Metadata: Metadata{Identifiers: []Identifier{
+				{ID: "isbn"},
+				{ID: " urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9\n", ElementID: "uid"},
+			}},
+		},
+		Encryption: Encryption{Obfuscated: map[string]string{
+			"idpf.otf":  ObfuscationIDPF,
+			"adobe.otf": ObfuscationAdobe,
+		}},
+	}
+
+	t.Run("idpf", func(t *testing.T) {
+		t.Parallel()
+
+		data := bytes.Clone(font)
+		require.NoError(t, epub.Deobfuscate("idpf.otf", data))
+		key := sha1.Sum([]byte("urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9"))
+		assert.Equal(t, 0xAA^key[0], data[0])
+		assert.Equal(t, 0xAA^key[1039%20], data[1039])
+		assert.Equal(t, font[1040:], data[1040:])
+
+		require.NoError(t, epub.Deobfuscate("idpf.otf", data))
+		assert.Equal(t, font, data, "obfuscation is its own inverse")
+	})
+
+	t.Run("adobe", func(t *testing.T) {
+		t.Parallel()
+
+		data := bytes.Clone(font)
+		require.NoError(t, epub.Deobfuscate("adobe.otf", data))
+		assert.Equal(t, byte(0xAA^0x07), data[0])
+		assert.Equal(t, byte(0xAA^0xa9), data[1023])
+		assert.Equal(t, font[1024:], data[1024:])
+	})
+
+	t.Run("not obfuscated", func(t *testing.T) {
+		t.Parallel()
+
+		data := bytes.Clone(font)
+		require.NoError(t, epub.Deobfuscate("plain.otf", data))
+		assert.Equal(t, font, data)
+	})
+
+	t.Run("no identifier", func(t *testing.T) {
+		t.Parallel()
+
+		noID := EPUB{Encryption: epub.Encryption}
+		require.ErrorIs(t, noID.Deobfuscate("idpf.otf", bytes.Clone(font)), ErrNoObfuscationKey)
+		require.ErrorIs(t, noID.Deobfuscate("adobe.otf", bytes.Clone(font)), ErrNoObfuscationKey)
+	})
+}
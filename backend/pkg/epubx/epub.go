@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 )
 
 const (
@@ -29,6 +30,9 @@ type Package struct {
 	Version          string   `xml:"version,attr"`
 	UniqueIdentifier string   `xml:"unique-identifier,attr"`
 	Metadata         Metadata `xml:"metadata"`
+	Manifest         Manifest `xml:"manifest"`
+	Spine            Spine    `xml:"spine"`
+	Guide            Guide    `xml:"guide"`
 }
 
 // Metadata contains the Dublin Core elements and EPUB 3 meta properties
@@ -52,12 +56,19 @@ type Metadata struct {
 type Identifier struct {
 	ID     string `xml:",chardata"`
 	Scheme string `xml:"scheme,attr,omitempty"`
+	// ElementID is the id attribute, the package's unique-identifier refers to it.
+	ElementID string `xml:"id,attr,omitempty"`
 }
 
+// Author is a creator or contributor. Role, a MARC relator code such as aut, ill or trl, and FileAs
+// are EPUB 2 attributes, in EPUB 3 they are metas refining the author's ID.
 type Author struct {
-	Name string `xml:",chardata"`
-	ID   string `xml:"id,attr,omitempty"`
-	Role string `xml:"role,attr,omitempty"` // EPUB 2
+	Name   string `xml:",chardata"`
+	ID     string `xml:"id,attr,omitempty"`
+	Role   string `xml:"role,attr,omitempty"`
+	FileAs string `xml:"file-as,attr,omitempty"`
+	// DisplaySeq is the EPUB 3 display-seq, zero if not set.
+	DisplaySeq int `xml:"-"`
 }
 
 type MetaProperty struct {
@@ -65,74 +76,222 @@ type MetaProperty struct {
 	Refines  string `xml:"refines,attr,omitempty"`
 	ID       string `xml:"id,attr,omitempty"`
 	Value    string `xml:",chardata"`
+
+	// Name and Content are set for EPUB 2 metas such as <meta name="cover" content="cover-id"/>.
+	Name    string `xml:"name,attr,omitempty"`
+	Content string `xml:"content,attr,omitempty"`
+}
+
+type Manifest struct {
+	Items []ManifestItem `xml:"item"`
+}
+
+type ManifestItem struct {
+	ID        string `xml:"id,attr"`
+	Href      string `xml:"href,attr"`
+	MediaType string `xml:"media-type,attr"`
+	// Properties is a space separated list, e.g. "cover-image" or "nav".
+	Properties string `xml:"properties,attr,omitempty"`
+	// Fallback is the id of the item to use instead if reading systems do not support MediaType.
+	Fallback string `xml:"fallback,attr,omitempty"`
+	// Path is Href resolved to a path in the container.
+	Path string `xml:"-"`
+}
+
+type Spine struct {
+	// Toc is the manifest id of the EPUB 2 NCX.
+	Toc string `xml:"toc,attr,omitempty"`
+	// PageProgressionDirection is ltr, rtl or empty for the reading system's default.
+	PageProgressionDirection string    `xml:"page-progression-direction,attr,omitempty"`
+	ItemRefs                 []ItemRef `xml:"itemref"`
+}
+
+type ItemRef struct {
+	ID    string `xml:"id,attr,omitempty"`
+	IDRef string `xml:"idref,attr"`
+	// Linear is "no" for auxiliary content, such as footnotes, outside of the reading order.
+	Linear     string `xml:"linear,attr,omitempty"`
+	Properties string `xml:"properties,attr,omitempty"`
+}
+
+func (r ItemRef) IsLinear() bool {
+	return r.Linear != "no"
+}
+
+// Guide is the EPUB 2 list of structural parts, deprecated by the EPUB 3 landmarks nav.
+type Guide struct {
+	References []GuideReference `xml:"reference"`
+}
+
+type GuideReference struct {
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	// Path is Href without the fragment resolved to a path in the container.
+	Path string `xml:"-"`
 }
 
 type EPUB struct {
 	Package
+	// RootFile is the path of the package document in the container, hrefs are relative to it.
+	RootFile string `xml:"-"`
+	// TOC is read from the EPUB 3 nav document, or from the EPUB 2 NCX if there is none.
+	TOC []TOCEntry `xml:"-"`
+	// Encryption tells whether the book is DRM protected and which of its fonts are obfuscated.
+	Encryption Encryption `xml:"-"`
 }
 
+// SpineItem is an item of the reading order.
+type SpineItem struct {
+	ManifestItem
+	Linear bool
+}
+
+// ReadingOrder returns the manifest items of the spine in order, itemrefs to missing items are left out.
+func (e EPUB) ReadingOrder() []SpineItem {
+	items := make([]SpineItem, 0, len(e.Spine.ItemRefs))
+	for _, ref := range e.Spine.ItemRefs {
+		if item, ok := e.Manifest.Item(ref.IDRef); ok {
+			items = append(items, SpineItem{ManifestItem: item, Linear: ref.IsLinear()})
+		}
+	}
+	return items
+}
+
+// ParseEPUB parses the EPUB in r, it fails on the first issue of SeverityError. Issues of the
+// package content, such as a missing NCX, are not checked, see ParseEPUBLenient and Validate.
 func ParseEPUB(r io.ReaderAt, size int64) (EPUB, error) {
+	p := parser{failFast: true}
+	return p.parse(r, size)
+}
+
+// ParseEPUBLenient parses the EPUB in r like a reading system would, it records every deviation
+// from the spec as an issue and only fails if the book cannot be read at all.
+func ParseEPUBLenient(r io.ReaderAt, size int64) (EPUB, []Issue, error) {
+	var p parser
+	epub, err := p.parse(r, size)
+	if err != nil {
+		return EPUB{}, p.issues, err
+	}
+	p.checkPackage(epub)
+	return epub, p.issues, nil
+}
+
+type parser struct {
+	// failFast makes the first issue of SeverityError fail the parse.
+	failFast bool
+	files    map[string]*zip.File
+	issues   []Issue
+}
+
+// report records an issue, it returns the issue if the parse must fail on it.
+func (p *parser) report(severity Severity, err error, detail string) error {
+	issue := Issue{Severity: severity, Err: err, Detail: detail}
+	if p.failFast && severity == SeverityError {
+		return issue
+	}
+	p.issues = append(p.issues, issue)
+	return nil
+}
+
+func (p *parser) parse(r io.ReaderAt, size int64) (EPUB, error) {
 	zr, err := zip.NewReader(r, size)
 	if err != nil {
 		return EPUB{}, err
 	}
 
 	if len(zr.File) > 0 && zr.File[0].Name != "mimetype" {
-		return EPUB{}, ErrMimetypeNotFirst
+		if err := p.report(SeverityError, ErrMimetypeNotFirst, ""); err != nil {
+			return EPUB{}, err
+		}
 	}
 
-	fileMap := make(map[string]*zip.File, len(zr.File))
+	p.files = make(map[string]*zip.File, len(zr.File))
 	for _, zfile := range zr.File {
-		fileMap[zfile.Name] = zfile
+		p.files[zfile.Name] = zfile
 	}
 
-	if fileMap[containerPath] == nil {
+	if p.files[containerPath] == nil {
 		return EPUB{}, fmt.Errorf("%w: %s", ErrMissingContainerXML, containerPath)
 	}
 
-	contentOPFFilePath, err := parseContainer(fileMap[containerPath])
+	contentOPFFilePath, err := parseContainer(p.files[containerPath])
 	if err != nil {
 		return EPUB{}, err
 	}
-	if mf := fileMap["mimetype"]; mf == nil {
-		return EPUB{}, ErrMissingMimetype
+	mf := p.files["mimetype"]
+	if mf == nil {
+		if err := p.report(SeverityError, ErrMissingMimetype, ""); err != nil {
+			return EPUB{}, err
+		}
 	} else if mf.Method != zip.Store {
-		return EPUB{}, ErrMimetypeCompressed
+		if err := p.report(SeverityError, ErrMimetypeCompressed, ""); err != nil {
+			return EPUB{}, err
+		}
 	}
-	if fileMap[contentOPFFilePath] == nil {
-		return EPUB{}, ErrMissingContentOPF
+	if p.files[contentOPFFilePath] == nil {
+		return EPUB{}, fmt.Errorf("%w: %s", ErrMissingContentOPF, contentOPFFilePath)
+	}
+
+	if mf != nil {
+		if err := p.checkMimetype(mf); err != nil {
+			return EPUB{}, err
+		}
 	}
 
-	mr, err := fileMap["mimetype"].Open()
+	cr, err := p.files[contentOPFFilePath].Open()
 	if err != nil {
 		return EPUB{}, err
 	}
-	defer mr.Close()
+	defer cr.Close()
 
-	buf := make([]byte, len(expectedMimetype))
-	n, err := io.ReadFull(mr, buf)
-	if err != nil && err != io.ErrUnexpectedEOF {
+	var epub EPUB
+	err = xml.NewDecoder(cr).Decode(&epub)
+	if err != nil {
 		return EPUB{}, err
 	}
-	if n != len(expectedMimetype) || string(buf) != expectedMimetype {
-		return EPUB{}, ErrInvalidMimetype
+	epub.RootFile = contentOPFFilePath
+	epub.Metadata.applyRefinements()
+	for i, item := range epub.Manifest.Items {
+		epub.Manifest.Items[i].Path, _ = resolveHref(contentOPFFilePath, item.Href)
+	}
+	for i, ref := range epub.Guide.References {
+		epub.Guide.References[i].Path, _ = resolveHref(contentOPFFilePath, ref.Href)
 	}
 
-	cr, err := fileMap[contentOPFFilePath].Open()
+	// readers can still page through a book without its table of contents
+	epub.TOC, err = parseTOC(epub, p.files)
 	if err != nil {
-		return EPUB{}, err
+		epub.TOC = nil
+		p.report(SeverityWarning, err, "")
 	}
-	defer cr.Close()
 
-	var epub EPUB
-	err = xml.NewDecoder(cr).Decode(&epub)
+	epub.Encryption, err = parseEncryption(p.files)
 	if err != nil {
-		return EPUB{}, err
+		p.report(SeverityWarning, err, "")
 	}
 
 	return epub, nil
 }
 
+func (p *parser) checkMimetype(f *zip.File) error {
+	mr, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer mr.Close()
+
+	buf := make([]byte, len(expectedMimetype))
+	n, err := io.ReadFull(mr, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if string(buf[:n]) != expectedMimetype {
+		return p.report(SeverityError, ErrInvalidMimetype, strconv.Quote(string(buf[:n])))
+	}
+	return nil
+}
+
 func parseContainer(f *zip.File) (string, error) {
 	r, err := f.Open()
 	if err != nil {
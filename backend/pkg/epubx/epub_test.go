@@ -31,14 +31,14 @@ func TestParseEPUB(t *testing.T) {
 
 	assert.Equal(t, []Identifier{
 		{ID: "5-94955-003-X", Scheme: "ISBN"},
-		{ID: "07aada78-943e-44a1-b680-4b2edba53ba9", Scheme: "uuid"},
+		{ID: "07aada78-943e-44a1-b680-4b2edba53ba9", Scheme: "uuid", ElementID: "uuid_id"},
 	}, meta.Identifiers)
 
 	assert.Equal(t, []string{"Воин"}, meta.Titles)
 	assert.Equal(t, []string{"ru"}, meta.Languages)
 
 	assert.Equal(t, []Author{
-		{Name: "Роберт Энтони Сальваторе", Role: "aut"},
+		{Name: "Роберт Энтони Сальваторе", Role: "aut", FileAs: "Unknown"},
 	}, meta.Creators)
 
 	assert.Equal(t, []Author{
@@ -71,14 +71,15 @@ func zipWithFile(t *testing.T, name string, data []byte) *zip.File {
 }
 
 // buildEPUBZip creates an in-memory zip with the given ordered files and returns a *bytes.Reader.
-// Files are written in the order provided. The mimetype entry uses zip.Store (uncompressed) per EPUB spec.
+// Files are written in the order provided. The mimetype entry uses zip.Store (uncompressed) per EPUB spec,
+// unless it sets deflate.
 func buildEPUBZip(t *testing.T, files ...zipEntry) *bytes.Reader {
 	t.Helper()
 	var buf bytes.Buffer
 	w := zip.NewWriter(&buf)
 	for _, f := range files {
 		method := zip.Deflate
-		if f.name == "mimetype" {
+		if f.name == "mimetype" && !f.deflate {
 			method = zip.Store
 		}
 		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
@@ -91,8 +92,9 @@ func buildEPUBZip(t *testing.T, files ...zipEntry) *bytes.Reader {
 }
 
 type zipEntry struct {
-	name string
-	data []byte
+	name    string
+	data    []byte
+	deflate bool
 }
 
 var (
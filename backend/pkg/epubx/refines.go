@@ -0,0 +1,120 @@
+package epubx
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Collection is a group the publication belongs to, from an EPUB 3 belongs-to-collection
+// or calibre's series metas.
+type Collection struct {
+	Name string
+	// Type is "series" or "set", empty if the EPUB does not tell.
+	Type string
+	// Position is the place of the publication in the collection, zero if unknown.
+	Position float64
+}
+
+// applyRefinements applies the EPUB 3 role, file-as and display-seq metas refining creators and
+// contributors, and orders them by display-seq.
+func (m *Metadata) applyRefinements() {
+	for _, authors := range [][]Author{m.Creators, m.Contributors} {
+		byID := make(map[string]*Author, len(authors))
+		for i := range authors {
+			if authors[i].ID != "" {
+				byID["#"+authors[i].ID] = &authors[i]
+			}
+		}
+		for _, meta := range m.Meta {
+			a := byID[meta.Refines]
+			if a == nil {
+				continue
+			}
+			value := strings.TrimSpace(meta.Value)
+			switch meta.Property {
+			case "role":
+				a.Role = value
+			case "file-as":
+				a.FileAs = value
+			case "display-seq":
+				a.DisplaySeq, _ = strconv.Atoi(value)
+			}
+		}
+		// Unnumbered ones keep their order after the numbered ones.
+		slices.SortStableFunc(authors, func(a, b Author) int {
+			switch {
+			case a.DisplaySeq > 0 && b.DisplaySeq > 0:
+				return cmp.Compare(a.DisplaySeq, b.DisplaySeq)
+			case a.DisplaySeq > 0:
+				return -1
+			case b.DisplaySeq > 0:
+				return 1
+			}
+			return 0
+		})
+	}
+}
+
+// Collections returns the EPUB 3 belongs-to-collection metas with their refinements.
+func (m Metadata) Collections() []Collection {
+	var collections []Collection
+	for _, meta := range m.Meta {
+		if meta.Property != "belongs-to-collection" || meta.Refines != "" {
+			continue
+		}
+		c := Collection{Name: strings.TrimSpace(meta.Value)}
+		if meta.ID != "" {
+			for _, r := range m.Meta {
+				if r.Refines != "#"+meta.ID {
+					continue
+				}
+				switch r.Property {
+				case "collection-type":
+					c.Type = strings.TrimSpace(r.Value)
+				case "group-position":
+					c.Position, _ = strconv.ParseFloat(strings.TrimSpace(r.Value), 64)
+				}
+			}
+		}
+		collections = append(collections, c)
+	}
+	return collections
+}
+
+// Series returns the first collection of type series, or the series calibre wrote into an EPUB 2 package.
+func (m Metadata) Series() (Collection, bool) {
+	for _, c := range m.Collections() {
+		if c.Type == "series" && c.Name != "" {
+			return c, true
+		}
+	}
+
+	var c Collection
+	for _, meta := range m.Meta {
+		switch meta.Name {
+		case "calibre:series":
+			c.Name = strings.TrimSpace(meta.Content)
+		case "calibre:series_index":
+			c.Position, _ = strconv.ParseFloat(strings.TrimSpace(meta.Content), 64)
+		}
+	}
+	if c.Name == "" {
+		return Collection{}, false
+	}
+	c.Type = "series"
+	return c, true
+}
+
+// Modified returns the dcterms:modified time of the publication, EPUB 3 requires one.
+func (m Metadata) Modified() (time.Time, bool) {
+	for _, meta := range m.Meta {
+		if meta.Property == "dcterms:modified" && meta.Refines == "" {
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(meta.Value))
+			return t, err == nil
+		}
+	}
+	return time.Time{}, false
+}
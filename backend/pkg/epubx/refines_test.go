@@ -0,0 +1,83 @@
+package epubx
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEPUB_refines(t *testing.T) {
+	t.Parallel()
+
+	opf := []byte(`<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Homeland</dc:title>
+    <dc:creator id="ill">Todd Lockwood</dc:creator>
+    <meta refines="#ill" property="role" scheme="marc:relators">ill</meta>
+    <meta refines="#ill" property="display-seq">2</meta>
+    <dc:creator id="aut">R. A. Salvatore</dc:creator>
+    <meta refines="#aut" property="role" scheme="marc:relators">aut</meta>
+    <meta refines="#aut" property="file-as">Salvatore, R. A.</meta>
+    <meta refines="#aut" property="display-seq">1</meta>
+    <dc:creator>Anonymous</dc:creator>
+    <dc:contributor id="trl">Н. Некрасова</dc:contributor>
+    <meta refines="#trl" property="role" scheme="marc:relators">trl</meta>
+    <meta property="belongs-to-collection" id="set">Forgotten Realms</meta>
+    <meta refines="#set" property="collection-type">set</meta>
+    <meta property="belongs-to-collection" id="series">The Dark Elf Trilogy</meta>
+    <meta refines="#series" property="collection-type">series</meta>
+    <meta refines="#series" property="group-position">1</meta>
+    <meta property="dcterms:modified">2024-05-06T07:08:09Z</meta>
+  </metadata>
+</package>`)
+	r := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: validContainer},
+		zipEntry{name: "content.opf", data: opf},
+	)
+	epub, err := ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	meta := epub.Metadata
+
+	assert.Equal(t, []Author{
+		{Name: "R. A. Salvatore", ID: "aut", Role: "aut", FileAs: "Salvatore, R. A.", DisplaySeq: 1},
+		{Name: "Todd Lockwood", ID: "ill", Role: "ill", DisplaySeq: 2},
+		{Name: "Anonymous"},
+	}, meta.Creators)
+	assert.Equal(t, []Author{{Name: "Н. Некрасова", ID: "trl", Role: "trl"}}, meta.Contributors)
+
+	assert.Equal(t, []Collection{
+		{Name: "Forgotten Realms", Type: "set"},
+		{Name: "The Dark Elf Trilogy", Type: "series", Position: 1},
+	}, meta.Collections())
+	series, ok := meta.Series()
+	require.True(t, ok)
+	assert.Equal(t, "The Dark Elf Trilogy", series.Name)
+
+	modified, ok := meta.Modified()
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC), modified)
+}
+
+func TestMetadata_Series_calibre(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+	epub, err := ParseEPUB(f, info.Size())
+	require.NoError(t, err)
+
+	series, ok := epub.Metadata.Series()
+	require.True(t, ok)
+	assert.Equal(t, Collection{Name: "Забытые королевства: Темный эльф", Type: "series", Position: 3}, series)
+
+	_, ok = epub.Metadata.Modified()
+	assert.False(t, ok, "EPUB 2 packages have no dcterms:modified")
+}
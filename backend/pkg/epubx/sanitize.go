@@ -0,0 +1,136 @@
+package epubx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/xmlx"
+)
+
+var ErrMalformedXML = errors.New("malformed xml")
+
+// droppedElements are removed together with their content, they run code or load other documents.
+var droppedElements = []string{"script", "iframe", "frame", "frameset", "object", "embed", "applet", "base"}
+
+// urlAttributes are the attributes SanitizeXHTML checks for script URLs.
+var urlAttributes = []string{"href", "src", "action", "formaction", "data", "poster", "background"}
+
+// SanitizeXHTML removes what could run code from an XHTML or SVG document of a book: script and
+// embedding elements, refresh metas, event handler attributes and javascript: URLs. The rest of the
+// document is kept, but re-serialized, empty elements get an explicit end tag.
+func SanitizeXHTML(data []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+	d.Entity = xml.HTMLEntity
+
+	var out bytes.Buffer
+	var open []xml.Name
+	skipDepth := 0
+	for {
+		// RawToken keeps the prefixes as written, so namespaces survive unchanged,
+		// but it does not match end tags to start tags, open does.
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			open = append(open, t.Name)
+			if skipDepth > 0 || isDropped(t) {
+				skipDepth++
+				continue
+			}
+			out.WriteByte('<')
+			out.WriteString(qualifiedName(t.Name))
+			for _, a := range t.Attr {
+				if isUnsafeAttr(a) {
+					continue
+				}
+				out.WriteByte(' ')
+				out.WriteString(qualifiedName(a.Name))
+				out.WriteString(`="`)
+				xmlx.WriteAttr(&out, a.Value)
+				out.WriteByte('"')
+			}
+			out.WriteByte('>')
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, fmt.Errorf("%w: unexpected </%s>", ErrMalformedXML, qualifiedName(t.Name))
+			}
+			open = open[:len(open)-1]
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</")
+			out.WriteString(qualifiedName(t.Name))
+			out.WriteByte('>')
+		case xml.CharData:
+			if skipDepth == 0 {
+				xmlx.WriteText(&out, string(t))
+			}
+		case xml.Comment:
+			// Comments are dropped, they could hide conditional comments for old browsers.
+		case xml.ProcInst:
+			if skipDepth == 0 && t.Target == "xml" {
+				// The decoder only reads UTF-8, so that is what the output is.
+				out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+			}
+		case xml.Directive:
+			if skipDepth == 0 {
+				out.WriteString("<!")
+				out.Write(t)
+				out.WriteByte('>')
+			}
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("%w: unclosed <%s>", ErrMalformedXML, qualifiedName(open[len(open)-1]))
+	}
+	return out.Bytes(), nil
+}
+
+func isDropped(e xml.StartElement) bool {
+	name := strings.ToLower(e.Name.Local)
+	if slices.Contains(droppedElements, name) {
+		return true
+	}
+	if name == "meta" {
+		return slices.ContainsFunc(e.Attr, func(a xml.Attr) bool { return strings.EqualFold(a.Name.Local, "http-equiv") })
+	}
+	return false
+}
+
+func isUnsafeAttr(a xml.Attr) bool {
+	name := strings.ToLower(a.Name.Local)
+	if strings.HasPrefix(name, "on") {
+		return true
+	}
+	if !slices.Contains(urlAttributes, name) {
+		return false
+	}
+	// Browsers ignore whitespace and control characters in the scheme.
+	value := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(a.Value))
+	return strings.HasPrefix(value, "javascript:") || strings.HasPrefix(value, "vbscript:") ||
+		strings.HasPrefix(value, "data:text/html") || strings.HasPrefix(value, "data:image/svg")
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
@@ -0,0 +1,70 @@
+package epubx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeXHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "document structure is kept",
+			input: `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><head><title>Глава 1</title></head>` +
+				`<body><p class="a" epub:type="z3998:fiction">Дзирт&nbsp;&amp; <em>Гвенвивар</em><br/></p></body></html>`,
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><head><title>Глава 1</title></head>` +
+				"<body><p class=\"a\" epub:type=\"z3998:fiction\">Дзирт &amp; <em>Гвенвивар</em><br></br></p></body></html>",
+		},
+		{
+			name:     "scripts and embeds with their content",
+			input:    `<body><script>alert(1)</script><p>a</p><object data="x.swf"><param name="p"/></object><iframe src="https://evil"/></body>`,
+			expected: `<body><p>a</p></body>`,
+		},
+		{
+			name:     "event handlers and script urls",
+			input:    `<p onclick="steal()" ONLOAD="x"><a href=" java&#x09;script:alert(1)">x</a><a href="ch2.xhtml#n1">y</a><img src="data:image/svg+xml;base64,PHN2Zz4="/></p>`,
+			expected: `<p><a>x</a><a href="ch2.xhtml#n1">y</a><img></img></p>`,
+		},
+		{
+			name:     "svg with namespaced links",
+			input:    `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><image xlink:href="javascript:x()"/><image xlink:href="cover.jpg"/></svg>`,
+			expected: `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><image></image><image xlink:href="cover.jpg"></image></svg>`,
+		},
+		{
+			name:     "refresh meta and base",
+			input:    `<head><meta http-equiv="refresh" content="0;url=https://evil"/><base href="https://evil/"/><meta charset="utf-8"/></head>`,
+			expected: `<head><meta charset="utf-8"></meta></head>`,
+		},
+		{
+			name:     "comments",
+			input:    `<p><!--[if IE]><script>x</script><![endif]-->a</p>`,
+			expected: `<p>a</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			out, err := SanitizeXHTML([]byte(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(out))
+		})
+	}
+
+	for _, malformed := range []string{`<p><b>unclosed</p>`, `<p>`, `</p>`} {
+		_, err := SanitizeXHTML([]byte(malformed))
+		assert.ErrorIs(t, err, ErrMalformedXML, malformed)
+	}
+}
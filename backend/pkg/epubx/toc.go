@@ -0,0 +1,230 @@
+package epubx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	opsNamespace = "http://www.idpf.org/2007/ops"
+	ncxMediaType = "application/x-dtbncx+xml"
+)
+
+var ErrInvalidTOC = errors.New("invalid table of contents")
+
+// TOCEntry is an entry of the table of contents. Path is the container path of the document it
+// links to, it is empty for headings that link nowhere and for links out of the book.
+type TOCEntry struct {
+	Title    string
+	Path     string
+	Fragment string
+	Children []TOCEntry
+}
+
+// resolveHref resolves the relative href of a document at base to a container path and a fragment.
+// The path is empty for absolute URLs and hrefs that point out of the container.
+func resolveHref(base, href string) (string, string) {
+	u, err := url.Parse(href)
+	if err != nil || u.IsAbs() || u.Host != "" {
+		return "", ""
+	}
+	if u.Path == "" {
+		return base, u.Fragment
+	}
+	p := path.Join(path.Dir(base), u.Path)
+	if strings.HasPrefix(u.Path, "/") {
+		p = path.Clean(strings.TrimPrefix(u.Path, "/"))
+	}
+	if !fs.ValidPath(p) {
+		return "", ""
+	}
+	return p, u.Fragment
+}
+
+// parseTOC reads the TOC from the nav document if the manifest has one, otherwise from the NCX.
+// A book without either has no TOC.
+func parseTOC(epub EPUB, files map[string]*zip.File) ([]TOCEntry, error) {
+	i := slices.IndexFunc(epub.Manifest.Items, func(item ManifestItem) bool { return item.HasProperty("nav") })
+	if i >= 0 {
+		if f := files[epub.Manifest.Items[i].Path]; f != nil {
+			return readTOC(f, parseNav)
+		}
+	}
+
+	ncx, ok := epub.Manifest.Item(epub.Spine.Toc)
+	if !ok {
+		i := slices.IndexFunc(epub.Manifest.Items, func(item ManifestItem) bool { return item.MediaType == ncxMediaType })
+		if i < 0 {
+			return nil, nil
+		}
+		ncx = epub.Manifest.Items[i]
+	}
+	if f := files[ncx.Path]; f != nil {
+		return readTOC(f, parseNCX)
+	}
+	return nil, nil
+}
+
+func readTOC(f *zip.File, parse func(r io.Reader, docPath string) ([]TOCEntry, error)) ([]TOCEntry, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	entries, err := parse(r, f.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidTOC, f.Name, err)
+	}
+	return entries, nil
+}
+
+type ncxDocument struct {
+	NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+}
+
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	NavPoints []ncxNavPoint `xml:"navPoint"`
+}
+
+func parseNCX(r io.Reader, docPath string) ([]TOCEntry, error) {
+	var doc ncxDocument
+	d := xml.NewDecoder(r)
+	d.Entity = xml.HTMLEntity
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var convert func(points []ncxNavPoint) []TOCEntry
+	convert = func(points []ncxNavPoint) []TOCEntry {
+		var entries []TOCEntry
+		for _, p := range points {
+			entry := TOCEntry{Title: collapseSpace(p.Label), Children: convert(p.NavPoints)}
+			entry.Path, entry.Fragment = resolveHref(docPath, p.Content.Src)
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	return convert(doc.NavPoints), nil
+}
+
+type navList struct {
+	Items []navListItem `xml:"li"`
+}
+
+type navListItem struct {
+	Link    *navLabel `xml:"a"`
+	Heading *navLabel `xml:"span"`
+	List    *navList  `xml:"ol"`
+}
+
+// navLabel is the text of the a or span of a nav list item, and the href of an a.
+type navLabel struct {
+	Href string
+	Text string
+}
+
+func (l *navLabel) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var title string
+	for _, a := range start.Attr {
+		switch a.Name.Local {
+		case "href":
+			l.Href = a.Value
+		case "title":
+			title = a.Value
+		}
+	}
+
+	var text strings.Builder
+	for depth := 0; depth >= 0; {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	l.Text = collapseSpace(text.String())
+	if l.Text == "" {
+		l.Text = title
+	}
+	return nil
+}
+
+// parseNav reads the entries of the <nav epub:type="toc"> of an EPUB 3 navigation document.
+func parseNav(r io.Reader, docPath string) ([]TOCEntry, error) {
+	d := xml.NewDecoder(r)
+	d.Entity = xml.HTMLEntity
+
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "nav" || !hasEPUBType(start, "toc") {
+			continue
+		}
+
+		var nav struct {
+			List navList `xml:"ol"`
+		}
+		if err := d.DecodeElement(&nav, &start); err != nil {
+			return nil, err
+		}
+		return nav.List.entries(docPath), nil
+	}
+}
+
+func (l *navList) entries(docPath string) []TOCEntry {
+	if l == nil {
+		return nil
+	}
+	var entries []TOCEntry
+	for _, item := range l.Items {
+		var entry TOCEntry
+		switch {
+		case item.Link != nil:
+			entry.Title = item.Link.Text
+			entry.Path, entry.Fragment = resolveHref(docPath, item.Link.Href)
+		case item.Heading != nil:
+			entry.Title = item.Heading.Text
+		}
+		entry.Children = item.List.entries(docPath)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func hasEPUBType(e xml.StartElement, typ string) bool {
+	for _, a := range e.Attr {
+		if a.Name.Space == opsNamespace && a.Name.Local == "type" {
+			return slices.Contains(strings.Fields(a.Value), typ)
+		}
+	}
+	return false
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,175 @@
+package epubx
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEPUB_spineAndNCX(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	epub, err := ParseEPUB(f, info.Size())
+	require.NoError(t, err)
+
+	assert.Equal(t, "ncx", epub.Spine.Toc)
+	order := epub.ReadingOrder()
+	require.Len(t, order, len(epub.Spine.ItemRefs))
+	assert.Equal(t, SpineItem{
+		ManifestItem: ManifestItem{ID: "titlepage", Href: "titlepage.xhtml", MediaType: "application/xhtml+xml", Path: "titlepage.xhtml"},
+		Linear:       true,
+	}, order[0])
+	assert.Equal(t, "index_split_000.xhtml", order[1].Path)
+
+	assert.Equal(t, []GuideReference{{Type: "cover", Title: "Cover", Href: "titlepage.xhtml", Path: "titlepage.xhtml"}}, epub.Guide.References)
+
+	require.NotEmpty(t, epub.TOC)
+	assert.Equal(t, TOCEntry{Title: "Роберт САЛЬВАТОРЕ ВОИН", Path: "index_split_001.xhtml"}, epub.TOC[0])
+	assert.Equal(t, "Часть 1 ВОСХОД", epub.TOC[2].Title)
+}
+
+func TestParseEPUB_nav(t *testing.T) {
+	t.Parallel()
+
+	opf := []byte(`<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Test</dc:title></metadata>
+  <manifest>
+    <item id="nav" href="nav/toc.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="ch1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="notes" href="text/notes.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine toc="ncx" page-progression-direction="rtl">
+    <itemref idref="ch1"/>
+    <itemref idref="missing"/>
+    <itemref idref="notes" linear="no"/>
+  </spine>
+</package>`)
+	nav := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<body>
+  <nav epub:type="landmarks"><ol><li><a href="../text/chapter%201.xhtml">Start</a></li></ol></nav>
+  <section>
+    <nav epub:type="toc">
+      <h1>Contents</h1>
+      <ol>
+        <li><span>Part&nbsp;I</span>
+          <ol>
+            <li><a href="../text/chapter%201.xhtml#s1">Chapter <em>one</em>
+              </a></li>
+            <li><a href="https://example.com/">Website</a></li>
+          </ol>
+        </li>
+        <li><a href="../text/notes.xhtml" title="Notes"><img src="notes.png" alt=""/></a></li>
+      </ol>
+    </nav>
+  </section>
+</body>
+</html>`)
+	ncx := []byte(`<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/"><navMap>
+  <navPoint><navLabel><text>From the NCX</text></navLabel><content src="text/notes.xhtml"/></navPoint>
+</navMap></ncx>`)
+
+	r := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: nestedOPFContainer("OEBPS/content.opf")},
+		zipEntry{name: "OEBPS/content.opf", data: opf},
+		zipEntry{name: "OEBPS/nav/toc.xhtml", data: nav},
+		zipEntry{name: "OEBPS/toc.ncx", data: ncx},
+	)
+	epub, err := ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+
+	assert.Equal(t, "rtl", epub.Spine.PageProgressionDirection)
+	order := epub.ReadingOrder()
+	require.Len(t, order, 2)
+	assert.Equal(t, "OEBPS/text/chapter 1.xhtml", order[0].Path)
+	assert.True(t, order[0].Linear)
+	assert.Equal(t, "notes", order[1].ID)
+	assert.False(t, order[1].Linear)
+
+	assert.Equal(t, []TOCEntry{
+		{
+			Title: "Part I",
+			Children: []TOCEntry{
+				{Title: "Chapter one", Path: "OEBPS/text/chapter 1.xhtml", Fragment: "s1"},
+				{Title: "Website"},
+			},
+		},
+		{Title: "Notes", Path: "OEBPS/text/notes.xhtml"},
+	}, epub.TOC)
+}
+
+func TestParseEPUB_ncxHTMLEntities(t *testing.T) {
+	t.Parallel()
+
+	opf := []byte(`<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <manifest><item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/></manifest>
+  <spine toc="ncx"/>
+</package>`)
+	ncx := []byte(`<ncx><navMap><navPoint><navLabel><text>Глава&nbsp;1 &mdash; Начало</text></navLabel><content src="ch1.xhtml"/></navPoint></navMap></ncx>`)
+	r := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: validContainer},
+		zipEntry{name: "content.opf", data: opf},
+		zipEntry{name: "toc.ncx", data: ncx},
+	)
+	epub, err := ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, []TOCEntry{{Title: "Глава 1 — Начало", Path: "ch1.xhtml"}}, epub.TOC)
+}
+
+func TestParseEPUB_invalidTOC(t *testing.T) {
+	t.Parallel()
+
+	opf := []byte(`<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <manifest><item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/></manifest>
+  <spine toc="ncx"/>
+</package>`)
+	r := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: validContainer},
+		zipEntry{name: "content.opf", data: opf},
+		zipEntry{name: "toc.ncx", data: []byte(`<ncx><navMap><navPoint>`)},
+	)
+	epub, err := ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	assert.Empty(t, epub.TOC)
+}
+
+func Test_resolveHref(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		base, href     string
+		path, fragment string
+	}{
+		{base: "content.opf", href: "text/ch1.xhtml", path: "text/ch1.xhtml"},
+		{base: "OEBPS/content.opf", href: "text/ch%201.xhtml#p2", path: "OEBPS/text/ch 1.xhtml", fragment: "p2"},
+		{base: "OEBPS/nav/toc.xhtml", href: "../text/ch1.xhtml", path: "OEBPS/text/ch1.xhtml"},
+		{base: "OEBPS/nav/toc.xhtml", href: "#intro", path: "OEBPS/nav/toc.xhtml", fragment: "intro"},
+		{base: "OEBPS/content.opf", href: "/images/cover.jpg", path: "images/cover.jpg"},
+		{base: "OEBPS/content.opf", href: "../../etc/passwd"},
+		{base: "OEBPS/content.opf", href: "https://example.com/ch1.xhtml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			t.Parallel()
+
+			p, fragment := resolveHref(tt.base, tt.href)
+			assert.Equal(t, tt.path, p)
+			assert.Equal(t, tt.fragment, fragment)
+		})
+	}
+}
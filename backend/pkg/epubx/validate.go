@@ -0,0 +1,145 @@
+package epubx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrDuplicateID         = errors.New("duplicate id")
+	ErrMissingResource     = errors.New("manifest item not in container")
+	ErrUnknownMediaType    = errors.New("media type is not a core media type and has no fallback")
+	ErrMissingManifestItem = errors.New("reference to missing manifest item")
+	ErrMissingNCX          = errors.New("missing ncx")
+	ErrMissingNav          = errors.New("missing nav document")
+)
+
+// Severity is how bad an Issue is.
+type Severity string
+
+const (
+	// SeverityError issues break the container, ParseEPUB fails on them.
+	SeverityError Severity = "error"
+	// SeverityWarning issues are in the package content, reading systems work around them.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a deviation of an EPUB from the spec. Err is or wraps a sentinel of this package.
+type Issue struct {
+	Severity Severity
+	Err      error
+	// Detail names what deviates, such as an id or an href, it may be empty.
+	Detail string
+}
+
+func (i Issue) Error() string {
+	if i.Detail == "" {
+		return i.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", i.Err, i.Detail)
+}
+
+func (i Issue) Unwrap() error {
+	return i.Err
+}
+
+// Report is the result of Validate, the book is valid only without any issues. Errors that
+// ParseEPUB stops at are collected in Issues like warnings.
+type Report struct {
+	Version string
+	Issues  []Issue
+	// Err is why the book cannot be read at all, Issues has the issues found until then.
+	Err error
+}
+
+func (r Report) Valid() bool {
+	return r.Err == nil && len(r.Issues) == 0
+}
+
+// Validate checks the EPUB in r for every issue. It parses leniently, so one error does not hide
+// the issues after it, only an unreadable container stops it early and sets Report.Err.
+func Validate(r io.ReaderAt, size int64) Report {
+	epub, issues, err := ParseEPUBLenient(r, size)
+	return Report{Version: epub.Version, Issues: issues, Err: err}
+}
+
+// coreMediaTypes are the types reading systems must support, items of other types need a fallback.
+// The OEB 1 types are from EPUB 2.
+var coreMediaTypes = []string{
+	"application/xhtml+xml", "application/x-dtbncx+xml", "application/smil+xml", "application/pls+xml",
+	"image/gif", "image/jpeg", "image/png", "image/svg+xml", "image/webp",
+	"audio/mpeg", "audio/mp4", "audio/ogg",
+	"text/css", "application/javascript", "application/ecmascript", "text/javascript",
+	"font/ttf", "application/font-sfnt", "font/otf", "application/vnd.ms-opentype",
+	"font/woff", "application/font-woff", "font/woff2",
+	"text/x-oeb1-document", "text/x-oeb1-css",
+}
+
+func isCoreMediaType(mediaType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	// Video is exempt from the fallback requirement.
+	return slices.Contains(coreMediaTypes, mediaType) || strings.HasPrefix(mediaType, "video/")
+}
+
+// checkPackage reports the issues of the package document of epub, they are all warnings.
+func (p *parser) checkPackage(epub EPUB) {
+	ids := make(map[string]bool)
+	checkID := func(id string) {
+		if id == "" {
+			return
+		}
+		if ids[id] {
+			p.report(SeverityWarning, ErrDuplicateID, id)
+		}
+		ids[id] = true
+	}
+	for _, a := range slices.Concat(epub.Metadata.Creators, epub.Metadata.Contributors) {
+		checkID(a.ID)
+	}
+	for _, m := range epub.Metadata.Meta {
+		checkID(m.ID)
+	}
+
+	for _, item := range epub.Manifest.Items {
+		checkID(item.ID)
+		if item.Path != "" && p.files[item.Path] == nil || item.Path == "" && !isRemote(item.Href) {
+			p.report(SeverityWarning, ErrMissingResource, item.Href)
+		}
+		if item.Fallback == "" && !isCoreMediaType(item.MediaType) {
+			p.report(SeverityWarning, ErrUnknownMediaType, fmt.Sprintf("%s (%s)", item.Href, item.MediaType))
+		}
+	}
+
+	for _, ref := range epub.Spine.ItemRefs {
+		checkID(ref.ID)
+		if _, ok := epub.Manifest.Item(ref.IDRef); !ok {
+			p.report(SeverityWarning, ErrMissingManifestItem, ref.IDRef)
+		}
+	}
+	if _, ok := epub.Manifest.Item(epub.Spine.Toc); epub.Spine.Toc != "" && !ok {
+		p.report(SeverityWarning, ErrMissingManifestItem, epub.Spine.Toc)
+	}
+
+	// EPUB 3 replaced the NCX with the nav document, it still may have one for older readers.
+	if strings.HasPrefix(epub.Version, "3") {
+		if !slices.ContainsFunc(epub.Manifest.Items, func(item ManifestItem) bool { return item.HasProperty("nav") }) {
+			p.report(SeverityWarning, ErrMissingNav, "")
+		}
+	} else if !slices.ContainsFunc(epub.Manifest.Items, func(item ManifestItem) bool { return item.MediaType == ncxMediaType }) {
+		p.report(SeverityWarning, ErrMissingNCX, "")
+	}
+}
+
+// isRemote reports whether href is an absolute URL, EPUB 3 allows audio, video and fonts to be remote.
+func isRemote(href string) bool {
+	u, err := url.Parse(href)
+	return err == nil && u.IsAbs()
+}
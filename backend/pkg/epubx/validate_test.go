@@ -0,0 +1,126 @@
+package epubx
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var brokenOPF = []byte(`<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Воин</dc:title>
+    <dc:creator id="ch1">Роберт Сальваторе</dc:creator>
+  </metadata>
+  <manifest>
+    <item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch2" href="missing.xhtml" media-type="application/xhtml+xml"/>
+    <item id="font" href="font.ttf" media-type="application/x-font-truetype"/>
+    <item id="book" href="book.pdf" media-type="application/pdf" fallback="ch1"/>
+    <item id="video" href="https://example.com/trailer.mp4" media-type="video/mp4"/>
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="ch1"/>
+    <itemref idref="ch3"/>
+  </spine>
+</package>`)
+
+func TestParseEPUBLenient(t *testing.T) {
+	t.Parallel()
+
+	files := []zipEntry{
+		{name: "mimetype", data: validMimetype, deflate: true},
+		{name: "META-INF/container.xml", data: validContainer},
+		{name: "content.opf", data: brokenOPF},
+		{name: "ch1.xhtml", data: []byte(`<html/>`)},
+		{name: "font.ttf", data: []byte("font")},
+		{name: "book.pdf", data: []byte("%PDF")},
+	}
+
+	r := buildEPUBZip(t, files...)
+	_, err := ParseEPUB(r, r.Size())
+	require.ErrorIs(t, err, ErrMimetypeCompressed)
+
+	epub, issues, err := ParseEPUBLenient(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Воин"}, epub.Metadata.Titles)
+	assert.Equal(t, []Issue{
+		{Severity: SeverityError, Err: ErrMimetypeCompressed},
+		{Severity: SeverityWarning, Err: ErrDuplicateID, Detail: "ch1"},
+		{Severity: SeverityWarning, Err: ErrMissingResource, Detail: "missing.xhtml"},
+		{Severity: SeverityWarning, Err: ErrUnknownMediaType, Detail: "font.ttf (application/x-font-truetype)"},
+		{Severity: SeverityWarning, Err: ErrMissingManifestItem, Detail: "ch3"},
+		{Severity: SeverityWarning, Err: ErrMissingManifestItem, Detail: "ncx"},
+		{Severity: SeverityWarning, Err: ErrMissingNCX},
+	}, issues)
+}
+
+func TestParseEPUBLenient_invalidTOC(t *testing.T) {
+	t.Parallel()
+
+	opf := []byte(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Test</dc:title></metadata>
+  <manifest><item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/></manifest>
+</package>`)
+	r := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: validContainer},
+		zipEntry{name: "content.opf", data: opf},
+		zipEntry{name: "nav.xhtml", data: []byte(`<html><body><nav>`)},
+	)
+
+	epub, issues, err := ParseEPUBLenient(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Test"}, epub.Metadata.Titles)
+	assert.Empty(t, epub.TOC)
+	require.Len(t, issues, 1)
+	assert.Equal(t, SeverityWarning, issues[0].Severity)
+	assert.ErrorIs(t, issues[0], ErrInvalidTOC)
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := os.Open(filename)
+		require.NoError(t, err)
+		defer f.Close()
+		info, err := f.Stat()
+		require.NoError(t, err)
+
+		report := Validate(f, info.Size())
+		assert.True(t, report.Valid(), report.Issues)
+		assert.Equal(t, "2.0", report.Version)
+	})
+
+	t.Run("missing nav", func(t *testing.T) {
+		t.Parallel()
+
+		r := buildEPUBZip(t,
+			zipEntry{name: "mimetype", data: validMimetype},
+			zipEntry{name: "META-INF/container.xml", data: validContainer},
+			zipEntry{name: "content.opf", data: minimalOPF},
+		)
+		report := Validate(r, r.Size())
+		assert.False(t, report.Valid())
+		assert.NoError(t, report.Err)
+		assert.Equal(t, []Issue{{Severity: SeverityWarning, Err: ErrMissingNav}}, report.Issues)
+	})
+
+	t.Run("unreadable", func(t *testing.T) {
+		t.Parallel()
+
+		r := buildEPUBZip(t,
+			zipEntry{name: "content.opf", data: minimalOPF},
+			zipEntry{name: "mimetype", data: validMimetype},
+		)
+		report := Validate(r, r.Size())
+		assert.False(t, report.Valid())
+		assert.ErrorIs(t, report.Err, ErrMissingContainerXML)
+		assert.Equal(t, []Issue{{Severity: SeverityError, Err: ErrMimetypeNotFirst}}, report.Issues)
+	})
+}
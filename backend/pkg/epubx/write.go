@@ -0,0 +1,587 @@
+package epubx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/xmlx"
+)
+
+// MaxPackageSize is the size of the largest package document WriteMetadata rewrites.
+const MaxPackageSize = 16 << 20
+
+const (
+	dcNamespace  = "http://purl.org/dc/elements/1.1/"
+	opfNamespace = "http://www.idpf.org/2007/opf"
+
+	newCoverID = "goread-cover"
+)
+
+var (
+	ErrUnsupportedCover = errors.New("unsupported cover media type")
+	ErrMissingMetadata  = errors.New("package has no metadata")
+	ErrPackageTooLarge  = errors.New("package document too large")
+)
+
+var coverExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// MetadataUpdate is the metadata WriteMetadata writes into the package document. Nil fields are
+// left as they are in the book, empty ones remove what the book has.
+type MetadataUpdate struct {
+	Title *string
+	// Creators replace the authors among the creators, Role is a MARC relator code, aut if empty.
+	// Creators with other roles, such as translators and illustrators, are kept.
+	Creators []Author
+	// Series replaces the series, one without a name removes it.
+	Series   *Collection
+	Subjects []string
+	// Description is HTML, as in most books.
+	Description *string
+	// Identifiers replace the identifiers except the unique identifier, fonts may be obfuscated with
+	// a key derived from it. EPUB 3 has no scheme attribute, only the values are written there.
+	Identifiers []Identifier
+	Cover       *Cover
+	// Modified is written as the dcterms:modified of EPUB 3 packages, zero keeps it.
+	Modified time.Time
+}
+
+// Cover is an image to make the cover of a book.
+type Cover struct {
+	Data      []byte
+	MediaType string
+}
+
+// WriteMetadata writes the EPUB in r to w with u applied to its package document. The mimetype
+// entry is written first and uncompressed, all other entries but the package document and a
+// replaced cover are copied byte for byte, still compressed as they were.
+func WriteMetadata(w io.Writer, r io.ReaderAt, size int64, u MetadataUpdate) error {
+	epub, _, err := ParseEPUBLenient(r, size)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	pw := packageWriter{epub: epub, u: u}
+	var coverPath string
+	if u.Cover != nil {
+		ext, ok := coverExtensions[u.Cover.MediaType]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCover, u.Cover.MediaType)
+		}
+		if item, ok := epub.CoverItem(); ok && item.Path != "" {
+			pw.coverItemID, coverPath = item.ID, item.Path
+		} else {
+			pw.newCoverHref = newCoverID + ext
+			for i := 2; files[path.Join(path.Dir(epub.RootFile), pw.newCoverHref)] != nil; i++ {
+				pw.newCoverHref = fmt.Sprintf("%s-%d%s", newCoverID, i, ext)
+			}
+			coverPath = path.Join(path.Dir(epub.RootFile), pw.newCoverHref)
+		}
+	}
+
+	opf := files[epub.RootFile]
+	data, err := readLimited(opf, MaxPackageSize)
+	if err != nil {
+		return err
+	}
+	pkg, err := pw.rewrite(data)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	if mf := files["mimetype"]; mf != nil && mf.Method == zip.Store {
+		err = zw.Copy(mf)
+	} else {
+		err = writeEntry(zw, &zip.FileHeader{Name: "mimetype", Method: zip.Store}, []byte(expectedMimetype))
+	}
+	if err != nil {
+		return err
+	}
+
+	coverWritten := false
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "mimetype":
+		case f.FileInfo().IsDir():
+			// Copy fails for directories compressed to a few bytes of nothing.
+			_, err = zw.CreateHeader(&zip.FileHeader{Name: f.Name, Modified: f.Modified})
+		case f.Name == opf.Name:
+			err = writeEntry(zw, &zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: f.Modified}, pkg)
+		case u.Cover != nil && f.Name == coverPath:
+			err = writeEntry(zw, &zip.FileHeader{Name: f.Name, Method: zip.Store, Modified: f.Modified}, u.Cover.Data)
+			coverWritten = true
+		default:
+			err = zw.Copy(f)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if u.Cover != nil && !coverWritten {
+		err := writeEntry(zw, &zip.FileHeader{Name: coverPath, Method: zip.Store, Modified: opf.Modified}, u.Cover.Data)
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeEntry(zw *zip.Writer, h *zip.FileHeader, data []byte) error {
+	w, err := zw.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readLimited(f *zip.File, limit int64) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrPackageTooLarge
+	}
+	return data, nil
+}
+
+// packageWriter rewrites a package document token by token, so everything WriteMetadata does not
+// change stays as it was, except that the document is re-serialized.
+type packageWriter struct {
+	epub EPUB
+	u    MetadataUpdate
+	// coverItemID is the manifest item whose image is replaced, newCoverHref the href of a new one.
+	coverItemID  string
+	newCoverHref string
+
+	out bytes.Buffer
+	// startOpen is set while the > of the last start tag is not written yet, so an element without
+	// content can be closed with />.
+	startOpen bool
+	// namespaces maps prefixes declared on the package and metadata elements to their namespaces.
+	namespaces map[string]string
+	dc, opf    string
+	// droppedRefs are the "#id" refines of metas that refine dropped elements.
+	droppedRefs map[string]bool
+	// creatorRoles are the roles of the creators with an id, refinements included.
+	creatorRoles map[string]string
+	version3     bool
+	// identifiers counts the dc:identifier elements seen, keptIdentifier is the index of the one the
+	// obfuscation key derives from.
+	identifiers    int
+	keptIdentifier int
+}
+
+func (pw *packageWriter) rewrite(data []byte) ([]byte, error) {
+	pw.namespaces = make(map[string]string)
+	pw.version3 = strings.HasPrefix(pw.epub.Version, "3")
+	pw.droppedRefs = make(map[string]bool)
+	pw.keptIdentifier = 0
+	for i, id := range pw.epub.Metadata.Identifiers {
+		if id.ElementID != "" && id.ElementID == pw.epub.UniqueIdentifier {
+			pw.keptIdentifier = i
+		}
+	}
+	pw.creatorRoles = make(map[string]string)
+	for _, a := range pw.epub.Metadata.Creators {
+		if a.ID != "" {
+			pw.creatorRoles[a.ID] = a.Role
+		}
+		if pw.u.Creators != nil && a.ID != "" && isAuthorRole(a.Role) {
+			pw.droppedRefs["#"+a.ID] = true
+		}
+	}
+	if pw.u.Series != nil {
+		for _, id := range seriesCollectionIDs(pw.epub.Metadata) {
+			pw.droppedRefs["#"+id] = true
+		}
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+
+	var open []xml.Name
+	var pending []byte // whitespace between the children of metadata, dropped with the next child
+	indent := "\n    "
+	skipDepth := 0
+	foundMetadata := false
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		inMetadata := len(open) >= 2 && open[1].Local == "metadata"
+		inManifest := len(open) >= 2 && open[1].Local == "manifest"
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			open = append(open, t.Name)
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			switch {
+			case len(open) <= 2:
+				pw.declare(t)
+				if len(open) == 2 && t.Name.Local == "metadata" {
+					foundMetadata = true
+					t.Attr = pw.metadataPrefixes(t.Attr)
+				}
+			case inMetadata && len(open) == 3:
+				if pw.dropped(t) {
+					pending = nil
+					skipDepth = 1
+					continue
+				}
+				if len(pending) > 0 {
+					indent = string(pending)
+				}
+				pw.flush(&pending)
+			case inManifest && len(open) == 3 && t.Name.Local == "item" && pw.coverItemID != "" && attr(t, "id") == pw.coverItemID:
+				t.Attr = setAttr(t.Attr, "media-type", pw.u.Cover.MediaType)
+			}
+			pw.start(t.Name, t.Attr)
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, fmt.Errorf("%w: unexpected </%s>", ErrMalformedXML, qualifiedName(t.Name))
+			}
+			open = open[:len(open)-1]
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if len(open) == 1 && t.Name.Local == "metadata" {
+				pw.writeMetadata(indent)
+				pw.flush(&pending)
+			}
+			if len(open) == 1 && t.Name.Local == "manifest" && pw.newCoverHref != "" {
+				pw.writeCoverItem(indent)
+			}
+			pw.end(t.Name)
+		case xml.CharData:
+			if skipDepth > 0 {
+				continue
+			}
+			if inMetadata && len(open) == 2 && len(bytes.TrimSpace(t)) == 0 {
+				pending = append(pending, t...)
+				continue
+			}
+			pw.closeStart()
+			xmlx.WriteText(&pw.out, string(t))
+		case xml.Comment:
+			if skipDepth == 0 {
+				pw.flush(&pending)
+				pw.closeStart()
+				pw.out.WriteString("<!--")
+				pw.out.Write(t)
+				pw.out.WriteString("-->")
+			}
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				pw.out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+			} else if skipDepth == 0 {
+				pw.closeStart()
+				fmt.Fprintf(&pw.out, "<?%s %s?>", t.Target, t.Inst)
+			}
+		case xml.Directive:
+			pw.closeStart()
+			pw.out.WriteString("<!")
+			pw.out.Write(t)
+			pw.out.WriteByte('>')
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("%w: unclosed <%s>", ErrMalformedXML, qualifiedName(open[len(open)-1]))
+	}
+	if !foundMetadata {
+		return nil, ErrMissingMetadata
+	}
+	return pw.out.Bytes(), nil
+}
+
+// declare records the namespaces declared on e.
+func (pw *packageWriter) declare(e xml.StartElement) {
+	for _, a := range e.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			pw.namespaces[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			pw.namespaces[""] = a.Value
+		}
+	}
+}
+
+// metadataPrefixes finds the prefixes of the DC and OPF namespaces, declaring them on the metadata
+// element if the package does not.
+func (pw *packageWriter) metadataPrefixes(attrs []xml.Attr) []xml.Attr {
+	pw.dc, pw.opf = "", ""
+	for prefix, ns := range pw.namespaces {
+		if prefix == "" {
+			continue
+		}
+		if ns == dcNamespace && (pw.dc == "" || prefix == "dc") {
+			pw.dc = prefix
+		}
+		if ns == opfNamespace && (pw.opf == "" || prefix == "opf") {
+			pw.opf = prefix
+		}
+	}
+	if pw.dc == "" {
+		pw.dc = "dc"
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Space: "xmlns", Local: "dc"}, Value: dcNamespace})
+		pw.namespaces["dc"] = dcNamespace
+	}
+	if pw.opf == "" && !pw.version3 {
+		pw.opf = "opf"
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Space: "xmlns", Local: "opf"}, Value: opfNamespace})
+		pw.namespaces["opf"] = opfNamespace
+	}
+	return attrs
+}
+
+// dropped reports whether e, a child of metadata, is replaced by the update.
+func (pw *packageWriter) dropped(e xml.StartElement) bool {
+	u := pw.u
+	if pw.namespaces[e.Name.Space] == dcNamespace {
+		switch e.Name.Local {
+		case "title":
+			return u.Title != nil
+		case "creator":
+			return u.Creators != nil && isAuthorRole(pw.creatorRole(e))
+		case "subject":
+			return u.Subjects != nil
+		case "description":
+			return u.Description != nil
+		case "identifier":
+			pw.identifiers++
+			return u.Identifiers != nil && pw.identifiers-1 != pw.keptIdentifier
+		}
+		return false
+	}
+	if e.Name.Local != "meta" {
+		return false
+	}
+
+	if pw.droppedRefs[attr(e, "refines")] {
+		return true
+	}
+	switch attr(e, "property") {
+	case "belongs-to-collection":
+		return pw.droppedRefs["#"+attr(e, "id")] && attr(e, "id") != ""
+	case "dcterms:modified":
+		return pw.version3 && !u.Modified.IsZero() && attr(e, "refines") == ""
+	}
+	switch attr(e, "name") {
+	case "calibre:series", "calibre:series_index":
+		return u.Series != nil
+	}
+	return false
+}
+
+// creatorRole returns the role of the dc:creator e, an EPUB 3 role refinement wins over the EPUB 2
+// opf:role attribute as in ParseEPUB.
+func (pw *packageWriter) creatorRole(e xml.StartElement) string {
+	if role, ok := pw.creatorRoles[attr(e, "id")]; ok && attr(e, "id") != "" {
+		return role
+	}
+	return attr(e, "role")
+}
+
+// isAuthorRole reports whether a creator with the role is an author, creators without a role are.
+func isAuthorRole(role string) bool {
+	return role == "" || role == "aut"
+}
+
+func (pw *packageWriter) writeMetadata(indent string) {
+	u := pw.u
+	if u.Title != nil && *u.Title != "" {
+		pw.element(indent, pw.dc+":title", nil, *u.Title)
+	}
+	for i, a := range u.Creators {
+		role := a.Role
+		if role == "" {
+			role = "aut"
+		}
+		if !pw.version3 {
+			attrs := []string{pw.opf + ":role", role}
+			if a.FileAs != "" {
+				attrs = append(attrs, pw.opf+":file-as", a.FileAs)
+			}
+			pw.element(indent, pw.dc+":creator", attrs, a.Name)
+			continue
+		}
+		id := fmt.Sprintf("goread-creator-%d", i+1)
+		pw.element(indent, pw.dc+":creator", []string{"id", id}, a.Name)
+		pw.element(indent, "meta", []string{"refines", "#" + id, "property", "role", "scheme", "marc:relators"}, role)
+		if a.FileAs != "" {
+			pw.element(indent, "meta", []string{"refines", "#" + id, "property", "file-as"}, a.FileAs)
+		}
+	}
+	for _, id := range u.Identifiers {
+		var attrs []string
+		if id.Scheme != "" && !pw.version3 {
+			attrs = []string{pw.opf + ":scheme", id.Scheme}
+		}
+		pw.element(indent, pw.dc+":identifier", attrs, id.ID)
+	}
+	if u.Description != nil && *u.Description != "" {
+		pw.element(indent, pw.dc+":description", nil, *u.Description)
+	}
+	for _, s := range u.Subjects {
+		pw.element(indent, pw.dc+":subject", nil, s)
+	}
+	if s := u.Series; s != nil && s.Name != "" {
+		position := strconv.FormatFloat(s.Position, 'f', -1, 64)
+		if pw.version3 {
+			pw.element(indent, "meta", []string{"property", "belongs-to-collection", "id", "goread-series"}, s.Name)
+			pw.element(indent, "meta", []string{"refines", "#goread-series", "property", "collection-type"}, "series")
+			if s.Position > 0 {
+				pw.element(indent, "meta", []string{"refines", "#goread-series", "property", "group-position"}, position)
+			}
+		} else {
+			pw.element(indent, "meta", []string{"name", "calibre:series", "content", s.Name}, "")
+			if s.Position > 0 {
+				pw.element(indent, "meta", []string{"name", "calibre:series_index", "content", position}, "")
+			}
+		}
+	}
+	if pw.newCoverHref != "" {
+		// EPUB 3 readers find the cover by the item's cover-image property, older ones by this meta.
+		pw.element(indent, "meta", []string{"name", "cover", "content", newCoverID}, "")
+	}
+	if pw.version3 && !u.Modified.IsZero() {
+		pw.element(indent, "meta", []string{"property", "dcterms:modified"}, u.Modified.UTC().Format(time.RFC3339))
+	}
+}
+
+func (pw *packageWriter) writeCoverItem(indent string) {
+	attrs := []string{"id", newCoverID, "href", pw.newCoverHref, "media-type", pw.u.Cover.MediaType}
+	if pw.version3 {
+		attrs = append(attrs, "properties", "cover-image")
+	}
+	pw.element(indent, "item", attrs, "")
+}
+
+// element writes an element with the attribute name and value pairs in attrs.
+func (pw *packageWriter) element(indent, name string, attrs []string, text string) {
+	pw.closeStart()
+	pw.out.WriteString(indent)
+	pw.out.WriteByte('<')
+	pw.out.WriteString(name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&pw.out, ` %s="`, attrs[i])
+		xmlx.WriteAttr(&pw.out, attrs[i+1])
+		pw.out.WriteByte('"')
+	}
+	if text == "" {
+		pw.out.WriteString("/>")
+		return
+	}
+	pw.out.WriteByte('>')
+	xmlx.WriteText(&pw.out, text)
+	fmt.Fprintf(&pw.out, "</%s>", name)
+}
+
+func (pw *packageWriter) start(name xml.Name, attrs []xml.Attr) {
+	pw.closeStart()
+	pw.out.WriteByte('<')
+	pw.out.WriteString(qualifiedName(name))
+	for _, a := range attrs {
+		pw.out.WriteByte(' ')
+		pw.out.WriteString(qualifiedName(a.Name))
+		pw.out.WriteString(`="`)
+		xmlx.WriteAttr(&pw.out, a.Value)
+		pw.out.WriteByte('"')
+	}
+	pw.startOpen = true
+}
+
+func (pw *packageWriter) end(name xml.Name) {
+	if pw.startOpen {
+		pw.out.WriteString("/>")
+		pw.startOpen = false
+		return
+	}
+	pw.out.WriteString("</")
+	pw.out.WriteString(qualifiedName(name))
+	pw.out.WriteByte('>')
+}
+
+func (pw *packageWriter) closeStart() {
+	if pw.startOpen {
+		pw.out.WriteByte('>')
+		pw.startOpen = false
+	}
+}
+
+func (pw *packageWriter) flush(pending *[]byte) {
+	if len(*pending) > 0 {
+		pw.closeStart()
+		pw.out.Write(*pending)
+		*pending = nil
+	}
+}
+
+// seriesCollectionIDs returns the ids of the belongs-to-collection metas of type series.
+func seriesCollectionIDs(m Metadata) []string {
+	var ids []string
+	for _, meta := range m.Meta {
+		if meta.Property != "belongs-to-collection" || meta.Refines != "" || meta.ID == "" {
+			continue
+		}
+		for _, r := range m.Meta {
+			if r.Refines == "#"+meta.ID && r.Property == "collection-type" && strings.TrimSpace(r.Value) == "series" {
+				ids = append(ids, meta.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func setAttr(attrs []xml.Attr, local, value string) []xml.Attr {
+	for i, a := range attrs {
+		if a.Name.Local == local {
+			attrs[i].Value = value
+			return attrs
+		}
+	}
+	return append(attrs, xml.Attr{Name: xml.Name{Local: local}, Value: value})
+}
@@ -0,0 +1,329 @@
+package epubx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMetadata(t *testing.T, r io.ReaderAt, size int64, u MetadataUpdate) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, WriteMetadata(&buf, r, size, u))
+	return bytes.NewReader(buf.Bytes())
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestWriteMetadata_epub2(t *testing.T) {
+	t.Parallel()
+
+	src, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	cover := []byte("\xff\xd8\xff new cover")
+
+	r := writeMetadata(t, bytes.NewReader(src), int64(len(src)), MetadataUpdate{
+		Title: ptr("Воин & <Дроу>"),
+		Creators: []Author{
+			{Name: "R. A. Salvatore", FileAs: "Salvatore, R. A."},
+			{Name: "Todd Lockwood", Role: "ill"},
+		},
+		Series:      &Collection{Name: "The Dark Elf Trilogy", Position: 3},
+		Subjects:    []string{"fantasy", "drow"},
+		Description: ptr("<p>Drizzt leaves the Underdark.</p>"),
+		Identifiers: []Identifier{{ID: "978-0-7869-3954-7", Scheme: "ISBN"}},
+		Cover:       &Cover{Data: cover, MediaType: "image/jpeg"},
+	})
+
+	epub, err := ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	meta := epub.Metadata
+	assert.Equal(t, []string{"Воин & <Дроу>"}, meta.Titles)
+	assert.Equal(t, []Author{
+		{Name: "R. A. Salvatore", Role: "aut", FileAs: "Salvatore, R. A."},
+		{Name: "Todd Lockwood", Role: "ill"},
+	}, meta.Creators)
+	series, ok := meta.Series()
+	require.True(t, ok)
+	assert.Equal(t, Collection{Name: "The Dark Elf Trilogy", Type: "series", Position: 3}, series)
+	assert.Equal(t, []string{"fantasy", "drow"}, meta.Subjects)
+	assert.Equal(t, "<p>Drizzt leaves the Underdark.</p>", meta.Description)
+	assert.Equal(t, []Identifier{
+		{ID: "07aada78-943e-44a1-b680-4b2edba53ba9", Scheme: "uuid", ElementID: "uuid_id"},
+		{ID: "978-0-7869-3954-7", Scheme: "ISBN"},
+	}, meta.Identifiers, "the unique identifier is kept")
+	assert.Equal(t, "07aada78-943e-44a1-b680-4b2edba53ba9", epub.Identifier())
+
+	// Untouched metadata stays.
+	assert.Equal(t, []string{"ru"}, meta.Languages)
+	assert.Equal(t, []string{"ИЦ «Максима»"}, meta.Publishers)
+	assert.Equal(t, []Author{{Name: "calibre (2.55.0) [http://calibre-ebook.com]", Role: "bkp"}}, meta.Contributors)
+
+	data, mediaType, err := ReadCover(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, cover, data)
+	assert.Equal(t, "image/jpeg", mediaType)
+
+	assertCopiedEntries(t, bytes.NewReader(src), int64(len(src)), r, epub.RootFile, "fb2_cover_calibre_mi.jpg")
+}
+
+func TestWriteMetadata_epub3(t *testing.T) {
+	t.Parallel()
+
+	opf := []byte(`<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="uid">urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9</dc:identifier>
+    <dc:identifier id="isbn">9780786939541</dc:identifier>
+    <dc:title>Homeland</dc:title>
+    <dc:creator id="aut">R. A. Salvatore</dc:creator>
+    <meta refines="#aut" property="role" scheme="marc:relators">aut</meta>
+    <meta refines="#aut" property="file-as">Salvatore, R. A.</meta>
+    <dc:language>en</dc:language>
+    <!-- kept -->
+    <meta property="belongs-to-collection" id="set">Forgotten Realms</meta>
+    <meta refines="#set" property="collection-type">set</meta>
+    <meta property="belongs-to-collection" id="series">The Dark Elf Trilogy</meta>
+    <meta refines="#series" property="collection-type">series</meta>
+    <meta refines="#series" property="group-position">1</meta>
+    <meta property="dcterms:modified">2024-05-06T07:08:09Z</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ch1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="ch1"/>
+  </spine>
+</package>`)
+	files := []zipEntry{
+		{name: "mimetype", data: validMimetype},
+		{name: "META-INF/container.xml", data: nestedOPFContainer("OEBPS/content.opf")},
+		{name: "OEBPS/content.opf", data: opf},
+		{name: "OEBPS/nav.xhtml", data: []byte(`<html xmlns="http://www.w3.org/1999/xhtml"><body><nav/></body></html>`)},
+		{name: "OEBPS/text/ch1.xhtml", data: []byte(`<html xmlns="http://www.w3.org/1999/xhtml"><body><p>1</p></body></html>`)},
+	}
+	modified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		update MetadataUpdate
+		check  func(t *testing.T, epub EPUB)
+	}{
+		{
+			name:   "nothing to update",
+			update: MetadataUpdate{},
+			check: func(t *testing.T, epub EPUB) {
+				assert.Equal(t, []string{"Homeland"}, epub.Metadata.Titles)
+				assert.Len(t, epub.Metadata.Meta, 8)
+				assert.Len(t, epub.Metadata.Identifiers, 2)
+			},
+		},
+		{
+			name: "creators, identifiers and modified",
+			update: MetadataUpdate{
+				Creators:    []Author{{Name: "Todd Lockwood", Role: "ill", FileAs: "Lockwood, Todd"}},
+				Identifiers: []Identifier{},
+				Modified:    modified,
+			},
+			check: func(t *testing.T, epub EPUB) {
+				assert.Equal(t, []Author{{Name: "Todd Lockwood", ID: "goread-creator-1", Role: "ill", FileAs: "Lockwood, Todd"}}, epub.Metadata.Creators)
+				assert.Equal(t, []Identifier{{ID: "urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9", ElementID: "uid"}}, epub.Metadata.Identifiers)
+				got, ok := epub.Metadata.Modified()
+				require.True(t, ok)
+				assert.Equal(t, modified, got)
+				series, ok := epub.Metadata.Series()
+				require.True(t, ok)
+				assert.Equal(t, Collection{Name: "The Dark Elf Trilogy", Type: "series", Position: 1}, series)
+			},
+		},
+		{
+			name:   "series removed",
+			update: MetadataUpdate{Series: &Collection{}},
+			check: func(t *testing.T, epub EPUB) {
+				_, ok := epub.Metadata.Series()
+				assert.False(t, ok)
+				assert.Equal(t, []Collection{{Name: "Forgotten Realms", Type: "set"}}, epub.Metadata.Collections())
+			},
+		},
+		{
+			name:   "series replaced",
+			update: MetadataUpdate{Series: &Collection{Name: "Legend of Drizzt", Position: 1.5}},
+			check: func(t *testing.T, epub EPUB) {
+				series, ok := epub.Metadata.Series()
+				require.True(t, ok)
+				assert.Equal(t, Collection{Name: "Legend of Drizzt", Type: "series", Position: 1.5}, series)
+			},
+		},
+		{
+			name:   "new cover",
+			update: MetadataUpdate{Cover: &Cover{Data: []byte("\x89PNG"), MediaType: "image/png"}},
+			check: func(t *testing.T, epub EPUB) {
+				item, ok := epub.CoverItem()
+				require.True(t, ok)
+				assert.Equal(t, ManifestItem{
+					ID:         "goread-cover",
+					Href:       "goread-cover.png",
+					MediaType:  "image/png",
+					Properties: "cover-image",
+					Path:       "OEBPS/goread-cover.png",
+				}, item)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := buildEPUBZip(t, files...)
+			r := writeMetadata(t, src, src.Size(), tt.update)
+
+			report := Validate(r, r.Size())
+			require.True(t, report.Valid(), "%v", report.Issues)
+			epub, err := ParseEPUB(r, r.Size())
+			require.NoError(t, err)
+			tt.check(t, epub)
+			assert.Equal(t, []string{"en"}, epub.Metadata.Languages)
+			assertCopiedEntries(t, src, src.Size(), r, "OEBPS/content.opf")
+		})
+	}
+}
+
+func TestWriteMetadata_keepsOtherCreators(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opf      string
+		expected []Author
+	}{
+		{
+			name: "epub 2",
+			opf: `<package xmlns="http://www.idpf.org/2007/opf" version="2.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:identifier id="uid">urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9</dc:identifier>
+    <dc:title>Воин</dc:title>
+    <dc:creator opf:role="aut">Роберт Сальваторе</dc:creator>
+    <dc:creator>R. A. Salvatore</dc:creator>
+    <dc:creator opf:role="trl" opf:file-as="Фишман, Александр">Александр Фишман</dc:creator>
+    <dc:language>ru</dc:language>
+  </metadata>
+  <manifest><item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/></manifest>
+  <spine><itemref idref="ch1"/></spine>
+</package>`,
+			expected: []Author{
+				{Name: "Александр Фишман", Role: "trl", FileAs: "Фишман, Александр"},
+				{Name: "Роберт Сальваторе", Role: "aut"},
+			},
+		},
+		{
+			name: "epub 3",
+			opf: `<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="uid">urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9</dc:identifier>
+    <dc:title>Воин</dc:title>
+    <dc:creator id="aut">Роберт Сальваторе</dc:creator>
+    <meta refines="#aut" property="role" scheme="marc:relators">aut</meta>
+    <dc:creator id="trl">Александр Фишман</dc:creator>
+    <meta refines="#trl" property="role" scheme="marc:relators">trl</meta>
+    <meta refines="#trl" property="file-as">Фишман, Александр</meta>
+    <dc:language>ru</dc:language>
+    <meta property="dcterms:modified">2024-05-06T07:08:09Z</meta>
+  </metadata>
+  <manifest><item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/></manifest>
+  <spine><itemref idref="ch1"/></spine>
+</package>`,
+			expected: []Author{
+				{Name: "Александр Фишман", ID: "trl", Role: "trl", FileAs: "Фишман, Александр"},
+				{Name: "Роберт Сальваторе", ID: "goread-creator-1", Role: "aut"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := buildEPUBZip(t,
+				zipEntry{name: "mimetype", data: validMimetype},
+				zipEntry{name: "META-INF/container.xml", data: validContainer},
+				zipEntry{name: "content.opf", data: []byte(tt.opf)},
+				zipEntry{name: "ch1.xhtml", data: []byte(`<html xmlns="http://www.w3.org/1999/xhtml"><body><p>1</p></body></html>`)},
+			)
+			r := writeMetadata(t, src, src.Size(), MetadataUpdate{Creators: []Author{{Name: "Роберт Сальваторе"}}})
+
+			epub, err := ParseEPUB(r, r.Size())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, epub.Metadata.Creators)
+		})
+	}
+}
+
+func TestWriteMetadata_errors(t *testing.T) {
+	t.Parallel()
+
+	src := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: validContainer},
+		zipEntry{name: "content.opf", data: minimalOPF},
+	)
+	err := WriteMetadata(io.Discard, src, src.Size(), MetadataUpdate{Cover: &Cover{MediaType: "image/svg+xml"}})
+	assert.ErrorIs(t, err, ErrUnsupportedCover)
+
+	noMetadata := buildEPUBZip(t,
+		zipEntry{name: "mimetype", data: validMimetype},
+		zipEntry{name: "META-INF/container.xml", data: validContainer},
+		zipEntry{name: "content.opf", data: []byte(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0"/>`)},
+	)
+	err = WriteMetadata(io.Discard, noMetadata, noMetadata.Size(), MetadataUpdate{Title: ptr("Homeland")})
+	assert.ErrorIs(t, err, ErrMissingMetadata)
+}
+
+// assertCopiedEntries asserts that got starts with a stored mimetype and has all entries of src but
+// the rewritten ones with the same compressed bytes.
+func assertCopiedEntries(t *testing.T, src io.ReaderAt, srcSize int64, got *bytes.Reader, rewritten ...string) {
+	t.Helper()
+
+	want, err := zip.NewReader(src, srcSize)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(got, got.Size())
+	require.NoError(t, err)
+
+	require.NotEmpty(t, zr.File)
+	assert.Equal(t, "mimetype", zr.File[0].Name)
+	assert.Equal(t, zip.Store, zr.File[0].Method)
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, f := range want.File {
+		g := files[f.Name]
+		require.NotNil(t, g, f.Name)
+		if f.Name == "mimetype" || f.FileInfo().IsDir() || slices.Contains(rewritten, f.Name) {
+			continue
+		}
+		assert.Equal(t, f.Method, g.Method, f.Name)
+		assert.Equal(t, f.CRC32, g.CRC32, f.Name)
+		assert.Equal(t, rawEntry(t, f), rawEntry(t, g), f.Name)
+	}
+}
+
+func rawEntry(t *testing.T, f *zip.File) []byte {
+	t.Helper()
+	r, err := f.OpenRaw()
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
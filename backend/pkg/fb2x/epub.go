@@ -0,0 +1,367 @@
+package fb2x
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/xmlx"
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/jpg":  ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// EPUB converts the book to a publication for epubx.WriteBook. Every top level section of the main
+// body is a chapter, as are the sections they contain; each other body, such as the notes, is a
+// chapter of its own. The identifier is urn:uuid:<document id> if the document id is a UUID.
+func (fb FictionBook) EPUB() epubx.Book {
+	b := epubx.Book{
+		Title:       fb.Title,
+		Language:    fb.Lang,
+		Publisher:   fb.Publisher,
+		Description: fb.Annotation,
+		Subjects:    fb.Genres,
+	}
+	if id := strings.ToLower(fb.DocumentID); uuidPattern.MatchString(id) {
+		b.Identifier = "urn:uuid:" + id
+	} else {
+		b.Identifier = fb.DocumentID
+	}
+	for _, a := range fb.Authors {
+		b.Creators = append(b.Creators, epubx.Author{Name: a.Name(), Role: "aut", FileAs: a.FileAs()})
+	}
+	for _, a := range fb.Translators {
+		b.Creators = append(b.Creators, epubx.Author{Name: a.Name(), Role: "trl", FileAs: a.FileAs()})
+	}
+	if fb.Sequence != nil {
+		b.Series = &epubx.Collection{Name: fb.Sequence.Name, Type: "series", Position: fb.Sequence.Number}
+	}
+	if bin, ok := fb.Binaries[fb.CoverID]; ok {
+		if _, ok := imageExtensions[bin.ContentType]; ok {
+			b.Cover = &epubx.Cover{Data: bin.Data, MediaType: normalizeImageType(bin.ContentType)}
+		}
+	}
+
+	c := converter{fb: fb, images: make(map[string]string), targets: make(map[string]string)}
+	c.planBodies()
+	for i, body := range fb.bodies {
+		b.Chapters = append(b.Chapters, c.bodyChapters(body, i)...)
+	}
+	b.Resources = c.resources
+	return b
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func normalizeImageType(t string) string {
+	if t == "image/jpg" {
+		return "image/jpeg"
+	}
+	return t
+}
+
+// converter renders the bodies of a FictionBook as XHTML chapters.
+type converter struct {
+	fb FictionBook
+	// chapterIDs are the ids of the chapters of sections and bodies.
+	chapterIDs map[*node]string
+	// targets maps the ids of elements to the chapters they end up in, to resolve links.
+	targets map[string]string
+	// images maps binary ids to the hrefs of their resources.
+	images    map[string]string
+	resources []epubx.Resource
+}
+
+// isNotes reports whether the body is not part of the main text, such as footnotes or comments.
+func isNotes(body *node, i int) bool {
+	return i > 0 && body.attr("name") != ""
+}
+
+// planBodies decides which chapter every section goes into before any is rendered, links may
+// point forward.
+func (c *converter) planBodies() {
+	c.chapterIDs = make(map[*node]string)
+	n := 0
+	newID := func() string {
+		n++
+		return fmt.Sprintf("ch%03d", n)
+	}
+
+	var planSection func(s *node, id string)
+	planSection = func(s *node, id string) {
+		c.chapterIDs[s] = id
+		c.addTargets(s, id)
+		for _, sub := range s.all("section") {
+			planSection(sub, newID())
+		}
+	}
+	for i, body := range c.fb.bodies {
+		id := newID()
+		c.chapterIDs[body] = id
+		if isNotes(body, i) {
+			c.addTargets(body, id)
+			for _, s := range body.all("section") {
+				c.addTargetsDeep(s, id)
+			}
+			continue
+		}
+		c.addTargets(body, id)
+		for _, s := range body.all("section") {
+			planSection(s, newID())
+		}
+	}
+}
+
+// addTargets maps the id of n and of its descendants, but not of nested sections, to the chapter.
+func (c *converter) addTargets(n *node, chapterID string) {
+	if id := n.attr("id"); id != "" {
+		c.targets[id] = chapterID
+	}
+	for _, child := range n.children {
+		if child.name != "section" && child.name != "" {
+			c.addTargets(child, chapterID)
+		}
+	}
+}
+
+func (c *converter) addTargetsDeep(n *node, chapterID string) {
+	if id := n.attr("id"); id != "" {
+		c.targets[id] = chapterID
+	}
+	for _, child := range n.children {
+		c.addTargetsDeep(child, chapterID)
+	}
+}
+
+func hasSubsections(s *node) bool {
+	return s.child("section") != nil
+}
+
+// bodyChapters renders the body, the content before its first section is a chapter of its own.
+func (c *converter) bodyChapters(body *node, i int) []epubx.Chapter {
+	var w xhtmlWriter
+	title := body.child("title").textContent()
+	if isNotes(body, i) {
+		if title == "" {
+			title = body.attr("name")
+		}
+		for _, child := range body.children {
+			c.block(&w, child, 1)
+		}
+		return []epubx.Chapter{{ID: c.chapterIDs[body], Title: title, Body: w.String()}}
+	}
+
+	var chapters []epubx.Chapter
+	for _, child := range body.children {
+		if child.name != "section" {
+			c.block(&w, child, 1)
+		}
+	}
+	if w.Len() > 0 {
+		// The title of the main body is usually the author and title of the book, it is no chapter.
+		chapters = append(chapters, epubx.Chapter{ID: c.chapterIDs[body], Body: w.String()})
+	}
+	for _, s := range body.all("section") {
+		chapters = append(chapters, c.sectionChapter(s, 1))
+	}
+	return chapters
+}
+
+func (c *converter) sectionChapter(s *node, depth int) epubx.Chapter {
+	var w xhtmlWriter
+	ch := epubx.Chapter{ID: c.chapterIDs[s], Title: s.child("title").textContent()}
+	if id := s.attr("id"); id != "" {
+		fmt.Fprintf(&w, `<div id="%s"></div>`, xmlx.EscapeAttr(id))
+	}
+	for _, child := range s.children {
+		if child.name == "section" && hasSubsections(s) {
+			ch.Children = append(ch.Children, c.sectionChapter(child, depth+1))
+			continue
+		}
+		c.block(&w, child, depth)
+	}
+	ch.Body = w.String()
+	if ch.Body == "" {
+		ch.Body = "<p></p>"
+	}
+	return ch
+}
+
+// block renders an element that is a block in FB2, depth is the heading level of titles.
+func (c *converter) block(w *xhtmlWriter, n *node, depth int) {
+	idAttr := ""
+	if id := n.attr("id"); id != "" {
+		idAttr = ` id="` + xmlx.EscapeAttr(id) + `"`
+	}
+	switch n.name {
+	case "title":
+		level := min(depth, 6)
+		fmt.Fprintf(w, "\n<h%d%s>", level, idAttr)
+		first := true
+		for _, p := range n.children {
+			if p.name != "p" {
+				continue
+			}
+			if !first {
+				w.WriteString("<br/>")
+			}
+			first = false
+			c.inline(w, p)
+		}
+		fmt.Fprintf(w, "</h%d>", level)
+	case "p":
+		fmt.Fprintf(w, "\n<p%s>", idAttr)
+		c.inline(w, n)
+		w.WriteString("</p>")
+	case "subtitle":
+		fmt.Fprintf(w, "\n<p class=\"subtitle\"%s>", idAttr)
+		c.inline(w, n)
+		w.WriteString("</p>")
+	case "text-author", "date":
+		fmt.Fprintf(w, "\n<p class=\"text-author\"%s>", idAttr)
+		c.inline(w, n)
+		w.WriteString("</p>")
+	case "v":
+		fmt.Fprintf(w, "\n<p%s>", idAttr)
+		c.inline(w, n)
+		w.WriteString("</p>")
+	case "empty-line":
+		w.WriteString("\n<p class=\"empty-line\"> </p>")
+	case "image":
+		if src, ok := c.image(n); ok {
+			fmt.Fprintf(w, "\n<div class=\"image\"%s><img src=\"%s\" alt=\"%s\"/></div>", idAttr, xmlx.EscapeAttr(src), xmlx.EscapeAttr(n.attr("alt")))
+		}
+	case "epigraph", "cite", "annotation", "poem", "stanza", "section":
+		tag := "div"
+		if n.name == "cite" || n.name == "epigraph" {
+			tag = "blockquote"
+		}
+		fmt.Fprintf(w, "\n<%s class=\"%s\"%s>", tag, n.name, idAttr)
+		for _, child := range n.children {
+			childDepth := depth
+			if n.name == "section" {
+				childDepth = depth + 1
+			}
+			if child.name == "title" && n.name != "section" {
+				// Titles of poems and stanzas are no headings of the table of contents.
+				w.WriteString("\n<p class=\"subtitle\">")
+				w.WriteString(xmlx.EscapeText(child.textContent()))
+				w.WriteString("</p>")
+				continue
+			}
+			c.block(w, child, childDepth)
+		}
+		fmt.Fprintf(w, "\n</%s>", tag)
+	case "table":
+		fmt.Fprintf(w, "\n<table%s>", idAttr)
+		for _, tr := range n.all("tr") {
+			w.WriteString("\n<tr>")
+			for _, cell := range tr.children {
+				if cell.name != "th" && cell.name != "td" {
+					continue
+				}
+				fmt.Fprintf(w, "<%s", cell.name)
+				for _, a := range []string{"colspan", "rowspan"} {
+					if v := cell.attr(a); v != "" {
+						fmt.Fprintf(w, ` %s="%s"`, a, xmlx.EscapeAttr(v))
+					}
+				}
+				w.WriteString(">")
+				c.inline(w, cell)
+				fmt.Fprintf(w, "</%s>", cell.name)
+			}
+			w.WriteString("</tr>")
+		}
+		w.WriteString("\n</table>")
+	}
+}
+
+var inlineTags = map[string]string{
+	"strong":        "strong",
+	"emphasis":      "em",
+	"strikethrough": "del",
+	"sub":           "sub",
+	"sup":           "sup",
+	"code":          "code",
+	"style":         "span",
+}
+
+// inline renders the inline content of n.
+func (c *converter) inline(w *xhtmlWriter, n *node) {
+	for _, child := range n.children {
+		switch child.name {
+		case "":
+			w.WriteString(xmlx.EscapeText(child.text))
+		case "a":
+			href := c.link(child.href())
+			if child.attr("type") == "note" {
+				fmt.Fprintf(w, `<a href="%s" epub:type="noteref">`, xmlx.EscapeAttr(href))
+			} else {
+				fmt.Fprintf(w, `<a href="%s">`, xmlx.EscapeAttr(href))
+			}
+			c.inline(w, child)
+			w.WriteString("</a>")
+		case "image":
+			if src, ok := c.image(child); ok {
+				fmt.Fprintf(w, `<img src="%s" alt="%s"/>`, xmlx.EscapeAttr(src), xmlx.EscapeAttr(child.attr("alt")))
+			}
+		default:
+			tag, ok := inlineTags[child.name]
+			if !ok {
+				c.inline(w, child)
+				continue
+			}
+			fmt.Fprintf(w, "<%s>", tag)
+			c.inline(w, child)
+			fmt.Fprintf(w, "</%s>", tag)
+		}
+	}
+}
+
+// link resolves an FB2 link, links to elements of the book point to the chapter they are in.
+func (c *converter) link(href string) string {
+	id, ok := strings.CutPrefix(href, "#")
+	if !ok {
+		return href
+	}
+	chapter, ok := c.targets[id]
+	if !ok {
+		return "#" + id
+	}
+	return chapter + ".xhtml#" + id
+}
+
+// image returns the href of the image element's binary relative to the chapters, adding the binary
+// to the resources the first time.
+func (c *converter) image(n *node) (string, bool) {
+	id := strings.TrimPrefix(n.href(), "#")
+	if href, ok := c.images[id]; ok {
+		return "../" + href, true
+	}
+	bin, ok := c.fb.Binaries[id]
+	if !ok {
+		return "", false
+	}
+	ext, ok := imageExtensions[bin.ContentType]
+	if !ok {
+		return "", false
+	}
+	name := unsafeNameChars.ReplaceAllString(strings.TrimSuffix(id, path.Ext(id)), "_")
+	href := fmt.Sprintf("images/%d-%s%s", len(c.resources)+1, name, ext)
+	c.images[id] = href
+	c.resources = append(c.resources, epubx.Resource{Href: href, MediaType: normalizeImageType(bin.ContentType), Data: bin.Data})
+	return "../" + href, true
+}
+
+type xhtmlWriter struct {
+	strings.Builder
+}
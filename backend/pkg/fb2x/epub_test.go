@@ -0,0 +1,69 @@
+package fb2x
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+func TestFictionBook_EPUB(t *testing.T) {
+	t.Parallel()
+
+	fb, err := Parse(bytes.NewReader(encode1251(t, sampleFB2)))
+	require.NoError(t, err)
+	book := fb.EPUB()
+
+	assert.Equal(t, "urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9", book.Identifier)
+	assert.Equal(t, "Воин", book.Title)
+	assert.Equal(t, "ru", book.Language)
+	assert.Equal(t, []epubx.Author{
+		{Name: "Роберт Энтони Сальваторе", Role: "aut", FileAs: "Сальваторе, Роберт Энтони"},
+		{Name: "Н. Некрасова", Role: "trl", FileAs: "Некрасова, Н."},
+	}, book.Creators)
+	assert.Equal(t, &epubx.Collection{Name: "Темный эльф", Type: "series", Position: 3}, book.Series)
+	assert.Equal(t, &epubx.Cover{Data: coverData, MediaType: "image/jpeg"}, book.Cover)
+	assert.Equal(t, []epubx.Resource{{Href: "images/1-map.png", MediaType: "image/png", Data: []byte("\x89PNG")}}, book.Resources)
+
+	require.Len(t, book.Chapters, 4)
+	intro, part, epilogue, notes := book.Chapters[0], book.Chapters[1], book.Chapters[2], book.Chapters[3]
+	assert.Empty(t, intro.Title, "the body title is no chapter")
+	assert.Contains(t, intro.Body, "<h1>Роберт Сальваторе<br/>Воин</h1>")
+	assert.Contains(t, intro.Body, `<blockquote class="epigraph">`)
+
+	assert.Equal(t, "Часть 1 Изгнание", part.Title)
+	require.Len(t, part.Children, 2)
+	ch1, ch2 := part.Children[0], part.Children[1]
+	assert.Equal(t, "Глава 1", ch1.Title)
+	assert.Contains(t, ch1.Body, `<div id="ch1"></div>`)
+	assert.Contains(t, ch1.Body, "<h2>Глава 1</h2>")
+	assert.Contains(t, ch1.Body, "<p>Текст главы<a href=\""+notes.ID+`.xhtml#n1" epub:type="noteref">[1]</a> и <strong>жирный</strong>.</p>`)
+	assert.Contains(t, ch1.Body, `<div class="poem">`+"\n"+`<p class="subtitle">Песня</p>`)
+	assert.Contains(t, ch1.Body, `<div class="image"><img src="../images/1-map.png" alt=""/></div>`)
+	assert.Contains(t, ch2.Body, `<a href="`+ch1.ID+`.xhtml#ch1">первую главу</a>`)
+
+	assert.Equal(t, "Эпилог", epilogue.Title)
+	assert.Contains(t, epilogue.Body, "<p>Конец &amp; <em>всё</em>.</p>")
+
+	assert.Equal(t, "Примечания", notes.Title)
+	assert.Contains(t, notes.Body, `<div class="section" id="n1">`)
+
+	var buf bytes.Buffer
+	require.NoError(t, epubx.WriteBook(&buf, book))
+	r := bytes.NewReader(buf.Bytes())
+	report := epubx.Validate(r, r.Size())
+	assert.True(t, report.Valid(), "%v", report.Issues)
+	epub, err := epubx.ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, []epubx.TOCEntry{
+		{Title: "Часть 1 Изгнание", Path: "OEBPS/text/ch002.xhtml", Children: []epubx.TOCEntry{
+			{Title: "Глава 1", Path: "OEBPS/text/ch003.xhtml"},
+			{Title: "Глава 2", Path: "OEBPS/text/ch004.xhtml"},
+		}},
+		{Title: "Эпилог", Path: "OEBPS/text/ch005.xhtml"},
+		{Title: "Примечания", Path: "OEBPS/text/ch006.xhtml"},
+	}, epub.TOC)
+}
@@ -0,0 +1,279 @@
+// Package fb2x reads FictionBook 2 documents, the format most Russian e-books come in, and
+// converts them to EPUB.
+package fb2x
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var (
+	ErrNotFictionBook     = errors.New("not a FictionBook document")
+	ErrUnsupportedCharset = errors.New("unsupported charset")
+)
+
+// FictionBook is a parsed FB2 document, see http://www.fictionbook.org/index.php/Eng:XML_Schema_Fictionbook_2.1.
+type FictionBook struct {
+	Title       string
+	Authors     []Author
+	Translators []Author
+	// Genres are FB2 genre codes, such as sf_fantasy.
+	Genres []string
+	// Annotation is the plain text of the annotation, a line per paragraph.
+	Annotation string
+	Lang       string
+	Sequence   *Sequence
+	// DocumentID is the id of the document-info, often a UUID.
+	DocumentID string
+	Publisher  string
+	ISBN       string
+	// CoverID is the id of the binary of the cover image, empty if the book has none.
+	CoverID  string
+	Binaries map[string]Binary
+
+	bodies []*node
+}
+
+type Author struct {
+	FirstName  string
+	MiddleName string
+	LastName   string
+	Nickname   string
+}
+
+// Name returns the full name of the author, the nickname if there is none.
+func (a Author) Name() string {
+	name := strings.Join(strings.Fields(a.FirstName+" "+a.MiddleName+" "+a.LastName), " ")
+	if name == "" {
+		return a.Nickname
+	}
+	return name
+}
+
+// FileAs returns the name to sort the author by, "Last, First Middle", empty without a last name.
+func (a Author) FileAs() string {
+	if a.LastName == "" {
+		return ""
+	}
+	rest := strings.Join(strings.Fields(a.FirstName+" "+a.MiddleName), " ")
+	if rest == "" {
+		return a.LastName
+	}
+	return a.LastName + ", " + rest
+}
+
+type Sequence struct {
+	Name string
+	// Number is the place of the book in the sequence, zero if unknown.
+	Number float64
+}
+
+type Binary struct {
+	ContentType string
+	Data        []byte
+}
+
+// node is an element or, if name is empty, a text node of the document.
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	children []*node
+	text     string
+}
+
+func (n *node) attr(local string) string {
+	for _, a := range n.attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// href returns the xlink:href, FB2 files bind the xlink namespace to all kinds of prefixes.
+func (n *node) href() string {
+	return n.attr("href")
+}
+
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) all(name string) []*node {
+	var nodes []*node
+	for _, c := range n.children {
+		if c.name == name {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+// textContent returns the text of n and its descendants with runs of whitespace collapsed.
+func (n *node) textContent() string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(*node)
+	walk = func(n *node) {
+		switch n.name {
+		case "":
+			b.WriteString(n.text)
+		case "p", "v", "subtitle", "text-author", "empty-line":
+			b.WriteByte(' ')
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Parse reads an FB2 document. Documents in charsets other than UTF-8, such as windows-1251 or
+// KOI8-R, are decoded as their XML declaration says.
+func Parse(r io.Reader) (FictionBook, error) {
+	root, err := parseTree(r)
+	if err != nil {
+		return FictionBook{}, err
+	}
+	if root.name != "FictionBook" {
+		return FictionBook{}, fmt.Errorf("%w: root element %s", ErrNotFictionBook, root.name)
+	}
+
+	fb := FictionBook{Binaries: make(map[string]Binary)}
+	if desc := root.child("description"); desc != nil {
+		fb.readDescription(desc)
+	}
+	for _, c := range root.children {
+		switch c.name {
+		case "body":
+			fb.bodies = append(fb.bodies, c)
+		case "binary":
+			data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(c.textContent()), ""))
+			if err != nil {
+				// A broken image is no reason to lose the book.
+				continue
+			}
+			fb.Binaries[c.attr("id")] = Binary{ContentType: c.attr("content-type"), Data: data}
+		}
+	}
+	return fb, nil
+}
+
+func (fb *FictionBook) readDescription(desc *node) {
+	if ti := desc.child("title-info"); ti != nil {
+		fb.Title = ti.child("book-title").textContent()
+		fb.Authors = readAuthors(ti.all("author"))
+		fb.Translators = readAuthors(ti.all("translator"))
+		for _, g := range ti.all("genre") {
+			if genre := g.textContent(); genre != "" {
+				fb.Genres = append(fb.Genres, genre)
+			}
+		}
+		if a := ti.child("annotation"); a != nil {
+			var lines []string
+			for _, c := range a.children {
+				if line := c.textContent(); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			fb.Annotation = strings.Join(lines, "\n")
+		}
+		fb.Lang = ti.child("lang").textContent()
+		if s := ti.child("sequence"); s != nil && strings.TrimSpace(s.attr("name")) != "" {
+			number, _ := strconv.ParseFloat(strings.TrimSpace(s.attr("number")), 64)
+			fb.Sequence = &Sequence{Name: strings.TrimSpace(s.attr("name")), Number: number}
+		}
+		if cp := ti.child("coverpage"); cp != nil {
+			if img := cp.child("image"); img != nil {
+				fb.CoverID = strings.TrimPrefix(img.href(), "#")
+			}
+		}
+	}
+	if di := desc.child("document-info"); di != nil {
+		fb.DocumentID = di.child("id").textContent()
+	}
+	if pi := desc.child("publish-info"); pi != nil {
+		fb.Publisher = pi.child("publisher").textContent()
+		fb.ISBN = pi.child("isbn").textContent()
+	}
+}
+
+func readAuthors(nodes []*node) []Author {
+	var authors []Author
+	for _, n := range nodes {
+		a := Author{
+			FirstName:  n.child("first-name").textContent(),
+			MiddleName: n.child("middle-name").textContent(),
+			LastName:   n.child("last-name").textContent(),
+			Nickname:   n.child("nickname").textContent(),
+		}
+		if a.Name() != "" {
+			authors = append(authors, a)
+		}
+	}
+	return authors
+}
+
+func parseTree(r io.Reader) (*node, error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedCharset, charset)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+	// Old FB2 files use HTML entities such as &nbsp; without declaring them.
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	var root *node
+	var open []*node
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNotFictionBook, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local, attrs: t.Copy().Attr}
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			open = append(open, n)
+		case xml.EndElement:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case xml.CharData:
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, &node{text: string(t)})
+			}
+		}
+	}
+	if root == nil {
+		return nil, ErrNotFictionBook
+	}
+	return root, nil
+}
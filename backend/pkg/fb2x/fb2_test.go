@@ -0,0 +1,125 @@
+package fb2x
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var coverData = []byte("\xff\xd8\xff cover")
+
+// sampleFB2 is a small book with the parts of FB2 converters trip over: nested sections, a poem,
+// an inline image, footnotes in a second body and an HTML entity.
+var sampleFB2 = `<?xml version="1.0" encoding="windows-1251"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+<description>
+  <title-info>
+    <genre>sf_fantasy</genre>
+    <genre>adventure</genre>
+    <author><first-name>Роберт</first-name><middle-name>Энтони</middle-name><last-name>Сальваторе</last-name></author>
+    <book-title>Воин</book-title>
+    <annotation><p>Покинув подземный мир,</p><p>темный эльф <emphasis>Дзирт</emphasis> странствует.</p></annotation>
+    <coverpage><image l:href="#cover.jpg"/></coverpage>
+    <lang>ru</lang>
+    <translator><first-name>Н.</first-name><last-name>Некрасова</last-name></translator>
+    <sequence name="Темный эльф" number="3"/>
+  </title-info>
+  <document-info><id>07AADA78-943E-44A1-B680-4B2EDBA53BA9</id></document-info>
+  <publish-info><publisher>ИЦ «Максима»</publisher><isbn>5-94955-003-X</isbn></publish-info>
+</description>
+<body>
+  <title><p>Роберт Сальваторе</p><p>Воин</p></title>
+  <epigraph><p>Эпиграф</p><text-author>Автор</text-author></epigraph>
+  <section>
+    <title><p>Часть 1</p><p>Изгнание</p></title>
+    <section id="ch1">
+      <title><p>Глава 1</p></title>
+      <p>Текст&nbsp;главы<a l:href="#n1" type="note">[1]</a> и <strong>жирный</strong>.</p>
+      <poem><title><p>Песня</p></title><stanza><v>Строка 1</v><v>Строка 2</v></stanza></poem>
+      <image l:href="#map.png"/>
+    </section>
+    <section>
+      <title><p>Глава 2</p></title>
+      <p>Ссылка на <a l:href="#ch1">первую главу</a>.</p>
+      <empty-line/>
+      <subtitle>* * *</subtitle>
+    </section>
+  </section>
+  <section>
+    <title><p>Эпилог</p></title>
+    <p>Конец &amp; <emphasis>всё</emphasis>.</p>
+  </section>
+</body>
+<body name="notes">
+  <title><p>Примечания</p></title>
+  <section id="n1"><title><p>1</p></title><p>Примечание.</p></section>
+</body>
+<binary id="cover.jpg" content-type="image/jpeg">` + base64.StdEncoding.EncodeToString(coverData) + `</binary>
+<binary id="map.png" content-type="image/png">` + base64.StdEncoding.EncodeToString([]byte("\x89PNG")) + `</binary>
+</FictionBook>`
+
+func encode1251(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := charmap.Windows1251.NewEncoder().String(s)
+	require.NoError(t, err)
+	return []byte(data)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	fb, err := Parse(bytes.NewReader(encode1251(t, sampleFB2)))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Воин", fb.Title)
+	assert.Equal(t, []Author{{FirstName: "Роберт", MiddleName: "Энтони", LastName: "Сальваторе"}}, fb.Authors)
+	assert.Equal(t, "Роберт Энтони Сальваторе", fb.Authors[0].Name())
+	assert.Equal(t, "Сальваторе, Роберт Энтони", fb.Authors[0].FileAs())
+	assert.Equal(t, []Author{{FirstName: "Н.", LastName: "Некрасова"}}, fb.Translators)
+	assert.Equal(t, []string{"sf_fantasy", "adventure"}, fb.Genres)
+	assert.Equal(t, "Покинув подземный мир,\nтемный эльф Дзирт странствует.", fb.Annotation)
+	assert.Equal(t, "ru", fb.Lang)
+	assert.Equal(t, &Sequence{Name: "Темный эльф", Number: 3}, fb.Sequence)
+	assert.Equal(t, "07AADA78-943E-44A1-B680-4B2EDBA53BA9", fb.DocumentID)
+	assert.Equal(t, "ИЦ «Максима»", fb.Publisher)
+	assert.Equal(t, "5-94955-003-X", fb.ISBN)
+	assert.Equal(t, "cover.jpg", fb.CoverID)
+	assert.Equal(t, Binary{ContentType: "image/jpeg", Data: coverData}, fb.Binaries["cover.jpg"])
+	assert.Len(t, fb.bodies, 2)
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{name: "not xml", data: "Глава 1\n\nТекст", wantErr: ErrNotFictionBook},
+		{name: "other root", data: `<html><body/></html>`, wantErr: ErrNotFictionBook},
+		{name: "unknown charset", data: `<?xml version="1.0" encoding="x-unknown"?><FictionBook/>`, wantErr: ErrUnsupportedCharset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(strings.NewReader(tt.data))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAuthor_Name(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Drizzt", Author{Nickname: "Drizzt"}.Name())
+	assert.Equal(t, "", Author{Nickname: "Drizzt"}.FileAs())
+	assert.Equal(t, "Salvatore", Author{LastName: "Salvatore"}.FileAs())
+}
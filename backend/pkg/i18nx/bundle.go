@@ -0,0 +1,60 @@
+package i18nx
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+var DefaultLanguage = language.English
+
+// fileLanguages maps the language part of locale file names (<domain>.<lang>.toml) to language tags.
+// It is needed because "kz" is a country code, go-i18n would parse it as an undefined language.
+var fileLanguages = map[string]language.Tag{
+	"en": language.English,
+	"ru": language.Russian,
+	"kz": language.Kazakh,
+}
+
+// NewBundle builds a bundle from every *.toml file in the root of fsys.
+func NewBundle(fsys fs.FS) (*i18n.Bundle, error) {
+	const op = errorx.Op("i18nx.NewBundle")
+
+	names, err := fs.Glob(fsys, "*.toml")
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+	if len(names) == 0 {
+		return nil, op.Msg("no locale files found")
+	}
+
+	bundle := i18n.NewBundle(DefaultLanguage)
+	unmarshalers := map[string]i18n.UnmarshalFunc{"toml": toml.Unmarshal}
+	for _, name := range names {
+		parts := strings.Split(strings.TrimSuffix(path.Base(name), ".toml"), ".")
+		tag, ok := fileLanguages[parts[len(parts)-1]]
+		if !ok {
+			return nil, op.Msgf("unknown language of locale file(%s)", name)
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, op.WrapMsgf(err, "failed to read locale file(%s)", name)
+		}
+		mf, err := i18n.ParseMessageFileBytes(data, name, unmarshalers)
+		if err != nil {
+			return nil, op.WrapMsgf(err, "failed to parse locale file(%s)", name)
+		}
+		if err := bundle.AddMessages(tag, mf.Messages...); err != nil {
+			return nil, op.WrapMsgf(err, "failed to add messages of locale file(%s)", name)
+		}
+	}
+
+	return bundle, nil
+}
@@ -0,0 +1,49 @@
+package i18nx
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestNewBundle(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"validation.en.toml": &fstest.MapFile{Data: []byte("[validation_required]\nother = \"cannot be blank\"\n")},
+		"validation.kz.toml": &fstest.MapFile{Data: []byte("[validation_required]\nother = \"бос болмауы тиіс\"\n")},
+	}
+
+	bundle, err := NewBundle(fsys)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []language.Tag{language.English, language.Kazakh}, bundle.LanguageTags())
+
+	msg, err := i18n.NewLocalizer(bundle, "kk").Localize(&i18n.LocalizeConfig{MessageID: ValidationRequired})
+	require.NoError(t, err)
+	assert.Equal(t, "бос болмауы тиіс", msg)
+}
+
+func TestNewBundle_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{name: "no locale files", fsys: fstest.MapFS{"README.md": &fstest.MapFile{}}},
+		{name: "unknown language", fsys: fstest.MapFS{"validation.xx.toml": &fstest.MapFile{}}},
+		{name: "malformed toml", fsys: fstest.MapFS{"validation.en.toml": &fstest.MapFile{Data: []byte("[[[")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewBundle(tt.fsys)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package i18nx
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+
+	"github.com/ARUMANDESU/goread/backend/locale"
+	"github.com/ARUMANDESU/goread/backend/pkg/errorx"
+)
+
+type languageKey struct{}
+
+// WithLanguage returns a copy of ctx carrying the language Translator should use.
+func WithLanguage(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, languageKey{}, tag)
+}
+
+// LanguageFromContext returns the language stored by WithLanguage.
+func LanguageFromContext(ctx context.Context) (language.Tag, bool) {
+	tag, ok := ctx.Value(languageKey{}).(language.Tag)
+	return tag, ok
+}
+
+// Translator renders messages of the locale files in the language stored in the context,
+// falling back to DefaultLanguage when the language or a message is missing.
+type Translator struct {
+	bundle  *i18n.Bundle
+	matcher language.Matcher
+}
+
+// NewTranslator returns a Translator over the locale files embedded into the binary.
+func NewTranslator() (*Translator, error) {
+	return NewTranslatorFS(locale.FS)
+}
+
+// NewTranslatorFS returns a Translator over the *.toml locale files in the root of fsys.
+func NewTranslatorFS(fsys fs.FS) (*Translator, error) {
+	const op = errorx.Op("i18nx.NewTranslatorFS")
+
+	bundle, err := NewBundle(fsys)
+	if err != nil {
+		return nil, op.Wrap(err)
+	}
+
+	// bundle.LanguageTags starts with the default language, so the matcher falls back to it
+	return &Translator{
+		bundle:  bundle,
+		matcher: language.NewMatcher(bundle.LanguageTags()),
+	}, nil
+}
+
+// Match negotiates the best supported language for an Accept-Language header value.
+func (t *Translator) Match(acceptLanguage string) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+	_, i, confidence := t.matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLanguage
+	}
+	return t.bundle.LanguageTags()[i]
+}
+
+// Translate renders the message with the given id and placeholder params, e.g. {PhMin: 2} for {{.min}}.
+// If the message does not exist in any language, id is returned.
+func (t *Translator) Translate(ctx context.Context, id string, params map[string]any) string {
+	return t.translate(ctx, &i18n.LocalizeConfig{MessageID: id, TemplateData: params}, id)
+}
+
+// TranslateError renders a validation error using its code as message id and its params as placeholders.
+// If the code does not exist in any locale file, the error's own message is used.
+func (t *Translator) TranslateError(ctx context.Context, err v.Error) string {
+	return t.translate(ctx, &i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{ID: err.Code(), Other: err.Message()},
+		TemplateData:   err.Params(),
+	}, err.Error())
+}
+
+func (t *Translator) translate(ctx context.Context, lc *i18n.LocalizeConfig, fallback string) string {
+	tag, ok := LanguageFromContext(ctx)
+	if !ok {
+		tag = DefaultLanguage
+	}
+
+	id := lc.MessageID
+	if lc.DefaultMessage != nil {
+		id = lc.DefaultMessage.ID
+	}
+
+	msg, _, err := i18n.NewLocalizer(t.bundle, tag.String()).LocalizeWithTag(lc)
+	var notFound *i18n.MessageNotFoundErr
+	switch {
+	case errors.As(err, &notFound):
+		slog.WarnContext(ctx, "missing translation, falling back to default language",
+			"language", tag.String(), "id", id)
+	case err != nil:
+		slog.WarnContext(ctx, "failed to translate message", "language", tag.String(), "id", id, "error", err)
+	}
+	if msg == "" {
+		return fallback
+	}
+
+	return msg
+}
@@ -0,0 +1,114 @@
+package i18nx
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	v "github.com/ARUMANDESU/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestTranslator_Match(t *testing.T) {
+	t.Parallel()
+
+	tr, err := NewTranslator()
+	require.NoError(t, err)
+
+	tests := []struct {
+		acceptLanguage string
+		expected       language.Tag
+	}{
+		{acceptLanguage: "", expected: language.English},
+		{acceptLanguage: "ru", expected: language.Russian},
+		{acceptLanguage: "ru-RU,ru;q=0.9,en-US;q=0.8", expected: language.Russian},
+		{acceptLanguage: "kk-KZ", expected: language.Kazakh},
+		{acceptLanguage: "de-DE,kk;q=0.5", expected: language.Kazakh},
+		{acceptLanguage: "de-DE", expected: language.English},
+		{acceptLanguage: "en-GB", expected: language.English},
+		{acceptLanguage: "garbage;;;", expected: language.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tr.Match(tt.acceptLanguage))
+		})
+	}
+}
+
+func TestTranslator_Translate(t *testing.T) {
+	t.Parallel()
+
+	tr, err := NewTranslatorFS(fstest.MapFS{
+		"validation.en.toml": &fstest.MapFile{Data: []byte(`
+[validation_length_out_of_range]
+other = "the length must be between {{.min}} and {{.max}}"
+
+[validation_required]
+other = "cannot be blank"
+`)},
+		"validation.ru.toml": &fstest.MapFile{Data: []byte(`
+[validation_length_out_of_range]
+other = "длина должна быть от {{.min}} до {{.max}}"
+`)},
+	})
+	require.NoError(t, err)
+
+	ru := WithLanguage(context.Background(), language.Russian)
+	params := map[string]any{PhMin: 2, PhMax: 10}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		id       string
+		expected string
+	}{
+		{name: "placeholders", ctx: ru, id: ValidationLengthOutOfRange, expected: "длина должна быть от 2 до 10"},
+		{name: "no language in context", ctx: context.Background(), id: ValidationLengthOutOfRange, expected: "the length must be between 2 and 10"},
+		{name: "missing in language falls back to english", ctx: ru, id: ValidationRequired, expected: "cannot be blank"},
+		{name: "missing everywhere returns id", ctx: ru, id: "unknown_key", expected: "unknown_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tr.Translate(tt.ctx, tt.id, params))
+		})
+	}
+}
+
+func TestTranslator_TranslateError(t *testing.T) {
+	t.Parallel()
+
+	tr, err := NewTranslator()
+	require.NoError(t, err)
+
+	kk := WithLanguage(context.Background(), language.Kazakh)
+
+	tests := []struct {
+		name     string
+		err      v.Error
+		expected string
+	}{
+		{
+			name:     "params",
+			err:      v.ErrLengthOutOfRange.SetParams(map[string]any{PhMin: 3, PhMax: 75}),
+			expected: "ұзындығы 3–75 аралығында болуы тиіс",
+		},
+		{
+			name:     "unknown code uses error message",
+			err:      v.NewError("validation_custom_unknown", "must be {{.min}} or more").SetParams(map[string]any{PhMin: 1}),
+			expected: "must be 1 or more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tr.TranslateError(kk, tt.err))
+		})
+	}
+}
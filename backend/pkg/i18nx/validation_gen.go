@@ -13,6 +13,8 @@ const (
 	ValidationDateOutOfRange = "validation_date_out_of_range"
 	ValidationEmpty = "validation_empty"
 	ValidationInInvalid = "validation_in_invalid"
+	ValidationInvalidCfi = "validation_invalid_cfi"
+	ValidationInvalidCursor = "validation_invalid_cursor"
 	ValidationInvalidPasswordFormat = "validation_invalid_password_format"
 	ValidationIsAlpha = "validation_is_alpha"
 	ValidationIsAlphanumeric = "validation_is_alphanumeric"
@@ -87,6 +89,8 @@ const (
 	ValidationNotInInvalid = "validation_not_in_invalid"
 	ValidationNotNilRequired = "validation_not_nil_required"
 	ValidationRequired = "validation_required"
+	ValidationUnavailableFormat = "validation_unavailable_format"
+	ValidationUnsupportedLocator = "validation_unsupported_locator"
 )
 
 // Default Messages
@@ -100,6 +104,8 @@ const (
 	ValidationDateOutOfRangeMessage = "the date is out of range"
 	ValidationEmptyMessage = "must be blank"
 	ValidationInInvalidMessage = "must be a valid value"
+	ValidationInvalidCfiMessage = "must be an EPUB CFI such as epubcfi(/6/4!/4/2/1:0)"
+	ValidationInvalidCursorMessage = "must be a cursor returned by a previous page"
 	ValidationInvalidPasswordFormatMessage = "must contain {{.min}}–{{.max}} characters, including at least one uppercase letter, one lowercase letter, one number, and one special character"
 	ValidationIsAlphaMessage = "must contain English letters only"
 	ValidationIsAlphanumericMessage = "must contain English letters and digits only"
@@ -174,6 +180,8 @@ const (
 	ValidationNotInInvalidMessage = "must not be in list"
 	ValidationNotNilRequiredMessage = "is required"
 	ValidationRequiredMessage = "cannot be blank"
+	ValidationUnavailableFormatMessage = "is not available for this item"
+	ValidationUnsupportedLocatorMessage = "is not supported by the format of this item"
 )
 
 // Placeholders
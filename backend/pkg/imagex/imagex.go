@@ -0,0 +1,108 @@
+// Package imagex resizes the images goread serves, such as comic pages, to what the client asked for.
+package imagex
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register decoders for the formats found in comic archives
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	JPEGQuality = 85
+	// MaxPixels bounds the size of images Decode decodes, a small file can claim huge dimensions.
+	MaxPixels = 100_000_000
+)
+
+var ErrTooLarge = errors.New("image too large")
+
+// Decode decodes data in any registered format, it returns ErrTooLarge for images over MaxPixels.
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Fit scales img down so it is at most maxWidth wide and maxHeight high, keeping the aspect ratio.
+// A zero limit is no limit, images that already fit are returned as is.
+func Fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxWidth > 0 && w > maxWidth {
+		h = max(h*maxWidth/w, 1)
+		w = maxWidth
+	}
+	if maxHeight > 0 && h > maxHeight {
+		w = max(w*maxHeight/h, 1)
+		h = maxHeight
+	}
+	if w == b.Dx() && h == b.Dy() {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// EncodeJPEG encodes img with JPEGQuality, transparent parts become white.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	if !isOpaque(img) {
+		bg := image.NewRGBA(img.Bounds())
+		draw.Draw(bg, bg.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(bg, bg.Bounds(), img, img.Bounds().Min, draw.Over)
+		img = bg
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Resize fits the encoded image data into the limits, see Fit, and returns it with its media type.
+// Data that already fits is returned unchanged if browsers can show it, anything else becomes a JPEG.
+func Resize(data []byte, maxWidth, maxHeight int) ([]byte, string, error) {
+	mediaType := http.DetectContentType(data)
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	fits := (maxWidth <= 0 || cfg.Width <= maxWidth) && (maxHeight <= 0 || cfg.Height <= maxHeight)
+	if fits && (mediaType == "image/jpeg" || mediaType == "image/png" || mediaType == "image/gif" || mediaType == "image/webp") {
+		return data, mediaType, nil
+	}
+
+	img, err := Decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	out, err := EncodeJPEG(Fit(img, maxWidth, maxHeight))
+	if err != nil {
+		return nil, "", err
+	}
+	return out, "image/jpeg", nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package imagex
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestFit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		width, height       int
+		maxWidth, maxHeight int
+		expected            image.Point
+	}{
+		{name: "no limits", width: 800, height: 1200, expected: image.Pt(800, 1200)},
+		{name: "already fits", width: 800, height: 1200, maxWidth: 1000, maxHeight: 1500, expected: image.Pt(800, 1200)},
+		{name: "width limit", width: 800, height: 1200, maxWidth: 400, expected: image.Pt(400, 600)},
+		{name: "height limit", width: 800, height: 1200, maxHeight: 300, expected: image.Pt(200, 300)},
+		{name: "both limits", width: 1600, height: 1200, maxWidth: 400, maxHeight: 200, expected: image.Pt(266, 200)},
+		{name: "never below one pixel", width: 1000, height: 1, maxWidth: 10, expected: image.Pt(10, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			img := image.NewGray(image.Rect(0, 0, tt.width, tt.height))
+			got := Fit(img, tt.maxWidth, tt.maxHeight)
+			assert.Equal(t, tt.expected, got.Bounds().Size())
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fitting image is unchanged", func(t *testing.T) {
+		t.Parallel()
+		data := encodePNG(t, image.NewGray(image.Rect(0, 0, 100, 150)))
+
+		got, mediaType, err := Resize(data, 100, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", mediaType)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("big image becomes a smaller jpeg", func(t *testing.T) {
+		t.Parallel()
+		data := encodePNG(t, image.NewGray(image.Rect(0, 0, 1000, 1500)))
+
+		got, mediaType, err := Resize(data, 200, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", mediaType)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(got))
+		require.NoError(t, err)
+		assert.Equal(t, 200, cfg.Width)
+		assert.Equal(t, 300, cfg.Height)
+	})
+
+	t.Run("transparency becomes white", func(t *testing.T) {
+		t.Parallel()
+		data := encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 40, 40)))
+
+		got, _, err := Resize(data, 20, 0)
+		require.NoError(t, err)
+		img, err := jpeg.Decode(bytes.NewReader(got))
+		require.NoError(t, err)
+		r, g, b, _ := img.At(10, 10).RGBA()
+		white, _, _, _ := color.White.RGBA()
+		assert.InDelta(t, white, r, 0x300)
+		assert.InDelta(t, white, g, 0x300)
+		assert.InDelta(t, white, b, 0x300)
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := Resize([]byte("PK\x03\x04"), 100, 0)
+		require.Error(t, err)
+	})
+}
+
+func TestDecode_tooLarge(t *testing.T) {
+	t.Parallel()
+
+	// A GIF header claiming a 60000x60000 screen, with no image data.
+	gif := []byte("GIF89a\x60\xea\x60\xea\x00\x00\x00\x3b")
+	_, err := Decode(gif)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	img, err := Decode(encodePNG(t, image.NewGray(image.Rect(0, 0, 3, 2))))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 3, 2), img.Bounds())
+}
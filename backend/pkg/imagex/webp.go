@@ -0,0 +1,452 @@
+package imagex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"slices"
+)
+
+// The VP8L bitstream is specified in https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification,
+// the constants below are named after its sections.
+const (
+	vp8lSignature = 0x2f
+	// vp8lMaxSize is the largest width and height that fit in the 14 bit header fields.
+	vp8lMaxSize = 1 << 14
+
+	transformSubtractGreen = 2
+
+	nLiteralCodes  = 256
+	nLengthCodes   = 24
+	nDistanceCodes = 40
+	// maxBackRef is the longest LZ77 backward reference the 24 length prefix codes can express.
+	maxBackRef = 4096
+	// minBackRef is the shortest run worth a backward reference instead of literal pixels.
+	minBackRef = 3
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+	nCodeLengthCodes        = 19
+
+	// distance codes of the pixel above and the pixel to the left, see the distance mapping in section 4.2.2
+	distanceCodeAbove = 1
+	distanceCodeLeft  = 2
+)
+
+var ErrWebPTooLarge = errors.New("image too large for webp")
+
+// codeLengthCodeOrder is the order the code lengths of the code length code are written in.
+var codeLengthCodeOrder = [nCodeLengthCodes]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP encodes img as a lossless WebP. Lossless keeps transparency and suits small images
+// such as thumbnails, for large photos EncodeJPEG gives much smaller files.
+//
+// The encoder only uses the subtract green transform and runs of the pixel to the left or above,
+// so its files are bigger than those of libwebp but any WebP decoder reads them.
+func EncodeWebP(img image.Image) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 1 || h < 1 || w > vp8lMaxSize || h > vp8lMaxSize {
+		return nil, ErrWebPTooLarge
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || nrgba.Rect.Min != (image.Point{}) || nrgba.Stride != 4*w {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+	}
+
+	// ARGB pixels with green subtracted from red and blue
+	argb := make([]uint32, w*h)
+	opaque := true
+	for i := range argb {
+		p := nrgba.Pix[4*i : 4*i+4]
+		r, g, b, a := p[0]-p[1], p[1], p[2]-p[1], p[3]
+		argb[i] = uint32(a)<<24 | uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+		opaque = opaque && a == 0xff
+	}
+
+	var bw bitWriter
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(w-1), 14)
+	bw.write(uint32(h-1), 14)
+	if opaque {
+		bw.write(0, 1)
+	} else {
+		bw.write(1, 1)
+	}
+	bw.write(0, 3) // version
+	bw.write(1, 1) // a transform follows
+	bw.write(transformSubtractGreen, 2)
+	bw.write(0, 1) // no more transforms
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // no meta prefix codes
+	encodePixels(&bw, argb, w)
+
+	data := bw.bytes()
+	var buf bytes.Buffer
+	size := 4 + 8 + len(data) + len(data)&1
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(size))
+	buf.WriteString("WEBPVP8L")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)&1 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes(), nil
+}
+
+// backRef is a LZ77 backward reference, a zero length means a literal pixel.
+type backRef struct {
+	length       int
+	distanceCode int
+}
+
+// encodePixels writes the prefix codes and the entropy coded pixels of an image w pixels wide.
+func encodePixels(bw *bitWriter, argb []uint32, w int) {
+	refs := make([]backRef, 0, len(argb))
+	var (
+		green    = make([]int, nLiteralCodes+nLengthCodes)
+		red      = make([]int, nLiteralCodes)
+		blue     = make([]int, nLiteralCodes)
+		alpha    = make([]int, nLiteralCodes)
+		distance = make([]int, nDistanceCodes)
+	)
+	for p := 0; p < len(argb); {
+		ref := findBackRef(argb, p, w)
+		if ref.length == 0 {
+			c := argb[p]
+			green[c>>8&0xff]++
+			red[c>>16&0xff]++
+			blue[c&0xff]++
+			alpha[c>>24]++
+			p++
+		} else {
+			lengthSymbol, _, _ := prefixCode(ref.length)
+			distanceSymbol, _, _ := prefixCode(ref.distanceCode)
+			green[nLiteralCodes+lengthSymbol]++
+			distance[distanceSymbol]++
+			p += ref.length
+		}
+		refs = append(refs, ref)
+	}
+
+	codes := make([]prefixCodes, 0, 5)
+	for _, histogram := range [][]int{green, red, blue, alpha, distance} {
+		codes = append(codes, writePrefixCode(bw, histogram))
+	}
+
+	p := 0
+	for _, ref := range refs {
+		if ref.length == 0 {
+			c := argb[p]
+			codes[0].write(bw, int(c>>8&0xff))
+			codes[1].write(bw, int(c>>16&0xff))
+			codes[2].write(bw, int(c&0xff))
+			codes[3].write(bw, int(c>>24))
+			p++
+			continue
+		}
+		symbol, nExtra, extra := prefixCode(ref.length)
+		codes[0].write(bw, nLiteralCodes+symbol)
+		bw.write(extra, nExtra)
+		symbol, nExtra, extra = prefixCode(ref.distanceCode)
+		codes[4].write(bw, symbol)
+		bw.write(extra, nExtra)
+		p += ref.length
+	}
+}
+
+// findBackRef returns the longer run at p that repeats the pixel to the left or the row above.
+func findBackRef(argb []uint32, p, w int) backRef {
+	limit := min(len(argb)-p, maxBackRef)
+	run := func(distance int) int {
+		if p < distance {
+			return 0
+		}
+		n := 0
+		for n < limit && argb[p+n] == argb[p+n-distance] {
+			n++
+		}
+		return n
+	}
+
+	best := backRef{}
+	if n := run(1); n >= minBackRef {
+		best = backRef{length: n, distanceCode: distanceCodeLeft}
+	}
+	if n := run(w); n >= minBackRef && n > best.length {
+		best = backRef{length: n, distanceCode: distanceCodeAbove}
+	}
+	return best
+}
+
+// prefixCode splits a LZ77 length or distance code, which start at 1, into a prefix symbol and
+// its extra bits.
+func prefixCode(value int) (symbol int, nExtra uint, extra uint32) {
+	v := uint32(value - 1)
+	if v < 4 {
+		return int(v), 0, 0
+	}
+	highest := 31
+	for v>>highest == 0 {
+		highest--
+	}
+	second := int(v>>(highest-1)) & 1
+	nExtra = uint(highest - 1)
+	return 2*highest + second, nExtra, v & (1<<nExtra - 1)
+}
+
+// prefixCodes are the canonical prefix codes of an alphabet, symbols with a zero length are unused.
+type prefixCodes struct {
+	lengths []uint8
+	codes   []uint32
+}
+
+func (c prefixCodes) write(bw *bitWriter, symbol int) {
+	// codes are read starting with their most significant bit
+	n := uint(c.lengths[symbol])
+	code := c.codes[symbol]
+	var reversed uint32
+	for i := uint(0); i < n; i++ {
+		reversed = reversed<<1 | code>>i&1
+	}
+	bw.write(reversed, n)
+}
+
+// writePrefixCode writes the prefix code of histogram and returns it. Alphabets using at most one
+// symbol below 256 get a simple code whose symbols take no bits at all.
+func writePrefixCode(bw *bitWriter, histogram []int) prefixCodes {
+	var used []int
+	for symbol, n := range histogram {
+		if n > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 || len(used) == 1 && used[0] < nLiteralCodes {
+		symbol := 0
+		if len(used) == 1 {
+			symbol = used[0]
+		}
+		bw.write(1, 1) // simple code
+		bw.write(0, 1) // of one symbol
+		if symbol < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(symbol), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(symbol), 8)
+		}
+		return prefixCodes{lengths: make([]uint8, len(histogram)), codes: make([]uint32, len(histogram))}
+	}
+	if len(used) == 1 {
+		// a normal code needs two symbols to give its symbol a length
+		histogram = slices.Clone(histogram)
+		histogram[0] = 1
+	}
+
+	lengths := codeLengths(histogram, maxCodeLength)
+	bw.write(0, 1) // normal code
+	writeCodeLengths(bw, lengths)
+	return prefixCodes{lengths: lengths, codes: canonicalCodes(lengths)}
+}
+
+// codeLengthToken is a symbol of the code length code with its extra bits.
+type codeLengthToken struct {
+	symbol int
+	nExtra uint
+	extra  uint32
+}
+
+// writeCodeLengths writes lengths compressed with the code length code, zero runs use the symbols
+// 17 and 18 and runs of other lengths repeat them with 16.
+func writeCodeLengths(bw *bitWriter, lengths []uint8) {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		n := 1
+		for i+n < len(lengths) && lengths[i+n] == l {
+			n++
+		}
+		i += n
+
+		if l == 0 {
+			for n >= 11 {
+				k := min(n, 138)
+				tokens = append(tokens, codeLengthToken{symbol: 18, nExtra: 7, extra: uint32(k - 11)})
+				n -= k
+			}
+			if n >= 3 {
+				tokens = append(tokens, codeLengthToken{symbol: 17, nExtra: 3, extra: uint32(n - 3)})
+				n = 0
+			}
+		} else {
+			tokens = append(tokens, codeLengthToken{symbol: int(l)})
+			n--
+			for n >= 3 {
+				k := min(n, 6)
+				tokens = append(tokens, codeLengthToken{symbol: 16, nExtra: 2, extra: uint32(k - 3)})
+				n -= k
+			}
+		}
+		for ; n > 0; n-- {
+			tokens = append(tokens, codeLengthToken{symbol: int(l)})
+		}
+	}
+
+	histogram := make([]int, nCodeLengthCodes)
+	for _, t := range tokens {
+		histogram[t.symbol]++
+	}
+	clLengths := make([]uint8, nCodeLengthCodes)
+	code := prefixCodes{lengths: make([]uint8, nCodeLengthCodes), codes: make([]uint32, nCodeLengthCodes)}
+	if isSingle(histogram) {
+		// a single symbol takes no bits, its length only has to be non-zero
+		clLengths[tokens[0].symbol] = 1
+	} else {
+		clLengths = codeLengths(histogram, maxCodeLengthCodeLength)
+		code = prefixCodes{lengths: clLengths, codes: canonicalCodes(clLengths)}
+	}
+
+	n := 4
+	for i, symbol := range codeLengthCodeOrder {
+		if clLengths[symbol] > 0 {
+			n = max(n, i+1)
+		}
+	}
+	bw.write(uint32(n-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:n] {
+		bw.write(uint32(clLengths[symbol]), 3)
+	}
+
+	bw.write(0, 1) // lengths for every symbol follow
+	for _, t := range tokens {
+		code.write(bw, t.symbol)
+		bw.write(t.extra, t.nExtra)
+	}
+}
+
+// isSingle reports whether exactly one symbol of histogram is used.
+func isSingle(histogram []int) bool {
+	n := 0
+	for _, count := range histogram {
+		if count > 0 {
+			n++
+		}
+	}
+	return n == 1
+}
+
+// codeLengths returns Huffman code lengths for the symbol frequencies in histogram that are at
+// most maxLength long. Frequencies are flattened until the tree is shallow enough.
+func codeLengths(histogram []int, maxLength uint8) []uint8 {
+	freq := slices.Clone(histogram)
+	for {
+		lengths := huffmanLengths(freq)
+		if slices.Max(lengths) <= maxLength {
+			return lengths
+		}
+		for i, n := range freq {
+			if n > 0 {
+				freq[i] = (n + 1) / 2
+			}
+		}
+	}
+}
+
+// huffmanLengths returns the depth of every symbol in a Huffman tree of freq, at least two
+// frequencies must be non-zero.
+func huffmanLengths(freq []int) []uint8 {
+	type node struct {
+		weight      int
+		symbol      int
+		left, right int
+	}
+	var nodes []node
+	for symbol, n := range freq {
+		if n > 0 {
+			nodes = append(nodes, node{weight: n, symbol: symbol, left: -1, right: -1})
+		}
+	}
+	slices.SortStableFunc(nodes, func(a, b node) int { return a.weight - b.weight })
+
+	// two queue construction, leaves are sorted and merged nodes are created in ascending order
+	nLeaves := len(nodes)
+	leaf, merged := 0, nLeaves
+	pop := func() int {
+		if leaf < nLeaves && (merged >= len(nodes) || nodes[leaf].weight <= nodes[merged].weight) {
+			leaf++
+			return leaf - 1
+		}
+		merged++
+		return merged - 1
+	}
+	for len(nodes) < 2*nLeaves-1 {
+		a, b := pop(), pop()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, symbol: -1, left: a, right: b})
+	}
+
+	lengths := make([]uint8, len(freq))
+	var walk func(i int, depth uint8)
+	walk = func(i int, depth uint8) {
+		if nodes[i].left < 0 {
+			lengths[nodes[i].symbol] = depth
+			return
+		}
+		walk(nodes[i].left, depth+1)
+		walk(nodes[i].right, depth+1)
+	}
+	walk(len(nodes)-1, 0)
+	return lengths
+}
+
+// canonicalCodes assigns canonical prefix codes to the code lengths, as decoders rebuild them.
+func canonicalCodes(lengths []uint8) []uint32 {
+	var histogram [maxCodeLength + 1]uint32
+	for _, l := range lengths {
+		histogram[l]++
+	}
+	histogram[0] = 0
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + histogram[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for symbol, l := range lengths {
+		if l > 0 {
+			codes[symbol] = next[l]
+			next[l]++
+		}
+	}
+	return codes
+}
+
+// bitWriter writes values least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf   []byte
+	bits  uint64
+	nBits uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	w.bits |= uint64(v) << w.nBits
+	w.nBits += n
+	for w.nBits >= 8 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits >>= 8
+		w.nBits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nBits > 0 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits, w.nBits = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,128 @@
+package imagex
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		img  func() image.Image
+	}{
+		{
+			name: "single pixel",
+			img: func() image.Image {
+				img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+				img.Set(0, 0, color.NRGBA{R: 200, G: 10, B: 30, A: 255})
+				return img
+			},
+		},
+		{
+			name: "uniform",
+			img: func() image.Image {
+				img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+				for i := range img.Pix {
+					img.Pix[i] = 0x80
+				}
+				return img
+			},
+		},
+		{
+			name: "gradient with borders",
+			img: func() image.Image {
+				img := image.NewNRGBA(image.Rect(0, 0, 160, 240))
+				for y := range 240 {
+					for x := range 160 {
+						c := color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x + y), A: 255}
+						if x < 8 || y < 8 {
+							c = color.NRGBA{A: 255}
+						}
+						img.SetNRGBA(x, y, c)
+					}
+				}
+				return img
+			},
+		},
+		{
+			name: "noise with transparency",
+			img: func() image.Image {
+				rnd := rand.New(rand.NewPCG(1, 2))
+				img := image.NewNRGBA(image.Rect(0, 0, 97, 61))
+				for i := range img.Pix {
+					img.Pix[i] = uint8(rnd.IntN(256))
+				}
+				return img
+			},
+		},
+		{
+			name: "sub image of rgba",
+			img: func() image.Image {
+				img := image.NewRGBA(image.Rect(0, 0, 50, 50))
+				for y := range 50 {
+					for x := range 50 {
+						img.Set(x, y, color.RGBA{R: uint8(5 * x), G: uint8(5 * y), B: 7, A: 255})
+					}
+				}
+				return img.SubImage(image.Rect(10, 20, 40, 45))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			img := tt.img()
+			data, err := EncodeWebP(img)
+			require.NoError(t, err)
+
+			got, err := webp.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			b := img.Bounds()
+			require.Equal(t, b.Size(), got.Bounds().Size())
+			for y := range b.Dy() {
+				for x := range b.Dx() {
+					expected := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y))
+					if !assert.Equal(t, expected, color.NRGBAModel.Convert(got.At(x, y)), "pixel %d,%d", x, y) {
+						return
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("too large", func(t *testing.T) {
+		t.Parallel()
+		_, err := EncodeWebP(image.NewGray(image.Rect(0, 0, 1<<14+1, 1)))
+		assert.ErrorIs(t, err, ErrWebPTooLarge)
+	})
+}
+
+func TestCodeLengths_limited(t *testing.T) {
+	t.Parallel()
+
+	// Fibonacci frequencies give the deepest Huffman trees.
+	histogram := make([]int, 30)
+	histogram[0], histogram[1] = 1, 1
+	for i := 2; i < len(histogram); i++ {
+		histogram[i] = histogram[i-1] + histogram[i-2]
+	}
+
+	lengths := codeLengths(histogram, maxCodeLength)
+	kraft := 0.0
+	for _, l := range lengths {
+		assert.LessOrEqual(t, l, uint8(maxCodeLength))
+		kraft += 1 / float64(uint(1)<<l)
+	}
+	assert.InDelta(t, 1.0, kraft, 1e-9, "the code must be complete")
+}
@@ -0,0 +1,46 @@
+// Package koreaderx implements the parts of KOReader that goread has to agree with.
+package koreaderx
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+const (
+	partialMD5Step = 1024
+	// partialMD5LastSample is the i of the last sample, at 1024<<20 = 1 GiB.
+	partialMD5LastSample = 10
+)
+
+// PartialMD5 returns the document hash KOReader uses to match progress between devices.
+// It is the MD5 of up to 1 KiB samples at offset 0 and at offsets 1024<<2i for i in 0..10,
+// that is 1 KiB, 4 KiB and so on up to 1 GiB, twelve samples in all, which keeps hashing big
+// files cheap. It mirrors util.partialMD5 of KOReader, size is the size of the file.
+//
+// Before the 1 GiB sample was included, files over 1 GiB got hashes KOReader never computes, so
+// their progress did not sync. Hashes of such files stored back then have to be recomputed, files
+// up to 1 GiB hash the same as before.
+func PartialMD5(r io.ReaderAt, size int64) (string, error) {
+	h := md5.New()
+	buf := make([]byte, partialMD5Step)
+
+	for i := -1; i <= partialMD5LastSample; i++ {
+		var offset int64
+		if i >= 0 {
+			offset = partialMD5Step << (2 * i)
+		}
+		if offset >= size {
+			break
+		}
+
+		n, err := r.ReadAt(buf[:min(partialMD5Step, size-offset)], offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		h.Write(buf[:n])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,102 @@
+package koreaderx
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// koreaderSampleOffsets are the offsets util.partialMD5 of KOReader reads, for i = -1, 10.
+var koreaderSampleOffsets = []int64{0, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216, 67108864, 268435456, 1073741824}
+
+// patternReader is a file of size bytes where byte i is i mod 251, without holding it in memory.
+type patternReader struct{ size int64 }
+
+func (r patternReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	n := int(min(int64(len(p)), r.size-off))
+	for i := range n {
+		p[i] = byte((off + int64(i)) % 251)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestPartialMD5(t *testing.T) {
+	t.Parallel()
+
+	// file where byte i is i mod 251, so every sample differs
+	file := func(size int) []byte {
+		b := make([]byte, size)
+		for i := range b {
+			b[i] = byte(i % 251)
+		}
+		return b
+	}
+
+	// expected hashes the samples the way KOReader reads them
+	expected := func(data []byte) string {
+		h := md5.New()
+		for _, offset := range koreaderSampleOffsets {
+			if offset >= int64(len(data)) {
+				break
+			}
+			h.Write(data[offset:min(offset+1024, int64(len(data)))])
+		}
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "smaller than a sample", data: file(100)},
+		{name: "overlapping first samples", data: file(1500)},
+		{name: "several samples", data: file(300_000)},
+		{name: "last sample is partial", data: file(1024*1024 + 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := PartialMD5(bytes.NewReader(tt.data), int64(len(tt.data)))
+			require.NoError(t, err)
+			assert.Equal(t, expected(tt.data), got)
+		})
+	}
+
+	t.Run("files over 1 GiB include the 1 GiB sample", func(t *testing.T) {
+		t.Parallel()
+		size := int64(1<<30 + 10)
+		r := patternReader{size: size}
+
+		h := md5.New()
+		for _, offset := range koreaderSampleOffsets {
+			sample := make([]byte, min(1024, size-offset))
+			_, err := r.ReadAt(sample, offset)
+			require.NoError(t, err)
+			h.Write(sample)
+		}
+
+		got, err := PartialMD5(r, size)
+		require.NoError(t, err)
+		assert.Equal(t, hex.EncodeToString(h.Sum(nil)), got)
+	})
+
+	t.Run("empty file is the md5 of nothing", func(t *testing.T) {
+		t.Parallel()
+		got, err := PartialMD5(bytes.NewReader(nil), 0)
+		require.NoError(t, err)
+		assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", got)
+	})
+}
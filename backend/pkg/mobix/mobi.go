@@ -0,0 +1,123 @@
+// Package mobix reads Mobipocket files, such as .mobi and .azw3 Kindle books.
+package mobix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	palmDBHeaderLen = 78
+	recordEntryLen  = 8
+
+	exthCoverOffset = 201
+	exthThumbOffset = 202
+	// noImage is what the EXTH offsets hold when they are unset.
+	noImage = 0xffffffff
+)
+
+var (
+	ErrNotMobipocket = errors.New("not a mobipocket file")
+	ErrNoCover       = errors.New("no cover")
+	ErrCorrupted     = errors.New("corrupted mobipocket file")
+)
+
+// Cover returns the cover image of the Mobipocket file in r, the thumbnail image if there is no cover.
+// It returns ErrNoCover if the EXTH header names neither.
+func Cover(r io.ReaderAt, size int64) ([]byte, error) {
+	header := make([]byte, palmDBHeaderLen)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotMobipocket, err)
+	}
+	if string(header[60:68]) != "BOOKMOBI" {
+		return nil, ErrNotMobipocket
+	}
+
+	records := int(binary.BigEndian.Uint16(header[76:78]))
+	if records == 0 || int64(palmDBHeaderLen+records*recordEntryLen) > size {
+		return nil, ErrCorrupted
+	}
+	list := make([]byte, records*recordEntryLen)
+	if _, err := r.ReadAt(list, palmDBHeaderLen); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	offsets := make([]int64, records+1)
+	for i := range records {
+		offsets[i] = int64(binary.BigEndian.Uint32(list[i*recordEntryLen:]))
+	}
+	offsets[records] = size
+	for i := range records {
+		if offsets[i] > offsets[i+1] {
+			return nil, ErrCorrupted
+		}
+	}
+	record := func(i int) ([]byte, error) {
+		if i < 0 || i >= records {
+			return nil, ErrCorrupted
+		}
+		b := make([]byte, offsets[i+1]-offsets[i])
+		if _, err := r.ReadAt(b, offsets[i]); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: %w", ErrCorrupted, err)
+		}
+		return b, nil
+	}
+
+	rec0, err := record(0)
+	if err != nil {
+		return nil, err
+	}
+	exth, firstImage, err := parseMOBIHeader(rec0)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := exth[exthCoverOffset]
+	if offset == noImage {
+		offset = exth[exthThumbOffset]
+	}
+	if offset == noImage || firstImage == noImage {
+		return nil, ErrNoCover
+	}
+	return record(int(firstImage) + int(offset))
+}
+
+// parseMOBIHeader returns the numeric EXTH records we care about, unset ones are noImage,
+// and the index of the first image record.
+func parseMOBIHeader(rec0 []byte) (map[uint32]uint32, uint32, error) {
+	// The MOBI header follows the 16 byte PalmDOC header, offsets are from the start of the record.
+	if len(rec0) < 0x84 || string(rec0[16:20]) != "MOBI" {
+		return nil, 0, ErrCorrupted
+	}
+	headerLen := int(binary.BigEndian.Uint32(rec0[20:24]))
+	firstImage := binary.BigEndian.Uint32(rec0[0x6c:0x70])
+	flags := binary.BigEndian.Uint32(rec0[0x80:0x84])
+
+	exth := map[uint32]uint32{exthCoverOffset: noImage, exthThumbOffset: noImage}
+	if flags&0x40 == 0 {
+		return exth, firstImage, nil
+	}
+
+	start := 16 + headerLen
+	if start+12 > len(rec0) || string(rec0[start:start+4]) != "EXTH" {
+		return nil, 0, ErrCorrupted
+	}
+	count := int(binary.BigEndian.Uint32(rec0[start+8 : start+12]))
+	pos := start + 12
+	for range count {
+		if pos+8 > len(rec0) {
+			return nil, 0, ErrCorrupted
+		}
+		typ := binary.BigEndian.Uint32(rec0[pos : pos+4])
+		n := int(binary.BigEndian.Uint32(rec0[pos+4 : pos+8]))
+		if n < 8 || pos+n > len(rec0) {
+			return nil, 0, ErrCorrupted
+		}
+		if (typ == exthCoverOffset || typ == exthThumbOffset) && n == 12 {
+			exth[typ] = binary.BigEndian.Uint32(rec0[pos+8 : pos+12])
+		}
+		pos += n
+	}
+	return exth, firstImage, nil
+}
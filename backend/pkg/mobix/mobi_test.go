@@ -0,0 +1,109 @@
+package mobix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildMOBI returns a PalmDB with a record 0 holding a MOBI header whose first image is record 2,
+// exth maps EXTH record types to uint32 values, a nil exth leaves the EXTH header out.
+func buildMOBI(t *testing.T, exth map[uint32]uint32, images ...[]byte) []byte {
+	t.Helper()
+
+	mobi := make([]byte, 0xe8)
+	copy(mobi[16:], "MOBI")
+	binary.BigEndian.PutUint32(mobi[20:], 0xe8-16)
+	binary.BigEndian.PutUint32(mobi[0x6c:], 2)
+	if exth != nil {
+		binary.BigEndian.PutUint32(mobi[0x80:], 0x40)
+		var recs bytes.Buffer
+		for typ, val := range exth {
+			_ = binary.Write(&recs, binary.BigEndian, []uint32{typ, 12, val})
+		}
+		var header bytes.Buffer
+		header.WriteString("EXTH")
+		_ = binary.Write(&header, binary.BigEndian, []uint32{uint32(12 + recs.Len()), uint32(len(exth))})
+		header.Write(recs.Bytes())
+		mobi = append(mobi, header.Bytes()...)
+	}
+
+	records := append([][]byte{mobi, []byte("text")}, images...)
+	var buf bytes.Buffer
+	header := make([]byte, palmDBHeaderLen)
+	copy(header, "Homeland")
+	copy(header[60:], "BOOKMOBI")
+	binary.BigEndian.PutUint16(header[76:], uint16(len(records)))
+	buf.Write(header)
+	offset := palmDBHeaderLen + len(records)*recordEntryLen + 2
+	for i, rec := range records {
+		_ = binary.Write(&buf, binary.BigEndian, []uint32{uint32(offset), uint32(i)})
+		offset += len(rec)
+	}
+	buf.Write([]byte{0, 0})
+	for _, rec := range records {
+		buf.Write(rec)
+	}
+	return buf.Bytes()
+}
+
+func TestCover(t *testing.T) {
+	t.Parallel()
+
+	thumb, cover := []byte("thumb"), []byte("cover")
+
+	tests := []struct {
+		name          string
+		file          []byte
+		expectedCover []byte
+		expectedErr   error
+	}{
+		{
+			name:          "cover offset",
+			file:          buildMOBI(t, map[uint32]uint32{exthCoverOffset: 1, exthThumbOffset: 0}, thumb, cover),
+			expectedCover: cover,
+		},
+		{
+			name:          "thumbnail when there is no cover",
+			file:          buildMOBI(t, map[uint32]uint32{exthCoverOffset: noImage, exthThumbOffset: 0}, thumb, cover),
+			expectedCover: thumb,
+		},
+		{
+			name:        "no exth",
+			file:        buildMOBI(t, nil, cover),
+			expectedErr: ErrNoCover,
+		},
+		{
+			name:        "offset past the last record",
+			file:        buildMOBI(t, map[uint32]uint32{exthCoverOffset: 5}, cover),
+			expectedErr: ErrCorrupted,
+		},
+		{
+			name:        "not a mobipocket file",
+			file:        append(make([]byte, 60), []byte("TEXtREAd"+string(make([]byte, 20)))...),
+			expectedErr: ErrNotMobipocket,
+		},
+		{
+			name:        "too short",
+			file:        []byte("BOOKMOBI"),
+			expectedErr: ErrNotMobipocket,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := Cover(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCover, data)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package txtx converts plain text books, often in a legacy Cyrillic code page, to EPUB.
+package txtx
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	xunicode "golang.org/x/text/encoding/unicode"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+	"github.com/ARUMANDESU/goread/backend/pkg/xmlx"
+)
+
+// Decode returns the text of data as UTF-8. A byte order mark selects UTF-8 or UTF-16; data
+// without one is UTF-8 if it is valid UTF-8, otherwise whichever of windows-1251, KOI8-R and
+// CP866, the DOS code page, yields more lowercase Cyrillic letters, as running text is mostly
+// lowercase. Text without Cyrillic letters, such as Latin-1, falls back to windows-1251.
+func Decode(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte("\xff\xfe")), bytes.HasPrefix(data, []byte("\xfe\xff")):
+		text, err := xunicode.UTF16(xunicode.LittleEndian, xunicode.ExpectBOM).NewDecoder().Bytes(data)
+		if err == nil {
+			return string(text)
+		}
+	case utf8.Valid(data):
+		return string(data)
+	}
+
+	best, bestScore := "", -1
+	for _, enc := range []encoding.Encoding{charmap.Windows1251, charmap.KOI8R, charmap.CodePage866} {
+		text, err := enc.NewDecoder().Bytes(data)
+		if err != nil {
+			continue
+		}
+		if score := lowercaseCyrillic(text); score > bestScore {
+			best, bestScore = string(text), score
+		}
+	}
+	return best
+}
+
+func lowercaseCyrillic(text []byte) int {
+	n := 0
+	for _, r := range string(text) {
+		if r >= 'а' && r <= 'я' || r == 'ё' {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	// chapterPattern and partPattern match the lines that start a chapter or a part, which holds
+	// chapters, by their first two words: "Глава 1", "Chapter IV. Title", "Часть третья".
+	chapterPattern = regexp.MustCompile(`(?i)^(глава|chapter)\s+(\S+)`)
+	partPattern    = regexp.MustCompile(`(?i)^(часть|книга|part|book)\s+(\S+)`)
+	numeralPattern = regexp.MustCompile(`(?i)^([0-9]+|[ivxlcdm]+)[.:]?$`)
+	// standalonePattern matches the lines that start a chapter by themselves.
+	standalonePattern = regexp.MustCompile(`(?i)^(пролог|эпилог|предисловие|послесловие|prologue|epilogue)([.:!]?$|[.:!]?\s)`)
+)
+
+const (
+	// maxHeadingLength is the length in runes above which a line is a paragraph whatever it starts with.
+	maxHeadingLength = 80
+	// maxChapterSize is the size in bytes above which text without headings is split into chapters,
+	// as readers slow down on huge content documents.
+	maxChapterSize = 150 << 10
+)
+
+// Book returns the chapters of a text file as a publication for epubx.WriteBook. Text files have
+// no metadata, the caller sets the identifier and title.
+func Book(data []byte) epubx.Book {
+	return epubx.Book{Chapters: Chapters(Decode(data))}
+}
+
+// Chapters splits text into chapters of XHTML paragraphs. Lines such as "Глава 1" or "Chapter 1"
+// start a chapter; lines such as "Часть 1" start a part whose chapters are its children. Paragraphs
+// are separated by blank lines, or are a line each in text that has few blank lines.
+func Chapters(text string) []epubx.Chapter {
+	var (
+		chapters []epubx.Chapter
+		// inPart is the index of the part chapters are added to, -1 before the first part.
+		inPart  = -1
+		started bool
+		title   string
+		isPart  bool
+		body    strings.Builder
+	)
+	emit := func() {
+		if !started {
+			return
+		}
+		c := epubx.Chapter{Title: title, Body: body.String()}
+		switch {
+		case isPart:
+			chapters = append(chapters, c)
+			inPart = len(chapters) - 1
+		case inPart >= 0:
+			chapters[inPart].Children = append(chapters[inPart].Children, c)
+		default:
+			chapters = append(chapters, c)
+		}
+		started, title, isPart = false, "", false
+		body.Reset()
+	}
+	start := func(heading string, part bool) {
+		emit()
+		started, title, isPart = true, heading, part
+		if heading == "" {
+			return
+		}
+		level := "h1"
+		if !part && inPart >= 0 {
+			level = "h2"
+		}
+		body.WriteString("<" + level + ">" + xmlx.EscapeText(heading) + "</" + level + ">")
+	}
+
+	for _, para := range paragraphs(text) {
+		switch {
+		case isPartHeading(para):
+			start(para, true)
+			continue
+		case isChapterHeading(para):
+			start(para, false)
+			continue
+		case !started, body.Len() > maxChapterSize:
+			start("", false)
+		}
+		body.WriteString("\n<p>" + xmlx.EscapeText(para) + "</p>")
+	}
+	emit()
+	return chapters
+}
+
+func isPartHeading(line string) bool {
+	return isNumbered(line, partPattern)
+}
+
+func isChapterHeading(line string) bool {
+	return isNumbered(line, chapterPattern) ||
+		utf8.RuneCountInString(line) <= maxHeadingLength && standalonePattern.MatchString(line)
+}
+
+// isNumbered reports whether line is a heading word followed by a numeral, or by one other word as
+// in "Глава первая"; "Book lovers will" is a paragraph.
+func isNumbered(line string, pattern *regexp.Regexp) bool {
+	if utf8.RuneCountInString(line) > maxHeadingLength {
+		return false
+	}
+	m := pattern.FindStringSubmatch(line)
+	return m != nil && (numeralPattern.MatchString(m[2]) || len(strings.Fields(line)) == 2)
+}
+
+// paragraphs returns the paragraphs of text with runs of whitespace collapsed. Control characters
+// XML does not allow, such as the end of file mark of DOS, count as whitespace.
+func paragraphs(text string) []string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	blank := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			blank++
+		}
+	}
+	// Text wrapped at a fixed width separates paragraphs with blank lines; text without them has a
+	// paragraph per line.
+	linePerParagraph := blank*10 < len(lines)
+
+	var paras []string
+	var cur []string
+	end := func() {
+		if len(cur) > 0 {
+			paras = append(paras, strings.Join(cur, " "))
+			cur = nil
+		}
+	}
+	for _, line := range lines {
+		line = strings.Join(strings.FieldsFunc(line, isSeparator), " ")
+		if line == "" {
+			end()
+			continue
+		}
+		// A heading is a paragraph of its own even in wrapped text.
+		heading := isChapterHeading(line) || isPartHeading(line)
+		if heading {
+			end()
+		}
+		cur = append(cur, line)
+		if linePerParagraph || heading {
+			end()
+		}
+	}
+	end()
+	return paras
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || !xmlx.IsChar(r)
+}
@@ -0,0 +1,131 @@
+package txtx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/ARUMANDESU/goread/backend/pkg/epubx"
+)
+
+const sample = "Покинув подземный мир, темный эльф начинает свое странствие."
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	win1251, err := charmap.Windows1251.NewEncoder().String(sample)
+	require.NoError(t, err)
+	koi8r, err := charmap.KOI8R.NewEncoder().String(sample)
+	require.NoError(t, err)
+	cp866, err := charmap.CodePage866.NewEncoder().String(sample)
+	require.NoError(t, err)
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(sample)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "utf-8", data: sample},
+		{name: "utf-8 with bom", data: "\xef\xbb\xbf" + sample},
+		{name: "utf-16 with bom", data: utf16},
+		{name: "windows-1251", data: win1251},
+		{name: "koi8-r", data: koi8r},
+		{name: "cp866", data: cp866},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, sample, Decode([]byte(tt.data)))
+		})
+	}
+}
+
+func TestChapters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want []epubx.Chapter
+	}{
+		{
+			name: "wrapped paragraphs",
+			text: "Роберт Сальваторе\r\nВоин\r\n\r\nГлава 1\r\nПервая строка\r\nабзаца.\r\n\r\n  Второй   абзац & <теги>.\r\n\r\nГлава вторая\r\n\r\nТекст.\x0c\r\n\x1a",
+			want: []epubx.Chapter{
+				{Body: "\n<p>Роберт Сальваторе Воин</p>"},
+				{Title: "Глава 1", Body: "<h1>Глава 1</h1>\n<p>Первая строка абзаца.</p>\n<p>Второй абзац &amp; &lt;теги&gt;.</p>"},
+				{Title: "Глава вторая", Body: "<h1>Глава вторая</h1>\n<p>Текст.</p>"},
+			},
+		},
+		{
+			name: "line per paragraph with parts",
+			text: "Пролог\nНачало.\nЧасть I. Изгнание\nГлава 1\nГлава первой главы.\nГлава 2\nКнига лежала на столе.\nЧасть 2\nЭпилог\nКонец.",
+			want: []epubx.Chapter{
+				{Title: "Пролог", Body: "<h1>Пролог</h1>\n<p>Начало.</p>"},
+				{Title: "Часть I. Изгнание", Body: "<h1>Часть I. Изгнание</h1>", Children: []epubx.Chapter{
+					{Title: "Глава 1", Body: "<h2>Глава 1</h2>\n<p>Глава первой главы.</p>"},
+					{Title: "Глава 2", Body: "<h2>Глава 2</h2>\n<p>Книга лежала на столе.</p>"},
+				}},
+				{Title: "Часть 2", Body: "<h1>Часть 2</h1>", Children: []epubx.Chapter{
+					{Title: "Эпилог", Body: "<h2>Эпилог</h2>\n<p>Конец.</p>"},
+				}},
+			},
+		},
+		{
+			name: "no text",
+			text: "\n \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, Chapters(tt.text))
+		})
+	}
+}
+
+func TestChapters_long(t *testing.T) {
+	t.Parallel()
+
+	para := strings.Repeat("слово ", 100)
+	text := strings.Repeat(para+"\n\n", 2*maxChapterSize/len(para))
+
+	chapters := Chapters(text)
+	require.Len(t, chapters, 3)
+	for _, c := range chapters {
+		assert.Empty(t, c.Title)
+		assert.LessOrEqual(t, len(c.Body), maxChapterSize+len(para)+10)
+	}
+}
+
+func TestBook(t *testing.T) {
+	t.Parallel()
+
+	data, err := charmap.Windows1251.NewEncoder().String("Глава 1\n\n" + sample + "\n\nГлава 2\n\n" + sample)
+	require.NoError(t, err)
+	book := Book([]byte(data))
+	book.Identifier = "urn:uuid:07aada78-943e-44a1-b680-4b2edba53ba9"
+	book.Title = "Воин"
+
+	var buf bytes.Buffer
+	require.NoError(t, epubx.WriteBook(&buf, book))
+	r := bytes.NewReader(buf.Bytes())
+	report := epubx.Validate(r, r.Size())
+	assert.True(t, report.Valid(), "%v", report.Issues)
+	epub, err := epubx.ParseEPUB(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, []epubx.TOCEntry{
+		{Title: "Глава 1", Path: "OEBPS/text/ch001.xhtml"},
+		{Title: "Глава 2", Path: "OEBPS/text/ch002.xhtml"},
+	}, epub.TOC)
+}
@@ -12,10 +12,37 @@ import (
 	"github.com/ARUMANDESU/goread/backend/pkg/i18nx"
 )
 
-var ErrInvalidPasswordFormat = v.NewError(i18nx.ValidationInvalidPasswordFormat, i18nx.ValidationInvalidPasswordFormatMessage)
+var (
+	ErrInvalidPasswordFormat = v.NewError(i18nx.ValidationInvalidPasswordFormat, i18nx.ValidationInvalidPasswordFormatMessage)
+	ErrInvalidCursor         = v.NewError(i18nx.ValidationInvalidCursor, i18nx.ValidationInvalidCursorMessage)
+	ErrInvalidCFI            = v.NewError(i18nx.ValidationInvalidCfi, i18nx.ValidationInvalidCfiMessage)
+	ErrUnsupportedLocator    = v.NewError(i18nx.ValidationUnsupportedLocator, i18nx.ValidationUnsupportedLocatorMessage)
+	ErrUnavailableFormat     = v.NewError(i18nx.ValidationUnavailableFormat, i18nx.ValidationUnavailableFormatMessage)
+)
+
+// DefaultPageLimit and MaxPageLimit bound the page size of the list queries.
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
 
 var Required = RequiredRule{}
 
+// Cursor validates an opaque page cursor with decode, which fails for cursors that are malformed
+// or do not fit the query. The empty cursor of the first page is valid.
+func Cursor(decode func(string) error) v.Rule {
+	return v.By(func(value any) error {
+		cursor, _ := value.(string)
+		if cursor == "" {
+			return nil
+		}
+		if err := decode(cursor); err != nil {
+			return ErrInvalidCursor
+		}
+		return nil
+	})
+}
+
 func Password(minLen, maxLen int) PasswordFormatRule {
 	return PasswordFormatRule{
 		min: minLen,
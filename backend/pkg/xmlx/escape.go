@@ -0,0 +1,77 @@
+// Package xmlx escapes text for the XML and XHTML documents goread writes, such as EPUB packages
+// and the chapters of converted books.
+package xmlx
+
+import (
+	"strings"
+)
+
+// Writer is what the Write functions write to, bytes.Buffer and strings.Builder are Writers.
+type Writer interface {
+	WriteString(s string) (int, error)
+	WriteRune(r rune) (int, error)
+}
+
+// WriteText writes s escaped as character data. Characters XML 1.0 does not allow, such as the
+// control characters of old text files, are dropped as no entity can stand for them.
+func WriteText(w Writer, s string) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			w.WriteString("&amp;")
+		case r == '<':
+			w.WriteString("&lt;")
+		case r == '>':
+			w.WriteString("&gt;")
+		case IsChar(r):
+			w.WriteRune(r)
+		}
+	}
+}
+
+// WriteAttr writes s escaped as an attribute value in double quotes. Tabs and line breaks are
+// written as character references, so parsers do not normalize them to spaces.
+func WriteAttr(w Writer, s string) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			w.WriteString("&amp;")
+		case r == '<':
+			w.WriteString("&lt;")
+		case r == '>':
+			w.WriteString("&gt;")
+		case r == '"':
+			w.WriteString("&quot;")
+		case r == '\n':
+			w.WriteString("&#xA;")
+		case r == '\r':
+			w.WriteString("&#xD;")
+		case r == '\t':
+			w.WriteString("&#x9;")
+		case IsChar(r):
+			w.WriteRune(r)
+		}
+	}
+}
+
+// EscapeText returns s escaped as by WriteText.
+func EscapeText(s string) string {
+	var b strings.Builder
+	WriteText(&b, s)
+	return b.String()
+}
+
+// EscapeAttr returns s escaped as by WriteAttr.
+func EscapeAttr(s string) string {
+	var b strings.Builder
+	WriteAttr(&b, s)
+	return b.String()
+}
+
+// IsChar reports whether r may appear in an XML 1.0 document, see the Char production of the spec.
+func IsChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xd7ff ||
+		r >= 0xe000 && r <= 0xfffd ||
+		r >= 0x10000 && r <= 0x10ffff
+}
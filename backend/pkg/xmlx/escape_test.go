@@ -0,0 +1,36 @@
+package xmlx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "markup", input: `Tom & "Jerry" <b>`, expected: `Tom &amp; "Jerry" &lt;b&gt;`},
+		{name: "whitespace kept", input: "a\tb\r\nc", expected: "a\tb\r\nc"},
+		{name: "control characters dropped", input: "конец\x1a\x00\x0c", expected: "конец"},
+		{name: "noncharacters dropped", input: "a￾b￿", expected: "ab"},
+		{name: "astral plane kept", input: "📖", expected: "📖"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, EscapeText(tt.input))
+		})
+	}
+}
+
+func TestEscapeAttr(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `a &amp; &quot;b&quot; &lt;c&gt;&#x9;&#xA;&#xD;`, EscapeAttr("a & \"b\" <c>\t\n\r\x1a"))
+}